@@ -0,0 +1,225 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudevent
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudevent"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/cloudevent"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+	"yunion.io/x/onecloud/pkg/util/printutils"
+)
+
+type CloudeventHookFilterOptions struct {
+	Provider       []string `help:"match cloud providers, e.g. Aliyun"`
+	ResourceType   []string `help:"match resource types, e.g. secgroupcache"`
+	EventAction    []string `help:"match event actions, wildcard * is supported, e.g. AuthorizeSecurityGroup*"`
+	Account        []string `help:"match accounts performing the action, wildcard * is supported"`
+	EventResult    string   `help:"match event result" choices:"any|success|failed"`
+	RequestKeyword string   `help:"match events with request containing the keyword, e.g. 0.0.0.0/0"`
+}
+
+func (opts *CloudeventHookFilterOptions) isSet() bool {
+	return len(opts.Provider) > 0 || len(opts.ResourceType) > 0 || len(opts.EventAction) > 0 ||
+		len(opts.Account) > 0 || opts.EventResult != "" || opts.RequestKeyword != ""
+}
+
+func (opts *CloudeventHookFilterOptions) filter() *api.CloudeventHookFilter {
+	return &api.CloudeventHookFilter{
+		Providers:      opts.Provider,
+		ResourceTypes:  opts.ResourceType,
+		Actions:        opts.EventAction,
+		Accounts:       opts.Account,
+		EventResult:    opts.EventResult,
+		RequestKeyword: opts.RequestKeyword,
+	}
+}
+
+type CloudeventHookActionParamsOptions struct {
+	Receiver    []string `help:"notify: receiver id or name"`
+	Robot       []string `help:"notify: robot id or name"`
+	ContactType string   `help:"notify: contact type, e.g. email"`
+	Topic       string   `help:"notify: message title"`
+	Priority    string   `help:"notify: message priority" choices:"normal|important|fatal"`
+
+	Url    string   `help:"webhook: url to post events to"`
+	Header []string `help:"webhook: extra request header in the form of key:value"`
+
+	PlaybookReference string `help:"ansible: ansible playbook reference id"`
+	AnsibleHost       string `help:"ansible: host to run the playbook on in json"`
+	AnsibleArgs       string `help:"ansible: playbook arguments in json"`
+
+	ScheduledTask string `help:"scheduledtask: id or name of scheduled task to trigger"`
+}
+
+func (opts *CloudeventHookActionParamsOptions) params() (*jsonutils.JSONDict, error) {
+	params := jsonutils.NewDict()
+	if len(opts.Receiver) > 0 {
+		params.Set("receivers", jsonutils.NewStringArray(opts.Receiver))
+	}
+	if len(opts.Robot) > 0 {
+		params.Set("robots", jsonutils.NewStringArray(opts.Robot))
+	}
+	for k, v := range map[string]string{
+		"contact_type":       opts.ContactType,
+		"topic":              opts.Topic,
+		"priority":           opts.Priority,
+		"url":                opts.Url,
+		"playbook_reference": opts.PlaybookReference,
+		"scheduled_task":     opts.ScheduledTask,
+	} {
+		if v != "" {
+			params.Set(k, jsonutils.NewString(v))
+		}
+	}
+	if len(opts.Header) > 0 {
+		headers := jsonutils.NewDict()
+		for _, h := range opts.Header {
+			i := strings.Index(h, ":")
+			if i <= 0 {
+				return nil, fmt.Errorf("invalid header %q, want key:value", h)
+			}
+			headers.Set(strings.TrimSpace(h[:i]), jsonutils.NewString(strings.TrimSpace(h[i+1:])))
+		}
+		params.Set("headers", headers)
+	}
+	for k, v := range map[string]string{
+		"host": opts.AnsibleHost,
+		"args": opts.AnsibleArgs,
+	} {
+		if v == "" {
+			continue
+		}
+		obj, err := jsonutils.ParseString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ansible %s json: %v", k, err)
+		}
+		params.Set(k, obj)
+	}
+	return params, nil
+}
+
+func init() {
+	printObject := printutils.PrintJSONObject
+
+	type CloudeventHookListOptions struct {
+		options.BaseListOptions
+
+		HookAction []string `help:"filter by hook action" choices:"notify|webhook|ansible|scheduledtask"`
+	}
+	R(&CloudeventHookListOptions{}, "cloud-event-hook-list", "List cloud event hooks", func(s *mcclient.ClientSession, opts *CloudeventHookListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.CloudeventHooks.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.CloudeventHooks.GetColumns(s))
+		return nil
+	})
+
+	type CloudeventHookIdOptions struct {
+		ID string `help:"ID or name of cloud event hook"`
+	}
+	R(&CloudeventHookIdOptions{}, "cloud-event-hook-show", "Show cloud event hook", func(s *mcclient.ClientSession, opts *CloudeventHookIdOptions) error {
+		result, err := modules.CloudeventHooks.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&CloudeventHookIdOptions{}, "cloud-event-hook-delete", "Delete cloud event hook", func(s *mcclient.ClientSession, opts *CloudeventHookIdOptions) error {
+		result, err := modules.CloudeventHooks.Delete(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	for _, action := range []string{"enable", "disable", "test"} {
+		action := action
+		R(&CloudeventHookIdOptions{}, "cloud-event-hook-"+action, strings.Title(action)+" cloud event hook", func(s *mcclient.ClientSession, opts *CloudeventHookIdOptions) error {
+			result, err := modules.CloudeventHooks.PerformAction(s, opts.ID, action, nil)
+			if err != nil {
+				return err
+			}
+			printObject(result)
+			return nil
+		})
+	}
+
+	type CloudeventHookCreateOptions struct {
+		NAME       string `help:"name of cloud event hook"`
+		HOOKACTION string `help:"action to take on matching events" choices:"notify|webhook|ansible|scheduledtask"`
+
+		CloudeventHookFilterOptions
+		CloudeventHookActionParamsOptions
+	}
+	R(&CloudeventHookCreateOptions{}, "cloud-event-hook-create", "Create cloud event hook", func(s *mcclient.ClientSession, opts *CloudeventHookCreateOptions) error {
+		params := jsonutils.NewDict()
+		params.Set("name", jsonutils.NewString(opts.NAME))
+		params.Set("hook_action", jsonutils.NewString(opts.HOOKACTION))
+		params.Set("filter", jsonutils.Marshal(opts.filter()))
+		actionParams, err := opts.params()
+		if err != nil {
+			return err
+		}
+		params.Set("hook_action_params", actionParams)
+		result, err := modules.CloudeventHooks.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type CloudeventHookUpdateOptions struct {
+		ID   string `help:"ID or name of cloud event hook"`
+		Name string `help:"new name of cloud event hook"`
+
+		CloudeventHookFilterOptions
+		CloudeventHookActionParamsOptions
+	}
+	R(&CloudeventHookUpdateOptions{}, "cloud-event-hook-update", "Update cloud event hook, filter and action params are replaced as a whole", func(s *mcclient.ClientSession, opts *CloudeventHookUpdateOptions) error {
+		params := jsonutils.NewDict()
+		if opts.Name != "" {
+			params.Set("name", jsonutils.NewString(opts.Name))
+		}
+		if opts.CloudeventHookFilterOptions.isSet() {
+			params.Set("filter", jsonutils.Marshal(opts.filter()))
+		}
+		actionParams, err := opts.params()
+		if err != nil {
+			return err
+		}
+		if actionParams.Length() > 0 {
+			params.Set("hook_action_params", actionParams)
+		}
+		result, err := modules.CloudeventHooks.Update(s, opts.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
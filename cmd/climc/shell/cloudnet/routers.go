@@ -42,6 +42,14 @@ func init() {
 		printObject(router)
 		return nil
 	})
+	R(&options.RouterPeerStatusOptions{}, "router-peer-status", "Show router wireguard peer handshake health and routes", func(s *mcclient.ClientSession, opts *options.RouterPeerStatusOptions) error {
+		result, err := modules.Routers.GetSpecific(s, opts.ID, "peer-status", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
 	R(&options.RouterListOptions{}, "router-list", "List routers", func(s *mcclient.ClientSession, opts *options.RouterListOptions) error {
 		params, err := base_options.ListStructToParams(opts)
 		if err != nil {
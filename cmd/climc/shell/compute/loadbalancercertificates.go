@@ -100,4 +100,12 @@ func init() {
 		printObject(lbcert)
 		return nil
 	})
+	R(&options.LoadbalancerCertificateAcmeRenewOptions{}, "lbcert-acme-renew", "Renew acme lbcert now", func(s *mcclient.ClientSession, opts *options.LoadbalancerCertificateAcmeRenewOptions) error {
+		lbcert, err := modules.LoadbalancerCertificates.PerformAction(s, opts.ID, "acme-renew", nil)
+		if err != nil {
+			return err
+		}
+		printObject(lbcert)
+		return nil
+	})
 }
@@ -137,4 +137,36 @@ func init() {
 		}
 		return printLbBackendStatus(backendStatus)
 	})
+	R(&options.LoadbalancerListenerGetStatsOptions{}, "lblistener-stats", "Get lblistener and backend stats", func(s *mcclient.ClientSession, opts *options.LoadbalancerListenerGetStatsOptions) error {
+		stats, err := modules.LoadbalancerListeners.GetSpecific(s, opts.ID, "stats", nil)
+		if err != nil {
+			return err
+		}
+		listener, err := stats.Get("listener")
+		if err != nil {
+			return err
+		}
+		printObject(listener)
+		backends, err := stats.GetArray("backends")
+		if err != nil {
+			return err
+		}
+		listResult := &modulebase.ListResult{
+			Data: backends,
+		}
+		columns := []string{
+			"id",
+			"name",
+			"address",
+			"healthy",
+			"conn_cur",
+			"conn_rate",
+			"hrsp_4xx_rate",
+			"hrsp_5xx_rate",
+			"response_time",
+			"stats_time",
+		}
+		printList(listResult, columns)
+		return nil
+	})
 }
@@ -71,6 +71,11 @@ func init() {
 		IsAutoAlloc *bool  `help:"Auto allocation IP pool"`
 		BgpType     string `help:"Internet service provider name" positional:"false"`
 		Desc        string `help:"Description" metavar:"DESCRIPTION"`
+
+		Ip6Prefix string `help:"IPv6 prefix of the network, e.g. 2001:db8:1:2::/64"`
+		Gateway6  string `help:"Default IPv6 gateway"`
+		Dns6      string `help:"IPv6 DNS servers, seperated by ','"`
+		Ip6Mode   string `help:"IPv6 address mode" choices:"static|slaac|dhcpv6"`
 	}
 	R(&NetworkCreateOptions{}, "network-create", "Create a virtual network", func(s *mcclient.ClientSession, args *NetworkCreateOptions) error {
 		params := jsonutils.NewDict()
@@ -102,6 +107,18 @@ func init() {
 		if args.IsAutoAlloc != nil {
 			params.Add(jsonutils.NewBool(*args.IsAutoAlloc), "is_auto_alloc")
 		}
+		if len(args.Ip6Prefix) > 0 {
+			params.Add(jsonutils.NewString(args.Ip6Prefix), "guest_ip6_prefix")
+		}
+		if len(args.Gateway6) > 0 {
+			params.Add(jsonutils.NewString(args.Gateway6), "guest_gateway6")
+		}
+		if len(args.Dns6) > 0 {
+			params.Add(jsonutils.NewString(args.Dns6), "guest_dns6")
+		}
+		if len(args.Ip6Mode) > 0 {
+			params.Add(jsonutils.NewString(args.Ip6Mode), "ip6_mode")
+		}
 		net, e := modules.Networks.CreateInContext(s, params, &modules.Wires, args.WIRE)
 		if e != nil {
 			return e
@@ -0,0 +1,71 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/cmd/climc/shell"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+	"yunion.io/x/onecloud/pkg/mcclient/options/compute"
+)
+
+func init() {
+	cmd := shell.NewResourceCmd(&modules.PricePlans)
+	cmd.List(&compute.PricePlanListOptions{})
+	cmd.Create(&compute.PricePlanCreateOptions{})
+	cmd.Update(&compute.PricePlanUpdateOptions{})
+	cmd.Show(&options.BaseIdOptions{})
+	cmd.Delete(&options.BaseIdOptions{})
+	cmd.Perform("enable", &options.BaseIdOptions{})
+	cmd.Perform("disable", &options.BaseIdOptions{})
+	cmd.Perform("public", &options.BasePublicOptions{})
+	cmd.Perform("private", &options.BaseIdOptions{})
+
+	cmd = shell.NewResourceCmd(&modules.RatingBills)
+	cmd.List(&compute.RatingBillListOptions{})
+	cmd.Show(&options.BaseIdOptions{})
+	cmd.PerformClass("regenerate", &compute.RatingBillRegenerateOptions{})
+
+	R(&compute.RatingBillSummaryOptions{}, "rating-bill-summary", "Summarize rating bills", func(s *mcclient.ClientSession, args *compute.RatingBillSummaryOptions) error {
+		params, err := args.Params()
+		if err != nil {
+			return err
+		}
+		ret, err := modules.RatingBills.Get(s, "summary", params)
+		if err != nil {
+			return err
+		}
+		data, _ := ret.GetArray("data")
+		result := &modulebase.ListResult{Data: data, Total: len(data)}
+		columns := []string{"key", "name", "currency", "amount"}
+		if len(args.GetExportFile()) > 0 {
+			exportList(result, args.GetExportFile(), args.GetExportKeys(), args.GetExportTexts(), columns)
+			return nil
+		}
+		printList(result, columns)
+		return nil
+	})
+
+	cmd = shell.NewResourceCmd(&modules.Budgets)
+	cmd.List(&compute.BudgetListOptions{})
+	cmd.Create(&compute.BudgetCreateOptions{})
+	cmd.Update(&compute.BudgetUpdateOptions{})
+	cmd.Show(&options.BaseIdOptions{})
+	cmd.Delete(&options.BaseIdOptions{})
+	cmd.Perform("enable", &options.BaseIdOptions{})
+	cmd.Perform("disable", &options.BaseIdOptions{})
+}
@@ -142,4 +142,58 @@ func init() {
 		printObject(ret)
 		return nil
 	})
+	type ScalingGroupCompleteLifecycleActionOptions struct {
+		ID            string `help:"ScalingGroup ID or Name"`
+		GUEST         string `help:"Guest ID or Name waiting on the lifecycle hook"`
+		RESULT        string `help:"Result of lifecycle action" choices:"continue|abandon"`
+		LifecycleHook string `help:"Lifecycle hook ID or Name"`
+	}
+	R(&ScalingGroupCompleteLifecycleActionOptions{}, "scaling-group-complete-lifecycle-action", "Report the result of lifecycle hook for guest of ScalingGroup",
+		func(s *mcclient.ClientSession, args *ScalingGroupCompleteLifecycleActionOptions) error {
+			params := jsonutils.NewDict()
+			params.Set("guest", jsonutils.NewString(args.GUEST))
+			params.Set("result", jsonutils.NewString(args.RESULT))
+			if len(args.LifecycleHook) > 0 {
+				params.Set("lifecycle_hook", jsonutils.NewString(args.LifecycleHook))
+			}
+			ret, err := modules.ScalingGroup.PerformAction(s, args.ID, "complete-lifecycle-action", params)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+	type ScalingGroupInstanceRefreshOptions struct {
+		ID                   string `help:"ScalingGroup ID or Name"`
+		MinHealthyPercentage *int   `help:"Min percentage of healthy instances during refresh, default 90"`
+		BatchTimeout         int    `help:"Timeout in seconds of each batch, default 1800"`
+	}
+	R(&ScalingGroupInstanceRefreshOptions{}, "scaling-group-instance-refresh", "Replace all instances of ScalingGroup in batches",
+		func(s *mcclient.ClientSession, args *ScalingGroupInstanceRefreshOptions) error {
+			params := jsonutils.NewDict()
+			if args.MinHealthyPercentage != nil {
+				params.Set("min_healthy_percentage", jsonutils.NewInt(int64(*args.MinHealthyPercentage)))
+			}
+			if args.BatchTimeout > 0 {
+				params.Set("batch_timeout", jsonutils.NewInt(int64(args.BatchTimeout)))
+			}
+			ret, err := modules.ScalingGroup.PerformAction(s, args.ID, "instance-refresh", params)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+	R(&ScalingGroupEnableOptions{}, "scaling-group-cancel-instance-refresh", "Cancel the instance refresh of ScalingGroup",
+		func(s *mcclient.ClientSession, args *ScalingGroupEnableOptions) error {
+			ret, err := modules.ScalingGroup.PerformAction(s, args.ID, "cancel-instance-refresh", jsonutils.NewDict())
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
 }
@@ -0,0 +1,132 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	type ScalingLifecycleHookListOptions struct {
+		options.BaseListOptions
+		ScalingGroup string `help:"ScalingGroup ID or Name"`
+		HookType     string `help:"Hook type" choices:"launching|terminating"`
+	}
+	R(&ScalingLifecycleHookListOptions{}, "scaling-lifecycle-hook-list", "List lifecycle hooks of ScalingGroup",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookListOptions) error {
+			params, err := options.ListStructToParams(args)
+			if err != nil {
+				return err
+			}
+			list, err := modules.ScalingLifecycleHook.List(s, params)
+			if err != nil {
+				return err
+			}
+			printList(list, modules.ScalingLifecycleHook.GetColumns(s))
+			return nil
+		},
+	)
+
+	type ScalingLifecycleHookCreateOptions struct {
+		NAME                     string
+		ScalingGroup             string `help:"ScalingGroup ID or Name" required:"true"`
+		HookType                 string `help:"Hook type" choices:"launching|terminating" required:"true"`
+		ActionType               string `help:"Action type" choices:"webhook|ansible" required:"true"`
+		WebhookUrl               string `help:"Url of webhook"`
+		AnsiblePlaybookReference string `help:"Ansible playbook reference ID or Name"`
+		AnsibleUser              string `help:"User to login the guest for ansible, default root"`
+		Timeout                  int    `help:"Timeout in seconds to wait for the result, default 300"`
+		DefaultResult            string `help:"Result on timeout or failure" choices:"continue|abandon"`
+	}
+	R(&ScalingLifecycleHookCreateOptions{}, "scaling-lifecycle-hook-create", "Create lifecycle hook of ScalingGroup",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookCreateOptions) error {
+			params := jsonutils.Marshal(args).(*jsonutils.JSONDict)
+			params.Set("name", jsonutils.NewString(args.NAME))
+			ret, err := modules.ScalingLifecycleHook.Create(s, params)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+
+	type ScalingLifecycleHookIdOptions struct {
+		ID string `help:"Lifecycle hook ID or Name"`
+	}
+	R(&ScalingLifecycleHookIdOptions{}, "scaling-lifecycle-hook-show", "Show lifecycle hook",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookIdOptions) error {
+			ret, err := modules.ScalingLifecycleHook.Get(s, args.ID, nil)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+	R(&ScalingLifecycleHookIdOptions{}, "scaling-lifecycle-hook-delete", "Delete lifecycle hook",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookIdOptions) error {
+			ret, err := modules.ScalingLifecycleHook.Delete(s, args.ID, nil)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+	R(&ScalingLifecycleHookIdOptions{}, "scaling-lifecycle-hook-enable", "Enable lifecycle hook",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookIdOptions) error {
+			ret, err := modules.ScalingLifecycleHook.PerformAction(s, args.ID, "enable", nil)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+	R(&ScalingLifecycleHookIdOptions{}, "scaling-lifecycle-hook-disable", "Disable lifecycle hook",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookIdOptions) error {
+			ret, err := modules.ScalingLifecycleHook.PerformAction(s, args.ID, "disable", nil)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+
+	type ScalingInstanceRefreshListOptions struct {
+		options.BaseListOptions
+		ScalingGroup string `help:"ScalingGroup ID or Name"`
+	}
+	R(&ScalingInstanceRefreshListOptions{}, "scaling-instance-refresh-list", "List instance refreshes of ScalingGroup",
+		func(s *mcclient.ClientSession, args *ScalingInstanceRefreshListOptions) error {
+			params, err := options.ListStructToParams(args)
+			if err != nil {
+				return err
+			}
+			list, err := modules.ScalingInstanceRefresh.List(s, params)
+			if err != nil {
+				return err
+			}
+			printList(list, modules.ScalingInstanceRefresh.GetColumns(s))
+			return nil
+		},
+	)
+}
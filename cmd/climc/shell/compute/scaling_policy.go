@@ -44,7 +44,7 @@ func init() {
 	type ScalingPolicyListOptions struct {
 		options.BaseListOptions
 		ScalingGroup string `help:"ScalingGroup ID or Name"`
-		TriggerType  string `help:"Trigger type" choices:"alarm|timing|cycle"`
+		TriggerType  string `help:"Trigger type" choices:"alarm|timing|cycle|target_tracking"`
 	}
 	R(&ScalingPolicyListOptions{}, "scaling-policy-list", "List Scaling Policy", func(s *mcclient.ClientSession,
 		args *ScalingPolicyListOptions) error {
@@ -81,14 +81,25 @@ func init() {
 		AlarmValue     float64 `help:"Value of Indicator" json:"value"`
 	}
 
+	type ScalingTargetTracking struct {
+		TrackingIndicator      string  `help:"Indicator for 'target_tracking' trigger" choices:"cpu|mem|disk_read|disk_write|flow_into|flow_out" json:"tracking_indicator"`
+		TrackingTargetValue    float64 `help:"Target value of the average indicator, for 'target_tracking' trigger" json:"tracking_target_value"`
+		TrackingWarmUp         int     `help:"Warm up time of new instances, unit: s" json:"tracking_warm_up"`
+		TrackingDisableScaleIn bool    `help:"Disable scaling in for 'target_tracking' trigger" json:"tracking_disable_scale_in"`
+		TrackingPredictive     string  `help:"Predictive mode, pre-scale by the pattern of last weeks" choices:"off|daily|weekly" json:"tracking_predictive"`
+		TrackingLookbackWeeks  int     `help:"Weeks of history for predictive mode" json:"tracking_lookback_weeks"`
+		TrackingAhead          int     `help:"How long to scale ahead for predictive mode, unit: s" json:"tracking_ahead"`
+	}
+
 	type ScalingPolicyCreateOptions struct {
 		NAME         string `help:"ScalingPolicy Name" json:"name"`
 		ScalingGroup string `help:"ScalingGroup ID or Name" json:"scaling_group"`
-		TriggerType  string `help:"Trigger type" choices:"alarm|timing|cycle" json:"trigger_type"`
+		TriggerType  string `help:"Trigger type" choices:"alarm|timing|cycle|target_tracking" json:"trigger_type"`
 
 		Timer
 		CycleTimer
 		ScalingAlarm
+		ScalingTargetTracking
 
 		Action      string `help:"Action for scaling policy" choices:"add|remove|set" json:"action"`
 		Number      int    `help:"Instance number for action" json:"number"`
@@ -134,6 +145,15 @@ func init() {
 					Operator:  args.AlarmOperator,
 					Value:     args.AlarmValue,
 				},
+				TargetTracking: api.ScalingTargetTrackingCreateInput{
+					Indicator:               args.TrackingIndicator,
+					TargetValue:             args.TrackingTargetValue,
+					WarmUp:                  args.TrackingWarmUp,
+					DisableScaleIn:          args.TrackingDisableScaleIn,
+					PredictiveMode:          args.TrackingPredictive,
+					PredictiveLookbackWeeks: args.TrackingLookbackWeeks,
+					PredictiveAhead:         args.TrackingAhead,
+				},
 				Action:      args.Action,
 				Number:      args.Number,
 				Unit:        args.Unit,
@@ -94,6 +94,7 @@ func init() {
 	cmd.Perform("migrate-network", &options.ServerMigrateNetworkOptions{})
 	cmd.Perform("set-sshport", &options.ServerSetSshportOptions{})
 	cmd.Perform("have-agent", &options.ServerHaveAgentOptions{})
+	cmd.Perform("set-metadata-options", &options.ServerSetMetadataOptionsOptions{})
 	cmd.Perform("change-disk-storage", &options.ServerChangeDiskStorageOptions{})
 	cmd.PerformClass("batch-user-metadata", &options.ServerBatchMetadataOptions{})
 	cmd.PerformClass("batch-set-user-metadata", &options.ServerBatchMetadataOptions{})
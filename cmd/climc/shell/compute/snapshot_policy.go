@@ -57,6 +57,15 @@ func init() {
 		RetentionDays  int   `help:"snapshot retention days"`
 		RepeatWeekdays []int `help:"snapshot create days on week"`
 		TimePoints     []int `help:"snapshot create time points on one day"`
+
+		CronExpression   string `help:"cron expression of snapshot schedule, e.g. '0 */6 * * *', overrides repeat_weekdays and time_points"`
+		RetentionCount   int    `help:"number of latest auto snapshots to keep"`
+		RetentionDaily   int    `help:"number of days to keep the latest auto snapshot of each day"`
+		RetentionWeekly  int    `help:"number of weeks to keep the latest auto snapshot of each week"`
+		RetentionMonthly int    `help:"number of months to keep the latest auto snapshot of each month"`
+		DiskType         string `help:"only snapshot disks of this type" choices:"sys|data"`
+		ConsistencyGroup bool   `help:"snapshot all disks of a guest together as an instance snapshot"`
+		BackupStorageId  string `help:"copy auto snapshots to this backup storage"`
 	}
 
 	R(&SnapshotPolicyCreateOptions{}, "snapshot-policy-create", "Create snapshot policy", func(s *mcclient.ClientSession, args *SnapshotPolicyCreateOptions) error {
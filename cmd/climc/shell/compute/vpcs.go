@@ -35,6 +35,8 @@ func init() {
 	cmd.Perform("private", &options.VpcIdOptions{})
 	cmd.Perform("public", &options.BasePublicOptions{})
 	cmd.Perform("change-owner", &options.VpcChangeOwnerOptions{})
+	cmd.Perform("accept-peering-connection", &options.VpcPeeringConnectionAcceptOptions{})
+	cmd.Perform("reject-peering-connection", &options.VpcPeeringConnectionAcceptOptions{})
 	cmd.Get("vpc-change-owner-candidate-domains", &options.VpcIdOptions{})
 	cmd.Get("topology", &options.VpcIdOptions{})
 
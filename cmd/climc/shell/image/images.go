@@ -15,14 +15,17 @@
 package image
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 
 	"github.com/cheggaaa/pb/v3"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
 
 	"yunion.io/x/onecloud/cmd/climc/shell"
 	"yunion.io/x/onecloud/pkg/mcclient"
@@ -61,6 +64,8 @@ type ImageOptionalOptions struct {
 	NetDriver          string   `help:"Preferred network driver" choices:"virtio|e1000|vmxnet3"`
 	DisableUsbKbd      bool     `help:"Disable usb keyboard on this image(for hypervisor kvm)"`
 	BootMode           string   `help:"UEFI support" choices:"UEFI|BIOS"`
+	Signature          string   `help:"Path of the detached signature file generated by cosign sign-blob"`
+	SignatureCert      string   `help:"Path of the PEM certificate or public key of the signer"`
 }
 
 func addImageOptionalOptions(s *mcclient.ClientSession, params *jsonutils.JSONDict, args ImageOptionalOptions) error {
@@ -146,6 +151,20 @@ func addImageOptionalOptions(s *mcclient.ClientSession, params *jsonutils.JSONDi
 	} else if args.BootMode == "BIOS" {
 		params.Add(jsonutils.JSONFalse, "properties", "uefi_support")
 	}
+	if len(args.Signature) > 0 {
+		sig, err := ioutil.ReadFile(args.Signature)
+		if err != nil {
+			return errors.Wrapf(err, "read signature %s", args.Signature)
+		}
+		params.Add(jsonutils.NewString(strings.TrimSpace(string(sig))), "properties", "signature")
+	}
+	if len(args.SignatureCert) > 0 {
+		cert, err := ioutil.ReadFile(args.SignatureCert)
+		if err != nil {
+			return errors.Wrapf(err, "read signature cert %s", args.SignatureCert)
+		}
+		params.Add(jsonutils.NewString(base64.StdEncoding.EncodeToString(cert)), "properties", "signature_cert")
+	}
 	return nil
 }
 
@@ -451,4 +470,16 @@ func init() {
 		printObject(img)
 		return nil
 	})
+
+	type ImageVerifySignatureOptions struct {
+		ID string `help:"ID or name of image to verify"`
+	}
+	R(&ImageVerifySignatureOptions{}, "image-verify-signature", "Verify the detached signature of an image", func(s *mcclient.ClientSession, opts *ImageVerifySignatureOptions) error {
+		ret, err := modules.Images.PerformAction(s, opts.ID, "verify-signature", nil)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
 }
@@ -0,0 +1,39 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"yunion.io/x/onecloud/cmd/climc/shell"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/mcclient/options/glance"
+)
+
+func init() {
+	policyCmd := shell.NewResourceCmd(&modules.ImageReplicationPolicies)
+	policyCmd.List(&glance.ImageReplicationPolicyListOptions{})
+	policyCmd.Show(&glance.ImageReplicationPolicyIdOptions{})
+	policyCmd.Create(&glance.ImageReplicationPolicyCreateOptions{})
+	policyCmd.Update(&glance.ImageReplicationPolicyUpdateOptions{})
+	policyCmd.Delete(&glance.ImageReplicationPolicyIdOptions{})
+	policyCmd.Perform("enable", &glance.ImageReplicationPolicyIdOptions{})
+	policyCmd.Perform("disable", &glance.ImageReplicationPolicyIdOptions{})
+	policyCmd.Perform("sync", &glance.ImageReplicationPolicyIdOptions{})
+
+	replicaCmd := shell.NewResourceCmd(&modules.ImageReplicas)
+	replicaCmd.List(&glance.ImageReplicaListOptions{})
+	replicaCmd.Show(&glance.ImageReplicaIdOptions{})
+	replicaCmd.Delete(&glance.ImageReplicaIdOptions{})
+	replicaCmd.Perform("sync", &glance.ImageReplicaIdOptions{})
+}
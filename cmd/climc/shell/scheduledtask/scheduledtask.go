@@ -38,7 +38,7 @@ func init() {
 	type ScheduledTaskListOptions struct {
 		options.BaseListOptions
 
-		ScheduledType string `help:"scheduled type" choices:"timing|cycle"`
+		ScheduledType string `help:"scheduled type" choices:"timing|cycle|cron"`
 		ResourceType  string `help:"resource type"`
 		Operation     string `help:"operation"`
 		UtcOffset     int    `help:"utc offset"`
@@ -82,17 +82,26 @@ func init() {
 		CycleEndTime   string `help:"End time for cycle timer, format:'2006-01-02 15:04:05'" json:"end_time"`
 	}
 
+	type CronTimer struct {
+		CronExpression string `help:"Cron expression for cron timer, e.g. '30 2 * * 1-5'" json:"expression"`
+		CronTimeZone   string `help:"Time zone of cron expression, e.g. 'Asia/Shanghai', default UTC" json:"time_zone"`
+	}
+
 	type ScheduledTaskCreateOptions struct {
 		NAME          string `help:"ScheduledTask Name" json:"name"`
-		ScheduledType string `help:"Scheudled Type" choices:"timing|cycle" json:"scheduled_type"`
+		ScheduledType string `help:"Scheudled Type" choices:"timing|cycle|cron" json:"scheduled_type"`
 
 		Timer
 		CycleTimer
+		CronTimer
 
-		ResourceType string   `help:"resource type"`
-		Operation    string   `help:"operation"`
-		LabelType    string   `help:"label type"`
-		Labels       []string `help:"labels"`
+		ResourceType    string   `help:"resource type" choices:"server|cloudaccount|disk|snapshot|scalinggroup|dbinstance"`
+		Operation       string   `help:"operation" choices:"start|stop|restart|sync|enable|disable|delete|snapshot|change-config|resize|run-playbook"`
+		OperationParams string   `help:"operation params in json, e.g. '{\"size\":\"100G\"}' for resize"`
+		LabelType       string   `help:"label type"`
+		Labels          []string `help:"labels"`
+		MaxConcurrency  int      `help:"max number of resources operated at the same time in one run"`
+		CatchUp         string   `help:"how to handle runs missed" choices:"skip|once"`
 	}
 	R(&ScheduledTaskCreateOptions{}, "scheduledtask-create", "Create Scheduled Task", func(s *mcclient.ClientSession, args *ScheduledTaskCreateOptions) error {
 		formatStr := "2006-01-02 15:04:05"
@@ -116,6 +125,13 @@ func init() {
 				return fmt.Errorf("invalid time format for 'end_time'")
 			}
 		}
+		var operationParams jsonutils.JSONObject
+		if len(args.OperationParams) > 0 {
+			operationParams, err = jsonutils.ParseString(args.OperationParams)
+			if err != nil {
+				return fmt.Errorf("invalid operation params: %v", err)
+			}
+		}
 		stCreateInput := apis.ScheduledTaskCreateInput{
 			ScheduledType: args.ScheduledType,
 			Timer: apis.TimerCreateInput{
@@ -130,10 +146,19 @@ func init() {
 				StartTime: starttime,
 				EndTime:   endtime,
 			},
-			ResourceType: args.ResourceType,
-			Operation:    args.Operation,
-			LabelType:    args.LabelType,
-			Labels:       args.Labels,
+			CronTimer: apis.CronTimerCreateInput{
+				Expression: args.CronExpression,
+				TimeZone:   args.CronTimeZone,
+				StartTime:  starttime,
+				EndTime:    endtime,
+			},
+			ResourceType:    args.ResourceType,
+			Operation:       args.Operation,
+			OperationParams: operationParams,
+			LabelType:       args.LabelType,
+			Labels:          args.Labels,
+			MaxConcurrency:  args.MaxConcurrency,
+			CatchUp:         args.CatchUp,
 		}
 		stCreateInput.Name = args.NAME
 		ret, err := modules.ScheduledTask.Create(s, jsonutils.Marshal(stCreateInput))
@@ -144,6 +169,34 @@ func init() {
 		return nil
 	})
 
+	type ScheduledTaskUpdateOptions struct {
+		ID              string `help:"ScheduledTask ID or Name" json:"-"`
+		Name            string `help:"ScheduledTask Name"`
+		Description     string `help:"Description"`
+		OperationParams string `help:"operation params in json" json:"-"`
+		MaxConcurrency  *int   `help:"max number of resources operated at the same time in one run"`
+		CatchUp         string `help:"how to handle runs missed" choices:"skip|once"`
+	}
+	R(&ScheduledTaskUpdateOptions{}, "scheduledtask-update", "Update Scheduled Task", func(s *mcclient.ClientSession, args *ScheduledTaskUpdateOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		if len(args.OperationParams) > 0 {
+			operationParams, err := jsonutils.ParseString(args.OperationParams)
+			if err != nil {
+				return fmt.Errorf("invalid operation params: %v", err)
+			}
+			params.Set("operation_params", operationParams)
+		}
+		ret, err := modules.ScheduledTask.Update(s, args.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
 	type ScheduledTaskEnableOptions struct {
 		ID string `help:"ScheduledTask ID or Name"`
 	}
@@ -117,5 +117,9 @@ func ExportList(list *modulebase.ListResult, file string, exportKeys string, exp
 		keys = columns
 		texts = columns
 	}
+	if strings.HasSuffix(strings.ToLower(file), ".csv") {
+		excelutils.ExportCsvFile(list.Data, keys, texts, file)
+		return
+	}
 	excelutils.ExportFile(list.Data, keys, texts, file)
 }
@@ -47,6 +47,7 @@ func main() {
 	var haproxyHelper *lbagent.HaproxyHelper
 	var apiHelper *lbagent.ApiHelper
 	var haStateWatcher *lbagent.HaStateWatcher
+	var acmeHelper *lbagent.AcmeHelper
 	var err error
 	{
 		haStateWatcher, err = lbagent.NewHaStateWatcher(opts)
@@ -60,12 +61,19 @@ func main() {
 			log.Fatalf("init haproxy helper failed: %s", err)
 		}
 	}
+	{
+		acmeHelper, err = lbagent.NewAcmeHelper(opts)
+		if err != nil {
+			log.Fatalf("init acme helper failed: %s", err)
+		}
+	}
 	{
 		apiHelper, err = lbagent.NewApiHelper(opts)
 		if err != nil {
 			log.Fatalf("init api helper failed: %s", err)
 		}
 		apiHelper.SetHaStateProvider(haStateWatcher)
+		apiHelper.SetAcmeHelper(acmeHelper)
 	}
 
 	{
@@ -74,10 +82,11 @@ func main() {
 		ctx, cancelFunc := context.WithCancel(context.Background())
 		ctx = context.WithValue(ctx, "wg", wg)
 		ctx = context.WithValue(ctx, "cmdChan", cmdChan)
-		wg.Add(3)
+		wg.Add(4)
 		go haStateWatcher.Run(ctx)
 		go haproxyHelper.Run(ctx)
 		go apiHelper.Run(ctx)
+		go acmeHelper.Run(ctx)
 
 		go func() {
 			sigChan := make(chan os.Signal)
@@ -197,6 +197,17 @@ type sExport struct {
 	Texts          []string
 }
 
+// write exports data as a CSV file if the export format is csv, otherwise xlsx
+func (export sExport) write(w http.ResponseWriter, fileName string, data []jsonutils.JSONObject) {
+	if export.ExportFormat == "csv" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.csv\"", fileName))
+		excelutils.ExportCsv(data, export.Keys, export.Texts, w)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.xlsx\"", fileName))
+	excelutils.Export(data, export.Keys, export.Texts, w)
+}
+
 func (f *ResourceHandlers) fetchExportQuery(query jsonutils.JSONObject) (jsonutils.JSONObject, sExport, error) {
 	export := sExport{}
 	query.Unmarshal(&export)
@@ -258,8 +269,7 @@ func (f *ResourceHandlers) doList(ctx context.Context, session *mcclient.ClientS
 		if len(export.ExportFileName) > 0 {
 			fileName = export.ExportFileName
 		}
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.xlsx\"", fileName))
-		excelutils.Export(ret.Data, export.Keys, export.Texts, w)
+		export.write(w, fileName, ret.Data)
 		return
 	}
 	appsrv.SendJSON(w, modulebase.ListResult2JSON(ret))
@@ -331,8 +341,7 @@ func (f *ResourceHandlers) getSpecHandler(ctx context.Context, w http.ResponseWr
 		if len(export.ExportFileName) > 0 {
 			fileName = export.ExportFileName
 		}
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.xlsx\"", fileName))
-		excelutils.Export(ret.Data, export.Keys, export.Texts, w)
+		export.write(w, fileName, ret.Data)
 		return
 	}
 	appsrv.SendJSON(w, modulebase.ListResult2JSON(ret))
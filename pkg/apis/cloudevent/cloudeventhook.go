@@ -0,0 +1,194 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudevent
+
+import (
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/gotypes"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	CLOUD_EVENT_HOOK_STATUS_AVAILABLE = "available"
+
+	CLOUD_EVENT_HOOK_ACTION_NOTIFY        = "notify"
+	CLOUD_EVENT_HOOK_ACTION_WEBHOOK       = "webhook"
+	CLOUD_EVENT_HOOK_ACTION_ANSIBLE       = "ansible"
+	CLOUD_EVENT_HOOK_ACTION_SCHEDULEDTASK = "scheduledtask"
+
+	CLOUD_EVENT_HOOK_RESULT_ANY     = "any"
+	CLOUD_EVENT_HOOK_RESULT_SUCCESS = "success"
+	CLOUD_EVENT_HOOK_RESULT_FAILED  = "failed"
+
+	CLOUD_EVENT_HOOK_TRIGGER_SUCCESS = "success"
+	CLOUD_EVENT_HOOK_TRIGGER_FAILED  = "failed"
+)
+
+var (
+	CLOUD_EVENT_HOOK_ACTIONS = []string{
+		CLOUD_EVENT_HOOK_ACTION_NOTIFY,
+		CLOUD_EVENT_HOOK_ACTION_WEBHOOK,
+		CLOUD_EVENT_HOOK_ACTION_ANSIBLE,
+		CLOUD_EVENT_HOOK_ACTION_SCHEDULEDTASK,
+	}
+	CLOUD_EVENT_HOOK_RESULTS = []string{
+		CLOUD_EVENT_HOOK_RESULT_ANY,
+		CLOUD_EVENT_HOOK_RESULT_SUCCESS,
+		CLOUD_EVENT_HOOK_RESULT_FAILED,
+	}
+)
+
+// 云平台操作日志订阅规则，各条件之间为与关系，同一条件的多个值之间为或关系
+type CloudeventHookFilter struct {
+	// 平台，为空时匹配所有平台
+	// example: Aliyun
+	Providers []string `json:"providers"`
+
+	// 资源类别，为空时匹配所有资源类别
+	// example: secgroupcache
+	ResourceTypes []string `json:"resource_types"`
+
+	// 操作类型，支持通配符*，为空时匹配所有操作
+	// example: AuthorizeSecurityGroup*
+	Actions []string `json:"actions"`
+
+	// 操作账号，支持通配符*，为空时匹配所有账号
+	Accounts []string `json:"accounts"`
+
+	// 执行结果
+	// enum: any, success, failed
+	// default: any
+	EventResult string `json:"event_result"`
+
+	// 请求内容需包含的关键字
+	// example: 0.0.0.0/0
+	RequestKeyword string `json:"request_keyword"`
+}
+
+func (filter *CloudeventHookFilter) Validate() error {
+	if filter.EventResult == "" {
+		filter.EventResult = CLOUD_EVENT_HOOK_RESULT_ANY
+	}
+	if !utils.IsInStringArray(filter.EventResult, CLOUD_EVENT_HOOK_RESULTS) {
+		return errors.Wrapf(httperrors.ErrInputParameter, "invalid event_result %q, want %v", filter.EventResult, CLOUD_EVENT_HOOK_RESULTS)
+	}
+	return nil
+}
+
+func (filter CloudeventHookFilter) String() string {
+	return jsonutils.Marshal(filter).String()
+}
+
+func (filter CloudeventHookFilter) IsZero() bool {
+	return len(filter.Providers) == 0 &&
+		len(filter.ResourceTypes) == 0 &&
+		len(filter.Actions) == 0 &&
+		len(filter.Accounts) == 0 &&
+		filter.EventResult == "" &&
+		filter.RequestKeyword == ""
+}
+
+type CloudeventHookCreateInput struct {
+	apis.EnabledStatusDomainLevelResourceCreateInput
+
+	// 订阅规则
+	Filter *CloudeventHookFilter `json:"filter"`
+
+	// 触发动作
+	// enum: notify, webhook, ansible, scheduledtask
+	//
+	// | hook_action   | hook_action_params                                  |
+	// |---------------|-----------------------------------------------------|
+	// | notify        | receivers, robots, contact_type, topic, priority    |
+	// | webhook       | url, headers                                        |
+	// | ansible       | playbook_reference, host, args                      |
+	// | scheduledtask | scheduled_task                                      |
+	//
+	// 触发时云平台操作日志会作为消息内容、请求体、ansible变量cloudevent传递
+	HookAction string `json:"hook_action"`
+
+	// 触发动作参数
+	HookActionParams jsonutils.JSONObject `json:"hook_action_params"`
+}
+
+type CloudeventHookNotifyParams struct {
+	// 接收人
+	Receivers []string `json:"receivers"`
+	// 机器人
+	Robots []string `json:"robots"`
+	// 通知渠道
+	// example: email
+	ContactType string `json:"contact_type"`
+	// 消息标题，默认为订阅名称
+	Topic string `json:"topic"`
+	// 消息级别
+	// enum: normal, important, fatal
+	Priority string `json:"priority"`
+}
+
+type CloudeventHookWebhookParams struct {
+	// 接收POST请求的地址
+	Url string `json:"url"`
+	// 附加请求头
+	Headers map[string]string `json:"headers"`
+}
+
+type CloudeventHookAnsibleParams struct {
+	// ansible playbook reference的Id
+	PlaybookReference string `json:"playbook_reference"`
+	// 执行playbook的主机
+	Host jsonutils.JSONObject `json:"host"`
+	// playbook参数
+	Args jsonutils.JSONObject `json:"args"`
+}
+
+type CloudeventHookScheduledtaskParams struct {
+	// 定时任务Id或名称
+	ScheduledTask string `json:"scheduled_task"`
+}
+
+type CloudeventHookUpdateInput struct {
+	apis.EnabledStatusDomainLevelResourceBaseUpdateInput
+
+	// 订阅规则
+	Filter *CloudeventHookFilter `json:"filter"`
+
+	// 触发动作参数
+	HookActionParams jsonutils.JSONObject `json:"hook_action_params"`
+}
+
+type CloudeventHookListInput struct {
+	apis.EnabledStatusDomainLevelResourceListInput
+
+	// 触发动作
+	HookAction []string `json:"hook_action"`
+}
+
+type CloudeventHookDetails struct {
+	apis.EnabledStatusDomainLevelResourceDetails
+	SCloudeventHook
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&CloudeventHookFilter{}), func() gotypes.ISerializable {
+		return &CloudeventHookFilter{}
+	})
+}
@@ -41,6 +41,18 @@ type SCloudevent struct {
 	Brand           string               `json:"brand"`
 }
 
+// SCloudeventHook is an autogenerated struct via yunion.io/x/onecloud/pkg/cloudevent/models.SCloudeventHook.
+type SCloudeventHook struct {
+	apis.SEnabledStatusDomainLevelResourceBase
+	Filter            *CloudeventHookFilter `json:"filter"`
+	HookAction        string                `json:"hook_action"`
+	HookActionParams  jsonutils.JSONObject  `json:"hook_action_params"`
+	TriggerCount      int                   `json:"trigger_count"`
+	LastTriggerAt     time.Time             `json:"last_trigger_at"`
+	LastTriggerStatus string                `json:"last_trigger_status"`
+	LastTriggerReason string                `json:"last_trigger_reason"`
+}
+
 // SCloudprovider is an autogenerated struct via yunion.io/x/onecloud/pkg/cloudevent/models.SCloudprovider.
 type SCloudprovider struct {
 	apis.SEnabledStatusStandaloneResourceBase
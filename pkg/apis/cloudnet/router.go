@@ -0,0 +1,58 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudnet
+
+const (
+	PEER_HEALTH_HEALTHY = "healthy"
+	PEER_HEALTH_STALE   = "stale"
+	PEER_HEALTH_NEVER   = "never"
+	PEER_HEALTH_UNKNOWN = "unknown"
+
+	// wireguard rekeys every 2 minutes when there is traffic
+	PEER_HANDSHAKE_HEALTHY_SECONDS = 180
+)
+
+type RouterPeerStatus struct {
+	// wireguard接口名
+	Ifname string `json:"ifname"`
+	// 所属mesh网络
+	MeshNetwork string `json:"mesh_network"`
+
+	// 对端路由器
+	PeerRouterId string `json:"peer_router_id"`
+	PeerRouter   string `json:"peer_router"`
+
+	PublicKey  string `json:"public_key"`
+	Endpoint   string `json:"endpoint"`
+	AllowedIPs string `json:"allowed_ips"`
+
+	// 最近一次握手时间，从未握手时为空
+	LatestHandshake string `json:"latest_handshake"`
+	// 接收字节数
+	TransferRx int64 `json:"transfer_rx"`
+	// 发送字节数
+	TransferTx int64 `json:"transfer_tx"`
+
+	// 对端健康状态
+	// enum: healthy, stale, never, unknown
+	Health string `json:"health"`
+}
+
+type RouterPeerStatusOutput struct {
+	Peers []RouterPeerStatus `json:"peers"`
+
+	// 按接口名分组的路由
+	Routes map[string][]string `json:"routes"`
+}
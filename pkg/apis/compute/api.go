@@ -55,9 +55,8 @@ type NetworkConfig struct {
 	// required: false
 	Address string `json:"address"`
 
-	// 子网内的IPv6地址
+	// 子网内的IPv6地址, 子网启用IPv6时, 若不指定会按子网的IPv6地址配置方式分配一个地址
 	// required: false
-	// swagger:ignore
 	Address6 string `json:"address6"`
 
 	// 驱动方式
@@ -366,6 +365,15 @@ type ServerCreateInput struct {
 	// required: false
 	UserData string `json:"user_data"`
 
+	// 元数据服务是否要求会话令牌
+	// enum: optional, required
+	// required: false
+	MetadataHttpTokens string `json:"metadata_http_tokens"`
+
+	// 是否通过config drive光盘提供元数据, 仅KVM支持
+	// required: false
+	ConfigDrive bool `json:"config_drive"`
+
 	// swagger:ignore
 	// Deprecated
 	Keypair string `json:"keypair" yunion-deprecated-by:"keypair_id"`
@@ -39,6 +39,8 @@ const (
 	CLOUD_PROVIDER_ONECLOUD    = "OneCloud"
 	CLOUD_PROVIDER_VMWARE      = "VMware"
 	CLOUD_PROVIDER_NUTANIX     = "Nutanix"
+	CLOUD_PROVIDER_PROXMOX     = "Proxmox"
+	CLOUD_PROVIDER_OVIRT       = "oVirt"
 	CLOUD_PROVIDER_ALIYUN      = "Aliyun"
 	CLOUD_PROVIDER_APSARA      = "Apsara"
 	CLOUD_PROVIDER_QCLOUD      = "Qcloud"
@@ -122,6 +124,8 @@ var (
 		CLOUD_PROVIDER_CLOUDPODS,
 		CLOUD_PROVIDER_NUTANIX,
 		CLOUD_PROVIDER_BINGO_CLOUD,
+		CLOUD_PROVIDER_PROXMOX,
+		CLOUD_PROVIDER_OVIRT,
 	}
 
 	CLOUD_PROVIDER_HOST_TYPE_MAP = map[string][]string{
@@ -184,6 +188,12 @@ var (
 		CLOUD_PROVIDER_BINGO_CLOUD: {
 			HOST_TYPE_BINGO_CLOUD,
 		},
+		CLOUD_PROVIDER_PROXMOX: {
+			HOST_TYPE_PROXMOX,
+		},
+		CLOUD_PROVIDER_OVIRT: {
+			HOST_TYPE_OVIRT,
+		},
 	}
 )
 
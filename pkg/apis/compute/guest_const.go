@@ -181,6 +181,8 @@ const (
 	HYPERVISOR_CLOUDPODS   = "cloudpods"
 	HYPERVISOR_NUTANIX     = "nutanix"
 	HYPERVISOR_BINGO_CLOUD = "bingocloud"
+	HYPERVISOR_PROXMOX     = "proxmox"
+	HYPERVISOR_OVIRT       = "ovirt"
 
 	//	HYPERVISOR_DEFAULT = HYPERVISOR_KVM
 	HYPERVISOR_DEFAULT = HYPERVISOR_KVM
@@ -230,6 +232,8 @@ var HYPERVISORS = []string{
 	HYPERVISOR_CLOUDPODS,
 	HYPERVISOR_NUTANIX,
 	HYPERVISOR_BINGO_CLOUD,
+	HYPERVISOR_PROXMOX,
+	HYPERVISOR_OVIRT,
 }
 
 var ONECLOUD_HYPERVISORS = []string{
@@ -259,6 +263,8 @@ var PRIVATE_CLOUD_HYPERVISORS = []string{
 	HYPERVISOR_HCSO,
 	HYPERVISOR_NUTANIX,
 	HYPERVISOR_BINGO_CLOUD,
+	HYPERVISOR_PROXMOX,
+	HYPERVISOR_OVIRT,
 }
 
 // var HYPERVISORS = []string{HYPERVISOR_ALIYUN}
@@ -285,6 +291,8 @@ var HYPERVISOR_HOSTTYPE = map[string]string{
 	HYPERVISOR_CLOUDPODS:   HOST_TYPE_CLOUDPODS,
 	HYPERVISOR_NUTANIX:     HOST_TYPE_NUTANIX,
 	HYPERVISOR_BINGO_CLOUD: HOST_TYPE_BINGO_CLOUD,
+	HYPERVISOR_PROXMOX:     HOST_TYPE_PROXMOX,
+	HYPERVISOR_OVIRT:       HOST_TYPE_OVIRT,
 }
 
 var HOSTTYPE_HYPERVISOR = map[string]string{
@@ -309,6 +317,8 @@ var HOSTTYPE_HYPERVISOR = map[string]string{
 	HOST_TYPE_CLOUDPODS:   HYPERVISOR_CLOUDPODS,
 	HOST_TYPE_NUTANIX:     HYPERVISOR_NUTANIX,
 	HOST_TYPE_BINGO_CLOUD: HYPERVISOR_BINGO_CLOUD,
+	HOST_TYPE_PROXMOX:     HYPERVISOR_PROXMOX,
+	HOST_TYPE_OVIRT:       HYPERVISOR_OVIRT,
 }
 
 const (
@@ -330,8 +340,20 @@ const (
 	VM_METADATA_OS_DISTRO           = "os_distribution"
 	VM_METADATA_OS_NAME             = "os_name"
 	VM_METADATA_OS_VERSION          = "os_version"
+
+	// session token requirement of the guest metadata service
+	VM_METADATA_HTTP_TOKENS = "metadata_http_tokens"
+	// IP TTL of the metadata token response
+	VM_METADATA_HTTP_PUT_RESPONSE_HOP_LIMIT = "metadata_http_put_response_hop_limit"
+	// deliver metadata through config drive ISO
+	VM_METADATA_CONFIG_DRIVE = "config_drive"
+
+	METADATA_HTTP_TOKENS_OPTIONAL = "optional"
+	METADATA_HTTP_TOKENS_REQUIRED = "required"
 )
 
+var METADATA_HTTP_TOKENS = []string{METADATA_HTTP_TOKENS_OPTIONAL, METADATA_HTTP_TOKENS_REQUIRED}
+
 func Hypervisors2HostTypes(hypervisors []string) []string {
 	hostTypes := make([]string, len(hypervisors))
 	for i := range hypervisors {
@@ -82,6 +82,11 @@ type GuestnetworkJsonDesc struct {
 	Mac        string               `json:"mac"`
 	Virtual    bool                 `json:"virtual"`
 	Ip         string               `json:"ip"`
+	Ip6        string               `json:"ip6,omitempty"`
+	Masklen6   int8                 `json:"masklen6,omitempty"`
+	Gateway6   string               `json:"gateway6,omitempty"`
+	Dns6       string               `json:"dns6,omitempty"`
+	Ip6Mode    string               `json:"ip6_mode,omitempty"`
 	Gateway    string               `json:"gateway"`
 	Dns        string               `json:"dns"`
 	Domain     string               `json:"domain"`
@@ -608,6 +608,16 @@ type ServerUserDataInput struct {
 	UserData string `json:"user_data"`
 }
 
+type ServerSetMetadataOptionsInput struct {
+	// 元数据服务是否要求会话令牌
+	// enum: optional, required
+	HttpTokens string `json:"http_tokens"`
+	// 令牌响应报文的IP TTL, 范围1-64
+	HttpPutResponseHopLimit int `json:"http_put_response_hop_limit"`
+	// 是否通过config drive光盘提供元数据
+	ConfigDrive *bool `json:"config_drive"`
+}
+
 type ServerAttachDiskInput struct {
 	DiskId string `json:"disk_id"`
 }
@@ -702,6 +712,8 @@ type GuestJsonDesc struct {
 	Secgroups          []*SecgroupJsonDesc `json:"secgroups"`
 	SecurityRules      string              `json:"security_rules"`
 	AdminSecurityRules string              `json:"admin_security_rules"`
+	// 安全组规则引用的对端安全组中虚拟机的地址, 以对端安全组 ID 为键
+	SecurityIpsets map[string][]string `json:"security_ipsets"`
 
 	ExtraOptions jsonutils.JSONObject `json:"extra_options"`
 
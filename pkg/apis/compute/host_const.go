@@ -40,6 +40,8 @@ const (
 	HOST_TYPE_CLOUDPODS   = "cloudpods"
 	HOST_TYPE_NUTANIX     = "nutanix"
 	HOST_TYPE_BINGO_CLOUD = "bingocloud"
+	HOST_TYPE_PROXMOX     = "proxmox"
+	HOST_TYPE_OVIRT       = "ovirt"
 
 	HOST_TYPE_DEFAULT = HOST_TYPE_HYPERVISOR
 
@@ -124,6 +126,8 @@ var HOST_TYPES = []string{
 	HOST_TYPE_CLOUDPODS,
 	HOST_TYPE_NUTANIX,
 	HOST_TYPE_BINGO_CLOUD,
+	HOST_TYPE_PROXMOX,
+	HOST_TYPE_OVIRT,
 }
 
 var NIC_TYPES = []string{NIC_TYPE_IPMI, NIC_TYPE_ADMIN}
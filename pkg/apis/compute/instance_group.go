@@ -16,6 +16,34 @@ package compute
 
 import "yunion.io/x/onecloud/pkg/apis"
 
+const (
+	// 反亲和, 同一拓扑域内的实例数量不超过granularity
+	INSTANCE_GROUP_POLICY_ANTI_AFFINITY = "anti_affinity"
+	// 亲和, 实例集中部署在同一拓扑域
+	INSTANCE_GROUP_POLICY_AFFINITY = "affinity"
+	// 均匀分布, 各拓扑域实例数量的差值不超过max_skew
+	INSTANCE_GROUP_POLICY_SPREAD = "spread"
+
+	INSTANCE_GROUP_TOPOLOGY_HOST     = "host"
+	INSTANCE_GROUP_TOPOLOGY_RACK     = "rack"
+	INSTANCE_GROUP_TOPOLOGY_SCHEDTAG = "schedtag"
+	INSTANCE_GROUP_TOPOLOGY_ZONE     = "zone"
+)
+
+var (
+	INSTANCE_GROUP_POLICIES = []string{
+		INSTANCE_GROUP_POLICY_ANTI_AFFINITY,
+		INSTANCE_GROUP_POLICY_AFFINITY,
+		INSTANCE_GROUP_POLICY_SPREAD,
+	}
+	INSTANCE_GROUP_TOPOLOGY_KEYS = []string{
+		INSTANCE_GROUP_TOPOLOGY_HOST,
+		INSTANCE_GROUP_TOPOLOGY_RACK,
+		INSTANCE_GROUP_TOPOLOGY_SCHEDTAG,
+		INSTANCE_GROUP_TOPOLOGY_ZONE,
+	}
+)
+
 type InstanceGroupListInput struct {
 	apis.VirtualResourceListInput
 
@@ -32,6 +60,11 @@ type InstanceGroupListInput struct {
 
 	// 调度策略
 	SchedStrategy string `json:"sched_strategy"`
+
+	// 以亲和策略过滤列表结果
+	Policy []string `json:"policy"`
+	// 以拓扑层级过滤列表结果
+	TopologyKey []string `json:"topology_key"`
 }
 
 type InstanceGroupDetail struct {
@@ -113,6 +113,9 @@ type LoadbalancerCertificateListInput struct {
 
 	CommonName              []string `json:"common_name"`
 	SubjectAlternativeNames []string `json:"subject_alternative_names"`
+
+	// 证书类型 upload|acme
+	CertificateType []string `json:"certificate_type"`
 }
 
 type LoadbalancerBackendListInput struct {
@@ -209,6 +209,35 @@ var LB_TLS_CERT_PUBKEY_ALGOS = choices.NewChoices(
 	LB_TLS_CERT_PUBKEY_ALGO_ECDSA,
 )
 
+const (
+	LB_CERT_TYPE_UPLOAD = "upload"
+	LB_CERT_TYPE_ACME   = "acme"
+)
+
+var LB_CERT_TYPES = choices.NewChoices(
+	LB_CERT_TYPE_UPLOAD,
+	LB_CERT_TYPE_ACME,
+)
+
+const (
+	LB_ACME_CHALLENGE_HTTP01 = "http-01"
+	LB_ACME_CHALLENGE_DNS01  = "dns-01"
+
+	LB_ACME_DIRECTORY_URL_DEFAULT = "https://acme-v02.api.letsencrypt.org/directory"
+
+	// acme certificates are renewed by lbagent this many days before expiry
+	LB_ACME_RENEW_BEFORE_DAYS = 30
+
+	// acme certificate waiting to be issued or renewed by lbagent
+	LB_CERT_STATUS_ACME_PENDING = "acme_pending"
+	LB_CERT_STATUS_ACME_FAILED  = "acme_failed"
+)
+
+var LB_ACME_CHALLENGES = choices.NewChoices(
+	LB_ACME_CHALLENGE_HTTP01,
+	LB_ACME_CHALLENGE_DNS01,
+)
+
 // TODO may want extra for legacy apps
 const (
 	LB_TLS_CIPHER_POLICY_1_0        = "tls_cipher_policy_1_0"
@@ -430,6 +459,12 @@ const (
 	LBAGENT_QUERY_ORIG_VAL = "lbagent"
 )
 
+const (
+	// measurements of listener and backend stats pushed by lbagent
+	LB_STATS_MEASUREMENT_LISTENER = "lb_listener"
+	LB_STATS_MEASUREMENT_BACKEND  = "lb_backend"
+)
+
 const (
 	LB_ASSOCIATE_TYPE_LISTENER = "listener"
 	LB_ASSOCIATE_TYPE_RULE     = "rule"
@@ -45,3 +45,16 @@ type LoadbalancerCertificateFilterListInput struct {
 	// 以证书名称排序
 	OrderByCertificate string `json:"order_by_certificate"`
 }
+
+type LoadbalancerCertificateAcmeCertificateInput struct {
+	// 签发的证书链，PEM格式
+	Certificate string `json:"certificate"`
+	// 证书私钥，PEM格式
+	PrivateKey string `json:"private_key"`
+
+	// 签发失败原因，不为空时表示签发失败
+	Error string `json:"error"`
+}
+
+type LoadbalancerCertificateAcmeRenewInput struct {
+}
@@ -23,6 +23,21 @@ const (
 	NETWORK_TYPE_CLASSIC = "classic"
 )
 
+const (
+	// IPv6地址由平台分配, 部署时静态配置到虚拟机中
+	NETWORK_IP6_MODE_STATIC = "static"
+	// IPv6地址由网卡MAC地址通过EUI-64生成, 虚拟机通过路由通告自动配置
+	NETWORK_IP6_MODE_SLAAC = "slaac"
+	// IPv6地址由平台分配, 虚拟机通过DHCPv6获取
+	NETWORK_IP6_MODE_DHCPV6 = "dhcpv6"
+)
+
+var NETWORK_IP6_MODES = []string{
+	NETWORK_IP6_MODE_STATIC,
+	NETWORK_IP6_MODE_SLAAC,
+	NETWORK_IP6_MODE_DHCPV6,
+}
+
 type WireResourceInput struct {
 	// 二层网络(ID或Name)的资源
 	WireId string `json:"wire_id"`
@@ -188,6 +203,37 @@ type NetworkCreateInput struct {
 	// example: cn.pool.ntp.org,0.cn.pool.ntp.org
 	GuestNtp string `json:"guest_ntp"`
 
+	// description: ipv6 range of guest, if not set, you shoud set guest_ip6_start,guest_ip6_end and guest_ip6_mask params
+	// example: 2001:db8:1:2::/64
+	GuestIp6Prefix string `json:"guest_ip6_prefix"`
+
+	// description: ipv6 range of guest ip start, if set guest_ip6_prefix, this parameter will be useless
+	// example: 2001:db8:1:2::100
+	GuestIp6Start string `json:"guest_ip6_start"`
+
+	// description: ipv6 range of guest ip end, if set guest_ip6_prefix, this parameter will be useless
+	// example: 2001:db8:1:2::ffff
+	GuestIp6End string `json:"guest_ip6_end"`
+
+	// description: ipv6 range of guest ip mask, if set guest_ip6_prefix, this parameter will be useless
+	// example: 64
+	// maximum: 126
+	// minimum: 48
+	GuestIp6Mask int8 `json:"guest_ip6_mask"`
+
+	// description: guest ipv6 gateway
+	// example: 2001:db8:1:2::1
+	GuestGateway6 string `json:"guest_gateway6"`
+
+	// description: guest ipv6 dns
+	// example: 2001:4860:4860::8888
+	GuestDns6 string `json:"guest_dns6"`
+
+	// description: ipv6 address mode
+	// enum: static,slaac,dhcpv6
+	// default: static
+	Ip6Mode string `json:"ip6_mode"`
+
 	// swagger:ignore
 	WireId string `json:"wire_id"`
 
@@ -363,6 +409,19 @@ type NetworkUpdateInput struct {
 
 	GuestDomain string `json:"guest_domain"`
 
+	// 起始IPv6地址
+	GuestIp6Start string `json:"guest_ip6_start"`
+	// 结束IPv6地址
+	GuestIp6End string `json:"guest_ip6_end"`
+	// IPv6掩码
+	GuestIp6Mask *int8 `json:"guest_ip6_mask"`
+	// IPv6网关地址
+	GuestGateway6 string `json:"guest_gateway6"`
+	// IPv6 DNS
+	GuestDns6 string `json:"guest_dns6"`
+	// IPv6地址配置方式
+	Ip6Mode string `json:"ip6_mode"`
+
 	VlanId *int `json:"vlan_id"`
 
 	// 分配策略
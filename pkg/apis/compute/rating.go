@@ -0,0 +1,243 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	PRICE_PLAN_STATUS_AVAILABLE = "available"
+
+	RATING_ITEM_CPU       = "cpu"
+	RATING_ITEM_MEM       = "mem"
+	RATING_ITEM_DISK      = "disk"
+	RATING_ITEM_GPU       = "gpu"
+	RATING_ITEM_EIP       = "eip"
+	RATING_ITEM_BANDWIDTH = "bandwidth"
+
+	RATING_RESOURCE_SERVER = "server"
+	RATING_RESOURCE_DISK   = "disk"
+	RATING_RESOURCE_EIP    = "eip"
+
+	RATING_BILL_DATE_FORMAT = "2006-01-02"
+
+	RATING_BILL_GROUP_BY_PROJECT = "project"
+	RATING_BILL_GROUP_BY_DOMAIN  = "domain"
+	RATING_BILL_GROUP_BY_ITEM    = "item"
+	RATING_BILL_GROUP_BY_DATE    = "bill_date"
+
+	BUDGET_SCOPE_PROJECT = "project"
+	BUDGET_SCOPE_DOMAIN  = "domain"
+
+	BUDGET_PERIOD_DAY   = "day"
+	BUDGET_PERIOD_MONTH = "month"
+
+	BUDGET_STATUS_NORMAL   = "normal"
+	BUDGET_STATUS_EXCEEDED = "exceeded"
+
+	DEFAULT_RATING_CURRENCY = "CNY"
+)
+
+var (
+	// 计费的私有云虚拟化平台
+	RATING_HYPERVISORS = []string{HYPERVISOR_KVM, HYPERVISOR_ESXI}
+
+	RATING_BILL_GROUP_BYS = []string{
+		RATING_BILL_GROUP_BY_PROJECT,
+		RATING_BILL_GROUP_BY_DOMAIN,
+		RATING_BILL_GROUP_BY_ITEM,
+		RATING_BILL_GROUP_BY_DATE,
+	}
+)
+
+// 各存储类型每GB每小时的价格, key为存储类型
+type StorageTypePrices map[string]float64
+
+func (self StorageTypePrices) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self StorageTypePrices) IsZero() bool {
+	return len(self) == 0
+}
+
+type PricePlanCreateInput struct {
+	apis.EnabledStatusInfrasResourceBaseCreateInput
+
+	// 货币单位
+	// default: CNY
+	Currency string `json:"currency"`
+
+	// 适用的虚拟化平台, 为空表示适用于所有平台
+	// enum: kvm, esxi
+	Hypervisor string `json:"hypervisor"`
+
+	// 每vCPU每小时的价格
+	CpuPrice float64 `json:"cpu_price"`
+	// 每GB内存每小时的价格
+	MemPrice float64 `json:"mem_price"`
+	// 每GB磁盘每小时的默认价格
+	DiskPrice float64 `json:"disk_price"`
+	// 各存储类型每GB磁盘每小时的价格, 未指定的存储类型使用disk_price
+	// example: {"local": 0.0001, "rbd": 0.0002}
+	StorageTypePrices StorageTypePrices `json:"storage_type_prices"`
+	// 每块GPU每小时的价格
+	GpuPrice float64 `json:"gpu_price"`
+	// 每个EIP每小时的价格
+	EipPrice float64 `json:"eip_price"`
+	// 每Mbps带宽每小时的价格
+	BandwidthPrice float64 `json:"bandwidth_price"`
+}
+
+type PricePlanUpdateInput struct {
+	apis.EnabledStatusInfrasResourceBaseUpdateInput
+
+	CpuPrice          *float64          `json:"cpu_price"`
+	MemPrice          *float64          `json:"mem_price"`
+	DiskPrice         *float64          `json:"disk_price"`
+	StorageTypePrices StorageTypePrices `json:"storage_type_prices"`
+	GpuPrice          *float64          `json:"gpu_price"`
+	EipPrice          *float64          `json:"eip_price"`
+	BandwidthPrice    *float64          `json:"bandwidth_price"`
+}
+
+type PricePlanListInput struct {
+	apis.EnabledStatusInfrasResourceBaseListInput
+
+	// 以虚拟化平台过滤
+	Hypervisor []string `json:"hypervisor"`
+	// 以货币单位过滤
+	Currency []string `json:"currency"`
+}
+
+type PricePlanDetails struct {
+	apis.EnabledStatusInfrasResourceBaseDetails
+
+	SPricePlan
+}
+
+type RatingBillListInput struct {
+	apis.StandaloneAnonResourceListInput
+	apis.ProjectizedResourceListInput
+
+	// 账单日期起始, 格式为2006-01-02, 包含当天
+	StartDate string `json:"start_date"`
+	// 账单日期截止, 格式为2006-01-02, 包含当天
+	EndDate string `json:"end_date"`
+
+	// 以资源类型过滤
+	// enum: server, disk, eip
+	ResourceType []string `json:"resource_type"`
+	// 以资源ID过滤
+	ResourceId []string `json:"resource_id"`
+	// 以计费项过滤
+	// enum: cpu, mem, disk, gpu, eip, bandwidth
+	Item []string `json:"item"`
+	// 以价格方案过滤
+	PricePlanId []string `json:"price_plan_id"`
+}
+
+type RatingBillDetails struct {
+	apis.StandaloneAnonResourceDetails
+	apis.ProjectizedResourceInfo
+
+	SRatingBill
+
+	// 价格方案名称
+	PricePlan string `json:"price_plan"`
+}
+
+type RatingBillSummaryInput struct {
+	RatingBillListInput
+
+	// 汇总维度
+	// enum: project, domain, item, bill_date
+	// default: project
+	GroupBy string `json:"group_by"`
+}
+
+type RatingBillSummary struct {
+	// 汇总维度的值, 如项目ID, 域ID, 计费项或账单日期
+	Key string `json:"key"`
+	// 项目或域的名称
+	Name string `json:"name"`
+
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+type RatingBillSummaryOutput struct {
+	Data []RatingBillSummary `json:"data"`
+}
+
+type RatingBillRegenerateInput struct {
+	// 重新计算的账单日期, 格式为2006-01-02, 默认为前一天
+	BillDate string `json:"bill_date"`
+}
+
+type BudgetCreateInput struct {
+	apis.VirtualResourceCreateInput
+	apis.EnabledBaseResourceCreateInput
+
+	// 预算范围, project表示所属项目, domain表示所属域
+	// enum: project, domain
+	// default: project
+	BudgetScope string `json:"budget_scope"`
+	// 预算周期
+	// enum: day, month
+	// default: month
+	Period string `json:"period"`
+	// 预算金额
+	Amount float64 `json:"amount"`
+	// 货币单位
+	// default: CNY
+	Currency string `json:"currency"`
+	// 费用达到预算金额的百分比时告警
+	// default: 100
+	AlertPercent int `json:"alert_percent"`
+}
+
+type BudgetUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	Amount       *float64 `json:"amount"`
+	AlertPercent *int     `json:"alert_percent"`
+}
+
+type BudgetListInput struct {
+	apis.VirtualResourceListInput
+	apis.EnabledResourceBaseListInput
+
+	BudgetScope []string `json:"budget_scope"`
+	Period      []string `json:"period"`
+}
+
+type BudgetDetails struct {
+	apis.VirtualResourceDetails
+
+	SBudget
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&StorageTypePrices{}), func() gotypes.ISerializable {
+		return &StorageTypePrices{}
+	})
+}
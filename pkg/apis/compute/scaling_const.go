@@ -29,6 +29,8 @@ const (
 	TRIGGER_TIMING = "timing" // 定时
 	TRIGGER_CYCLE  = "cycle"  // 周期定时
 
+	TRIGGER_TARGET_TRACKING = "target_tracking" // 目标追踪
+
 	ACTION_ADD    = "add"    // 增加
 	ACTION_REMOVE = "remove" // 减少
 	ACTION_SET    = "set"    // 设置
@@ -50,6 +52,10 @@ const (
 	OPERATOR_GT = "gt" // 大于
 	OPERATOR_LT = "lt" // 小于
 
+	PREDICTIVE_MODE_OFF    = "off"    // 不预测
+	PREDICTIVE_MODE_DAILY  = "daily"  // 按天的规律预测
+	PREDICTIVE_MODE_WEEKLY = "weekly" // 按周的规律预测
+
 	TIMER_TYPE_ONCE  = "once"
 	TIMER_TYPE_DAY   = "day"
 	TIMER_TYPE_WEEK  = "week"
@@ -61,6 +67,8 @@ const (
 	SG_GUEST_STATUS_REMOVING       = "removing"       // 移除中
 	SG_GUEST_STATUS_REMOVE_FAILED  = "remove_failed"  // 移除失败
 	SG_GUEST_STATUS_PENDING_REMOVE = "pending_remove" // 机器进入回收站
+	SG_GUEST_STATUS_LAUNCH_WAIT    = "launch_wait"    // 等待加入的生命周期挂钩
+	SG_GUEST_STATUS_TERMINATE_WAIT = "terminate_wait" // 等待移出的生命周期挂钩
 
 	// 只有ready状态是正常的
 	SG_STATUS_READY              = "ready"              // 正常
@@ -77,6 +85,20 @@ const (
 	SP_STATUS_DELETING      = "deleting"      // 删除中
 	SP_STATUS_DELETE_FAILED = "delete_failed" // 删除失败
 
+	LIFECYCLE_HOOK_LAUNCHING   = "launching"   // 实例加入
+	LIFECYCLE_HOOK_TERMINATING = "terminating" // 实例移出
+
+	LIFECYCLE_ACTION_WEBHOOK = "webhook"
+	LIFECYCLE_ACTION_ANSIBLE = "ansible"
+
+	LIFECYCLE_RESULT_CONTINUE = "continue" // 继续
+	LIFECYCLE_RESULT_ABANDON  = "abandon"  // 放弃
+
+	INSTANCE_REFRESH_STATUS_IN_PROGRESS = "in_progress" // 替换中
+	INSTANCE_REFRESH_STATUS_SUCCEED     = "succeed"     // 成功
+	INSTANCE_REFRESH_STATUS_FAILED      = "failed"      // 失败
+	INSTANCE_REFRESH_STATUS_CANCELLED   = "cancelled"   // 取消
+
 	SA_STATUS_WAIT         = "wait"         // 等待中
 	SA_STATUS_EXEC         = "execution"    // 执行中
 	SA_STATUS_SUCCEED      = "succeed"      // 成功
@@ -0,0 +1,137 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+type ScalingLifecycleHookDetails struct {
+	apis.VirtualResourceDetails
+	ScalingGroupResourceInfo
+	SScalingLifecycleHook
+}
+
+type ScalingLifecycleHookCreateInput struct {
+	apis.VirtualResourceCreateInput
+	apis.EnabledBaseResourceCreateInput
+
+	// description: scaling_group ID or Name
+	// example: sg-test-one
+	ScalingGroup string `json:"scaling_group"`
+
+	// swagger: ignore
+	ScalingGroupId string `json:"scaling_group_id"`
+
+	// description: 生命周期挂钩的类型，实例加入(launching)或移出(terminating)伸缩组时暂停
+	// enum: launching,terminating
+	// example: launching
+	HookType string `json:"hook_type"`
+
+	// description: 暂停时执行的动作
+	// enum: webhook,ansible
+	// example: webhook
+	ActionType string `json:"action_type"`
+
+	// description: webhook的地址，调用方通过 complete-lifecycle-action 回调结果
+	// example: http://192.168.1.2:8080/hooks
+	WebhookUrl string `json:"webhook_url"`
+
+	// description: ansible playbook reference ID or Name，执行结果作为挂钩的结果
+	AnsiblePlaybookReference string `json:"ansible_playbook_reference"`
+
+	// description: 登录实例执行ansible的用户
+	// example: root
+	AnsibleUser string `json:"ansible_user"`
+
+	// description: 等待的超时时间，单位s
+	// example: 300
+	Timeout int `json:"timeout"`
+
+	// description: 超时或执行失败时的默认结果，continue继续加入或移出实例，abandon放弃加入(删除实例)或跳过剩余挂钩
+	// enum: continue,abandon
+	// example: continue
+	DefaultResult string `json:"default_result"`
+}
+
+type ScalingLifecycleHookUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	WebhookUrl string `json:"webhook_url"`
+
+	AnsibleUser string `json:"ansible_user"`
+
+	Timeout *int `json:"timeout"`
+
+	// enum: continue,abandon
+	DefaultResult string `json:"default_result"`
+}
+
+type ScalingLifecycleHookListInput struct {
+	apis.VirtualResourceListInput
+	apis.EnabledResourceBaseListInput
+
+	ScalingGroupFilterListInput
+
+	// description: 生命周期挂钩的类型
+	// enum: launching,terminating
+	HookType string `json:"hook_type"`
+}
+
+type ScalingGroupCompleteLifecycleActionInput struct {
+	// description: 暂停中的实例 Id or Name
+	Guest string `json:"guest"`
+
+	// description: 生命周期挂钩 Id or Name，不指定则为实例当前所处的挂钩
+	LifecycleHook string `json:"lifecycle_hook"`
+
+	// description: 结果
+	// enum: continue,abandon
+	Result string `json:"result"`
+}
+
+type ScalingGroupInstanceRefreshInput struct {
+	// description: 替换过程中健康实例数占期望实例数的最小百分比, 不指定时为 90, 0 表示允许同时替换所有实例
+	// example: 90
+	MinHealthyPercentage *int `json:"min_healthy_percentage"`
+
+	// description: 每批替换完成的超时时间，单位s
+	// example: 1800
+	BatchTimeout int `json:"batch_timeout"`
+}
+
+type ScalingInstanceRefreshDetails struct {
+	apis.StatusStandaloneResourceDetails
+	ScalingGroupResourceInfo
+	SScalingInstanceRefresh
+}
+
+type ScalingInstanceRefreshListInput struct {
+	apis.StatusStandaloneResourceListInput
+	ScalingGroupFilterListInput
+}
+
+type ScalingLifecycleHookPayload struct {
+	LifecycleHookId   string    `json:"lifecycle_hook_id"`
+	LifecycleHookName string    `json:"lifecycle_hook_name"`
+	HookType          string    `json:"hook_type"`
+	ScalingGroupId    string    `json:"scaling_group_id"`
+	ScalingGroupName  string    `json:"scaling_group_name"`
+	GuestId           string    `json:"guest_id"`
+	GuestName         string    `json:"guest_name"`
+	Deadline          time.Time `json:"deadline"`
+}
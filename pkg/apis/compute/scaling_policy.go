@@ -26,6 +26,8 @@ type ScalingPolicyDetails struct {
 	CycleTimer CycleTimerDetails `json:"cycle_timer"`
 	//  告警方式触发
 	Alarm ScalingAlarmDetails `json:"alarm"`
+	// 目标追踪方式触发
+	TargetTracking ScalingTargetTrackingDetails `json:"target_tracking"`
 }
 
 type ScalingPolicyCreateInput struct {
@@ -40,14 +42,15 @@ type ScalingPolicyCreateInput struct {
 	ScalingGroupId string `json:"scaling_group_id"`
 
 	// description: trigger type
-	// enum: timing,cycle,alarm
+	// enum: timing,cycle,alarm,target_tracking
 	TriggerType string `json:"trigger_type"`
 
-	Timer      TimerCreateInput        `json:"timer"`
-	CycleTimer CycleTimerCreateInput   `json:"cycle_timer"`
-	Alarm      ScalingAlarmCreateInput `json:"alarm"`
+	Timer          TimerCreateInput                 `json:"timer"`
+	CycleTimer     CycleTimerCreateInput            `json:"cycle_timer"`
+	Alarm          ScalingAlarmCreateInput          `json:"alarm"`
+	TargetTracking ScalingTargetTrackingCreateInput `json:"target_tracking"`
 
-	// desciption: 伸缩策略的行为(增加还是删除或者调整为)
+	// desciption: 伸缩策略的行为(增加还是删除或者调整为)，目标追踪方式触发时忽略
 	// enum: add,remove,set
 	// example: add
 	Action string `json:"action"`
@@ -61,7 +64,8 @@ type ScalingPolicyCreateInput struct {
 	// example: s
 	Unit string `json:"unit"`
 
-	// desciption: Scaling activity triggered by alarms will be rejected during this period about CoolingTime
+	// desciption: Scaling activity triggered by alarms will be rejected during this period about CoolingTime,
+	// for target tracking it's the cooldown before scaling in after last scaling activity
 	// example: 300
 	CoolingTime int `json:"cooling_time"`
 }
@@ -75,7 +79,7 @@ type ScalingPolicyListInput struct {
 	ScalingGroupFilterListInput
 
 	// description: trigger type
-	// enum: timing,cycel,alarm
+	// enum: timing,cycel,alarm,target_tracking
 	// example: alarm
 	TriggerType string `json:"trigger_type"`
 }
@@ -81,6 +81,38 @@ type ScalingAlarmCreateInput struct {
 	Value float64 `json:"value"`
 }
 
+type ScalingTargetTrackingCreateInput struct {
+
+	// description: 追踪的监控指标
+	// example: cpu
+	// enum: cpu,mem,disk_read,disk_write,flow_into,flow_out
+	Indicator string `json:"indicator"`
+
+	// description: 监控指标的目标值(实例的平均值)，比如CPU利用率保持在55%
+	// example: 55
+	TargetValue float64 `json:"target_value"`
+
+	// description: 新实例的预热时间，单位s；预热中的实例不参与指标计算，且此期间不缩容
+	// example: 300
+	WarmUp int `json:"warm_up"`
+
+	// description: 是否禁止缩容
+	DisableScaleIn bool `json:"disable_scale_in"`
+
+	// description: 预测方式，根据过去几周的每日/每周规律提前扩容
+	// enum: off,daily,weekly
+	// example: weekly
+	PredictiveMode string `json:"predictive_mode"`
+
+	// description: 预测时参考的历史周数(1-4)
+	// example: 2
+	PredictiveLookbackWeeks int `json:"predictive_lookback_weeks"`
+
+	// description: 提前扩容的时间，单位s
+	// example: 600
+	PredictiveAhead int `json:"predictive_ahead"`
+}
+
 type TimerDetails struct {
 	// description: 执行时间
 	ExecTime time.Time `json:"exec_time"`
@@ -117,3 +149,30 @@ type ScalingAlarmDetails struct {
 	// description: 阈值
 	Value float64 `json:"value"`
 }
+
+type ScalingTargetTrackingDetails struct {
+	// description: 指标
+	Indicator string `json:"indicator"`
+	// description: 指标的目标值
+	TargetValue float64 `json:"target_value"`
+	// description: 新实例的预热时间
+	WarmUp int `json:"warm_up"`
+	// description: 是否禁止缩容
+	DisableScaleIn bool `json:"disable_scale_in"`
+	// description: 预测方式
+	PredictiveMode string `json:"predictive_mode"`
+	// description: 预测时参考的历史周数
+	PredictiveLookbackWeeks int `json:"predictive_lookback_weeks"`
+	// description: 提前扩容的时间
+	PredictiveAhead int `json:"predictive_ahead"`
+	// description: 最近一次计算的时间
+	LastEvaluateTime time.Time `json:"last_evaluate_time"`
+	// description: 最近一次计算得到的指标值
+	LastMetricValue float64 `json:"last_metric_value"`
+	// description: 最近一次计算得到的期望实例数
+	LastDesire int `json:"last_desire"`
+	// description: 最近一次计算的依据
+	LastReason string `json:"last_reason"`
+	// description: 最近一次预测的实例数
+	ForecastDesire int `json:"forecast_desire"`
+}
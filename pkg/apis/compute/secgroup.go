@@ -16,6 +16,7 @@ package compute
 
 import (
 	"fmt"
+	"net"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
@@ -98,6 +99,10 @@ type SSecgroupRuleCreateInput struct {
 	// 安全组ID
 	// required: true
 	SecgroupId string `json:"secgroup_id"`
+
+	// 已接受的本地IDC vpc对等连接Id, 指定时cidr须属于对端vpc的网段, cidr为空时使用对端vpc的网段
+	// required: false
+	VpcPeeringConnectionId string `json:"vpc_peering_connection_id"`
 }
 
 type SSecgroupRuleUpdateInput struct {
@@ -129,7 +134,8 @@ func (input *SSecgroupRuleResource) Check() error {
 	}
 
 	if len(input.CIDR) > 0 {
-		if !regutils.MatchCIDR(input.CIDR) && !regutils.MatchIPAddr(input.CIDR) {
+		// both ipv4 and ipv6 cidr are allowed
+		if _, _, err := net.ParseCIDR(input.CIDR); err != nil && !regutils.MatchIPAddr(input.CIDR) {
 			return fmt.Errorf("invalid ip address: %s", input.CIDR)
 		}
 	} else {
@@ -14,10 +14,29 @@
 
 package compute
 
+import (
+	"crypto/md5"
+	"fmt"
+)
+
 const (
 	SECGROUP_STATUS_READY      = "ready"
 	SECGROUP_STATUS_DELETING   = "deleting"   // 删除中
 	SECGROUP_STATUS_SYNC_RULES = "sync_rules" // 同步规则中
 
 	SECGROUP_DEFAULT_ID = "default"
+
+	// 对端安全组规则中地址的前缀, 后接对端安全组 ID, 地址列表见 security_ipsets
+	SECGROUP_PEER_IPSET_PREFIX = "ipset:"
 )
+
+// SecgroupPeerIpsetName returns name of the ipset kept on hosts for addresses
+// of guests in the peer security group.  Ipset names are limited to 31
+// characters, so the name is derived from the digest of the secgroup id
+func SecgroupPeerIpsetName(secgroupId string, ipv6 bool) string {
+	family := 4
+	if ipv6 {
+		family = 6
+	}
+	return fmt.Sprintf("sg%d-%x", family, md5.Sum([]byte(secgroupId)))[:28]
+}
@@ -55,6 +55,29 @@ type SSnapshotPolicyCreateInput struct {
 	RetentionDays  int   `json:"retention_days"`
 	RepeatWeekdays []int `json:"repeat_weekdays"`
 	TimePoints     []int `json:"time_points"`
+
+	// cron表达式, 例如 */30 * * * *, 两次快照间隔不能小于15分钟
+	// 指定后忽略 repeat_weekdays 和 time_points
+	CronExpression string `json:"cron_expression"`
+
+	// 保留最近的自动快照个数
+	RetentionCount int `json:"retention_count"`
+	// 按天保留快照的天数, 每天保留最新的一个快照
+	RetentionDaily int `json:"retention_daily"`
+	// 按周保留快照的周数, 每周保留最新的一个快照
+	RetentionWeekly int `json:"retention_weekly"`
+	// 按月保留快照的月数, 每月保留最新的一个快照
+	RetentionMonthly int `json:"retention_monthly"`
+
+	// 仅对指定类型的磁盘生效, 为空则对所有磁盘生效
+	// enum: sys, data
+	DiskType string `json:"disk_type"`
+
+	// 一致性快照组, 对虚拟机所有磁盘同时做快照, 支持的情况下会先冻结虚拟机文件系统
+	ConsistencyGroup bool `json:"consistency_group"`
+
+	// 快照完成后复制到指定的备份存储
+	BackupStorageId string `json:"backup_storage_id"`
 }
 
 type SSnapshotPolicyCreateInternalInput struct {
@@ -67,6 +90,17 @@ type SSnapshotPolicyCreateInternalInput struct {
 	RetentionDays  int
 	RepeatWeekdays uint8
 	TimePoints     uint32
+
+	CronExpression string
+
+	RetentionCount   int
+	RetentionDaily   int
+	RetentionWeekly  int
+	RetentionMonthly int
+
+	DiskType         string
+	ConsistencyGroup bool
+	BackupStorageId  string
 }
 
 type SnapshotListInput struct {
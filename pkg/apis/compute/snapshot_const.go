@@ -52,6 +52,9 @@ const (
 	INSTANCE_SNAPSHOT_DELETE_FAILED = "instance_snapshot_delete_failed"
 	INSTANCE_SNAPSHOT_RESET         = "instance_snapshot_reset"
 
+	// metadata key of instance snapshot recording the snapshot policy created it
+	INSTANCE_SNAPSHOT_METADATA_SNAPSHOTPOLICY_ID = "snapshotpolicy_id"
+
 	SNAPSHOT_POLICY_CACHE_STATUS_READY         = "ready"
 	SNAPSHOT_POLICY_CACHE_STATUS_DELETING      = "deleting"
 	SNAPSHOT_POLICY_CACHE_STATUS_DELETE_FAILED = "delete_failed"
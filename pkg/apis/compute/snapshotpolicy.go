@@ -27,6 +27,9 @@ type SnapshotPolicyDetails struct {
 	IsActivated           *bool `json:"is_activated,omitempty"`
 
 	BindingDiskCount int `json:"binding_disk_count"`
+
+	// 备份存储名称
+	BackupStorage string `json:"backup_storage"`
 }
 
 type SnapshotPolicyResourceInfo struct {
@@ -37,10 +37,20 @@ type StorageCreateInput struct {
 	// | rbd 			| rbd_client_mount_timeout	| 否 		|	120		|单位: 秒	|
 	// | nfs 			| nfs_host					| 是 		|			|网络文件系统主机	|
 	// | nfs 			| nfs_shared_dir			| 是 		|			|网络文件系统共享目录	|
+	// | cifs 			| cifs_host					| 是 		|			|CIFS/SMB服务器地址	|
+	// | cifs 			| cifs_shared_dir			| 是 		|			|CIFS/SMB共享名称	|
+	// | cifs 			| cifs_username				| 否 		|			|CIFS/SMB用户名	|
+	// | cifs 			| cifs_password				| 否 		|			|CIFS/SMB密码	|
+	// | cifs 			| cifs_domain				| 否 		|			|CIFS/SMB域	|
+	// | cifs 			| cifs_mount_options		| 否 		|			|额外的挂载参数	|
+	// | slvm 			| slvm_vg_name				| 是 		|			|共享卷组名称	|
 	// local: 本地存储
 	// rbd: ceph块存储, ceph存储创建时仅会检测是否重复创建，不会具体检测认证参数是否合法，只有挂载存储时
 	// 计算节点会验证参数，若挂载失败，宿主机和存储不会关联，可以通过查看存储日志查找挂载失败原因
-	// enum: local, rbd, nfs, gpfs
+	// slvm: 基于iSCSI/FC SAN共享LUN的LVM存储, 卷组需预先以lvmlockd(sanlock/dlm)方式创建, 磁盘为卷组中的厚置备逻辑卷,
+	// 快照为磁盘的完整拷贝, 运行中的虚拟机经临时写时复制快照在线拷贝, lvmlockd不允许对共享激活的卷创建快照, 热迁移期间不能创建快照
+	// cifs: CIFS/SMB共享文件存储, 由计算节点使用存储配置中的用户名密码挂载
+	// enum: local, rbd, nfs, gpfs, cifs, slvm
 	// required: true
 	StorageType string `json:"storage_type"`
 
@@ -90,6 +100,34 @@ type StorageCreateInput struct {
 	// 网络文件系统共享目录, storage_type 为 nfs 时, 此参数必传
 	// example: /nfs_root/
 	NfsSharedDir string `json:"nfs_shared_dir"`
+
+	// CIFS/SMB服务器地址, storage_type 为 cifs 时,此参数必传
+	// example: 192.168.222.5
+	CifsHost string `json:"cifs_host"`
+
+	// CIFS/SMB共享名称, 可包含子目录, storage_type 为 cifs 时, 此参数必传
+	// example: share/vms
+	CifsSharedDir string `json:"cifs_shared_dir"`
+
+	// CIFS/SMB用户名, 为空时以guest方式挂载
+	// example: administrator
+	CifsUsername string `json:"cifs_username"`
+
+	// CIFS/SMB密码, 加密后保存
+	CifsPassword string `json:"cifs_password"`
+
+	// CIFS/SMB域
+	// example: WORKGROUP
+	CifsDomain string `json:"cifs_domain"`
+
+	// 额外的挂载参数, 以逗号分隔
+	// example: vers=3.0,cache=none
+	CifsMountOptions string `json:"cifs_mount_options"`
+
+	// 共享卷组名称, storage_type 为 slvm 时, 此参数必传
+	// 卷组需建立在所有宿主机均可访问的共享LUN上, 并开启lvmlockd
+	// example: vg_san01
+	SLVMVgName string `json:"slvm_vg_name"`
 }
 
 type RbdTimeoutInput struct {
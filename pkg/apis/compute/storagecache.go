@@ -79,6 +79,9 @@ type CacheImageInput struct {
 	StoragecacheId string `json:"storagecache_id"`
 	// swagger: ignore
 	Checksum string `json:"checksum"`
+	// swagger: ignore
+	// image signature policy of the disk owner, enforced by host on cached image
+	SignaturePolicy string `json:"signature_policy"`
 }
 
 type StoragecacheResourceInput struct {
@@ -24,6 +24,7 @@ const (
 	VPC_PEERING_CONNECTION_STATUS_ACTIVE         = "active"
 	VPC_PEERING_CONNECTION_STATUS_DELETING       = "deleting"
 	VPC_PEERING_CONNECTION_STATUS_UNKNOWN        = "unknown"
+	VPC_PEERING_CONNECTION_STATUS_REJECTED       = "rejected"
 )
 
 type VpcPeeringConnectionDetails struct {
@@ -52,3 +53,15 @@ type VpcPeeringConnectionListInput struct {
 type VpcPeeringConnectionUpdateInput struct {
 	apis.EnabledStatusInfrasResourceBaseUpdateInput
 }
+
+type VpcPeeringConnectionAcceptInput struct {
+	// 待接受的对等连接, 其对端vpc须为当前vpc
+	// 仅适用于本地IDC vpc之间的对等连接
+	VpcPeeringConnectionId string `json:"vpc_peering_connection_id"`
+}
+
+type VpcPeeringConnectionRejectInput struct {
+	// 拒绝或撤销的对等连接, 其对端vpc须为当前vpc
+	// 仅适用于本地IDC vpc之间的对等连接
+	VpcPeeringConnectionId string `json:"vpc_peering_connection_id"`
+}
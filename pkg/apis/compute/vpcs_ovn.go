@@ -70,6 +70,28 @@ func VpcInterExtIP2() netutils.IPV4Addr {
 	return vpcInterExtIP2
 }
 
+const (
+	// one /30 for each onecloud vpc peering connection, vpc side takes
+	// the 1st address, peer vpc side the 2nd
+	sVpcPeeringCidr = "100.65.128.0/17"
+	VpcPeeringMask  = 30
+	VpcPeeringSlots = 1 << (VpcPeeringMask - 17)
+)
+
+var (
+	vpcPeeringCidr netutils.IPV4Prefix
+)
+
+func VpcPeeringCidr() netutils.IPV4Prefix {
+	return vpcPeeringCidr
+}
+
+// VpcPeeringIPs returns router port addresses of the peering link at slot
+func VpcPeeringIPs(slot int) (netutils.IPV4Addr, netutils.IPV4Addr) {
+	base := vpcPeeringCidr.Address + netutils.IPV4Addr(slot<<(32-VpcPeeringMask))
+	return base + 1, base + 2
+}
+
 const (
 	sVpcMappedCidr      = "100.64.0.0/17"
 	VpcMappedIPMask     = 17
@@ -134,6 +156,8 @@ func init() {
 	vpcInterExtIP1 = mi(netutils.NewIPV4Addr(sVpcInterExtIP1))
 	vpcInterExtIP2 = mi(netutils.NewIPV4Addr(sVpcInterExtIP2))
 
+	vpcPeeringCidr = mp(netutils.NewIPV4Prefix(sVpcPeeringCidr))
+
 	vpcMappedCidr = mp(netutils.NewIPV4Prefix(sVpcMappedCidr))
 	vpcMappedGatewayIP = mi(netutils.NewIPV4Addr(sVpcMappedGatewayIP))
 
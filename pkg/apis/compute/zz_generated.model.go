@@ -182,6 +182,26 @@ type SBucket struct {
 	AccessUrls     jsonutils.JSONObject `json:"access_urls"`
 }
 
+// SBudget is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SBudget.
+type SBudget struct {
+	apis.SVirtualResourceBase
+	apis.SEnabledResourceBase
+	// 预算范围
+	BudgetScope string `json:"budget_scope"`
+	// 预算周期
+	Period string `json:"period"`
+	// 预算金额
+	Amount float64 `json:"amount"`
+	// 货币单位
+	Currency string `json:"currency"`
+	// 告警百分比
+	AlertPercent int `json:"alert_percent"`
+	// 当前周期已使用金额
+	UsedAmount float64 `json:"used_amount"`
+	// 最近一次告警的周期
+	AlertedPeriod string `json:"alerted_period"`
+}
+
 // SCDNDomain is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SCDNDomain.
 type SCDNDomain struct {
 	apis.SEnabledStatusInfrasResourceBase
@@ -1051,8 +1071,19 @@ type SGroup struct {
 	// 调度策略
 	SchedStrategy string `json:"sched_strategy"`
 	// the upper limit number of guests with this group in a host
-	Granularity     int   `json:"granularity"`
+	Granularity int `json:"granularity"`
+	// 是否为硬约束, 为false时仅作为调度的优选条件
 	ForceDispersion *bool `json:"force_dispersion,omitempty"`
+	// 亲和策略
+	Policy string `json:"policy"`
+	// 拓扑层级
+	TopologyKey string `json:"topology_key"`
+	// topology_key为schedtag时, 以此为前缀的调度标签划分拓扑域
+	TopologySchedtagPrefix string `json:"topology_schedtag_prefix"`
+	// spread策略下各拓扑域实例数量允许的最大差值
+	MaxSkew int `json:"max_skew"`
+	// 软约束的权重
+	Weight int `json:"weight"`
 }
 
 // SGroupJointsBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGroupJointsBase.
@@ -1744,6 +1775,18 @@ type SLoadbalancerCertificate struct {
 	apis.SSharableVirtualResourceBase
 	apis.SExternalizedResourceBase
 	apis.SCertificateResourceBase
+	// 证书类型 upload|acme
+	CertificateType string `json:"certificate_type"`
+	// ACME证书域名，逗号分隔
+	AcmeDomains string `json:"acme_domains"`
+	// ACME验证方式 http-01|dns-01
+	AcmeChallenge string `json:"acme_challenge"`
+	// ACME服务地址
+	AcmeDirectoryUrl string `json:"acme_directory_url"`
+	// ACME账号联系邮箱
+	AcmeEmail string `json:"acme_email"`
+	// ACME账号私钥
+	AcmeAccountKey string `json:"acme_account_key"`
 }
 
 // SLoadbalancerCertificateResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerCertificateResourceBase.
@@ -2190,6 +2233,29 @@ type SPolicyDefinitionResourceBase struct {
 	PolicydefinitionId string `json:"policydefinition_id"`
 }
 
+// SPricePlan is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SPricePlan.
+type SPricePlan struct {
+	apis.SEnabledStatusInfrasResourceBase
+	// 货币单位
+	Currency string `json:"currency"`
+	// 适用的虚拟化平台, 为空表示所有平台
+	Hypervisor string `json:"hypervisor"`
+	// 每vCPU每小时的价格
+	CpuPrice float64 `json:"cpu_price"`
+	// 每GB内存每小时的价格
+	MemPrice float64 `json:"mem_price"`
+	// 每GB磁盘每小时的默认价格
+	DiskPrice float64 `json:"disk_price"`
+	// 各存储类型每GB磁盘每小时的价格
+	StorageTypePrices *StorageTypePrices `json:"storage_type_prices"`
+	// 每块GPU每小时的价格
+	GpuPrice float64 `json:"gpu_price"`
+	// 每个EIP每小时的价格
+	EipPrice float64 `json:"eip_price"`
+	// 每Mbps带宽每小时的价格
+	BandwidthPrice float64 `json:"bandwidth_price"`
+}
+
 // SProjectMapping is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SProjectMapping.
 type SProjectMapping struct {
 	apis.SEnabledStatusInfrasResourceBase
@@ -2227,6 +2293,39 @@ type SQcloudCachedLbbg struct {
 	AssociatedType string `json:"associated_type"`
 }
 
+// SRatingBill is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SRatingBill.
+type SRatingBill struct {
+	apis.SStandaloneAnonResourceBase
+	apis.SProjectizedResourceBase
+	// 账单日期
+	// example: 2006-01-02
+	BillDate string `json:"bill_date"`
+	// 资源类型
+	ResourceType string `json:"resource_type"`
+	// 资源ID
+	ResourceId string `json:"resource_id"`
+	// 资源名称
+	ResourceName string `json:"resource_name"`
+	// 价格方案ID
+	PricePlanId string `json:"price_plan_id"`
+	// 计费项
+	Item string `json:"item"`
+	// 计费规格, 例如磁盘的存储类型
+	Spec string `json:"spec"`
+	// 计费数量
+	Quantity float64 `json:"quantity"`
+	// 计量单位
+	Unit string `json:"unit"`
+	// 计费时长(小时)
+	Hours float64 `json:"hours"`
+	// 单价
+	UnitPrice float64 `json:"unit_price"`
+	// 金额
+	Amount float64 `json:"amount"`
+	// 货币单位
+	Currency string `json:"currency"`
+}
+
 // SReservedip is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SReservedip.
 type SReservedip struct {
 	apis.SResourceBase
@@ -2347,6 +2446,10 @@ type SScalingGroupGuest struct {
 	ScalingGroupId string `json:"scaling_group_id"`
 	GuestStatus    string `json:"guest_status"`
 	Manual         *bool  `json:"manual,omitempty"`
+	// LifecycleHookId is the lifecycle hook the guest is waiting on
+	LifecycleHookId   string    `json:"lifecycle_hook_id"`
+	LifecycleResult   string    `json:"lifecycle_result"`
+	LifecycleDeadline time.Time `json:"lifecycle_deadline"`
 }
 
 // SScalingGroupNetwork is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingGroupNetwork.
@@ -2362,6 +2465,42 @@ type SScalingGroupResourceBase struct {
 	ScalingGroupId string `json:"scaling_group_id"`
 }
 
+// SScalingInstanceRefresh is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingInstanceRefresh.
+type SScalingInstanceRefresh struct {
+	apis.SStatusStandaloneResourceBase
+	SScalingGroupResourceBase
+	// Healthy instances should be kept at least this percentage of the desire instance number
+	MinHealthyPercentage int `json:"min_healthy_percentage"`
+	BatchSize            int `json:"batch_size"`
+	// Timeout in seconds of each batch
+	BatchTimeout   int       `json:"batch_timeout"`
+	TotalNumber    int       `json:"total_number"`
+	ReplacedNumber int       `json:"replaced_number"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	// CurrentBatch is the comma separated ids of guests being replaced
+	CurrentBatch  string    `json:"current_batch"`
+	BatchDeadline time.Time `json:"batch_deadline"`
+	// ActivityId is the scaling activity recording the current batch
+	ActivityId string `json:"activity_id"`
+	Reason     string `json:"reason"`
+}
+
+// SScalingLifecycleHook is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingLifecycleHook.
+type SScalingLifecycleHook struct {
+	apis.SVirtualResourceBase
+	SScalingGroupResourceBase
+	apis.SEnabledResourceBase
+	HookType                 string `json:"hook_type"`
+	ActionType               string `json:"action_type"`
+	WebhookUrl               string `json:"webhook_url"`
+	AnsiblePlaybookReference string `json:"ansible_playbook_reference"`
+	AnsibleUser              string `json:"ansible_user"`
+	// Timeout in seconds to wait for the result
+	Timeout       int    `json:"timeout"`
+	DefaultResult string `json:"default_result"`
+}
+
 // SScalingPolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingPolicy.
 type SScalingPolicy struct {
 	apis.SVirtualResourceBase
@@ -2383,6 +2522,29 @@ type SScalingPolicyBase struct {
 	ScalingPolicyId string `json:"scaling_policy_id"`
 }
 
+// SScalingTargetTracking is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingTargetTracking.
+type SScalingTargetTracking struct {
+	apis.SStandaloneResourceBase
+	SScalingPolicyBase
+	Indicator   string  `json:"indicator"`
+	TargetValue float64 `json:"target_value"`
+	// Metrics of instances joined within WarmUp seconds are ignored and no scaling in happens meanwhile
+	WarmUp         int  `json:"warm_up"`
+	DisableScaleIn bool `json:"disable_scale_in"`
+	// PredictiveMode instruct to pre-scale by the daily or weekly pattern of last weeks
+	PredictiveMode          string `json:"predictive_mode"`
+	PredictiveLookbackWeeks int    `json:"predictive_lookback_weeks"`
+	PredictiveAhead         int    `json:"predictive_ahead"`
+	// Result of last evaluation
+	LastEvaluateTime time.Time `json:"last_evaluate_time"`
+	LastMetricValue  float64   `json:"last_metric_value"`
+	LastDesire       int       `json:"last_desire"`
+	LastReason       string    `json:"last_reason"`
+	// Desire instance number by the forecast and when it's computed
+	ForecastDesire int       `json:"forecast_desire"`
+	ForecastTime   time.Time `json:"forecast_time"`
+}
+
 // SScalingTimer is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingTimer.
 type SScalingTimer struct {
 	apis.SStandaloneResourceBase
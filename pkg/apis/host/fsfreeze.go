@@ -0,0 +1,29 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+type GuestFsFreezeRequest struct {
+	// filesystems are thawed automatically after timeout in case the caller
+	// never comes back to thaw them
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+type GuestFsFreezeResponse struct {
+	Count int `json:"count"`
+}
+
+type GuestFsThawResponse struct {
+	Count int `json:"count"`
+}
@@ -25,6 +25,7 @@ const (
 	TOTP_TYPE             = "totp"
 	RECOVERY_SECRETS_TYPE = "recovery_secret"
 	OIDC_CREDENTIAL_TYPE  = "oidc"
+	DNSSEC_KEY_TYPE       = "dnssec_key"
 )
 
 type SAccessKeySecretBlob struct {
@@ -43,3 +44,22 @@ type SAccessKeySecretInfo struct {
 	AccessKey string
 	SAccessKeySecretBlob
 }
+
+// SDnssecKeyBlob is the DNSSEC signing key of a zone kept in credential
+type SDnssecKeyBlob struct {
+	Zone string `json:"zone"`
+	// 256 for ZSK, 257 for KSK
+	Flags     uint16 `json:"flags"`
+	Algorithm uint8  `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+	// private key in BIND private-key format
+	PrivateKey string `json:"private_key"`
+
+	CreatedAt int64 `json:"created_at"`
+	// the key is published on creation and used for signing from then on,
+	// it is retired once a successor becomes active
+	ActivateAt int64 `json:"activate_at"`
+	// the slot of an automatically created key, of the keys created for
+	// the same slot by replicas concurrently only the earliest one is kept
+	Slot string `json:"slot,omitempty"`
+}
@@ -59,9 +59,42 @@ const (
 	IMAGE_INSTALLED_CLOUDINIT = "installed_cloud_init"
 	IMAGE_DISABLE_USB_KBD     = "disable_usb_kbd"
 
+	// detached signature properties
+	// base64 encoded signature over the sha256 digest of the image file, as generated by cosign sign-blob
+	IMAGE_SIGNATURE = "signature"
+	// base64 encoded PEM certificate (chain) or public key of the signer
+	IMAGE_SIGNATURE_CERT        = "signature_cert"
+	IMAGE_SIGNATURE_STATUS      = "signature_status"
+	IMAGE_SIGNATURE_SIGNER      = "signature_signer"
+	IMAGE_SIGNATURE_DIGEST      = "signature_digest"
+	IMAGE_SIGNATURE_DISK_FORMAT = "signature_disk_format"
+
+	IMAGE_SIGNATURE_STATUS_VERIFIED  = "verified"
+	IMAGE_SIGNATURE_STATUS_UNSIGNED  = "unsigned"
+	IMAGE_SIGNATURE_STATUS_UNTRUSTED = "untrusted"
+	IMAGE_SIGNATURE_STATUS_INVALID   = "invalid"
+	// the image carries a signature which failed to be checked
+	IMAGE_SIGNATURE_STATUS_UNVERIFIED = "unverified"
+
+	// project metadata key of image signature policy
+	IMAGE_SIGNATURE_POLICY_KEY = "image_signature_policy"
+
+	// boot any image
+	IMAGE_SIGNATURE_POLICY_PERMISSIVE = "permissive"
+	// refuse unsigned, unverified and invalid signed images
+	IMAGE_SIGNATURE_POLICY_REQUIRE_SIGNED = "require_signed"
+	// refuse images not signed by a trusted signer
+	IMAGE_SIGNATURE_POLICY_REQUIRE_TRUSTED = "require_trusted"
+
 	IMAGE_STATUS_UPDATING = "updating"
 )
 
 var (
+	IMAGE_SIGNATURE_POLICIES = []string{
+		IMAGE_SIGNATURE_POLICY_PERMISSIVE,
+		IMAGE_SIGNATURE_POLICY_REQUIRE_SIGNED,
+		IMAGE_SIGNATURE_POLICY_REQUIRE_TRUSTED,
+	}
+
 	ImageDeadStatus = []string{IMAGE_STATUS_DEACTIVATED, IMAGE_STATUS_KILLED, IMAGE_STATUS_DELETED, IMAGE_STATUS_PENDING_DELETE}
 )
@@ -70,6 +70,8 @@ type ImageDetails struct {
 	AutoDeleteAt time.Time `json:"auto_delete_at"`
 	// 删除保护
 	DisableDelete bool `json:"disable_delete"`
+	// 跨区域副本
+	Replicas []ImageReplicaInfo `json:"replicas"`
 	//OssChecksum   string    `json:"oss_checksum"`
 }
 
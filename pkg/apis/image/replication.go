@@ -0,0 +1,149 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"reflect"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/util/tagutils"
+)
+
+const (
+	IMAGE_REPLICATION_POLICY_STATUS_READY = "ready"
+
+	IMAGE_REPLICA_STATUS_PENDING     = "pending"
+	IMAGE_REPLICA_STATUS_REPLICATING = "replicating"
+	IMAGE_REPLICA_STATUS_READY       = "ready"
+	IMAGE_REPLICA_STATUS_FAILED      = "failed"
+
+	// properties recorded on the replicated image in target region
+	IMAGE_REPLICA_SOURCE_REGION   = "replica_source_region"
+	IMAGE_REPLICA_SOURCE_IMAGE_ID = "replica_source_image_id"
+	IMAGE_REPLICA_SOURCE_CHECKSUM = "replica_source_checksum"
+	// 以此为前缀的属性只能由镜像复制设置
+	IMAGE_REPLICA_PROPERTY_PREFIX = "replica_"
+
+	DEFAULT_IMAGE_REPLICATION_INTERVAL_MINUTES = 60
+)
+
+type TRegionList []string
+
+func (regions TRegionList) String() string {
+	return jsonutils.Marshal(regions).String()
+}
+
+func (regions TRegionList) IsZero() bool {
+	return len(regions) == 0
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&TRegionList{}), func() gotypes.ISerializable {
+		return &TRegionList{}
+	})
+}
+
+type ImageReplicationPolicyListInput struct {
+	apis.EnabledStatusStandaloneResourceListInput
+
+	// 源区域
+	SourceRegion string `json:"source_region"`
+	// 目标区域
+	TargetRegion string `json:"target_region"`
+}
+
+type ImageReplicationPolicyCreateInput struct {
+	apis.EnabledStatusStandaloneResourceCreateInput
+
+	// 源区域, 默认为当前区域
+	SourceRegion string `json:"source_region"`
+	// 目标区域列表
+	TargetRegions TRegionList `json:"target_regions"`
+	// 镜像标签选择器, 为空则复制所有镜像
+	Tags tagutils.TTagSet `json:"tags"`
+	// 复制周期, 单位分钟
+	IntervalMinutes int `json:"interval_minutes"`
+}
+
+type ImageReplicationPolicyUpdateInput struct {
+	apis.EnabledStatusStandaloneResourceBaseUpdateInput
+
+	// 目标区域列表
+	TargetRegions TRegionList `json:"target_regions"`
+	// 镜像标签选择器
+	Tags tagutils.TTagSet `json:"tags"`
+	// 复制周期, 单位分钟
+	IntervalMinutes int `json:"interval_minutes"`
+}
+
+type ImageReplicationPolicyDetails struct {
+	apis.EnabledStatusStandaloneResourceDetails
+
+	SImageReplicationPolicy
+
+	// 副本数量
+	ReplicaCount int `json:"replica_count"`
+	// 已就绪副本数量
+	ReadyReplicaCount int `json:"ready_replica_count"`
+}
+
+type ImageReplicationPolicySyncInput struct {
+}
+
+type ImageReplicaListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	// 源镜像
+	ImageId string `json:"image_id"`
+	// 复制策略
+	PolicyId string `json:"policy_id"`
+	// 目标区域
+	TargetRegion string `json:"target_region"`
+}
+
+type ImageReplicaDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	SImageReplica
+
+	// 源镜像名称
+	Image string `json:"image"`
+	// 复制策略名称
+	Policy string `json:"policy"`
+}
+
+// ImageReplicaInfo is the summary of a replica shown on the source image
+type ImageReplicaInfo struct {
+	TargetRegion  string    `json:"target_region"`
+	TargetImageId string    `json:"target_image_id"`
+	Status        string    `json:"status"`
+	Progress      float32   `json:"progress"`
+	LastSyncAt    time.Time `json:"last_sync_at"`
+}
+
+type ImageReplicateFromInput struct {
+	// 源区域
+	SourceRegion string `json:"source_region"`
+	// 源镜像ID
+	SourceImageId string `json:"source_image_id"`
+	// 源镜像校验和
+	Checksum string `json:"checksum"`
+	// 源镜像大小
+	Size int64 `json:"size"`
+}
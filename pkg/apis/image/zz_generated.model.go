@@ -15,7 +15,10 @@
 package image
 
 import (
+	"time"
+
 	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/util/tagutils"
 )
 
 // SGuestImage is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SGuestImage.
@@ -85,6 +88,38 @@ type SImageProperty struct {
 	Value string `json:"value"`
 }
 
+// SImageReplica is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageReplica.
+type SImageReplica struct {
+	apis.SStatusStandaloneResourceBase
+	// 源镜像Id
+	ImageId string `json:"image_id"`
+	// 复制策略Id
+	PolicyId string `json:"policy_id"`
+	// 目标区域
+	TargetRegion string `json:"target_region"`
+	// 目标区域镜像Id
+	TargetImageId string `json:"target_image_id"`
+	// 已复制的源镜像校验和
+	Checksum string `json:"checksum"`
+	// 上次同步完成时间
+	LastSyncAt time.Time `json:"last_sync_at"`
+}
+
+// SImageReplicationPolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageReplicationPolicy.
+type SImageReplicationPolicy struct {
+	apis.SEnabledStatusStandaloneResourceBase
+	// 源区域
+	SourceRegion string `json:"source_region"`
+	// 目标区域列表
+	TargetRegions *TRegionList `json:"target_regions"`
+	// 镜像标签选择器
+	Tags tagutils.TTagSet `json:"tags"`
+	// 复制周期, 单位分钟
+	IntervalMinutes int `json:"interval_minutes"`
+	// 上次执行时间
+	LastRunAt time.Time `json:"last_run_at"`
+}
+
 // SImageSubformat is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageSubformat.
 type SImageSubformat struct {
 	SImagePeripheral
@@ -31,6 +31,8 @@ var (
 	ActionChangeConfig   SAction = "change_config"
 	ActionResize         SAction = "resize"
 	ActionExpiredRelease SAction = "expired_release"
+	ActionExpire         SAction = "expire"
+	ActionBudgetExceed   SAction = "budget_exceed"
 	ActionExecute        SAction = "execute"
 	ActionChangeIpaddr   SAction = "change_ipaddr"
 	ActionSyncStatus     SAction = "sync_status"
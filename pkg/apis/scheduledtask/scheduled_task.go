@@ -17,6 +17,8 @@ package scheduledtask
 import (
 	"time"
 
+	"yunion.io/x/jsonutils"
+
 	"yunion.io/x/onecloud/pkg/apis"
 )
 
@@ -30,6 +32,10 @@ type ScheduledTaskDetails struct {
 	Timer TimerDetails `json:"timer"`
 	// 周期方式触发
 	CycleTimer CycleTimerDetails `json:"cycle_timer"`
+	// Cron表达式方式触发
+	CronTimer CronTimerDetails `json:"cron_timer"`
+	// 下次执行时间
+	NextTime time.Time `json:"next_time"`
 	// 绑定的所有标示
 	Labels       []string      `json:"labels,allowempty"`
 	LabelDetails []LabelDetail `json:"label_details,allowempty"`
@@ -57,6 +63,17 @@ type CycleTimerDetails struct {
 	EndTime time.Time `json:"end_time"`
 }
 
+type CronTimerDetails struct {
+	// description: Cron表达式
+	Expression string `json:"expression"`
+	// description: 时区
+	TimeZone string `json:"time_zone"`
+	// description: 此周期任务的开始时间
+	StartTime time.Time `json:"start_time"`
+	// description: 此周期任务的截止时间
+	EndTime time.Time `json:"end_time"`
+}
+
 type LabelDetail struct {
 	Label        string    `json:"label"`
 	IsolatedTime time.Time `json:"isolated_time"`
@@ -68,7 +85,7 @@ type ScheduledTaskListInput struct {
 
 	// description: resource type
 	// example: server
-	// enum: server
+	// enum: server,cloudaccount,disk,snapshot,scalinggroup,dbinstance
 	ResourceType string `json:"resource_type"`
 
 	// description: label type
@@ -81,7 +98,7 @@ type ScheduledTaskListInput struct {
 
 	// description: operation
 	// example: stop
-	// enum: start,stop,restart
+	// enum: start,stop,restart,sync,enable,disable,delete,snapshot,change-config,resize,run-playbook
 	Operation string `json:"operation"`
 }
 
@@ -90,20 +107,33 @@ type ScheduledTaskCreateInput struct {
 	apis.EnabledBaseResourceCreateInput
 
 	// description: scheduled type
-	// enum: cycle,timing
+	// enum: cycle,timing,cron
 	// example: timing
 	ScheduledType string                `json:"scheduled_type"`
 	Timer         TimerCreateInput      `json:"timer"`
 	CycleTimer    CycleTimerCreateInput `json:"cycle_timer"`
+	CronTimer     CronTimerCreateInput  `json:"cron_timer"`
 
 	// description: resource type
-	// enum: server
+	// enum: server,cloudaccount,disk,snapshot,scalinggroup,dbinstance
 	// example: server
 	ResourceType string `json:"resource_type"`
 	// description: operation
-	// enum: start,stop,restart
+	// enum: start,stop,restart,sync,enable,disable,delete,snapshot,change-config,resize,run-playbook
 	// example: stop
 	Operation string `json:"operation"`
+	// description: 操作参数, 如change-config的配置, resize的size, run-playbook的script
+	// example: {"vcpu_count":4,"vmem_size":"8G"}
+	OperationParams jsonutils.JSONObject `json:"operation_params"`
+
+	// description: 单次执行时同时操作资源的最大数量
+	// example: 20
+	MaxConcurrency int `json:"max_concurrency"`
+	// description: 错过执行时间后的补偿策略
+	// enum: skip,once
+	// example: skip
+	CatchUp string `json:"catch_up"`
+
 	// description: label type
 	// enum: tag,id
 	// example: id
@@ -148,6 +178,37 @@ type CycleTimerCreateInput struct {
 	EndTime time.Time `json:"end_time"`
 }
 
+type CronTimerCreateInput struct {
+
+	// description: 标准5段Cron表达式: 分 时 日 月 周
+	// example: 30 2 * * 1-5
+	Expression string `json:"expression"`
+
+	// description: 时区, 默认为UTC
+	// example: Asia/Shanghai
+	TimeZone string `json:"time_zone"`
+
+	// description: 开始时间
+	StartTime time.Time `json:"start_time"`
+
+	// description: 截止时间, 不指定则一直有效
+	EndTime time.Time `json:"end_time"`
+}
+
+type ScheduledTaskUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	// description: 操作参数
+	OperationParams jsonutils.JSONObject `json:"operation_params"`
+
+	// description: 单次执行时同时操作资源的最大数量
+	MaxConcurrency *int `json:"max_concurrency"`
+
+	// description: 错过执行时间后的补偿策略
+	// enum: skip,once
+	CatchUp string `json:"catch_up"`
+}
+
 type ScheduledTaskResourceInfo struct {
 	// description: 定时任务名称
 	// example: st-nihao
@@ -17,17 +17,30 @@ package scheduledtask
 const (
 	ST_TYPE_TIMING = "timing" // 定时
 	ST_TYPE_CYCLE  = "cycle"  // 周期
+	ST_TYPE_CRON   = "cron"   // Cron表达式
 
 	ST_STATUS_READY         = "ready"
 	ST_STATUS_CREATE_FAILED = "create_failed"
 
 	ST_RESOURCE_SERVER       = "server"
 	ST_RESOURCE_CLOUDACCOUNT = "cloudaccount"
+	ST_RESOURCE_DISK         = "disk"
+	ST_RESOURCE_SNAPSHOT     = "snapshot"
+	ST_RESOURCE_SCALINGGROUP = "scalinggroup"
+	ST_RESOURCE_DBINSTANCE   = "dbinstance"
 
 	ST_RESOURCE_OPERATION_START   = "start"
 	ST_RESOURCE_OPERATION_STOP    = "stop"
 	ST_RESOURCE_OPERATION_RESTART = "restart"
 	ST_RESOURCE_OPERATION_SYNC    = "sync"
+	ST_RESOURCE_OPERATION_ENABLE  = "enable"
+	ST_RESOURCE_OPERATION_DISABLE = "disable"
+	ST_RESOURCE_OPERATION_DELETE  = "delete"
+
+	ST_RESOURCE_OPERATION_SNAPSHOT      = "snapshot"
+	ST_RESOURCE_OPERATION_CHANGE_CONFIG = "change-config"
+	ST_RESOURCE_OPERATION_RESIZE        = "resize"
+	ST_RESOURCE_OPERATION_RUN_PLAYBOOK  = "run-playbook"
 
 	ST_LABEL_ID  = "id"
 	ST_LABEL_TAG = "tag"
@@ -42,4 +55,11 @@ const (
 	TIMER_TYPE_DAY   = "day"
 	TIMER_TYPE_WEEK  = "week"
 	TIMER_TYPE_MONTH = "month"
+	TIMER_TYPE_CRON  = "cron"
+
+	// 错过执行时间(如服务停止期间)后的补偿策略
+	ST_CATCH_UP_SKIP = "skip" // 跳过, 等待下次执行
+	ST_CATCH_UP_ONCE = "once" // 立即补执行一次
+
+	ST_MAX_CONCURRENCY_DEFAULT = 20
 )
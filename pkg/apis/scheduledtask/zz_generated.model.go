@@ -17,6 +17,8 @@ package scheduledtask
 import (
 	time "time"
 
+	"yunion.io/x/jsonutils"
+
 	"yunion.io/x/onecloud/pkg/apis"
 )
 
@@ -29,6 +31,12 @@ type SScheduledTask struct {
 	ResourceType string `json:"resource_type"`
 	Operation    string `json:"operation"`
 	LabelType    string `json:"label_type"`
+	// 操作参数
+	OperationParams jsonutils.JSONObject `json:"operation_params"`
+	// 单次执行时同时操作资源的最大数量
+	MaxConcurrency int `json:"max_concurrency"`
+	// 错过执行时间后的补偿策略
+	CatchUp string `json:"catch_up"`
 }
 
 // SScheduledTaskActivity is an autogenerated struct via yunion.io/x/onecloud/pkg/scheduledtask/models.SScheduledTaskActivity.
@@ -59,5 +67,9 @@ type STimer struct {
 	WeekDays byte `json:"week_days"`
 	// 0-31 0 is unlimited
 	MonthDays uint32 `json:"month_days"`
+	// Cron expression
+	CronExpression string `json:"cron_expression"`
+	// Time zone of cron expression
+	TimeZone  string `json:"time_zone"`
 	IsExpired bool   `json:"is_expired"`
 }
@@ -33,4 +33,5 @@ const (
 	APSARA    = "apsara"
 	JDCLOUD   = "jdcloud"
 	CLOUDPODS = "cloudpods"
+	PROXMOX   = "proxmox"
 )
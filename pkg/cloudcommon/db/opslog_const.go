@@ -121,6 +121,9 @@ const (
 	ACT_DISK_AUTO_SYNC_SNAPSHOT      = "disk_auto_sync_snapshot"
 	ACT_DISK_AUTO_SYNC_SNAPSHOT_FAIL = "disk_auto_sync_snapshot_fail"
 
+	ACT_GUEST_FS_FREEZE_FAIL = "guest_fs_freeze_fail"
+	ACT_GUEST_FS_THAW_FAIL   = "guest_fs_thaw_fail"
+
 	ACT_ALLOCATING           = "allocating"
 	ACT_BACKUP_ALLOCATING    = "backup_allocating"
 	ACT_ALLOCATE             = "allocate"
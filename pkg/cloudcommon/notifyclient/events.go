@@ -50,6 +50,8 @@ var (
 	ActionChangeConfig   = api.ActionChangeConfig
 	ActionResize         = api.ActionResize
 	ActionExpiredRelease = api.ActionExpiredRelease
+	ActionExpire         = api.ActionExpire
+	ActionBudgetExceed   = api.ActionBudgetExceed
 	ActionExecute        = api.ActionExecute
 
 	ActionMigrate            = api.ActionMigrate
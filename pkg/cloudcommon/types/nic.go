@@ -55,6 +55,11 @@ type SServerNic struct {
 	Bridge    string   `json:"bridge"`
 	Domain    string   `json:"domain"`
 	Ip        string   `json:"ip"`
+	Ip6       string   `json:"ip6,omitempty"`
+	Masklen6  int      `json:"masklen6,omitempty"`
+	Gateway6  string   `json:"gateway6,omitempty"`
+	Dns6      string   `json:"dns6,omitempty"`
+	Ip6Mode   string   `json:"ip6_mode,omitempty"`
 	Vlan      int      `json:"vlan"`
 	Driver    string   `json:"driver"`
 	Masklen   int      `json:"masklen"`
@@ -0,0 +1,402 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudevent"
+	"yunion.io/x/onecloud/pkg/cloudevent/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	ansible_modules "yunion.io/x/onecloud/pkg/mcclient/modules/ansible"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/identity"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/notify"
+	scheduledtask_modules "yunion.io/x/onecloud/pkg/mcclient/modules/scheduledtask"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+type iCloudeventHookAction interface {
+	// validate checks and normalizes params of the action
+	validate(ctx context.Context, userCred mcclient.TokenCredential, params jsonutils.JSONObject) (jsonutils.JSONObject, error)
+	// checkOwner makes sure resources referred by params belong to or are
+	// shared with the domain of hook.  Actions are fired with admin session,
+	// so it is checked on validation and again before each firing
+	checkOwner(ctx context.Context, s *mcclient.ClientSession, domainId string, params jsonutils.JSONObject) error
+	fire(ctx context.Context, s *mcclient.ClientSession, hook *SCloudeventHook, params jsonutils.JSONObject, event *SCloudevent) error
+}
+
+var cloudeventHookActions = map[string]iCloudeventHookAction{
+	api.CLOUD_EVENT_HOOK_ACTION_NOTIFY:        &sCloudeventHookNotify{},
+	api.CLOUD_EVENT_HOOK_ACTION_WEBHOOK:       &sCloudeventHookWebhook{},
+	api.CLOUD_EVENT_HOOK_ACTION_ANSIBLE:       &sCloudeventHookAnsible{},
+	api.CLOUD_EVENT_HOOK_ACTION_SCHEDULEDTASK: &sCloudeventHookScheduledtask{},
+}
+
+// isResourceOfDomain returns true if the resource, as fetched from its
+// service, is owned by the domain or shared with it
+func isResourceOfDomain(obj jsonutils.JSONObject, domainId string) bool {
+	if resDomainId, _ := obj.GetString("domain_id"); resDomainId == domainId {
+		return true
+	}
+	if !jsonutils.QueryBoolean(obj, "is_public", false) {
+		return false
+	}
+	if publicScope, _ := obj.GetString("public_scope"); publicScope == "system" {
+		return true
+	}
+	sharedDomains, _ := obj.GetArray("shared_domains")
+	for _, shared := range sharedDomains {
+		if id, _ := shared.GetString("id"); id == domainId {
+			return true
+		}
+	}
+	return false
+}
+
+func checkResourceOfDomain(s *mcclient.ClientSession, manager interface {
+	Get(s *mcclient.ClientSession, id string, params jsonutils.JSONObject) (jsonutils.JSONObject, error)
+	GetKeyword() string
+}, id string, domainId string) error {
+	obj, err := manager.Get(s, id, nil)
+	if err != nil {
+		return httperrors.NewResourceNotFoundError2(manager.GetKeyword(), id)
+	}
+	if !isResourceOfDomain(obj, domainId) {
+		return httperrors.NewForbiddenError("%s %s does not belong to domain %s", manager.GetKeyword(), id, domainId)
+	}
+	return nil
+}
+
+// cloudeventHookPayload is what gets delivered to the receivers
+func cloudeventHookPayload(hook *SCloudeventHook, event *SCloudevent) *jsonutils.JSONDict {
+	payload := jsonutils.NewDict()
+	payload.Set("hook_id", jsonutils.NewString(hook.Id))
+	payload.Set("hook", jsonutils.NewString(hook.Name))
+	payload.Set("cloudevent", jsonutils.Marshal(event))
+	return payload
+}
+
+type sCloudeventHookNotify struct{}
+
+func (a *sCloudeventHookNotify) validate(ctx context.Context, userCred mcclient.TokenCredential, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	input := api.CloudeventHookNotifyParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal notify params: %v", err)
+	}
+	if len(input.Receivers) == 0 && len(input.Robots) == 0 {
+		return nil, httperrors.NewMissingParameterError("receivers or robots")
+	}
+	switch notify.TNotifyPriority(input.Priority) {
+	case "":
+		input.Priority = string(notify.NotifyPriorityNormal)
+	case notify.NotifyPriorityNormal, notify.NotifyPriorityImportant, notify.NotifyPriorityCritical:
+	default:
+		return nil, httperrors.NewInputParameterError("invalid priority %q", input.Priority)
+	}
+	return jsonutils.Marshal(input), nil
+}
+
+func (a *sCloudeventHookNotify) checkOwner(ctx context.Context, s *mcclient.ClientSession, domainId string, params jsonutils.JSONObject) error {
+	input := api.CloudeventHookNotifyParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return errors.Wrap(err, "unmarshal notify params")
+	}
+	for _, uid := range input.Receivers {
+		user, err := identity.UsersV3.GetById(s, uid, nil)
+		if err != nil {
+			return httperrors.NewResourceNotFoundError2("user", uid)
+		}
+		if userDomainId, _ := user.GetString("domain_id"); userDomainId != domainId {
+			return httperrors.NewForbiddenError("receiver %s does not belong to domain %s", uid, domainId)
+		}
+	}
+	for _, robot := range input.Robots {
+		if err := checkResourceOfDomain(s, &notify.NotifyRobot, robot, domainId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *sCloudeventHookNotify) fire(ctx context.Context, s *mcclient.ClientSession, hook *SCloudeventHook, params jsonutils.JSONObject, event *SCloudevent) error {
+	input := api.CloudeventHookNotifyParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return errors.Wrap(err, "unmarshal notify params")
+	}
+	topic := input.Topic
+	if topic == "" {
+		topic = fmt.Sprintf("%s: %s %s %s", hook.Name, event.Provider, event.Action, event.Name)
+	}
+	msg := notify.SNotifyMessage{
+		Uid:         input.Receivers,
+		Robots:      input.Robots,
+		ContactType: notify.TNotifyChannel(input.ContactType),
+		Topic:       topic,
+		Priority:    notify.TNotifyPriority(input.Priority),
+		Msg:         cloudeventHookPayload(hook, event).PrettyString(),
+		Tag:         api.SERVICE_TYPE,
+	}
+	if err := notify.Notifications.Send(s, msg); err != nil {
+		return errors.Wrap(err, "send notification")
+	}
+	return nil
+}
+
+type sCloudeventHookWebhook struct{}
+
+func (a *sCloudeventHookWebhook) validate(ctx context.Context, userCred mcclient.TokenCredential, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	input := api.CloudeventHookWebhookParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal webhook params: %v", err)
+	}
+	if input.Url == "" {
+		return nil, httperrors.NewMissingParameterError("url")
+	}
+	u, err := url.Parse(input.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, httperrors.NewInputParameterError("invalid webhook url %q", input.Url)
+	}
+	return jsonutils.Marshal(input), nil
+}
+
+func (a *sCloudeventHookWebhook) checkOwner(ctx context.Context, s *mcclient.ClientSession, domainId string, params jsonutils.JSONObject) error {
+	// webhook is posted without any credential
+	return nil
+}
+
+func (a *sCloudeventHookWebhook) fire(ctx context.Context, s *mcclient.ClientSession, hook *SCloudeventHook, params jsonutils.JSONObject, event *SCloudevent) error {
+	input := api.CloudeventHookWebhookParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return errors.Wrap(err, "unmarshal webhook params")
+	}
+	header := http.Header{}
+	for k, v := range input.Headers {
+		header.Set(k, v)
+	}
+	client := httputils.GetTimeoutClient(time.Duration(options.Options.CloudeventHookTimeoutSeconds) * time.Second)
+	if !options.Options.CloudeventHookAllowInternalWebhook {
+		restrictWebhookClient(client)
+	}
+	_, _, err := httputils.JSONRequest(client, ctx, httputils.POST, input.Url, header, cloudeventHookPayload(hook, event), false)
+	if err != nil {
+		return errors.Wrapf(err, "post %s", input.Url)
+	}
+	return nil
+}
+
+// webhookDeniedNetworks are the addresses a webhook must not reach unless
+// cloudevent_hook_allow_internal_webhook is set by the admin: loopback,
+// link-local (including the metadata service), private and other
+// internal service networks
+var webhookDeniedNetworks = func() []*net.IPNet {
+	cidrs := []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+func checkWebhookAddr(ip net.IP) error {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, n := range webhookDeniedNetworks {
+		if n.Contains(ip) {
+			return errors.Wrapf(httperrors.ErrForbidden, "webhook to internal address %s", ip)
+		}
+	}
+	return nil
+}
+
+// restrictWebhookClient checks the address actually connected to, so that
+// names resolving or redirecting to internal addresses are refused as well
+func restrictWebhookClient(client *http.Client) {
+	tr, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	tr.Proxy = nil
+	tr.DialContext = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 5 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return errors.Wrapf(err, "split %s", address)
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return errors.Wrapf(httperrors.ErrForbidden, "webhook to unresolved address %s", host)
+			}
+			return checkWebhookAddr(ip)
+		},
+	}).DialContext
+}
+
+type sCloudeventHookAnsible struct{}
+
+func (a *sCloudeventHookAnsible) validate(ctx context.Context, userCred mcclient.TokenCredential, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	input := api.CloudeventHookAnsibleParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal ansible params: %v", err)
+	}
+	if input.PlaybookReference == "" {
+		return nil, httperrors.NewMissingParameterError("playbook_reference")
+	}
+	if input.Host == nil {
+		return nil, httperrors.NewMissingParameterError("host")
+	}
+	if input.Args != nil {
+		if _, ok := input.Args.(*jsonutils.JSONDict); !ok {
+			return nil, httperrors.NewInputParameterError("args must be a dict")
+		}
+	}
+	s := auth.GetSession(ctx, userCred, options.Options.Region, "")
+	ref, err := ansible_modules.AnsiblePlaybookReference.Get(s, input.PlaybookReference, nil)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2("ansibleplaybookreference", input.PlaybookReference)
+	}
+	input.PlaybookReference, _ = ref.GetString("id")
+	return jsonutils.Marshal(input), nil
+}
+
+// isServerIp returns true if ip is one of the addresses of server, as listed from region
+func isServerIp(server jsonutils.JSONObject, ip string) bool {
+	ips, _ := server.GetString("ips")
+	for _, addr := range strings.Split(ips, ",") {
+		if strings.TrimSpace(addr) == ip {
+			return true
+		}
+	}
+	eip, _ := server.GetString("eip")
+	return eip == ip
+}
+
+func (a *sCloudeventHookAnsible) checkOwner(ctx context.Context, s *mcclient.ClientSession, domainId string, params jsonutils.JSONObject) error {
+	input := api.CloudeventHookAnsibleParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return errors.Wrap(err, "unmarshal ansible params")
+	}
+	if err := checkResourceOfDomain(s, &ansible_modules.AnsiblePlaybookReference, input.PlaybookReference, domainId); err != nil {
+		return err
+	}
+	// playbook runs as root on the host, which must be a server of the domain
+	ip, _ := input.Host.GetString("ip")
+	if ip == "" {
+		return httperrors.NewMissingParameterError("host.ip")
+	}
+	query := jsonutils.NewDict()
+	query.Set("ip_addr", jsonutils.NewString(ip))
+	query.Set("project_domain_id", jsonutils.NewString(domainId))
+	query.Set("scope", jsonutils.NewString("system"))
+	query.Set("details", jsonutils.JSONTrue)
+	result, err := compute.Servers.List(s, query)
+	if err != nil {
+		return errors.Wrapf(err, "list servers of ip %s", ip)
+	}
+	for _, server := range result.Data {
+		if serverDomainId, _ := server.GetString("domain_id"); serverDomainId == domainId && isServerIp(server, ip) {
+			return nil
+		}
+	}
+	return httperrors.NewForbiddenError("host %s is not a server of domain %s", ip, domainId)
+}
+
+func (a *sCloudeventHookAnsible) fire(ctx context.Context, s *mcclient.ClientSession, hook *SCloudeventHook, params jsonutils.JSONObject, event *SCloudevent) error {
+	input := api.CloudeventHookAnsibleParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return errors.Wrap(err, "unmarshal ansible params")
+	}
+	args := jsonutils.NewDict()
+	if input.Args != nil {
+		args.Update(input.Args)
+	}
+	args.Set("cloudevent", cloudeventHookPayload(hook, event))
+	runParams := jsonutils.NewDict()
+	runParams.Set("host", input.Host)
+	runParams.Set("args", args)
+	if _, err := ansible_modules.AnsiblePlaybookReference.PerformAction(s, input.PlaybookReference, "run", runParams); err != nil {
+		return errors.Wrapf(err, "run ansible playbook reference %s", input.PlaybookReference)
+	}
+	return nil
+}
+
+type sCloudeventHookScheduledtask struct{}
+
+func (a *sCloudeventHookScheduledtask) validate(ctx context.Context, userCred mcclient.TokenCredential, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	input := api.CloudeventHookScheduledtaskParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal scheduledtask params: %v", err)
+	}
+	if input.ScheduledTask == "" {
+		return nil, httperrors.NewMissingParameterError("scheduled_task")
+	}
+	s := auth.GetSession(ctx, userCred, options.Options.Region, "")
+	st, err := scheduledtask_modules.ScheduledTask.Get(s, input.ScheduledTask, nil)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2("scheduledtask", input.ScheduledTask)
+	}
+	input.ScheduledTask, _ = st.GetString("id")
+	return jsonutils.Marshal(input), nil
+}
+
+func (a *sCloudeventHookScheduledtask) checkOwner(ctx context.Context, s *mcclient.ClientSession, domainId string, params jsonutils.JSONObject) error {
+	input := api.CloudeventHookScheduledtaskParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return errors.Wrap(err, "unmarshal scheduledtask params")
+	}
+	return checkResourceOfDomain(s, &scheduledtask_modules.ScheduledTask, input.ScheduledTask, domainId)
+}
+
+func (a *sCloudeventHookScheduledtask) fire(ctx context.Context, s *mcclient.ClientSession, hook *SCloudeventHook, params jsonutils.JSONObject, event *SCloudevent) error {
+	input := api.CloudeventHookScheduledtaskParams{}
+	if err := params.Unmarshal(&input); err != nil {
+		return errors.Wrap(err, "unmarshal scheduledtask params")
+	}
+	if _, err := scheduledtask_modules.ScheduledTask.PerformAction(s, input.ScheduledTask, "trigger", nil); err != nil {
+		return errors.Wrapf(err, "trigger scheduledtask %s", input.ScheduledTask)
+	}
+	return nil
+}
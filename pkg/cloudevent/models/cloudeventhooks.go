@@ -0,0 +1,400 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudevent"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudevent/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SCloudeventHookManager struct {
+	db.SEnabledStatusDomainLevelResourceBaseManager
+}
+
+var CloudeventHookManager *SCloudeventHookManager
+
+var cloudeventHookWorkerMan *appsrv.SWorkerManager
+
+func init() {
+	CloudeventHookManager = &SCloudeventHookManager{
+		SEnabledStatusDomainLevelResourceBaseManager: db.NewEnabledStatusDomainLevelResourceBaseManager(
+			SCloudeventHook{},
+			"cloudeventhooks_tbl",
+			"cloudeventhook",
+			"cloudeventhooks",
+		),
+	}
+	CloudeventHookManager.SetVirtualObject(CloudeventHookManager)
+
+	cloudeventHookWorkerMan = appsrv.NewWorkerManager("CloudeventHookWorkerManager", 4, 1024, false)
+}
+
+// 云平台操作日志订阅，匹配的操作日志同步后触发通知、webhook、ansible playbook或定时任务
+type SCloudeventHook struct {
+	db.SEnabledStatusDomainLevelResourceBase
+
+	// 订阅规则
+	Filter *api.CloudeventHookFilter `length:"text" list:"domain" update:"domain" create:"domain_optional"`
+
+	// 触发动作
+	HookAction string `width:"32" charset:"ascii" nullable:"false" list:"domain" create:"domain_required"`
+	// 触发动作参数
+	HookActionParams jsonutils.JSONObject `length:"text" nullable:"true" list:"domain" update:"domain" create:"domain_optional"`
+
+	// 触发次数
+	TriggerCount int `nullable:"false" default:"0" list:"domain"`
+	// 最近一次触发时间
+	LastTriggerAt time.Time `list:"domain"`
+	// 最近一次触发结果
+	LastTriggerStatus string `width:"16" charset:"ascii" list:"domain"`
+	// 最近一次触发失败原因
+	LastTriggerReason string `width:"1024" charset:"utf8" list:"domain"`
+}
+
+// 云平台操作日志订阅列表
+func (manager *SCloudeventHookManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.CloudeventHookListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusDomainLevelResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusDomainLevelResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBaseManager.ListItemFilter")
+	}
+	if len(query.HookAction) > 0 {
+		q = q.In("hook_action", query.HookAction)
+	}
+	return q, nil
+}
+
+func (manager *SCloudeventHookManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.CloudeventHookListInput,
+) (*sqlchemy.SQuery, error) {
+	return manager.SEnabledStatusDomainLevelResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusDomainLevelResourceListInput)
+}
+
+func (manager *SCloudeventHookManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return manager.SEnabledStatusDomainLevelResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (manager *SCloudeventHookManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.CloudeventHookDetails {
+	rows := make([]api.CloudeventHookDetails, len(objs))
+	stdRows := manager.SEnabledStatusDomainLevelResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.CloudeventHookDetails{
+			EnabledStatusDomainLevelResourceDetails: stdRows[i],
+		}
+	}
+	return rows
+}
+
+func (manager *SCloudeventHookManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.CloudeventHookCreateInput,
+) (api.CloudeventHookCreateInput, error) {
+	var err error
+	if input.Filter == nil {
+		input.Filter = &api.CloudeventHookFilter{}
+	}
+	if err := input.Filter.Validate(); err != nil {
+		return input, err
+	}
+	action, ok := cloudeventHookActions[input.HookAction]
+	if !ok {
+		return input, httperrors.NewInputParameterError("invalid hook_action %q, want %v", input.HookAction, api.CLOUD_EVENT_HOOK_ACTIONS)
+	}
+	if input.HookActionParams == nil {
+		input.HookActionParams = jsonutils.NewDict()
+	}
+	input.HookActionParams, err = action.validate(ctx, userCred, input.HookActionParams)
+	if err != nil {
+		return input, err
+	}
+	s := auth.GetAdminSession(ctx, options.Options.Region, "")
+	err = action.checkOwner(ctx, s, ownerId.GetProjectDomainId(), input.HookActionParams)
+	if err != nil {
+		return input, err
+	}
+	input.SetEnabled()
+	input.Status = api.CLOUD_EVENT_HOOK_STATUS_AVAILABLE
+	input.EnabledStatusDomainLevelResourceCreateInput, err = manager.SEnabledStatusDomainLevelResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusDomainLevelResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (hook *SCloudeventHook) ValidateUpdateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.CloudeventHookUpdateInput,
+) (api.CloudeventHookUpdateInput, error) {
+	var err error
+	if input.Filter != nil {
+		if err := input.Filter.Validate(); err != nil {
+			return input, err
+		}
+	}
+	if input.HookActionParams != nil {
+		action, ok := cloudeventHookActions[hook.HookAction]
+		if !ok {
+			return input, httperrors.NewInternalServerError("unknown hook_action %q", hook.HookAction)
+		}
+		input.HookActionParams, err = action.validate(ctx, userCred, input.HookActionParams)
+		if err != nil {
+			return input, err
+		}
+		s := auth.GetAdminSession(ctx, options.Options.Region, "")
+		err = action.checkOwner(ctx, s, hook.DomainId, input.HookActionParams)
+		if err != nil {
+			return input, err
+		}
+	}
+	input.EnabledStatusDomainLevelResourceBaseUpdateInput, err = hook.SEnabledStatusDomainLevelResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusDomainLevelResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func matchAnyPattern(patterns []string, val string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	val = strings.ToLower(val)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == val {
+			return true
+		}
+		if ok, _ := path.Match(pattern, val); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns whether the newly synced event should fire the hook
+func (hook *SCloudeventHook) Match(event *SCloudevent) bool {
+	if event.CreatedAt.Before(hook.CreatedAt) {
+		// do not fire for events backfilled
+		return false
+	}
+	return hook.matchFilter(event)
+}
+
+func (hook *SCloudeventHook) matchFilter(event *SCloudevent) bool {
+	filter := hook.Filter
+	if filter == nil {
+		return true
+	}
+	if len(filter.Providers) > 0 &&
+		!utils.IsInStringArray(event.Provider, filter.Providers) &&
+		!utils.IsInStringArray(event.Brand, filter.Providers) {
+		return false
+	}
+	if len(filter.ResourceTypes) > 0 && !utils.IsInStringArray(event.ResourceType, filter.ResourceTypes) {
+		return false
+	}
+	if !matchAnyPattern(filter.Actions, event.Action) {
+		return false
+	}
+	if !matchAnyPattern(filter.Accounts, event.Account) {
+		return false
+	}
+	switch filter.EventResult {
+	case api.CLOUD_EVENT_HOOK_RESULT_SUCCESS:
+		if !event.Success {
+			return false
+		}
+	case api.CLOUD_EVENT_HOOK_RESULT_FAILED:
+		if event.Success {
+			return false
+		}
+	}
+	if filter.RequestKeyword != "" {
+		if event.Request == nil || !strings.Contains(event.Request.String(), filter.RequestKeyword) {
+			return false
+		}
+	}
+	return true
+}
+
+func (manager *SCloudeventHookManager) getEnabledHooks(domainId string) ([]SCloudeventHook, error) {
+	hooks := []SCloudeventHook{}
+	q := manager.Query().IsTrue("enabled").Equals("domain_id", domainId)
+	if err := db.FetchModelObjects(manager, q, &hooks); err != nil {
+		return nil, errors.Wrap(err, "db.FetchModelObjects")
+	}
+	return hooks, nil
+}
+
+// FireHooks triggers enabled hooks of the cloudprovider domain matching the
+// newly synced events.  Hooks are executed asynchronously
+func (manager *SCloudeventHookManager) FireHooks(ctx context.Context, userCred mcclient.TokenCredential, cloudprovider *SCloudprovider, events []SCloudevent) {
+	if len(events) == 0 {
+		return
+	}
+	hooks, err := manager.getEnabledHooks(cloudprovider.DomainId)
+	if err != nil {
+		log.Errorf("fetch cloudevent hooks of domain %s: %v", cloudprovider.DomainId, err)
+		return
+	}
+	for i := range hooks {
+		for j := range events {
+			event := &events[j]
+			if !hooks[i].Match(event) {
+				continue
+			}
+			// tasks run concurrently, each of them works on its own copy
+			hook := hooks[i]
+			hook.SetModelManager(manager, &hook)
+			cloudeventHookWorkerMan.Run(&cloudeventHookTask{
+				hook:  &hook,
+				event: event,
+			}, nil, nil)
+		}
+	}
+}
+
+type cloudeventHookTask struct {
+	hook  *SCloudeventHook
+	event *SCloudevent
+}
+
+func (t *cloudeventHookTask) Dump() string {
+	return fmt.Sprintf("cloudevent hook %s(%s) event %d", t.hook.Name, t.hook.Id, t.event.EventId)
+}
+
+func (t *cloudeventHookTask) Run() {
+	ctx := context.Background()
+	err := t.hook.fire(ctx, t.event)
+	if err != nil {
+		log.Errorf("%s: %v", t.Dump(), err)
+	}
+	t.hook.markTriggered(ctx, err)
+}
+
+func (hook *SCloudeventHook) fire(ctx context.Context, event *SCloudevent) error {
+	action, ok := cloudeventHookActions[hook.HookAction]
+	if !ok {
+		return errors.Errorf("unknown hook_action %q", hook.HookAction)
+	}
+	s := auth.GetAdminSession(ctx, options.Options.Region, "")
+	params := hook.HookActionParams
+	if params == nil {
+		params = jsonutils.NewDict()
+	}
+	// referred resources may have been moved to other domains since validation
+	err := action.checkOwner(ctx, s, hook.DomainId, params)
+	if err != nil {
+		return errors.Wrap(err, "checkOwner")
+	}
+	return action.fire(ctx, s, hook, params, event)
+}
+
+// markTriggered records the trigger result on the latest row of the hook,
+// which is updated under lock as the hook may be fired by concurrent tasks
+func (hook *SCloudeventHook) markTriggered(ctx context.Context, err error) {
+	lockman.LockObject(ctx, hook)
+	defer lockman.ReleaseObject(ctx, hook)
+
+	obj, dberr := CloudeventHookManager.FetchById(hook.Id)
+	if dberr != nil {
+		log.Errorf("fetch cloudevent hook %s(%s): %v", hook.Name, hook.Id, dberr)
+		return
+	}
+	latest := obj.(*SCloudeventHook)
+	_, dberr = db.Update(latest, func() error {
+		latest.TriggerCount += 1
+		latest.LastTriggerAt = time.Now().UTC()
+		if err != nil {
+			latest.LastTriggerStatus = api.CLOUD_EVENT_HOOK_TRIGGER_FAILED
+			reason := err.Error()
+			if r := []rune(reason); len(r) > 1024 {
+				reason = string(r[:1024])
+			}
+			latest.LastTriggerReason = reason
+		} else {
+			latest.LastTriggerStatus = api.CLOUD_EVENT_HOOK_TRIGGER_SUCCESS
+			latest.LastTriggerReason = ""
+		}
+		return nil
+	})
+	if dberr != nil {
+		log.Errorf("update cloudevent hook %s(%s) trigger status: %v", hook.Name, hook.Id, dberr)
+	}
+}
+
+// 使用最近一条匹配的云平台操作日志触发订阅，用于测试订阅配置
+func (hook *SCloudeventHook) PerformTest(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	data jsonutils.JSONObject,
+) (jsonutils.JSONObject, error) {
+	q := CloudeventManager.Query().Equals("domain_id", hook.DomainId).Desc("created_at").Limit(1000)
+	events := []SCloudevent{}
+	if err := db.FetchModelObjects(CloudeventManager, q, &events); err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	for i := range events {
+		event := &events[i]
+		if !hook.matchFilter(event) {
+			continue
+		}
+		err := hook.fire(ctx, event)
+		hook.markTriggered(ctx, err)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		return jsonutils.Marshal(event), nil
+	}
+	return nil, httperrors.NewNotFoundError("no cloudevent matches hook %s", hook.Name)
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"net"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudevent"
+)
+
+func TestMatchAnyPattern(t *testing.T) {
+	cases := []struct {
+		name     string
+		patterns []string
+		val      string
+		want     bool
+	}{
+		{name: "empty patterns", patterns: nil, val: "DeleteInstance", want: true},
+		{name: "exact", patterns: []string{"DeleteInstance"}, val: "DeleteInstance", want: true},
+		{name: "case insensitive", patterns: []string{"deleteinstance"}, val: "DeleteInstance", want: true},
+		{name: "wildcard", patterns: []string{"AuthorizeSecurityGroup*"}, val: "AuthorizeSecurityGroupIngress", want: true},
+		{name: "any", patterns: []string{"*"}, val: "StopInstance", want: true},
+		{name: "second pattern", patterns: []string{"Create*", "Stop*"}, val: "StopInstance", want: true},
+		{name: "mismatch", patterns: []string{"Create*"}, val: "DeleteInstance", want: false},
+		{name: "prefix only", patterns: []string{"Delete"}, val: "DeleteInstance", want: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := matchAnyPattern(c.patterns, c.val); got != c.want {
+				t.Errorf("matchAnyPattern(%v, %q) = %v, want %v", c.patterns, c.val, got, c.want)
+			}
+		})
+	}
+}
+
+func TestCloudeventHookMatchFilter(t *testing.T) {
+	event := &SCloudevent{
+		ResourceType: "secgroupcache",
+		Action:       "AuthorizeSecurityGroupIngress",
+		Account:      "ops",
+		Success:      true,
+		Request:      jsonutils.Marshal(map[string]string{"port": "22"}),
+		Provider:     "Aliyun",
+		Brand:        "Aliyun",
+	}
+	cases := []struct {
+		name   string
+		filter *api.CloudeventHookFilter
+		want   bool
+	}{
+		{name: "nil filter", filter: nil, want: true},
+		{name: "empty filter", filter: &api.CloudeventHookFilter{}, want: true},
+		{
+			name: "all matched",
+			filter: &api.CloudeventHookFilter{
+				Providers:      []string{"Aliyun"},
+				ResourceTypes:  []string{"secgroupcache"},
+				Actions:        []string{"AuthorizeSecurityGroup*"},
+				Accounts:       []string{"*"},
+				EventResult:    api.CLOUD_EVENT_HOOK_RESULT_SUCCESS,
+				RequestKeyword: "port",
+			},
+			want: true,
+		},
+		{name: "provider mismatch", filter: &api.CloudeventHookFilter{Providers: []string{"Aws"}}, want: false},
+		{name: "resource type mismatch", filter: &api.CloudeventHookFilter{ResourceTypes: []string{"server"}}, want: false},
+		{name: "action mismatch", filter: &api.CloudeventHookFilter{Actions: []string{"Revoke*"}}, want: false},
+		{name: "account mismatch", filter: &api.CloudeventHookFilter{Accounts: []string{"dev*"}}, want: false},
+		{name: "result mismatch", filter: &api.CloudeventHookFilter{EventResult: api.CLOUD_EVENT_HOOK_RESULT_FAILED}, want: false},
+		{name: "keyword mismatch", filter: &api.CloudeventHookFilter{RequestKeyword: "0.0.0.0/0"}, want: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hook := &SCloudeventHook{Filter: c.filter}
+			if got := hook.matchFilter(event); got != c.want {
+				t.Errorf("matchFilter = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIsResourceOfDomain(t *testing.T) {
+	cases := []struct {
+		name string
+		obj  string
+		want bool
+	}{
+		{name: "owned", obj: `{"domain_id":"d1"}`, want: true},
+		{name: "other domain", obj: `{"domain_id":"d2"}`, want: false},
+		{name: "other domain private", obj: `{"domain_id":"d2","is_public":false,"public_scope":"system"}`, want: false},
+		{name: "shared to system", obj: `{"domain_id":"d2","is_public":true,"public_scope":"system"}`, want: true},
+		{name: "shared to domain", obj: `{"domain_id":"d2","is_public":true,"public_scope":"domain","shared_domains":[{"id":"d3"},{"id":"d1"}]}`, want: true},
+		{name: "shared to other domains", obj: `{"domain_id":"d2","is_public":true,"public_scope":"domain","shared_domains":[{"id":"d3"}]}`, want: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			obj, err := jsonutils.ParseString(c.obj)
+			if err != nil {
+				t.Fatalf("parse %s: %v", c.obj, err)
+			}
+			if got := isResourceOfDomain(obj, "d1"); got != c.want {
+				t.Errorf("isResourceOfDomain(%s) = %v, want %v", c.obj, got, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckWebhookAddr(t *testing.T) {
+	cases := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "8.8.8.8", wantErr: false},
+		{addr: "2001:4860:4860::8888", wantErr: false},
+		{addr: "127.0.0.1", wantErr: true},
+		{addr: "169.254.169.254", wantErr: true},
+		{addr: "10.1.2.3", wantErr: true},
+		{addr: "172.20.0.1", wantErr: true},
+		{addr: "192.168.1.1", wantErr: true},
+		{addr: "0.0.0.0", wantErr: true},
+		{addr: "::1", wantErr: true},
+		{addr: "::ffff:127.0.0.1", wantErr: true},
+		{addr: "fe80::1", wantErr: true},
+		{addr: "fd00::1", wantErr: true},
+	}
+	for _, c := range cases {
+		err := checkWebhookAddr(net.ParseIP(c.addr))
+		if (err != nil) != c.wantErr {
+			t.Errorf("checkWebhookAddr(%s) error = %v, wantErr %v", c.addr, err, c.wantErr)
+		}
+	}
+}
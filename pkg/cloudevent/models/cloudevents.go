@@ -220,6 +220,7 @@ func (manager *SCloudeventManager) OrderByExtraFields(
 
 func (manager *SCloudeventManager) SyncCloudevent(ctx context.Context, userCred mcclient.TokenCredential, cloudprovider *SCloudprovider, iEvents []cloudprovider.ICloudEvent) int {
 	count := 0
+	events := []SCloudevent{}
 	for _, iEvent := range iEvents {
 		event := &SCloudevent{
 			Name:            iEvent.GetName(),
@@ -259,8 +260,10 @@ func (manager *SCloudeventManager) SyncCloudevent(ctx context.Context, userCred
 			log.Errorf("failed to insert event: %s for cloudprovider: %s(%s) error: %v", jsonutils.Marshal(event).PrettyString(), cloudprovider.Name, cloudprovider.Id, err)
 			continue
 		}
+		events = append(events, *event)
 		count += 1
 	}
+	CloudeventHookManager.FireHooks(ctx, userCred, cloudprovider, events)
 	return count
 }
 
@@ -22,6 +22,11 @@ type CloudeventOptions struct {
 
 	CloudproviderSyncIntervalMinutes int `help:"frequency to sync region cloudprovider task" default:"15"`
 	CloudeventSyncIntervalHours      int `help:"frequency to sync cloud event task" default:"1"`
+	CloudeventSyncIntervalMinutes    int `help:"frequency in minutes to sync cloud event task, takes precedence over cloudevent_sync_interval_hours when positive"`
+	MinSyncMinutes                   int `help:"skip syncing cloud events of time range shorter than this" default:"60"`
+
+	CloudeventHookTimeoutSeconds       int  `help:"timeout of cloud event hook webhook requests" default:"30"`
+	CloudeventHookAllowInternalWebhook bool `help:"allow cloud event hook webhooks to post to loopback, link-local and private addresses" default:"false"`
 
 	SyncWithReadEvent bool `help:"sync read operation events" default:"false"`
 	OneSyncForHours   int  `help:"Onece sync for hours" default:"1"`
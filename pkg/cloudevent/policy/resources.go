@@ -23,6 +23,7 @@ var (
 	cloudeventSystemResources = []string{}
 	cloudeventDomainResources = []string{
 		"cloudevents",
+		"cloudeventhooks",
 	}
 	cloudeventUserResources = []string{}
 )
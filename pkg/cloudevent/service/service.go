@@ -57,7 +57,11 @@ func StartService() {
 	if !opts.IsSlaveNode {
 		cron := cronman.InitCronJobManager(true, options.Options.CronJobWorkerCount)
 		cron.AddJobAtIntervalsWithStartRun("SyncCloudprovider", time.Duration(opts.CloudproviderSyncIntervalMinutes)*time.Minute, models.CloudproviderManager.SyncCloudproviders, true)
-		cron.AddJobAtIntervalsWithStartRun("CloudeventSyncTask", time.Duration(opts.CloudeventSyncIntervalHours)*time.Hour, models.CloudproviderManager.SyncCloudeventTask, true)
+		syncInterval := time.Duration(opts.CloudeventSyncIntervalHours) * time.Hour
+		if opts.CloudeventSyncIntervalMinutes > 0 {
+			syncInterval = time.Duration(opts.CloudeventSyncIntervalMinutes) * time.Minute
+		}
+		cron.AddJobAtIntervalsWithStartRun("CloudeventSyncTask", syncInterval, models.CloudproviderManager.SyncCloudeventTask, true)
 		cron.Start()
 		defer cron.Stop()
 	}
@@ -69,9 +69,9 @@ func (self *CloudeventSyncTask) OnInit(ctx context.Context, obj db.IStandaloneMo
 		return
 	}
 
-	//小于1小时的暂时不同步
+	//小于MinSyncMinutes的暂时不同步
 	duration := end.Sub(start) + time.Second
-	if duration < time.Hour {
+	if duration < time.Duration(options.Options.MinSyncMinutes)*time.Minute {
 		self.taskComplete(ctx, provider)
 		return
 	}
@@ -94,7 +94,7 @@ func (self *CloudeventSyncTask) OnInit(ctx context.Context, obj db.IStandaloneMo
 			}
 		}
 		_count := models.CloudeventManager.SyncCloudevent(ctx, self.UserCred, provider, events)
-		log.Infof("Sync %d events for %s(%s) from %s(%d) hours", _count, provider.Name, provider.Id, start.Format("2006-01-02T15:04:05Z"), duration/time.Hour)
+		log.Infof("Sync %d events for %s(%s) from %s(%s)", _count, provider.Name, provider.Id, start.Format("2006-01-02T15:04:05Z"), duration)
 		count += _count
 
 		provider.SetLastSyncTimeAt(self.UserCred, end)
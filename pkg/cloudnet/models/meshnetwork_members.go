@@ -33,6 +33,10 @@ type SMeshNetworkMember struct {
 	MeshNetworkId    string
 	RouterId         string
 	AdvertiseSubnets string
+	// MappedSubnets are 1:1 NAT mappings of AdvertiseSubnets as seen by
+	// other members.  When not empty, it has the same number of subnets of
+	// the same mask length as AdvertiseSubnets
+	MappedSubnets string `width:"1024" charset:"ascii" nullable:"true"`
 }
 
 type SMeshNetworkMemberManager struct {
@@ -57,8 +61,11 @@ func (member *SMeshNetworkMember) subnetsStrList() []string {
 	return strings.Split(member.AdvertiseSubnets, ",")
 }
 
-func (member *SMeshNetworkMember) subnetsParsed() Subnets {
-	parts := member.subnetsStrList()
+func (member *SMeshNetworkMember) parseSubnets(s string) Subnets {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
 	r := make([]*netutils.IPV4Prefix, 0, len(parts))
 	for _, part := range parts {
 		p, err := netutils.NewIPV4Prefix(part)
@@ -71,6 +78,55 @@ func (member *SMeshNetworkMember) subnetsParsed() Subnets {
 	return Subnets(r)
 }
 
+// subnetsParsed returns the real subnets behind the member
+func (member *SMeshNetworkMember) subnetsParsed() Subnets {
+	return member.parseSubnets(member.AdvertiseSubnets)
+}
+
+func (member *SMeshNetworkMember) mappedSubnetsParsed() Subnets {
+	return member.parseSubnets(member.MappedSubnets)
+}
+
+// effectiveSubnets returns subnets of the member as seen by other members
+func (member *SMeshNetworkMember) effectiveSubnets() Subnets {
+	if mapped := member.mappedSubnetsParsed(); len(mapped) > 0 {
+		return mapped
+	}
+	return member.subnetsParsed()
+}
+
+// netmapRules returns NETMAP rules translating between mapped subnets and
+// real subnets of the member on wireguard interface ifname
+func (member *SMeshNetworkMember) netmapRules(ifname string) []SRule {
+	nets := member.subnetsParsed()
+	mapped := member.mappedSubnetsParsed()
+	if len(mapped) == 0 || len(mapped) != len(nets) {
+		return nil
+	}
+	rules := make([]SRule, 0, 2*len(nets))
+	for i := range nets {
+		rules = append(rules,
+			SRule{
+				Prio:          DEF_PRIO,
+				RouterId:      member.RouterId,
+				MatchDestNet:  mapped[i].String(),
+				MatchInIfname: ifname,
+				Action:        ACT_NETMAP,
+				ActionOptions: "--to " + nets[i].String(),
+			},
+			SRule{
+				Prio:           DEF_PRIO,
+				RouterId:       member.RouterId,
+				MatchSrcNet:    nets[i].String(),
+				MatchOutIfname: ifname,
+				Action:         ACT_NETMAP,
+				ActionOptions:  "--to " + mapped[i].String(),
+			},
+		)
+	}
+	return rules
+}
+
 func (member *SMeshNetworkMember) getRouter() (*SRouter, error) {
 	obj, err := db.FetchById(RouterManager, member.RouterId)
 	if err != nil {
@@ -139,14 +195,43 @@ func (man *SMeshNetworkMemberManager) getMemebersByMeshNetwork(ctx context.Conte
 	return members, nil
 }
 
-func (man *SMeshNetworkMemberManager) addMember(ctx context.Context, userCred mcclient.TokenCredential, mn *SMeshNetwork, router *SRouter, nets Subnets) (*SMeshNetworkMember, error) {
+func (man *SMeshNetworkMemberManager) addMember(ctx context.Context, userCred mcclient.TokenCredential, mn *SMeshNetwork, router *SRouter, nets, mappedNets Subnets) (*SMeshNetworkMember, error) {
 	member := &SMeshNetworkMember{
 		MeshNetworkId:    mn.Id,
 		RouterId:         router.Id,
 		AdvertiseSubnets: nets.String(),
+		MappedSubnets:    mappedNets.String(),
 	}
 	member.SetModelManager(man, member)
 	member.Name = fmt.Sprintf("%s-%s", mn.Name, router.Name)
 	man.TableSpec().Insert(ctx, member)
 	return member, nil
 }
+
+// netmapRulesByRouter returns NETMAP rules for all mesh networks the router
+// joined with mapped subnets
+func (man *SMeshNetworkMemberManager) netmapRulesByRouter(router *SRouter) ([]SRule, error) {
+	members, err := man.getByFilter(map[string]string{
+		"router_id": router.Id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rules []SRule
+		errs  []error
+	)
+	for i := range members {
+		member := &members[i]
+		if member.MappedSubnets == "" {
+			continue
+		}
+		iface, err := IfaceManager.getByMeshNetworkMember(member)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rules = append(rules, member.netmapRules(iface.Ifname)...)
+	}
+	return rules, yerrors.NewAggregate(errs)
+}
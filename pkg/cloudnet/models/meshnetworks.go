@@ -88,12 +88,19 @@ func (mn *SMeshNetwork) PerformRealize(ctx context.Context, userCred mcclient.To
 	return nil, err
 }
 
-func (mn *SMeshNetwork) addRouter(ctx context.Context, userCred mcclient.TokenCredential, router *SRouter, nets Subnets) error {
+// addRouter adds router as a member advertising subnets nets.  When
+// mappedNets is not empty, other members will see nets as mappedNets through
+// 1:1 NAT done on the router.  Subnets seen by members must not overlap
+func (mn *SMeshNetwork) addRouter(ctx context.Context, userCred mcclient.TokenCredential, router *SRouter, nets, mappedNets Subnets) error {
 	// XXX lock
 	members, err := MeshNetworkMemberManager.getMemebersByMeshNetwork(ctx, userCred, mn)
 	if err != nil {
 		return err
 	}
+	effNets := nets
+	if len(mappedNets) > 0 {
+		effNets = mappedNets
+	}
 	for i := range members {
 		member := &members[i]
 		if member.RouterId == router.Id {
@@ -101,12 +108,21 @@ func (mn *SMeshNetwork) addRouter(ctx context.Context, userCred mcclient.TokenCr
 				router.Name, mn.Name)
 		}
 		memberSubnets := member.subnetsParsed()
-		if _, p := memberSubnets.ContainsAnyEx(nets); p != nil {
-			return fmt.Errorf("router %s subnet %s already advertised by member %s(%s)",
-				router.Name, p.String(), member.Name, member.Id)
+		memberEffSubnets := member.effectiveSubnets()
+		if p0, p1 := memberEffSubnets.OverlapsAnyEx(effNets); p0 != nil {
+			return fmt.Errorf("router %s subnet %s overlaps with %s advertised by member %s(%s)",
+				router.Name, p1.String(), p0.String(), member.Name, member.Id)
+		}
+		if p0, p1 := memberEffSubnets.OverlapsAnyEx(nets); p0 != nil {
+			return fmt.Errorf("router %s local subnet %s overlaps with %s advertised by member %s(%s), a mapping is required",
+				router.Name, p1.String(), p0.String(), member.Name, member.Id)
+		}
+		if p0, p1 := memberSubnets.OverlapsAnyEx(effNets); p0 != nil {
+			return fmt.Errorf("router %s subnet %s overlaps with local subnet %s of member %s(%s), a mapping is required",
+				router.Name, p1.String(), p0.String(), member.Name, member.Id)
 		}
 	}
-	_, err = MeshNetworkMemberManager.addMember(ctx, userCred, mn, router, nets)
+	_, err = MeshNetworkMemberManager.addMember(ctx, userCred, mn, router, nets, mappedNets)
 	if err != nil {
 		return err
 	}
@@ -123,13 +139,13 @@ func (mn *SMeshNetwork) addRouter(ctx context.Context, userCred mcclient.TokenCr
 			errs = append(errs, err)
 			continue
 		}
-		if err := memberIface.addOrUpdatePeer(ctx, userCred, newIface, nets, router); err != nil {
+		if err := memberIface.addOrUpdatePeer(ctx, userCred, newIface, effNets, router); err != nil {
 			errs = append(errs, err)
 		}
 		if memberHost, err := RouterManager.getById(member.RouterId); err != nil {
 			errs = append(errs, err)
 		} else {
-			if err := newIface.addOrUpdatePeer(ctx, userCred, memberIface, member.subnetsParsed(), memberHost); err != nil {
+			if err := newIface.addOrUpdatePeer(ctx, userCred, memberIface, member.effectiveSubnets(), memberHost); err != nil {
 				errs = append(errs, err)
 			}
 		}
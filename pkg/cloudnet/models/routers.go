@@ -173,10 +173,13 @@ func (router *SRouter) PerformJoinMeshNetwork(ctx context.Context, userCred mccl
 	mnV := validators.NewModelIdOrNameValidator("mesh_network", "meshnetwork", userCred)
 	advSubnetsV := validators.NewValidatorByActor("advertise_subnets",
 		validators.NewActorJoinedBy(",", validators.NewActorIPv4Prefix()))
+	mappedSubnetsV := validators.NewValidatorByActor("mapped_subnets",
+		validators.NewActorJoinedBy(",", validators.NewActorIPv4Prefix()))
 	{
 		vs := []validators.IValidator{
 			mnV,
 			advSubnetsV,
+			mappedSubnetsV.Optional(true),
 		}
 		jd, ok := data.(*jsonutils.JSONDict)
 		if !ok {
@@ -193,8 +196,28 @@ func (router *SRouter) PerformJoinMeshNetwork(ctx context.Context, userCred mccl
 	if len(nets) == 0 {
 		return nil, httperrors.NewBadRequestError("advertise_subnets must not be empty")
 	}
+	var mappedNets Subnets
+	if mappedSubnetsV.Value != nil {
+		mappedNets = gotypes.ConvertSliceElemType(mappedSubnetsV.Value, (**netutils.IPV4Prefix)(nil)).([]*netutils.IPV4Prefix)
+	}
+	if len(mappedNets) > 0 {
+		if len(mappedNets) != len(nets) {
+			return nil, httperrors.NewBadRequestError("mapped_subnets count %d does not match advertise_subnets count %d",
+				len(mappedNets), len(nets))
+		}
+		for i := range nets {
+			if nets[i].MaskLen != mappedNets[i].MaskLen {
+				return nil, httperrors.NewBadRequestError("mapped subnet %s and advertised subnet %s must have the same mask length",
+					mappedNets[i].String(), nets[i].String())
+			}
+		}
+		if p0, p1 := mappedNets.OverlapsAnyEx(nets); p0 != nil {
+			return nil, httperrors.NewBadRequestError("mapped subnet %s overlaps with advertised subnet %s",
+				p0.String(), p1.String())
+		}
+	}
 	mn := mnV.Model.(*SMeshNetwork)
-	if err := mn.addRouter(ctx, userCred, router, nets); err != nil {
+	if err := mn.addRouter(ctx, userCred, router, nets, mappedNets); err != nil {
 		return nil, err
 	}
 	return data, nil
@@ -0,0 +1,136 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+	"yunion.io/x/onecloud/pkg/cloudnet/utils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/ssh"
+)
+
+func (router *SRouter) wgDump() ([]utils.WgDumpPeer, error) {
+	port := router.Port
+	if port <= 0 {
+		port = 22
+	}
+	cli, err := ssh.NewClient(router.Host, port, router.User, "", router.PrivateKey)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "ssh connect %s@%s:%d", router.User, router.Host, port)
+	}
+	defer cli.Close()
+	cmd := "wg show all dump"
+	if router.User != "root" {
+		cmd = "sudo -n " + cmd
+	}
+	lines, err := cli.Run(cmd)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "run %s", cmd)
+	}
+	return utils.ParseWgDump(lines)
+}
+
+func peerHealth(dumpPeer *utils.WgDumpPeer, now time.Time) string {
+	if dumpPeer == nil {
+		return api.PEER_HEALTH_UNKNOWN
+	}
+	if dumpPeer.LatestHandshake.IsZero() {
+		return api.PEER_HEALTH_NEVER
+	}
+	if now.Sub(dumpPeer.LatestHandshake) <= api.PEER_HANDSHAKE_HEALTHY_SECONDS*time.Second {
+		return api.PEER_HEALTH_HEALTHY
+	}
+	return api.PEER_HEALTH_STALE
+}
+
+// 获取路由器wireguard对端握手状态及路由
+func (router *SRouter) GetDetailsPeerStatus(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (api.RouterPeerStatusOutput, error) {
+	output := api.RouterPeerStatusOutput{
+		Peers: []api.RouterPeerStatus{},
+	}
+	routes, err := RouteManager.routeLinesRouter(router)
+	if err != nil {
+		return output, httperrors.NewGeneralError(errors.WithMessage(err, "fetch routes"))
+	}
+	output.Routes = routes
+
+	ifaces, err := IfaceManager.getByRouter(router)
+	if err != nil {
+		return output, httperrors.NewGeneralError(errors.WithMessage(err, "fetch ifaces"))
+	}
+	// a router unreachable is still worth a report with unknown health
+	dumpPeers := map[string]*utils.WgDumpPeer{}
+	if dump, err := router.wgDump(); err != nil {
+		log.Errorf("router %s(%s): wg dump: %v", router.Name, router.Id, err)
+	} else {
+		for i := range dump {
+			dumpPeer := &dump[i]
+			dumpPeers[dumpPeer.Ifname+"/"+dumpPeer.PublicKey] = dumpPeer
+		}
+	}
+
+	now := time.Now()
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.NetworkId == "" {
+			continue
+		}
+		meshNetworkName := iface.NetworkId
+		if obj, err := MeshNetworkManager.FetchById(iface.NetworkId); err == nil {
+			meshNetworkName = obj.GetName()
+		}
+		ifacePeers, err := IfacePeerManager.getByIface(iface)
+		if err != nil {
+			return output, httperrors.NewGeneralError(errors.WithMessagef(err, "fetch peers of %s", iface.Ifname))
+		}
+		for j := range ifacePeers {
+			ifacePeer := &ifacePeers[j]
+			status := api.RouterPeerStatus{
+				Ifname:       iface.Ifname,
+				MeshNetwork:  meshNetworkName,
+				PeerRouterId: ifacePeer.PeerRouterId,
+				PublicKey:    ifacePeer.PublicKey,
+				Endpoint:     ifacePeer.Endpoint,
+				AllowedIPs:   ifacePeer.AllowedIPs,
+			}
+			if peerRouter, err := RouterManager.getById(ifacePeer.PeerRouterId); err == nil {
+				status.PeerRouter = peerRouter.Name
+			}
+			dumpPeer := dumpPeers[iface.Ifname+"/"+ifacePeer.PublicKey]
+			if dumpPeer != nil {
+				if dumpPeer.Endpoint != "" {
+					status.Endpoint = dumpPeer.Endpoint
+				}
+				if !dumpPeer.LatestHandshake.IsZero() {
+					status.LatestHandshake = dumpPeer.LatestHandshake.UTC().Format(time.RFC3339)
+				}
+				status.TransferRx = dumpPeer.TransferRx
+				status.TransferTx = dumpPeer.TransferTx
+			}
+			status.Health = peerHealth(dumpPeer, now)
+			output.Peers = append(output.Peers, status)
+		}
+	}
+	return output, nil
+}
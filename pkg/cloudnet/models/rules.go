@@ -68,6 +68,7 @@ const (
 	ACT_TCPMSS         = "TCPMSS" // FORWARD chain for now
 	ACT_INPUT_ACCEPT   = "INPUT_ACCEPT"
 	ACT_FORWARD_ACCEPT = "FORWARD_ACCEPT"
+	ACT_NETMAP         = "NETMAP" // generated for mesh network mapped subnets
 
 	PROTO_TCP = "tcp"
 	PROTO_UDP = "udp"
@@ -403,6 +404,14 @@ func (rule *SRule) firewalldRule() (*firewalld.Rule, error) {
 			chain = "POSTROUTING"
 		}
 		action = rule.Action
+	case ACT_NETMAP:
+		table = "nat"
+		if rule.MatchInIfname != "" {
+			chain = "PREROUTING"
+		} else {
+			chain = "POSTROUTING"
+		}
+		action = rule.Action
 	case ACT_TCPMSS:
 		table = "mangle"
 		chain = "FORWARD" // save INPUT, OUTPUT for future occasions
@@ -474,5 +483,18 @@ func (man *SRuleManager) firewalldDirectByRouter(router *SRouter) (*firewalld.Di
 		}
 		rs = append(rs, r)
 	}
+	netmapRules, err := MeshNetworkMemberManager.netmapRulesByRouter(router)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for i := range netmapRules {
+		rule := &netmapRules[i]
+		r, err := rule.firewalldRule()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rs = append(rs, r)
+	}
 	return firewalld.NewDirect(rs...), errors.NewAggregate(errs)
 }
@@ -50,3 +50,20 @@ func (nets Subnets) ContainsAnyEx(nets1 Subnets) (bool, *netutils.IPV4Prefix) {
 	}
 	return false, nil
 }
+
+func (nets Subnets) OverlapsAny(nets1 Subnets) bool {
+	p0, _ := nets.OverlapsAnyEx(nets1)
+	return p0 != nil
+}
+
+func (nets Subnets) OverlapsAnyEx(nets1 Subnets) (*netutils.IPV4Prefix, *netutils.IPV4Prefix) {
+	for _, p0 := range nets {
+		r0 := p0.ToIPRange()
+		for _, p1 := range nets1 {
+			if r0.IsOverlap(p1.ToIPRange()) {
+				return p0, p1
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WgDumpPeer is a peer line of "wg show all dump" output
+type WgDumpPeer struct {
+	Ifname              string
+	PublicKey           string
+	Endpoint            string
+	AllowedIPs          string
+	LatestHandshake     time.Time
+	TransferRx          int64
+	TransferTx          int64
+	PersistentKeepalive int
+}
+
+// ParseWgDump parses output lines of "wg show all dump".  Interface lines
+// are skipped
+func ParseWgDump(lines []string) ([]WgDumpPeer, error) {
+	peers := []WgDumpPeer{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		switch len(fields) {
+		case 5:
+			// ifname, private key, public key, listen port, fwmark
+			continue
+		case 9:
+		default:
+			return nil, fmt.Errorf("unexpected wg dump line: %q", line)
+		}
+		peer := WgDumpPeer{
+			Ifname:     fields[0],
+			PublicKey:  fields[1],
+			Endpoint:   noneEmpty(fields[3]),
+			AllowedIPs: noneEmpty(fields[4]),
+		}
+		handshake, err := strconv.ParseInt(fields[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latest handshake %q: %v", fields[5], err)
+		}
+		if handshake > 0 {
+			peer.LatestHandshake = time.Unix(handshake, 0)
+		}
+		if peer.TransferRx, err = strconv.ParseInt(fields[6], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid transfer rx %q: %v", fields[6], err)
+		}
+		if peer.TransferTx, err = strconv.ParseInt(fields[7], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid transfer tx %q: %v", fields[7], err)
+		}
+		if fields[8] != "off" {
+			if peer.PersistentKeepalive, err = strconv.Atoi(fields[8]); err != nil {
+				return nil, fmt.Errorf("invalid persistent keepalive %q: %v", fields[8], err)
+			}
+		}
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
+func noneEmpty(s string) string {
+	if s == "(none)" {
+		return ""
+	}
+	return s
+}
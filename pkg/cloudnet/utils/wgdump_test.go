@@ -0,0 +1,65 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWgDump(t *testing.T) {
+	t.Run("good", func(t *testing.T) {
+		lines := []string{
+			"wg0\tWJYVsrTtAae1QS9YzefV4OmVM6mkJglR+GEgxQpTs2g=\tmOX0S5AuRqd8lQZWcqTlzOS+veo404gE7NyV4u3xVkg=\t20000\toff",
+			"wg0\tpeerA=\t(none)\t192.168.1.2:20000\t10.0.0.0/16,10.1.0.0/16\t1600000000\t1024\t2048\t25",
+			"wg0\tpeerB=\t(none)\t(none)\t(none)\t0\t0\t0\toff",
+			"",
+		}
+		peers, err := ParseWgDump(lines)
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		if len(peers) != 2 {
+			t.Fatalf("want 2 peers, got %d", len(peers))
+		}
+		a := peers[0]
+		if a.Ifname != "wg0" || a.PublicKey != "peerA=" || a.Endpoint != "192.168.1.2:20000" {
+			t.Errorf("bad peer: %#v", a)
+		}
+		if a.AllowedIPs != "10.0.0.0/16,10.1.0.0/16" {
+			t.Errorf("bad allowed ips: %s", a.AllowedIPs)
+		}
+		if !a.LatestHandshake.Equal(time.Unix(1600000000, 0)) {
+			t.Errorf("bad latest handshake: %s", a.LatestHandshake)
+		}
+		if a.TransferRx != 1024 || a.TransferTx != 2048 || a.PersistentKeepalive != 25 {
+			t.Errorf("bad counters: %#v", a)
+		}
+		b := peers[1]
+		if b.Endpoint != "" || b.AllowedIPs != "" || !b.LatestHandshake.IsZero() || b.PersistentKeepalive != 0 {
+			t.Errorf("bad peer: %#v", b)
+		}
+	})
+	t.Run("bad", func(t *testing.T) {
+		for _, line := range []string{
+			"wg0\tpeerA=",
+			"wg0\tpeerA=\t(none)\t(none)\t(none)\tx\t0\t0\toff",
+		} {
+			if _, err := ParseWgDump([]string{line}); err == nil {
+				t.Errorf("expect error for %q", line)
+			}
+		}
+	})
+}
@@ -130,6 +130,14 @@ func (self *SBaseGuestDriver) RequestCloseForward(ctx context.Context, userCred
 	return nil, cloudprovider.ErrNotImplemented
 }
 
+func (self *SBaseGuestDriver) RequestGuestFsFreeze(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, timeoutSeconds int) (int, error) {
+	return 0, cloudprovider.ErrNotImplemented
+}
+
+func (self *SBaseGuestDriver) RequestGuestFsThaw(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest) (int, error) {
+	return 0, cloudprovider.ErrNotImplemented
+}
+
 func (self *SBaseGuestDriver) RequestSaveImage(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, task taskman.ITask) error {
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestSaveImage")
 }
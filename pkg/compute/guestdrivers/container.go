@@ -20,6 +20,7 @@ import (
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
@@ -161,6 +162,11 @@ func (self *SContainerDriver) RequestUndeployGuestOnHost(ctx context.Context, gu
 
 func (self *SContainerDriver) GetJsonDescAtHost(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, params *jsonutils.JSONDict) (jsonutils.JSONObject, error) {
 	desc := guest.GetJsonDescAtHypervisor(ctx, host)
+	ipsets, err := guest.GetSecurityIpsets()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetSecurityIpsets")
+	}
+	desc.SecurityIpsets = ipsets
 	return jsonutils.Marshal(desc), nil
 }
 
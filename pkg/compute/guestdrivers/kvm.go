@@ -255,6 +255,11 @@ func (self *SKVMGuestDriver) RequestUndeployGuestOnHost(ctx context.Context, gue
 
 func (self *SKVMGuestDriver) GetJsonDescAtHost(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, params *jsonutils.JSONDict) (jsonutils.JSONObject, error) {
 	desc := guest.GetJsonDescAtHypervisor(ctx, host)
+	ipsets, err := guest.GetSecurityIpsets()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetSecurityIpsets")
+	}
+	desc.SecurityIpsets = ipsets
 	return jsonutils.Marshal(desc), nil
 }
 
@@ -517,6 +522,48 @@ func (self *SKVMGuestDriver) RequestCloseForward(ctx context.Context, userCred m
 	return resp, nil
 }
 
+func (self *SKVMGuestDriver) requestGuestFsFreezeAction(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, action string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	host, err := guest.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	var (
+		url        = fmt.Sprintf("%s/servers/%s/%s", host.ManagerUri, guest.Id, action)
+		httpClient = httputils.GetDefaultClient()
+		header     = mcclient.GetTokenHeaders(userCred)
+	)
+	_, respBody, err := httputils.JSONRequest(httpClient, ctx, "POST", url, header, body, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "host request %s", action)
+	}
+	return respBody, nil
+}
+
+func (self *SKVMGuestDriver) RequestGuestFsFreeze(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, timeoutSeconds int) (int, error) {
+	body := jsonutils.Marshal(&host_api.GuestFsFreezeRequest{TimeoutSeconds: timeoutSeconds})
+	respBody, err := self.requestGuestFsFreezeAction(ctx, userCred, guest, "fs-freeze", body)
+	if err != nil {
+		return 0, err
+	}
+	hostresp := &host_api.GuestFsFreezeResponse{}
+	if err := respBody.Unmarshal(hostresp); err != nil {
+		return 0, errors.Wrap(err, "unmarshal host response")
+	}
+	return hostresp.Count, nil
+}
+
+func (self *SKVMGuestDriver) RequestGuestFsThaw(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest) (int, error) {
+	respBody, err := self.requestGuestFsFreezeAction(ctx, userCred, guest, "fs-thaw", jsonutils.NewDict())
+	if err != nil {
+		return 0, err
+	}
+	hostresp := &host_api.GuestFsThawResponse{}
+	if err := respBody.Unmarshal(hostresp); err != nil {
+		return 0, errors.Wrap(err, "unmarshal host response")
+	}
+	return hostresp.Count, nil
+}
+
 func (self *SKVMGuestDriver) RequestListForward(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, req *guestdriver_types.ListForwardRequest) (*guestdriver_types.ListForwardResponse, error) {
 	var (
 		host, _    = guest.GetHost()
@@ -0,0 +1,185 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestdrivers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/billing"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+type SOVirtGuestDriver struct {
+	SManagedVirtualizedGuestDriver
+}
+
+func init() {
+	driver := SOVirtGuestDriver{}
+	models.RegisterGuestDriver(&driver)
+}
+
+func (self *SOVirtGuestDriver) DoScheduleCPUFilter() bool { return true }
+
+func (self *SOVirtGuestDriver) DoScheduleMemoryFilter() bool { return true }
+
+func (self *SOVirtGuestDriver) DoScheduleSKUFilter() bool { return false }
+
+func (self *SOVirtGuestDriver) DoScheduleStorageFilter() bool { return true }
+
+func (self *SOVirtGuestDriver) GetHypervisor() string {
+	return api.HYPERVISOR_OVIRT
+}
+
+func (self *SOVirtGuestDriver) GetProvider() string {
+	return api.CLOUD_PROVIDER_OVIRT
+}
+
+func (self *SOVirtGuestDriver) GetInstanceCapability() cloudprovider.SInstanceCapability {
+	return cloudprovider.SInstanceCapability{
+		Hypervisor: self.GetHypervisor(),
+		Provider:   self.GetProvider(),
+		DefaultAccount: cloudprovider.SDefaultAccount{
+			Linux: cloudprovider.SOsDefaultAccount{
+				DefaultAccount: api.VM_DEFAULT_LINUX_LOGIN_USER,
+				Changeable:     true,
+			},
+			Windows: cloudprovider.SOsDefaultAccount{
+				DefaultAccount: api.VM_DEFAULT_WINDOWS_LOGIN_USER,
+				Changeable:     false,
+			},
+		},
+	}
+}
+
+func (self *SOVirtGuestDriver) GetComputeQuotaKeys(scope rbacutils.TRbacScope, ownerId mcclient.IIdentityProvider, brand string) models.SComputeResourceKeys {
+	keys := models.SComputeResourceKeys{}
+	keys.SBaseProjectQuotaKeys = quotas.OwnerIdProjectQuotaKeys(scope, ownerId)
+	keys.CloudEnv = api.CLOUD_ENV_PRIVATE_CLOUD
+	keys.Provider = api.CLOUD_PROVIDER_OVIRT
+	keys.Brand = api.CLOUD_PROVIDER_OVIRT
+	keys.Hypervisor = api.HYPERVISOR_OVIRT
+	return keys
+}
+
+func (self *SOVirtGuestDriver) GetDefaultSysDiskBackend() string {
+	return ""
+}
+
+// GetUserDataType returns cloud-config, which is passed to the custom script of the vm initialization
+func (self *SOVirtGuestDriver) GetUserDataType() string {
+	return cloudprovider.CLOUD_CONFIG
+}
+
+// IsNeedInjectPasswordByCloudInit returns false, the password and ssh keys are set by the vm initialization of oVirt
+func (self *SOVirtGuestDriver) IsNeedInjectPasswordByCloudInit(desc *cloudprovider.SManagedVMCreateConfig) bool {
+	return false
+}
+
+func (self *SOVirtGuestDriver) ChooseHostStorage(host *models.SHost, guest *models.SGuest, diskConfig *api.DiskConfig, storageIds []string) (*models.SStorage, error) {
+	return self.chooseHostStorage(self, host, diskConfig.Backend, storageIds), nil
+}
+
+func (self *SOVirtGuestDriver) GetMinimalSysDiskSizeGb() int {
+	return options.Options.DefaultDiskSizeMB / 1024
+}
+
+func (self *SOVirtGuestDriver) RequestSyncSecgroupsOnHost(ctx context.Context, guest *models.SGuest, host *models.SHost, task taskman.ITask) error {
+	return nil // do nothing, not support securitygroup
+}
+
+func (self *SOVirtGuestDriver) GetMaxSecurityGroupCount() int {
+	//暂不支持绑定安全组
+	return 0
+}
+
+func (self *SOVirtGuestDriver) DoGuestCreateDisksTask(ctx context.Context, guest *models.SGuest, task taskman.ITask) error {
+	subtask, err := taskman.TaskManager.NewTask(ctx, "OVirtGuestCreateDiskTask", guest, task.GetUserCred(), task.GetParams(), task.GetTaskId(), "", nil)
+	if err != nil {
+		return err
+	}
+	subtask.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SOVirtGuestDriver) GetDetachDiskStatus() ([]string, error) {
+	return []string{api.VM_READY, api.VM_RUNNING}, nil
+}
+
+func (self *SOVirtGuestDriver) GetAttachDiskStatus() ([]string, error) {
+	return []string{api.VM_READY, api.VM_RUNNING}, nil
+}
+
+// GetChangeConfigStatus allows the running vm, the cpu sockets and memory are hot plugged by oVirt
+func (self *SOVirtGuestDriver) GetChangeConfigStatus(guest *models.SGuest) ([]string, error) {
+	return []string{api.VM_READY, api.VM_RUNNING}, nil
+}
+
+func (self *SOVirtGuestDriver) CanKeepDetachDisk() bool {
+	return true
+}
+
+func (self *SOVirtGuestDriver) GetRebuildRootStatus() ([]string, error) {
+	return []string{}, cloudprovider.ErrNotSupported
+}
+
+func (self *SOVirtGuestDriver) GetDeployStatus() ([]string, error) {
+	return []string{api.VM_READY}, nil
+}
+
+func (self *SOVirtGuestDriver) ValidateResizeDisk(guest *models.SGuest, disk *models.SDisk, storage *models.SStorage) error {
+	if !utils.IsInStringArray(guest.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return fmt.Errorf("Cannot resize disk when guest in status %s", guest.Status)
+	}
+	if disk.DiskSize/1024%1 > 0 {
+		return fmt.Errorf("Resize disk size must be an integer multiple of 1G")
+	}
+	return nil
+}
+
+func (self *SOVirtGuestDriver) ValidateCreateEip(ctx context.Context, userCred mcclient.TokenCredential, input api.ServerCreateEipInput) error {
+	return httperrors.NewInputParameterError("%s not support create eip", self.GetHypervisor())
+}
+
+func (self *SOVirtGuestDriver) AllowReconfigGuest() bool {
+	return true
+}
+
+func (self *SOVirtGuestDriver) RequestRenewInstance(guest *models.SGuest, bc billing.SBillingCycle) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (self *SOVirtGuestDriver) IsSupportEip() bool {
+	return false
+}
+
+func (self *SOVirtGuestDriver) IsSupportCdrom(guest *models.SGuest) (bool, error) {
+	return false, nil
+}
+
+func (self *SOVirtGuestDriver) RequestRemoteUpdate(ctx context.Context, guest *models.SGuest, userCred mcclient.TokenCredential, replaceTags bool) error {
+	return nil
+}
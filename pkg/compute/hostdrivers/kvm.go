@@ -60,7 +60,7 @@ func (self *SKVMHostDriver) GetHypervisor() string {
 }
 
 func (self *SKVMHostDriver) ValidateAttachStorage(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, storage *models.SStorage, input api.HostStorageCreateInput) (api.HostStorageCreateInput, error) {
-	if !utils.IsInStringArray(storage.StorageType, append([]string{api.STORAGE_LOCAL}, api.SHARED_STORAGE...)) {
+	if !utils.IsInStringArray(storage.StorageType, append(append([]string{api.STORAGE_LOCAL}, api.LOCAL_BLOCK_STORAGE...), api.SHARED_STORAGE...)) {
 		return input, httperrors.NewUnsupportOperationError("Unsupport attach %s storage for %s host", storage.StorageType, host.HostType)
 	}
 	if storage.StorageType == api.STORAGE_RBD {
@@ -69,6 +69,12 @@ func (self *SKVMHostDriver) ValidateAttachStorage(ctx context.Context, userCred
 		}
 		pool, _ := storage.StorageConf.GetString("pool")
 		input.MountPoint = fmt.Sprintf("rbd:%s", pool)
+	} else if storage.StorageType == api.STORAGE_SLVM {
+		if host.HostStatus != api.HOST_ONLINE {
+			return input, httperrors.NewInvalidStatusError("Attach shared lvm storage require host status is online")
+		}
+		vg, _ := storage.StorageConf.GetString("vg_name")
+		input.MountPoint = fmt.Sprintf("/dev/%s", vg)
 	} else if utils.IsInStringArray(storage.StorageType, api.SHARED_FILE_STORAGE) {
 		if len(input.MountPoint) == 0 {
 			return input, httperrors.NewMissingParameterError("mount_point")
@@ -259,6 +265,17 @@ func (self *SKVMHostDriver) RequestAllocateDiskOnStorage(ctx context.Context, us
 			input.SnapshotUrl = snapshot.Id
 			input.SrcDiskId = snapshot.DiskId
 			input.SrcPool, _ = snapshotStorage.StorageConf.GetString("pool")
+		} else if snapshotStorage.StorageType == api.STORAGE_SLVM {
+			input.SnapshotUrl = snapshot.Id
+			input.SrcDiskId = snapshot.DiskId
+			input.SrcPool, _ = snapshotStorage.StorageConf.GetString("vg_name")
+		} else if utils.IsInStringArray(snapshotStorage.StorageType, api.LOCAL_BLOCK_STORAGE) {
+			// snapshots are cloned natively, which works only inside the same pool
+			if snapshotStorage.Id != storage.Id {
+				return httperrors.NewUnsupportOperationError("Can't create disk on storage %s from snapshot %s of storage %s", storage.Name, snapshot.Name, snapshotStorage.Name)
+			}
+			input.SnapshotUrl = snapshot.Id
+			input.SrcDiskId = snapshot.DiskId
 		} else {
 			input.SnapshotUrl = snapshot.Location
 		}
@@ -0,0 +1,322 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SBudgetManager struct {
+	db.SVirtualResourceBaseManager
+	db.SEnabledResourceBaseManager
+}
+
+var BudgetManager *SBudgetManager
+
+func init() {
+	BudgetManager = &SBudgetManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SBudget{},
+			"budgets_tbl",
+			"budget",
+			"budgets",
+		),
+	}
+	BudgetManager.SetVirtualObject(BudgetManager)
+}
+
+// SBudget limits the cost of the rating bills of its project or domain in a
+// period, a notify event is raised when the cost exceeds the alert percent
+type SBudget struct {
+	db.SVirtualResourceBase
+	db.SEnabledResourceBase
+
+	// 预算范围
+	BudgetScope string `width:"16" charset:"ascii" nullable:"false" default:"project" list:"user" create:"optional"`
+	// 预算周期
+	Period string `width:"16" charset:"ascii" nullable:"false" default:"month" list:"user" create:"optional"`
+	// 预算金额
+	Amount float64 `nullable:"false" list:"user" update:"user" create:"required"`
+	// 货币单位
+	Currency string `width:"8" charset:"ascii" nullable:"false" default:"CNY" list:"user" create:"optional"`
+	// 告警百分比
+	AlertPercent int `nullable:"false" default:"100" list:"user" update:"user" create:"optional"`
+
+	// 当前周期已使用金额
+	UsedAmount float64 `nullable:"false" default:"0" list:"user"`
+	// 最近一次告警的周期
+	AlertedPeriod string `width:"10" charset:"ascii" nullable:"false" default:"" list:"user"`
+}
+
+// 列出预算
+func (manager *SBudgetManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.BudgetListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SEnabledResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledResourceBaseManager.ListItemFilter")
+	}
+	if len(query.BudgetScope) > 0 {
+		q = q.In("budget_scope", query.BudgetScope)
+	}
+	if len(query.Period) > 0 {
+		q = q.In("period", query.Period)
+	}
+	return q, nil
+}
+
+func (manager *SBudgetManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.BudgetListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SBudgetManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SBudgetManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SVirtualResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemExportKeys")
+	}
+	return q, nil
+}
+
+func (manager *SBudgetManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.BudgetDetails {
+	rows := make([]api.BudgetDetails, len(objs))
+	virtRows := manager.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.BudgetDetails{
+			VirtualResourceDetails: virtRows[i],
+		}
+	}
+	return rows
+}
+
+func validateBudgetAmount(amount float64, alertPercent int) error {
+	if amount <= 0 {
+		return httperrors.NewInputParameterError("amount should be greater than 0")
+	}
+	if alertPercent <= 0 {
+		return httperrors.NewInputParameterError("alert_percent should be greater than 0")
+	}
+	return nil
+}
+
+func (manager *SBudgetManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.BudgetCreateInput,
+) (api.BudgetCreateInput, error) {
+	if len(input.BudgetScope) == 0 {
+		input.BudgetScope = api.BUDGET_SCOPE_PROJECT
+	}
+	if !utils.IsInStringArray(input.BudgetScope, []string{api.BUDGET_SCOPE_PROJECT, api.BUDGET_SCOPE_DOMAIN}) {
+		return input, httperrors.NewInputParameterError("invalid budget_scope %s", input.BudgetScope)
+	}
+	if input.BudgetScope == api.BUDGET_SCOPE_DOMAIN && db.IsDomainAllowCreate(userCred, manager).Result.IsDeny() {
+		return input, httperrors.NewForbiddenError("not allow to create budget of domain")
+	}
+	if len(input.Period) == 0 {
+		input.Period = api.BUDGET_PERIOD_MONTH
+	}
+	if !utils.IsInStringArray(input.Period, []string{api.BUDGET_PERIOD_DAY, api.BUDGET_PERIOD_MONTH}) {
+		return input, httperrors.NewInputParameterError("invalid period %s", input.Period)
+	}
+	if len(input.Currency) == 0 {
+		input.Currency = api.DEFAULT_RATING_CURRENCY
+	}
+	if input.AlertPercent == 0 {
+		input.AlertPercent = 100
+	}
+	err := validateBudgetAmount(input.Amount, input.AlertPercent)
+	if err != nil {
+		return input, err
+	}
+	if input.Enabled == nil {
+		input.SetEnabled()
+	}
+	input.Status = api.BUDGET_STATUS_NORMAL
+	input.VirtualResourceCreateInput, err = manager.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *SBudget) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.BudgetUpdateInput) (api.BudgetUpdateInput, error) {
+	amount, alertPercent := self.Amount, self.AlertPercent
+	if input.Amount != nil {
+		amount = *input.Amount
+	}
+	if input.AlertPercent != nil {
+		alertPercent = *input.AlertPercent
+	}
+	err := validateBudgetAmount(amount, alertPercent)
+	if err != nil {
+		return input, err
+	}
+	input.VirtualResourceBaseUpdateInput, err = self.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	return input, err
+}
+
+// 启用预算
+func (self *SBudget) PerformEnable(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input apis.PerformEnableInput) (jsonutils.JSONObject, error) {
+	err := db.EnabledPerformEnable(self, ctx, userCred, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "EnabledPerformEnable")
+	}
+	return nil, nil
+}
+
+// 禁用预算
+func (self *SBudget) PerformDisable(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input apis.PerformDisableInput) (jsonutils.JSONObject, error) {
+	err := db.EnabledPerformEnable(self, ctx, userCred, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "EnabledPerformEnable")
+	}
+	return nil, nil
+}
+
+// getPeriod returns the key and the first bill date of the period containing day
+func (self *SBudget) getPeriod(day time.Time) (string, string) {
+	if self.Period == api.BUDGET_PERIOD_DAY {
+		date := day.Format(api.RATING_BILL_DATE_FORMAT)
+		return date, date
+	}
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return first.Format("2006-01"), first.Format(api.RATING_BILL_DATE_FORMAT)
+}
+
+// getUsedAmount sums up the rating bills of the scope of budget in [startDate, endDate]
+func (self *SBudget) getUsedAmount(startDate, endDate string) (float64, error) {
+	bills := RatingBillManager.Query().SubQuery()
+	q := bills.Query(sqlchemy.SUM("amount", bills.Field("amount"))).
+		Filter(sqlchemy.Equals(bills.Field("currency"), self.Currency)).
+		Filter(sqlchemy.GE(bills.Field("bill_date"), startDate)).
+		Filter(sqlchemy.LE(bills.Field("bill_date"), endDate))
+	if self.BudgetScope == api.BUDGET_SCOPE_DOMAIN {
+		q = q.Filter(sqlchemy.Equals(bills.Field("domain_id"), self.DomainId))
+	} else {
+		q = q.Filter(sqlchemy.Equals(bills.Field("tenant_id"), self.ProjectId))
+	}
+	ret := struct {
+		Amount float64
+	}{}
+	err := q.First(&ret)
+	if err != nil {
+		return 0, errors.Wrap(err, "sum amount of bills")
+	}
+	return ret.Amount, nil
+}
+
+// check updates the used amount of the period containing day, and raises the
+// notify event once in a period when the used amount reaches the alert percent
+func (self *SBudget) check(ctx context.Context, userCred mcclient.TokenCredential, day time.Time) error {
+	period, startDate := self.getPeriod(day)
+	used, err := self.getUsedAmount(startDate, day.Format(api.RATING_BILL_DATE_FORMAT))
+	if err != nil {
+		return err
+	}
+	exceeded := used >= self.Amount*float64(self.AlertPercent)/100
+	alert := exceeded && self.AlertedPeriod != period
+	status := api.BUDGET_STATUS_NORMAL
+	if exceeded {
+		status = api.BUDGET_STATUS_EXCEEDED
+	}
+	_, err = db.Update(self, func() error {
+		self.UsedAmount = used
+		self.Status = status
+		if alert {
+			self.AlertedPeriod = period
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update used amount")
+	}
+	if alert {
+		notifyclient.EventNotify(ctx, userCred, notifyclient.SEventNotifyParam{
+			Obj:    self,
+			Action: notifyclient.ActionBudgetExceed,
+		})
+	}
+	return nil
+}
+
+func (manager *SBudgetManager) checkBudgets(ctx context.Context, userCred mcclient.TokenCredential, day time.Time) error {
+	budgets := []SBudget{}
+	q := manager.Query().IsTrue("enabled")
+	err := db.FetchModelObjects(manager, q, &budgets)
+	if err != nil {
+		return errors.Wrap(err, "FetchModelObjects")
+	}
+	for i := range budgets {
+		err = budgets[i].check(ctx, userCred, day)
+		if err != nil {
+			log.Errorf("check budget %s error: %v", budgets[i].Name, err)
+		}
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -48,6 +49,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/image"
 	"yunion.io/x/onecloud/pkg/util/billing"
+	"yunion.io/x/onecloud/pkg/util/cronexpr"
 	"yunion.io/x/onecloud/pkg/util/rand"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
@@ -2381,6 +2383,34 @@ func (manager *SDiskManager) getAutoSnapshotDisksId(isExternal bool) ([]SSnapsho
 	if err != nil {
 		return nil, err
 	}
+	return manager.getSnapshotPolicyDisks(sps, isExternal)
+}
+
+// getCronAutoSnapshotDisks returns the bindings of snapshot policies
+// scheduled by cron expression, which should fire in (since, now]
+func (manager *SDiskManager) getCronAutoSnapshotDisks(since, now time.Time) ([]SSnapshotPolicyDisk, error) {
+	q := SnapshotPolicyManager.Query().IsNotEmpty("cron_expression").IsTrue("is_activated")
+	sps := make([]SSnapshotPolicy, 0)
+	err := q.All(&sps)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch cron snapshot policies")
+	}
+	spIds := make([]string, 0)
+	for i := range sps {
+		expr, err := cronexpr.Parse(sps[i].CronExpression)
+		if err != nil {
+			log.Errorf("snapshotpolicy %s invalid cron expression %q: %v", sps[i].Name, sps[i].CronExpression, err)
+			continue
+		}
+		next := expr.Next(since)
+		if !next.IsZero() && !next.After(now) {
+			spIds = append(spIds, sps[i].Id)
+		}
+	}
+	return manager.getSnapshotPolicyDisks(spIds, false)
+}
+
+func (manager *SDiskManager) getSnapshotPolicyDisks(sps []string, isExternal bool) ([]SSnapshotPolicyDisk, error) {
 	if len(sps) == 0 {
 		return nil, nil
 	}
@@ -2397,7 +2427,7 @@ func (manager *SDiskManager) getAutoSnapshotDisksId(isExternal bool) ([]SSnapsho
 	} else {
 		spdq.Filter(sqlchemy.IsNotEmpty(diskQ.Field("external_id")))
 	}
-	err = spdq.All(&spds)
+	err := spdq.All(&spds)
 	if err != nil {
 		return nil, err
 	}
@@ -2439,18 +2469,73 @@ func (manager *SDiskManager) AutoDiskSnapshot(ctx context.Context, userCred mccl
 		log.Infof("CronJob AutoDiskSnapshot: No disk need create snapshot")
 		return
 	}
+	manager.autoDiskSnapshot(ctx, userCred, spds, time.Now())
+}
+
+var autoCronDiskSnapshotLastRun struct {
+	sync.Mutex
+	at time.Time
+}
+
+// AutoCronDiskSnapshot creates snapshots for snapshot policies scheduled by
+// cron expression, it runs every minute and fires the policies scheduled
+// since last run
+func (manager *SDiskManager) AutoCronDiskSnapshot(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	autoCronDiskSnapshotLastRun.Lock()
+	defer autoCronDiskSnapshotLastRun.Unlock()
+
 	now := time.Now()
+	since := autoCronDiskSnapshotLastRun.at
+	if since.IsZero() {
+		since = now.Add(-time.Minute)
+	}
+	autoCronDiskSnapshotLastRun.at = now
+	spds, err := manager.getCronAutoSnapshotDisks(since, now)
+	if err != nil {
+		log.Errorf("Get cron auto snapshot disks failed: %s", err)
+		return
+	}
+	if len(spds) == 0 {
+		return
+	}
+	manager.autoDiskSnapshot(ctx, userCred, spds, now)
+}
+
+func (manager *SDiskManager) autoDiskSnapshot(ctx context.Context, userCred mcclient.TokenCredential, spds []SSnapshotPolicyDisk, now time.Time) {
+	// disks of one guest bound to the same consistency group are snapshotted together once
+	consistencyGroups := sets.NewString()
 	for i := 0; i < len(spds); i++ {
 		var (
 			disk                  = manager.FetchDiskById(spds[i].DiskId)
 			snapshotPolicy, _     = SnapshotPolicyManager.FetchSnapshotPolicyById(spds[i].SnapshotpolicyId)
 			snapshotName          = generateAutoSnapshotName()
 			autoSnapshotCount     = options.Options.DefaultMaxSnapshotCount - options.Options.DefaultMaxManualSnapshotCount
+			guest                 *SGuest
 			err                   error
 			snapCount             int
 			cleanOverdueSnapshots bool
 		)
 
+		if disk == nil || snapshotPolicy == nil || !snapshotPolicy.IsMatchDisk(disk) {
+			continue
+		}
+
+		if guest = disk.getConsistencyGroupGuest(snapshotPolicy); guest != nil {
+			if consistencyGroups.Has(guest.Id) {
+				continue
+			}
+			consistencyGroups.Insert(guest.Id)
+			if err = guest.CreateInstanceSnapshotAuto(ctx, userCred, snapshotName, snapshotPolicy); err != nil {
+				goto onFail
+			}
+			if err = guest.CleanOverdueAutoInstanceSnapshots(ctx, userCred, snapshotPolicy, now); err != nil {
+				log.Errorf("guest %s clean overdue auto instance snapshots: %v", guest.Name, err)
+			}
+			db.OpsLog.LogEvent(guest, db.ACT_DISK_AUTO_SNAPSHOT, "guest auto instance snapshot "+snapshotName, userCred)
+			snapshotPolicy.ExecuteNotify(ctx, userCred, guest.GetName())
+			continue
+		}
+
 		if err = disk.validateDiskAutoCreateSnapshot(); err != nil {
 			goto onFail
 		}
@@ -2467,7 +2552,7 @@ func (manager *SDiskManager) AutoDiskSnapshot(ctx context.Context, userCred mccl
 			goto onFail
 		}
 		// if auto snapshot count gt max auto snapshot count, do clean overdued snapshots
-		cleanOverdueSnapshots = snapCount > autoSnapshotCount
+		cleanOverdueSnapshots = snapCount > autoSnapshotCount || snapshotPolicy.IsRetainedByCount()
 		if cleanOverdueSnapshots {
 			disk.CleanOverdueSnapshots(ctx, userCred, snapshotPolicy, now)
 		}
@@ -2481,6 +2566,19 @@ func (manager *SDiskManager) AutoDiskSnapshot(ctx context.Context, userCred mccl
 	}
 }
 
+// getConsistencyGroupGuest returns the guest whose disks are snapshotted
+// together instead of disk alone, nil if instance snapshot is not available
+func (self *SDisk) getConsistencyGroupGuest(snapshotPolicy *SSnapshotPolicy) *SGuest {
+	if !snapshotPolicy.ConsistencyGroup {
+		return nil
+	}
+	guests := self.GetGuests()
+	if len(guests) != 1 || !utils.IsInStringArray(guests[0].Hypervisor, HypervisorIndependentInstanceSnapshot) {
+		return nil
+	}
+	return &guests[0]
+}
+
 func (self *SDisk) CreateSnapshotAuto(
 	ctx context.Context, userCred mcclient.TokenCredential,
 	snapshotName string, snapshotPolicy *SSnapshotPolicy,
@@ -2492,7 +2590,11 @@ func (self *SDisk) CreateSnapshotAuto(
 	}
 
 	db.OpsLog.LogEvent(snap, db.ACT_CREATE, "disk create snapshot auto", userCred)
-	err = snap.StartSnapshotCreateTask(ctx, userCred, nil, "")
+	params := jsonutils.NewDict()
+	if len(snapshotPolicy.BackupStorageId) > 0 {
+		params.Set("backup_storage_id", jsonutils.NewString(snapshotPolicy.BackupStorageId))
+	}
+	err = snap.StartSnapshotCreateTask(ctx, userCred, params, "")
 	if err != nil {
 		return errors.Wrap(err, "disk auto snapshot start snapshot task")
 	}
@@ -2866,6 +2968,9 @@ func (disk *SDisk) PerformBindSnapshotpolicy(ctx context.Context, userCred mccli
 		return nil, errors.Wrap(err, "db.FetchByIdOrName")
 	}
 	snapshotpolicy := imodel.(*SSnapshotPolicy)
+	if err := snapshotpolicy.ValidateBindDisk(disk); err != nil {
+		return nil, err
+	}
 
 	// try to bind
 	spd, err := SnapshotPolicyDiskManager.newSnapshotpolicyDisk(ctx, userCred, snapshotpolicy, disk)
@@ -0,0 +1,204 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/sets"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+func (group *SGroup) GetPolicy() string {
+	if len(group.Policy) == 0 {
+		return api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY
+	}
+	return group.Policy
+}
+
+func (group *SGroup) GetTopologyKey() string {
+	if len(group.TopologyKey) == 0 {
+		return api.INSTANCE_GROUP_TOPOLOGY_HOST
+	}
+	return group.TopologyKey
+}
+
+// IsHostAntiAffinity tells whether the group is the classic one which only
+// limits the number of guests in a host by granularity
+func (group *SGroup) IsHostAntiAffinity() bool {
+	return group.GetPolicy() == api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY && group.GetTopologyKey() == api.INSTANCE_GROUP_TOPOLOGY_HOST
+}
+
+// IsForced tells whether the policy of group is a hard constraint
+func (group *SGroup) IsForced() bool {
+	return !group.ForceDispersion.IsFalse()
+}
+
+func (group *SGroup) GetMaxSkew() int {
+	if group.MaxSkew < 1 {
+		return 1
+	}
+	return group.MaxSkew
+}
+
+// GetTopologyDomain returns the topology domain of host, empty string means
+// the host is out of any domain, e.g. the host has no schedtag with the topology prefix
+func (group *SGroup) GetTopologyDomain(host *SHost, schedtags []SSchedtag) string {
+	switch group.GetTopologyKey() {
+	case api.INSTANCE_GROUP_TOPOLOGY_RACK:
+		if len(host.Rack) == 0 {
+			return host.Id
+		}
+		return host.ZoneId + "/" + host.Rack
+	case api.INSTANCE_GROUP_TOPOLOGY_ZONE:
+		return host.ZoneId
+	case api.INSTANCE_GROUP_TOPOLOGY_SCHEDTAG:
+		for i := range schedtags {
+			if strings.HasPrefix(schedtags[i].Name, group.TopologySchedtagPrefix) {
+				return schedtags[i].Name
+			}
+		}
+		return ""
+	default:
+		return host.Id
+	}
+}
+
+// GetGuestTopologyDomains returns the topology domains of the guests of group,
+// guests of excludeGuestIds are skipped, e.g. the guest being migrated
+func (group *SGroup) GetGuestTopologyDomains(excludeGuestIds []string) (map[string]string, error) {
+	ggSub := GroupguestManager.Query("guest_id").Equals("group_id", group.Id).SubQuery()
+	q := GuestManager.Query("id", "host_id")
+	q = q.Join(ggSub, sqlchemy.Equals(ggSub.Field("guest_id"), q.Field("id")))
+	q = q.IsNotEmpty("host_id")
+	if len(excludeGuestIds) > 0 {
+		q = q.NotIn("id", excludeGuestIds)
+	}
+	guests := []struct {
+		Id     string
+		HostId string
+	}{}
+	err := q.All(&guests)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch guests of group")
+	}
+	ret := make(map[string]string, len(guests))
+	if len(guests) == 0 {
+		return ret, nil
+	}
+	if group.GetTopologyKey() == api.INSTANCE_GROUP_TOPOLOGY_HOST {
+		for _, guest := range guests {
+			ret[guest.Id] = guest.HostId
+		}
+		return ret, nil
+	}
+
+	hostIds := sets.NewString()
+	for _, guest := range guests {
+		hostIds.Insert(guest.HostId)
+	}
+	hosts := make([]SHost, 0, hostIds.Len())
+	err = db.FetchModelObjects(HostManager, HostManager.Query().In("id", hostIds.List()), &hosts)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch hosts of group guests")
+	}
+	hostDomains := make(map[string]string, len(hosts))
+	for i := range hosts {
+		var schedtags []SSchedtag
+		if group.GetTopologyKey() == api.INSTANCE_GROUP_TOPOLOGY_SCHEDTAG {
+			schedtags = hosts[i].GetSchedtags()
+		}
+		hostDomains[hosts[i].Id] = group.GetTopologyDomain(&hosts[i], schedtags)
+	}
+	for _, guest := range guests {
+		ret[guest.Id] = hostDomains[guest.HostId]
+	}
+	return ret, nil
+}
+
+// GetTopologyGuestCounts counts guests of group in each topology domain
+func (group *SGroup) GetTopologyGuestCounts(excludeGuestIds []string) (map[string]int, error) {
+	domains, err := group.GetGuestTopologyDomains(excludeGuestIds)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]int)
+	for _, domain := range domains {
+		if len(domain) > 0 {
+			ret[domain] += 1
+		}
+	}
+	return ret, nil
+}
+
+// CheckTopologyFit checks whether a new guest of group could be placed in the
+// domain, counts are the guest counts of the candidate domains
+func (group *SGroup) CheckTopologyFit(domain string, counts map[string]int) bool {
+	count := counts[domain]
+	switch group.GetPolicy() {
+	case api.INSTANCE_GROUP_POLICY_AFFINITY:
+		if len(domain) == 0 {
+			return false
+		}
+		total := 0
+		for _, c := range counts {
+			total += c
+		}
+		return total == 0 || count > 0
+	case api.INSTANCE_GROUP_POLICY_SPREAD:
+		if len(domain) == 0 {
+			return false
+		}
+		min := count
+		for _, c := range counts {
+			if c < min {
+				min = c
+			}
+		}
+		return count+1-min <= group.GetMaxSkew()
+	default:
+		return len(domain) == 0 || count < group.Granularity
+	}
+}
+
+// GetTopologyScore scores the domain for a new guest of group in range [0, 100],
+// the higher score the domain fits the policy better
+func (group *SGroup) GetTopologyScore(domain string, counts map[string]int) int {
+	if len(domain) == 0 {
+		if group.GetPolicy() == api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY {
+			return 100
+		}
+		return 0
+	}
+	count, max := counts[domain], 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	if max == 0 {
+		return 100
+	}
+	switch group.GetPolicy() {
+	case api.INSTANCE_GROUP_POLICY_AFFINITY:
+		return count * 100 / max
+	default:
+		return (max - count) * 100 / max
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestGroupCheckTopologyFit(t *testing.T) {
+	counts := map[string]int{"z1": 2, "z2": 0, "z3": 1}
+	cases := []struct {
+		name   string
+		group  SGroup
+		domain string
+		counts map[string]int
+		want   bool
+	}{
+		{
+			name:   "affinity first guest",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_AFFINITY},
+			domain: "z2",
+			counts: map[string]int{"z1": 0, "z2": 0},
+			want:   true,
+		},
+		{
+			name:   "affinity domain with guests",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_AFFINITY},
+			domain: "z3",
+			counts: counts,
+			want:   true,
+		},
+		{
+			name:   "affinity empty domain",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_AFFINITY},
+			domain: "z2",
+			counts: counts,
+			want:   false,
+		},
+		{
+			name:   "affinity out of domains",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_AFFINITY},
+			domain: "",
+			counts: map[string]int{},
+			want:   false,
+		},
+		{
+			name:   "spread least loaded domain",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_SPREAD, MaxSkew: 1},
+			domain: "z2",
+			counts: counts,
+			want:   true,
+		},
+		{
+			name:   "spread exceeding skew",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_SPREAD, MaxSkew: 1},
+			domain: "z3",
+			counts: counts,
+			want:   false,
+		},
+		{
+			name:   "spread within larger skew",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_SPREAD, MaxSkew: 3},
+			domain: "z1",
+			counts: counts,
+			want:   true,
+		},
+		{
+			name:   "spread zero skew taken as 1",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_SPREAD},
+			domain: "z1",
+			counts: map[string]int{"z1": 1, "z2": 1},
+			want:   true,
+		},
+		{
+			name:   "anti affinity under granularity",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY, TopologyKey: api.INSTANCE_GROUP_TOPOLOGY_ZONE, Granularity: 3},
+			domain: "z1",
+			counts: counts,
+			want:   true,
+		},
+		{
+			name:   "anti affinity reaching granularity",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY, TopologyKey: api.INSTANCE_GROUP_TOPOLOGY_ZONE, Granularity: 2},
+			domain: "z1",
+			counts: counts,
+			want:   false,
+		},
+		{
+			name:   "anti affinity out of domains",
+			group:  SGroup{Policy: api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY, TopologyKey: api.INSTANCE_GROUP_TOPOLOGY_SCHEDTAG, Granularity: 1},
+			domain: "",
+			counts: counts,
+			want:   true,
+		},
+	}
+	for _, c := range cases {
+		if got := c.group.CheckTopologyFit(c.domain, c.counts); got != c.want {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestGroupGetTopologyScore(t *testing.T) {
+	counts := map[string]int{"z1": 4, "z2": 0, "z3": 1}
+	cases := []struct {
+		name   string
+		policy string
+		domain string
+		counts map[string]int
+		want   int
+	}{
+		{"affinity most loaded", api.INSTANCE_GROUP_POLICY_AFFINITY, "z1", counts, 100},
+		{"affinity less loaded", api.INSTANCE_GROUP_POLICY_AFFINITY, "z3", counts, 25},
+		{"affinity empty", api.INSTANCE_GROUP_POLICY_AFFINITY, "z2", counts, 0},
+		{"affinity out of domains", api.INSTANCE_GROUP_POLICY_AFFINITY, "", counts, 0},
+		{"spread empty", api.INSTANCE_GROUP_POLICY_SPREAD, "z2", counts, 100},
+		{"spread less loaded", api.INSTANCE_GROUP_POLICY_SPREAD, "z3", counts, 75},
+		{"spread most loaded", api.INSTANCE_GROUP_POLICY_SPREAD, "z1", counts, 0},
+		{"spread out of domains", api.INSTANCE_GROUP_POLICY_SPREAD, "", counts, 0},
+		{"anti affinity less loaded", api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY, "z3", counts, 75},
+		{"anti affinity out of domains", api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY, "", counts, 100},
+		{"no guests yet", api.INSTANCE_GROUP_POLICY_AFFINITY, "z1", map[string]int{"z1": 0}, 100},
+	}
+	for _, c := range cases {
+		group := SGroup{Policy: c.policy}
+		if got := group.GetTopologyScore(c.domain, c.counts); got != c.want {
+			t.Errorf("%s: want %d, got %d", c.name, c.want, got)
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"yunion.io/x/pkg/tristate"
 	"yunion.io/x/pkg/util/netutils"
 	"yunion.io/x/pkg/util/sets"
+	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
 	"yunion.io/x/onecloud/pkg/apis"
@@ -86,8 +87,20 @@ type SGroup struct {
 	SchedStrategy string `width:"16" charset:"ascii" nullable:"true" default:"" list:"user" update:"user" create:"optional"`
 
 	// the upper limit number of guests with this group in a host
-	Granularity     int               `nullable:"false" list:"user" get:"user" create:"optional" update:"user" default:"1"`
+	Granularity int `nullable:"false" list:"user" get:"user" create:"optional" update:"user" default:"1"`
+	// 是否为硬约束, 为false时仅作为调度的优选条件
 	ForceDispersion tristate.TriState `list:"user" get:"user" create:"optional" update:"user" default:"true"`
+
+	// 亲和策略
+	Policy string `width:"16" charset:"ascii" nullable:"false" default:"anti_affinity" list:"user" get:"user" create:"optional" update:"user"`
+	// 拓扑层级
+	TopologyKey string `width:"16" charset:"ascii" nullable:"false" default:"host" list:"user" get:"user" create:"optional" update:"user"`
+	// topology_key为schedtag时, 以此为前缀的调度标签划分拓扑域
+	TopologySchedtagPrefix string `width:"64" charset:"utf8" nullable:"true" list:"user" get:"user" create:"optional" update:"user"`
+	// spread策略下各拓扑域实例数量允许的最大差值
+	MaxSkew int `nullable:"false" default:"1" list:"user" get:"user" create:"optional" update:"user"`
+	// 软约束的权重
+	Weight int `nullable:"false" default:"1" list:"user" get:"user" create:"optional" update:"user"`
 	// 是否启用
 	// Enabled tristate.TriState `nullable:"false" default:"true" create:"optional" list:"user" update:"user"`
 }
@@ -133,6 +146,12 @@ func (sm *SGroupManager) ListItemFilter(
 	if len(input.SchedStrategy) > 0 {
 		q = q.Equals("sched_strategy", input.SchedStrategy)
 	}
+	if len(input.Policy) > 0 {
+		q = q.In("policy", input.Policy)
+	}
+	if len(input.TopologyKey) > 0 {
+		q = q.In("topology_key", input.TopologyKey)
+	}
 
 	return q, nil
 }
@@ -206,6 +225,102 @@ func (sm *SGroupManager) FetchCustomizeColumns(
 	return rows
 }
 
+func (sm *SGroupManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	input := apis.VirtualResourceCreateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("unmarshal VirtualResourceCreateInput fail %s", err)
+	}
+	input, err = sm.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input)
+	if err != nil {
+		return nil, err
+	}
+	data.Update(jsonutils.Marshal(input))
+
+	conf := api.SGroup{
+		Granularity: 1,
+		Policy:      api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY,
+		TopologyKey: api.INSTANCE_GROUP_TOPOLOGY_HOST,
+		MaxSkew:     1,
+		Weight:      1,
+	}
+	data.Unmarshal(&conf)
+	if err := validateGroupPolicy(&conf); err != nil {
+		return nil, err
+	}
+	data.Update(jsonutils.Marshal(map[string]interface{}{
+		"policy":                   conf.Policy,
+		"topology_key":             conf.TopologyKey,
+		"topology_schedtag_prefix": conf.TopologySchedtagPrefix,
+	}))
+	return data, nil
+}
+
+func (group *SGroup) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	input := apis.VirtualResourceBaseUpdateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("unmarshal VirtualResourceBaseUpdateInput fail %s", err)
+	}
+	input, err = group.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input)
+	if err != nil {
+		return nil, err
+	}
+	data.Update(jsonutils.Marshal(input))
+
+	conf := api.SGroup{
+		Granularity:            group.Granularity,
+		Policy:                 group.Policy,
+		TopologyKey:            group.TopologyKey,
+		TopologySchedtagPrefix: group.TopologySchedtagPrefix,
+		MaxSkew:                group.MaxSkew,
+		Weight:                 group.Weight,
+	}
+	data.Unmarshal(&conf)
+	if err := validateGroupPolicy(&conf); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func validateGroupPolicy(conf *api.SGroup) error {
+	if len(conf.Policy) == 0 {
+		conf.Policy = api.INSTANCE_GROUP_POLICY_ANTI_AFFINITY
+	}
+	if !utils.IsInStringArray(conf.Policy, api.INSTANCE_GROUP_POLICIES) {
+		return httperrors.NewInputParameterError("invalid policy %q, must be one of %s", conf.Policy, api.INSTANCE_GROUP_POLICIES)
+	}
+	if len(conf.TopologyKey) == 0 {
+		conf.TopologyKey = api.INSTANCE_GROUP_TOPOLOGY_HOST
+	}
+	if !utils.IsInStringArray(conf.TopologyKey, api.INSTANCE_GROUP_TOPOLOGY_KEYS) {
+		return httperrors.NewInputParameterError("invalid topology_key %q, must be one of %s", conf.TopologyKey, api.INSTANCE_GROUP_TOPOLOGY_KEYS)
+	}
+	if conf.TopologyKey == api.INSTANCE_GROUP_TOPOLOGY_SCHEDTAG && len(conf.TopologySchedtagPrefix) == 0 {
+		return httperrors.NewMissingParameterError("topology_schedtag_prefix")
+	}
+	if conf.Granularity < 1 {
+		return httperrors.NewInputParameterError("granularity must be greater than 0")
+	}
+	if conf.MaxSkew < 1 {
+		return httperrors.NewInputParameterError("max_skew must be greater than 0")
+	}
+	if conf.Weight < 0 {
+		return httperrors.NewInputParameterError("weight must not be negative")
+	}
+	return nil
+}
+
+func (group *SGroup) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	group.SVirtualResourceBase.PostUpdate(ctx, userCred, query, data)
+
+	// the group placed in the host desc cache of scheduler is out of date
+	err := group.ClearAllScheDescCache()
+	if err != nil {
+		log.Errorf("fail to clear scheduler desc cache after updating group %s: %s", group.Name, err.Error())
+	}
+}
+
 func (group *SGroup) GetGuestCount() int {
 	q := GroupguestManager.Query().Equals("group_id", group.Id)
 	count, _ := q.CountWithError()
@@ -1237,8 +1237,12 @@ func (self *SGuest) PerformAddSecgroup(ctx context.Context, userCred mcclient.To
 	return nil, self.StartSyncTask(ctx, userCred, true, "")
 }
 
-func (self *SGuest) saveDefaultSecgroupId(userCred mcclient.TokenCredential, secGrpId string, isAdmin bool) error {
+func (self *SGuest) saveDefaultSecgroupId(ctx context.Context, userCred mcclient.TokenCredential, secGrpId string, isAdmin bool) error {
 	if (!isAdmin && secGrpId != self.SecgrpId) || (isAdmin && secGrpId != self.AdminSecgrpId) {
+		changed := []string{secGrpId, self.SecgrpId}
+		if isAdmin {
+			changed = []string{secGrpId, self.AdminSecgrpId}
+		}
 		diff, err := db.Update(self, func() error {
 			if isAdmin {
 				self.AdminSecgrpId = secGrpId
@@ -1251,6 +1255,7 @@ func (self *SGuest) saveDefaultSecgroupId(userCred mcclient.TokenCredential, sec
 			return errors.Wrap(err, "db.Update")
 		}
 		db.OpsLog.LogEvent(self, db.ACT_UPDATE, diff, userCred)
+		SecurityGroupManager.syncPeerReferrers(ctx, userCred, changed)
 	}
 	return nil
 }
@@ -1327,7 +1332,7 @@ func (self *SGuest) PerformRevokeAdminSecgroup(ctx context.Context, userCred mcc
 		notes = "clean admin secgroup"
 	}
 
-	err := self.saveDefaultSecgroupId(userCred, adminSecgrpId, true)
+	err := self.saveDefaultSecgroupId(ctx, userCred, adminSecgrpId, true)
 	if err != nil {
 		return nil, errors.Wrap(err, "saveDefaultSecgroupId")
 	}
@@ -1368,7 +1373,7 @@ func (self *SGuest) performAssignSecgroup(ctx context.Context, userCred mcclient
 		return nil, httperrors.NewInputParameterError("The secgroup name %s does not meet the requirements, please change the name", secObj.GetName())
 	}
 
-	err = self.saveDefaultSecgroupId(userCred, input.SecgroupId, isAdmin)
+	err = self.saveDefaultSecgroupId(ctx, userCred, input.SecgroupId, isAdmin)
 	if err != nil {
 		return nil, err
 	}
@@ -1456,6 +1461,7 @@ func (self *SGuest) saveSecgroups(ctx context.Context, userCred mcclient.TokenCr
 	for i := 1; i < len(secgroupIds); i++ {
 		newIds.Add(secgroupIds[i])
 	}
+	changed := []string{}
 	for _, removed := range set.Difference(oldIds, newIds).List() {
 		id := removed.(string)
 		gs, ok := secgroupMaps[id]
@@ -1464,6 +1470,7 @@ func (self *SGuest) saveSecgroups(ctx context.Context, userCred mcclient.TokenCr
 			if err != nil {
 				return errors.Wrapf(err, "Delete guest secgroup for guest %s secgroup %s", self.Name, id)
 			}
+			changed = append(changed, id)
 		}
 	}
 	for _, added := range set.Difference(newIds, oldIds).List() {
@@ -1472,8 +1479,10 @@ func (self *SGuest) saveSecgroups(ctx context.Context, userCred mcclient.TokenCr
 		if err != nil {
 			return errors.Wrapf(err, "New guest secgroup for guest %s with secgroup %s", self.Name, id)
 		}
+		changed = append(changed, id)
 	}
-	return self.saveDefaultSecgroupId(userCred, secgroupIds[0], false)
+	SecurityGroupManager.syncPeerReferrers(ctx, userCred, changed)
+	return self.saveDefaultSecgroupId(ctx, userCred, secgroupIds[0], false)
 }
 
 func (self *SGuest) newGuestSecgroup(ctx context.Context, secgroupId string) error {
@@ -1528,6 +1537,10 @@ func (self *SGuest) PerformRebuildRoot(ctx context.Context, userCred mcclient.To
 		if err != nil {
 			return nil, err
 		}
+		err = validateImageSignaturePolicy(ctx, userCred, self.GetOwnerId(), self.Hypervisor, img.Id)
+		if err != nil {
+			return nil, err
+		}
 
 		// compare os arch
 		if len(self.InstanceType) > 0 {
@@ -1715,6 +1728,11 @@ func (self *SGuest) PerformCreatedisk(ctx context.Context, userCred mcclient.Tok
 		}
 		diskSize += diskInfo.SizeMb
 	}
+	err = validateDisksImageSignaturePolicy(ctx, userCred, self.GetOwnerId(), self.Hypervisor, disksConf)
+	if err != nil {
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_CREATE, err.Error(), userCred, false)
+		return nil, err
+	}
 	host, _ := self.GetHost()
 	if host == nil {
 		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_CREATE, "No valid host", userCred, false)
@@ -2216,6 +2234,7 @@ func (self *SGuest) PerformChangeIpaddr(ctx context.Context, userCred mcclient.T
 		}); err != nil {
 			return nil, err
 		}
+		self.syncPeerSecgroupReferrers(ctx, userCred)
 
 		return ngn, nil
 	}()
@@ -2291,12 +2310,17 @@ func (self *SGuest) PerformDetachnetwork(ctx context.Context, userCred mcclient.
 	}
 
 	if self.Status == api.VM_READY {
-		return nil, self.detachNetworks(ctx, userCred, gns, input.Reserve, true)
+		err = self.detachNetworks(ctx, userCred, gns, input.Reserve, true)
+		if err == nil {
+			self.syncPeerSecgroupReferrers(ctx, userCred)
+		}
+		return nil, err
 	}
 	err = self.detachNetworks(ctx, userCred, gns, input.Reserve, false)
 	if err != nil {
 		return nil, err
 	}
+	self.syncPeerSecgroupReferrers(ctx, userCred)
 	return nil, self.StartSyncTask(ctx, userCred, false, "")
 }
 
@@ -2348,6 +2372,7 @@ func (self *SGuest) PerformAttachnetwork(ctx context.Context, userCred mcclient.
 			return nil, httperrors.NewBadRequestError("%v", err)
 		}
 	}
+	self.syncPeerSecgroupReferrers(ctx, userCred)
 
 	if self.Status == api.VM_READY {
 		err = self.StartGuestDeployTask(ctx, userCred, nil, "deploy", "")
@@ -2679,13 +2704,16 @@ func (self *SGuest) RevokeAllSecgroups(ctx context.Context, userCred mcclient.To
 	if err != nil {
 		return errors.Wrapf(err, "GetGuestSecgroups")
 	}
+	changed := []string{}
 	for i := range gss {
 		err = gss[i].Delete(ctx, userCred)
 		if err != nil {
 			return errors.Wrap(err, "Delete")
 		}
+		changed = append(changed, gss[i].SecgroupId)
 	}
-	return self.saveDefaultSecgroupId(userCred, options.Options.DefaultSecurityGroupId, false)
+	SecurityGroupManager.syncPeerReferrers(ctx, userCred, changed)
+	return self.saveDefaultSecgroupId(ctx, userCred, options.Options.DefaultSecurityGroupId, false)
 }
 
 func (self *SGuest) DoPendingDelete(ctx context.Context, userCred mcclient.TokenCredential) {
@@ -3157,6 +3185,40 @@ func (self *SGuest) PerformUserData(ctx context.Context, userCred mcclient.Token
 	return nil, nil
 }
 
+// 设置虚拟机元数据服务选项
+func (self *SGuest) PerformSetMetadataOptions(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetMetadataOptionsInput) (jsonutils.JSONObject, error) {
+	if self.GetHypervisor() != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewUnsupportOperationError("metadata options are only supported by %s", api.HYPERVISOR_KVM)
+	}
+	meta := map[string]interface{}{}
+	if len(input.HttpTokens) > 0 {
+		if !utils.IsInStringArray(input.HttpTokens, api.METADATA_HTTP_TOKENS) {
+			return nil, httperrors.NewInputParameterError("invalid http_tokens %s", input.HttpTokens)
+		}
+		meta[api.VM_METADATA_HTTP_TOKENS] = input.HttpTokens
+	}
+	if input.HttpPutResponseHopLimit != 0 {
+		if input.HttpPutResponseHopLimit < 1 || input.HttpPutResponseHopLimit > 64 {
+			return nil, httperrors.NewOutOfRangeError("http_put_response_hop_limit should be in range 1-64")
+		}
+		meta[api.VM_METADATA_HTTP_PUT_RESPONSE_HOP_LIMIT] = fmt.Sprintf("%d", input.HttpPutResponseHopLimit)
+	}
+	if input.ConfigDrive != nil {
+		meta[api.VM_METADATA_CONFIG_DRIVE] = fmt.Sprintf("%v", *input.ConfigDrive)
+	}
+	if len(meta) == 0 {
+		return nil, nil
+	}
+	err := self.SetAllMetadata(ctx, meta, userCred)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if len(self.HostId) > 0 {
+		return nil, self.StartSyncTask(ctx, userCred, false, "")
+	}
+	return nil, nil
+}
+
 func (self *SGuest) PerformSetQemuParams(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	isaSerial, err := data.GetString("disable_isa_serial")
 	if err == nil {
@@ -4764,7 +4826,7 @@ func (self *SGuest) InstaceCreateSnapshot(
 	pendingUsage *SRegionQuota,
 ) error {
 	self.SetStatus(userCred, api.VM_START_INSTANCE_SNAPSHOT, "instance snapshot")
-	return instanceSnapshot.StartCreateInstanceSnapshotTask(ctx, userCred, pendingUsage, "")
+	return instanceSnapshot.StartCreateInstanceSnapshotTask(ctx, userCred, pendingUsage, nil, "")
 }
 
 func (self *SGuest) InstanceCreateBackup(ctx context.Context, userCred mcclient.TokenCredential, instanceBackup *SInstanceBackup) error {
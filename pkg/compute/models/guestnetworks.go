@@ -247,6 +247,7 @@ type newGuestNetworkArgs struct {
 	index int8
 
 	ipAddr              string
+	ip6Addr             string
 	allocDir            api.IPAllocationDirection
 	tryReserved         bool
 	requireDesignatedIP bool
@@ -274,6 +275,7 @@ func (manager *SGuestnetworkManager) newGuestNetwork(
 		network              = args.network
 		index                = args.index
 		address              = args.ipAddr
+		address6             = args.ip6Addr
 		mac                  = args.macAddr
 		driver               = args.nicDriver
 		bwLimit              = args.bwLimit
@@ -342,6 +344,14 @@ func (manager *SGuestnetworkManager) newGuestNetwork(
 			gn.IpAddr = ipAddr
 		}
 
+		if network.IsIPv6Enabled() && provider == api.CLOUD_PROVIDER_ONECLOUD {
+			addrTable6 := network.GetUsedAddresses6()
+			gn.Ip6Addr, err = network.getFreeIP6(addrTable6, address6, gn.MacAddr)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		if vpc.Id != api.DEFAULT_VPC_ID && provider == api.CLOUD_PROVIDER_ONECLOUD {
 			var err error
 			GuestnetworkManager.lockAllocMappedAddr(ctx)
@@ -572,6 +582,13 @@ func (self *SGuestnetwork) getJsonDesc() *api.GuestnetworkJsonDesc {
 	} else {
 		desc.Ip = self.IpAddr
 	}
+	if !self.Virtual && len(self.Ip6Addr) > 0 && net.IsIPv6Enabled() {
+		desc.Ip6 = self.Ip6Addr
+		desc.Masklen6 = net.GuestIp6Mask
+		desc.Gateway6 = net.GuestGateway6
+		desc.Dns6 = net.GuestDns6
+		desc.Ip6Mode = net.getIp6Mode()
+	}
 	desc.Gateway = net.GuestGateway
 	desc.Dns = net.GetDNS()
 	desc.Domain = net.GetDomain()
@@ -978,18 +995,19 @@ func (self *SGuestnetwork) ToNetworkConfig() *api.NetworkConfig {
 	}
 	wire, _ := net.GetWire()
 	ret := &api.NetworkConfig{
-		Index:   int(self.Index),
-		Network: net.Id,
-		Wire:    wire.Id,
-		Mac:     self.MacAddr,
-		Address: self.IpAddr,
-		Driver:  self.Driver,
-		BwLimit: self.BwLimit,
-		Project: net.ProjectId,
-		Domain:  net.DomainId,
-		Ifname:  self.Ifname,
-		NetType: net.ServerType,
-		Exit:    net.IsExitNetwork(),
+		Index:    int(self.Index),
+		Network:  net.Id,
+		Wire:     wire.Id,
+		Mac:      self.MacAddr,
+		Address:  self.IpAddr,
+		Address6: self.Ip6Addr,
+		Driver:   self.Driver,
+		BwLimit:  self.BwLimit,
+		Project:  net.ProjectId,
+		Domain:   net.DomainId,
+		Ifname:   self.Ifname,
+		NetType:  net.ServerType,
+		Exit:     net.IsExitNetwork(),
 	}
 	return ret
 }
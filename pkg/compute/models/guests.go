@@ -671,7 +671,7 @@ func (manager *SGuestManager) initHostname() error {
 func (manager *SGuestManager) clearSecgroups() error {
 	guests := make([]SGuest, 0, 10)
 	q := manager.Query()
-	q = q.In("hypervisor", []string{api.HYPERVISOR_ESXI, api.HYPERVISOR_NUTANIX}).Filter(
+	q = q.In("hypervisor", []string{api.HYPERVISOR_ESXI, api.HYPERVISOR_NUTANIX, api.HYPERVISOR_PROXMOX, api.HYPERVISOR_OVIRT}).Filter(
 		sqlchemy.OR(
 			sqlchemy.IsNotEmpty(q.Field("secgrp_id")),
 			sqlchemy.IsNotEmpty(q.Field("admin_secgrp_id")),
@@ -1455,6 +1455,10 @@ func (manager *SGuestManager) validateCreateData(
 			}
 			input.Disks[i+1] = diskConfig
 		}
+		err = validateDisksImageSignaturePolicy(ctx, userCred, ownerId, hypervisor, input.Disks)
+		if err != nil {
+			return nil, err
+		}
 
 		if len(input.Duration) > 0 {
 
@@ -1606,6 +1610,9 @@ func (manager *SGuestManager) validateCreateData(
 	if err := userdata.ValidateUserdata(input.UserData); err != nil {
 		return nil, httperrors.NewInputParameterError("Invalid userdata: %v", err)
 	}
+	if len(input.MetadataHttpTokens) > 0 && !utils.IsInStringArray(input.MetadataHttpTokens, api.METADATA_HTTP_TOKENS) {
+		return nil, httperrors.NewInputParameterError("invalid metadata_http_tokens %s", input.MetadataHttpTokens)
+	}
 
 	err = manager.ValidatePolicyDefinitions(ctx, userCred, ownerId, query, input)
 	if err != nil {
@@ -1941,6 +1948,12 @@ func (guest *SGuest) PostCreate(ctx context.Context, userCred mcclient.TokenCred
 	if len(userData) > 0 {
 		guest.setUserData(ctx, userCred, userData)
 	}
+	if httpTokens, _ := data.GetString("metadata_http_tokens"); len(httpTokens) > 0 {
+		guest.SetMetadata(ctx, api.VM_METADATA_HTTP_TOKENS, httpTokens, userCred)
+	}
+	if jsonutils.QueryBoolean(data, "config_drive", false) {
+		guest.SetMetadata(ctx, api.VM_METADATA_CONFIG_DRIVE, "true", userCred)
+	}
 
 	if guest.GetDriver().GetMaxSecurityGroupCount() > 0 {
 		secgroups, _ := jsonutils.GetStringArray(data, "secgroups")
@@ -2451,11 +2464,70 @@ func (self *SGuest) getSecurityGroupsRules() string {
 	}
 	rules := []string{}
 	for _, rule := range secrules {
-		rules = append(rules, rule.String())
+		rules = append(rules, rule.kvmRuleString())
 	}
 	return strings.Join(rules, SECURITY_GROUP_SEPARATOR)
 }
 
+// GetSecurityIpsets returns addresses of guests in peer security groups
+// referred to by the rules of the guest, indexed by peer security group id.
+// Hosts keep one ipset for each of them, which rules in desc match against
+func (self *SGuest) GetSecurityIpsets() (map[string][]string, error) {
+	secgroupIds := []string{}
+	secgroups, err := self.GetSecgroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetSecgroups")
+	}
+	for _, secgroup := range secgroups {
+		secgroupIds = append(secgroupIds, secgroup.Id)
+	}
+	if len(self.AdminSecgrpId) > 0 {
+		secgroupIds = append(secgroupIds, self.AdminSecgrpId)
+	}
+	ipsets := map[string][]string{}
+	if len(secgroupIds) == 0 {
+		return ipsets, nil
+	}
+	q := SecurityGroupRuleManager.Query("peer_secgroup_id").In("secgroup_id", secgroupIds).IsNotEmpty("peer_secgroup_id").Distinct()
+	rows := []struct {
+		PeerSecgroupId string
+	}{}
+	err = q.All(&rows)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch peer secgroup ids")
+	}
+	for _, row := range rows {
+		peer, err := SecurityGroupManager.FetchSecgroupById(row.PeerSecgroupId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch peer secgroup %s", row.PeerSecgroupId)
+		}
+		addrs, err := peer.GetGuestAddresses()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetGuestAddresses of secgroup %s", peer.Id)
+		}
+		ipsets[peer.Id] = addrs
+	}
+	return ipsets, nil
+}
+
+// syncPeerSecgroupReferrers resyncs guests referring to security groups of
+// the guest as peer, for the change of its addresses
+func (self *SGuest) syncPeerSecgroupReferrers(ctx context.Context, userCred mcclient.TokenCredential) {
+	secgroupIds := []string{}
+	secgroups, err := self.GetSecgroups()
+	if err != nil {
+		log.Errorf("GetSecgroups of guest %s: %v", self.Name, err)
+		return
+	}
+	for _, secgroup := range secgroups {
+		secgroupIds = append(secgroupIds, secgroup.Id)
+	}
+	if len(self.AdminSecgrpId) > 0 {
+		secgroupIds = append(secgroupIds, self.AdminSecgrpId)
+	}
+	SecurityGroupManager.syncPeerReferrers(ctx, userCred, secgroupIds)
+}
+
 func (self *SGuest) getAdminSecurityRules() string {
 	secgrp := self.getAdminSecgroup()
 	if secgrp != nil {
@@ -2887,6 +2959,7 @@ type Attach2NetworkArgs struct {
 	Network *SNetwork
 
 	IpAddr              string
+	Ip6Addr             string
 	AllocDir            api.IPAllocationDirection
 	TryReserved         bool
 	RequireDesignatedIP bool
@@ -2909,6 +2982,7 @@ func (args *Attach2NetworkArgs) onceArgs(i int) attach2NetworkOnceArgs {
 		network: args.Network,
 
 		ipAddr:              args.IpAddr,
+		ip6Addr:             args.Ip6Addr,
 		allocDir:            args.AllocDir,
 		tryReserved:         args.TryReserved,
 		requireDesignatedIP: args.RequireDesignatedIP,
@@ -2924,6 +2998,7 @@ func (args *Attach2NetworkArgs) onceArgs(i int) attach2NetworkOnceArgs {
 	}
 	if i > 0 {
 		r.ipAddr = ""
+		r.ip6Addr = ""
 		r.bwLimit = 0
 		r.virtual = true
 		r.tryReserved = false
@@ -2939,6 +3014,7 @@ type attach2NetworkOnceArgs struct {
 	network *SNetwork
 
 	ipAddr              string
+	ip6Addr             string
 	allocDir            api.IPAllocationDirection
 	tryReserved         bool
 	requireDesignatedIP bool
@@ -3007,6 +3083,7 @@ func (self *SGuest) attach2NetworkOnce(
 		index: index,
 
 		ipAddr:              args.ipAddr,
+		ip6Addr:             args.ip6Addr,
 		allocDir:            args.allocDir,
 		tryReserved:         args.tryReserved,
 		requireDesignatedIP: args.requireDesignatedIP,
@@ -3580,6 +3657,7 @@ func (self *SGuest) CreateNetworksOnHost(
 			return errors.Wrap(err, "self.attach2NetworkDesc")
 		}
 	}
+	self.syncPeerSecgroupReferrers(ctx, userCred)
 	return nil
 }
 
@@ -3634,6 +3712,7 @@ func (self *SGuest) attach2NamedNetworkDesc(ctx context.Context, userCred mcclie
 			Network:             net,
 			PendingUsage:        pendingUsage,
 			IpAddr:              netConfig.Address,
+			Ip6Addr:             netConfig.Address6,
 			NicDriver:           netConfig.Driver,
 			BwLimit:             netConfig.BwLimit,
 			Virtual:             netConfig.Vip,
@@ -3934,7 +4013,12 @@ func (self *SGuest) DetachAllNetworks(ctx context.Context, userCred mcclient.Tok
 	if err != nil {
 		return err
 	}
-	return GuestnetworkManager.DeleteGuestNics(ctx, userCred, gns, false)
+	err = GuestnetworkManager.DeleteGuestNics(ctx, userCred, gns, false)
+	if err != nil {
+		return err
+	}
+	self.syncPeerSecgroupReferrers(ctx, userCred)
+	return nil
 }
 
 func (self *SGuest) EjectIso(userCred mcclient.TokenCredential) bool {
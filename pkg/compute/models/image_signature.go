@@ -0,0 +1,117 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	imageapi "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// GetImageSignaturePolicy resolves the image signature policy of the owner,
+// project metadata overrides domain metadata, which overrides the default
+func GetImageSignaturePolicy(ctx context.Context, ownerId mcclient.IIdentityProvider) string {
+	isValid := func(policy string) bool {
+		return utils.IsInStringArray(policy, imageapi.IMAGE_SIGNATURE_POLICIES)
+	}
+	if ownerId != nil && len(ownerId.GetProjectId()) > 0 {
+		project, _ := db.TenantCacheManager.FetchTenantById(ctx, ownerId.GetProjectId())
+		if project != nil {
+			if policy := project.GetMetadata(ctx, imageapi.IMAGE_SIGNATURE_POLICY_KEY, nil); isValid(policy) {
+				return policy
+			}
+		}
+	}
+	if ownerId != nil && len(ownerId.GetProjectDomainId()) > 0 {
+		domain, _ := db.TenantCacheManager.FetchDomainById(ctx, ownerId.GetProjectDomainId())
+		if domain != nil {
+			if policy := domain.GetMetadata(ctx, imageapi.IMAGE_SIGNATURE_POLICY_KEY, nil); isValid(policy) {
+				return policy
+			}
+		}
+	}
+	if isValid(options.Options.DefaultImageSignaturePolicy) {
+		return options.Options.DefaultImageSignaturePolicy
+	}
+	return imageapi.IMAGE_SIGNATURE_POLICY_PERMISSIVE
+}
+
+// validateImageSignaturePolicy refuses to boot from a glance image whose
+// signature status does not satisfy the policy of the owner project, the
+// image is refreshed from glance as the cached signature status may be stale
+func validateImageSignaturePolicy(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, hypervisor string, imageId string) error {
+	if len(imageId) == 0 {
+		return nil
+	}
+	if driver, ok := guestDrivers[hypervisor]; ok && driver.GetProvider() != api.CLOUD_PROVIDER_ONECLOUD {
+		// signatures are only carried by glance images
+		return nil
+	}
+	policy := GetImageSignaturePolicy(ctx, ownerId)
+	if policy == imageapi.IMAGE_SIGNATURE_POLICY_PERMISSIVE {
+		return nil
+	}
+	img, err := CachedimageManager.getImageInfo(ctx, userCred, imageId, true)
+	if err != nil {
+		return errors.Wrapf(err, "refresh image %s", imageId)
+	}
+	return checkImageSignatureStatus(policy, imageId, img.Properties[imageapi.IMAGE_SIGNATURE_STATUS])
+}
+
+// validateDisksImageSignaturePolicy checks the images of all disks created from an image
+func validateDisksImageSignaturePolicy(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, hypervisor string, disks []*api.DiskConfig) error {
+	checked := map[string]bool{}
+	for _, disk := range disks {
+		if disk == nil || len(disk.ImageId) == 0 || checked[disk.ImageId] {
+			continue
+		}
+		checked[disk.ImageId] = true
+		if err := validateImageSignaturePolicy(ctx, userCred, ownerId, hypervisor, disk.ImageId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkImageSignatureStatus(policy string, imageId string, status string) error {
+	if len(status) == 0 {
+		status = imageapi.IMAGE_SIGNATURE_STATUS_UNSIGNED
+	}
+	switch policy {
+	case imageapi.IMAGE_SIGNATURE_POLICY_REQUIRE_SIGNED:
+		if status == imageapi.IMAGE_SIGNATURE_STATUS_UNSIGNED {
+			return httperrors.NewForbiddenError("image %s is not signed", imageId)
+		}
+		if status == imageapi.IMAGE_SIGNATURE_STATUS_INVALID {
+			return httperrors.NewForbiddenError("image %s has an invalid signature", imageId)
+		}
+		if status == imageapi.IMAGE_SIGNATURE_STATUS_UNVERIFIED {
+			return httperrors.NewForbiddenError("image %s has a signature failed to be verified", imageId)
+		}
+	case imageapi.IMAGE_SIGNATURE_POLICY_REQUIRE_TRUSTED:
+		if status != imageapi.IMAGE_SIGNATURE_STATUS_VERIFIED {
+			return httperrors.NewForbiddenError("image %s is not signed by a trusted signer: %s", imageId, status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	imageapi "yunion.io/x/onecloud/pkg/apis/image"
+)
+
+func TestCheckImageSignatureStatus(t *testing.T) {
+	statuses := []string{
+		"",
+		imageapi.IMAGE_SIGNATURE_STATUS_UNSIGNED,
+		imageapi.IMAGE_SIGNATURE_STATUS_UNVERIFIED,
+		imageapi.IMAGE_SIGNATURE_STATUS_INVALID,
+		imageapi.IMAGE_SIGNATURE_STATUS_UNTRUSTED,
+		imageapi.IMAGE_SIGNATURE_STATUS_VERIFIED,
+	}
+	allowed := map[string][]bool{
+		imageapi.IMAGE_SIGNATURE_POLICY_PERMISSIVE:      {true, true, true, true, true, true},
+		imageapi.IMAGE_SIGNATURE_POLICY_REQUIRE_SIGNED:  {false, false, false, false, true, true},
+		imageapi.IMAGE_SIGNATURE_POLICY_REQUIRE_TRUSTED: {false, false, false, false, false, true},
+	}
+	for policy, want := range allowed {
+		for i, status := range statuses {
+			err := checkImageSignatureStatus(policy, "img1", status)
+			if (err == nil) != want[i] {
+				t.Errorf("policy %s status %q: want allowed %v, got %v", policy, status, want[i], err)
+			}
+		}
+	}
+}
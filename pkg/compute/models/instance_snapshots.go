@@ -17,6 +17,7 @@ package models
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -277,10 +278,11 @@ func (self *SInstanceSnapshot) StartCreateInstanceSnapshotTask(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
 	pendingUsage quotas.IQuota,
+	params *jsonutils.JSONDict,
 	parentTaskId string,
 ) error {
 	if task, err := taskman.TaskManager.NewTask(
-		ctx, "InstanceSnapshotCreateTask", self, userCred, nil, parentTaskId, "", pendingUsage); err != nil {
+		ctx, "InstanceSnapshotCreateTask", self, userCred, params, parentTaskId, "", pendingUsage); err != nil {
 		return err
 	} else {
 		task.ScheduleRun(nil)
@@ -376,6 +378,89 @@ func (manager *SInstanceSnapshotManager) CreateInstanceSnapshot(ctx context.Cont
 	return instanceSnapshot, nil
 }
 
+// CreateInstanceSnapshotAuto snapshots all disks of guest together for the
+// consistency group of snapshot policy, guest filesystems are frozen while
+// snapshotting if guest is running
+func (self *SGuest) CreateInstanceSnapshotAuto(ctx context.Context, userCred mcclient.TokenCredential, name string, sp *SSnapshotPolicy) error {
+	if len(self.BackupHostId) > 0 {
+		return errors.Errorf("Can't do instance snapshot with backup guest")
+	}
+	if !utils.IsInStringArray(self.Status, []string{api.VM_RUNNING, api.VM_READY}) {
+		return errors.Errorf("Guest(%s) in status(%s) cannot do instance snapshot", self.Id, self.Status)
+	}
+	isp, err := InstanceSnapshotManager.CreateInstanceSnapshot(ctx, userCred, self, name, false)
+	if err != nil {
+		return errors.Wrap(err, "CreateInstanceSnapshot")
+	}
+	err = self.Inherit(ctx, &isp.SStandaloneAnonResourceBase)
+	if err != nil {
+		return errors.Wrapf(err, "unable to inherit from guest %s to instance snapshot %s", self.GetId(), isp.GetId())
+	}
+	err = isp.SetMetadata(ctx, api.INSTANCE_SNAPSHOT_METADATA_SNAPSHOTPOLICY_ID, sp.Id, userCred)
+	if err != nil {
+		return errors.Wrap(err, "SetMetadata")
+	}
+	params := jsonutils.NewDict()
+	if self.Status == api.VM_RUNNING {
+		params.Set("fs_freeze", jsonutils.JSONTrue)
+	}
+	if len(sp.BackupStorageId) > 0 {
+		params.Set("backup_storage_id", jsonutils.NewString(sp.BackupStorageId))
+	}
+	self.SetStatus(userCred, api.VM_START_INSTANCE_SNAPSHOT, "auto instance snapshot")
+	return isp.StartCreateInstanceSnapshotTask(ctx, userCred, nil, params, "")
+}
+
+// CleanOverdueAutoInstanceSnapshots deletes instance snapshots created for
+// the consistency group of snapshot policy which are out of retention
+func (self *SGuest) CleanOverdueAutoInstanceSnapshots(ctx context.Context, userCred mcclient.TokenCredential, sp *SSnapshotPolicy, now time.Time) error {
+	q := InstanceSnapshotManager.Query().Equals("guest_id", self.Id).NotIn("status", []string{
+		api.INSTANCE_SNAPSHOT_FAILED, api.INSTANCE_SNAPSHOT_START_DELETE, api.INSTANCE_SNAPSHOT_DELETE_FAILED,
+	}).Desc("created_at")
+	isps := make([]SInstanceSnapshot, 0)
+	err := db.FetchModelObjects(InstanceSnapshotManager, q, &isps)
+	if err != nil {
+		return errors.Wrap(err, "FetchModelObjects")
+	}
+	autoIsps := make([]*SInstanceSnapshot, 0, len(isps))
+	for i := range isps {
+		if isps[i].GetMetadata(ctx, api.INSTANCE_SNAPSHOT_METADATA_SNAPSHOTPOLICY_ID, nil) == sp.Id {
+			autoIsps = append(autoIsps, &isps[i])
+		}
+	}
+	overdue := make([]*SInstanceSnapshot, 0)
+	if sp.IsRetainedByCount() {
+		createdAt := make([]time.Time, len(autoIsps))
+		for i := range autoIsps {
+			createdAt[i] = autoIsps[i].CreatedAt.Local()
+		}
+		keep := sp.RetainSnapshots(createdAt)
+		for i := range autoIsps {
+			if !keep[i] {
+				overdue = append(overdue, autoIsps[i])
+			}
+		}
+	} else if sp.RetentionDays > 0 {
+		deadline := now.AddDate(0, 0, -1*sp.RetentionDays)
+		for i := range autoIsps {
+			if autoIsps[i].CreatedAt.Before(deadline) {
+				overdue = append(overdue, autoIsps[i])
+			}
+		}
+	}
+	for _, isp := range overdue {
+		// instance snapshot in use by servers created from it can't be deleted
+		if isp.Status != api.INSTANCE_SNAPSHOT_READY || isp.RefCount > 0 {
+			continue
+		}
+		err := isp.StartInstanceSnapshotDeleteTask(ctx, userCred, "")
+		if err != nil {
+			return errors.Wrapf(err, "delete instance snapshot %s", isp.Name)
+		}
+	}
+	return nil
+}
+
 var HypervisorIndependentInstanceSnapshot = []string{
 	api.HYPERVISOR_KVM,
 }
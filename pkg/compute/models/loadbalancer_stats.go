@@ -0,0 +1,133 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/influxdb"
+)
+
+// GetDetailsStats returns the latest listener and backend stats pushed by
+// lbagent, e.g. connections, request rate, 4xx/5xx responses, latency and
+// health state of backends
+func (lblis *SLoadbalancerListener) GetDetailsStats(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	ret := jsonutils.NewDict()
+	ret.Set("listener", jsonutils.NewDict())
+	ret.Set("backends", jsonutils.NewArray())
+	if lblis.GetCloudprovider() != nil {
+		return ret, nil
+	}
+
+	dbinst, dbName, err := lbGetInfluxdbByLbId(lblis.LoadbalancerId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "find influxdb for loadbalancer %s", lblis.LoadbalancerId)
+	}
+	listenerStats, err := lbQueryLatestStats(dbinst, dbName, api.LB_STATS_MEASUREMENT_LISTENER, lblis.Id, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "query listener stats")
+	}
+	if len(listenerStats) > 0 {
+		ret.Set("listener", listenerStats[0])
+	}
+	backendStats, err := lbQueryLatestStats(dbinst, dbName, api.LB_STATS_MEASUREMENT_BACKEND, lblis.Id, "backend_id")
+	if err != nil {
+		return nil, errors.Wrap(err, "query backend stats")
+	}
+	if len(backendStats) == 0 {
+		return ret, nil
+	}
+
+	ids := make([]string, 0, len(backendStats))
+	for _, stats := range backendStats {
+		id, _ := stats.GetString("backend_id")
+		ids = append(ids, id)
+	}
+	backends := []SLoadbalancerBackend{}
+	q := LoadbalancerBackendManager.Query().In("id", ids)
+	if err := db.FetchModelObjects(LoadbalancerBackendManager, q, &backends); err != nil {
+		return nil, errors.Wrap(err, "fetch backends")
+	}
+	backendMap := map[string]*SLoadbalancerBackend{}
+	for i := range backends {
+		backendMap[backends[i].Id] = &backends[i]
+	}
+	backendJsons := make([]jsonutils.JSONObject, 0, len(backendStats))
+	for _, stats := range backendStats {
+		id, _ := stats.GetString("backend_id")
+		backend, ok := backendMap[id]
+		if !ok {
+			// removed since
+			continue
+		}
+		stats.Set("id", jsonutils.NewString(backend.Id))
+		stats.Set("name", jsonutils.NewString(backend.Name))
+		stats.Set("backend_type", jsonutils.NewString(backend.BackendType))
+		stats.Set("backend_group_id", jsonutils.NewString(backend.BackendGroupId))
+		backendJsons = append(backendJsons, stats)
+	}
+	ret.Set("backends", jsonutils.NewArray(backendJsons...))
+	return ret, nil
+}
+
+// lbQueryLatestStats returns the latest point in recent 5 minutes of
+// listener, one for each value of groupBy tag if it's not empty
+func lbQueryLatestStats(dbinst *influxdb.SInfluxdb, dbName, measurement, listenerId, groupBy string) ([]*jsonutils.JSONDict, error) {
+	querySql := fmt.Sprintf("select * from %s..%s where listener_id = '%s' and time > now() - 5m", dbName, measurement, listenerId)
+	if groupBy != "" {
+		querySql += fmt.Sprintf(" group by %s", groupBy)
+	}
+	querySql += " order by time desc limit 1"
+	queryRes, err := dbinst.Query(querySql)
+	if err != nil {
+		return nil, errors.Wrap(err, "query influxdb")
+	}
+	if len(queryRes) != 1 {
+		return nil, fmt.Errorf("query influxdb: expecting 1 set of results, got %d", len(queryRes))
+	}
+	ret := []*jsonutils.JSONDict{}
+	for _, resSeries := range queryRes[0] {
+		if len(resSeries.Values) == 0 {
+			continue
+		}
+		stats := jsonutils.NewDict()
+		if resSeries.Tags != nil {
+			stats.Update(resSeries.Tags)
+		}
+		resColumns := resSeries.Values[0]
+		for j, colName := range resSeries.Columns {
+			if j >= len(resColumns) {
+				break
+			}
+			colVal := resColumns[j]
+			if colVal == nil {
+				colVal = jsonutils.JSONNull
+			}
+			if colName == "time" {
+				colName = "stats_time"
+			}
+			stats.Set(colName, colVal)
+		}
+		ret = append(ret, stats)
+	}
+	return ret, nil
+}
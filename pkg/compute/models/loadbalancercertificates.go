@@ -26,6 +26,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/regutils"
 	"yunion.io/x/sqlchemy"
 
 	"yunion.io/x/onecloud/pkg/apis"
@@ -63,7 +64,6 @@ func init() {
 
 // TODO
 //
-//  - notify users of cert expiration
 //  - ca info: self-signed, public ca
 type SLoadbalancerCertificate struct {
 	db.SSharableVirtualResourceBase
@@ -73,6 +73,20 @@ type SLoadbalancerCertificate struct {
 	// SCloudregionResourceBase
 
 	db.SCertificateResourceBase
+
+	// 证书类型 upload|acme
+	CertificateType string `width:"16" charset:"ascii" nullable:"false" default:"upload" list:"user" create:"optional"`
+
+	// ACME证书域名，逗号分隔
+	AcmeDomains string `width:"1024" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// ACME验证方式 http-01|dns-01
+	AcmeChallenge string `width:"16" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// ACME服务地址
+	AcmeDirectoryUrl string `width:"256" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// ACME账号联系邮箱
+	AcmeEmail string `width:"256" charset:"ascii" nullable:"true" list:"user" create:"optional" update:"user"`
+	// ACME账号私钥
+	AcmeAccountKey string `type:"text" nullable:"true" list:"admin"`
 }
 
 func (lbcert *SLoadbalancerCertificate) GetCachedCerts() ([]SCachedLoadbalancerCertificate, error) {
@@ -99,6 +113,13 @@ func (lbcert *SLoadbalancerCertificate) ValidateUpdateData(ctx context.Context,
 		updateData.Set("description", jsonutils.NewString(desc))
 	}
 
+	if email, err := data.GetString("acme_email"); err == nil && lbcert.IsAcme() {
+		if email != "" && !regutils.MatchEmail(email) {
+			return nil, httperrors.NewInputParameterError("invalid acme_email %s", email)
+		}
+		updateData.Set("acme_email", jsonutils.NewString(email))
+	}
+
 	input := apis.SharableVirtualResourceBaseUpdateInput{}
 	err := updateData.Unmarshal(&input)
 	if err != nil {
@@ -115,6 +136,10 @@ func (lbcert *SLoadbalancerCertificate) ValidateUpdateData(ctx context.Context,
 
 func (lbcert *SLoadbalancerCertificate) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	lbcert.SSharableVirtualResourceBase.PostCreate(ctx, userCred, ownerProjId, query, data)
+	if lbcert.IsAcme() {
+		lbcert.SetStatus(userCred, api.LB_CERT_STATUS_ACME_PENDING, "")
+		return
+	}
 	lbcert.SetStatus(userCred, api.LB_STATUS_ENABLED, "")
 }
 
@@ -275,6 +300,9 @@ func (man *SLoadbalancerCertificateManager) ListItemFilter(
 	if len(query.SubjectAlternativeNames) > 0 {
 		q = q.In("subject_alternative_names", query.SubjectAlternativeNames)
 	}
+	if len(query.CertificateType) > 0 {
+		q = q.In("certificate_type", query.CertificateType)
+	}
 
 	return q, nil
 }
@@ -307,11 +335,22 @@ func (man *SLoadbalancerCertificateManager) QueryDistinctExtraField(q *sqlchemy.
 }
 
 func (man *SLoadbalancerCertificateManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
-	v := validators.NewCertKeyValidator("certificate", "private_key")
-	if err := v.Validate(data); err != nil {
+	certTypeV := validators.NewStringChoicesValidator("certificate_type", api.LB_CERT_TYPES)
+	certTypeV.Default(api.LB_CERT_TYPE_UPLOAD)
+	if err := certTypeV.Validate(data); err != nil {
 		return nil, err
 	}
-	data = v.UpdateCertKeyInfo(ctx, data)
+	if certTypeV.Value == api.LB_CERT_TYPE_ACME {
+		if err := man.validateAcmeCreateData(ctx, userCred, ownerId, data); err != nil {
+			return nil, err
+		}
+	} else {
+		v := validators.NewCertKeyValidator("certificate", "private_key")
+		if err := v.Validate(data); err != nil {
+			return nil, err
+		}
+		data = v.UpdateCertKeyInfo(ctx, data)
+	}
 
 	input := apis.SharableVirtualResourceCreateInput{}
 	err := data.Unmarshal(&input)
@@ -0,0 +1,223 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/regutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+// ParseAcmeDomains splits comma or space separated domain list, with
+// duplicates removed and order kept
+func ParseAcmeDomains(s string) []string {
+	var (
+		r    []string
+		seen = map[string]struct{}{}
+	)
+	for _, domain := range strings.FieldsFunc(s, func(c rune) bool {
+		return c == ',' || c == ' '
+	}) {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		if _, ok := seen[domain]; ok {
+			continue
+		}
+		seen[domain] = struct{}{}
+		r = append(r, domain)
+	}
+	return r
+}
+
+// fetchAcmeDnsZone finds the public dns zone with the longest name that
+// domain belongs to, among those owned by or shared with ownerId
+func (man *SLoadbalancerCertificateManager) fetchAcmeDnsZone(domain string, ownerId mcclient.IIdentityProvider) (*SDnsZone, error) {
+	domain = strings.TrimPrefix(domain, "*.")
+	for name := domain; strings.Contains(name, "."); name = name[strings.Index(name, ".")+1:] {
+		q := DnsZoneManager.Query().Equals("name", name).Equals("zone_type", string(cloudprovider.PublicZone))
+		q = DnsZoneManager.FilterByOwner(q, ownerId, rbacutils.ScopeDomain)
+		zones := []SDnsZone{}
+		if err := db.FetchModelObjects(DnsZoneManager, q, &zones); err != nil {
+			return nil, errors.Wrapf(err, "fetch dns zone %s", name)
+		}
+		if len(zones) > 0 {
+			return &zones[0], nil
+		}
+	}
+	return nil, errors.Wrapf(httperrors.ErrNotFound, "no public dns zone for %s", domain)
+}
+
+func newAcmeAccountKey() (string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", errors.Wrap(err, "generate key")
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", errors.Wrap(err, "marshal key")
+	}
+	d := pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: der,
+	})
+	return string(d), nil
+}
+
+func (man *SLoadbalancerCertificateManager) validateAcmeCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, data *jsonutils.JSONDict) error {
+	challengeV := validators.NewStringChoicesValidator("acme_challenge", api.LB_ACME_CHALLENGES)
+	challengeV.Default(api.LB_ACME_CHALLENGE_HTTP01)
+	if err := challengeV.Validate(data); err != nil {
+		return err
+	}
+	challenge := challengeV.Value
+
+	domainsStr, _ := data.GetString("acme_domains")
+	domains := ParseAcmeDomains(domainsStr)
+	if len(domains) == 0 {
+		return httperrors.NewMissingParameterError("acme_domains")
+	}
+	for _, domain := range domains {
+		name := domain
+		if strings.HasPrefix(name, "*.") {
+			if challenge != api.LB_ACME_CHALLENGE_DNS01 {
+				return httperrors.NewInputParameterError("wildcard domain %s requires %s challenge", domain, api.LB_ACME_CHALLENGE_DNS01)
+			}
+			name = name[2:]
+		}
+		if !regutils.MatchDomainName(name) || !strings.Contains(name, ".") {
+			return httperrors.NewInputParameterError("invalid acme domain %s", domain)
+		}
+		if challenge == api.LB_ACME_CHALLENGE_DNS01 {
+			if _, err := man.fetchAcmeDnsZone(domain, ownerId); err != nil {
+				if errors.Cause(err) == httperrors.ErrNotFound {
+					return httperrors.NewInputParameterError("%v", err)
+				}
+				return httperrors.NewGeneralError(err)
+			}
+		}
+	}
+
+	directoryUrl, _ := data.GetString("acme_directory_url")
+	if directoryUrl == "" {
+		directoryUrl = api.LB_ACME_DIRECTORY_URL_DEFAULT
+	} else if u, err := url.Parse(directoryUrl); err != nil || u.Scheme != "https" || u.Host == "" {
+		return httperrors.NewInputParameterError("invalid acme_directory_url %s", directoryUrl)
+	}
+	if email, _ := data.GetString("acme_email"); email != "" && !regutils.MatchEmail(email) {
+		return httperrors.NewInputParameterError("invalid acme_email %s", email)
+	}
+	accountKey, err := newAcmeAccountKey()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+
+	data.Set("acme_domains", jsonutils.NewString(strings.Join(domains, ",")))
+	data.Set("acme_challenge", jsonutils.NewString(challenge))
+	data.Set("acme_directory_url", jsonutils.NewString(directoryUrl))
+	data.Set("acme_account_key", jsonutils.NewString(accountKey))
+	data.Remove("certificate")
+	data.Remove("private_key")
+	return nil
+}
+
+func (lbcert *SLoadbalancerCertificate) IsAcme() bool {
+	return lbcert.CertificateType == api.LB_CERT_TYPE_ACME
+}
+
+// PerformAcmeCertificate stores certificate issued by lbagent, or records
+// the failure
+func (lbcert *SLoadbalancerCertificate) PerformAcmeCertificate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.LoadbalancerCertificateAcmeCertificateInput) (jsonutils.JSONObject, error) {
+	if !db.IsAdminAllowPerform(ctx, userCred, lbcert, "acme-certificate") {
+		return nil, httperrors.NewForbiddenError("only admin is allowed to perform acme-certificate")
+	}
+	if !lbcert.IsAcme() {
+		return nil, httperrors.NewNotSupportedError("certificate %s is not of type %s", lbcert.Name, api.LB_CERT_TYPE_ACME)
+	}
+	if input.Error != "" {
+		lbcert.SetStatus(userCred, api.LB_CERT_STATUS_ACME_FAILED, input.Error)
+		return nil, nil
+	}
+
+	data := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+	data.Remove("error")
+	v := validators.NewCertKeyValidator("certificate", "private_key")
+	if err := v.Validate(data); err != nil {
+		return nil, err
+	}
+	data = v.UpdateCertKeyInfo(ctx, data)
+	_, err := db.Update(lbcert, func() error {
+		return data.Unmarshal(&lbcert.SCertificateResourceBase)
+	})
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "update certificate"))
+	}
+	lbcert.SetStatus(userCred, api.LB_STATUS_ENABLED, "acme certificate issued")
+	return nil, nil
+}
+
+// PerformAcmeRenew asks lbagent to renew the certificate now
+func (lbcert *SLoadbalancerCertificate) PerformAcmeRenew(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.LoadbalancerCertificateAcmeRenewInput) (jsonutils.JSONObject, error) {
+	if !lbcert.IsAcme() {
+		return nil, httperrors.NewNotSupportedError("certificate %s is not of type %s", lbcert.Name, api.LB_CERT_TYPE_ACME)
+	}
+	return nil, lbcert.SetStatus(userCred, api.LB_CERT_STATUS_ACME_PENDING, "renew")
+}
+
+// CheckLoadbalancerCertificateExpireAt notifies owners of certificates about
+// to expire.  It is meant to run once a day so that each certificate falls
+// into the window of each advance day exactly once.  Acme certificates are
+// only notified when it's too late for the normal renewal
+func CheckLoadbalancerCertificateExpireAt(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	for _, advanceDay := range advanceDays {
+		upLimit := time.Now().AddDate(0, 0, advanceDay)
+		downLimit := time.Now().AddDate(0, 0, advanceDay-1)
+		q := LoadbalancerCertificateManager.Query().IsFalse("pending_deleted").LE("not_after", upLimit).GT("not_after", downLimit)
+		lbcerts := []SLoadbalancerCertificate{}
+		if err := db.FetchModelObjects(LoadbalancerCertificateManager, q, &lbcerts); err != nil {
+			log.Errorf("unable to list loadbalancercertificates: %v", err)
+			return
+		}
+		for i := range lbcerts {
+			lbcert := &lbcerts[i]
+			if lbcert.IsAcme() && advanceDay >= api.LB_ACME_RENEW_BEFORE_DAYS {
+				continue
+			}
+			notifyclient.EventNotify(ctx, userCred, notifyclient.SEventNotifyParam{
+				Obj:         lbcert,
+				Action:      notifyclient.ActionExpire,
+				AdvanceDays: advanceDay,
+			})
+		}
+	}
+}
@@ -712,6 +712,16 @@ func (self *SNatGateway) GetVpc() (*SVpc, error) {
 	return vpc.(*SVpc), nil
 }
 
+// IsManaged tells whether the nat gateway lives in a managed vpc.  Nat
+// gateways of on-premise vpcs are realized by vpcagent
+func (self *SNatGateway) IsManaged() bool {
+	vpc, err := self.GetVpc()
+	if err != nil {
+		return false
+	}
+	return vpc.IsManaged()
+}
+
 func (self *SNatGateway) GetINatGateway() (cloudprovider.ICloudNatGateway, error) {
 	vpc, err := self.GetVpc()
 	if err != nil {
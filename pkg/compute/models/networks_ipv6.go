@@ -0,0 +1,356 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"crypto/rand"
+	"math/big"
+	"net"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+const (
+	minIp6MaskLen = 48
+	maxIp6MaskLen = 126
+	// SLAAC only works with /64 prefix
+	slaacIp6MaskLen = 64
+
+	// number of addresses to walk through when random allocation fails
+	maxIp6StepAlloc = 65536
+)
+
+type sNetworkIp6Config struct {
+	Prefix  string
+	Start   string
+	End     string
+	Mask    int8
+	Gateway string
+	Dns     string
+	Mode    string
+}
+
+func (conf sNetworkIp6Config) isEmpty() bool {
+	return conf.Prefix == "" && conf.Start == "" && conf.End == ""
+}
+
+// validate checks and normalizes the ipv6 config of a network, ipv6 is
+// disabled when neither prefix nor range is set
+func (conf sNetworkIp6Config) validate() (sNetworkIp6Config, error) {
+	if conf.isEmpty() {
+		return sNetworkIp6Config{}, nil
+	}
+	var start, end net.IP
+	if len(conf.Prefix) > 0 {
+		ip, ipnet, err := net.ParseCIDR(conf.Prefix)
+		if err != nil || ip.To4() != nil {
+			return conf, httperrors.NewInputParameterError("invalid guest_ip6_prefix %s", conf.Prefix)
+		}
+		ones, _ := ipnet.Mask.Size()
+		conf.Mask = int8(ones)
+		if conf.Mask < minIp6MaskLen || conf.Mask > maxIp6MaskLen {
+			return conf, httperrors.NewInputParameterError("ipv6 masklen should be in range %d~%d", minIp6MaskLen, maxIp6MaskLen)
+		}
+		start = netutils2.IPv6Add(ipnet.IP, big.NewInt(1))
+		end = make(net.IP, net.IPv6len)
+		for i := range end {
+			end[i] = ipnet.IP[i] | ^ipnet.Mask[i]
+		}
+	} else {
+		if conf.Mask < minIp6MaskLen || conf.Mask > maxIp6MaskLen {
+			return conf, httperrors.NewInputParameterError("ipv6 masklen should be in range %d~%d", minIp6MaskLen, maxIp6MaskLen)
+		}
+		var err error
+		start, err = netutils2.ParseIPv6(conf.Start)
+		if err != nil {
+			return conf, httperrors.NewInputParameterError("invalid guest_ip6_start: %v", err)
+		}
+		end, err = netutils2.ParseIPv6(conf.End)
+		if err != nil {
+			return conf, httperrors.NewInputParameterError("invalid guest_ip6_end: %v", err)
+		}
+		if netutils2.IPv6Compare(start, end) > 0 {
+			start, end = end, start
+		}
+		if !netutils2.IPv6InSubnet(end, start, int(conf.Mask)) {
+			return conf, httperrors.NewInputParameterError("ipv6 start and end ip not in the same subnet")
+		}
+	}
+	conf.Prefix = ""
+	conf.Start = start.String()
+	conf.End = end.String()
+
+	if conf.Mode == "" {
+		conf.Mode = api.NETWORK_IP6_MODE_STATIC
+	}
+	if !utils.IsInStringArray(conf.Mode, api.NETWORK_IP6_MODES) {
+		return conf, httperrors.NewInputParameterError("invalid ip6_mode %s, must be one of %s", conf.Mode, api.NETWORK_IP6_MODES)
+	}
+	if conf.Mode == api.NETWORK_IP6_MODE_SLAAC && conf.Mask != slaacIp6MaskLen {
+		return conf, httperrors.NewInputParameterError("ipv6 masklen must be %d for slaac", slaacIp6MaskLen)
+	}
+	if len(conf.Gateway) > 0 {
+		gw, err := netutils2.ParseIPv6(conf.Gateway)
+		if err != nil {
+			return conf, httperrors.NewInputParameterError("invalid guest_gateway6: %v", err)
+		}
+		if !netutils2.IPv6InSubnet(gw, start, int(conf.Mask)) {
+			return conf, httperrors.NewInputParameterError("ipv6 gateway must be in the same subnet as start, end ip")
+		}
+		conf.Gateway = gw.String()
+	}
+	if len(conf.Dns) > 0 {
+		dnss := strings.Split(conf.Dns, ",")
+		for i := range dnss {
+			ip, err := netutils2.ParseIPv6(strings.TrimSpace(dnss[i]))
+			if err != nil {
+				return conf, httperrors.NewInputParameterError("invalid guest_dns6: %v", err)
+			}
+			dnss[i] = ip.String()
+		}
+		conf.Dns = strings.Join(dnss, ",")
+	}
+	return conf, nil
+}
+
+func (self *SNetwork) IsIPv6Enabled() bool {
+	return len(self.GuestIp6Start) > 0 && len(self.GuestIp6End) > 0 && self.GuestIp6Mask > 0
+}
+
+func (self *SNetwork) getIp6Mode() string {
+	if len(self.Ip6Mode) == 0 {
+		return api.NETWORK_IP6_MODE_STATIC
+	}
+	return self.Ip6Mode
+}
+
+func (self *SNetwork) getIp6Range() (net.IP, net.IP) {
+	return net.ParseIP(self.GuestIp6Start), net.ParseIP(self.GuestIp6End)
+}
+
+func (self *SNetwork) isIp6InRange(ip net.IP) bool {
+	start, end := self.getIp6Range()
+	if start == nil || end == nil {
+		return false
+	}
+	return netutils2.IPv6Compare(ip, start) >= 0 && netutils2.IPv6Compare(ip, end) <= 0
+}
+
+func isOverlapNetworks6(nets []SNetwork, start, end net.IP) bool {
+	for i := range nets {
+		if !nets[i].IsIPv6Enabled() {
+			continue
+		}
+		start2, end2 := nets[i].getIp6Range()
+		if netutils2.IPv6Compare(start, end2) <= 0 && netutils2.IPv6Compare(start2, end) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *SNetwork) GetUsedAddresses6() map[string]bool {
+	used := make(map[string]bool)
+	q := GuestnetworkManager.Query("ip6_addr").Equals("network_id", self.Id)
+	q = q.Filter(sqlchemy.IsNotEmpty(q.Field("ip6_addr")))
+	results, err := q.AllStringMap()
+	if err != nil {
+		log.Errorf("GetUsedAddresses6 fail %s", err)
+		return used
+	}
+	for _, result := range results {
+		used[result["ip6_addr"]] = true
+	}
+	return used
+}
+
+// getFreeIP6 allocates an ipv6 address for a nic with mac.  With slaac the
+// address is derived from mac as the guest would configure itself, else
+// the candidate is tried first, then random addresses in the range.  The
+// gateway is never allocated, as the range of prefix form starts with it
+func (self *SNetwork) getFreeIP6(addrTable map[string]bool, candidate string, mac string) (string, error) {
+	if len(self.GuestGateway6) > 0 && !addrTable[self.GuestGateway6] {
+		used := make(map[string]bool, len(addrTable)+1)
+		for addr := range addrTable {
+			used[addr] = true
+		}
+		used[self.GuestGateway6] = true
+		addrTable = used
+	}
+	start, end := self.getIp6Range()
+	if self.getIp6Mode() == api.NETWORK_IP6_MODE_SLAAC {
+		ip, err := netutils2.IPv6EUI64(start, mac)
+		if err != nil {
+			return "", httperrors.NewInputParameterError("derive slaac address from %s: %v", mac, err)
+		}
+		if len(candidate) > 0 && !net.ParseIP(candidate).Equal(ip) {
+			return "", httperrors.NewInputParameterError("candidate %s is not the slaac address %s of %s", candidate, ip, mac)
+		}
+		if addrTable[ip.String()] {
+			return "", httperrors.NewConflictError("slaac address %s of %s is used", ip, mac)
+		}
+		return ip.String(), nil
+	}
+
+	if len(candidate) > 0 {
+		ip, err := netutils2.ParseIPv6(candidate)
+		if err != nil {
+			return "", httperrors.NewInputParameterError("%v", err)
+		}
+		if !self.isIp6InRange(ip) {
+			return "", httperrors.NewInputParameterError("candidate %s out of range", candidate)
+		}
+		if !addrTable[ip.String()] {
+			return ip.String(), nil
+		}
+	}
+
+	size := netutils2.IPv6RangeSize(start, end)
+	const MAX_TRIES = 5
+	for i := 0; i < MAX_TRIES; i += 1 {
+		offset, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			break
+		}
+		ip := netutils2.IPv6Add(start, offset)
+		if !addrTable[ip.String()] {
+			return ip.String(), nil
+		}
+	}
+	// the range is crowded, walk through it from start
+	ip := start
+	for i := 0; i < maxIp6StepAlloc && self.isIp6InRange(ip); i++ {
+		if !addrTable[ip.String()] {
+			return ip.String(), nil
+		}
+		ip = netutils2.IPv6Add(ip, big.NewInt(1))
+	}
+	return "", httperrors.NewInsufficientResourceError("Out of IPv6 address")
+}
+
+func (manager *SNetworkManager) validateCreateIp6(input api.NetworkCreateInput, vpc *SVpc, region *SCloudregion) (api.NetworkCreateInput, error) {
+	conf, err := sNetworkIp6Config{
+		Prefix:  input.GuestIp6Prefix,
+		Start:   input.GuestIp6Start,
+		End:     input.GuestIp6End,
+		Mask:    input.GuestIp6Mask,
+		Gateway: input.GuestGateway6,
+		Dns:     input.GuestDns6,
+		Mode:    input.Ip6Mode,
+	}.validate()
+	if err != nil {
+		return input, err
+	}
+	if !conf.isEmpty() {
+		if region.Provider == api.CLOUD_PROVIDER_ONECLOUD && vpc.Id != api.DEFAULT_VPC_ID && len(conf.Gateway) == 0 {
+			// the gateway is the address of ovn logical router port
+			return input, httperrors.NewMissingParameterError("guest_gateway6")
+		}
+		nets, err := vpc.GetNetworks()
+		if err != nil {
+			return input, httperrors.NewInternalServerError("fail to GetNetworks of vpc: %v", err)
+		}
+		if isOverlapNetworks6(nets, net.ParseIP(conf.Start), net.ParseIP(conf.End)) {
+			return input, httperrors.NewInputParameterError("Conflict ipv6 address space with existing networks in vpc %q", vpc.GetName())
+		}
+	}
+	input.GuestIp6Prefix = ""
+	input.GuestIp6Start = conf.Start
+	input.GuestIp6End = conf.End
+	input.GuestIp6Mask = conf.Mask
+	input.GuestGateway6 = conf.Gateway
+	input.GuestDns6 = conf.Dns
+	input.Ip6Mode = conf.Mode
+	return input, nil
+}
+
+func (self *SNetwork) validateUpdateIp6(input api.NetworkUpdateInput) (api.NetworkUpdateInput, error) {
+	conf := sNetworkIp6Config{
+		Start:   self.GuestIp6Start,
+		End:     self.GuestIp6End,
+		Mask:    self.GuestIp6Mask,
+		Gateway: self.GuestGateway6,
+		Dns:     self.GuestDns6,
+		Mode:    self.Ip6Mode,
+	}
+	changed := false
+	for _, field := range []struct {
+		val *string
+		in  string
+	}{
+		{&conf.Start, input.GuestIp6Start},
+		{&conf.End, input.GuestIp6End},
+		{&conf.Gateway, input.GuestGateway6},
+		{&conf.Dns, input.GuestDns6},
+		{&conf.Mode, input.Ip6Mode},
+	} {
+		if len(field.in) > 0 {
+			*field.val = field.in
+			changed = true
+		}
+	}
+	if input.GuestIp6Mask != nil {
+		conf.Mask = *input.GuestIp6Mask
+		changed = true
+	}
+	if !changed {
+		return input, nil
+	}
+
+	conf, err := conf.validate()
+	if err != nil {
+		return input, err
+	}
+	if conf.isEmpty() {
+		return input, httperrors.NewInputParameterError("guest_ip6_start and guest_ip6_end are required")
+	}
+	vpc, _ := self.GetVpc()
+	if vpc != nil && vpc.Id != api.DEFAULT_VPC_ID && self.isOneCloudVpcNetwork() && len(conf.Gateway) == 0 {
+		return input, httperrors.NewMissingParameterError("guest_gateway6")
+	}
+	start, end := net.ParseIP(conf.Start), net.ParseIP(conf.End)
+	nets := NetworkManager.getAllNetworks(self.WireId, self.Id)
+	if isOverlapNetworks6(nets, start, end) {
+		return input, httperrors.NewInputParameterError("Conflict ipv6 address space with existing networks")
+	}
+	for usedIp := range self.GetUsedAddresses6() {
+		ip := net.ParseIP(usedIp)
+		if conf.Mode == api.NETWORK_IP6_MODE_SLAAC {
+			if !netutils2.IPv6InSubnet(ip, start, int(conf.Mask)) {
+				return input, httperrors.NewInputParameterError("IPv6 address been assigned out of new subnet")
+			}
+		} else if netutils2.IPv6Compare(ip, start) < 0 || netutils2.IPv6Compare(ip, end) > 0 {
+			return input, httperrors.NewInputParameterError("IPv6 address been assigned out of new range")
+		}
+	}
+	if conf.Mode != self.getIp6Mode() && len(self.GetUsedAddresses6()) > 0 {
+		return input, httperrors.NewInputParameterError("ip6_mode can not be changed when addresses are assigned")
+	}
+
+	input.GuestIp6Start = conf.Start
+	input.GuestIp6End = conf.End
+	input.GuestIp6Mask = &conf.Mask
+	input.GuestGateway6 = conf.Gateway
+	input.GuestDns6 = conf.Dns
+	input.Ip6Mode = conf.Mode
+	return input, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+)
+
+func TestNetworkGetFreeIP6SkipGateway(t *testing.T) {
+	conf, err := sNetworkIp6Config{
+		Prefix:  "2001:db8::/126",
+		Gateway: "2001:db8::1",
+	}.validate()
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if conf.Start != conf.Gateway {
+		t.Fatalf("want range of prefix form starting with gateway %s, got %s", conf.Gateway, conf.Start)
+	}
+	network := &SNetwork{
+		GuestIp6Start: conf.Start,
+		GuestIp6End:   conf.End,
+		GuestIp6Mask:  conf.Mask,
+		GuestGateway6: conf.Gateway,
+		Ip6Mode:       conf.Mode,
+	}
+	cases := []struct {
+		name      string
+		addrTable map[string]bool
+		candidate string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "first allocation",
+			addrTable: map[string]bool{"2001:db8::2": true},
+			want:      "2001:db8::3",
+		},
+		{
+			name:      "gateway as candidate",
+			addrTable: map[string]bool{"2001:db8::3": true},
+			candidate: "2001:db8::1",
+			want:      "2001:db8::2",
+		},
+		{
+			name:      "only gateway left",
+			addrTable: map[string]bool{"2001:db8::2": true, "2001:db8::3": true},
+			wantErr:   true,
+		},
+	}
+	for _, c := range cases {
+		for i := 0; i < 16; i++ {
+			got, err := network.getFreeIP6(c.addrTable, c.candidate, "00:22:11:33:44:55")
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("%s: want error, got %s", c.name, got)
+				}
+				break
+			}
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+				break
+			}
+			if got != c.want {
+				t.Errorf("%s: want %s, got %s", c.name, c.want, got)
+				break
+			}
+		}
+	}
+	if len(cases[0].addrTable) != 1 {
+		t.Errorf("gateway leaked into address table of caller")
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SPricePlanManager struct {
+	db.SEnabledStatusInfrasResourceBaseManager
+}
+
+var PricePlanManager *SPricePlanManager
+
+func init() {
+	PricePlanManager = &SPricePlanManager{
+		SEnabledStatusInfrasResourceBaseManager: db.NewEnabledStatusInfrasResourceBaseManager(
+			SPricePlan{},
+			"price_plans_tbl",
+			"price_plan",
+			"price_plans",
+		),
+	}
+	PricePlanManager.SetVirtualObject(PricePlanManager)
+}
+
+// SPricePlan defines the unit-hour prices of the on-premise resources, which
+// are used to rate the usage of resources of the domain owning or sharing it
+type SPricePlan struct {
+	db.SEnabledStatusInfrasResourceBase
+
+	// 货币单位
+	Currency string `width:"8" charset:"ascii" nullable:"false" default:"CNY" list:"user" create:"optional"`
+	// 适用的虚拟化平台, 为空表示所有平台
+	Hypervisor string `width:"16" charset:"ascii" nullable:"false" default:"" list:"user" create:"optional"`
+
+	// 每vCPU每小时的价格
+	CpuPrice float64 `nullable:"false" default:"0" list:"user" update:"domain" create:"optional"`
+	// 每GB内存每小时的价格
+	MemPrice float64 `nullable:"false" default:"0" list:"user" update:"domain" create:"optional"`
+	// 每GB磁盘每小时的默认价格
+	DiskPrice float64 `nullable:"false" default:"0" list:"user" update:"domain" create:"optional"`
+	// 各存储类型每GB磁盘每小时的价格
+	StorageTypePrices *api.StorageTypePrices `list:"user" update:"domain" create:"optional"`
+	// 每块GPU每小时的价格
+	GpuPrice float64 `nullable:"false" default:"0" list:"user" update:"domain" create:"optional"`
+	// 每个EIP每小时的价格
+	EipPrice float64 `nullable:"false" default:"0" list:"user" update:"domain" create:"optional"`
+	// 每Mbps带宽每小时的价格
+	BandwidthPrice float64 `nullable:"false" default:"0" list:"user" update:"domain" create:"optional"`
+}
+
+func validatePrices(prices ...*float64) error {
+	for _, price := range prices {
+		if price != nil && *price < 0 {
+			return httperrors.NewInputParameterError("price should not be negative")
+		}
+	}
+	return nil
+}
+
+func validateStorageTypePrices(prices api.StorageTypePrices) error {
+	for storageType, price := range prices {
+		if price < 0 {
+			return httperrors.NewInputParameterError("price of storage type %s should not be negative", storageType)
+		}
+	}
+	return nil
+}
+
+// 列出价格方案
+func (manager *SPricePlanManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.PricePlanListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	if len(query.Hypervisor) > 0 {
+		q = q.In("hypervisor", query.Hypervisor)
+	}
+	if len(query.Currency) > 0 {
+		q = q.In("currency", query.Currency)
+	}
+	return q, nil
+}
+
+func (manager *SPricePlanManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.PricePlanCreateInput,
+) (api.PricePlanCreateInput, error) {
+	if len(input.Hypervisor) > 0 && !utils.IsInStringArray(input.Hypervisor, api.RATING_HYPERVISORS) {
+		return input, httperrors.NewInputParameterError("hypervisor should be one of %s", api.RATING_HYPERVISORS)
+	}
+	if len(input.Currency) == 0 {
+		input.Currency = api.DEFAULT_RATING_CURRENCY
+	}
+	err := validatePrices(&input.CpuPrice, &input.MemPrice, &input.DiskPrice, &input.GpuPrice, &input.EipPrice, &input.BandwidthPrice)
+	if err != nil {
+		return input, err
+	}
+	err = validateStorageTypePrices(input.StorageTypePrices)
+	if err != nil {
+		return input, err
+	}
+	input.SetEnabled()
+	input.Status = api.PRICE_PLAN_STATUS_AVAILABLE
+	input.EnabledStatusInfrasResourceBaseCreateInput, err = manager.SEnabledStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *SPricePlan) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.PricePlanUpdateInput) (api.PricePlanUpdateInput, error) {
+	err := validatePrices(input.CpuPrice, input.MemPrice, input.DiskPrice, input.GpuPrice, input.EipPrice, input.BandwidthPrice)
+	if err != nil {
+		return input, err
+	}
+	err = validateStorageTypePrices(input.StorageTypePrices)
+	if err != nil {
+		return input, err
+	}
+	input.EnabledStatusInfrasResourceBaseUpdateInput, err = self.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput)
+	return input, err
+}
+
+func (manager *SPricePlanManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.PricePlanDetails {
+	rows := make([]api.PricePlanDetails, len(objs))
+	stdRows := manager.SEnabledStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.PricePlanDetails{
+			EnabledStatusInfrasResourceBaseDetails: stdRows[i],
+		}
+	}
+	return rows
+}
+
+func (manager *SPricePlanManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SPricePlanManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.PricePlanListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SPricePlanManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemExportKeys")
+	}
+	return q, nil
+}
+
+// GetDiskPrice returns the unit-hour price of disk on storage of storageType
+func (self *SPricePlan) GetDiskPrice(storageType string) float64 {
+	if self.StorageTypePrices != nil {
+		if price, ok := (*self.StorageTypePrices)[storageType]; ok {
+			return price
+		}
+	}
+	return self.DiskPrice
+}
+
+// isAvailableToDomain tells whether resources of domain could be rated by the plan
+func (self *SPricePlan) isAvailableToDomain(domainId string) bool {
+	if self.DomainId == domainId {
+		return true
+	}
+	if !self.IsPublic {
+		return false
+	}
+	if rbacutils.String2Scope(self.PublicScope) == rbacutils.ScopeSystem {
+		return true
+	}
+	sharedDomains := self.GetSharedDomains()
+	return utils.IsInStringArray(domainId, sharedDomains)
+}
+
+// sPricePlanMatcher picks the price plan for resources from the enabled plans
+type sPricePlanMatcher struct {
+	plans []SPricePlan
+}
+
+func (manager *SPricePlanManager) newPricePlanMatcher() (*sPricePlanMatcher, error) {
+	q := manager.Query().IsTrue("enabled").Asc("created_at")
+	plans := []SPricePlan{}
+	err := db.FetchModelObjects(manager, q, &plans)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return &sPricePlanMatcher{plans: plans}, nil
+}
+
+// Match prefers the plan owned by the domain of resource to the shared ones,
+// and the plan of the same hypervisor to the general ones. An empty hypervisor
+// matches the plans of any hypervisor, e.g. the eips.
+func (m *sPricePlanMatcher) Match(domainId, hypervisor string) *SPricePlan {
+	var ret *SPricePlan
+	retScore := -1
+	for i := range m.plans {
+		plan := &m.plans[i]
+		if len(plan.Hypervisor) > 0 && len(hypervisor) > 0 && plan.Hypervisor != hypervisor {
+			continue
+		}
+		if !plan.isAvailableToDomain(domainId) {
+			continue
+		}
+		score := 0
+		if plan.DomainId == domainId {
+			score += 2
+		}
+		if plan.Hypervisor == hypervisor {
+			score += 1
+		}
+		if score > retScore {
+			ret, retScore = plan, score
+		}
+	}
+	return ret
+}
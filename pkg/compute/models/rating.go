@@ -0,0 +1,515 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// sRatedResource is the lifecycle and specs of a guest, disk or eip being rated
+type sRatedResource struct {
+	Id        string
+	Name      string
+	ProjectId string `json:"tenant_id"`
+	DomainId  string
+	CreatedAt time.Time
+	Deleted   bool
+	DeletedAt time.Time
+
+	// status and specs of guest
+	Status     string
+	Hypervisor string
+	VcpuCount  int
+	VmemSize   int
+
+	// specs of disk
+	StorageId string
+	DiskSize  int
+
+	// specs of eip
+	Bandwidth int
+}
+
+// lifeIn returns the period the resource lives in [start, end)
+func (res *sRatedResource) lifeIn(start, end time.Time) (time.Time, time.Time, bool) {
+	from, to := res.CreatedAt, end
+	if res.Deleted && res.DeletedAt.Before(to) {
+		to = res.DeletedAt
+	}
+	if from.Before(start) {
+		from = start
+	}
+	return from, to, to.After(from)
+}
+
+// hoursIn returns the hours the resource lives in [start, end)
+func (res *sRatedResource) hoursIn(start, end time.Time) float64 {
+	from, to, ok := res.lifeIn(start, end)
+	if !ok {
+		return 0
+	}
+	return to.Sub(from).Hours()
+}
+
+// sRatedChange is a change of the specs or status of a rated resource recorded
+// in the operation logs, specs are changed by the deltas
+type sRatedChange struct {
+	ObjId string
+	At    time.Time
+
+	AddCpu  int
+	AddMem  int
+	AddSize int
+
+	FromStatus string
+	ToStatus   string
+}
+
+// sRatedUsage is a period in which the specs and status of a resource are unchanged
+type sRatedUsage struct {
+	Hours     float64
+	Status    string
+	VcpuCount int
+	VmemSize  int
+	DiskSize  int
+}
+
+func (u *sRatedUsage) apply(change *sRatedChange) {
+	u.VcpuCount += change.AddCpu
+	u.VmemSize += change.AddMem
+	u.DiskSize += change.AddSize
+	if len(change.ToStatus) > 0 {
+		u.Status = change.ToStatus
+	}
+}
+
+func (u *sRatedUsage) revert(change *sRatedChange) {
+	u.VcpuCount -= change.AddCpu
+	u.VmemSize -= change.AddMem
+	u.DiskSize -= change.AddSize
+	if len(change.FromStatus) > 0 {
+		u.Status = change.FromStatus
+	}
+}
+
+// usagesIn splits the life of the resource in [start, end) by its changes. The
+// specs and status before the day are derived backward from the current ones,
+// so changes must be sorted by time and include those happened after end
+func (res *sRatedResource) usagesIn(start, end time.Time, changes []sRatedChange) []sRatedUsage {
+	from, to, ok := res.lifeIn(start, end)
+	if !ok {
+		return nil
+	}
+	cur := sRatedUsage{
+		Status:    res.Status,
+		VcpuCount: res.VcpuCount,
+		VmemSize:  res.VmemSize,
+		DiskSize:  res.DiskSize,
+	}
+	for i := len(changes) - 1; i >= 0 && !changes[i].At.Before(from); i-- {
+		cur.revert(&changes[i])
+	}
+	usages := []sRatedUsage{}
+	for i := range changes {
+		if changes[i].At.Before(from) {
+			continue
+		}
+		if !changes[i].At.Before(to) {
+			break
+		}
+		if changes[i].At.After(from) {
+			cur.Hours = changes[i].At.Sub(from).Hours()
+			usages = append(usages, cur)
+			from = changes[i].At
+		}
+		cur.apply(&changes[i])
+	}
+	cur.Hours = to.Sub(from).Hours()
+	return append(usages, cur)
+}
+
+// sRatingContext collects the bills of a day
+type sRatingContext struct {
+	start   time.Time
+	end     time.Time
+	matcher *sPricePlanMatcher
+	bills   []SRatingBill
+}
+
+// ratingBillId identifies the bill of a quantity of item, bills regenerated
+// for the same day replace the previous ones
+func ratingBillId(billDate, resType, resId, item, spec string, quantity float64) string {
+	key := fmt.Sprintf("%s/%s/%s/%s/%s/%g", billDate, resType, resId, item, spec, quantity)
+	return fmt.Sprintf("%x", md5.Sum([]byte(key)))
+}
+
+func (rc *sRatingContext) addBill(res *sRatedResource, resType string, plan *SPricePlan, item, spec, unit string, quantity, hours, price float64) {
+	billDate := rc.start.Format(api.RATING_BILL_DATE_FORMAT)
+	bill := SRatingBill{
+		BillDate:     billDate,
+		ResourceType: resType,
+		ResourceId:   res.Id,
+		ResourceName: res.Name,
+		PricePlanId:  plan.Id,
+		Item:         item,
+		Spec:         spec,
+		Quantity:     quantity,
+		Unit:         unit,
+		Hours:        hours,
+		UnitPrice:    price,
+		Amount:       quantity * hours * price,
+		Currency:     plan.Currency,
+	}
+	bill.Id = ratingBillId(billDate, resType, res.Id, item, spec, quantity)
+	bill.ProjectId = res.ProjectId
+	bill.DomainId = res.DomainId
+	rc.bills = append(rc.bills, bill)
+}
+
+// addBills bills the quantity of item in each usage, usages of the same
+// quantity are billed together
+func (rc *sRatingContext) addBills(res *sRatedResource, resType string, plan *SPricePlan, item, spec, unit string, price float64, usages []sRatedUsage, quantity func(u *sRatedUsage) float64) {
+	quantities := []float64{}
+	hours := map[float64]float64{}
+	for i := range usages {
+		q := quantity(&usages[i])
+		if q <= 0 || usages[i].Hours <= 0 {
+			continue
+		}
+		if _, ok := hours[q]; !ok {
+			quantities = append(quantities, q)
+		}
+		hours[q] += usages[i].Hours
+	}
+	for _, q := range quantities {
+		rc.addBill(res, resType, plan, item, spec, unit, q, hours[q], price)
+	}
+}
+
+// fetchChanges returns the changes of resources since the start of the day
+// from the operation logs, sorted by time
+func (rc *sRatingContext) fetchChanges(keyword string, ids []string, actions []string) (map[string][]sRatedChange, error) {
+	q := db.OpsLog.Query("obj_id", "action", "notes", "ops_time").Equals("obj_type", keyword)
+	q = q.In("obj_id", ids).In("action", actions).GE("ops_time", rc.start).Asc("ops_time", "id")
+	logs := []struct {
+		ObjId   string
+		Action  string
+		Notes   string
+		OpsTime time.Time
+	}{}
+	err := q.All(&logs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch operation logs of %s", keyword)
+	}
+	changes := map[string][]sRatedChange{}
+	for _, l := range logs {
+		change, ok := parseRatedChange(l.Action, l.Notes)
+		if !ok {
+			continue
+		}
+		change.ObjId = l.ObjId
+		change.At = l.OpsTime
+		changes[l.ObjId] = append(changes[l.ObjId], change)
+	}
+	return changes, nil
+}
+
+// parseRatedChange parses the notes of status updates, flavor changes of
+// guests and resizes of disks
+func parseRatedChange(action, notes string) (sRatedChange, bool) {
+	change := sRatedChange{}
+	switch action {
+	case db.ACT_UPDATE_STATUS:
+		// notes are old=>new or old=>new: reason
+		idx := strings.Index(notes, "=>")
+		if idx < 0 {
+			return change, false
+		}
+		change.FromStatus = notes[:idx]
+		change.ToStatus = notes[idx+2:]
+		if end := strings.Index(change.ToStatus, ":"); end >= 0 {
+			change.ToStatus = change.ToStatus[:end]
+		}
+		return change, len(change.FromStatus) > 0 && len(change.ToStatus) > 0
+	case db.ACT_CHANGE_FLAVOR, db.ACT_RESIZE:
+		spec, err := jsonutils.ParseString(notes)
+		if err != nil {
+			return change, false
+		}
+		addCpu, _ := spec.Int("add_cpu")
+		addMem, _ := spec.Int("add_mem")
+		addSize, _ := spec.Int("add_size")
+		change.AddCpu, change.AddMem, change.AddSize = int(addCpu), int(addMem), int(addSize)
+		return change, addCpu != 0 || addMem != 0 || addSize != 0
+	}
+	return change, false
+}
+
+// lifecycleFilter selects the resources, including the deleted ones, living in the day
+func (rc *sRatingContext) lifecycleFilter(q *sqlchemy.SQuery) *sqlchemy.SQuery {
+	return q.LT("created_at", rc.end).Filter(sqlchemy.OR(
+		sqlchemy.IsFalse(q.Field("deleted")),
+		sqlchemy.GE(q.Field("deleted_at"), rc.start),
+	))
+}
+
+func (rc *sRatingContext) rateGuests() error {
+	q := GuestManager.RawQuery("id", "name", "tenant_id", "domain_id", "created_at", "deleted", "deleted_at", "status", "hypervisor", "vcpu_count", "vmem_size")
+	q = rc.lifecycleFilter(q).In("hypervisor", api.RATING_HYPERVISORS)
+	guests := []sRatedResource{}
+	err := q.All(&guests)
+	if err != nil {
+		return errors.Wrap(err, "fetch guests")
+	}
+	if len(guests) == 0 {
+		return nil
+	}
+	guestIds := make([]string, len(guests))
+	for i := range guests {
+		guestIds[i] = guests[i].Id
+	}
+
+	changes, err := rc.fetchChanges(GuestManager.Keyword(), guestIds, []string{db.ACT_UPDATE_STATUS, db.ACT_CHANGE_FLAVOR})
+	if err != nil {
+		return err
+	}
+
+	gpus := []struct {
+		GuestId string
+		Count   int
+	}{}
+	devs := IsolatedDeviceManager.Query().SubQuery()
+	err = devs.Query(devs.Field("guest_id"), sqlchemy.COUNT("count")).
+		Filter(sqlchemy.In(devs.Field("guest_id"), guestIds)).
+		Filter(sqlchemy.In(devs.Field("dev_type"), api.VALID_GPU_TYPES)).
+		GroupBy(devs.Field("guest_id")).All(&gpus)
+	if err != nil {
+		return errors.Wrap(err, "fetch gpus of guests")
+	}
+	gpuCount := make(map[string]int, len(gpus))
+	for _, gpu := range gpus {
+		gpuCount[gpu.GuestId] = gpu.Count
+	}
+
+	bws := []struct {
+		GuestId string
+		BwLimit int
+	}{}
+	gns := GuestnetworkManager.Query().SubQuery()
+	err = gns.Query(gns.Field("guest_id"), sqlchemy.SUM("bw_limit", gns.Field("bw_limit"))).
+		Filter(sqlchemy.In(gns.Field("guest_id"), guestIds)).
+		GroupBy(gns.Field("guest_id")).All(&bws)
+	if err != nil {
+		return errors.Wrap(err, "fetch bandwidth of guests")
+	}
+	bwLimit := make(map[string]int, len(bws))
+	for _, bw := range bws {
+		bwLimit[bw.GuestId] = bw.BwLimit
+	}
+
+	for i := range guests {
+		guest := &guests[i]
+		plan := rc.matcher.Match(guest.DomainId, guest.Hypervisor)
+		if plan == nil {
+			continue
+		}
+		// guests are only billed while running
+		usages := guest.usagesIn(rc.start, rc.end, changes[guest.Id])
+		running := func(quantity func(u *sRatedUsage) float64) func(u *sRatedUsage) float64 {
+			return func(u *sRatedUsage) float64 {
+				if !utils.IsInStringArray(u.Status, api.VM_RUNNING_STATUS) {
+					return 0
+				}
+				return quantity(u)
+			}
+		}
+		rc.addBills(guest, api.RATING_RESOURCE_SERVER, plan, api.RATING_ITEM_CPU, "", "vcpu", plan.CpuPrice, usages,
+			running(func(u *sRatedUsage) float64 { return float64(u.VcpuCount) }))
+		rc.addBills(guest, api.RATING_RESOURCE_SERVER, plan, api.RATING_ITEM_MEM, "", "GB", plan.MemPrice, usages,
+			running(func(u *sRatedUsage) float64 { return float64(u.VmemSize) / 1024 }))
+		rc.addBills(guest, api.RATING_RESOURCE_SERVER, plan, api.RATING_ITEM_GPU, "", "gpu", plan.GpuPrice, usages,
+			running(func(u *sRatedUsage) float64 { return float64(gpuCount[guest.Id]) }))
+		rc.addBills(guest, api.RATING_RESOURCE_SERVER, plan, api.RATING_ITEM_BANDWIDTH, "", "Mbps", plan.BandwidthPrice, usages,
+			running(func(u *sRatedUsage) float64 { return float64(bwLimit[guest.Id]) }))
+	}
+	return nil
+}
+
+// ratedStorages returns the storages of the on-premise kvm hosts and vmware
+func (rc *sRatingContext) ratedStorages() (map[string]string, map[string]string, error) {
+	providers := CloudproviderManager.Query("id").Equals("provider", api.CLOUD_PROVIDER_VMWARE).SubQuery()
+	q := StorageManager.Query("id", "storage_type", "manager_id")
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.IsNullOrEmpty(q.Field("manager_id")),
+		sqlchemy.In(q.Field("manager_id"), providers),
+	))
+	storages := []struct {
+		Id          string
+		StorageType string
+		ManagerId   string
+	}{}
+	err := q.All(&storages)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "fetch storages")
+	}
+	storageTypes := make(map[string]string, len(storages))
+	hypervisors := make(map[string]string, len(storages))
+	for _, storage := range storages {
+		storageTypes[storage.Id] = storage.StorageType
+		hypervisors[storage.Id] = api.HYPERVISOR_KVM
+		if len(storage.ManagerId) > 0 {
+			hypervisors[storage.Id] = api.HYPERVISOR_ESXI
+		}
+	}
+	return storageTypes, hypervisors, nil
+}
+
+func (rc *sRatingContext) rateDisks() error {
+	storageTypes, hypervisors, err := rc.ratedStorages()
+	if err != nil {
+		return err
+	}
+	if len(storageTypes) == 0 {
+		return nil
+	}
+	storageIds := make([]string, 0, len(storageTypes))
+	for id := range storageTypes {
+		storageIds = append(storageIds, id)
+	}
+	q := DiskManager.RawQuery("id", "name", "tenant_id", "domain_id", "created_at", "deleted", "deleted_at", "storage_id", "disk_size")
+	q = rc.lifecycleFilter(q).In("storage_id", storageIds)
+	disks := []sRatedResource{}
+	err = q.All(&disks)
+	if err != nil {
+		return errors.Wrap(err, "fetch disks")
+	}
+	if len(disks) == 0 {
+		return nil
+	}
+	diskIds := make([]string, len(disks))
+	for i := range disks {
+		diskIds[i] = disks[i].Id
+	}
+	changes, err := rc.fetchChanges(DiskManager.Keyword(), diskIds, []string{db.ACT_RESIZE})
+	if err != nil {
+		return err
+	}
+	for i := range disks {
+		disk := &disks[i]
+		plan := rc.matcher.Match(disk.DomainId, hypervisors[disk.StorageId])
+		if plan == nil {
+			continue
+		}
+		storageType := storageTypes[disk.StorageId]
+		usages := disk.usagesIn(rc.start, rc.end, changes[disk.Id])
+		rc.addBills(disk, api.RATING_RESOURCE_DISK, plan, api.RATING_ITEM_DISK, storageType, "GB", plan.GetDiskPrice(storageType), usages,
+			func(u *sRatedUsage) float64 { return float64(u.DiskSize) / 1024 })
+	}
+	return nil
+}
+
+func (rc *sRatingContext) rateEips() error {
+	q := ElasticipManager.RawQuery("id", "name", "tenant_id", "domain_id", "created_at", "deleted", "deleted_at", "manager_id", "bandwidth")
+	q = rc.lifecycleFilter(q).Filter(sqlchemy.IsNullOrEmpty(q.Field("manager_id")))
+	eips := []sRatedResource{}
+	err := q.All(&eips)
+	if err != nil {
+		return errors.Wrap(err, "fetch eips")
+	}
+	for i := range eips {
+		eip := &eips[i]
+		plan := rc.matcher.Match(eip.DomainId, "")
+		if plan == nil {
+			continue
+		}
+		hours := eip.hoursIn(rc.start, rc.end)
+		if hours <= 0 {
+			continue
+		}
+		rc.addBill(eip, api.RATING_RESOURCE_EIP, plan, api.RATING_ITEM_EIP, "", "eip", 1, hours, plan.EipPrice)
+		if eip.Bandwidth > 0 {
+			rc.addBill(eip, api.RATING_RESOURCE_EIP, plan, api.RATING_ITEM_BANDWIDTH, "", "Mbps", float64(eip.Bandwidth), hours, plan.BandwidthPrice)
+		}
+	}
+	return nil
+}
+
+// GenerateRatingBills rates the on-premise guests, disks and eips living in
+// the UTC day of date with the enabled price plans. The bills of the day are
+// regenerated if there are, then the budgets are checked.
+func GenerateRatingBills(ctx context.Context, userCred mcclient.TokenCredential, date time.Time) error {
+	date = date.UTC()
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	matcher, err := PricePlanManager.newPricePlanMatcher()
+	if err != nil {
+		return errors.Wrap(err, "newPricePlanMatcher")
+	}
+	rc := &sRatingContext{
+		start:   start,
+		end:     start.AddDate(0, 0, 1),
+		matcher: matcher,
+	}
+	if len(matcher.plans) > 0 {
+		for _, rate := range []func() error{rc.rateGuests, rc.rateDisks, rc.rateEips} {
+			err = rate()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// bills are upserted before the stale ones are removed, so the bills of
+	// the day are never missing even if the rating is interrupted
+	billDate := start.Format(api.RATING_BILL_DATE_FORMAT)
+	billIds := make(map[string]bool, len(rc.bills))
+	for i := range rc.bills {
+		bill := &rc.bills[i]
+		bill.SetModelManager(RatingBillManager, bill)
+		err = RatingBillManager.TableSpec().InsertOrUpdate(ctx, bill)
+		if err != nil {
+			return errors.Wrapf(err, "upsert bill of %s %s", bill.ResourceType, bill.ResourceId)
+		}
+		billIds[bill.Id] = true
+	}
+	err = RatingBillManager.deleteStaleBills(billDate, billIds)
+	if err != nil {
+		return errors.Wrapf(err, "delete stale bills of %s", billDate)
+	}
+	log.Infof("generate %d rating bills of %s", len(rc.bills), billDate)
+
+	return BudgetManager.checkBudgets(ctx, userCred, start)
+}
+
+// RateResourcesDaily generates the rating bills of yesterday
+func RateResourcesDaily(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	err := GenerateRatingBills(ctx, userCred, time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		log.Errorf("GenerateRatingBills error: %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SRatingBillManager struct {
+	db.SStandaloneAnonResourceBaseManager
+	db.SProjectizedResourceBaseManager
+}
+
+var RatingBillManager *SRatingBillManager
+
+func init() {
+	RatingBillManager = &SRatingBillManager{
+		SStandaloneAnonResourceBaseManager: db.NewStandaloneAnonResourceBaseManager(
+			SRatingBill{},
+			"rating_bills_tbl",
+			"rating_bill",
+			"rating_bills",
+		),
+	}
+	RatingBillManager.SetVirtualObject(RatingBillManager)
+}
+
+// SRatingBill is the daily cost of an item of an on-premise resource, which is
+// generated by the rating job and is read only
+type SRatingBill struct {
+	db.SStandaloneAnonResourceBase
+	db.SProjectizedResourceBase
+
+	// 账单日期
+	BillDate string `width:"10" charset:"ascii" nullable:"false" index:"true" list:"user"`
+	// 资源类型
+	ResourceType string `width:"16" charset:"ascii" nullable:"false" list:"user"`
+	// 资源ID
+	ResourceId string `width:"128" charset:"ascii" nullable:"false" index:"true" list:"user"`
+	// 资源名称
+	ResourceName string `width:"256" charset:"utf8" nullable:"false" list:"user"`
+	// 价格方案ID
+	PricePlanId string `width:"36" charset:"ascii" nullable:"false" list:"user"`
+	// 计费项
+	Item string `width:"16" charset:"ascii" nullable:"false" list:"user"`
+	// 计费规格, 例如磁盘的存储类型
+	Spec string `width:"64" charset:"ascii" nullable:"false" default:"" list:"user"`
+	// 计费数量
+	Quantity float64 `nullable:"false" list:"user"`
+	// 计量单位
+	Unit string `width:"16" charset:"ascii" nullable:"false" list:"user"`
+	// 计费时长(小时)
+	Hours float64 `nullable:"false" list:"user"`
+	// 单价
+	UnitPrice float64 `nullable:"false" list:"user"`
+	// 金额
+	Amount float64 `nullable:"false" list:"user"`
+	// 货币单位
+	Currency string `width:"8" charset:"ascii" nullable:"false" list:"user"`
+}
+
+func (manager *SRatingBillManager) ResourceScope() rbacutils.TRbacScope {
+	return manager.SProjectizedResourceBaseManager.ResourceScope()
+}
+
+func (manager *SRatingBillManager) FilterByOwner(q *sqlchemy.SQuery, owner mcclient.IIdentityProvider, scope rbacutils.TRbacScope) *sqlchemy.SQuery {
+	return manager.SProjectizedResourceBaseManager.FilterByOwner(q, owner, scope)
+}
+
+func (manager *SRatingBillManager) FetchOwnerId(ctx context.Context, data jsonutils.JSONObject) (mcclient.IIdentityProvider, error) {
+	return manager.SProjectizedResourceBaseManager.FetchOwnerId(ctx, data)
+}
+
+func (self *SRatingBill) GetOwnerId() mcclient.IIdentityProvider {
+	return self.SProjectizedResourceBase.GetOwnerId()
+}
+
+func (manager *SRatingBillManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input jsonutils.JSONObject,
+) (jsonutils.JSONObject, error) {
+	return input, httperrors.NewUnsupportOperationError("rating bills are generated by the rating job")
+}
+
+func parseBillDate(date string) (time.Time, error) {
+	tm, err := time.Parse(api.RATING_BILL_DATE_FORMAT, date)
+	if err != nil {
+		return tm, httperrors.NewInputParameterError("invalid date %s, should be in format %s", date, api.RATING_BILL_DATE_FORMAT)
+	}
+	return tm, nil
+}
+
+// 列出计费账单
+func (manager *SRatingBillManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RatingBillListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SStandaloneAnonResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneAnonResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SProjectizedResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ProjectizedResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SProjectizedResourceBaseManager.ListItemFilter")
+	}
+	if len(query.StartDate) > 0 {
+		if _, err := parseBillDate(query.StartDate); err != nil {
+			return nil, err
+		}
+		q = q.GE("bill_date", query.StartDate)
+	}
+	if len(query.EndDate) > 0 {
+		if _, err := parseBillDate(query.EndDate); err != nil {
+			return nil, err
+		}
+		q = q.LE("bill_date", query.EndDate)
+	}
+	if len(query.ResourceType) > 0 {
+		q = q.In("resource_type", query.ResourceType)
+	}
+	if len(query.ResourceId) > 0 {
+		q = q.In("resource_id", query.ResourceId)
+	}
+	if len(query.Item) > 0 {
+		q = q.In("item", query.Item)
+	}
+	if len(query.PricePlanId) > 0 {
+		q = q.In("price_plan_id", query.PricePlanId)
+	}
+	return q, nil
+}
+
+func (manager *SRatingBillManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RatingBillListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SStandaloneAnonResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneAnonResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SProjectizedResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.ProjectizedResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SProjectizedResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SRatingBillManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SStandaloneAnonResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SProjectizedResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SRatingBillManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SStandaloneAnonResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneAnonResourceBaseManager.ListItemExportKeys")
+	}
+	q, err = manager.SProjectizedResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "SProjectizedResourceBaseManager.ListItemExportKeys")
+	}
+	return q, nil
+}
+
+func (manager *SRatingBillManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.RatingBillDetails {
+	rows := make([]api.RatingBillDetails, len(objs))
+	stdRows := manager.SStandaloneAnonResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	projRows := manager.SProjectizedResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	planIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i] = api.RatingBillDetails{
+			StandaloneAnonResourceDetails: stdRows[i],
+			ProjectizedResourceInfo:       projRows[i],
+		}
+		planIds[i] = objs[i].(*SRatingBill).PricePlanId
+	}
+	plans := make(map[string]SPricePlan)
+	err := db.FetchStandaloneObjectsByIds(PricePlanManager, planIds, &plans)
+	if err != nil {
+		return rows
+	}
+	for i := range rows {
+		if plan, ok := plans[planIds[i]]; ok {
+			rows[i].PricePlan = plan.Name
+		}
+	}
+	return rows
+}
+
+// 按项目, 域, 计费项或日期汇总计费账单
+func (manager *SRatingBillManager) GetPropertySummary(ctx context.Context, userCred mcclient.TokenCredential, query api.RatingBillSummaryInput) (api.RatingBillSummaryOutput, error) {
+	output := api.RatingBillSummaryOutput{Data: []api.RatingBillSummary{}}
+	if len(query.GroupBy) == 0 {
+		query.GroupBy = api.RATING_BILL_GROUP_BY_PROJECT
+	}
+	if !utils.IsInStringArray(query.GroupBy, api.RATING_BILL_GROUP_BYS) {
+		return output, httperrors.NewInputParameterError("group_by should be one of %s", api.RATING_BILL_GROUP_BYS)
+	}
+	field := query.GroupBy
+	switch query.GroupBy {
+	case api.RATING_BILL_GROUP_BY_PROJECT:
+		field = "tenant_id"
+	case api.RATING_BILL_GROUP_BY_DOMAIN:
+		field = "domain_id"
+	}
+	q, err := db.ListItemQueryFilters(manager, ctx, manager.Query(), userCred, jsonutils.Marshal(query.RatingBillListInput), policy.PolicyActionList)
+	if err != nil {
+		return output, err
+	}
+	sq := q.SubQuery()
+	summaryQ := sq.Query(
+		sq.Field(field, "key"),
+		sq.Field("currency"),
+		sqlchemy.SUM("amount", sq.Field("amount")),
+	).GroupBy(sq.Field(field), sq.Field("currency")).Asc("key")
+	err = summaryQ.All(&output.Data)
+	if err != nil {
+		return output, errors.Wrap(err, "summaryQ.All")
+	}
+	if field == "tenant_id" || field == "domain_id" {
+		keys := make([]string, len(output.Data))
+		for i := range output.Data {
+			keys[i] = output.Data[i].Key
+		}
+		tenants := db.DefaultProjectsFetcher(ctx, keys, field == "domain_id")
+		for i := range output.Data {
+			if tenant, ok := tenants[output.Data[i].Key]; ok {
+				output.Data[i].Name = tenant.Name
+			}
+		}
+	}
+	return output, nil
+}
+
+// 重新计算指定日期的计费账单
+func (manager *SRatingBillManager) PerformRegenerate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RatingBillRegenerateInput) (jsonutils.JSONObject, error) {
+	if db.IsAdminAllowClassPerform(userCred, manager, "regenerate").Result.IsDeny() {
+		return nil, httperrors.NewForbiddenError("only system admin can regenerate rating bills")
+	}
+	day := time.Now().AddDate(0, 0, -1)
+	if len(input.BillDate) > 0 {
+		var err error
+		day, err = parseBillDate(input.BillDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if day.After(time.Now()) {
+		return nil, httperrors.NewInputParameterError("could not rate the future")
+	}
+	err := GenerateRatingBills(ctx, userCred, day)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GenerateRatingBills")
+	}
+	return nil, nil
+}
+
+// deleteStaleBills removes the bills of date which are not regenerated, in one transaction
+func (manager *SRatingBillManager) deleteStaleBills(date string, billIds map[string]bool) error {
+	bills := []struct {
+		Id string
+	}{}
+	err := manager.Query("id").Equals("bill_date", date).All(&bills)
+	if err != nil {
+		return errors.Wrap(err, "fetch bills")
+	}
+	vars := [][]interface{}{}
+	for _, bill := range bills {
+		if !billIds[bill.Id] {
+			vars = append(vars, []interface{}{bill.Id})
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	_, err = sqlchemy.GetDefaultDB().TxBatchExec(
+		fmt.Sprintf(
+			"delete from %s where id = ?",
+			manager.TableSpec().Name(),
+		), vars,
+	)
+	return err
+}
@@ -0,0 +1,224 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+func TestRatedResourceHoursIn(t *testing.T) {
+	start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	cases := []struct {
+		createdAt time.Time
+		deletedAt time.Time
+		want      float64
+	}{
+		{createdAt: start.Add(-time.Hour), want: 24},
+		{createdAt: start.Add(6 * time.Hour), want: 18},
+		{createdAt: start.Add(-time.Hour), deletedAt: start.Add(3 * time.Hour), want: 3},
+		{createdAt: start.Add(2 * time.Hour), deletedAt: start.Add(5 * time.Hour), want: 3},
+		{createdAt: start.Add(-time.Hour), deletedAt: end.Add(time.Hour), want: 24},
+		{createdAt: end.Add(time.Hour), want: 0},
+	}
+	for i, c := range cases {
+		res := &sRatedResource{CreatedAt: c.createdAt}
+		if !c.deletedAt.IsZero() {
+			res.Deleted = true
+			res.DeletedAt = c.deletedAt
+		}
+		if got := res.hoursIn(start, end); got != c.want {
+			t.Errorf("case %d: want %f, got %f", i, c.want, got)
+		}
+	}
+}
+
+func TestRatedResourceUsagesIn(t *testing.T) {
+	start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	at := func(hours int) time.Time {
+		return start.Add(time.Duration(hours) * time.Hour)
+	}
+	res := &sRatedResource{CreatedAt: start.AddDate(0, 0, -1), Status: compute.VM_RUNNING, VcpuCount: 4, VmemSize: 4096}
+	cases := []struct {
+		name    string
+		res     *sRatedResource
+		changes []sRatedChange
+		want    []sRatedUsage
+	}{
+		{
+			name: "unchanged",
+			res:  res,
+			want: []sRatedUsage{{Hours: 24, Status: compute.VM_RUNNING, VcpuCount: 4, VmemSize: 4096}},
+		},
+		{
+			name: "stopped and started",
+			res:  res,
+			changes: []sRatedChange{
+				{At: at(6), FromStatus: compute.VM_RUNNING, ToStatus: compute.VM_READY},
+				{At: at(16), FromStatus: compute.VM_READY, ToStatus: compute.VM_RUNNING},
+			},
+			want: []sRatedUsage{
+				{Hours: 6, Status: compute.VM_RUNNING, VcpuCount: 4, VmemSize: 4096},
+				{Hours: 10, Status: compute.VM_READY, VcpuCount: 4, VmemSize: 4096},
+				{Hours: 8, Status: compute.VM_RUNNING, VcpuCount: 4, VmemSize: 4096},
+			},
+		},
+		{
+			name: "resized in and after the day",
+			res:  res,
+			changes: []sRatedChange{
+				{At: at(12), AddCpu: 2, AddMem: 2048},
+				{At: at(30), AddCpu: -2},
+				{At: at(31), FromStatus: compute.VM_READY, ToStatus: compute.VM_RUNNING},
+			},
+			want: []sRatedUsage{
+				{Hours: 12, Status: compute.VM_READY, VcpuCount: 4, VmemSize: 2048},
+				{Hours: 12, Status: compute.VM_READY, VcpuCount: 6, VmemSize: 4096},
+			},
+		},
+		{
+			name: "created and deleted in the day",
+			res:  &sRatedResource{CreatedAt: at(2), Deleted: true, DeletedAt: at(20), Status: compute.VM_READY, DiskSize: 20480},
+			changes: []sRatedChange{
+				{At: at(2), FromStatus: compute.VM_INIT, ToStatus: compute.VM_RUNNING},
+				{At: at(10), AddSize: 10240},
+				{At: at(19), FromStatus: compute.VM_RUNNING, ToStatus: compute.VM_READY},
+			},
+			want: []sRatedUsage{
+				{Hours: 8, Status: compute.VM_RUNNING, DiskSize: 10240},
+				{Hours: 9, Status: compute.VM_RUNNING, DiskSize: 20480},
+				{Hours: 1, Status: compute.VM_READY, DiskSize: 20480},
+			},
+		},
+		{
+			name: "created after the day",
+			res:  &sRatedResource{CreatedAt: end.Add(time.Hour)},
+			want: nil,
+		},
+	}
+	for _, c := range cases {
+		got := c.res.usagesIn(start, end, c.changes)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestParseRatedChange(t *testing.T) {
+	cases := []struct {
+		action string
+		notes  string
+		want   sRatedChange
+		ok     bool
+	}{
+		{action: db.ACT_UPDATE_STATUS, notes: "running=>ready", want: sRatedChange{FromStatus: "running", ToStatus: "ready"}, ok: true},
+		{action: db.ACT_UPDATE_STATUS, notes: "running=>stopping: by user", want: sRatedChange{FromStatus: "running", ToStatus: "stopping"}, ok: true},
+		{action: db.ACT_UPDATE_STATUS, notes: "running", ok: false},
+		{action: db.ACT_CHANGE_FLAVOR, notes: `{"add_cpu":2,"add_mem":-1024}`, want: sRatedChange{AddCpu: 2, AddMem: -1024}, ok: true},
+		{action: db.ACT_RESIZE, notes: `{"name":"disk1","size":20480,"add_size":10240}`, want: sRatedChange{AddSize: 10240}, ok: true},
+		{action: db.ACT_RESIZE, notes: `{"name":"disk1","size":20480}`, ok: false},
+		{action: db.ACT_CHANGE_FLAVOR, notes: "invalid", ok: false},
+	}
+	for i, c := range cases {
+		got, ok := parseRatedChange(c.action, c.notes)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("case %d: want %v %v, got %v %v", i, c.want, c.ok, got, ok)
+		}
+	}
+}
+
+func TestRatingContextAddBills(t *testing.T) {
+	start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	rc := &sRatingContext{start: start, end: start.AddDate(0, 0, 1)}
+	res := &sRatedResource{Id: "guest1"}
+	plan := &SPricePlan{CpuPrice: 0.5}
+	usages := []sRatedUsage{
+		{Hours: 6, VcpuCount: 4},
+		{Hours: 10, VcpuCount: 8},
+		{Hours: 8, VcpuCount: 4},
+		{Hours: 3, VcpuCount: 0},
+	}
+	rc.addBills(res, compute.RATING_RESOURCE_SERVER, plan, compute.RATING_ITEM_CPU, "", "vcpu", plan.CpuPrice, usages,
+		func(u *sRatedUsage) float64 { return float64(u.VcpuCount) })
+	if len(rc.bills) != 2 {
+		t.Fatalf("want 2 bills, got %d", len(rc.bills))
+	}
+	want := []struct {
+		quantity, hours, amount float64
+	}{{4, 14, 28}, {8, 10, 40}}
+	for i, w := range want {
+		bill := rc.bills[i]
+		if bill.Quantity != w.quantity || bill.Hours != w.hours || bill.Amount != w.amount {
+			t.Errorf("bill %d: want %v, got quantity %f hours %f amount %f", i, w, bill.Quantity, bill.Hours, bill.Amount)
+		}
+	}
+	if rc.bills[0].Id == rc.bills[1].Id {
+		t.Errorf("bills of different quantities should have different ids")
+	}
+	if id := ratingBillId("2021-03-01", compute.RATING_RESOURCE_SERVER, "guest1", compute.RATING_ITEM_CPU, "", 4); id != rc.bills[0].Id {
+		t.Errorf("regenerated bill should have the same id %s, got %s", rc.bills[0].Id, id)
+	}
+}
+
+func TestPricePlanMatch(t *testing.T) {
+	plan := func(id, domainId, hypervisor string, public bool) SPricePlan {
+		p := SPricePlan{Hypervisor: hypervisor}
+		p.Id = id
+		p.DomainId = domainId
+		if public {
+			p.IsPublic = true
+			p.PublicScope = "system"
+		}
+		return p
+	}
+	m := &sPricePlanMatcher{plans: []SPricePlan{
+		plan("public", "default", "", true),
+		plan("public-kvm", "default", compute.HYPERVISOR_KVM, true),
+		plan("d1", "d1", "", false),
+		plan("d2-esxi", "d2", compute.HYPERVISOR_ESXI, false),
+	}}
+	cases := []struct {
+		domainId   string
+		hypervisor string
+		want       string
+	}{
+		{domainId: "d0", hypervisor: compute.HYPERVISOR_KVM, want: "public-kvm"},
+		{domainId: "d0", hypervisor: compute.HYPERVISOR_ESXI, want: "public"},
+		{domainId: "d1", hypervisor: compute.HYPERVISOR_KVM, want: "d1"},
+		{domainId: "d2", hypervisor: compute.HYPERVISOR_KVM, want: "public-kvm"},
+		{domainId: "d2", hypervisor: compute.HYPERVISOR_ESXI, want: "d2-esxi"},
+		{domainId: "d2", hypervisor: "", want: "d2-esxi"},
+	}
+	for i, c := range cases {
+		got := m.Match(c.domainId, c.hypervisor)
+		if got == nil || got.Id != c.want {
+			t.Errorf("case %d: want %s, got %v", i, c.want, got)
+		}
+	}
+
+	p := SPricePlan{DiskPrice: 0.1, StorageTypePrices: &compute.StorageTypePrices{"rbd": 0.2}}
+	if price := p.GetDiskPrice("rbd"); price != 0.2 {
+		t.Errorf("want disk price 0.2 of rbd, got %f", price)
+	}
+	if price := p.GetDiskPrice("local"); price != 0.1 {
+		t.Errorf("want disk price 0.1 of local, got %f", price)
+	}
+}
@@ -534,9 +534,17 @@ func (sg *SScalingGroup) Scale(ctx context.Context, triggerDesc IScalingTriggerD
 	}
 
 	ret := sg.exec(ctx, action)
+	// record the reasoning of trigger such as target tracking
+	if tr, ok := triggerDesc.(IScalingTriggerReason); ok {
+		if len(ret.reason) > 0 {
+			ret.reason = truncateScalingReason(fmt.Sprintf("%s; %s", tr.TriggerReason(), ret.reason))
+		} else {
+			ret.reason = tr.TriggerReason()
+		}
+	}
 	switch ret.code {
 	case 0:
-		err = scalingActivity.SetResult(ret.actionStr, api.SA_STATUS_SUCCEED, "", ret.intanceNum)
+		err = scalingActivity.SetResult(ret.actionStr, api.SA_STATUS_SUCCEED, ret.reason, ret.intanceNum)
 		isExec = true
 	case 1:
 		err = scalingActivity.SetResult(ret.actionStr, api.SA_STATUS_PART_SUCCEED, ret.reason, ret.intanceNum)
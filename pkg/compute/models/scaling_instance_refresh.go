@@ -0,0 +1,405 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+const (
+	// the latest health state of loadbalancer backend in this window is used
+	instanceRefreshLbHealthWindow = 2 * time.Minute
+)
+
+type SScalingInstanceRefreshManager struct {
+	db.SStatusStandaloneResourceBaseManager
+	SScalingGroupResourceBaseManager
+}
+
+// SScalingInstanceRefresh replaces the instances of scaling group which joined before StartTime in batches,
+// so that the instances are recreated by the latest guest template.
+type SScalingInstanceRefresh struct {
+	db.SStatusStandaloneResourceBase
+	SScalingGroupResourceBase
+
+	// Healthy instances should be kept at least this percentage of the desire instance number
+	MinHealthyPercentage int `nullable:"false" default:"90" allow_zero:"true" list:"user" get:"user"`
+	BatchSize            int `nullable:"false" default:"1" list:"user" get:"user"`
+	// Timeout in seconds of each batch
+	BatchTimeout int `nullable:"false" default:"1800" list:"user" get:"user"`
+
+	TotalNumber    int       `nullable:"false" default:"0" list:"user" get:"user"`
+	ReplacedNumber int       `nullable:"false" default:"0" list:"user" get:"user"`
+	StartTime      time.Time `list:"user" get:"user"`
+	EndTime        time.Time `list:"user" get:"user"`
+
+	// CurrentBatch is the comma separated ids of guests being replaced
+	CurrentBatch  string    `type:"text" list:"user" get:"user"`
+	BatchDeadline time.Time `list:"user" get:"user"`
+	// ActivityId is the scaling activity recording the current batch
+	ActivityId string `width:"36" charset:"ascii" list:"user" get:"user"`
+	Reason     string `width:"1024" charset:"utf8" list:"user" get:"user"`
+}
+
+var ScalingInstanceRefreshManager *SScalingInstanceRefreshManager
+
+func init() {
+	ScalingInstanceRefreshManager = &SScalingInstanceRefreshManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SScalingInstanceRefresh{},
+			"scalinginstancerefreshes_tbl",
+			"scalinginstancerefresh",
+			"scalinginstancerefreshes",
+		),
+	}
+	ScalingInstanceRefreshManager.SetVirtualObject(ScalingInstanceRefreshManager)
+}
+
+func (sirm *SScalingInstanceRefreshManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := sirm.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return sirm.SScalingGroupResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (sirm *SScalingInstanceRefreshManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential, query api.ScalingInstanceRefreshListInput) (*sqlchemy.SQuery, error) {
+	return sirm.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+}
+
+func (sirm *SScalingInstanceRefreshManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ScalingInstanceRefreshDetails {
+	rows := make([]api.ScalingInstanceRefreshDetails, len(objs))
+	statusRows := sirm.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	sgRows := sirm.SScalingGroupResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i].StatusStandaloneResourceDetails = statusRows[i]
+		rows[i].ScalingGroupResourceInfo = sgRows[i]
+	}
+	return rows
+}
+
+func (sirm *SScalingInstanceRefreshManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential, input api.ScalingInstanceRefreshListInput) (*sqlchemy.SQuery, error) {
+	q, err := sirm.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, input.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, err
+	}
+	q, err = sirm.SScalingGroupResourceBaseManager.ListItemFilter(ctx, q, userCred, input.ScalingGroupFilterListInput)
+	if err != nil {
+		return nil, err
+	}
+	q = q.Desc("start_time")
+	return q, nil
+}
+
+func (sirm *SScalingInstanceRefreshManager) NamespaceScope() rbacutils.TRbacScope {
+	return rbacutils.ScopeProject
+}
+
+func (sirm *SScalingInstanceRefreshManager) ResourceScope() rbacutils.TRbacScope {
+	return rbacutils.ScopeProject
+}
+
+func (sirm *SScalingInstanceRefreshManager) FilterByOwner(q *sqlchemy.SQuery, owner mcclient.IIdentityProvider, scope rbacutils.TRbacScope) *sqlchemy.SQuery {
+	if owner != nil {
+		switch scope {
+		case rbacutils.ScopeProject, rbacutils.ScopeDomain:
+			scalingGroupQ := ScalingGroupManager.Query("id", "domain_id").SubQuery()
+			q = q.Join(scalingGroupQ, sqlchemy.Equals(q.Field("scaling_group_id"), scalingGroupQ.Field("id")))
+			q = q.Filter(sqlchemy.Equals(scalingGroupQ.Field("domain_id"), owner.GetProjectDomainId()))
+		}
+	}
+	return q
+}
+
+func (sirm *SScalingInstanceRefreshManager) FetchOwnerId(ctx context.Context, data jsonutils.JSONObject) (mcclient.IIdentityProvider, error) {
+	return db.FetchDomainInfo(ctx, data)
+}
+
+func (sir *SScalingInstanceRefresh) GetOwnerId() mcclient.IIdentityProvider {
+	scalingGroup := sir.GetScalingGroup()
+	if scalingGroup != nil {
+		return scalingGroup.GetOwnerId()
+	}
+	return nil
+}
+
+// InstanceRefreshBatchSize returns the number of instances could be replaced at the same time without
+// the healthy instances falling below minHealthyPercentage of desire
+func InstanceRefreshBatchSize(desire, minHealthyPercentage int) int {
+	size := desire * (100 - minHealthyPercentage) / 100
+	if size < 1 {
+		return 1
+	}
+	return size
+}
+
+func (sir *SScalingInstanceRefresh) CurrentBatchGuestIds() []string {
+	if len(sir.CurrentBatch) == 0 {
+		return nil
+	}
+	return strings.Split(sir.CurrentBatch, ",")
+}
+
+func (sir *SScalingInstanceRefresh) StartBatch(guestIds []string, activityId string, now time.Time) error {
+	_, err := db.Update(sir, func() error {
+		sir.CurrentBatch = strings.Join(guestIds, ",")
+		sir.ActivityId = activityId
+		sir.BatchDeadline = now.Add(time.Duration(sir.BatchTimeout) * time.Second)
+		return nil
+	})
+	return err
+}
+
+func (sir *SScalingInstanceRefresh) FinishBatch(replaced int) error {
+	_, err := db.Update(sir, func() error {
+		sir.ReplacedNumber += replaced
+		sir.CurrentBatch = ""
+		sir.BatchDeadline = time.Time{}
+		sir.ActivityId = ""
+		return nil
+	})
+	return err
+}
+
+func (sir *SScalingInstanceRefresh) SetResult(status, reason string) error {
+	_, err := db.Update(sir, func() error {
+		sir.Status = status
+		sir.EndTime = time.Now()
+		sir.Reason = truncateScalingReason(reason)
+		return nil
+	})
+	return err
+}
+
+// InstanceRefreshInProgress returns the instance refresh in progress of scaling group, nil if there's none
+func (sg *SScalingGroup) InstanceRefreshInProgress() (*SScalingInstanceRefresh, error) {
+	q := ScalingInstanceRefreshManager.Query().Equals("scaling_group_id", sg.Id).
+		Equals("status", api.INSTANCE_REFRESH_STATUS_IN_PROGRESS)
+	refreshes := make([]SScalingInstanceRefresh, 0, 1)
+	err := db.FetchModelObjects(ScalingInstanceRefreshManager, q, &refreshes)
+	if err != nil {
+		return nil, errors.Wrap(err, "db.FetchModelObjects")
+	}
+	if len(refreshes) == 0 {
+		return nil, nil
+	}
+	return &refreshes[0], nil
+}
+
+// OutdatedGuestIds returns at most limit ready guests joined before the time, the earliest first
+func (sg *SScalingGroup) OutdatedGuestIds(before time.Time, limit int) ([]string, error) {
+	q := ScalingGroupGuestManager.Query("guest_id").Equals("scaling_group_id", sg.Id).
+		Equals("guest_status", api.SG_GUEST_STATUS_READY).LT("created_at", before).Asc("created_at")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	return scalingFetchGuestIds(q)
+}
+
+// HealthyGuestIds returns the ready and running guests of scaling group. If the health check mode is loadbalancer,
+// the loadbalancer backends of guests should be healthy too.
+func (sg *SScalingGroup) HealthyGuestIds() ([]string, error) {
+	sggSubQ := ScalingGroupGuestManager.Query("guest_id").Equals("scaling_group_id", sg.Id).
+		Equals("guest_status", api.SG_GUEST_STATUS_READY).SubQuery()
+	q := GuestManager.Query("id").In("id", sggSubQ).Equals("status", api.VM_RUNNING)
+	guestIds, err := scalingFetchGuestIds(q)
+	if err != nil {
+		return nil, err
+	}
+	if sg.HealthCheckMode != api.HEALTH_CHECK_MODE_LOADBALANCER || len(sg.BackendGroupId) == 0 || len(guestIds) == 0 {
+		return guestIds, nil
+	}
+	backends := make([]SLoadbalancerBackend, 0, len(guestIds))
+	bq := LoadbalancerBackendManager.Query().Equals("backend_group_id", sg.BackendGroupId).
+		In("backend_id", guestIds).IsFalse("pending_deleted")
+	err = db.FetchModelObjects(LoadbalancerBackendManager, bq, &backends)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch loadbalancer backends")
+	}
+	if len(backends) == 0 {
+		return nil, nil
+	}
+	lbbg, err := sg.SLoadbalancerBackendgroupResourceBase.GetLoadbalancerBackendGroup()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetLoadbalancerBackendGroup")
+	}
+	dbinst, dbName, err := lbGetInfluxdbByLbId(lbbg.LoadbalancerId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "find influxdb for loadbalancer %s", lbbg.LoadbalancerId)
+	}
+	querySql := fmt.Sprintf(`SELECT last("healthy") FROM "%s".."%s" WHERE "backend_group_id" = '%s' AND time > now() - %ds GROUP BY "backend_id"`,
+		dbName, api.LB_STATS_MEASUREMENT_BACKEND, sg.BackendGroupId, int(instanceRefreshLbHealthWindow.Seconds()))
+	queryRes, err := dbinst.Query(querySql)
+	if err != nil {
+		return nil, errors.Wrap(err, "query influxdb")
+	}
+	healthyBackends := make(map[string]bool)
+	if len(queryRes) > 0 {
+		for _, series := range queryRes[0] {
+			if len(series.Values) == 0 || len(series.Values[0]) < 2 || series.Values[0][1] == nil || series.Tags == nil {
+				continue
+			}
+			backendId, _ := series.Tags.GetString("backend_id")
+			healthy, _ := series.Values[0][1].Int()
+			healthyBackends[backendId] = healthy == 1
+		}
+	}
+	ret := make([]string, 0, len(backends))
+	for i := range backends {
+		if healthyBackends[backends[i].Id] {
+			ret = append(ret, backends[i].BackendId)
+		}
+	}
+	return ret, nil
+}
+
+func scalingFetchGuestIds(q *sqlchemy.SQuery) ([]string, error) {
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "SQuery.Rows")
+	}
+	defer rows.Close()
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// PerformInstanceRefresh starts to replace all instances of scaling group in batches
+func (sg *SScalingGroup) PerformInstanceRefresh(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input api.ScalingGroupInstanceRefreshInput) (jsonutils.JSONObject, error) {
+	if sg.Enabled.IsFalse() {
+		return nil, httperrors.NewForbiddenError("Can't refresh instances of a disabled ScalingGroup")
+	}
+	minHealthyPercentage := 90
+	if input.MinHealthyPercentage != nil {
+		minHealthyPercentage = *input.MinHealthyPercentage
+	}
+	if minHealthyPercentage < 0 || minHealthyPercentage > 100 {
+		return nil, httperrors.NewOutOfRangeError("min_healthy_percentage should be between 0 and 100")
+	}
+	if input.BatchTimeout == 0 {
+		input.BatchTimeout = 1800
+	}
+	if input.BatchTimeout < 300 {
+		return nil, httperrors.NewOutOfRangeError("batch_timeout should be at least 300 seconds")
+	}
+
+	lockman.LockObject(ctx, sg)
+	defer lockman.ReleaseObject(ctx, sg)
+
+	refresh, err := sg.InstanceRefreshInProgress()
+	if err != nil {
+		return nil, errors.Wrap(err, "InstanceRefreshInProgress")
+	}
+	if refresh != nil {
+		return nil, httperrors.NewConflictError("instance refresh '%s' of ScalingGroup is in progress", refresh.Id)
+	}
+	total, err := sg.GuestNumber()
+	if err != nil {
+		return nil, errors.Wrap(err, "GuestNumber")
+	}
+	refresh = &SScalingInstanceRefresh{
+		MinHealthyPercentage: minHealthyPercentage,
+		BatchSize:            InstanceRefreshBatchSize(sg.DesireInstanceNumber, minHealthyPercentage),
+		BatchTimeout:         input.BatchTimeout,
+		TotalNumber:          total,
+		StartTime:            time.Now(),
+	}
+	refresh.ScalingGroupId = sg.Id
+	refresh.Status = api.INSTANCE_REFRESH_STATUS_IN_PROGRESS
+	refresh.SetModelManager(ScalingInstanceRefreshManager, refresh)
+	err = ScalingInstanceRefreshManager.TableSpec().Insert(ctx, refresh)
+	if err != nil {
+		return nil, errors.Wrap(err, "insert instance refresh")
+	}
+	db.OpsLog.LogEvent(sg, db.ACT_UPDATE, fmt.Sprintf("start instance refresh %s", refresh.Id), userCred)
+	return jsonutils.Marshal(refresh), nil
+}
+
+// PerformCancelInstanceRefresh stops the instance refresh in progress, instances already replaced are kept
+func (sg *SScalingGroup) PerformCancelInstanceRefresh(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	lockman.LockObject(ctx, sg)
+	defer lockman.ReleaseObject(ctx, sg)
+
+	refresh, err := sg.InstanceRefreshInProgress()
+	if err != nil {
+		return nil, errors.Wrap(err, "InstanceRefreshInProgress")
+	}
+	if refresh == nil {
+		return nil, httperrors.NewInvalidStatusError("no instance refresh of ScalingGroup is in progress")
+	}
+	err = refresh.SetResult(api.INSTANCE_REFRESH_STATUS_CANCELLED, fmt.Sprintf("cancelled by %s", userCred.GetUserName()))
+	if err != nil {
+		return nil, errors.Wrap(err, "SetResult")
+	}
+	db.OpsLog.LogEvent(sg, db.ACT_UPDATE, fmt.Sprintf("cancel instance refresh %s", refresh.Id), userCred)
+	return nil, nil
+}
+
+// RemoveLifecycleHooksAndInstanceRefreshes deletes the lifecycle hooks and instance refresh records of scaling group
+func (sg *SScalingGroup) RemoveLifecycleHooksAndInstanceRefreshes(ctx context.Context, userCred mcclient.TokenCredential) error {
+	hooks := make([]SScalingLifecycleHook, 0)
+	err := db.FetchModelObjects(ScalingLifecycleHookManager, ScalingLifecycleHookManager.Query().Equals("scaling_group_id", sg.Id), &hooks)
+	if err != nil {
+		return errors.Wrap(err, "fetch lifecycle hooks")
+	}
+	for i := range hooks {
+		err := db.DeleteModel(ctx, userCred, &hooks[i])
+		if err != nil {
+			return errors.Wrapf(err, "delete lifecycle hook %s", hooks[i].Id)
+		}
+	}
+	refreshes := make([]SScalingInstanceRefresh, 0)
+	err = db.FetchModelObjects(ScalingInstanceRefreshManager, ScalingInstanceRefreshManager.Query().Equals("scaling_group_id", sg.Id), &refreshes)
+	if err != nil {
+		return errors.Wrap(err, "fetch instance refreshes")
+	}
+	for i := range refreshes {
+		err := refreshes[i].Delete(ctx, userCred)
+		if err != nil {
+			return errors.Wrapf(err, "delete instance refresh %s", refreshes[i].Id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInstanceRefreshBatchSize(t *testing.T) {
+	cases := []struct {
+		desire     int
+		minHealthy int
+		want       int
+	}{
+		{desire: 10, minHealthy: 90, want: 1},
+		{desire: 10, minHealthy: 50, want: 5},
+		{desire: 10, minHealthy: 0, want: 10},
+		{desire: 3, minHealthy: 90, want: 1},
+		{desire: 25, minHealthy: 75, want: 6},
+		{desire: 0, minHealthy: 90, want: 1},
+	}
+	for i, c := range cases {
+		if got := InstanceRefreshBatchSize(c.desire, c.minHealthy); got != c.want {
+			t.Errorf("case %d: want %d, got %d", i, c.want, got)
+		}
+	}
+}
+
+func TestInstanceRefreshCurrentBatchGuestIds(t *testing.T) {
+	refresh := SScalingInstanceRefresh{}
+	if ids := refresh.CurrentBatchGuestIds(); len(ids) != 0 {
+		t.Errorf("want no guest, got %v", ids)
+	}
+	refresh.CurrentBatch = "g1,g2"
+	if ids := refresh.CurrentBatchGuestIds(); !reflect.DeepEqual(ids, []string{"g1", "g2"}) {
+		t.Errorf("want [g1 g2], got %v", ids)
+	}
+}
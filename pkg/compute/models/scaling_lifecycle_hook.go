@@ -0,0 +1,341 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	ansible_modules "yunion.io/x/onecloud/pkg/mcclient/modules/ansible"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SScalingLifecycleHookManager struct {
+	db.SVirtualResourceBaseManager
+	SScalingGroupResourceBaseManager
+	db.SEnabledResourceBaseManager
+}
+
+// SScalingLifecycleHook pauses the instance when it's launching into or terminating from the scaling group,
+// and the instance goes on after the webhook or ansible playbook reports the result or the timeout is reached.
+type SScalingLifecycleHook struct {
+	db.SVirtualResourceBase
+	SScalingGroupResourceBase
+	db.SEnabledResourceBase
+
+	HookType   string `width:"16" charset:"ascii" nullable:"false" create:"required" list:"user" get:"user"`
+	ActionType string `width:"16" charset:"ascii" nullable:"false" create:"required" list:"user" get:"user"`
+
+	WebhookUrl string `width:"512" charset:"ascii" create:"optional" list:"user" get:"user" update:"user"`
+
+	AnsiblePlaybookReference string `width:"36" charset:"ascii" create:"optional" list:"user" get:"user"`
+	AnsibleUser              string `width:"32" charset:"ascii" default:"root" create:"optional" list:"user" get:"user" update:"user"`
+
+	// Timeout in seconds to wait for the result
+	Timeout       int    `nullable:"false" default:"300" create:"optional" list:"user" get:"user" update:"user"`
+	DefaultResult string `width:"16" charset:"ascii" default:"continue" create:"optional" list:"user" get:"user" update:"user"`
+}
+
+var ScalingLifecycleHookManager *SScalingLifecycleHookManager
+
+func init() {
+	ScalingLifecycleHookManager = &SScalingLifecycleHookManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SScalingLifecycleHook{},
+			"scalinglifecyclehooks_tbl",
+			"scalinglifecyclehook",
+			"scalinglifecyclehooks",
+		),
+	}
+	ScalingLifecycleHookManager.SetVirtualObject(ScalingLifecycleHookManager)
+}
+
+func (slhm *SScalingLifecycleHookManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential, input api.ScalingLifecycleHookListInput) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = slhm.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, input.VirtualResourceListInput)
+	if err != nil {
+		return q, err
+	}
+	q, err = slhm.SScalingGroupResourceBaseManager.ListItemFilter(ctx, q, userCred, input.ScalingGroupFilterListInput)
+	if err != nil {
+		return q, err
+	}
+	q, err = slhm.SEnabledResourceBaseManager.ListItemFilter(ctx, q, userCred, input.EnabledResourceBaseListInput)
+	if err != nil {
+		return q, err
+	}
+	if len(input.HookType) != 0 {
+		q = q.Equals("hook_type", input.HookType)
+	}
+	return q, nil
+}
+
+func (slhm *SScalingLifecycleHookManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := slhm.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return slhm.SScalingGroupResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (slh *SScalingLifecycleHook) GetUniqValues() jsonutils.JSONObject {
+	return jsonutils.Marshal(map[string]string{"scaling_group_id": slh.ScalingGroupId})
+}
+
+func (slhm *SScalingLifecycleHookManager) FetchUniqValues(ctx context.Context, data jsonutils.JSONObject) jsonutils.JSONObject {
+	return slhm.SScalingGroupResourceBaseManager.FetchUniqValues(ctx, data)
+}
+
+func (slhm *SScalingLifecycleHookManager) FilterByUniqValues(q *sqlchemy.SQuery, values jsonutils.JSONObject) *sqlchemy.SQuery {
+	return slhm.SScalingGroupResourceBaseManager.FilterByUniqValues(q, values)
+}
+
+func (slhm *SScalingLifecycleHookManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential, query api.ScalingLifecycleHookListInput) (*sqlchemy.SQuery, error) {
+	return slhm.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+}
+
+func (slhm *SScalingLifecycleHookManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ScalingLifecycleHookDetails {
+	rows := make([]api.ScalingLifecycleHookDetails, len(objs))
+	virtRows := slhm.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	sgRows := slhm.SScalingGroupResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i].VirtualResourceDetails = virtRows[i]
+		rows[i].ScalingGroupResourceInfo = sgRows[i]
+	}
+	return rows
+}
+
+func validateLifecycleWebhookUrl(webhookUrl string) error {
+	u, err := url.Parse(webhookUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return httperrors.NewInputParameterError("invalid webhook url %q", webhookUrl)
+	}
+	return nil
+}
+
+func validateLifecycleDefaultResult(result string) error {
+	if !utils.IsInStringArray(result, []string{api.LIFECYCLE_RESULT_CONTINUE, api.LIFECYCLE_RESULT_ABANDON}) {
+		return httperrors.NewInputParameterError("unknown lifecycle result %q", result)
+	}
+	return nil
+}
+
+func (slhm *SScalingLifecycleHookManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.ScalingLifecycleHookCreateInput) (
+	api.ScalingLifecycleHookCreateInput, error) {
+	var err error
+	input.VirtualResourceCreateInput, err = slhm.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query,
+		input.VirtualResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+
+	// check scaling group
+	idOrName := input.ScalingGroup
+	if len(input.ScalingGroupId) != 0 {
+		idOrName = input.ScalingGroupId
+	}
+	model, err := ScalingGroupManager.FetchByIdOrName(userCred, idOrName)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return input, httperrors.NewInputParameterError("no such scaling group %s", idOrName)
+	}
+	if err != nil {
+		return input, errors.Wrap(err, "ScalingGroupManager.FetchByIdOrName")
+	}
+	sg := model.(*SScalingGroup)
+	if sg.ProjectId != ownerId.GetProjectId() {
+		return input, httperrors.NewForbiddenError("scaling group %s does not belong to project %s", idOrName, ownerId.GetProjectId())
+	}
+	input.ScalingGroupId = sg.GetId()
+
+	if !utils.IsInStringArray(input.HookType, []string{api.LIFECYCLE_HOOK_LAUNCHING, api.LIFECYCLE_HOOK_TERMINATING}) {
+		return input, httperrors.NewInputParameterError("unknown lifecycle hook type %q", input.HookType)
+	}
+	switch input.ActionType {
+	case api.LIFECYCLE_ACTION_WEBHOOK:
+		if len(input.WebhookUrl) == 0 {
+			return input, httperrors.NewMissingParameterError("webhook_url")
+		}
+		if err := validateLifecycleWebhookUrl(input.WebhookUrl); err != nil {
+			return input, err
+		}
+	case api.LIFECYCLE_ACTION_ANSIBLE:
+		if len(input.AnsiblePlaybookReference) == 0 {
+			return input, httperrors.NewMissingParameterError("ansible_playbook_reference")
+		}
+		s := auth.GetSession(ctx, userCred, options.Options.Region, "")
+		ref, err := ansible_modules.AnsiblePlaybookReference.Get(s, input.AnsiblePlaybookReference, nil)
+		if err != nil {
+			return input, httperrors.NewResourceNotFoundError2("ansibleplaybookreference", input.AnsiblePlaybookReference)
+		}
+		input.AnsiblePlaybookReference, _ = ref.GetString("id")
+		if len(input.AnsibleUser) == 0 {
+			input.AnsibleUser = "root"
+		}
+	default:
+		return input, httperrors.NewInputParameterError("unknown lifecycle action type %q", input.ActionType)
+	}
+
+	if input.Timeout == 0 {
+		input.Timeout = 300
+	}
+	if input.Timeout < 30 || input.Timeout > 7200 {
+		return input, httperrors.NewOutOfRangeError("timeout should be between 30 and 7200 seconds")
+	}
+	if len(input.DefaultResult) == 0 {
+		input.DefaultResult = api.LIFECYCLE_RESULT_CONTINUE
+	}
+	if err := validateLifecycleDefaultResult(input.DefaultResult); err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (slh *SScalingLifecycleHook) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input api.ScalingLifecycleHookUpdateInput) (api.ScalingLifecycleHookUpdateInput, error) {
+	var err error
+	input.VirtualResourceBaseUpdateInput, err = slh.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	if len(input.WebhookUrl) != 0 {
+		if slh.ActionType != api.LIFECYCLE_ACTION_WEBHOOK {
+			return input, httperrors.NewInputParameterError("webhook_url is only for lifecycle hook with webhook action")
+		}
+		if err := validateLifecycleWebhookUrl(input.WebhookUrl); err != nil {
+			return input, err
+		}
+	}
+	if input.Timeout != nil && (*input.Timeout < 30 || *input.Timeout > 7200) {
+		return input, httperrors.NewOutOfRangeError("timeout should be between 30 and 7200 seconds")
+	}
+	if len(input.DefaultResult) != 0 {
+		if err := validateLifecycleDefaultResult(input.DefaultResult); err != nil {
+			return input, err
+		}
+	}
+	return input, nil
+}
+
+func (slh *SScalingLifecycleHook) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	// slh.Project must be same with slh.ScalingGroup
+	sg := slh.GetScalingGroup()
+	if sg == nil {
+		return httperrors.NewResourceNotFoundError2(ScalingGroupManager.Keyword(), slh.ScalingGroupId)
+	}
+	slh.Status = api.SP_STATUS_READY
+	slh.SetEnabled(true)
+	return slh.SVirtualResourceBase.CustomizeCreate(ctx, userCred, sg.GetOwnerId(), query, data)
+}
+
+func (slh *SScalingLifecycleHook) PerformEnable(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input apis.PerformEnableInput) (jsonutils.JSONObject, error) {
+	err := db.EnabledPerformEnable(slh, ctx, userCred, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "EnabledPerformEnable")
+	}
+	return nil, nil
+}
+
+func (slh *SScalingLifecycleHook) PerformDisable(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input apis.PerformDisableInput) (jsonutils.JSONObject, error) {
+	err := db.EnabledPerformEnable(slh, ctx, userCred, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "EnabledPerformEnable")
+	}
+	return nil, nil
+}
+
+// LifecycleHooks returns the enabled lifecycle hooks of hookType in order of creation
+func (sg *SScalingGroup) LifecycleHooks(hookType string) ([]SScalingLifecycleHook, error) {
+	q := ScalingLifecycleHookManager.Query().Equals("scaling_group_id", sg.Id).Equals("hook_type", hookType).
+		IsTrue("enabled").Asc("created_at")
+	hooks := make([]SScalingLifecycleHook, 0)
+	err := db.FetchModelObjects(ScalingLifecycleHookManager, q, &hooks)
+	if err != nil {
+		return nil, errors.Wrap(err, "db.FetchModelObjects")
+	}
+	return hooks, nil
+}
+
+// PerformCompleteLifecycleAction is called back by the receiver of the lifecycle hook to report the result
+// for the instance waiting on it
+func (sg *SScalingGroup) PerformCompleteLifecycleAction(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input api.ScalingGroupCompleteLifecycleActionInput) (jsonutils.JSONObject, error) {
+	if err := validateLifecycleDefaultResult(input.Result); err != nil {
+		return nil, err
+	}
+	if len(input.Guest) == 0 {
+		return nil, httperrors.NewMissingParameterError("guest")
+	}
+	guest, err := GuestManager.FetchByIdOrName(userCred, input.Guest)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return nil, httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), input.Guest)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "GuestManager.FetchByIdOrName")
+	}
+	sggs, err := ScalingGroupGuestManager.Fetch(sg.Id, guest.GetId())
+	if err != nil {
+		return nil, errors.Wrap(err, "ScalingGroupGuestManager.Fetch")
+	}
+	if len(sggs) == 0 {
+		return nil, httperrors.NewInputParameterError("Guest '%s' don't belong to ScalingGroup '%s'", guest.GetId(), sg.Id)
+	}
+	sgg := &sggs[0]
+	if !utils.IsInStringArray(sgg.GuestStatus, []string{api.SG_GUEST_STATUS_LAUNCH_WAIT, api.SG_GUEST_STATUS_TERMINATE_WAIT}) {
+		return nil, httperrors.NewInvalidStatusError("Guest '%s' is not waiting on lifecycle hook", guest.GetId())
+	}
+	if len(input.LifecycleHook) != 0 {
+		hook, err := ScalingLifecycleHookManager.FetchByIdOrName(userCred, input.LifecycleHook)
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(ScalingLifecycleHookManager.Keyword(), input.LifecycleHook)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "ScalingLifecycleHookManager.FetchByIdOrName")
+		}
+		if hook.GetId() != sgg.LifecycleHookId {
+			return nil, httperrors.NewConflictError("Guest '%s' is not waiting on lifecycle hook '%s'", guest.GetId(), hook.GetId())
+		}
+	}
+	err = sgg.SetLifecycleResult(input.Result)
+	if err != nil {
+		return nil, errors.Wrap(err, "SetLifecycleResult")
+	}
+	return nil, nil
+}
@@ -189,6 +189,15 @@ func (sp *SScalingPolicy) getMoreDetails(ctx context.Context, userCred mcclient.
 			return out, errors.Wrap(err, "ScalingTimerManager.FetchById")
 		}
 		out.CycleTimer = model.(*SScalingTimer).CycleTimerDetails()
+	case api.TRIGGER_TARGET_TRACKING:
+		model, err := ScalingTargetTrackingManager.FetchById(sp.TriggerId)
+		if errors.Cause(err) == sql.ErrNoRows {
+			return out, nil
+		}
+		if err != nil {
+			return out, errors.Wrap(err, "ScalingTargetTrackingManager.FetchById")
+		}
+		out.TargetTracking = model.(*SScalingTargetTracking).TargetTrackingDetails()
 	}
 
 	return out, nil
@@ -219,14 +228,18 @@ func (spm *SScalingPolicyManager) ValidateCreateData(ctx context.Context, userCr
 	}
 	input.ScalingGroupId = model.GetId()
 
-	if !utils.IsInStringArray(input.TriggerType, []string{api.TRIGGER_TIMING, api.TRIGGER_CYCLE, api.TRIGGER_ALARM}) {
+	if !utils.IsInStringArray(input.TriggerType, []string{api.TRIGGER_TIMING, api.TRIGGER_CYCLE, api.TRIGGER_ALARM,
+		api.TRIGGER_TARGET_TRACKING}) {
 		return input, httperrors.NewInputParameterError("unkown trigger type %s", input.TriggerType)
 	}
-	if !utils.IsInStringArray(input.Action, []string{api.ACTION_ADD, api.ACTION_REMOVE, api.ACTION_SET}) {
-		return input, httperrors.NewInputParameterError("unkown scaling policy action %s", input.Action)
-	}
-	if !utils.IsInStringArray(input.Unit, []string{api.UNIT_ONE, api.UNIT_PERCENT}) {
-		return input, httperrors.NewInputParameterError("unkown scaling policy unit %s", input.Unit)
+	// target tracking computes the desire instance number by itself
+	if input.TriggerType != api.TRIGGER_TARGET_TRACKING {
+		if !utils.IsInStringArray(input.Action, []string{api.ACTION_ADD, api.ACTION_REMOVE, api.ACTION_SET}) {
+			return input, httperrors.NewInputParameterError("unkown scaling policy action %s", input.Action)
+		}
+		if !utils.IsInStringArray(input.Unit, []string{api.UNIT_ONE, api.UNIT_PERCENT}) {
+			return input, httperrors.NewInputParameterError("unkown scaling policy unit %s", input.Unit)
+		}
 	}
 	trigger, err := ScalingPolicyManager.Trigger(&input)
 	if err != nil {
@@ -341,6 +354,17 @@ func (sp *SScalingPolicy) Trigger(input *api.ScalingPolicyCreateInput) (IScaling
 				RealCumulate:       0,
 				LastTriggerTime:    time.Now(),
 			}, nil
+		case api.TRIGGER_TARGET_TRACKING:
+			return &SScalingTargetTracking{
+				SScalingPolicyBase:      SScalingPolicyBase{sp.GetId()},
+				Indicator:               input.TargetTracking.Indicator,
+				TargetValue:             input.TargetTracking.TargetValue,
+				WarmUp:                  input.TargetTracking.WarmUp,
+				DisableScaleIn:          input.TargetTracking.DisableScaleIn,
+				PredictiveMode:          input.TargetTracking.PredictiveMode,
+				PredictiveLookbackWeeks: input.TargetTracking.PredictiveLookbackWeeks,
+				PredictiveAhead:         input.TargetTracking.PredictiveAhead,
+			}, nil
 		default:
 			return nil, fmt.Errorf("unkown trigger type %s", sp.TriggerType)
 		}
@@ -361,6 +385,12 @@ func (sp *SScalingPolicy) Trigger(input *api.ScalingPolicyCreateInput) (IScaling
 			return nil, errors.Wrap(err, "SScalingAlarmManager.FetchById")
 		}
 		return model.(*SScalingAlarm), nil
+	case api.TRIGGER_TARGET_TRACKING:
+		model, err := ScalingTargetTrackingManager.FetchById(sp.TriggerId)
+		if err != nil {
+			return nil, errors.Wrap(err, "SScalingTargetTrackingManager.FetchById")
+		}
+		return model.(*SScalingTargetTracking), nil
 	default:
 		return nil, fmt.Errorf("unkown trigger type %s", sp.TriggerType)
 	}
@@ -374,6 +404,7 @@ func (sp *SScalingPolicy) PerformTrigger(ctx context.Context, userCred mcclient.
 
 	var (
 		triggerDesc IScalingTriggerDesc
+		action      IScalingAction = sp
 		err         error
 	)
 	if sp.Enabled.IsFalse() {
@@ -405,8 +436,12 @@ func (sp *SScalingPolicy) PerformTrigger(ctx context.Context, userCred mcclient.
 			return nil, nil
 		}
 		triggerDesc = trigger
+		// trigger such as target tracking computes the desire instance number by itself
+		if ta, ok := trigger.(IScalingAction); ok {
+			action = ta
+		}
 	}
-	err = sg.Scale(ctx, triggerDesc, sp, sp.CoolingTime)
+	err = sg.Scale(ctx, triggerDesc, action, sp.CoolingTime)
 	if err != nil {
 		return nil, errors.Wrap(err, "ScalingPolicy.Scale")
 	}
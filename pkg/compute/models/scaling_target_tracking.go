@@ -0,0 +1,504 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/influxdb"
+)
+
+const (
+	// metrics of instances in this recent window are averaged as the current value
+	targetTrackingMetricWindow = 5 * time.Minute
+	// no scaling if the ratio of the metric to the target value is within the tolerance
+	targetTrackingTolerance = 0.1
+	// forecast is refreshed at this interval and covers the same span
+	predictiveRefreshInterval = 30 * time.Minute
+)
+
+type SScalingTargetTrackingManager struct {
+	db.SStandaloneResourceBaseManager
+}
+
+// SScalingTargetTracking computes the desire instance number continuously from monitor data
+// to keep the average indicator of instances at the target value
+type SScalingTargetTracking struct {
+	db.SStandaloneResourceBase
+
+	SScalingPolicyBase
+
+	Indicator   string `width:"32" charset:"ascii"`
+	TargetValue float64
+
+	// Metrics of instances joined within WarmUp seconds are ignored and no scaling in happens meanwhile
+	WarmUp         int  `nullable:"false" default:"300"`
+	DisableScaleIn bool `nullable:"false" default:"false"`
+
+	// PredictiveMode instruct to pre-scale by the daily or weekly pattern of last weeks
+	PredictiveMode          string `width:"16" charset:"ascii" default:"off"`
+	PredictiveLookbackWeeks int    `nullable:"false" default:"2"`
+	PredictiveAhead         int    `nullable:"false" default:"600"`
+
+	// Result of last evaluation
+	LastEvaluateTime time.Time
+	LastMetricValue  float64
+	LastDesire       int
+	LastReason       string `width:"1024" charset:"utf8"`
+
+	// Desire instance number by the forecast and when it's computed
+	ForecastDesire int
+	ForecastTime   time.Time
+}
+
+var ScalingTargetTrackingManager *SScalingTargetTrackingManager
+
+func init() {
+	ScalingTargetTrackingManager = &SScalingTargetTrackingManager{
+		SStandaloneResourceBaseManager: db.NewStandaloneResourceBaseManager(
+			SScalingTargetTracking{},
+			"scalingtargettrackings_tbl",
+			"scalingtargettracking",
+			"scalingtargettrackings",
+		),
+	}
+	ScalingTargetTrackingManager.SetVirtualObject(ScalingTargetTrackingManager)
+}
+
+func (stt *SScalingTargetTracking) TargetTrackingDetails() api.ScalingTargetTrackingDetails {
+	return api.ScalingTargetTrackingDetails{
+		Indicator:               stt.Indicator,
+		TargetValue:             stt.TargetValue,
+		WarmUp:                  stt.WarmUp,
+		DisableScaleIn:          stt.DisableScaleIn,
+		PredictiveMode:          stt.PredictiveMode,
+		PredictiveLookbackWeeks: stt.PredictiveLookbackWeeks,
+		PredictiveAhead:         stt.PredictiveAhead,
+		LastEvaluateTime:        stt.LastEvaluateTime,
+		LastMetricValue:         stt.LastMetricValue,
+		LastDesire:              stt.LastDesire,
+		LastReason:              stt.LastReason,
+		ForecastDesire:          stt.ForecastDesire,
+	}
+}
+
+func (stt *SScalingTargetTracking) ValidateCreateData(input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error) {
+	tt := &input.TargetTracking
+	if _, ok := indicatorMap[tt.Indicator]; !ok {
+		return input, httperrors.NewInputParameterError("unkown indicator in target tracking %s", tt.Indicator)
+	}
+	if tt.TargetValue <= 0 {
+		return input, httperrors.NewInputParameterError("target value in target tracking should be positive")
+	}
+	if units[tt.Indicator] == "%" && tt.TargetValue > 100 {
+		return input, httperrors.NewInputParameterError("target value of %s should not be greater than 100", tt.Indicator)
+	}
+	if tt.WarmUp < 0 {
+		return input, httperrors.NewInputParameterError("warm up in target tracking should not be negative")
+	}
+	if tt.WarmUp == 0 {
+		tt.WarmUp = 300
+	}
+	if len(tt.PredictiveMode) == 0 {
+		tt.PredictiveMode = api.PREDICTIVE_MODE_OFF
+	}
+	if !utils.IsInStringArray(tt.PredictiveMode, []string{api.PREDICTIVE_MODE_OFF, api.PREDICTIVE_MODE_DAILY,
+		api.PREDICTIVE_MODE_WEEKLY}) {
+		return input, httperrors.NewInputParameterError("unkown predictive mode %s", tt.PredictiveMode)
+	}
+	if tt.PredictiveLookbackWeeks == 0 {
+		tt.PredictiveLookbackWeeks = 2
+	}
+	if tt.PredictiveLookbackWeeks < 1 || tt.PredictiveLookbackWeeks > 4 {
+		return input, httperrors.NewInputParameterError("predictive lookback weeks should between 1 and 4")
+	}
+	if tt.PredictiveAhead == 0 {
+		tt.PredictiveAhead = 600
+	}
+	if tt.PredictiveAhead < 0 || tt.PredictiveAhead > 86400 {
+		return input, httperrors.NewInputParameterError("predictive ahead should between 0 and 86400")
+	}
+	// the desire instance number is computed, action is always to set it
+	input.Action = api.ACTION_SET
+	input.Unit = api.UNIT_ONE
+	return input, nil
+}
+
+func (stt *SScalingTargetTracking) Register(ctx context.Context, userCred mcclient.TokenCredential) error {
+	err := ScalingTargetTrackingManager.TableSpec().Insert(ctx, stt)
+	if err != nil {
+		return errors.Wrap(err, "STableSpec.Insert")
+	}
+	return nil
+}
+
+func (stt *SScalingTargetTracking) UnRegister(ctx context.Context, userCred mcclient.TokenCredential) error {
+	err := stt.Delete(ctx, userCred)
+	if err != nil {
+		return errors.Wrap(err, "SScalingTargetTracking.Delete")
+	}
+	return nil
+}
+
+func (stt *SScalingTargetTracking) TriggerId() string {
+	return stt.GetId()
+}
+
+func (stt *SScalingTargetTracking) TriggerDescription() string {
+	name := stt.ScalingPolicyId
+	sp, _ := stt.ScalingPolicy()
+	if sp != nil {
+		name = sp.Name
+	}
+	return fmt.Sprintf(`Target tracking task(keep the average %s of the instance at %s) execute scaling policy "%s"`,
+		descs[stt.Indicator], stt.formatValue(stt.TargetValue), name)
+}
+
+// TriggerReason explains how the desire instance number was computed in the last evaluation
+func (stt *SScalingTargetTracking) TriggerReason() string {
+	return stt.LastReason
+}
+
+// IsTrigger evaluates the scaling group against the target and return true if the desire instance number
+// should be changed, the result is kept to be used by Exec.
+func (stt *SScalingTargetTracking) IsTrigger() bool {
+	now := time.Now()
+	state, desire, reason, err := stt.evaluate(now)
+	if err != nil {
+		log.Errorf("evaluate ScalingTargetTracking of ScalingPolicy '%s' failed: %s", stt.ScalingPolicyId, err.Error())
+		reason = fmt.Sprintf("evaluate failed: %s", err.Error())
+		desire = stt.LastDesire
+	}
+	_, uerr := db.Update(stt, func() error {
+		stt.LastEvaluateTime = now
+		stt.LastMetricValue = state.Metric
+		stt.LastDesire = desire
+		stt.LastReason = truncateScalingReason(reason)
+		return nil
+	})
+	if uerr != nil {
+		log.Errorf("db.Update in ScalingTargetTracking.IsTrigger failed: %s", uerr.Error())
+		return false
+	}
+	return err == nil && desire != state.Current
+}
+
+// Exec implement IScalingAction
+func (stt *SScalingTargetTracking) Exec(from int) int {
+	return stt.LastDesire
+}
+
+// CheckCoolTime implement IScalingAction, cooldown only limits scaling in and is handled by the evaluation
+func (stt *SScalingTargetTracking) CheckCoolTime() bool {
+	return false
+}
+
+type sTargetTrackingState struct {
+	// Current is the desire instance number of scaling group
+	Current int
+	// Warming is the number of instances in warm up
+	Warming int
+	// Measured is the number of instances whose metrics are collected
+	Measured  int
+	Metric    float64
+	HasMetric bool
+	// Forecast is the instance number needed by the forecast, 0 means no forecast
+	Forecast int
+	Cooling  bool
+	// Min and Max are the bounds of instance number of scaling group
+	Min int
+	Max int
+}
+
+// evaluate collects the state of scaling group and decides the desire instance number
+func (stt *SScalingTargetTracking) evaluate(now time.Time) (sTargetTrackingState, int, string, error) {
+	var state sTargetTrackingState
+	sg, err := stt.ScalingGroup()
+	if err != nil {
+		return state, 0, "", errors.Wrap(err, "ScalingGroup")
+	}
+	state.Current = sg.DesireInstanceNumber
+	state.Min = sg.MinInstanceNumber
+	state.Max = sg.MaxInstanceNumber
+	state.Cooling = !sg.AllowScale()
+	dbinst, err := scalingGetInfluxdb()
+	if err != nil {
+		return state, 0, "", err
+	}
+	warmingIds, err := stt.warmingGuestIds(sg, now)
+	if err != nil {
+		return state, 0, "", errors.Wrap(err, "warmingGuestIds")
+	}
+	state.Warming = len(warmingIds)
+	state.Metric, state.Measured, err = stt.queryMetric(dbinst, sg.Id, warmingIds)
+	if err != nil {
+		return state, 0, "", errors.Wrap(err, "queryMetric")
+	}
+	state.HasMetric = state.Measured > 0
+	if stt.PredictiveMode == api.PREDICTIVE_MODE_DAILY || stt.PredictiveMode == api.PREDICTIVE_MODE_WEEKLY {
+		state.Forecast, err = stt.forecast(dbinst, sg.Id, now)
+		if err != nil {
+			// forecast is only an addition to the reactive scaling
+			log.Errorf("forecast of ScalingPolicy '%s' failed: %s", stt.ScalingPolicyId, err.Error())
+		}
+	}
+	desire, reason := stt.decide(state)
+	return state, desire, reason, nil
+}
+
+// decide computes the desire instance number and the reason from the state
+func (stt *SScalingTargetTracking) decide(state sTargetTrackingState) (int, string) {
+	var (
+		desire = state.Current
+		reason strings.Builder
+	)
+	if state.HasMetric {
+		desire = targetTrackingDesire(state.Current, state.Metric, stt.TargetValue)
+		fmt.Fprintf(&reason, "the average %s of %d instances is %s against the target %s, %d instances are needed",
+			descs[stt.Indicator], state.Measured, stt.formatValue(state.Metric), stt.formatValue(stt.TargetValue), desire)
+	} else {
+		reason.WriteString("no monitor data of instances")
+	}
+	if state.Warming > 0 {
+		fmt.Fprintf(&reason, ", %d instances are warming up", state.Warming)
+	}
+	if state.Forecast > desire {
+		fmt.Fprintf(&reason, "; the %s forecast needs %d instances in %ds", stt.PredictiveMode, state.Forecast,
+			stt.PredictiveAhead)
+		desire = state.Forecast
+	}
+	if desire < state.Current {
+		switch {
+		case stt.DisableScaleIn:
+			reason.WriteString("; scaling in is disabled")
+			desire = state.Current
+		case state.Warming > 0:
+			reason.WriteString("; no scaling in while instances are warming up")
+			desire = state.Current
+		case state.Cooling:
+			reason.WriteString("; no scaling in during the cooldown")
+			desire = state.Current
+		}
+	}
+	// clamp to the bounds, otherwise the policy keeps triggering at them
+	switch {
+	case desire > state.Max:
+		fmt.Fprintf(&reason, "; limited by the max instance number %d", state.Max)
+		desire = state.Max
+	case desire < state.Min:
+		fmt.Fprintf(&reason, "; limited by the min instance number %d", state.Min)
+		desire = state.Min
+	}
+	return desire, reason.String()
+}
+
+func (stt *SScalingTargetTracking) formatValue(v float64) string {
+	return fmt.Sprintf("%.2f%s", v, units[stt.Indicator])
+}
+
+// targetTrackingDesire returns the instance number to bring the average metric to the target value
+func targetTrackingDesire(current int, metric, target float64) int {
+	if current <= 0 || target <= 0 {
+		return current
+	}
+	ratio := metric / target
+	if math.Abs(ratio-1) <= targetTrackingTolerance {
+		return current
+	}
+	// minus a tiny number to avoid the error of float multiplication
+	return int(math.Ceil(float64(current)*ratio - 1e-9))
+}
+
+func (stt *SScalingTargetTracking) warmingGuestIds(sg *SScalingGroup, now time.Time) ([]string, error) {
+	q := ScalingGroupGuestManager.Query("guest_id").Equals("scaling_group_id", sg.Id)
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.Equals(q.Field("guest_status"), api.SG_GUEST_STATUS_JOINING),
+		sqlchemy.GT(q.Field("created_at"), now.Add(-time.Duration(stt.WarmUp)*time.Second)),
+	))
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "SQuery.Rows")
+	}
+	defer rows.Close()
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// queryMetric returns the average indicator of instances except the warming ones and the number of instances
+func (stt *SScalingTargetTracking) queryMetric(dbinst *influxdb.SInfluxdb, sgId string, excludeIds []string) (float64, int, error) {
+	tf := indicatorMap[stt.Indicator]
+	conds := []string{
+		fmt.Sprintf(`"vm_scaling_group_id" = '%s'`, sgId),
+		fmt.Sprintf("time > now() - %ds", int(targetTrackingMetricWindow.Seconds())),
+	}
+	for _, id := range excludeIds {
+		conds = append(conds, fmt.Sprintf(`"vm_id" != '%s'`, id))
+	}
+	querySql := fmt.Sprintf(`SELECT mean("m"), count("m") FROM (SELECT mean("%s") AS "m" FROM "telegraf".."%s" WHERE %s GROUP BY "vm_id")`,
+		tf.Field, tf.Table, strings.Join(conds, " AND "))
+	queryRes, err := dbinst.Query(querySql)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "query influxdb")
+	}
+	if len(queryRes) == 0 || len(queryRes[0]) == 0 || len(queryRes[0][0].Values) == 0 {
+		return 0, 0, nil
+	}
+	row := queryRes[0][0].Values[0]
+	if len(row) < 3 || row[1] == nil || row[2] == nil {
+		return 0, 0, nil
+	}
+	metric, err := row[1].Float()
+	if err != nil {
+		return 0, 0, nil
+	}
+	count, err := row[2].Int()
+	if err != nil {
+		return 0, 0, nil
+	}
+	return metric, int(count), nil
+}
+
+// forecast returns the instance number needed in the coming period by the pattern of last weeks,
+// it's refreshed every predictiveRefreshInterval
+func (stt *SScalingTargetTracking) forecast(dbinst *influxdb.SInfluxdb, sgId string, now time.Time) (int, error) {
+	if now.Sub(stt.ForecastTime) < predictiveRefreshInterval {
+		return stt.ForecastDesire, nil
+	}
+	period, cycles := 24*time.Hour, stt.PredictiveLookbackWeeks*7
+	if stt.PredictiveMode == api.PREDICTIVE_MODE_WEEKLY {
+		period, cycles = 7*24*time.Hour, stt.PredictiveLookbackWeeks
+	}
+	points, err := stt.queryLoadHistory(dbinst, sgId, time.Duration(stt.PredictiveLookbackWeeks)*7*24*time.Hour)
+	if err != nil {
+		return 0, err
+	}
+	from := now.Add(time.Duration(stt.PredictiveAhead) * time.Second)
+	forecast := 0
+	if load, ok := predictLoad(points, from, from.Add(predictiveRefreshInterval), period, cycles); ok {
+		forecast = int(math.Ceil(load/stt.TargetValue - 1e-9))
+	}
+	_, err = db.Update(stt, func() error {
+		stt.ForecastDesire = forecast
+		stt.ForecastTime = now
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "db.Update")
+	}
+	return forecast, nil
+}
+
+type sLoadPoint struct {
+	Time time.Time
+	// Load is the sum of indicator of all instances
+	Load float64
+}
+
+// queryLoadHistory returns the hourly total load of scaling group in the last duration
+func (stt *SScalingTargetTracking) queryLoadHistory(dbinst *influxdb.SInfluxdb, sgId string, duration time.Duration) ([]sLoadPoint, error) {
+	tf := indicatorMap[stt.Indicator]
+	timeCond := fmt.Sprintf("time > now() - %dh", int(duration.Hours())+1)
+	querySql := fmt.Sprintf(`SELECT sum("m") FROM (SELECT mean("%s") AS "m" FROM "telegraf".."%s" WHERE "vm_scaling_group_id" = '%s' AND %s GROUP BY time(1h), "vm_id") WHERE %s GROUP BY time(1h)`,
+		tf.Field, tf.Table, sgId, timeCond, timeCond)
+	queryRes, err := dbinst.Query(querySql)
+	if err != nil {
+		return nil, errors.Wrap(err, "query influxdb")
+	}
+	points := make([]sLoadPoint, 0)
+	if len(queryRes) == 0 || len(queryRes[0]) == 0 {
+		return points, nil
+	}
+	for _, row := range queryRes[0][0].Values {
+		if len(row) < 2 || row[0] == nil || row[1] == nil {
+			continue
+		}
+		ms, err := row[0].Int()
+		if err != nil {
+			continue
+		}
+		load, err := row[1].Float()
+		if err != nil {
+			continue
+		}
+		points = append(points, sLoadPoint{Time: time.Unix(0, ms*int64(time.Millisecond)), Load: load})
+	}
+	return points, nil
+}
+
+// predictLoad forecasts the load of each hour between from and to as the average load at the same hour of
+// the previous cycles of period, and returns the peak of them
+func predictLoad(points []sLoadPoint, from, to time.Time, period time.Duration, cycles int) (float64, bool) {
+	loads := make(map[int64]float64, len(points))
+	for _, p := range points {
+		loads[p.Time.Truncate(time.Hour).Unix()] = p.Load
+	}
+	var (
+		peak  float64
+		found bool
+	)
+	for t := from.Truncate(time.Hour); !t.After(to); t = t.Add(time.Hour) {
+		sum, n := 0.0, 0
+		for k := 1; k <= cycles; k++ {
+			if load, ok := loads[t.Add(-time.Duration(k)*period).Unix()]; ok {
+				sum += load
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		if avg := sum / float64(n); !found || avg > peak {
+			peak, found = avg, true
+		}
+	}
+	return peak, found
+}
+
+func scalingGetInfluxdb() (*influxdb.SInfluxdb, error) {
+	url, err := auth.GetServiceURL(apis.SERVICE_TYPE_INFLUXDB, options.Options.Region, "", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "get influxdb url")
+	}
+	return influxdb.NewInfluxdb(url), nil
+}
+
+func truncateScalingReason(reason string) string {
+	runes := []rune(reason)
+	if len(runes) > 1024 {
+		return string(runes[:1024])
+	}
+	return reason
+}
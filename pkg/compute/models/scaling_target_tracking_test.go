@@ -0,0 +1,125 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestTargetTrackingDesire(t *testing.T) {
+	cases := []struct {
+		current int
+		metric  float64
+		target  float64
+		want    int
+	}{
+		{current: 4, metric: 80, target: 55, want: 6},
+		{current: 4, metric: 57, target: 55, want: 4},
+		{current: 4, metric: 20, target: 55, want: 2},
+		{current: 4, metric: 68.75, target: 55, want: 5},
+		{current: 0, metric: 80, target: 55, want: 0},
+	}
+	for i, c := range cases {
+		if got := targetTrackingDesire(c.current, c.metric, c.target); got != c.want {
+			t.Errorf("case %d: want %d, got %d", i, c.want, got)
+		}
+	}
+}
+
+func TestPredictLoad(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	now := time.Date(2020, 4, 20, 8, 50, 0, 0, time.UTC)
+	points := []sLoadPoint{
+		{Time: now.Add(-week).Truncate(time.Hour).Add(time.Hour), Load: 300},
+		{Time: now.Add(-2 * week).Truncate(time.Hour).Add(time.Hour), Load: 500},
+		{Time: now.Add(-week).Truncate(time.Hour), Load: 100},
+		{Time: now.Add(-24 * time.Hour).Truncate(time.Hour).Add(time.Hour), Load: 1000},
+	}
+	load, ok := predictLoad(points, now.Add(10*time.Minute), now.Add(40*time.Minute), week, 2)
+	if !ok || load != 400 {
+		t.Fatalf("want 400, got %f(%v)", load, ok)
+	}
+	_, ok = predictLoad(points, now.Add(3*time.Hour), now.Add(4*time.Hour), week, 2)
+	if ok {
+		t.Fatalf("want no forecast")
+	}
+}
+
+func TestScalingTargetTrackingDecide(t *testing.T) {
+	stt := &SScalingTargetTracking{
+		Indicator:      compute.INDICATOR_CPU,
+		TargetValue:    50,
+		PredictiveMode: compute.PREDICTIVE_MODE_WEEKLY,
+	}
+	cases := []struct {
+		state  sTargetTrackingState
+		want   int
+		reason string
+	}{
+		{
+			state:  sTargetTrackingState{Min: 1, Max: 10, Current: 4, Measured: 4, Metric: 75, HasMetric: true},
+			want:   6,
+			reason: "6 instances are needed",
+		},
+		{
+			state:  sTargetTrackingState{Min: 1, Max: 10, Current: 4, Measured: 4, Metric: 75, HasMetric: true, Forecast: 8},
+			want:   8,
+			reason: "weekly forecast needs 8 instances",
+		},
+		{
+			state:  sTargetTrackingState{Min: 1, Max: 10, Current: 4, Measured: 3, Warming: 1, Metric: 20, HasMetric: true},
+			want:   4,
+			reason: "no scaling in while instances are warming up",
+		},
+		{
+			state:  sTargetTrackingState{Min: 1, Max: 10, Current: 4, Measured: 4, Metric: 20, HasMetric: true, Cooling: true},
+			want:   4,
+			reason: "no scaling in during the cooldown",
+		},
+		{
+			state:  sTargetTrackingState{Min: 1, Max: 10, Current: 4, Measured: 4, Metric: 20, HasMetric: true},
+			want:   2,
+			reason: "2 instances are needed",
+		},
+		{
+			state:  sTargetTrackingState{Min: 1, Max: 10, Current: 4},
+			want:   4,
+			reason: "no monitor data",
+		},
+		{
+			state:  sTargetTrackingState{Min: 1, Max: 5, Current: 5, Measured: 5, Metric: 90, HasMetric: true},
+			want:   5,
+			reason: "limited by the max instance number 5",
+		},
+		{
+			state:  sTargetTrackingState{Min: 3, Max: 10, Current: 4, Measured: 4, Metric: 10, HasMetric: true},
+			want:   3,
+			reason: "limited by the min instance number 3",
+		},
+	}
+	for i, c := range cases {
+		got, reason := stt.decide(c.state)
+		if got != c.want {
+			t.Errorf("case %d: want %d, got %d", i, c.want, got)
+		}
+		if !strings.Contains(reason, c.reason) {
+			t.Errorf("case %d: reason %q should contain %q", i, reason, c.reason)
+		}
+	}
+}
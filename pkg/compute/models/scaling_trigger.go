@@ -38,6 +38,11 @@ type IScalingTriggerDesc interface {
 	TriggerDescription() string
 }
 
+// IScalingTriggerReason is implemented by the trigger which explains its decision in detail
+type IScalingTriggerReason interface {
+	TriggerReason() string
+}
+
 type IScalingTrigger interface {
 	IScalingTriggerDesc
 
@@ -318,6 +323,7 @@ type sTableField struct {
 
 var indicatorMap = map[string]sTableField{
 	api.INDICATOR_CPU:        {"vm_cpu", "usage_active"},
+	api.INDICATOR_MEM:        {"vm_mem", "used_percent"},
 	api.INDICATOR_DISK_WRITE: {"vm_diskio", "write_bps"},
 	api.INDICATOR_DISK_READ:  {"vm_diskio", "read_bps"},
 	api.INDICATOR_FLOW_INTO:  {"vm_netio", "bps_recv"},
@@ -53,6 +53,11 @@ type SScalingGroupGuest struct {
 	ScalingGroupId string            `width:"36" charset:"ascii" nullable:"false"`
 	GuestStatus    string            `width:"36" charset:"ascii" nullable:"false" index:"true"`
 	Manual         tristate.TriState `nullable:"false" default:"false"`
+
+	// LifecycleHookId is the lifecycle hook the guest is waiting on
+	LifecycleHookId   string `width:"36" charset:"ascii"`
+	LifecycleResult   string `width:"16" charset:"ascii"`
+	LifecycleDeadline time.Time
 }
 
 func (sggm *SScalingGroupGuestManager) GetSlaveFieldName() string {
@@ -110,3 +115,35 @@ func (sggm *SScalingGroupGuestManager) Query(fields ...string) *sqlchemy.SQuery
 	return sggm.SVirtualJointResourceBaseManager.Query(fields...).NotEquals("guest_status",
 		compute.SG_GUEST_STATUS_PENDING_REMOVE)
 }
+
+// StartLifecycleAction makes the guest wait on the lifecycle hook until the result is reported or the deadline
+func (sgg *SScalingGroupGuest) StartLifecycleAction(hookId, status string, deadline time.Time) error {
+	_, err := db.Update(sgg, func() error {
+		sgg.GuestStatus = status
+		sgg.LifecycleHookId = hookId
+		sgg.LifecycleResult = ""
+		sgg.LifecycleDeadline = deadline
+		return nil
+	})
+	return err
+}
+
+func (sgg *SScalingGroupGuest) SetLifecycleResult(result string) error {
+	_, err := db.Update(sgg, func() error {
+		sgg.LifecycleResult = result
+		return nil
+	})
+	return err
+}
+
+// FinishLifecycleAction clears the lifecycle hook info and restore the guest status
+func (sgg *SScalingGroupGuest) FinishLifecycleAction(status string) error {
+	_, err := db.Update(sgg, func() error {
+		sgg.GuestStatus = status
+		sgg.LifecycleHookId = ""
+		sgg.LifecycleResult = ""
+		sgg.LifecycleDeadline = time.Time{}
+		return nil
+	})
+	return err
+}
@@ -16,11 +16,14 @@ package models
 
 import (
 	"context"
+	"database/sql"
 	"net"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
 	"yunion.io/x/pkg/util/regutils"
 	"yunion.io/x/pkg/util/secrules"
 	"yunion.io/x/pkg/util/stringutils"
@@ -282,6 +285,16 @@ func (manager *SSecurityGroupRuleManager) ValidateCreateData(ctx context.Context
 		}
 	}
 
+	if len(input.VpcPeeringConnectionId) > 0 {
+		if len(input.PeerSecgroupId) > 0 {
+			return input, httperrors.NewConflictError("vpc_peering_connection_id and peer_secgroup_id are mutually exclusive")
+		}
+		input.CIDR, err = secgroup.validatePeerVpcCidr(input.VpcPeeringConnectionId, input.CIDR)
+		if err != nil {
+			return input, err
+		}
+	}
+
 	err = input.Check()
 	if err != nil {
 		return input, err
@@ -294,6 +307,82 @@ func (manager *SSecurityGroupRuleManager) ValidateCreateData(ctx context.Context
 	return input, nil
 }
 
+// validatePeerVpcCidr returns the cidr of rule referring to the peer vpc of
+// an accepted onecloud vpc peering connection.  The vpc of the other side must
+// be in the domain of the secgroup, the peer vpc may be in another domain
+func (self *SSecurityGroup) validatePeerVpcCidr(peeringId string, cidr string) (string, error) {
+	obj, err := VpcPeeringConnectionManager.FetchById(peeringId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return "", httperrors.NewResourceNotFoundError2(VpcPeeringConnectionManager.Keyword(), peeringId)
+		}
+		return "", httperrors.NewGeneralError(err)
+	}
+	peering := obj.(*SVpcPeeringConnection)
+	if peering.Status != api.VPC_PEERING_CONNECTION_STATUS_ACTIVE {
+		return "", httperrors.NewInvalidStatusError("vpc peering connection %s status is %s", peering.Name, peering.Status)
+	}
+	vpc, err := peering.GetVpc()
+	if err != nil {
+		return "", httperrors.NewGeneralError(err)
+	}
+	peerVpc, err := peering.GetPeerVpc()
+	if err != nil {
+		return "", httperrors.NewGeneralError(err)
+	}
+	if vpc.IsManaged() || peerVpc.IsManaged() {
+		return "", httperrors.NewUnsupportOperationError("only onecloud vpc peering connection can be referred by secgroup rule")
+	}
+	cidrBlocks := []string{}
+	switch {
+	case vpc.DomainId == self.DomainId && peerVpc.DomainId == self.DomainId:
+		// both sides are visible to the secgroup owner
+		cidrBlocks = append(cidrBlocks, vpc.CidrBlock, peerVpc.CidrBlock)
+	case vpc.DomainId == self.DomainId:
+		cidrBlocks = append(cidrBlocks, peerVpc.CidrBlock)
+	case peerVpc.DomainId == self.DomainId:
+		cidrBlocks = append(cidrBlocks, vpc.CidrBlock)
+	default:
+		return "", httperrors.NewForbiddenError("vpc peering connection %s is not connected to vpc of domain %s", peering.Name, self.DomainId)
+	}
+	return checkPeerVpcCidr(cidr, strings.Join(cidrBlocks, ","))
+}
+
+// checkPeerVpcCidr makes sure cidr is within the comma separated cidr blocks
+// of peer vpc, the only block is taken if cidr is empty
+func checkPeerVpcCidr(cidr string, cidrBlock string) (string, error) {
+	blocks := []netutils.IPV4Prefix{}
+	for _, block := range strings.Split(cidrBlock, ",") {
+		if len(block) == 0 {
+			continue
+		}
+		prefix, err := netutils.NewIPV4Prefix(block)
+		if err != nil {
+			return "", httperrors.NewGeneralError(errors.Wrapf(err, "invalid vpc cidr block %s", block))
+		}
+		blocks = append(blocks, prefix)
+	}
+	if len(blocks) == 0 {
+		return "", httperrors.NewInputParameterError("peer vpc has no cidr block")
+	}
+	if len(cidr) == 0 {
+		if len(blocks) > 1 {
+			return "", httperrors.NewMissingParameterError("cidr")
+		}
+		return blocks[0].String(), nil
+	}
+	prefix, err := netutils.NewIPV4Prefix(cidr)
+	if err != nil {
+		return "", httperrors.NewInputParameterError("invalid ipv4 cidr %s", cidr)
+	}
+	for _, block := range blocks {
+		if block.ToIPRange().ContainsRange(prefix.ToIPRange()) {
+			return cidr, nil
+		}
+	}
+	return "", httperrors.NewInputParameterError("cidr %s is not in peer vpc cidr block %s", cidr, cidrBlock)
+}
+
 func (self *SSecurityGroupRule) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SSecgroupRuleUpdateInput) (api.SSecgroupRuleUpdateInput, error) {
 	priority := int(self.Priority)
 	if input.Priority == nil {
@@ -351,6 +440,25 @@ func (self *SSecurityGroupRule) String() string {
 	return rule.String()
 }
 
+// kvmRuleString returns rule passed to hosts in guest desc.  Peer security
+// group rule matches the ipset of the peer security group, whose addresses
+// are passed along in security_ipsets of guest desc
+func (self *SSecurityGroupRule) kvmRuleString() string {
+	if len(self.PeerSecgroupId) == 0 {
+		return self.String()
+	}
+	rule := *self
+	rule.PeerSecgroupId = ""
+	rule.CIDR = ""
+	s := rule.String()
+	if s == "" {
+		return ""
+	}
+	// direction:action [cidr] protocol [ports], with cidr omitted for any
+	segs := strings.SplitN(s, " ", 2)
+	return segs[0] + " " + api.SECGROUP_PEER_IPSET_PREFIX + self.PeerSecgroupId + " " + segs[1]
+}
+
 func (self *SSecurityGroupRule) toRule() (*secrules.SecurityRule, error) {
 	rule := secrules.SecurityRule{
 		Priority:    int(self.Priority),
@@ -361,11 +469,19 @@ func (self *SSecurityGroupRule) toRule() (*secrules.SecurityRule, error) {
 	}
 	if regutils.MatchCIDR(self.CIDR) {
 		_, rule.IPNet, _ = net.ParseCIDR(self.CIDR)
-	} else if regutils.MatchIPAddr(self.CIDR) {
+	} else if regutils.MatchIP4Addr(self.CIDR) {
 		rule.IPNet = &net.IPNet{
 			IP:   net.ParseIP(self.CIDR),
 			Mask: net.CIDRMask(32, 32),
 		}
+	} else if _, ipnet, err := net.ParseCIDR(self.CIDR); err == nil {
+		// ipv6 cidr
+		rule.IPNet = ipnet
+	} else if regutils.MatchIP6Addr(self.CIDR) {
+		rule.IPNet = &net.IPNet{
+			IP:   net.ParseIP(self.CIDR),
+			Mask: net.CIDRMask(128, 128),
+		}
 	} else {
 		rule.IPNet = &net.IPNet{
 			IP:   net.IPv4zero,
@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package models
+
+import (
+	"testing"
+)
+
+func TestSecurityGroupRuleKvmRuleString(t *testing.T) {
+	cases := []struct {
+		name string
+		rule SSecurityGroupRule
+		want string
+	}{
+		{
+			name: "cidr",
+			rule: SSecurityGroupRule{Priority: 1, Direction: "in", Action: "allow", Protocol: "tcp", Ports: "22", CIDR: "10.0.0.0/8"},
+			want: "in:allow 10.0.0.0/8 tcp 22",
+		},
+		{
+			name: "peer with ports",
+			rule: SSecurityGroupRule{Priority: 1, Direction: "in", Action: "allow", Protocol: "tcp", Ports: "80,443", PeerSecgroupId: "sg-web"},
+			want: "in:allow ipset:sg-web tcp 80,443",
+		},
+		{
+			name: "peer any protocol",
+			rule: SSecurityGroupRule{Priority: 1, Direction: "out", Action: "deny", Protocol: "any", PeerSecgroupId: "sg-db"},
+			want: "out:deny ipset:sg-db any",
+		},
+	}
+	for _, c := range cases {
+		if got := c.rule.kvmRuleString(); got != c.want {
+			t.Errorf("%s: want %q, got %q", c.name, c.want, got)
+		}
+	}
+}
+
+func TestCheckPeerVpcCidr(t *testing.T) {
+	cases := []struct {
+		name      string
+		cidr      string
+		cidrBlock string
+		want      string
+		wantErr   bool
+	}{
+		{name: "default to the only block", cidr: "", cidrBlock: "10.1.0.0/16", want: "10.1.0.0/16"},
+		{name: "subnet", cidr: "10.1.2.0/24", cidrBlock: "10.1.0.0/16", want: "10.1.2.0/24"},
+		{name: "single ip", cidr: "10.1.2.3", cidrBlock: "10.1.0.0/16", want: "10.1.2.3"},
+		{name: "second block", cidr: "192.168.1.0/24", cidrBlock: "10.1.0.0/16,192.168.0.0/16", want: "192.168.1.0/24"},
+		{name: "ambiguous default", cidr: "", cidrBlock: "10.1.0.0/16,192.168.0.0/16", wantErr: true},
+		{name: "outside", cidr: "10.2.0.0/24", cidrBlock: "10.1.0.0/16", wantErr: true},
+		{name: "wider than block", cidr: "10.0.0.0/8", cidrBlock: "10.1.0.0/16", wantErr: true},
+		{name: "any", cidr: "0.0.0.0/0", cidrBlock: "10.1.0.0/16", wantErr: true},
+		{name: "no block", cidr: "10.1.0.0/24", cidrBlock: "", wantErr: true},
+		{name: "invalid", cidr: "10.1.0.0/33", cidrBlock: "10.1.0.0/16", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := checkPeerVpcCidr(c.cidr, c.cidrBlock)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
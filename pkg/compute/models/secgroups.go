@@ -354,6 +354,57 @@ func (self *SSecurityGroup) GetKvmGuests() ([]SGuest, error) {
 	return guests, nil
 }
 
+// GetGuestAddresses returns ipv4 and ipv6 addresses of guests in the
+// security group, which are what peer security group rules referring to it match
+func (self *SSecurityGroup) GetGuestAddresses() ([]string, error) {
+	guests := self.GetGuestsQuery().SubQuery()
+	guestnetworks := GuestnetworkManager.Query().SubQuery()
+	q := guestnetworks.Query(guestnetworks.Field("ip_addr"), guestnetworks.Field("ip6_addr")).
+		Filter(sqlchemy.In(guestnetworks.Field("guest_id"), guests.Query(guests.Field("id")).SubQuery())).
+		Filter(sqlchemy.OR(
+			sqlchemy.IsNotEmpty(guestnetworks.Field("ip_addr")),
+			sqlchemy.IsNotEmpty(guestnetworks.Field("ip6_addr")),
+		))
+	rows := []struct {
+		IpAddr  string
+		Ip6Addr string
+	}{}
+	err := q.All(&rows)
+	if err != nil {
+		return nil, errors.Wrap(err, "q.All")
+	}
+	addrs := stringutils2.NewSortedStrings(nil)
+	for i := range rows {
+		for _, addr := range []string{rows[i].IpAddr, rows[i].Ip6Addr} {
+			if len(addr) > 0 {
+				addrs = addrs.Append(addr)
+			}
+		}
+	}
+	return []string(addrs), nil
+}
+
+// syncPeerReferrers resyncs kvm guests of security groups having rules
+// referring to any of secgroupIds as peer, for the change of its members.
+// The desc synced carries the members in security_ipsets, with which hosts
+// refresh their ipsets of the peer security groups
+func (manager *SSecurityGroupManager) syncPeerReferrers(ctx context.Context, userCred mcclient.TokenCredential, secgroupIds []string) {
+	if len(secgroupIds) == 0 {
+		return
+	}
+	rules := SecurityGroupRuleManager.Query("secgroup_id").In("peer_secgroup_id", secgroupIds).Distinct()
+	secgroups := []SSecurityGroup{}
+	q := manager.Query().In("id", rules.SubQuery())
+	err := db.FetchModelObjects(manager, q, &secgroups)
+	if err != nil {
+		log.Errorf("fetch peer secgroup referrers of %s: %v", secgroupIds, err)
+		return
+	}
+	for i := range secgroups {
+		secgroups[i].DoSync(ctx, userCred)
+	}
+}
+
 func (self *SSecurityGroup) GetSecgroupCacheQuery() *sqlchemy.SQuery {
 	return SecurityGroupCacheManager.Query().Equals("secgroup_id", self.Id)
 }
@@ -691,7 +742,7 @@ func (self *SSecurityGroup) getSecurityRuleString() (string, error) {
 	}
 	var rules []string
 	for _, rule := range secgrouprules {
-		rules = append(rules, rule.String())
+		rules = append(rules, rule.kvmRuleString())
 	}
 	return strings.Join(rules, SECURITY_GROUP_SEPARATOR), nil
 }
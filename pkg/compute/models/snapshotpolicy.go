@@ -26,6 +26,7 @@ import (
 	"yunion.io/x/pkg/tristate"
 	"yunion.io/x/pkg/util/compare"
 	"yunion.io/x/pkg/util/sets"
+	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
@@ -33,11 +34,13 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/options"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/bitmap"
+	"yunion.io/x/onecloud/pkg/util/cronexpr"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
 	"yunion.io/x/onecloud/pkg/util/validate"
@@ -57,12 +60,36 @@ type SSnapshotPolicy struct {
 	RetentionDays int `nullable:"false" list:"user" get:"user" create:"required"`
 
 	// 1~7, 1 is Monday
-	RepeatWeekdays uint8 `charset:"utf8" create:"required" list:"user" get:"user"`
+	RepeatWeekdays uint8 `charset:"utf8" create:"optional" list:"user" get:"user"`
 	// 0~23
-	TimePoints  uint32            `charset:"utf8" create:"required" list:"user" get:"user"`
+	TimePoints  uint32            `charset:"utf8" create:"optional" list:"user" get:"user"`
 	IsActivated tristate.TriState `list:"user" get:"user" create:"optional" default:"true"`
+
+	// cron表达式, 设置后忽略 RepeatWeekdays 和 TimePoints
+	CronExpression string `width:"64" charset:"ascii" nullable:"true" list:"user" get:"user" create:"optional"`
+
+	// 保留最近的自动快照个数
+	RetentionCount int `nullable:"false" default:"0" list:"user" get:"user" create:"optional"`
+	// 按天保留快照的天数
+	RetentionDaily int `nullable:"false" default:"0" list:"user" get:"user" create:"optional"`
+	// 按周保留快照的周数
+	RetentionWeekly int `nullable:"false" default:"0" list:"user" get:"user" create:"optional"`
+	// 按月保留快照的月数
+	RetentionMonthly int `nullable:"false" default:"0" list:"user" get:"user" create:"optional"`
+
+	// 生效的磁盘类型, 为空则对所有磁盘生效
+	DiskType string `width:"32" charset:"ascii" nullable:"true" list:"user" get:"user" create:"optional"`
+	// 是否对虚拟机所有磁盘同时做快照
+	ConsistencyGroup bool `nullable:"false" default:"false" list:"user" get:"user" create:"optional"`
+	// 快照复制的目标备份存储
+	BackupStorageId string `width:"36" charset:"ascii" nullable:"true" list:"user" get:"user" create:"optional"`
 }
 
+const (
+	// the minimal interval between two snapshots scheduled by cron expression
+	SNAPSHOT_POLICY_MIN_CRON_INTERVAL = 15 * time.Minute
+)
+
 var SnapshotPolicyManager *SSnapshotPolicyManager
 
 func init() {
@@ -124,6 +151,7 @@ func (manager *SSnapshotPolicyManager) GetSnapshotPoliciesAt(week, timePoint uin
 	q = q.Filter(sqlchemy.Equals(sqlchemy.AND_Val("", q.Field("repeat_weekdays"), 1<<week), 1<<week))
 	q = q.Filter(sqlchemy.Equals(sqlchemy.AND_Val("", q.Field("time_points"), 1<<timePoint), 1<<timePoint))
 	q = q.Equals("is_activated", true)
+	q = q.Filter(sqlchemy.IsNullOrEmpty(q.Field("cron_expression")))
 
 	sps := make([]SSnapshotPolicy, 0)
 	err := q.All(&sps)
@@ -179,32 +207,54 @@ func (manager *SSnapshotPolicyManager) ValidateCreateData(ctx context.Context, u
 		return nil, err
 	}
 
-	if input.RetentionDays < -1 || input.RetentionDays == 0 || input.RetentionDays > options.Options.RetentionDaysLimit {
-		return nil, httperrors.NewInputParameterError("Retention days must in 1~%d or -1", options.Options.RetentionDaysLimit)
+	err = manager.validateRetention(input)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(input.RepeatWeekdays) == 0 {
-		return nil, httperrors.NewMissingParameterError("repeat_weekdays")
-	}
+	if len(input.CronExpression) > 0 {
+		if _, err := validateSnapshotPolicyCron(input.CronExpression); err != nil {
+			return nil, httperrors.NewInputParameterError("invalid cron_expression %q: %v", input.CronExpression, err)
+		}
+		input.RepeatWeekdays, input.TimePoints = nil, nil
+	} else {
+		if len(input.RepeatWeekdays) == 0 {
+			return nil, httperrors.NewMissingParameterError("repeat_weekdays")
+		}
 
-	if len(input.RepeatWeekdays) > options.Options.RepeatWeekdaysLimit {
-		return nil, httperrors.NewInputParameterError("repeat_weekdays only contains %d days at most",
-			options.Options.RepeatWeekdaysLimit)
-	}
-	input.RepeatWeekdays, err = validate.DaysCheck(input.RepeatWeekdays, 1, 7)
-	if err != nil {
-		return nil, httperrors.NewInputParameterError("%v", err)
+		if len(input.RepeatWeekdays) > options.Options.RepeatWeekdaysLimit {
+			return nil, httperrors.NewInputParameterError("repeat_weekdays only contains %d days at most",
+				options.Options.RepeatWeekdaysLimit)
+		}
+		input.RepeatWeekdays, err = validate.DaysCheck(input.RepeatWeekdays, 1, 7)
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("%v", err)
+		}
+
+		if len(input.TimePoints) == 0 {
+			return nil, httperrors.NewMissingParameterError("time_points")
+		}
+		if len(input.TimePoints) > options.Options.TimePointsLimit {
+			return nil, httperrors.NewInputParameterError("time_points only contains %d points at most", options.Options.TimePointsLimit)
+		}
+		input.TimePoints, err = validate.DaysCheck(input.TimePoints, 0, 23)
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("%v", err)
+		}
 	}
 
-	if len(input.TimePoints) == 0 {
-		return nil, httperrors.NewMissingParameterError("time_points")
+	if !utils.IsInStringArray(input.DiskType, []string{"", api.DISK_TYPE_SYS, api.DISK_TYPE_DATA}) {
+		return nil, httperrors.NewInputParameterError("disk_type must be %s or %s", api.DISK_TYPE_SYS, api.DISK_TYPE_DATA)
 	}
-	if len(input.TimePoints) > options.Options.TimePointsLimit {
-		return nil, httperrors.NewInputParameterError("time_points only contains %d points at most", options.Options.TimePointsLimit)
+	if input.ConsistencyGroup && len(input.DiskType) > 0 {
+		return nil, httperrors.NewConflictError("consistency_group snapshots all disks of server, conflict with disk_type")
 	}
-	input.TimePoints, err = validate.DaysCheck(input.TimePoints, 0, 23)
-	if err != nil {
-		return nil, httperrors.NewInputParameterError("%v", err)
+
+	if len(input.BackupStorageId) > 0 {
+		_, err = validators.ValidateModel(userCred, BackupStorageManager, &input.BackupStorageId)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	internalInput := manager.sSnapshotPolicyCreateInputToInternal(input)
@@ -212,6 +262,61 @@ func (manager *SSnapshotPolicyManager) ValidateCreateData(ctx context.Context, u
 	return data, nil
 }
 
+func (manager *SSnapshotPolicyManager) validateRetention(input *api.SSnapshotPolicyCreateInput) error {
+	for k, v := range map[string]int{
+		"retention_count":   input.RetentionCount,
+		"retention_daily":   input.RetentionDaily,
+		"retention_weekly":  input.RetentionWeekly,
+		"retention_monthly": input.RetentionMonthly,
+	} {
+		if v < 0 {
+			return httperrors.NewInputParameterError("%s must not be negative", k)
+		}
+	}
+	keep := input.RetentionCount + input.RetentionDaily + input.RetentionWeekly + input.RetentionMonthly
+	if keep > 0 {
+		autoSnapshotCount := options.Options.DefaultMaxSnapshotCount - options.Options.DefaultMaxManualSnapshotCount
+		if keep > autoSnapshotCount {
+			return httperrors.NewOutOfLimitError("retained snapshots %d exceeds max auto snapshot count %d", keep, autoSnapshotCount)
+		}
+		// snapshots are retained by count, keep them until rotated out
+		if input.RetentionDays == 0 {
+			input.RetentionDays = -1
+		}
+	}
+	if input.RetentionDays < -1 || input.RetentionDays == 0 || input.RetentionDays > options.Options.RetentionDaysLimit {
+		return httperrors.NewInputParameterError("Retention days must in 1~%d or -1", options.Options.RetentionDaysLimit)
+	}
+	return nil
+}
+
+// validateSnapshotPolicyCron parses the cron expression and makes sure that
+// two successive snapshots are at least SNAPSHOT_POLICY_MIN_CRON_INTERVAL apart
+func validateSnapshotPolicyCron(spec string) (*cronexpr.Expression, error) {
+	expr, err := cronexpr.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	prev := expr.Next(time.Now())
+	if prev.IsZero() {
+		return nil, errors.Errorf("never scheduled")
+	}
+	// sampling the following occurrences covers at least a week when the
+	// interval is minimal, which is enough to hit the gaps of hour and day boundaries
+	for i := 0; i < 1024; i++ {
+		next := expr.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if next.Sub(prev) < SNAPSHOT_POLICY_MIN_CRON_INTERVAL {
+			return nil, errors.Errorf("interval between %s and %s is less than %s",
+				prev.Format(time.RFC3339), next.Format(time.RFC3339), SNAPSHOT_POLICY_MIN_CRON_INTERVAL)
+		}
+		prev = next
+	}
+	return expr, nil
+}
+
 func (manager *SSnapshotPolicyManager) OnCreateComplete(ctx context.Context, items []db.IModel,
 	userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	for i := range items {
@@ -372,9 +477,41 @@ func (sp *SSnapshotPolicy) getMoreDetails(out api.SnapshotPolicyDetails) api.Sna
 	out.RepeatWeekdaysDisplay = SnapshotPolicyManager.RepeatWeekdaysToIntArray(sp.RepeatWeekdays)
 	out.TimePointsDisplay = SnapshotPolicyManager.TimePointsToIntArray(sp.TimePoints)
 	out.BindingDiskCount, _ = SnapshotPolicyDiskManager.FetchDiskCountBySPID(sp.Id)
+	if len(sp.BackupStorageId) > 0 {
+		if bs, _ := BackupStorageManager.FetchById(sp.BackupStorageId); bs != nil {
+			out.BackupStorage = bs.GetName()
+		}
+	}
 	return out
 }
 
+// IsLocalOnly returns whether the snapshot policy relies on features that
+// are implemented by region itself and can't be applied to cloud providers
+func (sp *SSnapshotPolicy) IsLocalOnly() bool {
+	return len(sp.CronExpression) > 0 || sp.ConsistencyGroup || len(sp.BackupStorageId) > 0 || sp.IsRetainedByCount()
+}
+
+// IsRetainedByCount returns whether auto snapshots are rotated by count and
+// daily/weekly/monthly tiers instead of retention days
+func (sp *SSnapshotPolicy) IsRetainedByCount() bool {
+	return sp.RetentionCount > 0 || sp.RetentionDaily > 0 || sp.RetentionWeekly > 0 || sp.RetentionMonthly > 0
+}
+
+func (sp *SSnapshotPolicy) IsMatchDisk(disk *SDisk) bool {
+	return len(sp.DiskType) == 0 || sp.DiskType == disk.DiskType
+}
+
+func (sp *SSnapshotPolicy) ValidateBindDisk(disk *SDisk) error {
+	if !sp.IsMatchDisk(disk) {
+		return httperrors.NewInputParameterError("snapshotpolicy %s only applies to %s disk, disk %s is %s",
+			sp.Name, sp.DiskType, disk.Name, disk.DiskType)
+	}
+	if sp.IsLocalOnly() && len(disk.ExternalId) > 0 {
+		return httperrors.NewUnsupportOperationError("snapshotpolicy %s can't be applied to managed disk %s", sp.Name, disk.Name)
+	}
+	return nil
+}
+
 func (sp *SSnapshotPolicy) GetCloudproviderId() string {
 	return ""
 }
@@ -549,6 +686,8 @@ func (manager *SSnapshotPolicyManager) newFromCloudSnapshotPolicy(
 		q := manager.Query().Equals("repeat_weekdays", snapshotPolicyTmp.RepeatWeekdays).Equals("time_points",
 			snapshotPolicyTmp.TimePoints).Equals("retention_days", snapshotPolicyTmp.RetentionDays).Equals(
 			"is_activated", snapshotPolicyTmp.IsActivated.Bool()).Equals("tenant_id", extProjectId)
+		q = q.Filter(sqlchemy.IsNullOrEmpty(q.Field("cron_expression"))).IsFalse("consistency_group")
+		q = q.Filter(sqlchemy.IsNullOrEmpty(q.Field("backup_storage_id")))
 		count, err := q.CountWithError()
 		if err != nil {
 			return nil, err
@@ -566,7 +705,7 @@ func (manager *SSnapshotPolicyManager) newFromCloudSnapshotPolicy(
 		if list, ok := snapshotpolicyCluster[extkey]; ok {
 			// find first snapshotpolicy enough to rebase
 			for _, sp := range list {
-				if sp.ProjectId == extProjectId {
+				if sp.ProjectId == extProjectId && !sp.IsLocalOnly() {
 					snapshotPolicy = sp
 					break
 				}
@@ -657,6 +796,9 @@ func (sp *SSnapshotPolicy) Key() uint64 {
 }
 
 func (sp *SSnapshotPolicy) Equals(cloudSP cloudprovider.ICloudSnapshotPolicy) bool {
+	if sp.IsLocalOnly() {
+		return false
+	}
 	rws, err := cloudSP.GetRepeatWeekdays()
 	if err != nil {
 		return false
@@ -703,6 +845,17 @@ func (manager *SSnapshotPolicyManager) sSnapshotPolicyCreateInputToInternal(inpu
 		ProjectId:     input.ProjectId,
 		DomainId:      input.DomainId,
 		RetentionDays: input.RetentionDays,
+
+		CronExpression: input.CronExpression,
+
+		RetentionCount:   input.RetentionCount,
+		RetentionDaily:   input.RetentionDaily,
+		RetentionWeekly:  input.RetentionWeekly,
+		RetentionMonthly: input.RetentionMonthly,
+
+		DiskType:         input.DiskType,
+		ConsistencyGroup: input.ConsistencyGroup,
+		BackupStorageId:  input.BackupStorageId,
 	}
 
 	ret.RepeatWeekdays = manager.RepeatWeekdaysParseIntArray(input.RepeatWeekdays)
@@ -774,6 +927,17 @@ func computeNextSyncTime(weekDays, timePoints []int, base time.Time) time.Time {
 }
 
 func (sp *SSnapshotPolicy) ComputeNextSyncTime(base time.Time) time.Time {
+	if len(sp.CronExpression) > 0 {
+		if base.IsZero() {
+			base = time.Now()
+		}
+		expr, err := cronexpr.Parse(sp.CronExpression)
+		if err != nil {
+			log.Errorf("snapshotpolicy %s invalid cron expression %q: %v", sp.Name, sp.CronExpression, err)
+			return time.Time{}
+		}
+		return expr.Next(base)
+	}
 	weekDays := SnapshotPolicyManager.RepeatWeekdaysToIntArray(sp.RepeatWeekdays)
 	timePoints := SnapshotPolicyManager.TimePointsToIntArray(sp.TimePoints)
 	if sp.RetentionDays <= 0 {
@@ -791,6 +955,49 @@ func (sp *SSnapshotPolicy) ComputeNextSyncTime(base time.Time) time.Time {
 	return computeNextSyncTime(set.List(), timePoints, base)
 }
 
+// RetainSnapshots returns whether each snapshot is kept by the count and
+// daily, weekly and monthly retention of snapshot policy, createdAt must be
+// sorted from the newest to the oldest. Besides the latest RetentionCount
+// snapshots, the newest snapshot of each of the latest RetentionDaily days,
+// RetentionWeekly weeks and RetentionMonthly months having snapshots is kept
+func (sp *SSnapshotPolicy) RetainSnapshots(createdAt []time.Time) []bool {
+	keep := make([]bool, len(createdAt))
+	for i := 0; i < len(createdAt) && i < sp.RetentionCount; i++ {
+		keep[i] = true
+	}
+	tiers := []struct {
+		count  int
+		period func(t time.Time) string
+	}{
+		{
+			count:  sp.RetentionDaily,
+			period: func(t time.Time) string { return t.Format("2006-01-02") },
+		},
+		{
+			count: sp.RetentionWeekly,
+			period: func(t time.Time) string {
+				year, week := t.ISOWeek()
+				return fmt.Sprintf("%d-%d", year, week)
+			},
+		},
+		{
+			count:  sp.RetentionMonthly,
+			period: func(t time.Time) string { return t.Format("2006-01") },
+		},
+	}
+	for _, tier := range tiers {
+		periods := sets.NewString()
+		for i := 0; i < len(createdAt) && periods.Len() < tier.count; i++ {
+			period := tier.period(createdAt[i])
+			if !periods.Has(period) {
+				periods.Insert(period)
+				keep[i] = true
+			}
+		}
+	}
+	return keep
+}
+
 func (sp *SSnapshotPolicy) GenerateCreateSpParams() *cloudprovider.SnapshotPolicyInput {
 	intWeekdays := SnapshotPolicyManager.RepeatWeekdaysToIntArray(sp.RepeatWeekdays)
 	intTimePoints := SnapshotPolicyManager.TimePointsToIntArray(sp.TimePoints)
@@ -816,6 +1023,9 @@ func (sp *SSnapshotPolicy) PerformCache(ctx context.Context, userCred mcclient.T
 	if err != nil {
 		return nil, httperrors.NewMissingParameterError("provider_id")
 	}
+	if sp.IsLocalOnly() {
+		return nil, httperrors.NewUnsupportOperationError("snapshotpolicy %s is not supported by cloud provider", sp.Name)
+	}
 	_, err = SnapshotPolicyCacheManager.NewCache(ctx, userCred, sp.Id, regionId, providerId)
 	if err != nil {
 		return nil, err
@@ -841,6 +1051,9 @@ func (sp *SSnapshotPolicy) PerformBindDisks(ctx context.Context, userCred mcclie
 			return nil, httperrors.NewInputParameterError("no such disk %s", diskId)
 		}
 		disk.SetModelManager(DiskManager, disk)
+		if err := sp.ValidateBindDisk(disk); err != nil {
+			return nil, err
+		}
 		diskSlice[i] = disk
 	}
 
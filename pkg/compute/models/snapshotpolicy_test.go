@@ -206,3 +206,74 @@ func TestSSnapshotPolicy_ComputeNextSyncTime(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateSnapshotPolicyCron(t *testing.T) {
+	cases := []struct {
+		spec string
+		ok   bool
+	}{
+		{"*/15 * * * *", true},
+		{"0 2 * * *", true},
+		{"30 1 * * 1,3,5", true},
+		{"0,10 * * * *", false},
+		{"* * * * *", false},
+		{"0 0 30 2 *", false},
+		{"invalid", false},
+	}
+	for _, c := range cases {
+		_, err := validateSnapshotPolicyCron(c.spec)
+		if (err == nil) != c.ok {
+			t.Errorf("spec %q want ok %v, got error %v", c.spec, c.ok, err)
+		}
+	}
+}
+
+func TestSSnapshotPolicy_RetainSnapshots(t *testing.T) {
+	timeStr := "2006-01-02 15:04"
+	// newest first
+	createdAt := []string{
+		"2021-03-02 12:00",
+		"2021-03-02 00:00",
+		"2021-03-01 12:00",
+		"2021-03-01 00:00",
+		"2021-02-28 12:00",
+		"2021-02-21 12:00",
+		"2021-01-31 12:00",
+		"2021-01-01 12:00",
+	}
+	times := make([]time.Time, len(createdAt))
+	for i := range createdAt {
+		times[i], _ = time.Parse(timeStr, createdAt[i])
+	}
+	cases := []struct {
+		in   *SSnapshotPolicy
+		want []bool
+	}{
+		{
+			in:   &SSnapshotPolicy{RetentionCount: 3},
+			want: []bool{true, true, true, false, false, false, false, false},
+		},
+		{
+			in:   &SSnapshotPolicy{RetentionDaily: 3},
+			want: []bool{true, false, true, false, true, false, false, false},
+		},
+		{
+			// 2021-03-01 is monday of a new ISO week
+			in:   &SSnapshotPolicy{RetentionWeekly: 2},
+			want: []bool{true, false, false, false, true, false, false, false},
+		},
+		{
+			in:   &SSnapshotPolicy{RetentionCount: 1, RetentionMonthly: 3},
+			want: []bool{true, false, false, false, true, false, true, false},
+		},
+	}
+	for i, c := range cases {
+		got := c.in.RetainSnapshots(times)
+		for j := range got {
+			if got[j] != c.want[j] {
+				t.Errorf("case %d: want %v, got %v", i, c.want, got)
+				break
+			}
+		}
+	}
+}
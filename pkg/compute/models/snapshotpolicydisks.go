@@ -485,6 +485,13 @@ func (self *SSnapshotPolicyDiskManager) ValidateCreateData(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	if disk == nil {
+		return nil, httperrors.NewResourceNotFoundError2(DiskManager.Keyword(), diskId)
+	}
+	err = snapshotPolicy.ValidateBindDisk(disk)
+	if err != nil {
+		return nil, err
+	}
 	storage, _ := disk.GetStorage()
 	region, _ := storage.GetRegion()
 	err = region.GetDriver().ValidateCreateSnapshopolicyDiskData(ctx, userCred, disk, snapshotPolicy)
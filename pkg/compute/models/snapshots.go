@@ -431,6 +431,28 @@ func (self *SSnapshot) StartSnapshotCreateTask(ctx context.Context, userCred mcc
 	return nil
 }
 
+// StartCopyToBackupStorageTask copies the snapshot to backup storage by
+// creating a disk backup from it, the snapshot itself is kept
+func (self *SSnapshot) StartCopyToBackupStorageTask(ctx context.Context, userCred mcclient.TokenCredential, backupStorageId string) error {
+	backup, err := func() (*SDiskBackup, error) {
+		lockman.LockClass(ctx, DiskBackupManager, "name")
+		defer lockman.ReleaseClass(ctx, DiskBackupManager, "name")
+
+		name, err := db.GenerateName(ctx, DiskBackupManager, self.GetOwnerId(), self.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "GenerateName")
+		}
+		return DiskBackupManager.CreateBackup(ctx, self.GetOwnerId(), self.DiskId, backupStorageId, name)
+	}()
+	if err != nil {
+		return errors.Wrapf(err, "create backup of snapshot %s", self.Name)
+	}
+	params := jsonutils.NewDict()
+	params.Set("snapshot_id", jsonutils.NewString(self.Id))
+	params.Set("keep_snapshot", jsonutils.JSONTrue)
+	return backup.StartBackupCreateTask(ctx, userCred, params, "")
+}
+
 func (self *SSnapshot) GetGuest() (*SGuest, error) {
 	iDisk, err := DiskManager.FetchById(self.DiskId)
 	if err != nil {
@@ -509,6 +531,31 @@ func (self *SSnapshotManager) GetDiskManualSnapshotCount(diskId string) (int, er
 	return self.Query().Equals("disk_id", diskId).Equals("fake_deleted", false).CountWithError()
 }
 
+// FetchRotatedAutoSnapshots returns ready auto snapshots of disk which are
+// not retained by the count retention of snapshot policy, oldest first
+func (self *SSnapshotManager) FetchRotatedAutoSnapshots(diskId string, sp *SSnapshotPolicy) ([]SSnapshot, error) {
+	q := self.Query().Equals("disk_id", diskId).Equals("created_by", api.SNAPSHOT_AUTO).
+		Equals("fake_deleted", false).NotIn("status", []string{api.SNAPSHOT_FAILED, api.SNAPSHOT_DELETING}).
+		Desc("created_at")
+	snapshots := make([]SSnapshot, 0)
+	err := db.FetchModelObjects(self, q, &snapshots)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	createdAt := make([]time.Time, len(snapshots))
+	for i := range snapshots {
+		createdAt[i] = snapshots[i].CreatedAt.Local()
+	}
+	keep := sp.RetainSnapshots(createdAt)
+	ret := make([]SSnapshot, 0)
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if !keep[i] && snapshots[i].Status == api.SNAPSHOT_READY {
+			ret = append(ret, snapshots[i])
+		}
+	}
+	return ret, nil
+}
+
 func (self *SSnapshotManager) IsDiskSnapshotsNeedConvert(diskId string) (bool, error) {
 	count, err := self.Query().Equals("disk_id", diskId).
 		In("status", []string{api.SNAPSHOT_READY, api.SNAPSHOT_DELETING}).
@@ -134,7 +134,14 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	}
 	vpc := _vpc.(*SVpc)
 
+	crossDomain := false
 	_peerVpc, err := VpcManager.FetchByIdOrName(userCred, input.PeerVpcId)
+	if err != nil && errors.Cause(err) == sql.ErrNoRows {
+		// onecloud vpc of other domains can only be referred to by id
+		// and peering with it has to be accepted by its owner
+		_peerVpc, err = VpcManager.FetchById(input.PeerVpcId)
+		crossDomain = true
+	}
 	if err != nil {
 		if errors.Cause(err) == sql.ErrNoRows {
 			return input, httperrors.NewResourceNotFoundError2("Peervpc", input.PeerVpcId)
@@ -142,9 +149,17 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 		return input, httperrors.NewGeneralError(err)
 	}
 	peerVpc := _peerVpc.(*SVpc)
+	input.VpcId = vpc.Id
+	input.PeerVpcId = peerVpc.Id
 
+	if len(vpc.ManagerId) == 0 && len(peerVpc.ManagerId) == 0 {
+		return manager.validateOnecloudCreateData(ctx, userCred, ownerId, vpc, peerVpc, input)
+	}
+	if crossDomain {
+		return input, httperrors.NewResourceNotFoundError2("Peervpc", input.PeerVpcId)
+	}
 	if len(vpc.ManagerId) == 0 || len(peerVpc.ManagerId) == 0 {
-		return input, httperrors.NewInputParameterError("Only public cloud support vpcpeering")
+		return input, httperrors.NewInputParameterError("vpc peering between onecloud vpc and public cloud vpc is not supported")
 	}
 
 	// get account,providerFactory
@@ -161,31 +176,9 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 
 	// check vpc ip range overlap
 	if !factory.IsSupportVpcPeeringVpcCidrOverlap() {
-		vpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		peervpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		vpcCidrBlocks := strings.Split(vpc.CidrBlock, ",")
-		peervpcCidrBlocks := strings.Split(peerVpc.CidrBlock, ",")
-		for i := range vpcCidrBlocks {
-			vpcIpv4Range, err := netutils.NewIPV4Prefix(vpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", vpcCidrBlocks[i]))
-			}
-			vpcIpv4Ranges = append(vpcIpv4Ranges, vpcIpv4Range.ToIPRange())
-		}
-
-		for i := range peervpcCidrBlocks {
-			peervpcIpv4Range, err := netutils.NewIPV4Prefix(peervpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", peervpcCidrBlocks[i]))
-			}
-			peervpcIpv4Ranges = append(peervpcIpv4Ranges, peervpcIpv4Range.ToIPRange())
-		}
-		for i := range vpcIpv4Ranges {
-			for j := range peervpcIpv4Ranges {
-				if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
-					return input, httperrors.NewNotSupportedError("ipv4 range overlap")
-				}
-			}
+		err := checkVpcPeeringCidrOverlap(vpc, peerVpc)
+		if err != nil {
+			return input, err
 		}
 	}
 
@@ -218,8 +211,103 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	return input, nil
 }
 
+// checkVpcPeeringCidrOverlap returns error when cidr blocks of the two vpcs
+// overlap, in which case they cannot route to each other
+func checkVpcPeeringCidrOverlap(vpc, peerVpc *SVpc) error {
+	vpcIpv4Ranges := []netutils.IPV4AddrRange{}
+	peervpcIpv4Ranges := []netutils.IPV4AddrRange{}
+	vpcCidrBlocks := strings.Split(vpc.CidrBlock, ",")
+	peervpcCidrBlocks := strings.Split(peerVpc.CidrBlock, ",")
+	for i := range vpcCidrBlocks {
+		vpcIpv4Range, err := netutils.NewIPV4Prefix(vpcCidrBlocks[i])
+		if err != nil {
+			return httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", vpcCidrBlocks[i]))
+		}
+		vpcIpv4Ranges = append(vpcIpv4Ranges, vpcIpv4Range.ToIPRange())
+	}
+
+	for i := range peervpcCidrBlocks {
+		peervpcIpv4Range, err := netutils.NewIPV4Prefix(peervpcCidrBlocks[i])
+		if err != nil {
+			return httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", peervpcCidrBlocks[i]))
+		}
+		peervpcIpv4Ranges = append(peervpcIpv4Ranges, peervpcIpv4Range.ToIPRange())
+	}
+	for i := range vpcIpv4Ranges {
+		for j := range peervpcIpv4Ranges {
+			if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
+				return httperrors.NewNotSupportedError("ipv4 range overlap")
+			}
+		}
+	}
+	return nil
+}
+
+// validateOnecloudCreateData validates peering between two onecloud vpcs,
+// which are realized by vpcagent as interconnected ovn logical routers
+func (manager *SVpcPeeringConnectionManager) validateOnecloudCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	vpc *SVpc,
+	peerVpc *SVpc,
+	input api.VpcPeeringConnectionCreateInput,
+) (api.VpcPeeringConnectionCreateInput, error) {
+	if vpc.Id == peerVpc.Id {
+		return input, httperrors.NewInputParameterError("cannot peer vpc %s with itself", vpc.Name)
+	}
+	for _, v := range []*SVpc{vpc, peerVpc} {
+		if v.Id == api.DEFAULT_VPC_ID {
+			return input, httperrors.NewInputParameterError("default vpc cannot be peered")
+		}
+		if len(v.CidrBlock) == 0 {
+			return input, httperrors.NewInputParameterError("vpc %s has no cidr_block", v.Name)
+		}
+	}
+	if input.Bandwidth > 0 {
+		return input, httperrors.NewInputParameterError("bandwidth is not supported for onecloud vpc peering")
+	}
+	err := checkVpcPeeringCidrOverlap(vpc, peerVpc)
+	if err != nil {
+		return input, err
+	}
+
+	q := manager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.AND(
+			sqlchemy.Equals(q.Field("vpc_id"), vpc.Id),
+			sqlchemy.Equals(q.Field("peer_vpc_id"), peerVpc.Id),
+		),
+		sqlchemy.AND(
+			sqlchemy.Equals(q.Field("vpc_id"), peerVpc.Id),
+			sqlchemy.Equals(q.Field("peer_vpc_id"), vpc.Id),
+		),
+	))
+	cnt, err := q.CountWithError()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", vpc.Name, peerVpc.Name)
+	}
+	return input, nil
+}
+
+// isRequireAccept returns whether the onecloud peering connection crosses
+// domain boundary and has to be accepted by owner of the peer vpc
+func (self *SVpcPeeringConnection) isRequireAccept(userCred mcclient.TokenCredential, peerVpc *SVpc) bool {
+	if peerVpc.DomainId == self.DomainId {
+		return false
+	}
+	return !userCred.HasSystemAdminPrivilege()
+}
+
 func (self *SVpcPeeringConnection) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	params := jsonutils.NewDict()
+	peerVpc, err := self.GetPeerVpc()
+	if err == nil && !peerVpc.IsManaged() && self.isRequireAccept(userCred, peerVpc) {
+		params.Set("require_accept", jsonutils.JSONTrue)
+	}
 	task, err := taskman.TaskManager.NewTask(ctx, "VpcPeeringConnectionCreateTask", self, userCred, params, "", "", nil)
 	if err != nil {
 		return
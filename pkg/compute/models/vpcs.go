@@ -193,7 +193,8 @@ func (self *SVpc) GetVpcPeeringConnectionCount() (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	q = self.getRequesterVpcPeeringConnectionQuery()
+	// rejected ones are left to be deleted by the requester
+	q = self.getAccepterVpcPeeringConnectionQuery().NotEquals("status", api.VPC_PEERING_CONNECTION_STATUS_REJECTED)
 	accepterPeerCount, err := q.CountWithError()
 	if err != nil {
 		return 0, err
@@ -1590,6 +1591,61 @@ func (self *SVpc) GetAccepterVpcPeeringConnectionByExtId(extId string) (*SVpcPee
 	return peer.(*SVpcPeeringConnection), nil
 }
 
+// fetchAccepterVpcPeeringConnection returns onecloud peering connection whose
+// peer vpc is self.  The connection may belong to other domain
+func (self *SVpc) fetchAccepterVpcPeeringConnection(peerId string) (*SVpcPeeringConnection, error) {
+	if self.IsManaged() {
+		return nil, httperrors.NewUnsupportOperationError("only onecloud vpc peering connection needs acceptance")
+	}
+	if len(peerId) == 0 {
+		return nil, httperrors.NewMissingParameterError("vpc_peering_connection_id")
+	}
+	obj, err := VpcPeeringConnectionManager.FetchById(peerId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(VpcPeeringConnectionManager.Keyword(), peerId)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	peer := obj.(*SVpcPeeringConnection)
+	if peer.PeerVpcId != self.Id {
+		return nil, httperrors.NewResourceNotFoundError2(VpcPeeringConnectionManager.Keyword(), peerId)
+	}
+	return peer, nil
+}
+
+// 接受其他域发起的对等连接
+func (self *SVpc) PerformAcceptPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.VpcPeeringConnectionAcceptInput) (jsonutils.JSONObject, error) {
+	peer, err := self.fetchAccepterVpcPeeringConnection(input.VpcPeeringConnectionId)
+	if err != nil {
+		return nil, err
+	}
+	if peer.Status != api.VPC_PEERING_CONNECTION_STATUS_PENDING_ACCEPT {
+		return nil, httperrors.NewInvalidStatusError("vpc peering connection %s status is %s", peer.Name, peer.Status)
+	}
+	err = peer.SetStatus(userCred, api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "accepted by "+userCred.GetProjectDomain())
+	if err != nil {
+		return nil, errors.Wrap(err, "SetStatus")
+	}
+	return nil, nil
+}
+
+// 拒绝或撤销其他域发起的对等连接
+func (self *SVpc) PerformRejectPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.VpcPeeringConnectionRejectInput) (jsonutils.JSONObject, error) {
+	peer, err := self.fetchAccepterVpcPeeringConnection(input.VpcPeeringConnectionId)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsInStringArray(peer.Status, []string{api.VPC_PEERING_CONNECTION_STATUS_PENDING_ACCEPT, api.VPC_PEERING_CONNECTION_STATUS_ACTIVE}) {
+		return nil, httperrors.NewInvalidStatusError("vpc peering connection %s status is %s", peer.Name, peer.Status)
+	}
+	err = peer.SetStatus(userCred, api.VPC_PEERING_CONNECTION_STATUS_REJECTED, "rejected by "+userCred.GetProjectDomain())
+	if err != nil {
+		return nil, errors.Wrap(err, "SetStatus")
+	}
+	return nil, nil
+}
+
 func (self *SVpc) BackSycVpcPeeringConnectionsVpc(exts []cloudprovider.ICloudVpcPeeringConnection) compare.SyncResult {
 	result := compare.SyncResult{}
 	for i := range exts {
@@ -176,6 +176,8 @@ type ComputeOptions struct {
 
 	ProhibitRefreshingCloudImage bool `help:"Prohibit refreshing cloud image"`
 
+	DefaultImageSignaturePolicy string `help:"Default policy for booting unsigned or untrusted images, can be overridden by project or domain metadata image_signature_policy" default:"permissive" choices:"permissive|require_signed|require_trusted"`
+
 	GlobalMacPrefix string `help:"Global prefix of MAC address, default to 00:22" default:"00:22"`
 
 	DefaultIPAllocationDirection string `help:"default IP allocation direction" default:"stepdown"`
@@ -196,6 +198,9 @@ type SASControllerOptions struct {
 	ConcurrentUpper     int `help:"This represents the upper limit of concurrent sacling sctivities" default:"500"`
 	CheckScaleInterval  int `help:"The interval between the two checks about scaling, unit: s" default:"60"`
 	CheckHealthInterval int `help:"The interval bewteen the two check about instance's health unit: m" default:"1"`
+
+	TargetTrackingInterval  int `help:"The interval between the two evaluations of target tracking scaling policies, unit: s" default:"60"`
+	InstanceRefreshInterval int `help:"The interval between the two checks about instance refreshes, unit: s" default:"30"`
 }
 
 var (
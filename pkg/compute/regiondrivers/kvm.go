@@ -103,7 +103,17 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context
 		return nil, httperrors.NewInputParameterError("zone info missing")
 	}
 	if vpc.Id != api.DEFAULT_VPC_ID {
-		return nil, httperrors.NewInputParameterError("vpc lb is not allowed for now")
+		// vpc loadbalancers are realized as ovn load balancers by
+		// vpcagent, no lbcluster is involved
+		if clusterV.Model != nil {
+			return nil, httperrors.NewInputParameterError("vpc lb cannot be assigned to lbcluster")
+		}
+		data.Set("cloudregion_id", jsonutils.NewString(region.GetId()))
+		data.Set("zone_id", jsonutils.NewString(zone.GetId()))
+		data.Set("vpc_id", jsonutils.NewString(vpc.GetId()))
+		data.Set("network_type", jsonutils.NewString(api.LB_NETWORK_TYPE_VPC))
+		data.Set("address_type", jsonutils.NewString(api.LB_ADDR_TYPE_INTRANET))
+		return data, nil
 	}
 
 	if clusterV.Model == nil {
@@ -511,6 +521,16 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 		return nil, err
 	}
 
+	// vpc lb is realized as ovn load balancer, which works at L4 only
+	if lb.NetworkType == api.LB_NETWORK_TYPE_VPC {
+		if listenerType != api.LB_LISTENER_TYPE_TCP && listenerType != api.LB_LISTENER_TYPE_UDP {
+			return nil, httperrors.NewInputParameterError("vpc lb supports only tcp/udp listener, got %s", listenerType)
+		}
+		if aclStatusV.Value == api.LB_BOOL_ON {
+			return nil, httperrors.NewInputParameterError("vpc lb does not support acl")
+		}
+	}
+
 	if redirectType := redirectV.Value; redirectType != api.LB_REDIRECT_OFF {
 		if listenerType != api.LB_LISTENER_TYPE_HTTP && listenerType != api.LB_LISTENER_TYPE_HTTPS {
 			return nil, httperrors.NewInputParameterError("redirect can only be enabled for http/https listener")
@@ -1367,8 +1387,48 @@ func (self *SKVMRegionDriver) RequestSyncSnapshotStatus(ctx context.Context, use
 	return nil
 }
 
+func (self *SKVMRegionDriver) IsSupportedNatGateway() bool {
+	return true
+}
+
+func (self *SKVMRegionDriver) IsSupportedNatAutoRenew() bool {
+	return false
+}
+
+func (self *SKVMRegionDriver) ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error) {
+	if len(input.Duration) > 0 {
+		return input, httperrors.NewInputParameterError("prepaid nat gateway is not supported")
+	}
+	if input.VpcId == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewInputParameterError("nat gateway is not supported in default vpc")
+	}
+	_vpc, err := models.VpcManager.FetchById(input.VpcId)
+	if err != nil {
+		return input, httperrors.NewGeneralError(errors.Wrapf(err, "fetch vpc %s", input.VpcId))
+	}
+	vpc := _vpc.(*models.SVpc)
+	switch vpc.ExternalAccessMode {
+	case api.VPC_EXTERNAL_ACCESS_MODE_EIP, api.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW:
+	default:
+		return input, httperrors.NewInputParameterError("vpc %s external access mode %s does not support eip",
+			vpc.Name, vpc.ExternalAccessMode)
+	}
+	return input, nil
+}
+
 func (self *SKVMRegionDriver) RequestAssociateEipForNAT(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, eip *models.SElasticip, task taskman.ITask) error {
-	return errors.Wrapf(cloudprovider.ErrNotSupported, "RequestAssociateEipForNAT")
+	opts := api.ElasticipAssociateInput{
+		InstanceType: api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY,
+		InstanceId:   nat.Id,
+	}
+	return eip.StartEipAssociateInstanceTask(ctx, userCred, opts, task.GetTaskId())
+}
+
+func (self *SKVMRegionDriver) RequestSyncNatGatewayStatus(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nat.SetStatus(userCred, api.NAT_STAUTS_AVAILABLE, "")
+	})
+	return nil
 }
 
 func (self *SKVMRegionDriver) RequestPreSnapshotPolicyApply(ctx context.Context, userCred mcclient.
@@ -1663,6 +1723,11 @@ func (self *SKVMRegionDriver) RequestAssociatEip(ctx context.Context, userCred m
 				return nil, errors.Wrapf(err, "set associated eip for groupnic %s (guest:%s, network:%s)",
 					groupnic.IpAddr, groupnic.GroupId, groupnic.NetworkId)
 			}
+		} else if input.InstanceType == api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY {
+			nat := obj.(*models.SNatGateway)
+			if nat.IsManaged() {
+				return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "nat gateway %s of managed vpc", nat.Name)
+			}
 		} else {
 			return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "instance type %s", input.InstanceType)
 		}
@@ -84,6 +84,7 @@ func InitHandlers(app *appsrv.Application) {
 
 		models.ScalingTimerManager,
 		models.ScalingAlarmManager,
+		models.ScalingTargetTrackingManager,
 		models.ScalingGroupGuestManager,
 		models.ScalingGroupNetworkManager,
 
@@ -189,6 +190,8 @@ func InitHandlers(app *appsrv.Application) {
 		models.ScalingGroupManager,
 		models.ScalingPolicyManager,
 		models.ScalingActivityManager,
+		models.ScalingLifecycleHookManager,
+		models.ScalingInstanceRefreshManager,
 		models.PolicyDefinitionManager,
 		models.PolicyAssignmentManager,
 
@@ -211,6 +214,10 @@ func InitHandlers(app *appsrv.Application) {
 
 		models.ProjectMappingManager,
 
+		models.PricePlanManager,
+		models.RatingBillManager,
+		models.BudgetManager,
+
 		models.WafRuleGroupManager,
 		models.WafRuleGroupCacheManager,
 		models.WafIPSetManager,
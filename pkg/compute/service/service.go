@@ -163,6 +163,7 @@ func StartService() {
 		cron.AddJobAtIntervalsWithStartRun("AutoSyncExtDiskSnapshot", time.Duration(opts.SyncExtDiskSnapshotIntervalMinutes)*time.Minute, models.DiskManager.AutoSyncExtDiskSnapshot, true)
 
 		cron.AddJobEveryFewHour("AutoDiskSnapshot", 1, 5, 0, models.DiskManager.AutoDiskSnapshot, false)
+		cron.AddJobAtIntervals("AutoCronDiskSnapshot", time.Minute, models.DiskManager.AutoCronDiskSnapshot)
 		cron.AddJobEveryFewHour("SnapshotsCleanup", 1, 35, 0, models.SnapshotManager.CleanupSnapshots, false)
 
 		cron.AddJobAtIntervalsWithStartRun("SyncSkus", time.Duration(opts.ServerSkuSyncIntervalMinutes)*time.Minute, models.SyncServerSkus, true)
@@ -179,6 +180,8 @@ func StartService() {
 		cron.AddJobEveryFewHour("InspectAllTemplate", 1, 0, 0, models.GuestTemplateManager.InspectAllTemplate, true)
 
 		cron.AddJobEveryFewHour("CheckBillingResourceExpireAt", 1, 0, 0, models.CheckBillingResourceExpireAt, true)
+		cron.AddJobEveryFewDays("CheckLoadbalancerCertificateExpireAt", 1, 1, 0, 0, models.CheckLoadbalancerCertificateExpireAt, false)
+		cron.AddJobEveryFewDays("RateResourcesDaily", 1, 0, 30, 0, models.RateResourcesDaily, false)
 		go cron.Start2(ctx, electObj)
 
 		// init auto scaling controller
@@ -0,0 +1,141 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SCifsStorageDriver struct {
+	SBaseStorageDriver
+}
+
+func init() {
+	driver := SCifsStorageDriver{}
+	models.RegisterStorageDriver(&driver)
+}
+
+func (self *SCifsStorageDriver) GetStorageType() string {
+	return api.STORAGE_CIFS
+}
+
+// cifsCredentialOptions are mount.cifs options carrying credentials, they
+// must be given by dedicated fields so that they never show up in mount options
+var cifsCredentialOptions = []string{"username", "user", "password", "pass", "credentials", "cred", "domain", "dom"}
+
+func validateCifsCreateInput(input *api.StorageCreateInput) error {
+	if len(input.CifsHost) == 0 {
+		return httperrors.NewMissingParameterError("cifs_host")
+	}
+	input.CifsSharedDir = strings.Trim(input.CifsSharedDir, "/")
+	if len(input.CifsSharedDir) == 0 {
+		return httperrors.NewMissingParameterError("cifs_shared_dir")
+	}
+	if len(input.CifsPassword) > 0 && len(input.CifsUsername) == 0 {
+		return httperrors.NewMissingParameterError("cifs_username")
+	}
+	// mount options are joined by comma, credentials must be given by dedicated fields
+	for _, opt := range strings.Split(input.CifsMountOptions, ",") {
+		key := strings.ToLower(strings.TrimSpace(strings.SplitN(opt, "=", 2)[0]))
+		if utils.IsInStringArray(key, cifsCredentialOptions) {
+			return httperrors.NewInputParameterError("mount option %s not allowed in cifs_mount_options", key)
+		}
+	}
+	return nil
+}
+
+func (self *SCifsStorageDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *api.StorageCreateInput) error {
+	input.StorageConf = jsonutils.NewDict()
+	if err := validateCifsCreateInput(input); err != nil {
+		return err
+	}
+
+	storages := []models.SStorage{}
+	q := models.StorageManager.Query().Equals("storage_type", api.STORAGE_CIFS)
+	err := db.FetchModelObjects(models.StorageManager, q, &storages)
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	for i := 0; i < len(storages); i++ {
+		host, _ := storages[i].StorageConf.GetString("cifs_host")
+		dir, _ := storages[i].StorageConf.GetString("cifs_shared_dir")
+		if host == input.CifsHost && dir == input.CifsSharedDir {
+			return httperrors.NewDuplicateResourceError("This CIFS Storage[%s/%s] has already exist", storages[i].Name, input.CifsSharedDir)
+		}
+	}
+
+	// password is encrypted with storage id in PostCreate
+	input.StorageConf.Update(jsonutils.Marshal(map[string]string{
+		"cifs_host":          input.CifsHost,
+		"cifs_shared_dir":    input.CifsSharedDir,
+		"cifs_username":      input.CifsUsername,
+		"cifs_password":      input.CifsPassword,
+		"cifs_domain":        input.CifsDomain,
+		"cifs_mount_options": input.CifsMountOptions,
+	}))
+	return nil
+}
+
+func (self *SCifsStorageDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, storage *models.SStorage, data jsonutils.JSONObject) {
+	// never keep the plain password in storage conf, even if storagecache is not created
+	passwd, _ := storage.StorageConf.GetString("cifs_password")
+	if len(passwd) > 0 {
+		sec, err := utils.EncryptAESBase64(storage.Id, passwd)
+		if err != nil {
+			log.Errorf("encrypt cifs password for storage %s error: %v", storage.Name, err)
+			sec = ""
+		}
+		_, err = db.Update(storage, func() error {
+			conf := jsonutils.NewDict()
+			conf.Update(storage.StorageConf)
+			conf.Set("cifs_password", jsonutils.NewString(sec))
+			storage.StorageConf = conf
+			return nil
+		})
+		if err != nil {
+			log.Errorf("update cifs password for storage %s error: %v", storage.Name, err)
+		}
+	}
+
+	sc := &models.SStoragecache{}
+	sc.Path = options.Options.DefaultImageCacheDir
+	sc.ExternalId = storage.Id
+	sc.Name = "cifs-" + storage.Name + time.Now().Format("2006-01-02 15:04:05")
+	if err := models.StoragecacheManager.TableSpec().Insert(ctx, sc); err != nil {
+		log.Errorf("insert storagecache for storage %s error: %v", storage.Name, err)
+		return
+	}
+	_, err := db.Update(storage, func() error {
+		storage.StoragecacheId = sc.Id
+		storage.Status = api.STORAGE_ONLINE
+		return nil
+	})
+	if err != nil {
+		log.Errorf("update storagecache info for storage %s error: %v", storage.Name, err)
+	}
+}
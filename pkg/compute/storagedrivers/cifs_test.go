@@ -0,0 +1,100 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"strings"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestValidateCifsCreateInput(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     api.StorageCreateInput
+		wantErr   string
+		sharedDir string
+	}{
+		{
+			name:      "guest",
+			input:     api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "/share/"},
+			sharedDir: "share",
+		},
+		{
+			name:      "credentials",
+			input:     api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "share/vm", CifsUsername: "admin", CifsPassword: "secret", CifsDomain: "WORKGROUP"},
+			sharedDir: "share/vm",
+		},
+		{
+			name:      "username without password",
+			input:     api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "share", CifsUsername: "admin"},
+			sharedDir: "share",
+		},
+		{
+			name:      "plain mount options",
+			input:     api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "share", CifsMountOptions: "vers=3.0,uid=0"},
+			sharedDir: "share",
+		},
+		{
+			name:    "missing server",
+			input:   api.StorageCreateInput{CifsSharedDir: "share"},
+			wantErr: "cifs_host",
+		},
+		{
+			name:    "missing share",
+			input:   api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "/"},
+			wantErr: "cifs_shared_dir",
+		},
+		{
+			name:    "password without username",
+			input:   api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "share", CifsPassword: "secret"},
+			wantErr: "cifs_username",
+		},
+		{
+			name:    "username in mount options",
+			input:   api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "share", CifsMountOptions: "vers=3.0, username=admin"},
+			wantErr: "username",
+		},
+		{
+			name:    "password in mount options",
+			input:   api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "share", CifsMountOptions: "PASSWORD=secret"},
+			wantErr: "password",
+		},
+		{
+			name:    "credentials file in mount options",
+			input:   api.StorageCreateInput{CifsHost: "10.0.0.1", CifsSharedDir: "share", CifsMountOptions: "credentials=/etc/passwd"},
+			wantErr: "credentials",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			input := c.input
+			err := validateCifsCreateInput(&input)
+			if len(c.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("got %v, want error containing %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v, want nil", err)
+			}
+			if input.CifsSharedDir != c.sharedDir {
+				t.Errorf("shared dir %q, want %q", input.CifsSharedDir, c.sharedDir)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+// SLocalBlockStorageDriver is the common part of local block storages, they
+// are reported by host like local storage, disks are raw volumes and snapshots
+// are taken natively on the host
+type SLocalBlockStorageDriver struct {
+	SBaseStorageDriver
+}
+
+// validateStorageConf makes sure the storage conf reported by host has key
+func (self *SLocalBlockStorageDriver) validateStorageConf(input *api.StorageCreateInput, key string) error {
+	if input.StorageConf == nil {
+		return httperrors.NewMissingParameterError("storage_conf")
+	}
+	if !input.StorageConf.Contains(key) {
+		return httperrors.NewMissingParameterError(fmt.Sprintf("storage_conf.%s", key))
+	}
+	return nil
+}
+
+func (self *SLocalBlockStorageDriver) createStoragecache(ctx context.Context, storage *models.SStorage, path string) {
+	// images are cached as read-only volumes of the storage
+	sc := &models.SStoragecache{}
+	sc.SetModelManager(models.StoragecacheManager, sc)
+	sc.Name = fmt.Sprintf("imagecache-%s", storage.Id)
+	sc.Path = path
+	sc.ExternalId = storage.Id
+	if err := models.StoragecacheManager.TableSpec().Insert(ctx, sc); err != nil {
+		log.Errorf("insert storagecache for storage %s error: %v", storage.Name, err)
+		return
+	}
+	_, err := db.Update(storage, func() error {
+		storage.StoragecacheId = sc.Id
+		return nil
+	})
+	if err != nil {
+		log.Errorf("update storagecache info for storage %s error: %v", storage.Name, err)
+	}
+}
+
+func (self *SLocalBlockStorageDriver) ValidateSnapshotDelete(ctx context.Context, snapshot *models.SSnapshot) error {
+	return nil
+}
+
+func (self *SLocalBlockStorageDriver) ValidateCreateSnapshotData(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, input *api.SnapshotCreateInput) error {
+	return nil
+}
+
+func (self *SLocalBlockStorageDriver) RequestCreateSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask) error {
+	disk, err := snapshot.GetDisk()
+	if err != nil {
+		return errors.Wrap(err, "snapshot get disk")
+	}
+	storage := snapshot.GetStorage()
+	host, err := disk.GetMasterHost()
+	if err != nil {
+		return errors.Wrapf(err, "disk %s get master host", disk.Id)
+	}
+	url := fmt.Sprintf("%s/disks/%s/snapshot/%s", host.ManagerUri, storage.Id, disk.Id)
+	header := task.GetTaskRequestHeader()
+	params := jsonutils.NewDict()
+	params.Set("snapshot_id", jsonutils.NewString(snapshot.Id))
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, params, false)
+	if err != nil {
+		return errors.Wrap(err, "request create snapshot")
+	}
+	return nil
+}
+
+func (self *SLocalBlockStorageDriver) RequestDeleteSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask) error {
+	storage := snapshot.GetStorage()
+	host := storage.GetMasterHost()
+	if host == nil {
+		return errors.Errorf("storage %s can't get master host", storage.Id)
+	}
+	url := fmt.Sprintf("%s/disks/%s/delete-snapshot/%s", host.ManagerUri, storage.Id, snapshot.DiskId)
+	header := task.GetTaskRequestHeader()
+	params := jsonutils.NewDict()
+	params.Set("snapshot_id", jsonutils.NewString(snapshot.Id))
+	_, _, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, params, false)
+	if err != nil {
+		return errors.Wrap(err, "request delete snapshot")
+	}
+	return nil
+}
+
+// native snapshots never join the disk backing chain
+func (self *SLocalBlockStorageDriver) SnapshotIsOutOfChain(disk *models.SDisk) bool {
+	return true
+}
+
+func (self *SLocalBlockStorageDriver) OnDiskReset(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, snapshot *models.SSnapshot, data jsonutils.JSONObject) error {
+	return nil
+}
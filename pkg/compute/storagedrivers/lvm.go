@@ -0,0 +1,49 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SLvmStorageDriver struct {
+	SLocalBlockStorageDriver
+}
+
+func init() {
+	driver := SLvmStorageDriver{}
+	models.RegisterStorageDriver(&driver)
+}
+
+func (self *SLvmStorageDriver) GetStorageType() string {
+	return api.STORAGE_LVM
+}
+
+// ValidateCreateData keeps the storage conf reported by host
+func (self *SLvmStorageDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *api.StorageCreateInput) error {
+	return self.validateStorageConf(input, "vg_name")
+}
+
+func (self *SLvmStorageDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, storage *models.SStorage, data jsonutils.JSONObject) {
+	vg, _ := storage.StorageConf.GetString("vg_name")
+	self.createStoragecache(ctx, storage, fmt.Sprintf("/dev/%s", vg))
+}
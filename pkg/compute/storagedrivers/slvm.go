@@ -0,0 +1,184 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+// lvm allows vg and lv names consisting of a-z A-Z 0-9 + _ . -, not starting with a hyphen
+var slvmNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9+_.][a-zA-Z0-9+_.-]*$`)
+
+type SSLVMStorageDriver struct {
+	SBaseStorageDriver
+}
+
+func init() {
+	driver := SSLVMStorageDriver{}
+	models.RegisterStorageDriver(&driver)
+}
+
+func (self *SSLVMStorageDriver) GetStorageType() string {
+	return api.STORAGE_SLVM
+}
+
+func (self *SSLVMStorageDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *api.StorageCreateInput) error {
+	input.StorageConf = jsonutils.NewDict()
+	input.SLVMVgName = strings.TrimSpace(input.SLVMVgName)
+	if len(input.SLVMVgName) == 0 {
+		return httperrors.NewMissingParameterError("slvm_vg_name")
+	}
+	if !slvmNameRegexp.MatchString(input.SLVMVgName) {
+		return httperrors.NewInputParameterError("invalid volume group name %s", input.SLVMVgName)
+	}
+
+	storages := []models.SStorage{}
+	q := models.StorageManager.Query().Equals("storage_type", api.STORAGE_SLVM).Equals("zone_id", input.ZoneId)
+	err := db.FetchModelObjects(models.StorageManager, q, &storages)
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	for i := 0; i < len(storages); i++ {
+		vg, _ := storages[i].StorageConf.GetString("vg_name")
+		if vg == input.SLVMVgName {
+			return httperrors.NewDuplicateResourceError("This shared LVM Storage[%s/%s] has already exist", storages[i].Name, vg)
+		}
+	}
+
+	input.StorageConf.Update(jsonutils.Marshal(map[string]string{
+		"vg_name": input.SLVMVgName,
+	}))
+	return nil
+}
+
+func (self *SSLVMStorageDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, storage *models.SStorage, data jsonutils.JSONObject) {
+	// images are cached as logical volumes in the same volume group
+	vg, _ := storage.StorageConf.GetString("vg_name")
+	sc := &models.SStoragecache{}
+	sc.SetModelManager(models.StoragecacheManager, sc)
+	sc.Name = fmt.Sprintf("imagecache-%s", storage.Id)
+	sc.Path = fmt.Sprintf("/dev/%s", vg)
+	sc.ExternalId = storage.Id
+	if err := models.StoragecacheManager.TableSpec().Insert(ctx, sc); err != nil {
+		log.Errorf("insert storagecache for storage %s error: %v", storage.Name, err)
+		return
+	}
+	_, err := db.Update(storage, func() error {
+		storage.StoragecacheId = sc.Id
+		return nil
+	})
+	if err != nil {
+		log.Errorf("update storagecache info for storage %s error: %v", storage.Name, err)
+	}
+}
+
+func (self *SSLVMStorageDriver) ValidateSnapshotDelete(ctx context.Context, snapshot *models.SSnapshot) error {
+	return nil
+}
+
+// validateSLVMSnapshotGuestStatus allows snapshots of disks of stopped or
+// running guests. The host running a guest holds the exclusive lvmlockd lock of
+// its volumes and copies them from a temporary copy-on-write snapshot, which
+// lvmlockd refuses while the lock is shared by two hosts during live migration
+func validateSLVMSnapshotGuestStatus(status string) error {
+	switch status {
+	case api.VM_READY, api.VM_RUNNING:
+		return nil
+	case api.VM_START_MIGRATE, api.VM_MIGRATING:
+		return httperrors.NewInvalidStatusError("Cannot do snapshot of shared lvm disk when VM in status %s, lvmlockd only allows snapshots of volumes exclusively active on one host", status)
+	default:
+		return httperrors.NewInvalidStatusError("Cannot do snapshot of shared lvm disk when VM in status %s", status)
+	}
+}
+
+func (self *SSLVMStorageDriver) ValidateCreateSnapshotData(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, input *api.SnapshotCreateInput) error {
+	for _, guest := range disk.GetGuests() {
+		if err := validateSLVMSnapshotGuestStatus(guest.Status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *SSLVMStorageDriver) RequestCreateSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask) error {
+	disk, err := snapshot.GetDisk()
+	if err != nil {
+		return errors.Wrap(err, "snapshot get disk")
+	}
+	storage := snapshot.GetStorage()
+	// the volume of a running guest can only be snapshotted on its host
+	var host *models.SHost
+	if guest := disk.GetGuest(); guest != nil && guest.Status == api.VM_RUNNING {
+		host, err = guest.GetHost()
+		if err != nil {
+			return errors.Wrapf(err, "guest %s get host", guest.Id)
+		}
+	} else {
+		host, err = disk.GetMasterHost()
+		if err != nil {
+			return errors.Wrapf(err, "disk %s get master host", disk.Id)
+		}
+	}
+	url := fmt.Sprintf("%s/disks/%s/snapshot/%s", host.ManagerUri, storage.Id, disk.Id)
+	header := task.GetTaskRequestHeader()
+	params := jsonutils.NewDict()
+	params.Set("snapshot_id", jsonutils.NewString(snapshot.Id))
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, params, false)
+	if err != nil {
+		return errors.Wrap(err, "request create snapshot")
+	}
+	return nil
+}
+
+func (self *SSLVMStorageDriver) RequestDeleteSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask) error {
+	storage := snapshot.GetStorage()
+	host := storage.GetMasterHost()
+	if host == nil {
+		return errors.Errorf("storage %s can't get master host", storage.Id)
+	}
+	url := fmt.Sprintf("%s/disks/%s/delete-snapshot/%s", host.ManagerUri, storage.Id, snapshot.DiskId)
+	header := task.GetTaskRequestHeader()
+	params := jsonutils.NewDict()
+	params.Set("snapshot_id", jsonutils.NewString(snapshot.Id))
+	_, _, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, params, false)
+	if err != nil {
+		return errors.Wrap(err, "request delete snapshot")
+	}
+	return nil
+}
+
+// snapshots are independent logical volumes, they never join the disk backing chain
+func (self *SSLVMStorageDriver) SnapshotIsOutOfChain(disk *models.SDisk) bool {
+	return true
+}
+
+func (self *SSLVMStorageDriver) OnDiskReset(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, snapshot *models.SSnapshot, data jsonutils.JSONObject) error {
+	return nil
+}
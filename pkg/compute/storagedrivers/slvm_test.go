@@ -0,0 +1,47 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"strings"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestValidateSLVMSnapshotGuestStatus(t *testing.T) {
+	cases := []struct {
+		status  string
+		wantErr string
+	}{
+		{status: api.VM_READY},
+		{status: api.VM_RUNNING},
+		{status: api.VM_START_MIGRATE, wantErr: "lvmlockd"},
+		{status: api.VM_MIGRATING, wantErr: "lvmlockd"},
+		{status: api.VM_STARTING, wantErr: api.VM_STARTING},
+	}
+	for _, c := range cases {
+		err := validateSLVMSnapshotGuestStatus(c.status)
+		if len(c.wantErr) == 0 {
+			if err != nil {
+				t.Errorf("status %s got %v, want nil", c.status, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("status %s got %v, want error containing %q", c.status, err, c.wantErr)
+		}
+	}
+}
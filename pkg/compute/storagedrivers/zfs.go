@@ -0,0 +1,67 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SZfsStorageDriver struct {
+	SLocalBlockStorageDriver
+}
+
+func init() {
+	driver := SZfsStorageDriver{}
+	models.RegisterStorageDriver(&driver)
+}
+
+func (self *SZfsStorageDriver) GetStorageType() string {
+	return api.STORAGE_ZFS
+}
+
+// ValidateCreateData keeps the storage conf reported by host
+func (self *SZfsStorageDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *api.StorageCreateInput) error {
+	return self.validateStorageConf(input, "dataset")
+}
+
+func (self *SZfsStorageDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, storage *models.SStorage, data jsonutils.JSONObject) {
+	dataset, _ := storage.StorageConf.GetString("dataset")
+	self.createStoragecache(ctx, storage, fmt.Sprintf("/dev/zvol/%s", dataset))
+}
+
+// OnDiskReset removes snapshots taken after the snapshot, zfs destroys them on rollback
+func (self *SZfsStorageDriver) OnDiskReset(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, snapshot *models.SSnapshot, data jsonutils.JSONObject) error {
+	snapshots := make([]models.SSnapshot, 0)
+	q := models.SnapshotManager.Query().Equals("disk_id", disk.Id).GT("created_at", snapshot.CreatedAt)
+	err := db.FetchModelObjects(models.SnapshotManager, q, &snapshots)
+	if err != nil {
+		return err
+	}
+	for i := range snapshots {
+		if err := snapshots[i].RealDelete(ctx, userCred); err != nil {
+			log.Errorf("delete snapshot %s of disk %s error: %v", snapshots[i].Id, disk.Id, err)
+		}
+	}
+	return nil
+}
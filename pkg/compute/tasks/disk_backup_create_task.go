@@ -42,7 +42,7 @@ func init() {
 
 func (self *DiskBackupCreateTask) taskFailed(ctx context.Context, backup *models.SDiskBackup, reason jsonutils.JSONObject, status string) {
 	snapshotId, _ := self.Params.GetString("snapshot_id")
-	if len(snapshotId) > 0 {
+	if len(snapshotId) > 0 && !self.Params.Contains("keep_snapshot") {
 		snapshotModel, err := models.SnapshotManager.FetchById(snapshotId)
 		if err != nil {
 			log.Errorf("unable to get snapshot %s: %s", snapshotId, err.Error())
@@ -130,6 +130,10 @@ func (self *DiskBackupCreateTask) OnSave(ctx context.Context, backup *models.SDi
 		backup.SizeMb = int(sizeMb)
 		return nil
 	})
+	if self.Params.Contains("keep_snapshot") {
+		self.taksSuccess(ctx, backup, nil)
+		return
+	}
 	snapshot := snapshotModel.(*models.SSnapshot)
 	err = snapshot.StartSnapshotDeleteTask(ctx, self.UserCred, false, self.GetId())
 	if err != nil {
@@ -139,6 +143,10 @@ func (self *DiskBackupCreateTask) OnSave(ctx context.Context, backup *models.SDi
 }
 
 func (self *DiskBackupCreateTask) OnSaveFailed(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	if self.Params.Contains("keep_snapshot") {
+		self.taskFailed(ctx, backup, data, api.BACKUP_STATUS_SAVE_FAILED)
+		return
+	}
 	snapshotId, _ := self.Params.GetString("snapshot_id")
 	snapshotModel, err := models.SnapshotManager.FetchById(snapshotId)
 	if err != nil {
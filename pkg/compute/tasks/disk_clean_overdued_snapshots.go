@@ -66,6 +66,26 @@ func (self *DiskCleanOverduedSnapshots) OnInit(ctx context.Context, obj db.IStan
 	}
 	cleanOverdueSnapshots = snapCount > (options.Options.DefaultMaxSnapshotCount - options.Options.DefaultMaxManualSnapshotCount)
 
+	if sp.IsRetainedByCount() && !cleanOverdueSnapshots {
+		snapshots, err := models.SnapshotManager.FetchRotatedAutoSnapshots(disk.Id, sp)
+		if err != nil {
+			self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+			return
+		}
+		if len(snapshots) == 0 {
+			self.SetStageComplete(ctx, nil)
+			return
+		}
+		// rotate snapshots one by one, OnInit is called back after snapshot deleted
+		snapshot := &snapshots[0]
+		snapshot.SetModelManager(models.SnapshotManager, snapshot)
+		err = snapshot.StartSnapshotDeleteTask(ctx, self.UserCred, false, self.Id)
+		if err != nil {
+			self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+		}
+		return
+	}
+
 	if sp.RetentionDays > 0 && !cleanOverdueSnapshots {
 		t := now.AddDate(0, 0, -1*sp.RetentionDays)
 		snapCount, err = models.SnapshotManager.Query().Equals("fake_deleted", false).Equals("disk_id", disk.Id).
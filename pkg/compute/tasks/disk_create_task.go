@@ -45,9 +45,10 @@ func (self *DiskCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel,
 	if len(imageId) > 0 && len(disk.SnapshotId) == 0 && len(disk.BackupId) == 0 {
 		self.SetStage("OnStorageCacheImageComplete", nil)
 		input := api.CacheImageInput{
-			ImageId:      imageId,
-			Format:       disk.DiskFormat,
-			ParentTaskId: self.GetTaskId(),
+			ImageId:         imageId,
+			Format:          disk.DiskFormat,
+			ParentTaskId:    self.GetTaskId(),
+			SignaturePolicy: models.GetImageSignaturePolicy(ctx, disk.GetOwnerId()),
 		}
 		guest := disk.GetGuest()
 		if guest != nil {
@@ -137,7 +137,10 @@ func (self *DiskResizeTask) OnDiskResizeComplete(ctx context.Context, disk *mode
 	}
 
 	self.CleanHostSchedCache(disk)
-	db.OpsLog.LogEvent(disk, db.ACT_RESIZE, disk.GetShortDesc(ctx), self.UserCred)
+	desc := disk.GetShortDesc(ctx)
+	// the size change is rated by the rating job
+	desc.Set("add_size", jsonutils.NewInt(int64(diff)))
+	db.OpsLog.LogEvent(disk, db.ACT_RESIZE, desc, self.UserCred)
 	logclient.AddActionLogWithStartable(self, disk, logclient.ACT_RESIZE, nil, self.UserCred, true)
 	self.OnDiskResized(ctx, disk)
 }
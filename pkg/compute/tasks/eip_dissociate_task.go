@@ -150,6 +150,9 @@ func (self *EipDissociateTask) OnInit(ctx context.Context, obj db.IStandaloneMod
 						errs = append(errs, errors.Wrapf(err, "nic %s", groupnic.IpAddr))
 					}
 				}
+			case api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY:
+				// nat entries using the eip are removed by nat
+				// gateway delete task
 			default:
 				errs = append(errs, errors.Wrapf(httperrors.ErrNotSupported, "not supported type %s", eip.AssociateType))
 			}
@@ -434,6 +434,16 @@ func (self *NutanixGuestCreateDiskTask) OnInit(ctx context.Context, obj db.IStan
 	self.SetStageComplete(ctx, nil)
 }
 
+// ProxmoxGuestCreateDiskTask creates the disks on the storages of proxmox by the vm config api
+type ProxmoxGuestCreateDiskTask struct {
+	NutanixGuestCreateDiskTask
+}
+
+// OVirtGuestCreateDiskTask creates the disks on the storage domains of oVirt by the disk attachments api
+type OVirtGuestCreateDiskTask struct {
+	NutanixGuestCreateDiskTask
+}
+
 func init() {
 	taskman.RegisterTask(GuestCreateBackupDisksTask{})
 	taskman.RegisterTask(GuestCreateDiskTask{})
@@ -441,4 +451,6 @@ func init() {
 	taskman.RegisterTask(ManagedGuestCreateDiskTask{})
 	taskman.RegisterTask(ESXiGuestCreateDiskTask{})
 	taskman.RegisterTask(NutanixGuestCreateDiskTask{})
+	taskman.RegisterTask(ProxmoxGuestCreateDiskTask{})
+	taskman.RegisterTask(OVirtGuestCreateDiskTask{})
 }
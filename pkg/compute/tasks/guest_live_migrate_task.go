@@ -328,6 +328,11 @@ func (self *GuestMigrateTask) sharedStorageMigrateConf(ctx context.Context, gues
 	body.Set("qemu_version", jsonutils.NewString(guest.GetQemuVersion(self.UserCred)))
 	body.Set("qemu_cmdline", jsonutils.NewString(guest.GetQemuCmdline(self.UserCred)))
 	targetDesc := guest.GetJsonDescAtHypervisor(ctx, targetHost)
+	var err error
+	targetDesc.SecurityIpsets, err = guest.GetSecurityIpsets()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetSecurityIpsets")
+	}
 	body.Set("desc", jsonutils.Marshal(targetDesc))
 	return body, nil
 }
@@ -364,6 +369,11 @@ func (self *GuestMigrateTask) localStorageMigrateConf(ctx context.Context,
 	if len(targetDesc.Disks) == 0 {
 		return nil, errors.Errorf("Get disksDesc error")
 	}
+	var err error
+	targetDesc.SecurityIpsets, err = guest.GetSecurityIpsets()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetSecurityIpsets")
+	}
 	targetStorages, _ := self.Params.GetArray("target_storages")
 	for i := 0; i < len(disks); i++ {
 		targetStorageId, err := targetStorages[i].GetString()
@@ -43,9 +43,10 @@ func (self *HADiskCreateTask) OnStorageCacheImageComplete(
 	imageId := disk.GetTemplateId()
 	self.SetStage("OnBackupStorageCacheImageComplete", nil)
 	input := api.CacheImageInput{
-		ImageId:      imageId,
-		Format:       disk.DiskFormat,
-		ParentTaskId: self.GetTaskId(),
+		ImageId:         imageId,
+		Format:          disk.DiskFormat,
+		ParentTaskId:    self.GetTaskId(),
+		SignaturePolicy: models.GetImageSignaturePolicy(ctx, disk.GetOwnerId()),
 	}
 	storagecache.StartImageCacheTask(ctx, self.UserCred, input)
 }
@@ -99,9 +100,10 @@ func (self *DiskCreateBackupTask) OnInit(ctx context.Context, obj db.IStandalone
 	if len(imageId) > 0 {
 		self.SetStage("OnBackupStorageCacheImageComplete", nil)
 		input := api.CacheImageInput{
-			ImageId:      imageId,
-			Format:       disk.DiskFormat,
-			ParentTaskId: self.GetTaskId(),
+			ImageId:         imageId,
+			Format:          disk.DiskFormat,
+			ParentTaskId:    self.GetTaskId(),
+			SignaturePolicy: models.GetImageSignaturePolicy(ctx, disk.GetOwnerId()),
 		}
 		storagecache.StartImageCacheTask(ctx, self.UserCred, input)
 	} else {
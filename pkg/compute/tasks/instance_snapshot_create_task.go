@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
@@ -55,6 +56,7 @@ func (self *InstanceSnapshotCreateTask) taskFail(
 	if guest == nil {
 		guest = models.GuestManager.FetchGuestById(isp.GuestId)
 	}
+	self.fsThaw(ctx, guest)
 	isp.SetStatus(self.UserCred, compute.INSTANCE_SNAPSHOT_FAILED, reason.String())
 	guest.SetStatus(self.UserCred, compute.VM_INSTANCE_SNAPSHOT_FAILED, reason.String())
 
@@ -71,8 +73,12 @@ func (self *InstanceSnapshotCreateTask) taskComplete(
 	if guest == nil {
 		guest = models.GuestManager.FetchGuestById(isp.GuestId)
 	}
+	self.fsThaw(ctx, guest)
 	isp.SetStatus(self.UserCred, compute.INSTANCE_SNAPSHOT_READY, "")
 	guest.StartSyncstatus(ctx, self.UserCred, "")
+	if backupStorageId, _ := self.Params.GetString("backup_storage_id"); len(backupStorageId) > 0 {
+		self.copyToBackupStorage(ctx, isp, backupStorageId)
+	}
 
 	db.OpsLog.LogEvent(isp, db.ACT_ALLOCATE, "instance snapshot create success", self.UserCred)
 	logclient.AddActionLogWithStartable(self, isp, logclient.ACT_CREATE, "", self.UserCred, true)
@@ -89,6 +95,9 @@ func (self *InstanceSnapshotCreateTask) OnInit(
 	isp := obj.(*models.SInstanceSnapshot)
 	guest := models.GuestManager.FetchGuestById(isp.GuestId)
 	self.SetStage("OnInstanceSnapshot", nil)
+	if jsonutils.QueryBoolean(self.Params, "fs_freeze", false) {
+		self.fsFreeze(ctx, guest)
+	}
 	params := jsonutils.NewDict()
 	params.Set("disk_index", jsonutils.NewInt(0))
 	if err := isp.GetRegionDriver().RequestCreateInstanceSnapshot(ctx, guest, isp, self, params); err != nil {
@@ -129,3 +138,43 @@ func (self *InstanceSnapshotCreateTask) OnInstanceSnapshot(ctx context.Context,
 func (self *InstanceSnapshotCreateTask) OnInstanceSnapshotFailed(ctx context.Context, isp *models.SInstanceSnapshot, data jsonutils.JSONObject) {
 	self.taskFail(ctx, isp, nil, data)
 }
+
+// fsFreeze freezes guest filesystems to make snapshots of all disks
+// consistent, instance snapshot goes on without freezing if it's not
+// available, e.g. guest agent is not installed
+func (self *InstanceSnapshotCreateTask) fsFreeze(ctx context.Context, guest *models.SGuest) {
+	cnt, err := guest.GetDriver().RequestGuestFsFreeze(ctx, self.UserCred, guest, 0)
+	if err != nil {
+		log.Warningf("guest %s fs freeze failed, snapshot without freezing: %v", guest.Name, err)
+		db.OpsLog.LogEvent(guest, db.ACT_GUEST_FS_FREEZE_FAIL, err.Error(), self.UserCred)
+		return
+	}
+	log.Infof("guest %s %d filesystems frozen", guest.Name, cnt)
+	self.SetStage("OnInstanceSnapshot", jsonutils.Marshal(map[string]bool{"fs_frozen": true}).(*jsonutils.JSONDict))
+}
+
+func (self *InstanceSnapshotCreateTask) fsThaw(ctx context.Context, guest *models.SGuest) {
+	if guest == nil || !jsonutils.QueryBoolean(self.Params, "fs_frozen", false) {
+		return
+	}
+	// guest agent thaws filesystems by itself on timeout if this fails
+	_, err := guest.GetDriver().RequestGuestFsThaw(ctx, self.UserCred, guest)
+	if err != nil {
+		log.Errorf("guest %s fs thaw failed: %v", guest.Name, err)
+		db.OpsLog.LogEvent(guest, db.ACT_GUEST_FS_THAW_FAIL, err.Error(), self.UserCred)
+	}
+}
+
+func (self *InstanceSnapshotCreateTask) copyToBackupStorage(ctx context.Context, isp *models.SInstanceSnapshot, backupStorageId string) {
+	snapshots, err := isp.GetSnapshots()
+	if err != nil {
+		log.Errorf("unable to get snapshots of instance snapshot %s: %v", isp.Name, err)
+		return
+	}
+	for i := range snapshots {
+		err := snapshots[i].StartCopyToBackupStorageTask(ctx, self.UserCred, backupStorageId)
+		if err != nil {
+			log.Errorf("copy snapshot %s to backup storage %s: %v", snapshots[i].Name, backupStorageId, err)
+		}
+	}
+}
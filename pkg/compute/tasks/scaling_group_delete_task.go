@@ -141,6 +141,13 @@ func (self *ScalingGroupDeleteTask) OnInit(ctx context.Context, obj db.IStandalo
 		}
 	}
 
+	// delete lifecycle hooks and instance refreshes
+	err = sg.RemoveLifecycleHooksAndInstanceRefreshes(ctx, self.UserCred)
+	if err != nil {
+		self.taskFailed(ctx, sg, jsonutils.NewString(fmt.Sprintf("ScalingGroup.RemoveLifecycleHooksAndInstanceRefreshes: %s", err.Error())))
+		return
+	}
+
 	err = sg.RealDelete(ctx, self.UserCred)
 	if err != nil {
 		self.taskFailed(ctx, sg, jsonutils.NewString(fmt.Sprintf("ScalingGroup.RealDelete: %s", err.Error())))
@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
@@ -55,6 +56,12 @@ func (self *SnapshotCreateTask) TaskComplete(ctx context.Context, snapshot *mode
 		Obj:    snapshot,
 		Action: notifyclient.ActionCreate,
 	})
+	if backupStorageId, _ := self.Params.GetString("backup_storage_id"); len(backupStorageId) > 0 {
+		err := snapshot.StartCopyToBackupStorageTask(ctx, self.UserCred, backupStorageId)
+		if err != nil {
+			log.Errorf("copy snapshot %s to backup storage %s: %v", snapshot.Name, backupStorageId, err)
+		}
+	}
 	self.SetStageComplete(ctx, nil)
 }
 
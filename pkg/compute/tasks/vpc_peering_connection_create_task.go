@@ -58,6 +58,17 @@ func (self *VpcPeeringConnectionCreateTask) OnInit(ctx context.Context, obj db.I
 		return
 	}
 
+	if !vpc.IsManaged() {
+		// realized by vpcagent once active
+		status := api.VPC_PEERING_CONNECTION_STATUS_ACTIVE
+		if jsonutils.QueryBoolean(self.Params, "require_accept", false) {
+			status = api.VPC_PEERING_CONNECTION_STATUS_PENDING_ACCEPT
+		}
+		peer.SetStatus(self.UserCred, status, "")
+		self.taskComplete(ctx, peer)
+		return
+	}
+
 	iVpc, err := vpc.GetIVpc()
 	if err != nil {
 		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetIVpc"))
@@ -51,6 +51,14 @@ func (self *VpcPeeringConnectionSyncstatusTask) OnInit(ctx context.Context, obj
 		return
 	}
 
+	if !svpc.IsManaged() {
+		// nothing to sync from.  Status is driven by create and acceptance
+		originStatus, _ := self.Params.GetString("origin_status")
+		peer.SetStatus(self.UserCred, originStatus, "")
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+
 	extVpc, err := svpc.GetIVpc()
 	if err != nil {
 		self.taskFail(ctx, peer, errors.Wrap(err, "svpc.GetIVpc()"))
@@ -48,6 +48,7 @@ type SASController struct {
 	scalingQueue    chan struct{}
 	timerQueue      chan struct{}
 	scalingGroupSet *SLockedSet
+	refreshSet      *SLockedSet
 	scalingSql      *sqlchemy.SQuery
 	// record the consecutive failures of scaling group's scale
 	failRecord map[string]int
@@ -89,10 +90,13 @@ func (asc *SASController) Init(options options.SASControllerOptions, cronm *cron
 	asc.options = options
 	cronm.AddJobAtIntervalsWithStartRun("CheckTimer", time.Duration(options.TimerInterval)*time.Second, asc.Timer, true)
 	cronm.AddJobAtIntervalsWithStartRun("CheckScale", time.Duration(options.CheckScaleInterval)*time.Second, asc.CheckScale, true)
+	cronm.AddJobAtIntervalsWithStartRun("CheckTargetTracking", time.Duration(options.TargetTrackingInterval)*time.Second, asc.TargetTracking, false)
+	cronm.AddJobAtIntervalsWithStartRun("CheckInstanceRefresh", time.Duration(options.InstanceRefreshInterval)*time.Second, asc.CheckInstanceRefresh, false)
 	cronm.AddJobAtIntervalsWithStartRun("CheckInstanceHealth", time.Duration(options.CheckHealthInterval)*time.Minute, asc.CheckInstanceHealth, true)
 	asc.timerQueue = make(chan struct{}, 20)
 	asc.scalingQueue = make(chan struct{}, options.ConcurrentUpper)
 	asc.scalingGroupSet = &SLockedSet{set: sets.NewString()}
+	asc.refreshSet = &SLockedSet{set: sets.NewString()}
 	asc.failRecord = make(map[string]int)
 
 	// init scalingSql
@@ -320,24 +324,15 @@ func (asc *SASController) DetachInstances(ctx context.Context, userCred mcclient
 	if err != nil {
 		return nil, errors.Wrap(err, "find suitable instances failed")
 	}
-	removeParams := jsonutils.NewDict()
-	removeParams.Set("scaling_group", jsonutils.NewString(sg.Id))
-	removeParams.Set("delete_server", jsonutils.JSONTrue)
-	removeParams.Set("auto", jsonutils.JSONTrue)
 	session := auth.GetSession(ctx, userCred, "", "")
-	failedList := make([]string, 0)
-	waitList := make([]string, 0, len(instances))
+	guestIds := make([]string, 0, len(instances))
 	instanceMap := make(map[string]SInstance, len(instances))
-	// request to detach instances with scaling group
 	for i := range instances {
 		instanceMap[instances[i].Id] = SInstance{instances[i].Id, instances[i].Name}
-		_, err := modules.Servers.PerformAction(session, instances[i].GetId(), "detach-scaling-group", removeParams)
-		if err != nil {
-			failedList = append(failedList, fmt.Sprintf("remove instance '%s' failed: %s", instances[i].GetId(), err.Error()))
-			continue
-		}
-		waitList = append(waitList, instances[i].GetId())
+		guestIds = append(guestIds, instances[i].GetId())
 	}
+	// request to detach instances with scaling group after the terminating lifecycle hooks
+	waitList, failedList := asc.requestDetachInstances(ctx, session, sg, guestIds)
 	// wait for all requests finished
 	succeedList := sets.NewString(waitList...)
 	ticker := time.NewTicker(3 * time.Second)
@@ -395,16 +390,85 @@ func (asc *SASController) findSuitableInstance(sg *models.SScalingGroup, num int
 	guestQ := models.GuestManager.Query().In("id", ggSubQ)
 	switch sg.ShrinkPrinciple {
 	case compute.SHRINK_EARLIEST_CREATION_FIRST:
-		guestQ = guestQ.Asc("created_at").Limit(num)
+		guestQ = guestQ.Asc("created_at")
 	case compute.SHRINK_LATEST_CREATION_FIRST:
-		guestQ = guestQ.Desc("created_at").Limit(num)
+		guestQ = guestQ.Desc("created_at")
 	}
 	guests := make([]models.SGuest, 0, num)
 	err := db.FetchModelObjects(models.GuestManager, guestQ, &guests)
 	if err != nil {
 		return nil, err
 	}
-	return guests, nil
+	if len(guests) <= num {
+		return guests, nil
+	}
+	return pickSpreadInstances(guests, num)
+}
+
+// pickSpreadInstances picks num guests in the order of guests, but the ones in
+// the most crowded topology domains of their spread instance groups go first,
+// so that the groups keep spread after scaling in
+func pickSpreadInstances(guests []models.SGuest, num int) ([]models.SGuest, error) {
+	guestIds := make([]string, len(guests))
+	for i := range guests {
+		guestIds[i] = guests[i].Id
+	}
+	ggSubQ := models.GroupguestManager.Query("group_id").In("guest_id", guestIds).SubQuery()
+	groupQ := models.GroupManager.Query().In("id", ggSubQ).Equals("policy", compute.INSTANCE_GROUP_POLICY_SPREAD)
+	groups := make([]models.SGroup, 0)
+	err := db.FetchModelObjects(models.GroupManager, groupQ, &groups)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch spread instance groups")
+	}
+	if len(groups) == 0 {
+		return guests[:num], nil
+	}
+
+	// guest id => group id => domain
+	guestDomains := make(map[string]map[string]string)
+	groupCounts := make(map[string]map[string]int)
+	for i := range groups {
+		domains, err := groups[i].GetGuestTopologyDomains(nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetGuestTopologyDomains of group %s", groups[i].Name)
+		}
+		counts := make(map[string]int)
+		for guestId, domain := range domains {
+			if len(domain) == 0 {
+				continue
+			}
+			counts[domain] += 1
+			if _, ok := guestDomains[guestId]; !ok {
+				guestDomains[guestId] = make(map[string]string)
+			}
+			guestDomains[guestId][groups[i].Id] = domain
+		}
+		groupCounts[groups[i].Id] = counts
+	}
+
+	picked := make([]bool, len(guests))
+	ret := make([]models.SGuest, 0, num)
+	for len(ret) < num {
+		idx, max := -1, -1
+		for i := range guests {
+			if picked[i] {
+				continue
+			}
+			crowd := 0
+			for groupId, domain := range guestDomains[guests[i].Id] {
+				crowd += groupCounts[groupId][domain]
+			}
+			if crowd > max {
+				idx, max = i, crowd
+			}
+		}
+		picked[idx] = true
+		for groupId, domain := range guestDomains[guests[idx].Id] {
+			groupCounts[groupId][domain] -= 1
+		}
+		ret = append(ret, guests[idx])
+	}
+	return ret, nil
 }
 
 func (asc *SASController) createInstances(session *mcclient.ClientSession, params jsonutils.JSONObject, count int,
@@ -700,6 +764,11 @@ func (asc *SASController) actionAfterCreate(
 		}
 		return
 	}
+	// pause on the launching lifecycle hooks before serving
+	if result, reason := asc.runLifecycleHooks(ctx, session, sg, ret.Id, compute.LIFECYCLE_HOOK_LAUNCHING); result == compute.LIFECYCLE_RESULT_ABANDON {
+		rollback(reason)
+		return
+	}
 	// bind lb
 	if len(sg.BackendGroupId) != 0 {
 		params := jsonutils.NewDict()
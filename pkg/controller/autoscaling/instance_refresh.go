@@ -0,0 +1,155 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaling
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+)
+
+// CheckInstanceRefresh pushes forward all instance refreshes in progress
+func (asc *SASController) CheckInstanceRefresh(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := models.ScalingInstanceRefreshManager.Query().Equals("status", compute.INSTANCE_REFRESH_STATUS_IN_PROGRESS)
+	refreshes := make([]models.SScalingInstanceRefresh, 0, 1)
+	err := db.FetchModelObjects(models.ScalingInstanceRefreshManager, q, &refreshes)
+	if err != nil {
+		log.Errorf("db.FetchModelObjects error: %s", err.Error())
+		return
+	}
+	for i := range refreshes {
+		refresh := &refreshes[i]
+		if !asc.refreshSet.CheckAndInsert(refresh.Id) {
+			continue
+		}
+		go func() {
+			defer asc.refreshSet.Delete(refresh.Id)
+			asc.stepInstanceRefresh(ctx, userCred, refresh)
+		}()
+	}
+}
+
+// stepInstanceRefresh replaces the outdated instances batch by batch. A batch is started only if all desire
+// instances are healthy, so that at least MinHealthyPercentage of them keep serving during the batch, and the
+// batch is finished when the instances of it are removed and the replacements become healthy.
+func (asc *SASController) stepInstanceRefresh(ctx context.Context, userCred mcclient.TokenCredential,
+	refresh *models.SScalingInstanceRefresh) {
+	fail := func(reason string) {
+		if len(refresh.ActivityId) > 0 {
+			if model, err := models.ScalingActivityManager.FetchById(refresh.ActivityId); err == nil {
+				model.(*models.SScalingActivity).SetFailed("", reason)
+			}
+		}
+		err := refresh.SetResult(compute.INSTANCE_REFRESH_STATUS_FAILED, reason)
+		if err != nil {
+			log.Errorf("set result of instance refresh '%s' failed: %s", refresh.Id, err.Error())
+		}
+	}
+	sg := refresh.GetScalingGroup()
+	if sg == nil {
+		fail(fmt.Sprintf("ScalingGroup '%s' not found", refresh.ScalingGroupId))
+		return
+	}
+	if sg.Enabled.IsFalse() {
+		fail("ScalingGroup was disabled")
+		return
+	}
+	healthy, err := sg.HealthyGuestIds()
+	if err != nil {
+		log.Errorf("fetch healthy guests of ScalingGroup '%s' failed: %s", sg.Id, err.Error())
+		if len(refresh.CurrentBatch) > 0 && time.Now().After(refresh.BatchDeadline) {
+			fail(fmt.Sprintf("unable to check the health of instances: %s", err.Error()))
+		}
+		return
+	}
+	desire := sg.DesireInstanceNumber
+	now := time.Now()
+
+	if batch := refresh.CurrentBatchGuestIds(); len(batch) > 0 {
+		sggs, err := sg.ScalingGroupGuests(batch)
+		if err != nil {
+			log.Errorf("ScalingGroup.ScalingGroupGuests error: %s", err.Error())
+			return
+		}
+		if len(sggs) == 0 && len(healthy) >= desire {
+			if model, err := models.ScalingActivityManager.FetchById(refresh.ActivityId); err == nil {
+				model.(*models.SScalingActivity).SetResult(
+					fmt.Sprintf("Instances '%s' are replaced", strings.Join(batch, "', '")),
+					compute.SA_STATUS_SUCCEED,
+					fmt.Sprintf("%d of %d desire instances are healthy after the batch", len(healthy), desire),
+					len(healthy),
+				)
+			}
+			err = refresh.FinishBatch(len(batch))
+			if err != nil {
+				log.Errorf("finish batch of instance refresh '%s' failed: %s", refresh.Id, err.Error())
+			}
+			return
+		}
+		if now.After(refresh.BatchDeadline) {
+			fail(fmt.Sprintf("the batch wasn't finished in %d seconds: %d of %d instances are still in ScalingGroup and %d of %d desire instances are healthy",
+				refresh.BatchTimeout, len(sggs), len(batch), len(healthy), desire))
+		}
+		return
+	}
+
+	outdated, err := sg.OutdatedGuestIds(refresh.StartTime, refresh.BatchSize)
+	if err != nil {
+		log.Errorf("fetch outdated guests of ScalingGroup '%s' failed: %s", sg.Id, err.Error())
+		return
+	}
+	if len(outdated) == 0 {
+		err := refresh.SetResult(compute.INSTANCE_REFRESH_STATUS_SUCCEED,
+			fmt.Sprintf("%d instances are replaced", refresh.ReplacedNumber))
+		if err != nil {
+			log.Errorf("set result of instance refresh '%s' failed: %s", refresh.Id, err.Error())
+		}
+		return
+	}
+	if len(healthy) < desire {
+		log.Infof("instance refresh '%s' waits for ScalingGroup '%s' to be healthy: %d of %d", refresh.Id, sg.Id, len(healthy), desire)
+		return
+	}
+
+	activity, err := models.ScalingActivityManager.CreateScalingActivity(ctx, sg.Id,
+		fmt.Sprintf("Instance refresh replaces %d outdated instances", len(outdated)), compute.SA_STATUS_EXEC)
+	if err != nil {
+		log.Errorf("create ScalingActivity for instance refresh '%s' failed: %s", refresh.Id, err.Error())
+		return
+	}
+	err = refresh.StartBatch(outdated, activity.Id, now)
+	if err != nil {
+		activity.SetFailed("", err.Error())
+		log.Errorf("start batch of instance refresh '%s' failed: %s", refresh.Id, err.Error())
+		return
+	}
+	log.Infof("instance refresh '%s' starts to replace %v: %d of %d desire instances are healthy, at least %d%% of them keep healthy",
+		refresh.Id, outdated, len(healthy), desire, refresh.MinHealthyPercentage)
+	session := auth.GetSession(ctx, userCred, "", "")
+	// the desire instance number is unchanged, so the replacements are created by the latest guest template
+	_, failedList := asc.requestDetachInstances(ctx, session, sg, outdated)
+	if len(failedList) > 0 {
+		fail(strings.Join(failedList, "; "))
+	}
+}
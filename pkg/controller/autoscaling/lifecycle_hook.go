@@ -0,0 +1,255 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaling
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	ansible_api "yunion.io/x/onecloud/pkg/apis/ansible"
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	ansible_modules "yunion.io/x/onecloud/pkg/mcclient/modules/ansible"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	lifecycleCheckInterval  = 3 * time.Second
+	lifecycleWebhookTimeout = 30 * time.Second
+)
+
+// runLifecycleHooks pauses the guest on every enabled lifecycle hook of hookType in turn, and returns the final
+// result and the reason. The guest will be abandoned if any hook results in abandon.
+func (asc *SASController) runLifecycleHooks(ctx context.Context, session *mcclient.ClientSession,
+	sg *models.SScalingGroup, guestId, hookType string) (string, string) {
+	hooks, err := sg.LifecycleHooks(hookType)
+	if err != nil {
+		log.Errorf("fetch %s lifecycle hooks of ScalingGroup '%s' failed: %s", hookType, sg.Id, err.Error())
+		return compute.LIFECYCLE_RESULT_CONTINUE, ""
+	}
+	if len(hooks) == 0 {
+		return compute.LIFECYCLE_RESULT_CONTINUE, ""
+	}
+	sggs, err := models.ScalingGroupGuestManager.Fetch(sg.Id, guestId)
+	if err != nil || len(sggs) == 0 {
+		log.Errorf("ScalingGroupGuestManager.Fetch failed; ScalingGroup '%s', Guest '%s'", sg.Id, guestId)
+		return compute.LIFECYCLE_RESULT_CONTINUE, ""
+	}
+	sgg := &sggs[0]
+	waitStatus, doneStatus := compute.SG_GUEST_STATUS_LAUNCH_WAIT, compute.SG_GUEST_STATUS_JOINING
+	if hookType == compute.LIFECYCLE_HOOK_TERMINATING {
+		waitStatus, doneStatus = compute.SG_GUEST_STATUS_TERMINATE_WAIT, sgg.GuestStatus
+	}
+	defer func() {
+		err := sgg.FinishLifecycleAction(doneStatus)
+		if err != nil {
+			log.Errorf("finish lifecycle action of Guest '%s' failed: %s", guestId, err.Error())
+		}
+	}()
+	for i := range hooks {
+		result, reason := asc.runLifecycleHook(ctx, session, sg, sgg, &hooks[i], waitStatus)
+		log.Infof("lifecycle hook '%s' of Guest '%s': %s, %s", hooks[i].Id, guestId, result, reason)
+		if result == compute.LIFECYCLE_RESULT_ABANDON {
+			return result, reason
+		}
+	}
+	return compute.LIFECYCLE_RESULT_CONTINUE, ""
+}
+
+func (asc *SASController) runLifecycleHook(ctx context.Context, session *mcclient.ClientSession,
+	sg *models.SScalingGroup, sgg *models.SScalingGroupGuest, hook *models.SScalingLifecycleHook, waitStatus string) (string, string) {
+	deadline := time.Now().Add(time.Duration(hook.Timeout) * time.Second)
+	defaultResult := func(format string, args ...interface{}) (string, string) {
+		return hook.DefaultResult, fmt.Sprintf("%s, so take the default result '%s' of lifecycle hook '%s'",
+			fmt.Sprintf(format, args...), hook.DefaultResult, hook.Name)
+	}
+	err := sgg.StartLifecycleAction(hook.Id, waitStatus, deadline)
+	if err != nil {
+		return defaultResult("unable to pause instance '%s': %s", sgg.GuestId, err.Error())
+	}
+	switch hook.ActionType {
+	case compute.LIFECYCLE_ACTION_WEBHOOK:
+		result, err := asc.lifecycleWebhook(ctx, sg, sgg, hook, deadline)
+		if err != nil {
+			return defaultResult("webhook for instance '%s' failed: %s", sgg.GuestId, err.Error())
+		}
+		if len(result) > 0 {
+			return result, fmt.Sprintf("webhook of lifecycle hook '%s' responded '%s' for instance '%s'", hook.Name, result, sgg.GuestId)
+		}
+	case compute.LIFECYCLE_ACTION_ANSIBLE:
+		err := asc.lifecycleAnsible(ctx, session, sg, sgg, hook, deadline)
+		if err != nil {
+			return defaultResult("ansible playbook for instance '%s' failed: %s", sgg.GuestId, err.Error())
+		}
+		return compute.LIFECYCLE_RESULT_CONTINUE, fmt.Sprintf("ansible playbook of lifecycle hook '%s' succeeded for instance '%s'", hook.Name, sgg.GuestId)
+	default:
+		return defaultResult("unknown action type '%s'", hook.ActionType)
+	}
+	// wait for the result reported by complete-lifecycle-action
+	ticker := time.NewTicker(lifecycleCheckInterval)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		<-ticker.C
+		sggs, err := models.ScalingGroupGuestManager.Fetch(sg.Id, sgg.GuestId)
+		if err != nil {
+			log.Errorf("ScalingGroupGuestManager.Fetch failed: %s", err.Error())
+			continue
+		}
+		if len(sggs) == 0 {
+			return compute.LIFECYCLE_RESULT_ABANDON, fmt.Sprintf("instance '%s' was removed from ScalingGroup while waiting on lifecycle hook '%s'", sgg.GuestId, hook.Name)
+		}
+		if result := sggs[0].LifecycleResult; len(result) > 0 {
+			return result, fmt.Sprintf("the result of lifecycle hook '%s' for instance '%s' is reported as '%s'", hook.Name, sgg.GuestId, result)
+		}
+	}
+	return defaultResult("no result was reported for instance '%s' in %d seconds", sgg.GuestId, hook.Timeout)
+}
+
+func lifecycleHookPayload(sg *models.SScalingGroup, sgg *models.SScalingGroupGuest, hook *models.SScalingLifecycleHook,
+	deadline time.Time) compute.ScalingLifecycleHookPayload {
+	payload := compute.ScalingLifecycleHookPayload{
+		LifecycleHookId:   hook.Id,
+		LifecycleHookName: hook.Name,
+		HookType:          hook.HookType,
+		ScalingGroupId:    sg.Id,
+		ScalingGroupName:  sg.Name,
+		GuestId:           sgg.GuestId,
+		Deadline:          deadline,
+	}
+	if guest, err := models.GuestManager.FetchById(sgg.GuestId); err == nil {
+		payload.GuestName = guest.GetName()
+	}
+	return payload
+}
+
+// lifecycleWebhook posts the lifecycle event to the webhook, the result could be responded directly
+// or be reported by complete-lifecycle-action later
+func (asc *SASController) lifecycleWebhook(ctx context.Context, sg *models.SScalingGroup, sgg *models.SScalingGroupGuest,
+	hook *models.SScalingLifecycleHook, deadline time.Time) (string, error) {
+	payload := jsonutils.Marshal(lifecycleHookPayload(sg, sgg, hook, deadline))
+	client := httputils.GetTimeoutClient(lifecycleWebhookTimeout)
+	_, resp, err := httputils.JSONRequest(client, ctx, httputils.POST, hook.WebhookUrl, nil, payload, false)
+	if err != nil {
+		return "", errors.Wrapf(err, "post %s", hook.WebhookUrl)
+	}
+	if resp == nil {
+		return "", nil
+	}
+	result, _ := resp.GetString("result")
+	switch result {
+	case compute.LIFECYCLE_RESULT_CONTINUE, compute.LIFECYCLE_RESULT_ABANDON:
+		return result, nil
+	}
+	return "", nil
+}
+
+// lifecycleAnsible runs the ansible playbook on the guest and waits for it finished
+func (asc *SASController) lifecycleAnsible(ctx context.Context, session *mcclient.ClientSession, sg *models.SScalingGroup,
+	sgg *models.SScalingGroupGuest, hook *models.SScalingLifecycleHook, deadline time.Time) error {
+	model, err := models.GuestManager.FetchById(sgg.GuestId)
+	if err != nil {
+		return errors.Wrapf(err, "fetch guest %s", sgg.GuestId)
+	}
+	guest := model.(*models.SGuest)
+	ips := guest.GetRealIPs()
+	if len(ips) == 0 {
+		return errors.Errorf("guest %s has no ip address", guest.Id)
+	}
+	args := jsonutils.NewDict()
+	args.Set("scaling_lifecycle", jsonutils.Marshal(lifecycleHookPayload(sg, sgg, hook, deadline)))
+	runParams := jsonutils.Marshal(ansible_api.AnsiblePlaybookReferenceRunInput{
+		Host: ansible_api.AnsibleHost{
+			User: hook.AnsibleUser,
+			IP:   ips[0],
+			Port: 22,
+			Name: guest.Name,
+		},
+		Args: args,
+	})
+	ret, err := ansible_modules.AnsiblePlaybookReference.PerformAction(session, hook.AnsiblePlaybookReference, "run", runParams)
+	if err != nil {
+		return errors.Wrapf(err, "run ansible playbook reference %s", hook.AnsiblePlaybookReference)
+	}
+	instanceId, _ := ret.GetString("ansible_playbook_instance_id")
+	if len(instanceId) == 0 {
+		return errors.Errorf("no ansible playbook instance is returned")
+	}
+	ticker := time.NewTicker(lifecycleCheckInterval)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		<-ticker.C
+		instance, err := ansible_modules.AnsiblePlaybookInstance.Get(session, instanceId, nil)
+		if err != nil {
+			log.Errorf("AnsiblePlaybookInstance.Get failed: %s", err.Error())
+			continue
+		}
+		status, _ := instance.GetString("status")
+		switch status {
+		case ansible_api.AnsiblePlaybookStatusSucceeded:
+			return nil
+		case ansible_api.AnsiblePlaybookStatusFailed, ansible_api.AnsiblePlaybookStatusCanceled, ansible_api.AnsiblePlaybookStatusUnknown:
+			return errors.Errorf("ansible playbook instance %s is %s", instanceId, status)
+		}
+	}
+	stopParams := jsonutils.NewDict()
+	stopParams.Set("ansible_playbook_instance_id", jsonutils.NewString(instanceId))
+	_, err = ansible_modules.AnsiblePlaybookReference.PerformAction(session, hook.AnsiblePlaybookReference, "stop", stopParams)
+	if err != nil {
+		log.Errorf("stop ansible playbook instance %s failed: %s", instanceId, err.Error())
+	}
+	return errors.Errorf("ansible playbook instance %s timeout", instanceId)
+}
+
+// requestDetachInstances runs the terminating lifecycle hooks of guests concurrently, and then requests to detach
+// them from scaling group with deleting. It returns the guests requested successfully and the failure reasons.
+func (asc *SASController) requestDetachInstances(ctx context.Context, session *mcclient.ClientSession,
+	sg *models.SScalingGroup, guestIds []string) ([]string, []string) {
+	removeParams := jsonutils.NewDict()
+	removeParams.Set("scaling_group", jsonutils.NewString(sg.Id))
+	removeParams.Set("delete_server", jsonutils.JSONTrue)
+	removeParams.Set("auto", jsonutils.JSONTrue)
+	var (
+		lock        sync.Mutex
+		wg          sync.WaitGroup
+		succeedList = make([]string, 0, len(guestIds))
+		failedList  = make([]string, 0)
+	)
+	for _, guestId := range guestIds {
+		wg.Add(1)
+		go func(guestId string) {
+			defer wg.Done()
+			// the guest is removed whatever the result of terminating lifecycle hooks is
+			asc.runLifecycleHooks(ctx, session, sg, guestId, compute.LIFECYCLE_HOOK_TERMINATING)
+			_, err := modules.Servers.PerformAction(session, guestId, "detach-scaling-group", removeParams)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				failedList = append(failedList, fmt.Sprintf("remove instance '%s' failed: %s", guestId, err.Error()))
+				return
+			}
+			succeedList = append(succeedList, guestId)
+		}(guestId)
+	}
+	wg.Wait()
+	return succeedList, failedList
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaling
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+)
+
+// TargetTracking requests to trigger all ready target tracking scaling policies, each of them evaluates the
+// monitor data and changes the desire instance number of scaling group if necessary
+func (asc *SASController) TargetTracking(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	sgSubQ := models.ScalingGroupManager.Query("id").IsTrue("enabled").SubQuery()
+	q := models.ScalingPolicyManager.Query().Equals("trigger_type", compute.TRIGGER_TARGET_TRACKING).
+		Equals("status", compute.SP_STATUS_READY).IsTrue("enabled").In("scaling_group_id", sgSubQ)
+	policies := make([]models.SScalingPolicy, 0, 5)
+	err := db.FetchModelObjects(models.ScalingPolicyManager, q, &policies)
+	if err != nil {
+		log.Errorf("db.FetchModelObjects error: %s", err.Error())
+		return
+	}
+	session := auth.GetSession(ctx, userCred, "", "")
+	triggerParams := jsonutils.NewDict()
+	for i := range policies {
+		spId := policies[i].Id
+		asc.timerQueue <- struct{}{}
+		go func() {
+			defer func() {
+				<-asc.timerQueue
+			}()
+			_, err := modules.ScalingPolicy.PerformAction(session, spId, "trigger", triggerParams)
+			if err != nil {
+				log.Errorf("unable to request to trigger ScalingPolicy '%s': %s", spId, err.Error())
+			}
+		}()
+	}
+}
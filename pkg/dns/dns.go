@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
@@ -87,12 +89,37 @@ type SRegionDNS struct {
 	Region        string
 	K8sSkip       bool
 
+	// TSIG keys indexed by fqdn key name
+	TsigKeys map[string]*tsigKey
+	// peers allowed to transfer the primary zone
+	TransferPeers []transferPeer
+	// address of the transfer listener serving TSIG signed transfers
+	TransferListen string
+	// secondaries notified on zone change
+	NotifyPeers    []notifyPeer
+	NotifyInterval time.Duration
+
+	Dnssec          bool
+	ZskLifetime     time.Duration
+	Nsec3Iterations uint16
+	Nsec3Salt       string
+
 	K8sManager            *k8s.SKubeClusterManager
 	primaryZoneLabelCount int
+
+	zoneLock sync.RWMutex
+	zone     *zoneSnapshot
+	// *dnssecSigner, set once the keys are loaded
+	signer atomic.Value
 }
 
 func New() *SRegionDNS {
-	r := &SRegionDNS{}
+	r := &SRegionDNS{
+		TsigKeys:        map[string]*tsigKey{},
+		NotifyInterval:  defaultNotifyInterval,
+		ZskLifetime:     defaultZskLifetime,
+		Nsec3Iterations: defaultNsec3Iterations,
+	}
 	return r
 }
 
@@ -118,7 +145,6 @@ func (r *SRegionDNS) initDB(c *caddy.Controller) error {
 }
 
 func (r *SRegionDNS) initK8s() {
-	r.initAuth()
 	r.K8sManager = k8s.NewKubeClusterManager(r.Region, 30*time.Second)
 	r.K8sManager.Start()
 }
@@ -143,6 +169,10 @@ func (r *SRegionDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, rmsg *d
 	state := request.Request{W: w, Req: rmsg, Context: ctx}
 	zone := plugin.Zones(r.Zones).Matches(state.Name())
 	switch state.QType() {
+	case dns.TypeAXFR, dns.TypeIXFR:
+		return r.Transfer(ctx, state)
+	case dns.TypeDNSKEY, dns.TypeNSEC3PARAM:
+		records, err = r.dnssecRecords(state)
 	case dns.TypeA:
 		records, err = plugin.A(r, zone, state, nil, opt)
 	case dns.TypeAAAA:
@@ -176,15 +206,19 @@ func (r *SRegionDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, rmsg *d
 		if r.Fall.Through(state.Name()) {
 			return plugin.NextOrFailure(r.Name(), r.Next, ctx, w, rmsg)
 		}
-		return plugin.BackendError(r, zone, dns.RcodeNameError, state, nil /* err */, opt)
+		return r.backendError(zone, dns.RcodeNameError, state, nil /* err */, opt)
 	} else if err == errRefused {
-		return plugin.BackendError(r, zone, dns.RcodeRefused, state, err, opt)
+		return r.backendError(zone, dns.RcodeRefused, state, err, opt)
 	} else if err == errNotFound {
-		return plugin.BackendError(r, zone, dns.RcodeNameError, state, err, opt)
+		return r.backendError(zone, dns.RcodeNameError, state, err, opt)
 	}
 
 	if len(records) == 0 {
-		return plugin.BackendError(r, zone, dns.RcodeNameError, state, err, opt)
+		if err == nil {
+			// the name exists without records of the type
+			return r.backendError(zone, dns.RcodeSuccess, state, nil, opt)
+		}
+		return r.backendError(zone, dns.RcodeNameError, state, err, opt)
 	}
 
 	m := new(dns.Msg)
@@ -192,6 +226,9 @@ func (r *SRegionDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, rmsg *d
 	m.Authoritative, m.RecursionAvailable = true, true
 	m.Answer = append(m.Answer, records...)
 	m.Extra = append(m.Extra, extra...)
+	if r.shouldSign(state) {
+		r.getSigner().signMsg(m)
+	}
 
 	state.SizeAndDo(m)
 	m = state.Scrub(m)
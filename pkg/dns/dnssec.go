@@ -0,0 +1,578 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+
+	"yunion.io/x/jsonutils"
+	ylog "yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	identityapi "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/identity"
+)
+
+const (
+	dnssecAlgorithm = dns.ECDSAP256SHA256
+	dnssecKeyTTL    = 3600
+
+	dnskeyFlagZSK = 256
+	dnskeyFlagKSK = 257
+
+	defaultZskLifetime = 30 * 24 * time.Hour
+	// a new ZSK is published this long before it signs, so that resolvers
+	// have it cached before meeting its signatures
+	zskPrepublishPeriod = 2 * time.Hour
+	// an old ZSK stays published this long after its successor signs, so
+	// that cached signatures of it still validate
+	zskRetirePeriod = 2 * time.Hour
+
+	dnssecKeySyncInterval = 10 * time.Minute
+
+	sigValidity      = 7 * 24 * time.Hour
+	sigInceptionSkew = time.Hour
+	// cached signatures are renewed when they are older than this
+	sigRefresh       = 24 * time.Hour
+	sigCacheCapacity = 10000
+)
+
+type dnssecKey struct {
+	credId     string
+	dnskey     *dns.DNSKEY
+	signer     crypto.Signer
+	createdAt  time.Time
+	activateAt time.Time
+	slot       string
+	// creation time of the credential in keystone, which orders the keys
+	// created by replicas concurrently
+	credCreatedAt time.Time
+}
+
+func (k *dnssecKey) isKsk() bool {
+	return k.dnskey.Flags == dnskeyFlagKSK
+}
+
+type cachedSig struct {
+	sig      *dns.RRSIG
+	signedAt time.Time
+}
+
+// dnssecSigner signs the answers of a zone online, keys are kept in the
+// keystone credential store so that all dns replicas share them.
+//
+// ZSKs are rolled over automatically by pre-publishing.  KSK rollover
+// involves the DS record in parent zone and is left to the operator: add a
+// new KSK credential, update DS, then delete the old credential
+type dnssecSigner struct {
+	zone            string
+	zskLifetime     time.Duration
+	nsec3Iterations uint16
+	nsec3Salt       string
+
+	lock      sync.RWMutex
+	ksks      []*dnssecKey
+	zsk       *dnssecKey
+	published []*dnssecKey
+
+	cacheLock sync.Mutex
+	sigCache  map[string]*cachedSig
+}
+
+func newDnssecSigner(zone string, zskLifetime time.Duration, iterations uint16, salt string) *dnssecSigner {
+	return &dnssecSigner{
+		zone:            zone,
+		zskLifetime:     zskLifetime,
+		nsec3Iterations: iterations,
+		nsec3Salt:       salt,
+		sigCache:        map[string]*cachedSig{},
+	}
+}
+
+func (r *SRegionDNS) initDnssec() {
+	signer := newDnssecSigner(r.PrimaryZone, r.ZskLifetime, r.Nsec3Iterations, r.Nsec3Salt)
+	for {
+		err := signer.syncKeys(r)
+		if err != nil {
+			ylog.Errorf("sync dnssec keys of %s: %v", r.PrimaryZone, err)
+		} else if r.getSigner() == nil {
+			r.signer.Store(signer)
+			ylog.Infof("dnssec signing of zone %s enabled", r.PrimaryZone)
+		}
+		time.Sleep(dnssecKeySyncInterval)
+	}
+}
+
+func (r *SRegionDNS) getSigner() *dnssecSigner {
+	signer, _ := r.signer.Load().(*dnssecSigner)
+	return signer
+}
+
+func (s *dnssecSigner) ready() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.zsk != nil && len(s.ksks) > 0
+}
+
+func (s *dnssecSigner) newKey(flags uint16, activateAt time.Time, slot string) (identityapi.SDnssecKeyBlob, error) {
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: s.zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: dnssecKeyTTL},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dnssecAlgorithm,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		return identityapi.SDnssecKeyBlob{}, errors.Wrap(err, "Generate")
+	}
+	return identityapi.SDnssecKeyBlob{
+		Zone:       s.zone,
+		Flags:      flags,
+		Algorithm:  dnssecAlgorithm,
+		PublicKey:  k.PublicKey,
+		PrivateKey: k.PrivateKeyString(priv),
+		CreatedAt:  time.Now().Unix(),
+		ActivateAt: activateAt.Unix(),
+		Slot:       slot,
+	}, nil
+}
+
+func parseDnssecKey(credId string, blob identityapi.SDnssecKeyBlob) (*dnssecKey, error) {
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: blob.Zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: dnssecKeyTTL},
+		Flags:     blob.Flags,
+		Protocol:  3,
+		Algorithm: blob.Algorithm,
+		PublicKey: blob.PublicKey,
+	}
+	priv, err := k.NewPrivateKey(blob.PrivateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewPrivateKey")
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key of %s is not a signer", credId)
+	}
+	return &dnssecKey{
+		credId:     credId,
+		dnskey:     k,
+		signer:     signer,
+		createdAt:  time.Unix(blob.CreatedAt, 0),
+		activateAt: time.Unix(blob.ActivateAt, 0),
+		slot:       blob.Slot,
+	}, nil
+}
+
+func (s *dnssecSigner) fetchKeys(r *SRegionDNS) ([]*dnssecKey, error) {
+	session := r.getAdminSession(context.Background())
+	creds, err := identity.Credentials.FetchDnssecKeys(session)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchDnssecKeys")
+	}
+	keys := make([]*dnssecKey, 0)
+	for i := range creds {
+		credId, _ := creds[i].GetString("id")
+		blob := identityapi.SDnssecKeyBlob{}
+		blobStr, _ := creds[i].GetString("blob")
+		blobJson, err := jsonutils.ParseString(blobStr)
+		if err == nil {
+			err = blobJson.Unmarshal(&blob)
+		}
+		if err != nil {
+			ylog.Errorf("invalid dnssec key %s: %v", credId, err)
+			continue
+		}
+		if !strings.EqualFold(blob.Zone, s.zone) {
+			continue
+		}
+		key, err := parseDnssecKey(credId, blob)
+		if err != nil {
+			ylog.Errorf("invalid dnssec key %s: %v", credId, err)
+			continue
+		}
+		key.credCreatedAt, _ = creds[i].GetTime("created_at")
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// dedupSlotKeys splits the keys created for the same slot by replicas
+// concurrently.  The earliest created one of each slot is kept, so that all
+// replicas agree on it and the one that may already sign stays
+func dedupSlotKeys(keys []*dnssecKey) ([]*dnssecKey, []*dnssecKey) {
+	first := map[string]*dnssecKey{}
+	for _, k := range keys {
+		if len(k.slot) == 0 {
+			continue
+		}
+		f, ok := first[k.slot]
+		if !ok || k.credCreatedAt.Before(f.credCreatedAt) ||
+			(k.credCreatedAt.Equal(f.credCreatedAt) && k.credId < f.credId) {
+			first[k.slot] = k
+		}
+	}
+	kept, dups := []*dnssecKey{}, []*dnssecKey{}
+	for _, k := range keys {
+		if len(k.slot) > 0 && first[k.slot] != k {
+			dups = append(dups, k)
+		} else {
+			kept = append(kept, k)
+		}
+	}
+	return kept, dups
+}
+
+func (s *dnssecSigner) createKey(r *SRegionDNS, flags uint16, activateAt time.Time, slot string) error {
+	blob, err := s.newKey(flags, activateAt, slot)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("dnssec-%s-%d-%d", strings.TrimSuffix(s.zone, "."), flags, blob.CreatedAt)
+	session := r.getAdminSession(context.Background())
+	_, err = identity.Credentials.CreateDnssecKey(session, blob, name)
+	if err != nil {
+		return errors.Wrap(err, "CreateDnssecKey")
+	}
+	return nil
+}
+
+// createMissingKeys creates the KSK and ZSK of a zone without keys, and
+// pre-publishes the successor of an expired ZSK.  Each key is created for a
+// slot named after what it replaces, keys of the same slot created by other
+// replicas at the same time are dropped by dedupSlotKeys
+func (s *dnssecSigner) createMissingKeys(r *SRegionDNS, ksks, zsks []*dnssecKey, now time.Time) (bool, error) {
+	created := false
+	if len(ksks) == 0 {
+		if err := s.createKey(r, dnskeyFlagKSK, now, "ksk"); err != nil {
+			return false, errors.Wrap(err, "create KSK")
+		}
+		created = true
+	}
+	if len(zsks) == 0 {
+		if err := s.createKey(r, dnskeyFlagZSK, now, "zsk"); err != nil {
+			return false, errors.Wrap(err, "create ZSK")
+		}
+		return true, nil
+	}
+	last := zsks[len(zsks)-1]
+	if !last.activateAt.After(now) && now.Sub(last.activateAt) >= s.zskLifetime {
+		if err := s.createKey(r, dnskeyFlagZSK, now.Add(zskPrepublishPeriod), "zsk-after-"+last.credId); err != nil {
+			return false, errors.Wrap(err, "rollover ZSK")
+		}
+		ylog.Infof("ZSK %d of zone %s rolled over", last.dnskey.KeyTag(), s.zone)
+		created = true
+	}
+	return created, nil
+}
+
+// syncKeys loads the keys of the zone, creates the missing ones and rolls
+// over the ZSK
+func (s *dnssecSigner) syncKeys(r *SRegionDNS) error {
+	return s.doSyncKeys(r, true)
+}
+
+func (s *dnssecSigner) doSyncKeys(r *SRegionDNS, allowCreate bool) error {
+	keys, err := s.fetchKeys(r)
+	if err != nil {
+		return err
+	}
+	keys, dups := dedupSlotKeys(keys)
+	for _, k := range dups {
+		session := r.getAdminSession(context.Background())
+		if _, err := identity.Credentials.Delete(session, k.credId, nil); err != nil {
+			ylog.Errorf("delete duplicate key %d of zone %s: %v", k.dnskey.KeyTag(), s.zone, err)
+		} else {
+			ylog.Infof("duplicate key %d of zone %s for slot %s deleted", k.dnskey.KeyTag(), s.zone, k.slot)
+		}
+	}
+	now := time.Now()
+	ksks, zsks := []*dnssecKey{}, []*dnssecKey{}
+	for _, k := range keys {
+		if k.isKsk() {
+			ksks = append(ksks, k)
+		} else {
+			zsks = append(zsks, k)
+		}
+	}
+	sort.Slice(zsks, func(i, j int) bool {
+		if zsks[i].activateAt.Equal(zsks[j].activateAt) {
+			return zsks[i].dnskey.KeyTag() < zsks[j].dnskey.KeyTag()
+		}
+		return zsks[i].activateAt.Before(zsks[j].activateAt)
+	})
+
+	if allowCreate {
+		created, err := s.createMissingKeys(r, ksks, zsks, now)
+		if err != nil {
+			return err
+		}
+		if created {
+			// load again for the new keys, without creating any more
+			return s.doSyncKeys(r, false)
+		}
+	}
+
+	// the active ZSK is the latest activated one, those before it are
+	// retired after their successor signs long enough
+	var active *dnssecKey
+	published := make([]*dnssecKey, 0, len(keys))
+	published = append(published, ksks...)
+	for i, k := range zsks {
+		if k.activateAt.After(now) {
+			published = append(published, k)
+			continue
+		}
+		active = k
+		if i+1 < len(zsks) {
+			next := zsks[i+1]
+			if !next.activateAt.After(now) && now.Sub(next.activateAt) >= zskRetirePeriod {
+				session := r.getAdminSession(context.Background())
+				if _, err := identity.Credentials.Delete(session, k.credId, nil); err != nil {
+					ylog.Errorf("delete retired ZSK %d of zone %s: %v", k.dnskey.KeyTag(), s.zone, err)
+				} else {
+					ylog.Infof("ZSK %d of zone %s retired", k.dnskey.KeyTag(), s.zone)
+				}
+				continue
+			}
+		}
+		published = append(published, k)
+	}
+
+	s.lock.Lock()
+	if len(s.ksks) == 0 {
+		for _, k := range ksks {
+			ylog.Infof("KSK of zone %s, add to the parent zone: %s", s.zone, k.dnskey.ToDS(dns.SHA256).String())
+		}
+	}
+	s.ksks = ksks
+	s.zsk = active
+	s.published = published
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *dnssecSigner) dnskeys() []dns.RR {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	rrs := make([]dns.RR, 0, len(s.published))
+	for _, k := range s.published {
+		rrs = append(rrs, dns.Copy(k.dnskey))
+	}
+	return rrs
+}
+
+func (s *dnssecSigner) nsec3param() dns.RR {
+	return &dns.NSEC3PARAM{
+		Hdr:        dns.RR_Header{Name: s.zone, Rrtype: dns.TypeNSEC3PARAM, Class: dns.ClassINET, Ttl: 0},
+		Hash:       dns.SHA1,
+		Iterations: s.nsec3Iterations,
+		SaltLength: uint8(len(s.nsec3Salt) / 2),
+		Salt:       s.nsec3Salt,
+	}
+}
+
+// signMsg adds the signatures of the rrsets of the zone in all sections
+func (s *dnssecSigner) signMsg(m *dns.Msg) {
+	m.Answer = s.signSection(m.Answer)
+	m.Ns = s.signSection(m.Ns)
+	m.Extra = s.signSection(m.Extra)
+}
+
+func (s *dnssecSigner) signSection(rrs []dns.RR) []dns.RR {
+	type rrsetKey struct {
+		name  string
+		rtype uint16
+	}
+	keys := []rrsetKey{}
+	rrsets := map[rrsetKey][]dns.RR{}
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		if hdr.Rrtype == dns.TypeOPT || hdr.Rrtype == dns.TypeRRSIG {
+			continue
+		}
+		if !dns.IsSubDomain(s.zone, hdr.Name) {
+			continue
+		}
+		k := rrsetKey{strings.ToLower(hdr.Name), hdr.Rrtype}
+		if _, ok := rrsets[k]; !ok {
+			keys = append(keys, k)
+		}
+		rrsets[k] = append(rrsets[k], rr)
+	}
+	for _, k := range keys {
+		sigs, err := s.signRRSet(rrsets[k])
+		if err != nil {
+			ylog.Errorf("sign %s %s: %v", k.name, dns.TypeToString[k.rtype], err)
+			continue
+		}
+		rrs = append(rrs, sigs...)
+	}
+	return rrs
+}
+
+func (s *dnssecSigner) signRRSet(rrset []dns.RR) ([]dns.RR, error) {
+	s.lock.RLock()
+	signKeys := []*dnssecKey{s.zsk}
+	if rrset[0].Header().Rrtype == dns.TypeDNSKEY {
+		signKeys = s.ksks
+	}
+	s.lock.RUnlock()
+
+	lines := make([]string, len(rrset))
+	for i := range rrset {
+		lines[i] = rrset[i].String()
+	}
+	sort.Strings(lines)
+	content := strings.Join(lines, "\n")
+
+	now := time.Now()
+	sigs := make([]dns.RR, 0, len(signKeys))
+	for _, key := range signKeys {
+		if key == nil {
+			continue
+		}
+		tag := key.dnskey.KeyTag()
+		cacheKey := fmt.Sprintf("%d\n%s", tag, content)
+		s.cacheLock.Lock()
+		cached, ok := s.sigCache[cacheKey]
+		s.cacheLock.Unlock()
+		if ok && now.Sub(cached.signedAt) < sigRefresh {
+			sigs = append(sigs, dns.Copy(cached.sig))
+			continue
+		}
+
+		hdr := rrset[0].Header()
+		sig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Name: hdr.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: hdr.Ttl},
+			Algorithm:  key.dnskey.Algorithm,
+			SignerName: s.zone,
+			KeyTag:     tag,
+			OrigTtl:    hdr.Ttl,
+			Inception:  uint32(now.Add(-sigInceptionSkew).Unix()),
+			Expiration: uint32(now.Add(sigValidity).Unix()),
+		}
+		if err := sig.Sign(key.signer, rrset); err != nil {
+			return nil, err
+		}
+		s.cacheLock.Lock()
+		if len(s.sigCache) >= sigCacheCapacity {
+			s.sigCache = map[string]*cachedSig{}
+		}
+		s.sigCache[cacheKey] = &cachedSig{sig: sig, signedAt: now}
+		s.cacheLock.Unlock()
+		sigs = append(sigs, dns.Copy(sig))
+	}
+	return sigs, nil
+}
+
+func (r *SRegionDNS) shouldSign(state request.Request) bool {
+	signer := r.getSigner()
+	return signer != nil && state.Do() && dns.IsSubDomain(r.PrimaryZone, state.Name()) && signer.ready()
+}
+
+// dnssecRecords answers DNSKEY and NSEC3PARAM queries of the zone apex
+func (r *SRegionDNS) dnssecRecords(state request.Request) ([]dns.RR, error) {
+	signer := r.getSigner()
+	if signer == nil || !signer.ready() || state.Name() != r.PrimaryZone {
+		return nil, errNotFound
+	}
+	if state.QType() == dns.TypeDNSKEY {
+		return signer.dnskeys(), nil
+	}
+	return []dns.RR{signer.nsec3param()}, nil
+}
+
+// backendError writes an error or NODATA response, the denial of existence
+// is signed for DNSSEC aware requests of the zone
+func (r *SRegionDNS) backendError(zone string, rcode int, state request.Request, err error, opt plugin.Options) (int, error) {
+	if !r.shouldSign(state) || rcode == dns.RcodeRefused {
+		return plugin.BackendError(r, zone, rcode, state, err, opt)
+	}
+	m := new(dns.Msg)
+	m.SetRcode(state.Req, rcode)
+	m.Authoritative, m.RecursionAvailable = true, true
+	soa := r.soaRecord(r.Serial(state))
+	m.Ns = []dns.RR{soa}
+	signer := r.getSigner()
+	nameTypes := func(name string) []uint16 {
+		return r.nameTypes(state, name)
+	}
+	switch rcode {
+	case dns.RcodeNameError:
+		m.Ns = append(m.Ns, signer.denyExistence(state.Name(), soa.Minttl, nameTypes)...)
+	case dns.RcodeSuccess:
+		m.Ns = append(m.Ns, signer.denyData(state.Name(), soa.Minttl, nameTypes(state.Name()))...)
+	}
+	signer.signMsg(m)
+
+	state.SizeAndDo(m)
+	m = state.Scrub(m)
+	state.W.WriteMsg(m)
+	return dns.RcodeSuccess, err
+}
+
+// nameTypes returns types of records of name as seen by the requester of
+// state, for type bitmaps of NSEC3
+func (r *SRegionDNS) nameTypes(state request.Request, name string) []uint16 {
+	lookup := func(qtype uint16) []msg.Service {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		services, err := r.Records(request.Request{W: state.W, Req: req, Context: state.Context, Zone: state.Zone}, false)
+		if err != nil {
+			return nil
+		}
+		return services
+	}
+	types := []uint16{}
+	for _, svc := range lookup(dns.TypeA) {
+		ip := net.ParseIP(svc.Host)
+		if ip == nil {
+			// CNAME answers queries of any other type
+			return []uint16{dns.TypeCNAME}
+		}
+		if ip.To4() != nil {
+			types = append(types, dns.TypeA)
+			break
+		}
+	}
+	for _, svc := range lookup(dns.TypeAAAA) {
+		if ip := net.ParseIP(svc.Host); ip != nil && ip.To4() == nil {
+			types = append(types, dns.TypeAAAA)
+			break
+		}
+	}
+	for _, qtype := range []uint16{dns.TypeTXT, dns.TypeMX, dns.TypeSRV} {
+		for _, svc := range lookup(qtype) {
+			// addresses of hosts and guests are returned for any type
+			if net.ParseIP(svc.Host) == nil {
+				types = append(types, qtype)
+				break
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dns
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDedupSlotKeys(t *testing.T) {
+	now := time.Now()
+	keys := []*dnssecKey{
+		{credId: "b", slot: "ksk", credCreatedAt: now},
+		{credId: "a", slot: "ksk", credCreatedAt: now.Add(time.Second)},
+		{credId: "d", slot: "zsk", credCreatedAt: now},
+		{credId: "c", slot: "zsk", credCreatedAt: now},
+		{credId: "e", credCreatedAt: now},
+		{credId: "f", credCreatedAt: now},
+	}
+	kept, dups := dedupSlotKeys(keys)
+	ids := func(keys []*dnssecKey) []string {
+		ret := make([]string, len(keys))
+		for i := range keys {
+			ret[i] = keys[i].credId
+		}
+		return ret
+	}
+	if got, want := ids(kept), []string{"b", "c", "e", "f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("kept: want %v, got %v", want, got)
+	}
+	if got, want := ids(dups), []string{"a", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dups: want %v, got %v", want, got)
+	}
+}
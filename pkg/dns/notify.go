@@ -0,0 +1,102 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	ylog "yunion.io/x/log"
+)
+
+const (
+	defaultNotifyInterval = 30 * time.Second
+
+	notifyRetries = 3
+)
+
+// notifyPeer is a secondary to be notified on zone change
+type notifyPeer struct {
+	Addr string
+	Key  string
+}
+
+func parseNotifyPeer(addr string, key string) (notifyPeer, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return notifyPeer{}, err
+	}
+	peer := notifyPeer{Addr: addr}
+	if len(key) > 0 {
+		peer.Key = dns.Fqdn(strings.ToLower(key))
+	}
+	return peer, nil
+}
+
+// watchZone reloads the zone periodically, the serial is bumped and the
+// secondaries are notified when the content changes
+func (r *SRegionDNS) watchZone() {
+	for {
+		changed, err := r.refreshZone()
+		if err != nil {
+			ylog.Errorf("refresh zone %s: %v", r.PrimaryZone, err)
+		} else if changed {
+			zone := r.getZone()
+			ylog.Infof("zone %s changed, serial %d", r.PrimaryZone, zone.serial)
+			for i := range r.NotifyPeers {
+				go r.sendNotify(r.NotifyPeers[i], zone.serial)
+			}
+		}
+		time.Sleep(r.NotifyInterval)
+	}
+}
+
+func (r *SRegionDNS) sendNotify(peer notifyPeer, serial uint32) {
+	m := new(dns.Msg)
+	m.SetNotify(r.PrimaryZone)
+	m.Answer = []dns.RR{r.soaRecord(serial)}
+
+	c := new(dns.Client)
+	if len(peer.Key) > 0 {
+		key := r.TsigKeys[peer.Key]
+		c.TsigSecret = map[string]string{key.Name: key.Secret}
+		m.SetTsig(key.Name, key.Algorithm, tsigFudge, time.Now().Unix())
+	}
+
+	var err error
+	for i := 0; i < notifyRetries; i++ {
+		var resp *dns.Msg
+		resp, _, err = c.Exchange(m, peer.Addr)
+		if err == nil && resp.Rcode == dns.RcodeSuccess {
+			return
+		}
+		if err == nil {
+			err = errRcode(resp.Rcode)
+		}
+		time.Sleep(time.Duration(i+1) * time.Second)
+	}
+	ylog.Warningf("notify %s of zone %s serial %d: %v", peer.Addr, r.PrimaryZone, serial, err)
+}
+
+type errRcode int
+
+func (e errRcode) Error() string {
+	return "rcode " + dns.RcodeToString[int(e)]
+}
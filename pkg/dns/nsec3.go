@@ -0,0 +1,175 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"encoding/base32"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultNsec3Iterations = 1
+)
+
+var nsec3ApexTypes = []uint16{
+	dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeDNSKEY, dns.TypeNSEC3PARAM,
+}
+
+// denyExistence returns the NSEC3 proof of nonexistence of name.  Records
+// are generated on the fly rather than from the zone, so the "white lies"
+// of RFC 7129 are used: each NSEC3 covers just the hash of the denied name.
+// The closest encloser is the nearest ancestor of name for which types
+// reports any record, or the zone apex
+func (s *dnssecSigner) denyExistence(name string, ttl uint32, types func(name string) []uint16) []dns.RR {
+	name = strings.ToLower(dns.Fqdn(name))
+	labels := dns.SplitDomainName(name)
+	apexLabels := dns.CountLabel(s.zone)
+	if len(labels) <= apexLabels {
+		return nil
+	}
+	var (
+		encloser      = s.zone
+		encloserTypes = nsec3ApexTypes
+		nextCloser    = dns.Fqdn(strings.Join(labels[len(labels)-apexLabels-1:], "."))
+	)
+	for i := 1; i < len(labels)-apexLabels; i++ {
+		ancestor := dns.Fqdn(strings.Join(labels[i:], "."))
+		if t := types(ancestor); len(t) > 0 {
+			encloser = ancestor
+			encloserTypes = nsec3Types(t)
+			nextCloser = dns.Fqdn(strings.Join(labels[i-1:], "."))
+			break
+		}
+	}
+	wildcard := "*." + encloser
+
+	rrs := []dns.RR{}
+	owners := map[string]bool{}
+	for _, rr := range []dns.RR{
+		s.nsec3Match(encloser, encloserTypes, ttl),
+		s.nsec3Cover(nextCloser, ttl),
+		s.nsec3Cover(wildcard, ttl),
+	} {
+		if rr == nil || owners[rr.Header().Name] {
+			continue
+		}
+		owners[rr.Header().Name] = true
+		rrs = append(rrs, rr)
+	}
+	return rrs
+}
+
+// denyData returns the NSEC3 proof that name has no records of types other
+// than types, for NODATA responses
+func (s *dnssecSigner) denyData(name string, ttl uint32, types []uint16) []dns.RR {
+	name = strings.ToLower(dns.Fqdn(name))
+	if name == s.zone {
+		types = nsec3ApexTypes
+	} else {
+		types = nsec3Types(types)
+	}
+	if rr := s.nsec3Match(name, types, ttl); rr != nil {
+		return []dns.RR{rr}
+	}
+	return nil
+}
+
+// nsec3Types returns the sorted type bitmap of a signed name with types
+func nsec3Types(types []uint16) []uint16 {
+	ret := []uint16{dns.TypeRRSIG}
+	for _, t := range types {
+		dup := false
+		for _, t2 := range ret {
+			if t == t2 {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			ret = append(ret, t)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+func (s *dnssecSigner) hashName(name string) string {
+	return dns.HashName(name, dns.SHA1, s.nsec3Iterations, s.nsec3Salt)
+}
+
+func (s *dnssecSigner) nsec3(ownerHash, nextHash string, types []uint16, ttl uint32) *dns.NSEC3 {
+	return &dns.NSEC3{
+		Hdr: dns.RR_Header{
+			Name:   strings.ToLower(ownerHash) + "." + s.zone,
+			Rrtype: dns.TypeNSEC3,
+			Class:  dns.ClassINET,
+			Ttl:    ttl,
+		},
+		Hash:       dns.SHA1,
+		Iterations: s.nsec3Iterations,
+		SaltLength: uint8(len(s.nsec3Salt) / 2),
+		Salt:       s.nsec3Salt,
+		HashLength: 20,
+		NextDomain: nextHash,
+		TypeBitMap: types,
+	}
+}
+
+// nsec3Match returns the NSEC3 with the hash of name as owner
+func (s *dnssecSigner) nsec3Match(name string, types []uint16, ttl uint32) dns.RR {
+	hash := s.hashName(name)
+	next := nsec3HashAdd(hash, 1)
+	if next == "" {
+		return nil
+	}
+	return s.nsec3(hash, next, types, ttl)
+}
+
+// nsec3Cover returns the NSEC3 whose range covers just the hash of name
+func (s *dnssecSigner) nsec3Cover(name string, ttl uint32) dns.RR {
+	hash := s.hashName(name)
+	prev, next := nsec3HashAdd(hash, -1), nsec3HashAdd(hash, 1)
+	if prev == "" || next == "" {
+		return nil
+	}
+	return s.nsec3(prev, next, nil, ttl)
+}
+
+// nsec3HashAdd adds delta to the base32hex encoded hash as a big endian
+// number, wrapping around
+func nsec3HashAdd(hash string, delta int) string {
+	enc := base32.HexEncoding.WithPadding(base32.NoPadding)
+	buf, err := enc.DecodeString(strings.ToUpper(hash))
+	if err != nil || len(buf) == 0 {
+		return ""
+	}
+	carry := delta
+	for i := len(buf) - 1; i >= 0 && carry != 0; i-- {
+		v := int(buf[i]) + carry
+		buf[i] = byte(v & 0xff)
+		switch {
+		case v > 0xff:
+			carry = 1
+		case v < 0:
+			carry = -1
+		default:
+			carry = 0
+		}
+	}
+	return enc.EncodeToString(buf)
+}
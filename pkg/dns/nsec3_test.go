@@ -0,0 +1,150 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dns
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestNsec3HashAdd(t *testing.T) {
+	zeros := strings.Repeat("0", 32)
+	ones := strings.Repeat("V", 32)
+	cases := []struct {
+		Hash  string
+		Delta int
+		Want  string
+	}{
+		{zeros, 1, strings.Repeat("0", 31) + "1"},
+		{zeros[:31] + "V", 1, strings.Repeat("0", 30) + "10"},
+		{strings.Repeat("0", 31) + "1", -1, zeros},
+		{strings.Repeat("0", 30) + "10", -1, zeros[:31] + "V"},
+		{ones, 1, zeros},
+		{zeros, -1, ones},
+		{strings.ToLower(ones), 1, zeros},
+		{"not base32hex", 1, ""},
+		{"", 1, ""},
+	}
+	for _, c := range cases {
+		if got := nsec3HashAdd(c.Hash, c.Delta); got != c.Want {
+			t.Errorf("nsec3HashAdd(%q, %d): want %q, got %q", c.Hash, c.Delta, c.Want, got)
+		}
+	}
+}
+
+func TestDenyExistence(t *testing.T) {
+	s := newDnssecSigner("cloud.local.", defaultZskLifetime, 1, "AABB")
+	noTypes := func(string) []uint16 { return nil }
+	name := "foo.bar.cloud.local."
+	rrs := s.denyExistence(name, 30, noTypes)
+	if len(rrs) != 3 {
+		t.Fatalf("want 3 NSEC3 records, got %d: %v", len(rrs), rrs)
+	}
+	nsec3s := make([]*dns.NSEC3, 0, len(rrs))
+	for _, rr := range rrs {
+		nsec3, ok := rr.(*dns.NSEC3)
+		if !ok {
+			t.Fatalf("want NSEC3, got %s", rr)
+		}
+		if nsec3.Hdr.Ttl != 30 || nsec3.Iterations != 1 || nsec3.Salt != "AABB" {
+			t.Errorf("unexpected NSEC3 parameters: %s", nsec3)
+		}
+		nsec3s = append(nsec3s, nsec3)
+	}
+	// the zone apex is the closest encloser
+	if !nsec3s[0].Match("cloud.local.") {
+		t.Errorf("%s does not match the closest encloser", nsec3s[0])
+	}
+	// the next closer name and the wildcard at the closest encloser are
+	// covered, but not the names next to them
+	for i, covered := range []string{"bar.cloud.local.", "*.cloud.local."} {
+		nsec3 := nsec3s[i+1]
+		if !nsec3.Cover(covered) {
+			t.Errorf("%s does not cover %s", nsec3, covered)
+		}
+		for _, other := range []string{"cloud.local.", "baz.cloud.local.", "foo.cloud.local."} {
+			if nsec3.Cover(other) {
+				t.Errorf("%s covers %s", nsec3, other)
+			}
+		}
+	}
+
+	for _, name := range []string{"cloud.local.", "example.com."} {
+		if rrs := s.denyExistence(name, 30, noTypes); len(rrs) != 0 {
+			t.Errorf("denyExistence(%s): want nothing, got %v", name, rrs)
+		}
+	}
+}
+
+func TestDenyExistenceClosestEncloser(t *testing.T) {
+	s := newDnssecSigner("cloud.local.", defaultZskLifetime, 1, "AABB")
+	types := func(name string) []uint16 {
+		if name == "bar.cloud.local." {
+			return []uint16{dns.TypeAAAA, dns.TypeA}
+		}
+		return nil
+	}
+	rrs := s.denyExistence("a.foo.bar.cloud.local.", 30, types)
+	if len(rrs) != 3 {
+		t.Fatalf("want 3 NSEC3 records, got %d: %v", len(rrs), rrs)
+	}
+	encloser := rrs[0].(*dns.NSEC3)
+	if !encloser.Match("bar.cloud.local.") {
+		t.Errorf("%s does not match the closest encloser bar.cloud.local.", encloser)
+	}
+	if want := []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeRRSIG}; !reflect.DeepEqual(encloser.TypeBitMap, want) {
+		t.Errorf("closest encloser types: want %v, got %v", want, encloser.TypeBitMap)
+	}
+	for i, covered := range []string{"foo.bar.cloud.local.", "*.bar.cloud.local."} {
+		nsec3 := rrs[i+1].(*dns.NSEC3)
+		if !nsec3.Cover(covered) {
+			t.Errorf("%s does not cover %s", nsec3, covered)
+		}
+	}
+	// the existing ancestor is never denied
+	for _, rr := range rrs[1:] {
+		if rr.(*dns.NSEC3).Cover("bar.cloud.local.") {
+			t.Errorf("%s covers existing bar.cloud.local.", rr)
+		}
+	}
+}
+
+func TestDenyData(t *testing.T) {
+	s := newDnssecSigner("cloud.local.", defaultZskLifetime, 1, "AABB")
+	cases := []struct {
+		name  string
+		types []uint16
+		want  []uint16
+	}{
+		{"vm1.cloud.local.", []uint16{dns.TypeA}, []uint16{dns.TypeA, dns.TypeRRSIG}},
+		{"VM2.cloud.local", []uint16{dns.TypeAAAA, dns.TypeA, dns.TypeA}, []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeRRSIG}},
+		{"cloud.local.", nil, nsec3ApexTypes},
+	}
+	for _, c := range cases {
+		rrs := s.denyData(c.name, 30, c.types)
+		if len(rrs) != 1 {
+			t.Fatalf("%s: want 1 NSEC3 record, got %v", c.name, rrs)
+		}
+		nsec3 := rrs[0].(*dns.NSEC3)
+		if !nsec3.Match(dns.Fqdn(c.name)) {
+			t.Errorf("%s does not match %s", nsec3, c.name)
+		}
+		if !reflect.DeepEqual(nsec3.TypeBitMap, c.want) {
+			t.Errorf("%s: want types %v, got %v", c.name, c.want, nsec3.TypeBitMap)
+		}
+	}
+}
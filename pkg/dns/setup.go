@@ -15,7 +15,11 @@
 package dns
 
 import (
+	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -23,6 +27,7 @@ import (
 	"github.com/mholt/caddy"
 	"github.com/miekg/dns"
 
+	ylog "yunion.io/x/log"
 	"yunion.io/x/pkg/util/regutils"
 )
 
@@ -55,8 +60,52 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(PluginName, err)
 	}
 
-	if !rDNS.K8sSkip {
-		go rDNS.initK8s()
+	for _, peer := range rDNS.TransferPeers {
+		if _, ok := rDNS.TsigKeys[peer.Key]; len(peer.Key) > 0 && !ok {
+			return fmt.Errorf("tsig key %q of transfer peer %s not defined", peer.Key, peer.Net)
+		}
+		if len(peer.Key) > 0 && len(rDNS.TransferListen) == 0 {
+			return fmt.Errorf("transfer_listen is required to verify tsig key %q of transfer peer %s", peer.Key, peer.Net)
+		}
+	}
+	if len(rDNS.TransferListen) > 0 {
+		servers := rDNS.newTransferServers()
+		c.OnStartup(func() error {
+			for _, srv := range servers {
+				go func(srv *dns.Server) {
+					if err := srv.ListenAndServe(); err != nil {
+						ylog.Errorf("transfer listener %s/%s: %v", srv.Net, srv.Addr, err)
+					}
+				}(srv)
+			}
+			return nil
+		})
+		c.OnShutdown(func() error {
+			for _, srv := range servers {
+				srv.Shutdown()
+			}
+			return nil
+		})
+	}
+	for _, peer := range rDNS.NotifyPeers {
+		if _, ok := rDNS.TsigKeys[peer.Key]; len(peer.Key) > 0 && !ok {
+			return fmt.Errorf("tsig key %q of notify peer %s not defined", peer.Key, peer.Addr)
+		}
+	}
+	if len(rDNS.TransferPeers) > 0 || len(rDNS.NotifyPeers) > 0 {
+		go rDNS.watchZone()
+	}
+
+	if !rDNS.K8sSkip || rDNS.Dnssec {
+		go func() {
+			rDNS.initAuth()
+			if !rDNS.K8sSkip {
+				rDNS.initK8s()
+			}
+			if rDNS.Dnssec {
+				rDNS.initDnssec()
+			}
+		}()
 	}
 
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
@@ -132,6 +181,90 @@ func parseConfig(c *caddy.Controller) (*SRegionDNS, error) {
 					rDNS.Upstream = u
 				case "k8s_skip":
 					rDNS.K8sSkip = true
+				case "tsig_key":
+					args := c.RemainingArgs()
+					if len(args) != 3 {
+						return nil, c.ArgErr()
+					}
+					key, err := newTsigKey(args[0], args[1], args[2])
+					if err != nil {
+						return nil, c.Err(err.Error())
+					}
+					rDNS.TsigKeys[key.Name] = key
+				case "transfer":
+					args := c.RemainingArgs()
+					if len(args) < 1 || len(args) > 2 {
+						return nil, c.ArgErr()
+					}
+					key := ""
+					if len(args) == 2 {
+						key = args[1]
+					}
+					peer, err := parseTransferPeer(args[0], key)
+					if err != nil {
+						return nil, c.Errf("invalid transfer peer %q: %v", args[0], err)
+					}
+					rDNS.TransferPeers = append(rDNS.TransferPeers, peer)
+				case "transfer_listen":
+					if !c.NextArg() {
+						return nil, c.ArgErr()
+					}
+					rDNS.TransferListen = c.Val()
+				case "notify":
+					args := c.RemainingArgs()
+					if len(args) < 1 || len(args) > 2 {
+						return nil, c.ArgErr()
+					}
+					key := ""
+					if len(args) == 2 {
+						key = args[1]
+					}
+					peer, err := parseNotifyPeer(args[0], key)
+					if err != nil {
+						return nil, c.Errf("invalid notify peer %q: %v", args[0], err)
+					}
+					rDNS.NotifyPeers = append(rDNS.NotifyPeers, peer)
+				case "notify_interval":
+					if !c.NextArg() {
+						return nil, c.ArgErr()
+					}
+					secs, err := strconv.Atoi(c.Val())
+					if err != nil || secs <= 0 {
+						return nil, c.Errf("invalid notify_interval %q", c.Val())
+					}
+					rDNS.NotifyInterval = time.Duration(secs) * time.Second
+				case "dnssec":
+					rDNS.Dnssec = true
+				case "dnssec_zsk_lifetime":
+					if !c.NextArg() {
+						return nil, c.ArgErr()
+					}
+					d, err := time.ParseDuration(c.Val())
+					if err != nil || d < zskPrepublishPeriod+zskRetirePeriod {
+						return nil, c.Errf("invalid dnssec_zsk_lifetime %q", c.Val())
+					}
+					rDNS.ZskLifetime = d
+				case "nsec3_iterations":
+					if !c.NextArg() {
+						return nil, c.ArgErr()
+					}
+					n, err := strconv.ParseUint(c.Val(), 10, 16)
+					if err != nil {
+						return nil, c.Errf("invalid nsec3_iterations %q", c.Val())
+					}
+					rDNS.Nsec3Iterations = uint16(n)
+				case "nsec3_salt":
+					if !c.NextArg() {
+						return nil, c.ArgErr()
+					}
+					salt := c.Val()
+					if salt == "-" {
+						salt = ""
+					}
+					if _, err := hex.DecodeString(salt); err != nil || len(salt) > 510 {
+						return nil, c.Errf("invalid nsec3_salt %q", c.Val())
+					}
+					rDNS.Nsec3Salt = strings.ToUpper(salt)
 				default:
 					if c.Val() != "}" {
 						return nil, c.Errf("unknown property %q", c.Val())
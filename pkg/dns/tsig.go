@@ -0,0 +1,180 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+
+	ylog "yunion.io/x/log"
+)
+
+const tsigFudge = 300
+
+var tsigAlgorithms = map[string]string{
+	"hmac-md5":    dns.HmacMD5,
+	"hmac-sha1":   dns.HmacSHA1,
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+type tsigKey struct {
+	Name      string
+	Algorithm string
+	// base64 encoded
+	Secret string
+}
+
+func newTsigKey(name, algorithm, secret string) (*tsigKey, error) {
+	alg, ok := tsigAlgorithms[strings.TrimSuffix(strings.ToLower(algorithm), ".")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tsig algorithm %q", algorithm)
+	}
+	if _, err := base64.StdEncoding.DecodeString(secret); err != nil {
+		return nil, fmt.Errorf("tsig secret of %s is not base64 encoded", name)
+	}
+	return &tsigKey{
+		Name:      dns.Fqdn(strings.ToLower(name)),
+		Algorithm: alg,
+		Secret:    secret,
+	}, nil
+}
+
+// transferPeer allows the peers in Net to transfer the zone, the transfer
+// must be signed by Key if it is set
+type transferPeer struct {
+	Net *net.IPNet
+	Key string
+}
+
+func parseTransferPeer(cidr string, key string) (transferPeer, error) {
+	if !strings.Contains(cidr, "/") {
+		if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+			cidr += "/32"
+		} else {
+			cidr += "/128"
+		}
+	}
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return transferPeer{}, err
+	}
+	peer := transferPeer{Net: ipnet}
+	if len(key) > 0 {
+		peer.Key = dns.Fqdn(strings.ToLower(key))
+	}
+	return peer, nil
+}
+
+var errTsigUnverifiable = errors.New("tsig of requests can only be verified on transfer_listen")
+
+// verifyTsig returns the key signing the request, or nil if the request
+// is not signed.  The MAC has to be verified on the bytes received, which
+// only the transfer listener keeps: it is handed the secrets and reports the
+// result by w.  coredns does neither, signed requests through it are
+// rejected
+func (r *SRegionDNS) verifyTsig(w dns.ResponseWriter, req *dns.Msg, wireVerified bool) (*tsigKey, error) {
+	t := req.IsTsig()
+	if t == nil {
+		return nil, nil
+	}
+	key, ok := r.TsigKeys[strings.ToLower(t.Hdr.Name)]
+	if !ok {
+		return nil, dns.ErrSecret
+	}
+	if !strings.EqualFold(t.Algorithm, key.Algorithm) {
+		return nil, dns.ErrKeyAlg
+	}
+	if !wireVerified {
+		return nil, errTsigUnverifiable
+	}
+	if err := w.TsigStatus(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// newTransferServers returns the servers of the transfer listener, which
+// answer AXFR and IXFR only
+func (r *SRegionDNS) newTransferServers() []*dns.Server {
+	secrets := map[string]string{}
+	for name, key := range r.TsigKeys {
+		secrets[name] = key.Secret
+	}
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		state := request.Request{W: w, Req: req, Context: context.Background()}
+		switch state.QType() {
+		case dns.TypeAXFR, dns.TypeIXFR:
+			r.transfer(state, true)
+		default:
+			m := new(dns.Msg)
+			m.SetRcode(req, dns.RcodeRefused)
+			w.WriteMsg(m)
+		}
+	})
+	servers := make([]*dns.Server, 0, 2)
+	for _, network := range []string{"tcp", "udp"} {
+		servers = append(servers, &dns.Server{
+			Addr:       r.TransferListen,
+			Net:        network,
+			TsigSecret: secrets,
+			Handler:    handler,
+		})
+	}
+	return servers
+}
+
+// writeTsigMsg writes m signed by key, the MAC is returned for signing
+// the following messages of a multi-message response
+func writeTsigMsg(w dns.ResponseWriter, m *dns.Msg, key *tsigKey, requestMAC string, timersOnly bool) (string, error) {
+	if key == nil {
+		return "", w.WriteMsg(m)
+	}
+	m.SetTsig(key.Name, key.Algorithm, tsigFudge, time.Now().Unix())
+	buf, mac, err := dns.TsigGenerate(m, key.Secret, requestMAC, timersOnly)
+	if err != nil {
+		return "", err
+	}
+	_, err = w.Write(buf)
+	return mac, err
+}
+
+// checkTransferAcl returns the key to sign the response and the rcode to
+// refuse the request with
+func (r *SRegionDNS) checkTransferAcl(w dns.ResponseWriter, req *dns.Msg, ip net.IP, wireVerified bool) (*tsigKey, int) {
+	key, err := r.verifyTsig(w, req, wireVerified)
+	if err != nil {
+		ylog.Warningf("zone transfer from %s: tsig verify failed: %v", ip, err)
+		return nil, dns.RcodeNotAuth
+	}
+	for _, peer := range r.TransferPeers {
+		if !peer.Net.Contains(ip) {
+			continue
+		}
+		if len(peer.Key) == 0 || (key != nil && key.Name == peer.Key) {
+			return key, dns.RcodeSuccess
+		}
+	}
+	ylog.Warningf("zone transfer from %s refused", ip)
+	return key, dns.RcodeRefused
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	testTsigKey    = "xfr-key."
+	testTsigSecret = "c2VjcmV0LXNlY3JldC1zZWNyZXQ="
+)
+
+func newTestTransferDNS(t *testing.T) *SRegionDNS {
+	r := New()
+	r.PrimaryZone = "cloud.local."
+	key, err := newTsigKey(testTsigKey, "hmac-sha256", testTsigSecret)
+	if err != nil {
+		t.Fatalf("newTsigKey: %v", err)
+	}
+	r.TsigKeys[key.Name] = key
+	peer, err := parseTransferPeer("127.0.0.1", testTsigKey)
+	if err != nil {
+		t.Fatalf("parseTransferPeer: %v", err)
+	}
+	r.TransferPeers = []transferPeer{peer}
+	r.TransferListen = "127.0.0.1:0"
+	rr, _ := dns.NewRR("www.cloud.local. 30 IN A 10.0.0.1")
+	r.zone = &zoneSnapshot{serial: 10, records: []dns.RR{rr}}
+	return r
+}
+
+func startTestTransferServer(t *testing.T, r *SRegionDNS) (*dns.Server, string) {
+	srv := r.newTransferServers()[0]
+	started := make(chan struct{})
+	srv.NotifyStartedFunc = func() { close(started) }
+	go srv.ListenAndServe()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("transfer server not started")
+	}
+	return srv, srv.Listener.Addr().String()
+}
+
+func transferZone(addr, secret string) ([]dns.RR, error) {
+	m := new(dns.Msg)
+	m.SetAxfr("cloud.local.")
+	m.SetTsig(testTsigKey, dns.HmacSHA256, tsigFudge, time.Now().Unix())
+	tr := &dns.Transfer{TsigSecret: map[string]string{testTsigKey: secret}}
+	envs, err := tr.In(m, addr)
+	if err != nil {
+		return nil, err
+	}
+	rrs := []dns.RR{}
+	for env := range envs {
+		if env.Error != nil {
+			return nil, env.Error
+		}
+		rrs = append(rrs, env.RR...)
+	}
+	return rrs, nil
+}
+
+func TestTransferTsig(t *testing.T) {
+	r := newTestTransferDNS(t)
+	srv, addr := startTestTransferServer(t, r)
+	defer srv.Shutdown()
+
+	rrs, err := transferZone(addr, testTsigSecret)
+	if err != nil {
+		t.Fatalf("signed transfer: %v", err)
+	}
+	if len(rrs) != 3 || rrs[0].Header().Rrtype != dns.TypeSOA || rrs[2].Header().Rrtype != dns.TypeSOA {
+		t.Errorf("want SOA, A, SOA, got %v", rrs)
+	}
+
+	if _, err := transferZone(addr, "d3Jvbmctc2VjcmV0"); err == nil {
+		t.Errorf("transfer signed by wrong secret is not refused")
+	}
+}
+
+func TestVerifyTsigUnverifiable(t *testing.T) {
+	r := newTestTransferDNS(t)
+	m := new(dns.Msg)
+	m.SetAxfr("cloud.local.")
+	m.SetTsig(testTsigKey, dns.HmacSHA256, tsigFudge, time.Now().Unix())
+	// a signed request repacked by coredns can't be verified
+	if _, err := r.verifyTsig(nil, m, false); err != errTsigUnverifiable {
+		t.Errorf("want %v, got %v", errTsigUnverifiable, err)
+	}
+
+	m = new(dns.Msg)
+	m.SetAxfr("cloud.local.")
+	if key, err := r.verifyTsig(nil, m, false); key != nil || err != nil {
+		t.Errorf("unsigned request: want no key and no error, got %v, %v", key, err)
+	}
+}
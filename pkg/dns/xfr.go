@@ -16,14 +16,43 @@ package dns
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/request"
 	"github.com/miekg/dns"
+
+	ylog "yunion.io/x/log"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
 )
 
+const (
+	// number of records in one message of zone transfer
+	xfrEnvelopeSize = 100
+)
+
+// zoneSnapshot is the content of the primary zone for transfer, the serial
+// is bumped whenever the content changes
+type zoneSnapshot struct {
+	serial  uint32
+	digest  string
+	records []dns.RR
+}
+
 // Serial implements the Transferer interface
 func (r *SRegionDNS) Serial(state request.Request) uint32 {
+	if zone := r.getZone(); zone != nil {
+		return zone.serial
+	}
 	return uint32(time.Now().Unix())
 }
 
@@ -32,7 +61,289 @@ func (r *SRegionDNS) MinTTL(state request.Request) uint32 {
 	return 30
 }
 
-// Transferer implements the Transferer interface
+func (r *SRegionDNS) getZone() *zoneSnapshot {
+	r.zoneLock.RLock()
+	defer r.zoneLock.RUnlock()
+	return r.zone
+}
+
+func (r *SRegionDNS) soaRecord(serial uint32) *dns.SOA {
+	return &dns.SOA{
+		Hdr: dns.RR_Header{
+			Name:   r.PrimaryZone,
+			Rrtype: dns.TypeSOA,
+			Class:  dns.ClassINET,
+			Ttl:    30,
+		},
+		Mbox:    "hostmaster." + r.PrimaryZone,
+		Ns:      defaultNSName + r.PrimaryZone,
+		Serial:  serial,
+		Refresh: 7200,
+		Retry:   1800,
+		Expire:  86400,
+		Minttl:  30,
+	}
+}
+
+// refreshZone reloads the zone content, returns true if it changed since
+// last load
+func (r *SRegionDNS) refreshZone() (bool, error) {
+	records, err := r.loadZoneRecords()
+	if err != nil {
+		return false, err
+	}
+	lines := make([]string, len(records))
+	for i := range records {
+		lines[i] = records[i].String()
+	}
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(lines, "\n"))))
+
+	r.zoneLock.Lock()
+	defer r.zoneLock.Unlock()
+	if r.zone != nil && r.zone.digest == digest {
+		return false, nil
+	}
+	serial := uint32(time.Now().Unix())
+	changed := r.zone != nil
+	if changed && !serialLess(r.zone.serial, serial) {
+		serial = r.zone.serial + 1
+	}
+	r.zone = &zoneSnapshot{
+		serial:  serial,
+		digest:  digest,
+		records: records,
+	}
+	return changed, nil
+}
+
+func (r *SRegionDNS) loadZoneRecords() ([]dns.RR, error) {
+	records := []dns.RR{}
+	addA := func(name string, ip string, ttl uint32) {
+		fqdn := dns.Fqdn(strings.ToLower(name))
+		if _, ok := dns.IsDomainName(fqdn); !ok {
+			return
+		}
+		if addr := net.ParseIP(ip); addr != nil {
+			hdr := dns.RR_Header{Name: fqdn, Class: dns.ClassINET, Ttl: ttl}
+			if addr.To4() != nil {
+				hdr.Rrtype = dns.TypeA
+				records = append(records, &dns.A{Hdr: hdr, A: addr})
+			} else {
+				hdr.Rrtype = dns.TypeAAAA
+				records = append(records, &dns.AAAA{Hdr: hdr, AAAA: addr})
+			}
+		}
+	}
+
+	// hosts
+	hosts := models.HostManager.Query().SubQuery()
+	q := hosts.Query(hosts.Field("name"), hosts.Field("access_ip")).
+		Filter(sqlchemy.IsNotEmpty(hosts.Field("access_ip")))
+	if err := scanNameIps(q, func(name, ip string) {
+		addA(name+"."+r.PrimaryZone, ip, defaultTTL)
+	}); err != nil {
+		return nil, err
+	}
+
+	// guests
+	guestnics := models.GuestnetworkManager.Query().SubQuery()
+	guests := models.GuestManager.Query().SubQuery()
+	networks := models.NetworkManager.Query().SubQuery()
+	q = guestnics.Query(guests.Field("name"), guestnics.Field("ip_addr")).
+		Join(guests, sqlchemy.AND(
+			sqlchemy.Equals(guests.Field("id"), guestnics.Field("guest_id")),
+			sqlchemy.OR(sqlchemy.IsNull(guests.Field("pending_deleted")),
+				sqlchemy.IsFalse(guests.Field("pending_deleted"))))).
+		Join(networks, sqlchemy.Equals(networks.Field("id"), guestnics.Field("network_id"))).
+		Filter(sqlchemy.IsNotEmpty(guestnics.Field("ip_addr"))).
+		Filter(sqlchemy.IsNotNull(networks.Field("guest_gateway")))
+	if err := scanNameIps(q, func(name, ip string) {
+		addA(name+"."+r.PrimaryZone, ip, defaultTTL)
+	}); err != nil {
+		return nil, err
+	}
+
+	// public dns records under the zone, project records are not exposed
+	recs := make([]models.SDnsRecord, 0)
+	rq := models.DnsRecordManager.Query().IsTrue("enabled").IsTrue("is_public")
+	if err := db.FetchModelObjects(models.DnsRecordManager, rq, &recs); err != nil {
+		return nil, err
+	}
+	for i := range recs {
+		fqdn := dns.Fqdn(strings.ToLower(recs[i].Name))
+		if !dns.IsSubDomain(r.PrimaryZone, fqdn) {
+			continue
+		}
+		ttl := uint32(recs[i].Ttl)
+		if ttl == 0 {
+			ttl = defaultTTL
+		}
+		for _, rec := range recs[i].GetInfo() {
+			rr, err := dnsRecordToRR(fqdn, ttl, rec)
+			if err != nil {
+				ylog.Warningf("dns record %s: %v", recs[i].Name, err)
+				continue
+			}
+			records = append(records, rr)
+		}
+	}
+
+	// dedup and keep a stable order for digest
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].String() < records[j].String()
+	})
+	ret := make([]dns.RR, 0, len(records))
+	for i := range records {
+		if i > 0 && dns.IsDuplicate(records[i], records[i-1]) {
+			continue
+		}
+		ret = append(ret, records[i])
+	}
+	return ret, nil
+}
+
+func scanNameIps(q *sqlchemy.SQuery, f func(name, ip string)) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, ip string
+		if err := rows.Scan(&name, &ip); err != nil {
+			return err
+		}
+		f(name, ip)
+	}
+	return nil
+}
+
+// dnsRecordToRR converts a record of dnsrecords, e.g. "A:10.0.0.1",
+// "SRV:host:port:weight:priority"
+func dnsRecordToRR(name string, ttl uint32, rec string) (dns.RR, error) {
+	parts := strings.SplitN(rec, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid record %q", rec)
+	}
+	typ, val := parts[0], parts[1]
+	if typ == "SRV" {
+		segs := strings.Split(val, ":")
+		if len(segs) < 2 {
+			return nil, fmt.Errorf("invalid SRV record %q", rec)
+		}
+		port, err := strconv.Atoi(segs[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid SRV port %q", rec)
+		}
+		weight, priority := 100, 0
+		if len(segs) >= 3 {
+			weight, _ = strconv.Atoi(segs[2])
+		}
+		if len(segs) >= 4 {
+			priority, _ = strconv.Atoi(segs[3])
+		}
+		return &dns.SRV{
+			Hdr:      dns.RR_Header{Name: name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: ttl},
+			Priority: uint16(priority),
+			Weight:   uint16(weight),
+			Port:     uint16(port),
+			Target:   dns.Fqdn(segs[0]),
+		}, nil
+	}
+	if typ == "CNAME" || typ == "PTR" {
+		val = dns.Fqdn(val)
+	}
+	return dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, ttl, typ, val))
+}
+
+// serialLess compares serial numbers as defined in RFC 1982
+func serialLess(a, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+// Transfer implements the Transferer interface
 func (r *SRegionDNS) Transfer(ctx context.Context, state request.Request) (int, error) {
-	return dns.RcodeServerFailure, nil
+	return r.transfer(state, false)
+}
+
+// transfer answers IXFR in the AXFR form as the history of the zone is not
+// kept.  wireVerified tells the request comes from the transfer listener,
+// which verifies TSIG
+func (r *SRegionDNS) transfer(state request.Request, wireVerified bool) (int, error) {
+	if state.Name() != r.PrimaryZone || len(r.TransferPeers) == 0 {
+		return r.backendError(state.Name(), dns.RcodeRefused, state, nil, plugin.Options{})
+	}
+	key, rcode := r.checkTransferAcl(state.W, state.Req, net.ParseIP(state.IP()), wireVerified)
+	if rcode != dns.RcodeSuccess {
+		m := new(dns.Msg)
+		m.SetRcode(state.Req, rcode)
+		if _, err := writeTsigMsg(state.W, m, key, requestMAC(state.Req), false); err != nil {
+			return dns.RcodeServerFailure, err
+		}
+		return dns.RcodeSuccess, nil
+	}
+
+	zone := r.getZone()
+	if zone == nil {
+		if _, err := r.refreshZone(); err != nil {
+			ylog.Errorf("load zone %s: %v", r.PrimaryZone, err)
+			return dns.RcodeServerFailure, err
+		}
+		zone = r.getZone()
+	}
+	soa := r.soaRecord(zone.serial)
+
+	// IXFR carries the serial of the secondary in authority section,
+	// a single SOA tells it is up to date
+	upToDate := false
+	if state.QType() == dns.TypeIXFR && len(state.Req.Ns) > 0 {
+		if clientSoa, ok := state.Req.Ns[0].(*dns.SOA); ok && !serialLess(clientSoa.Serial, zone.serial) {
+			upToDate = true
+		}
+	}
+	if upToDate || (state.QType() == dns.TypeIXFR && state.Proto() == "udp") {
+		m := new(dns.Msg)
+		m.SetReply(state.Req)
+		m.Authoritative = true
+		m.Answer = []dns.RR{soa}
+		if _, err := writeTsigMsg(state.W, m, key, requestMAC(state.Req), false); err != nil {
+			return dns.RcodeServerFailure, err
+		}
+		return dns.RcodeSuccess, nil
+	}
+	if state.Proto() != "tcp" {
+		return r.backendError(state.Name(), dns.RcodeRefused, state, nil, plugin.Options{})
+	}
+
+	records := make([]dns.RR, 0, len(zone.records)+2)
+	records = append(records, soa)
+	records = append(records, zone.records...)
+	records = append(records, soa)
+
+	mac := requestMAC(state.Req)
+	for i := 0; i < len(records); i += xfrEnvelopeSize {
+		end := i + xfrEnvelopeSize
+		if end > len(records) {
+			end = len(records)
+		}
+		m := new(dns.Msg)
+		m.SetReply(state.Req)
+		m.Authoritative = true
+		m.Answer = records[i:end]
+		var err error
+		mac, err = writeTsigMsg(state.W, m, key, mac, i > 0)
+		if err != nil {
+			ylog.Errorf("zone transfer to %s: %v", state.IP(), err)
+			return dns.RcodeServerFailure, err
+		}
+	}
+	ylog.Infof("zone %s serial %d transferred to %s", r.PrimaryZone, zone.serial, state.IP())
+	return dns.RcodeSuccess, nil
+}
+
+func requestMAC(req *dns.Msg) string {
+	if t := req.IsTsig(); t != nil {
+		return t.MAC
+	}
+	return ""
 }
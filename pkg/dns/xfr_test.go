@@ -0,0 +1,39 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dns
+
+import (
+	"testing"
+)
+
+func TestSerialLess(t *testing.T) {
+	cases := []struct {
+		A    uint32
+		B    uint32
+		Want bool
+	}{
+		{1, 2, true},
+		{2, 1, false},
+		{5, 5, false},
+		{0xffffffff, 0, true},
+		{0, 0xffffffff, false},
+		{0xfffffff0, 0x10, true},
+		{0x10, 0xfffffff0, false},
+	}
+	for _, c := range cases {
+		if got := serialLess(c.A, c.B); got != c.Want {
+			t.Errorf("serialLess(%d, %d): want %v, got %v", c.A, c.B, c.Want, got)
+		}
+	}
+}
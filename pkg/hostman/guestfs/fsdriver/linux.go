@@ -682,6 +682,44 @@ func (d *sDebianLikeRootFs) deployNetplanConfigFile(rootFs IDiskPartition, nics
 	return nil
 }
 
+func getNicDns6(nicDesc *types.SServerNic) []string {
+	dnslist := make([]string, 0)
+	for _, dns := range strings.Split(nicDesc.Dns6, ",") {
+		if dns = strings.TrimSpace(dns); len(dns) > 0 {
+			dnslist = append(dnslist, dns)
+		}
+	}
+	return dnslist
+}
+
+// getNicIp6ConfigCmds returns the inet6 stanza of /etc/network/interfaces,
+// default route is only set on the main nic
+func getNicIp6ConfigCmds(nicDesc *types.SServerNic, isMain bool) string {
+	var cmds strings.Builder
+	switch nicDesc.Ip6Mode {
+	case api.NETWORK_IP6_MODE_SLAAC:
+		cmds.WriteString(fmt.Sprintf("iface %s inet6 auto\n", nicDesc.Name))
+	case api.NETWORK_IP6_MODE_DHCPV6:
+		cmds.WriteString(fmt.Sprintf("iface %s inet6 dhcp\n", nicDesc.Name))
+	default:
+		cmds.WriteString(fmt.Sprintf("iface %s inet6 static\n", nicDesc.Name))
+		cmds.WriteString(fmt.Sprintf("    address %s\n", nicDesc.Ip6))
+		cmds.WriteString(fmt.Sprintf("    netmask %d\n", nicDesc.Masklen6))
+	}
+	if len(nicDesc.Gateway6) > 0 && isMain {
+		if nicDesc.Ip6Mode == api.NETWORK_IP6_MODE_SLAAC || nicDesc.Ip6Mode == api.NETWORK_IP6_MODE_DHCPV6 {
+			cmds.WriteString(fmt.Sprintf("    up ip -6 route replace default via %s dev %s || true\n", nicDesc.Gateway6, nicDesc.Name))
+		} else {
+			cmds.WriteString(fmt.Sprintf("    gateway %s\n", nicDesc.Gateway6))
+		}
+	}
+	if dnslist := getNicDns6(nicDesc); len(dnslist) > 0 {
+		cmds.WriteString(fmt.Sprintf("    dns-nameservers %s\n", strings.Join(dnslist, " ")))
+	}
+	cmds.WriteString("\n")
+	return cmds.String()
+}
+
 func (d *sDebianLikeRootFs) DeployNetworkingScripts(rootFs IDiskPartition, nics []*types.SServerNic) error {
 	if err := d.sLinuxRootFs.DeployNetworkingScripts(rootFs, nics); err != nil {
 		return err
@@ -759,12 +797,18 @@ func (d *sDebianLikeRootFs) DeployNetworkingScripts(rootFs IDiskPartition, nics
 				cmds.WriteString(getNicTeamingConfigCmds(nicDesc.TeamingSlaves))
 			}
 			cmds.WriteString("\n")
+			if len(nicDesc.Ip6) > 0 {
+				cmds.WriteString(getNicIp6ConfigCmds(nicDesc, nicDesc.Ip == mainIp))
+			}
 		} else {
 			cmds.WriteString(fmt.Sprintf("iface %s inet dhcp\n", nicDesc.Name))
 			if len(nicDesc.TeamingSlaves) > 0 {
 				cmds.WriteString(getNicTeamingConfigCmds(nicDesc.TeamingSlaves))
 			}
 			cmds.WriteString("\n")
+			if len(nicDesc.Ip6) > 0 {
+				cmds.WriteString(getNicIp6ConfigCmds(nicDesc, nicDesc.Ip == mainIp))
+			}
 		}
 	}
 
@@ -1077,6 +1121,31 @@ func (r *sRedhatLikeRootFs) enableBondingModule(rootFs IDiskPartition, bondNics
 	return rootFs.FilePutContents("/etc/modprobe.d/bonding.conf", content.String(), false, false)
 }
 
+// getNicIp6Ifcfg returns the ipv6 settings of ifcfg-* network scripts
+func getNicIp6Ifcfg(nicDesc *types.SServerNic, isMain bool) string {
+	var cmds strings.Builder
+	cmds.WriteString("IPV6INIT=yes\n")
+	switch nicDesc.Ip6Mode {
+	case api.NETWORK_IP6_MODE_SLAAC:
+		cmds.WriteString("IPV6_AUTOCONF=yes\n")
+	case api.NETWORK_IP6_MODE_DHCPV6:
+		cmds.WriteString("IPV6_AUTOCONF=no\n")
+		cmds.WriteString("DHCPV6C=yes\n")
+	default:
+		cmds.WriteString("IPV6_AUTOCONF=no\n")
+		cmds.WriteString(fmt.Sprintf("IPV6ADDR=%s/%d\n", nicDesc.Ip6, nicDesc.Masklen6))
+	}
+	if len(nicDesc.Gateway6) > 0 && isMain {
+		cmds.WriteString(fmt.Sprintf("IPV6_DEFAULTGW=%s\n", nicDesc.Gateway6))
+	}
+	dnslist := getNicDns6(nicDesc)
+	for i := 0; i < len(dnslist); i++ {
+		// numbered after ipv4 dns servers
+		cmds.WriteString(fmt.Sprintf("DNS%d=%s\n", len(netutils2.GetNicDns(nicDesc))+i+1, dnslist[i]))
+	}
+	return cmds.String()
+}
+
 func (r *sRedhatLikeRootFs) deployNetworkingScripts(rootFs IDiskPartition, nics []*types.SServerNic, relInfo *deployapi.ReleaseInfo, forceEnableNM bool) error {
 	if err := r.sLinuxRootFs.DeployNetworkingScripts(rootFs, nics); err != nil {
 		return err
@@ -1194,6 +1263,9 @@ func (r *sRedhatLikeRootFs) deployNetworkingScripts(rootFs IDiskPartition, nics
 		} else {
 			cmds.WriteString("BOOTPROTO=dhcp\n")
 		}
+		if len(nicDesc.Ip6) > 0 && nicDesc.TeamingMaster == nil && !nicDesc.Virtual {
+			cmds.WriteString(getNicIp6Ifcfg(nicDesc, nicDesc.Ip == mainIp))
+		}
 		var fn = fmt.Sprintf("/etc/sysconfig/network-scripts/ifcfg-%s", nicDesc.Name)
 		log.Debugf("%s: %s", fn, cmds.String())
 		if err := rootFs.FilePutContents(fn, cmds.String(), false, false); err != nil {
@@ -121,5 +121,10 @@ func getNetplanEthernetConfig(nic *types.SServerNic, isBond bool) *netplan.Ether
 		nicConf = netplan.NewDHCP4EthernetConfig()
 	}
 
+	if len(nic.Ip6) > 0 && !nic.Virtual {
+		addr := fmt.Sprintf("%s/%d", nic.Ip6, nic.Masklen6)
+		nicConf.SetIPv6(nic.Ip6Mode, addr, nic.Gateway6, getNicDns6(nic))
+	}
+
 	return nicConf
 }
@@ -0,0 +1,45 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesthandlers
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	hostapis "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/hostman/guestman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+func guestFsFreeze(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	req := &hostapis.GuestFsFreezeRequest{}
+	if err := body.Unmarshal(req); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal: %v", err)
+	}
+	resp, err := guestman.GetGuestManager().GuestFsFreeze(ctx, sid, req)
+	if err != nil {
+		return nil, err
+	}
+	return jsonutils.Marshal(resp), nil
+}
+
+func guestFsThaw(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	resp, err := guestman.GetGuestManager().GuestFsThaw(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	return jsonutils.Marshal(resp), nil
+}
@@ -84,6 +84,8 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			"list-forward":         guestListForward,
 			"close-forward":        guestCloseForward,
 			"storage-clone-disk":   guestStorageCloneDisk,
+			"fs-freeze":            guestFsFreeze,
+			"fs-thaw":              guestFsThaw,
 		} {
 			app.AddHandler("POST",
 				fmt.Sprintf("%s/%s/<sid>/%s", prefix, keyWord, action),
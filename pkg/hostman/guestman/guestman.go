@@ -296,6 +296,9 @@ func (m *SGuestManager) LoadServer(sid string) {
 		log.Errorf("On load server error: %s", err)
 		return
 	}
+	if err := secgroupIpsets.Sync(sid, guest.Desc); err != nil {
+		log.Errorf("On load server %s sync secgroup ipsets error: %s", sid, err)
+	}
 
 	if jsonutils.QueryBoolean(guest.Desc, "need_sync_stream_disks", false) {
 		go guest.sendStreamDisksComplete(context.Background())
@@ -337,6 +340,25 @@ func (m *SGuestManager) GetGuestNicDesc(mac, ip, port, bridge string, isCandidat
 	return guestDesc, nic
 }
 
+// GetGuestNicDescsOnBridge returns nics of loaded guests attached to bridge
+func (m *SGuestManager) GetGuestNicDescsOnBridge(bridge string) []jsonutils.JSONObject {
+	ret := []jsonutils.JSONObject{}
+	m.Servers.Range(func(k interface{}, v interface{}) bool {
+		guest := v.(*SKVMGuestInstance)
+		if !guest.IsLoaded() {
+			return true
+		}
+		nics, _ := guest.Desc.GetArray("nics")
+		for _, nic := range nics {
+			if nicBridge, _ := nic.GetString("bridge"); nicBridge == bridge {
+				ret = append(ret, nic)
+			}
+		}
+		return true
+	})
+	return ret
+}
+
 func (m *SGuestManager) getGuestNicDescInCandidate(mac, ip, port, bridge string) (jsonutils.JSONObject, jsonutils.JSONObject) {
 	for _, guest := range m.CandidateServers {
 		if guest.IsLoaded() {
@@ -680,6 +702,7 @@ func (m *SGuestManager) getStatus(sid string) string {
 func (m *SGuestManager) Delete(sid string) (*SKVMGuestInstance, error) {
 	if guest, ok := m.GetServer(sid); ok {
 		m.Servers.Delete(sid)
+		secgroupIpsets.Release(sid)
 		// 这里应该不需要append到deleted servers
 		// 据观察 deleted servers 目的是为了给ofp_delegate使用，ofp已经不用了
 		return guest, nil
@@ -958,6 +981,8 @@ func (m *SGuestManager) Resume(ctx context.Context, sid string, isLiveMigrate bo
 			guest.StartPresendArp()
 		}
 		resumeTask.Start()
+		// both after migration on destination and after failed migration on source
+		go guest.activateSLVMDisksExclusive()
 	}
 	if guest.Monitor == nil {
 		guest.StartMonitor(ctx, cb)
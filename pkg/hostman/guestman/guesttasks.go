@@ -219,6 +219,54 @@ func (d *SGuestDiskSyncTask) OnChangeCdromContentSucc(results string) {
 	d.syncDisksConf()
 }
 
+/**
+ *  GuestConfigDriveSyncTask
+**/
+
+type SGuestConfigDriveSyncTask struct {
+	guest    *SKVMGuestInstance
+	callback func(...error)
+}
+
+func NewGuestConfigDriveSyncTask(guest *SKVMGuestInstance) *SGuestConfigDriveSyncTask {
+	return &SGuestConfigDriveSyncTask{guest, nil}
+}
+
+func (t *SGuestConfigDriveSyncTask) Start(callback func(...error)) {
+	t.callback = callback
+	t.guest.Monitor.GetBlocks(t.onGetBlockInfo)
+}
+
+func (t *SGuestConfigDriveSyncTask) onGetBlockInfo(blocks []monitor.QemuBlock) {
+	for _, r := range blocks {
+		if r.Device == "config-drive" {
+			// regenerate the ISO from the synced desc, then reopen the media
+			isoPath, err := t.guest.prepareConfigDrive()
+			if err != nil {
+				log.Errorf("guest %s prepare config drive: %v", t.guest.GetName(), err)
+				t.callback(err)
+				return
+			}
+			if len(isoPath) == 0 {
+				// config drive disabled, the device is removed on next start
+				t.guest.Monitor.EjectCdrom(r.Device, t.onChangeSucc)
+				return
+			}
+			t.guest.Monitor.ChangeCdrom(r.Device, isoPath, t.onChangeSucc)
+			return
+		}
+	}
+	log.Infof("guest %s has no config drive attached, it will be attached on next start", t.guest.GetName())
+	t.callback()
+}
+
+func (t *SGuestConfigDriveSyncTask) onChangeSucc(results string) {
+	if len(results) > 0 {
+		log.Errorf("guest %s change config drive: %s", t.guest.GetName(), results)
+	}
+	t.callback()
+}
+
 func (d *SGuestDiskSyncTask) removeDisk(disk jsonutils.JSONObject) {
 	index, _ := disk.Int("index")
 	devId := fmt.Sprintf("drive_%d", index)
@@ -23,6 +23,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -58,6 +59,9 @@ const (
 	LIVE_MIGRATE_PORT_BASE        = 4396
 	BUILT_IN_NBD_SERVER_PORT_BASE = 7777
 	MAX_TRY                       = 3
+
+	// 10 minutes for the other host to release the shared lvm disks
+	SLVM_EXCLUSIVE_MAX_TRY = 60
 )
 
 type SKVMGuestInstance struct {
@@ -74,6 +78,9 @@ type SKVMGuestInstance struct {
 	migrateTask *SGuestLiveMigrateTask
 	stopping    bool
 	syncMeta    *jsonutils.JSONDict
+
+	fsFreezeLock sync.Mutex
+	fsThawTimer  *time.Timer
 }
 
 func NewKVMGuestInstance(id string, manager *SGuestManager) *SKVMGuestInstance {
@@ -903,6 +910,9 @@ func (s *SKVMGuestInstance) SaveDesc(desc jsonutils.JSONObject) error {
 	if err := fileutils2.FilePutContents(s.GetDescFilePath(), desc.String(), false); err != nil {
 		log.Errorln(err)
 	}
+	if err := secgroupIpsets.Sync(s.Id, s.Desc); err != nil {
+		return errors.Wrapf(err, "sync secgroup ipsets of %s", s.GetName())
+	}
 	return nil
 }
 
@@ -1027,6 +1037,13 @@ func (s *SKVMGuestInstance) delTmpDisks(ctx context.Context, migrated bool) erro
 		if disk.Contains("path") {
 			diskPath, _ := disk.GetString("path")
 			d, _ := storageman.GetManager().GetDiskByPath(diskPath)
+			if sd, ok := d.(*storageman.SSLVMDisk); ok && migrated {
+				// the guest runs on another host now, release the lock
+				if err := sd.Deactivate(); err != nil {
+					log.Errorf("deactivate disk %s: %v", sd.GetId(), err)
+				}
+				continue
+			}
 			if d != nil && d.GetType() == api.STORAGE_LOCAL && migrated {
 				skipRecycle := true
 				if err := d.DeleteAllSnapshot(skipRecycle); err != nil {
@@ -1413,6 +1430,12 @@ func (s *SKVMGuestInstance) SyncConfig(ctx context.Context, desc jsonutils.JSONO
 		tasks = append(tasks, task)
 	}
 
+	if s.isConfigDriveEnabled() {
+		task := NewGuestConfigDriveSyncTask(s)
+		runTaskNames = append(runTaskNames, jsonutils.NewString("config_drive_sync"))
+		tasks = append(tasks, task)
+	}
+
 	NewGuestSyncConfigTaskExecutor(ctx, s, tasks, callBack).Start(1)
 	res := jsonutils.NewDict()
 	res.Set("task", jsonutils.NewArray(runTaskNames...))
@@ -1765,7 +1788,7 @@ func (s *SKVMGuestInstance) PrepareDisksMigrate(liveMigrage bool) (*jsonutils.JS
 			if err != nil {
 				return nil, errors.Wrapf(err, "GetDiskByPath(%s)", diskPath)
 			}
-			if d.GetType() == api.STORAGE_LOCAL {
+			if utils.IsInStringArray(d.GetType(), []string{api.STORAGE_LOCAL, api.STORAGE_SLVM}) {
 				back, err := d.PrepareMigrate(liveMigrage)
 				if err != nil {
 					return nil, err
@@ -1806,7 +1829,7 @@ func (s *SKVMGuestInstance) IsSharedStorage() bool {
 	return true
 }
 
-func (s *SKVMGuestInstance) generateDiskSetupScripts(disks []api.GuestdiskJsonDesc) (string, error) {
+func (s *SKVMGuestInstance) generateDiskSetupScripts(disks []api.GuestdiskJsonDesc, needMigrate bool) (string, error) {
 	cmd := " "
 	for i := range disks {
 		diskPath := disks[i].Path
@@ -1818,11 +1841,56 @@ func (s *SKVMGuestInstance) generateDiskSetupScripts(disks []api.GuestdiskJsonDe
 			disks[i].StorageType = d.GetType()
 		}
 		diskIndex := disks[i].Index
+		if sd, ok := d.(*storageman.SSLVMDisk); ok && needMigrate {
+			cmd += sd.GetMigrateDiskSetupScripts(int(diskIndex))
+			continue
+		}
 		cmd += d.GetDiskSetupScripts(int(diskIndex))
 	}
 	return cmd, nil
 }
 
+// getSLVMDisks returns disks on shared lvm storages, whose lvmlockd lock
+// follows the guest among hosts
+func (s *SKVMGuestInstance) getSLVMDisks() []*storageman.SSLVMDisk {
+	ret := []*storageman.SSLVMDisk{}
+	disks, _ := s.Desc.GetArray("disks")
+	for _, disk := range disks {
+		diskPath, _ := disk.GetString("path")
+		d, err := storageman.GetManager().GetDiskByPath(diskPath)
+		if err != nil {
+			continue
+		}
+		if sd, ok := d.(*storageman.SSLVMDisk); ok {
+			ret = append(ret, sd)
+		}
+	}
+	return ret
+}
+
+// activateSLVMDisksExclusive takes back the exclusive lock of shared lvm disks
+// after live migration, once the other host has released its shared lock by
+// undeploying the guest
+func (s *SKVMGuestInstance) activateSLVMDisksExclusive() {
+	disks := s.getSLVMDisks()
+	for tried := 0; len(disks) > 0 && tried < SLVM_EXCLUSIVE_MAX_TRY; tried++ {
+		if tried > 0 {
+			time.Sleep(10 * time.Second)
+		}
+		pending := []*storageman.SSLVMDisk{}
+		for _, d := range disks {
+			if err := d.ActivateExclusive(); err != nil {
+				log.Debugf("activate disk %s exclusively: %v", d.GetId(), err)
+				pending = append(pending, d)
+			}
+		}
+		disks = pending
+	}
+	for _, d := range disks {
+		log.Errorf("guest %s disk %s is still shared with another host", s.GetName(), d.GetId())
+	}
+}
+
 func (s *SKVMGuestInstance) getQemuCmdline() (string, error) {
 	content, err := fileutils2.FileGetContents(s.GetStartScriptPath())
 	if err != nil {
@@ -17,6 +17,7 @@ package guestman
 import (
 	"fmt"
 	"net"
+	"os"
 	"path"
 	"strings"
 	"time"
@@ -34,7 +35,9 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
 	qemucerts "yunion.io/x/onecloud/pkg/hostman/guestman/qemu/certs"
+	"yunion.io/x/onecloud/pkg/hostman/metadata"
 	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 	"yunion.io/x/onecloud/pkg/util/qemutils"
 	"yunion.io/x/onecloud/pkg/util/sysutils"
@@ -153,6 +156,32 @@ func (s *SKVMGuestInstance) disableIsaSerialDev() bool {
 	return val == "true"
 }
 
+func (s *SKVMGuestInstance) isConfigDriveEnabled() bool {
+	val, _ := s.Desc.GetString("metadata", api.VM_METADATA_CONFIG_DRIVE)
+	return val == "true"
+}
+
+func (s *SKVMGuestInstance) getConfigDrivePath() string {
+	return path.Join(s.HomeDir(), "config-drive.iso")
+}
+
+// prepareConfigDrive regenerates the config drive ISO from current desc,
+// or removes the stale one if config drive is disabled
+func (s *SKVMGuestInstance) prepareConfigDrive() (string, error) {
+	isoPath := s.getConfigDrivePath()
+	if !s.isConfigDriveEnabled() {
+		if fileutils2.Exists(isoPath) {
+			os.Remove(isoPath)
+		}
+		return "", nil
+	}
+	err := metadata.GenerateConfigDrive(s.Desc, options.HostOptions.ConfigDriveFormat, s.HomeDir(), isoPath)
+	if err != nil {
+		return "", errors.Wrap(err, "GenerateConfigDrive")
+	}
+	return isoPath, nil
+}
+
 func (s *SKVMGuestInstance) disablePvpanicDev() bool {
 	val, _ := s.Desc.GetString("metadata", "disable_pvpanic")
 	return val == "true"
@@ -301,7 +330,7 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	cmd += "sleep 1\n"
 	cmd += fmt.Sprintf("echo %d > %s\n", input.VNCPort, s.GetVncFilePath())
 
-	diskScripts, err := s.generateDiskSetupScripts(input.Disks)
+	diskScripts, err := s.generateDiskSetupScripts(input.Disks, jsonutils.QueryBoolean(data, "need_migrate", false))
 	if err != nil {
 		return "", errors.Wrap(err, "generateDiskSetupScripts")
 	}
@@ -406,6 +435,11 @@ function nic_mtu() {
 		cdromPath, _ := cdrom.GetString("path")
 		input.CdromPath = cdromPath
 	}
+	configDrivePath, err := s.prepareConfigDrive()
+	if err != nil {
+		return "", errors.Wrap(err, "prepareConfigDrive")
+	}
+	input.ConfigDrivePath = configDrivePath
 
 	// UEFI ovmf file path
 	if s.getBios() == qemu.BIOS_UEFI || input.QemuArch == qemu.Arch_aarch64 {
@@ -646,6 +680,11 @@ func (s *SKVMGuestInstance) generateStopScript(data *jsonutils.JSONDict) string
 		downscript := s.getNicDownScriptPath(nic)
 		cmd += fmt.Sprintf("%s %s\n", downscript, ifname)
 	}
+
+	// release the lock of shared lvm disks, so the guest can start on other hosts
+	for _, d := range s.getSLVMDisks() {
+		cmd += d.GetDiskTeardownScripts()
+	}
 	return cmd
 }
 
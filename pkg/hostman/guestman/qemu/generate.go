@@ -49,6 +49,7 @@ type GenerateStartOptionsInput struct {
 	IsQ35                 bool
 	BootOrder             string
 	CdromPath             string
+	ConfigDrivePath       string
 	Nics                  []jsonutils.JSONObject
 	OVNIntegrationBridge  string
 	Disks                 []api.GuestdiskJsonDesc
@@ -184,6 +185,15 @@ func GenerateStartOptions(
 	// cdrom
 	opts = append(opts, drvOpt.Cdrom(input.CdromPath, input.OsName, input.IsQ35, len(input.Disks))...)
 
+	// config drive
+	if input.ConfigDrivePath != "" {
+		dev, err := getConfigDriveDevice(drvOpt.IsArm(), input.Disks, input.CdromPath, input.OsName, input.IsQ35)
+		if err != nil {
+			return "", errors.Wrap(err, "getConfigDriveDevice")
+		}
+		opts = append(opts, drvOpt.ConfigDrive(input.ConfigDrivePath, dev)...)
+	}
+
 	// genereate nics
 	nicOpts, err := generateNicOptions(drvOpt, input)
 	if err != nil {
@@ -349,6 +359,86 @@ func getDiskDeviceOption(optDrv QemuOptions, disk api.GuestdiskJsonDesc, isArm b
 
 }
 
+// getConfigDriveDevice chooses the cdrom device of config drive from the
+// slots left by disks and cdrom, see getDiskDeviceOption and Cdrom
+func getConfigDriveDevice(isArm bool, disks []api.GuestdiskJsonDesc, cdromPath string, osName string, isQ35 bool) (string, error) {
+	hasScsi := false
+	for _, disk := range disks {
+		if utils.IsInStringArray(disk.Driver, []string{DISK_DRIVER_SCSI, DISK_DRIVER_PVSCSI}) ||
+			(isArm && utils.IsInStringArray(disk.Driver, []string{DISK_DRIVER_IDE, DISK_DRIVER_SATA})) {
+			hasScsi = true
+		}
+	}
+	if isArm {
+		if hasScsi {
+			return "scsi-cd,bus=scsi.0", nil
+		}
+		if cdromPath != "" {
+			// the only scsi bus is the one of cdrom
+			return "scsi-cd", nil
+		}
+		return "virtio-scsi-device -device scsi-cd", nil
+	}
+
+	// pc has 2 ide buses of 2 units, q35 has 6 ahci ports of 1 unit
+	buses, units := 2, 2
+	if isQ35 {
+		buses, units = 6, 1
+	}
+	used := make(map[int]map[int]bool)
+	use := func(bus, unit int) {
+		if used[bus] == nil {
+			used[bus] = make(map[int]bool)
+		}
+		used[bus][unit] = true
+	}
+	firstFreeUnit := func(bus int) int {
+		for unit := 0; unit < units; unit++ {
+			if !used[bus][unit] {
+				return unit
+			}
+		}
+		return -1
+	}
+	for _, disk := range disks {
+		idx := int(disk.Index)
+		switch disk.Driver {
+		case DISK_DRIVER_IDE:
+			if isQ35 {
+				use(idx/2, 0)
+			} else {
+				use(idx/2, idx%2)
+			}
+		case DISK_DRIVER_SATA:
+			use(idx, 0)
+		}
+	}
+	if osName != OS_NAME_MACOS {
+		// cdrom always sits on ide.1, at the first free unit of pc
+		if isQ35 {
+			use(1, 0)
+		} else if unit := firstFreeUnit(1); unit >= 0 {
+			use(1, unit)
+		}
+	} else if cdromPath != "" {
+		use(len(disks), 0)
+	}
+	for bus := 0; bus < buses; bus++ {
+		unit := firstFreeUnit(bus)
+		if unit < 0 {
+			continue
+		}
+		if isQ35 {
+			return fmt.Sprintf("ide-cd,bus=ide.%d", bus), nil
+		}
+		return fmt.Sprintf("ide-cd,bus=ide.%d,unit=%d", bus, unit), nil
+	}
+	if hasScsi {
+		return "scsi-cd,bus=scsi.0", nil
+	}
+	return "", errors.Errorf("no free ide slot or scsi controller for config drive")
+}
+
 func GetNicAddr(index int, disksLen int, isoDevsLen int, isVdiSpice bool) int {
 	var pciBase = 10
 	if disksLen > 10 {
@@ -20,6 +20,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 )
 
 func TestGenerateStartCommand(t *testing.T) {
@@ -42,3 +44,111 @@ func TestGenerateStartCommand(t *testing.T) {
 	log.Errorf("cmd: %s", cmd)
 	log.Errorf("error: %s", err)
 }
+
+func TestGetConfigDriveDevice(t *testing.T) {
+	disks := func(driver string, count int) []api.GuestdiskJsonDesc {
+		ret := make([]api.GuestdiskJsonDesc, count)
+		for i := range ret {
+			ret[i].Index = int8(i)
+			ret[i].Driver = driver
+		}
+		return ret
+	}
+	cases := []struct {
+		name      string
+		isArm     bool
+		disks     []api.GuestdiskJsonDesc
+		cdromPath string
+		osName    string
+		isQ35     bool
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:  "pc virtio disks",
+			disks: disks(DISK_DRIVER_VIRTIO, 2),
+			want:  "ide-cd,bus=ide.0,unit=0",
+		},
+		{
+			name:  "pc one ide disk",
+			disks: disks(DISK_DRIVER_IDE, 1),
+			want:  "ide-cd,bus=ide.0,unit=1",
+		},
+		{
+			name:  "pc two ide disks",
+			disks: disks(DISK_DRIVER_IDE, 2),
+			want:  "ide-cd,bus=ide.1,unit=1",
+		},
+		{
+			name:    "pc three ide disks",
+			disks:   disks(DISK_DRIVER_IDE, 3),
+			wantErr: true,
+		},
+		{
+			name:  "pc four ide disks with scsi",
+			disks: append(disks(DISK_DRIVER_IDE, 4), api.GuestdiskJsonDesc{Index: 4, Driver: DISK_DRIVER_SCSI}),
+			want:  "scsi-cd,bus=scsi.0",
+		},
+		{
+			name:  "q35 virtio disks",
+			disks: disks(DISK_DRIVER_VIRTIO, 1),
+			isQ35: true,
+			want:  "ide-cd,bus=ide.0",
+		},
+		{
+			name:  "q35 three sata disks",
+			disks: disks(DISK_DRIVER_SATA, 3),
+			isQ35: true,
+			want:  "ide-cd,bus=ide.3",
+		},
+		{
+			name:      "q35 macos with cdrom",
+			disks:     disks(DISK_DRIVER_SATA, 2),
+			cdromPath: "/iso/macos.iso",
+			osName:    OS_NAME_MACOS,
+			isQ35:     true,
+			want:      "ide-cd,bus=ide.3",
+		},
+		{
+			name:  "arm scsi disks",
+			isArm: true,
+			disks: disks(DISK_DRIVER_SCSI, 1),
+			want:  "scsi-cd,bus=scsi.0",
+		},
+		{
+			name:  "arm sata disks replaced with scsi",
+			isArm: true,
+			disks: disks(DISK_DRIVER_SATA, 1),
+			want:  "scsi-cd,bus=scsi.0",
+		},
+		{
+			name:      "arm virtio disks with cdrom",
+			isArm:     true,
+			disks:     disks(DISK_DRIVER_VIRTIO, 1),
+			cdromPath: "/iso/cd.iso",
+			want:      "scsi-cd",
+		},
+		{
+			name:  "arm virtio disks",
+			isArm: true,
+			disks: disks(DISK_DRIVER_VIRTIO, 1),
+			want:  "virtio-scsi-device -device scsi-cd",
+		},
+	}
+	for _, c := range cases {
+		got, err := getConfigDriveDevice(c.isArm, c.disks, c.cdromPath, c.osName, c.isQ35)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, got %q", c.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: want %q, got %q", c.name, c.want, got)
+		}
+	}
+}
@@ -110,6 +110,7 @@ type QemuOptions interface {
 	VNC(port uint, usePasswd bool) string
 	VGA(vType string, alterOpt string) string
 	Cdrom(cdromPath string, osName string, isQ35 bool, disksLen int) []string
+	ConfigDrive(isoPath string, dev string) []string
 	SerialDevice() []string
 	QGA(homeDir string) []string
 	PvpanicDevice() string
@@ -432,6 +433,15 @@ func (o baseOptions_x86_64) Cdrom(cdromPath string, osName string, isQ35 bool, d
 	return opts
 }
 
+// ConfigDrive attaches the metadata ISO as cdrom device dev, whose
+// controller and slot are chosen by getConfigDriveDevice
+func (o baseOptions_x86_64) ConfigDrive(isoPath string, dev string) []string {
+	return []string{
+		o.Device(dev + ",drive=config-drive"),
+		o.Drive(fmt.Sprintf("id=config-drive,media=cdrom,if=none,readonly=on,file=%s", isoPath)),
+	}
+}
+
 func (o baseOptions_x86_64) SerialDevice() []string {
 	return []string{
 		o.Chardev("pty", "charserial0", ""),
@@ -514,6 +524,13 @@ func (o baseOptions_aarch64) Cdrom(cdromPath string, osName string, isQ35 bool,
 	return opts
 }
 
+func (o baseOptions_aarch64) ConfigDrive(isoPath string, dev string) []string {
+	return []string{
+		o.Device(dev + ",drive=config-drive,share-rw=true"),
+		o.Drive(fmt.Sprintf("if=none,file=%s,id=config-drive,media=cdrom,readonly=on", isoPath)),
+	}
+}
+
 func (o baseOptions_aarch64) SerialDevice() []string {
 	return nil
 }
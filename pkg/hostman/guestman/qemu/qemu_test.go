@@ -67,4 +67,9 @@ func Test_baseOptions(t *testing.T) {
 	// test vga
 	assert.Equal("-vga std", opt.VGA("std", ""))
 	assert.Equal("-vga x", opt.VGA("std", "-vga x"))
+	// test config drive
+	assert.Equal([]string{
+		"-device ide-cd,bus=ide.0,unit=1,drive=config-drive",
+		"-drive id=config-drive,media=cdrom,if=none,readonly=on,file=/opt/cloud/workspace/servers/test/config-drive.iso",
+	}, opt.ConfigDrive("/opt/cloud/workspace/servers/test/config-drive.iso", "ide-cd,bus=ide.0,unit=1"))
 }
@@ -0,0 +1,127 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	QGA_TIMEOUT               = 30 * time.Second
+	FS_FREEZE_DEFAULT_TIMEOUT = 5 * time.Minute
+)
+
+func (s *SKVMGuestInstance) GetQgaSocketPath() string {
+	return path.Join(s.HomeDir(), "qga.sock")
+}
+
+func (s *SKVMGuestInstance) newQemuGuestAgent() (*monitor.QemuGuestAgent, error) {
+	return monitor.NewQemuGuestAgent(s.Id, s.GetQgaSocketPath(), QGA_TIMEOUT)
+}
+
+// FsFreeze freezes guest filesystems through qemu-guest-agent, filesystems
+// are thawed automatically after timeout
+func (s *SKVMGuestInstance) FsFreeze(timeout time.Duration) (int, error) {
+	s.fsFreezeLock.Lock()
+	defer s.fsFreezeLock.Unlock()
+
+	qga, err := s.newQemuGuestAgent()
+	if err != nil {
+		return 0, err
+	}
+	defer qga.Close()
+	cnt, err := qga.FsFreezeFreeze()
+	if err != nil {
+		// guest agent may have frozen part of filesystems before failure
+		if _, e := qga.FsFreezeThaw(); e != nil {
+			log.Errorf("guest %s thaw after freeze failure: %v", s.GetName(), e)
+		}
+		return 0, err
+	}
+	if s.fsThawTimer != nil {
+		s.fsThawTimer.Stop()
+	}
+	s.fsThawTimer = time.AfterFunc(timeout, func() {
+		log.Warningf("guest %s filesystems frozen over %s, thaw automatically", s.GetName(), timeout)
+		if _, err := s.FsThaw(); err != nil {
+			log.Errorf("guest %s auto thaw: %v", s.GetName(), err)
+		}
+	})
+	return cnt, nil
+}
+
+func (s *SKVMGuestInstance) FsThaw() (int, error) {
+	s.fsFreezeLock.Lock()
+	defer s.fsFreezeLock.Unlock()
+
+	if s.fsThawTimer != nil {
+		s.fsThawTimer.Stop()
+		s.fsThawTimer = nil
+	}
+	qga, err := s.newQemuGuestAgent()
+	if err != nil {
+		return 0, err
+	}
+	defer qga.Close()
+	return qga.FsFreezeThaw()
+}
+
+func (m *SGuestManager) getRunningServer(sid string) (*SKVMGuestInstance, error) {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
+	}
+	if !guest.IsRunning() {
+		return nil, httperrors.NewBadRequestError("Guest %s is not running", sid)
+	}
+	return guest, nil
+}
+
+func (m *SGuestManager) GuestFsFreeze(ctx context.Context, sid string, req *hostapi.GuestFsFreezeRequest) (*hostapi.GuestFsFreezeResponse, error) {
+	guest, err := m.getRunningServer(sid)
+	if err != nil {
+		return nil, err
+	}
+	timeout := FS_FREEZE_DEFAULT_TIMEOUT
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+	cnt, err := guest.FsFreeze(timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "FsFreeze")
+	}
+	return &hostapi.GuestFsFreezeResponse{Count: cnt}, nil
+}
+
+func (m *SGuestManager) GuestFsThaw(ctx context.Context, sid string) (*hostapi.GuestFsThawResponse, error) {
+	guest, err := m.getRunningServer(sid)
+	if err != nil {
+		return nil, err
+	}
+	cnt, err := guest.FsThaw()
+	if err != nil {
+		return nil, errors.Wrap(err, "FsThaw")
+	}
+	return &hostapi.GuestFsThawResponse{Count: cnt}, nil
+}
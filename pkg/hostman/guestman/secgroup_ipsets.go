@@ -0,0 +1,163 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/regutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+// execIpsetRestore feeds script to ipset restore, swapped in tests
+var execIpsetRestore = func(script string) error {
+	cmd := procutils.NewRemoteCommandAsFarAsPossible("ipset", "-exist", "restore")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return errors.Wrap(err, "StdinPipe")
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return errors.Wrap(err, "StderrPipe")
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "Start")
+	}
+	io.WriteString(stdin, script)
+	stdin.Close()
+	output, _ := ioutil.ReadAll(stderr)
+	if err := cmd.Wait(); err != nil {
+		return errors.Wrapf(err, "ipset restore: %s", output)
+	}
+	return nil
+}
+
+// sSecgroupIpsets keeps one ipset of each address family for every peer
+// security group referred to by the firewall rules of guests on the host.
+// The sets are shared by the guests and refreshed whenever the desc of a
+// guest referring to them is saved, e.g. on the resync of referrers after
+// the members of the peer security group change
+type sSecgroupIpsets struct {
+	lock sync.Mutex
+	// peer secgroup ids referred to by each guest
+	refs map[string][]string
+}
+
+var secgroupIpsets = &sSecgroupIpsets{refs: map[string][]string{}}
+
+// Sync refreshes the ipsets in security_ipsets of guest desc and destroys
+// those no guest refers to any more
+func (m *sSecgroupIpsets) Sync(guestId string, desc jsonutils.JSONObject) error {
+	ipsets := map[string][]string{}
+	if desc.Contains("security_ipsets") {
+		if err := desc.Unmarshal(&ipsets, "security_ipsets"); err != nil {
+			return errors.Wrap(err, "unmarshal security_ipsets")
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(ipsets) > 0 {
+		if err := execIpsetRestore(ipsetRestoreScript(ipsets)); err != nil {
+			return errors.Wrap(err, "update secgroup ipsets")
+		}
+	}
+	secgroupIds := make([]string, 0, len(ipsets))
+	for secgroupId := range ipsets {
+		secgroupIds = append(secgroupIds, secgroupId)
+	}
+	m.setRefs(guestId, secgroupIds)
+	return nil
+}
+
+// Release drops the references of the deleted guest
+func (m *sSecgroupIpsets) Release(guestId string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.setRefs(guestId, nil)
+}
+
+func (m *sSecgroupIpsets) setRefs(guestId string, secgroupIds []string) {
+	released := m.refs[guestId]
+	if len(secgroupIds) > 0 {
+		m.refs[guestId] = secgroupIds
+	} else {
+		delete(m.refs, guestId)
+	}
+	inUse := map[string]bool{}
+	for _, ids := range m.refs {
+		for _, id := range ids {
+			inUse[id] = true
+		}
+	}
+	for _, id := range released {
+		if inUse[id] {
+			continue
+		}
+		// the set is left if firewall rules still match against it, and
+		// will be reused once referred to again
+		script := fmt.Sprintf("destroy %s\ndestroy %s\n", api.SecgroupPeerIpsetName(id, false), api.SecgroupPeerIpsetName(id, true))
+		if err := execIpsetRestore(script); err != nil {
+			log.Warningf("destroy ipsets of secgroup %s: %v", id, err)
+		}
+	}
+}
+
+// ipsetRestoreScript fills temporary sets and swaps them with the sets of
+// peer security groups, so that rules never match against a partial set
+func ipsetRestoreScript(ipsets map[string][]string) string {
+	secgroupIds := make([]string, 0, len(ipsets))
+	for secgroupId := range ipsets {
+		secgroupIds = append(secgroupIds, secgroupId)
+	}
+	sort.Strings(secgroupIds)
+	lines := []string{}
+	for _, secgroupId := range secgroupIds {
+		for _, ipv6 := range []bool{false, true} {
+			name := api.SecgroupPeerIpsetName(secgroupId, ipv6)
+			tmp := name + "-t"
+			family := "inet"
+			if ipv6 {
+				family = "inet6"
+			}
+			lines = append(lines,
+				fmt.Sprintf("create %s hash:net family %s", name, family),
+				fmt.Sprintf("create %s hash:net family %s", tmp, family),
+				fmt.Sprintf("flush %s", tmp),
+			)
+			for _, addr := range ipsets[secgroupId] {
+				if (ipv6 && regutils.MatchIP6Addr(addr)) || (!ipv6 && regutils.MatchIP4Addr(addr)) {
+					lines = append(lines, fmt.Sprintf("add %s %s", tmp, addr))
+				}
+			}
+			lines = append(lines,
+				fmt.Sprintf("swap %s %s", tmp, name),
+				fmt.Sprintf("destroy %s", tmp),
+			)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
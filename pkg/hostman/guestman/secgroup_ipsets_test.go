@@ -0,0 +1,88 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"strings"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestIpsetRestoreScript(t *testing.T) {
+	v4 := api.SecgroupPeerIpsetName("sg-web", false)
+	v6 := api.SecgroupPeerIpsetName("sg-web", true)
+	if len(v4) > 31 || v4 == v6 || !strings.HasPrefix(v4, "sg4-") || !strings.HasPrefix(v6, "sg6-") {
+		t.Fatalf("bad ipset names %s %s", v4, v6)
+	}
+	got := ipsetRestoreScript(map[string][]string{"sg-web": {"10.0.0.2", "fd00::2", "10.0.0.3"}})
+	want := strings.Join([]string{
+		"create " + v4 + " hash:net family inet",
+		"create " + v4 + "-t hash:net family inet",
+		"flush " + v4 + "-t",
+		"add " + v4 + "-t 10.0.0.2",
+		"add " + v4 + "-t 10.0.0.3",
+		"swap " + v4 + "-t " + v4,
+		"destroy " + v4 + "-t",
+		"create " + v6 + " hash:net family inet6",
+		"create " + v6 + "-t hash:net family inet6",
+		"flush " + v6 + "-t",
+		"add " + v6 + "-t fd00::2",
+		"swap " + v6 + "-t " + v6,
+		"destroy " + v6 + "-t",
+	}, "\n") + "\n"
+	if got != want {
+		t.Errorf("script:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSecgroupIpsetsRefs(t *testing.T) {
+	scripts := []string{}
+	origin := execIpsetRestore
+	execIpsetRestore = func(script string) error {
+		scripts = append(scripts, script)
+		return nil
+	}
+	defer func() { execIpsetRestore = origin }()
+
+	m := &sSecgroupIpsets{refs: map[string][]string{}}
+	desc := jsonutils.Marshal(map[string]interface{}{
+		"security_ipsets": map[string][]string{"sg-web": {"10.0.0.2"}},
+	})
+	for _, guestId := range []string{"guest1", "guest2"} {
+		if err := m.Sync(guestId, desc); err != nil {
+			t.Fatalf("Sync %s: %v", guestId, err)
+		}
+	}
+	if len(scripts) != 2 {
+		t.Fatalf("want 2 updates, got %d", len(scripts))
+	}
+
+	// still referred to by guest2
+	m.Release("guest1")
+	if len(scripts) != 2 {
+		t.Errorf("ipsets destroyed while in use: %s", scripts[len(scripts)-1])
+	}
+	// guest2 doesn't refer to the peer any more
+	if err := m.Sync("guest2", jsonutils.NewDict()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	want := "destroy " + api.SecgroupPeerIpsetName("sg-web", false) + "\ndestroy " + api.SecgroupPeerIpsetName("sg-web", true) + "\n"
+	if len(scripts) != 3 || scripts[2] != want {
+		t.Errorf("want ipsets destroyed, got %q", scripts[2:])
+	}
+}
@@ -29,6 +29,7 @@ var HealthCheckReactor IHealthCheckReactor
 
 type IGuestDescGetter interface {
 	GetGuestNicDesc(mac, ip, port, bridge string, isCandidate bool) (jsonutils.JSONObject, jsonutils.JSONObject)
+	GetGuestNicDescsOnBridge(bridge string) []jsonutils.JSONObject
 }
 
 var GuestDescGetter IGuestDescGetter
@@ -0,0 +1,109 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host_health
+
+import (
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/hostman/options"
+)
+
+// IHealthChecker checks a local resource the host depends on, such as
+// the mount of a shared storage, CheckHealth may try to repair it by itself
+type IHealthChecker interface {
+	GetName() string
+	CheckHealth() error
+}
+
+type sHealthChecker struct {
+	checker  IHealthChecker
+	failures int
+}
+
+var (
+	checkers     = map[string]*sHealthChecker{}
+	checkersLock = &sync.Mutex{}
+	checkersOnce = &sync.Once{}
+)
+
+// RegisterHealthChecker registers a checker running periodically, after
+// StorageHealthCheckMaxFailures consecutive failures the host is taken as
+// unhealthy if host health manager is enabled
+func RegisterHealthChecker(checker IHealthChecker) {
+	checkersLock.Lock()
+	defer checkersLock.Unlock()
+	checkers[checker.GetName()] = &sHealthChecker{checker: checker}
+	checkersOnce.Do(func() {
+		go startHealthCheckers()
+	})
+}
+
+func UnregisterHealthChecker(name string) {
+	checkersLock.Lock()
+	defer checkersLock.Unlock()
+	delete(checkers, name)
+}
+
+func startHealthCheckers() {
+	interval := options.HostOptions.StorageHealthCheckIntervalSecond
+	if interval <= 0 {
+		interval = 30
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if runHealthCheckers() {
+			if manager != nil {
+				manager.OnUnhealth()
+			} else {
+				log.Errorf("health checker failed, but host health manager not init")
+			}
+		}
+	}
+}
+
+// runHealthCheckers returns true if any checker exceeds max failures
+func runHealthCheckers() bool {
+	checkersLock.Lock()
+	list := make([]*sHealthChecker, 0, len(checkers))
+	for _, c := range checkers {
+		list = append(list, c)
+	}
+	checkersLock.Unlock()
+
+	maxFailures := options.HostOptions.StorageHealthCheckMaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 3
+	}
+	unhealthy := false
+	for _, c := range list {
+		err := c.checker.CheckHealth()
+		checkersLock.Lock()
+		if err != nil {
+			c.failures += 1
+			log.Errorf("health checker %s failed %d times: %v", c.checker.GetName(), c.failures, err)
+			if c.failures >= maxFailures {
+				unhealthy = true
+			}
+		} else {
+			c.failures = 0
+		}
+		checkersLock.Unlock()
+	}
+	return unhealthy
+}
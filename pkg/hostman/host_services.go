@@ -115,6 +115,9 @@ func (host *SHostService) RunService() {
 		&metadata.Service{
 			Address: options.HostOptions.Address,
 			Port:    options.HostOptions.Port + 1000,
+
+			HttpTokensRequired:      options.HostOptions.MetadataHttpTokensRequired,
+			HttpPutResponseHopLimit: options.HostOptions.MetadataHttpPutResponseHopLimit,
 			DescGetter: metadata.DescGetterFunc(func(ip string) jsonutils.JSONObject {
 				guestDesc, _ := guestman.GetGuestManager().GetGuestNicDesc("", ip, "", "", false)
 				return guestDesc
@@ -0,0 +1,569 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostdhcp
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/types"
+	guestman "yunion.io/x/onecloud/pkg/hostman/guestman/types"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+const (
+	DHCP6_SERVER_PORT = 547
+	DHCP6_CLIENT_PORT = 546
+
+	dhcp6Solicit            = 1
+	dhcp6Advertise          = 2
+	dhcp6Request            = 3
+	dhcp6Confirm            = 4
+	dhcp6Renew              = 5
+	dhcp6Rebind             = 6
+	dhcp6Reply              = 7
+	dhcp6InformationRequest = 11
+
+	dhcp6OptClientId    = 1
+	dhcp6OptServerId    = 2
+	dhcp6OptIaNa        = 3
+	dhcp6OptIaAddr      = 5
+	dhcp6OptRapidCommit = 14
+	dhcp6OptDnsServers  = 23
+
+	duidTypeLLT = 1
+	duidTypeLL  = 3
+
+	ndOptSourceLinkAddr = 1
+	ndOptPrefixInfo     = 3
+	ndOptMtu            = 5
+	ndOptRdnss          = 25
+
+	rdnssLifetime = 1800 * time.Second
+
+	// unsolicited advertisements are sent well within the router lifetime,
+	// as recommended by RFC 4861 MaxRtrAdvInterval and AdvDefaultLifetime
+	raInterval       = 600 * time.Second
+	raRouterLifetime = 1800 * time.Second
+)
+
+var (
+	allDhcpAgents = net.ParseIP("ff02::1:2")
+	allRouters    = net.ParseIP("ff02::2")
+	allNodes      = net.ParseIP("ff02::1")
+	linkLocal     = net.ParseIP("fe80::")
+)
+
+// sDHCP6Listener owns the dhcpv6 and icmpv6 sockets shared by the servers of
+// all bridges.  There can be only one socket bound to [::]:547, so packets
+// are dispatched to the server of the incoming interface
+type sDHCP6Listener struct {
+	dhcpConn *ipv6.PacketConn
+	ndConn   *icmp.PacketConn
+
+	servers sync.Map
+}
+
+var (
+	dhcp6Listener     *sDHCP6Listener
+	dhcp6ListenerLock sync.Mutex
+)
+
+func getDHCP6Listener() (*sDHCP6Listener, error) {
+	dhcp6ListenerLock.Lock()
+	defer dhcp6ListenerLock.Unlock()
+	if dhcp6Listener != nil {
+		return dhcp6Listener, nil
+	}
+	l := &sDHCP6Listener{}
+	if err := l.listen(); err != nil {
+		return nil, err
+	}
+	go l.serveND()
+	go l.serveDHCP6()
+	dhcp6Listener = l
+	return l, nil
+}
+
+func (l *sDHCP6Listener) listen() error {
+	conn, err := net.ListenPacket("udp6", net.JoinHostPort("::", strconv.Itoa(DHCP6_SERVER_PORT)))
+	if err != nil {
+		return errors.Wrap(err, "listen dhcpv6")
+	}
+	l.dhcpConn = ipv6.NewPacketConn(conn)
+	if err := l.dhcpConn.SetControlMessage(ipv6.FlagInterface, true); err != nil {
+		return errors.Wrap(err, "set dhcpv6 control message")
+	}
+
+	l.ndConn, err = icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return errors.Wrap(err, "listen icmpv6")
+	}
+	pc := l.ndConn.IPv6PacketConn()
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	filter.Accept(ipv6.ICMPTypeRouterSolicitation)
+	if err := pc.SetICMPFilter(&filter); err != nil {
+		return errors.Wrap(err, "set icmpv6 filter")
+	}
+	if err := pc.SetControlMessage(ipv6.FlagInterface, true); err != nil {
+		return errors.Wrap(err, "set icmpv6 control message")
+	}
+	if err := pc.SetMulticastHopLimit(255); err != nil {
+		return errors.Wrap(err, "set multicast hop limit")
+	}
+	if err := pc.SetHopLimit(255); err != nil {
+		return errors.Wrap(err, "set hop limit")
+	}
+	return nil
+}
+
+// register joins the multicast groups on the interface of server
+func (l *sDHCP6Listener) register(s *SGuestDHCP6Server) error {
+	if err := l.dhcpConn.JoinGroup(s.intf, &net.UDPAddr{IP: allDhcpAgents}); err != nil {
+		return errors.Wrapf(err, "join all dhcp agents group on %s", s.iface)
+	}
+	if err := l.ndConn.IPv6PacketConn().JoinGroup(s.intf, &net.IPAddr{IP: allRouters}); err != nil {
+		return errors.Wrapf(err, "join all routers group on %s", s.iface)
+	}
+	l.servers.Store(s.intf.Index, s)
+	return nil
+}
+
+func (l *sDHCP6Listener) getServer(cm *ipv6.ControlMessage) *SGuestDHCP6Server {
+	if cm == nil {
+		return nil
+	}
+	s, ok := l.servers.Load(cm.IfIndex)
+	if !ok {
+		return nil
+	}
+	return s.(*SGuestDHCP6Server)
+}
+
+func (l *sDHCP6Listener) serveND() {
+	pc := l.ndConn.IPv6PacketConn()
+	buf := make([]byte, 1500)
+	for {
+		n, cm, src, err := pc.ReadFrom(buf)
+		if err != nil {
+			log.Errorf("read icmpv6: %s", err)
+			return
+		}
+		s := l.getServer(cm)
+		if s == nil {
+			continue
+		}
+		s.serveRouterSolicitation(buf[:n], src)
+	}
+}
+
+func (l *sDHCP6Listener) serveDHCP6() {
+	buf := make([]byte, 1500)
+	for {
+		n, cm, src, err := l.dhcpConn.ReadFrom(buf)
+		if err != nil {
+			log.Errorf("read dhcpv6: %s", err)
+			return
+		}
+		s := l.getServer(cm)
+		if s == nil {
+			continue
+		}
+		resp := s.serveDHCP6Internal(buf[:n])
+		if resp == nil {
+			continue
+		}
+		wcm := &ipv6.ControlMessage{IfIndex: s.intf.Index}
+		if _, err := l.dhcpConn.WriteTo(resp, wcm, src); err != nil {
+			log.Errorf("send dhcpv6 reply to %s on %s: %s", src, s.iface, err)
+		}
+	}
+}
+
+func (l *sDHCP6Listener) sendRouterAdvertisement(s *SGuestDHCP6Server, msg []byte, dst net.IP) error {
+	wcm := &ipv6.ControlMessage{IfIndex: s.intf.Index, HopLimit: 255}
+	_, err := l.ndConn.IPv6PacketConn().WriteTo(msg, wcm, &net.IPAddr{IP: dst, Zone: s.iface})
+	return err
+}
+
+// SGuestDHCP6Server answers router solicitations and DHCPv6 requests of
+// guests attached to the bridge with the ipv6 config of their nics, and
+// advertises periodically so that guests keep the prefix and default router.
+// Guests on a network with ipv6 gateway take the host as the default router
+type SGuestDHCP6Server struct {
+	iface string
+	intf  *net.Interface
+
+	listener *sDHCP6Listener
+}
+
+func NewGuestDHCP6Server(iface string) (*SGuestDHCP6Server, error) {
+	intf, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, errors.Wrapf(err, "InterfaceByName %s", iface)
+	}
+	return &SGuestDHCP6Server{
+		iface: iface,
+		intf:  intf,
+	}, nil
+}
+
+func (s *SGuestDHCP6Server) Start(blocking bool) {
+	log.Infof("SGuestDHCP6Server starting on %s ...", s.iface)
+	serve := func() {
+		listener, err := getDHCP6Listener()
+		if err != nil {
+			log.Errorf("DHCPv6 listen error: %s", err)
+			return
+		}
+		if err := listener.register(s); err != nil {
+			log.Errorf("DHCPv6 register %s error: %s", s.iface, err)
+			return
+		}
+		s.listener = listener
+		s.advertise()
+	}
+	if blocking {
+		serve()
+	} else {
+		go serve()
+	}
+}
+
+func (s *SGuestDHCP6Server) getGuestNic(mac string) (jsonutils.JSONObject, *types.SServerNic) {
+	if guestman.GuestDescGetter == nil {
+		return nil, nil
+	}
+	guestDesc, guestNic := guestman.GuestDescGetter.GetGuestNicDesc(mac, "", "", s.iface, false)
+	if guestNic == nil {
+		guestDesc, guestNic = guestman.GuestDescGetter.GetGuestNicDesc(mac, "", "", s.iface, true)
+	}
+	if guestNic == nil {
+		return nil, nil
+	}
+	nic := unmarshalIp6Nic(guestNic)
+	if nic == nil {
+		return nil, nil
+	}
+	return guestDesc, nic
+}
+
+func unmarshalIp6Nic(guestNic jsonutils.JSONObject) *types.SServerNic {
+	if jsonutils.QueryBoolean(guestNic, "virtual", false) {
+		return nil
+	}
+	nic := new(types.SServerNic)
+	if err := guestNic.Unmarshal(nic); err != nil {
+		log.Errorf("unmarshal guest nic %s: %s", guestNic, err)
+		return nil
+	}
+	if len(nic.Ip6) == 0 {
+		return nil
+	}
+	return nic
+}
+
+func (s *SGuestDHCP6Server) serveRouterSolicitation(pkt []byte, src net.Addr) {
+	// type(1) code(1) checksum(2) reserved(4) options
+	if len(pkt) < 8 || ipv6.ICMPType(pkt[0]) != ipv6.ICMPTypeRouterSolicitation {
+		return
+	}
+	mac := ndSourceLinkAddr(pkt[8:])
+	if len(mac) == 0 {
+		return
+	}
+	_, nic := s.getGuestNic(mac)
+	if nic == nil || nic.Ip6Mode == compute.NETWORK_IP6_MODE_STATIC {
+		return
+	}
+	ip := src.(*net.IPAddr).IP
+	if err := s.listener.sendRouterAdvertisement(s, s.makeRouterAdvertisement(nic), ip); err != nil {
+		log.Errorf("send router advertisement to %s: %s", mac, err)
+	}
+}
+
+// advertise sends unsolicited router advertisements periodically.  They are
+// multicast if all guests on the bridge share the same ipv6 config, or sent
+// to the EUI-64 link local address of each guest otherwise, so that guests
+// never take the prefix of another network
+func (s *SGuestDHCP6Server) advertise() {
+	for {
+		s.sendUnsolicitedRouterAdvertisements()
+		time.Sleep(raInterval)
+	}
+}
+
+func (s *SGuestDHCP6Server) sendUnsolicitedRouterAdvertisements() {
+	if guestman.GuestDescGetter == nil {
+		return
+	}
+	nics := []*types.SServerNic{}
+	msgs := map[string][]byte{}
+	for _, guestNic := range guestman.GuestDescGetter.GetGuestNicDescsOnBridge(s.iface) {
+		nic := unmarshalIp6Nic(guestNic)
+		if nic == nil || nic.Ip6Mode == compute.NETWORK_IP6_MODE_STATIC {
+			continue
+		}
+		msg := s.makeRouterAdvertisement(nic)
+		msgs[string(msg)] = msg
+		nics = append(nics, nic)
+	}
+	if len(msgs) == 1 {
+		for _, msg := range msgs {
+			if err := s.listener.sendRouterAdvertisement(s, msg, allNodes); err != nil {
+				log.Errorf("send router advertisement on %s: %s", s.iface, err)
+			}
+		}
+		return
+	}
+	for _, nic := range nics {
+		ip, err := netutils2.IPv6EUI64(linkLocal, nic.Mac)
+		if err != nil {
+			log.Errorf("link local address of %s: %s", nic.Mac, err)
+			continue
+		}
+		if err := s.listener.sendRouterAdvertisement(s, s.makeRouterAdvertisement(nic), ip); err != nil {
+			log.Errorf("send router advertisement to %s: %s", nic.Mac, err)
+		}
+	}
+}
+
+func ndSourceLinkAddr(opts []byte) string {
+	for len(opts) >= 8 {
+		typ, l := opts[0], int(opts[1])*8
+		if l == 0 || l > len(opts) {
+			return ""
+		}
+		if typ == ndOptSourceLinkAddr && l >= 8 {
+			return net.HardwareAddr(opts[2:8]).String()
+		}
+		opts = opts[l:]
+	}
+	return ""
+}
+
+func (s *SGuestDHCP6Server) makeRouterAdvertisement(nic *types.SServerNic) []byte {
+	msg := make([]byte, 16)
+	msg[0] = byte(ipv6.ICMPTypeRouterAdvertisement)
+	// cur hop limit
+	msg[4] = 64
+	if nic.Ip6Mode == compute.NETWORK_IP6_MODE_DHCPV6 {
+		// managed address configuration
+		msg[5] |= 0x80
+	}
+	if len(nic.Gateway6) > 0 {
+		// router lifetime, the host forwards to the gateway of the network
+		binary.BigEndian.PutUint16(msg[6:8], uint16(raRouterLifetime/time.Second))
+	}
+	// reachable time(8:12) and retrans timer(12:16) are left unspecified
+
+	prefix := make([]byte, 32)
+	prefix[0] = ndOptPrefixInfo
+	prefix[1] = 4
+	prefix[2] = byte(nic.Masklen6)
+	// on-link
+	prefix[3] = 0x80
+	if nic.Ip6Mode == compute.NETWORK_IP6_MODE_SLAAC {
+		// autonomous address configuration
+		prefix[3] |= 0x40
+	}
+	lifetime := uint32(options.HostOptions.DhcpLeaseTime)
+	binary.BigEndian.PutUint32(prefix[4:8], lifetime)
+	binary.BigEndian.PutUint32(prefix[8:12], lifetime)
+	copy(prefix[16:32], net.ParseIP(nic.Ip6).Mask(net.CIDRMask(nic.Masklen6, 128)))
+	msg = append(msg, prefix...)
+
+	if nic.Mtu > 0 {
+		mtu := make([]byte, 8)
+		mtu[0] = ndOptMtu
+		mtu[1] = 1
+		binary.BigEndian.PutUint32(mtu[4:8], uint32(nic.Mtu))
+		msg = append(msg, mtu...)
+	}
+
+	if dns := parseIp6List(nic.Dns6); len(dns) > 0 {
+		rdnss := make([]byte, 8, 8+16*len(dns))
+		rdnss[0] = ndOptRdnss
+		rdnss[1] = byte(1 + 2*len(dns))
+		binary.BigEndian.PutUint32(rdnss[4:8], uint32(rdnssLifetime/time.Second))
+		for _, ip := range dns {
+			rdnss = append(rdnss, ip...)
+		}
+		msg = append(msg, rdnss...)
+	}
+	// checksum is filled by kernel for icmpv6 raw sockets
+	return msg
+}
+
+func parseIp6List(s string) []net.IP {
+	ret := make([]net.IP, 0)
+	for _, seg := range strings.Split(s, ",") {
+		ip := net.ParseIP(strings.TrimSpace(seg))
+		if ip != nil && ip.To4() == nil {
+			ret = append(ret, ip.To16())
+		}
+	}
+	return ret
+}
+
+type sDhcp6Message struct {
+	msgType  byte
+	txId     []byte
+	clientId []byte
+	iaId     []byte
+	rapid    bool
+}
+
+func parseDhcp6Message(pkt []byte) *sDhcp6Message {
+	if len(pkt) < 4 {
+		return nil
+	}
+	msg := &sDhcp6Message{
+		msgType: pkt[0],
+		txId:    pkt[1:4],
+	}
+	opts := pkt[4:]
+	for len(opts) >= 4 {
+		code := binary.BigEndian.Uint16(opts[0:2])
+		l := int(binary.BigEndian.Uint16(opts[2:4]))
+		if 4+l > len(opts) {
+			return nil
+		}
+		val := opts[4 : 4+l]
+		switch code {
+		case dhcp6OptClientId:
+			msg.clientId = val
+		case dhcp6OptIaNa:
+			if l >= 12 {
+				msg.iaId = val[0:4]
+			}
+		case dhcp6OptRapidCommit:
+			msg.rapid = true
+		}
+		opts = opts[4+l:]
+	}
+	return msg
+}
+
+// duidMac extracts the link layer address of DUID-LLT and DUID-LL
+func duidMac(duid []byte) string {
+	if len(duid) < 4 || binary.BigEndian.Uint16(duid[2:4]) != 1 {
+		// not ethernet
+		return ""
+	}
+	switch binary.BigEndian.Uint16(duid[0:2]) {
+	case duidTypeLLT:
+		if len(duid) >= 14 {
+			return net.HardwareAddr(duid[8:14]).String()
+		}
+	case duidTypeLL:
+		if len(duid) >= 10 {
+			return net.HardwareAddr(duid[4:10]).String()
+		}
+	}
+	return ""
+}
+
+func appendDhcp6Option(buf []byte, code uint16, val []byte) []byte {
+	hdr := make([]byte, 4)
+	binary.BigEndian.PutUint16(hdr[0:2], code)
+	binary.BigEndian.PutUint16(hdr[2:4], uint16(len(val)))
+	buf = append(buf, hdr...)
+	return append(buf, val...)
+}
+
+func (s *SGuestDHCP6Server) serverId() []byte {
+	duid := make([]byte, 4, 4+len(s.intf.HardwareAddr))
+	binary.BigEndian.PutUint16(duid[0:2], duidTypeLL)
+	binary.BigEndian.PutUint16(duid[2:4], 1)
+	return append(duid, s.intf.HardwareAddr...)
+}
+
+func (s *SGuestDHCP6Server) serveDHCP6Internal(pkt []byte) []byte {
+	msg := parseDhcp6Message(pkt)
+	if msg == nil || len(msg.clientId) == 0 {
+		return nil
+	}
+	var respType byte
+	switch msg.msgType {
+	case dhcp6Solicit:
+		respType = dhcp6Advertise
+		if msg.rapid {
+			respType = dhcp6Reply
+		}
+	case dhcp6Request, dhcp6Confirm, dhcp6Renew, dhcp6Rebind, dhcp6InformationRequest:
+		respType = dhcp6Reply
+	default:
+		return nil
+	}
+	mac := duidMac(msg.clientId)
+	if len(mac) == 0 {
+		log.Debugf("dhcpv6 client id %x without link layer address", msg.clientId)
+		return nil
+	}
+	_, nic := s.getGuestNic(mac)
+	if nic == nil {
+		return nil
+	}
+	if nic.Ip6Mode != compute.NETWORK_IP6_MODE_DHCPV6 && msg.msgType != dhcp6InformationRequest {
+		return nil
+	}
+
+	resp := []byte{respType}
+	resp = append(resp, msg.txId...)
+	resp = appendDhcp6Option(resp, dhcp6OptClientId, msg.clientId)
+	resp = appendDhcp6Option(resp, dhcp6OptServerId, s.serverId())
+	if msg.rapid && respType == dhcp6Reply && msg.msgType == dhcp6Solicit {
+		resp = appendDhcp6Option(resp, dhcp6OptRapidCommit, nil)
+	}
+	if len(msg.iaId) > 0 && msg.msgType != dhcp6InformationRequest {
+		lease := uint32(options.HostOptions.DhcpLeaseTime)
+		renew := uint32(options.HostOptions.DhcpRenewalTime)
+		iaAddr := make([]byte, 24)
+		copy(iaAddr[0:16], net.ParseIP(nic.Ip6).To16())
+		binary.BigEndian.PutUint32(iaAddr[16:20], lease)
+		binary.BigEndian.PutUint32(iaAddr[20:24], lease)
+
+		iaNa := make([]byte, 12)
+		copy(iaNa[0:4], msg.iaId)
+		binary.BigEndian.PutUint32(iaNa[4:8], renew)
+		binary.BigEndian.PutUint32(iaNa[8:12], renew+(lease-renew)/2)
+		iaNa = appendDhcp6Option(iaNa, dhcp6OptIaAddr, iaAddr)
+		resp = appendDhcp6Option(resp, dhcp6OptIaNa, iaNa)
+	}
+	if dns := parseIp6List(nic.Dns6); len(dns) > 0 {
+		val := make([]byte, 0, 16*len(dns))
+		for _, ip := range dns {
+			val = append(val, ip...)
+		}
+		resp = appendDhcp6Option(resp, dhcp6OptDnsServers, val)
+	}
+	log.Infof("Make DHCPv6 Reply %s TO %s", nic.Ip6, mac)
+	return resp
+}
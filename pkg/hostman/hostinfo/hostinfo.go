@@ -124,6 +124,9 @@ func (h *SHostInfo) GetBridgeDev(bridge string) hostbridge.IBridgeDriver {
 func (h *SHostInfo) StartDHCPServer() {
 	for _, nic := range h.Nics {
 		nic.dhcpServer.Start(false)
+		if nic.dhcp6Server != nil {
+			nic.dhcp6Server.Start(false)
+		}
 	}
 }
 
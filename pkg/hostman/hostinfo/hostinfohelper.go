@@ -180,6 +180,8 @@ type SNIC struct {
 	Bandwidth  int
 	BridgeDev  hostbridge.IBridgeDriver
 	dhcpServer *hostdhcp.SGuestDHCPServer
+
+	dhcp6Server *hostdhcp.SGuestDHCP6Server
 }
 
 func (n *SNIC) EnableDHCPRelay() bool {
@@ -303,6 +305,12 @@ func NewNIC(desc string) (*SNIC, error) {
 	if err != nil {
 		return nil, err
 	}
+	if options.HostOptions.EnableDhcp6Server {
+		nic.dhcp6Server, err = hostdhcp.NewGuestDHCP6Server(nic.Bridge)
+		if err != nil {
+			return nil, errors.Wrap(err, "new dhcpv6 server")
+		}
+	}
 	// dhcp server start after guest manager init
 	return nic, nil
 }
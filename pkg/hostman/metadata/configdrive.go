@@ -0,0 +1,172 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	// OpenStack config drive, read by the ConfigDrive datasource of
+	// cloud-init and by cloudbase-init
+	CONFIG_DRIVE_FORMAT_CONFIG2 = "config-2"
+	// NoCloud seed, read by the NoCloud datasource of cloud-init
+	CONFIG_DRIVE_FORMAT_CIDATA = "cidata"
+)
+
+var CONFIG_DRIVE_FORMATS = []string{
+	CONFIG_DRIVE_FORMAT_CONFIG2,
+	CONFIG_DRIVE_FORMAT_CIDATA,
+}
+
+// GenerateConfigDrive builds the metadata ISO of a guest at isoPath, the
+// files are staged in a temporary directory under workDir and the ISO is
+// renamed into place at last, so a running qemu keeps reading the old
+// content until the media is changed
+func GenerateConfigDrive(guestDesc jsonutils.JSONObject, format string, workDir string, isoPath string) error {
+	tmpDir, err := ioutil.TempDir(workDir, "config-drive")
+	if err != nil {
+		return errors.Wrap(err, "TempDir")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var files map[string]string
+	switch format {
+	case CONFIG_DRIVE_FORMAT_CONFIG2:
+		files = getConfig2Files(guestDesc)
+	case CONFIG_DRIVE_FORMAT_CIDATA:
+		files = getCidataFiles(guestDesc)
+	default:
+		return errors.Wrapf(errors.ErrNotSupported, "config drive format %s", format)
+	}
+
+	rootDir := path.Join(tmpDir, "root")
+	for name, content := range files {
+		fp := path.Join(rootDir, name)
+		if err := os.MkdirAll(path.Dir(fp), 0755); err != nil {
+			return errors.Wrapf(err, "mkdir %s", path.Dir(fp))
+		}
+		if err := ioutil.WriteFile(fp, []byte(content), 0644); err != nil {
+			return errors.Wrapf(err, "write %s", fp)
+		}
+	}
+
+	tmpIso := path.Join(tmpDir, "config-drive.iso")
+	output, err := procutils.NewCommand("mkisofs",
+		"-o", tmpIso,
+		"-V", format,
+		"-J", "-R",
+		"-input-charset", "utf-8",
+		"-quiet",
+		rootDir,
+	).Output()
+	if err != nil {
+		return errors.Wrapf(err, "mkisofs %s", output)
+	}
+	if err := os.Rename(tmpIso, isoPath); err != nil {
+		return errors.Wrapf(err, "rename %s", isoPath)
+	}
+	return nil
+}
+
+func getConfig2Files(guestDesc jsonutils.JSONObject) map[string]string {
+	files := map[string]string{
+		"openstack/latest/meta_data.json":    getOpenstackMetaData(guestDesc).String(),
+		"openstack/latest/network_data.json": getOpenstackNetworkData(guestDesc).String(),
+		"openstack/latest/vendor_data.json":  jsonutils.NewDict().String(),
+	}
+	if userData := getUserData(guestDesc); len(userData) > 0 {
+		files["openstack/latest/user_data"] = userData
+	}
+	return files
+}
+
+func getCidataFiles(guestDesc jsonutils.JSONObject) map[string]string {
+	uuid, _ := guestDesc.GetString("uuid")
+	hostname, _ := guestDesc.GetString("hostname")
+	if len(hostname) == 0 {
+		hostname, _ = guestDesc.GetString("name")
+	}
+	// json is a subset of yaml
+	metaData := jsonutils.NewDict()
+	metaData.Add(jsonutils.NewString(uuid), "instance-id")
+	metaData.Add(jsonutils.NewString(hostname), "local-hostname")
+	if pubkey, _ := guestDesc.GetString("pubkey"); len(pubkey) > 0 {
+		metaData.Add(jsonutils.NewStringArray([]string{pubkey}), "public-keys")
+	}
+	return map[string]string{
+		"meta-data":      metaData.String(),
+		"user-data":      getUserData(guestDesc),
+		"network-config": getNetworkConfigV2(guestDesc).String(),
+	}
+}
+
+// getNetworkConfigV2 returns the cloud-init network config version 2
+func getNetworkConfigV2(guestDesc jsonutils.JSONObject) jsonutils.JSONObject {
+	ethernets := jsonutils.NewDict()
+	nics, _ := guestDesc.GetArray("nics")
+	mainNic, _ := netutils2.GetMainNic(nics)
+	mainIp := ""
+	if mainNic != nil {
+		mainIp, _ = mainNic.GetString("ip")
+	}
+	for i, nic := range nics {
+		mac, _ := nic.GetString("mac")
+		ip, _ := nic.GetString("ip")
+		masklen, _ := nic.Int("masklen")
+		eth := jsonutils.NewDict()
+		match := jsonutils.NewDict()
+		match.Add(jsonutils.NewString(mac), "macaddress")
+		eth.Add(match, "match")
+		eth.Add(jsonutils.NewString(fmt.Sprintf("eth%d", i)), "set-name")
+		eth.Add(jsonutils.NewStringArray([]string{fmt.Sprintf("%s/%d", ip, masklen)}), "addresses")
+		if mtu, _ := nic.Int("mtu"); mtu > 0 {
+			eth.Add(jsonutils.NewInt(mtu), "mtu")
+		}
+		if gateway, _ := nic.GetString("gateway"); len(gateway) > 0 && ip == mainIp {
+			eth.Add(jsonutils.NewString(gateway), "gateway4")
+		}
+		dns := []string{}
+		dnsStr, _ := nic.GetString("dns")
+		for _, addr := range strings.Split(dnsStr, ",") {
+			if addr = strings.TrimSpace(addr); len(addr) > 0 {
+				dns = append(dns, addr)
+			}
+		}
+		if len(dns) > 0 {
+			nameservers := jsonutils.NewDict()
+			nameservers.Add(jsonutils.NewStringArray(dns), "addresses")
+			if domain, _ := nic.GetString("domain"); len(domain) > 0 {
+				nameservers.Add(jsonutils.NewStringArray([]string{domain}), "search")
+			}
+			eth.Add(nameservers, "nameservers")
+		}
+		ethernets.Add(eth, fmt.Sprintf("eth%d", i))
+	}
+	ret := jsonutils.NewDict()
+	ret.Add(jsonutils.NewInt(2), "version")
+	ret.Add(ethernets, "ethernets")
+	return ret
+}
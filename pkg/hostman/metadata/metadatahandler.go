@@ -35,6 +35,7 @@ import (
 )
 
 func Start(app *appsrv.Application, s *Service) {
+	s.tokens = newTokenManager()
 	s.addHandler(app)
 	addr := net.JoinHostPort(s.Address, strconv.Itoa(s.Port))
 	log.Infof("Start metadata service on http://%s", addr)
@@ -56,6 +57,13 @@ type Service struct {
 	Port    int
 
 	DescGetter DescGetter
+
+	// require session token for guests not setting metadata_http_tokens
+	HttpTokensRequired bool
+	// default IP TTL of the token response
+	HttpPutResponseHopLimit int
+
+	tokens *tokenManager
 }
 
 func (s *Service) getGuestNicDesc(r *http.Request) (guestDesc jsonutils.JSONObject) {
@@ -81,39 +89,60 @@ func (s *Service) addHandler(app *appsrv.Application) {
 		app.AddHandler(method, fmt.Sprintf("%s/<version:%s>/meta-data",
 			prefix, `(latest|\d{4}-\d{2}-\d{2})`), s.metaData)
 	}
+	app.AddHandler("PUT", fmt.Sprintf("%s/<version:%s>/api/token",
+		prefix, `(latest|\d{4}-\d{2}-\d{2})`), s.sessionToken)
+
+	s.addOpenstackHandler(app, prefix)
 }
 
-func (s *Service) versionOnly(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	hostutils.Response(ctx, w, strings.Join([]string{"meta-data", "user-data"}, "\n"))
+func (s *Service) sessionToken(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	s.putToken(w, r)
 }
 
-func (s *Service) userData(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+// getAuthorizedGuestDesc returns nil and responses the error if the request
+// is from an unknown address or fails the session token check
+func (s *Service) getAuthorizedGuestDesc(ctx context.Context, w http.ResponseWriter, r *http.Request) jsonutils.JSONObject {
 	guestDesc := s.getGuestNicDesc(r)
 	if guestDesc == nil {
 		hostutils.Response(ctx, w, "")
-		return
+		return nil
+	}
+	if !s.checkToken(r, guestDesc) {
+		hostutils.Response(ctx, w, httperrors.NewUnauthorizedError("invalid or missing metadata token"))
+		return nil
 	}
+	return guestDesc
+}
 
+func getUserData(guestDesc jsonutils.JSONObject) string {
 	if !guestDesc.Contains("user_data") {
-		hostutils.Response(ctx, w, "")
-		return
+		return ""
 	}
-
 	guestUserData, _ := guestDesc.GetString("user_data")
 	userDataDecoded, err := base64.StdEncoding.DecodeString(guestUserData)
 	if err != nil {
 		guestId, _ := guestDesc.GetString("id")
 		log.Errorf("Error format user_data %s, %s", guestId, guestUserData)
-		hostutils.Response(ctx, w, "")
+		return ""
+	}
+	return string(userDataDecoded)
+}
+
+func (s *Service) versionOnly(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	hostutils.Response(ctx, w, strings.Join([]string{"meta-data", "user-data"}, "\n"))
+}
+
+func (s *Service) userData(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	guestDesc := s.getAuthorizedGuestDesc(ctx, w, r)
+	if guestDesc == nil {
 		return
 	}
-	hostutils.Response(ctx, w, string(userDataDecoded))
+	hostutils.Response(ctx, w, getUserData(guestDesc))
 }
 
 func (s *Service) metaData(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	guestDesc := s.getGuestNicDesc(r)
+	guestDesc := s.getAuthorizedGuestDesc(ctx, w, r)
 	if guestDesc == nil {
-		hostutils.Response(ctx, w, "")
 		return
 	}
 
@@ -157,7 +186,7 @@ func (s *Service) metaData(ctx context.Context, w http.ResponseWriter, r *http.R
 					hostutils.Response(ctx, w, "openssh-key")
 					return
 				} else if len(req) == 3 {
-					pubkey, _ := guestDesc.GetString("pubkeu")
+					pubkey, _ := guestDesc.GetString("pubkey")
 					hostutils.Response(ctx, w, pubkey)
 					return
 				}
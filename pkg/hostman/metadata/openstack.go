@@ -0,0 +1,244 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+// OpenStack metadata format consumed by the OpenStack datasource of
+// cloud-init and by cloudbase-init
+var openstackVersions = []string{
+	"2012-08-10",
+	"2013-04-04",
+	"2013-10-17",
+	"2015-10-15",
+	"2016-06-30",
+	"2016-10-06",
+	"2017-02-22",
+	"2018-08-27",
+	"latest",
+}
+
+const (
+	openstackMetaData    = "meta_data.json"
+	openstackNetworkData = "network_data.json"
+	openstackUserData    = "user_data"
+	openstackVendorData  = "vendor_data.json"
+
+	// keep in sync with db.USER_TAG_PREFIX
+	userTagPrefix = "user:"
+)
+
+func (s *Service) addOpenstackHandler(app *appsrv.Application, prefix string) {
+	version := `(latest|\d{4}-\d{2}-\d{2})`
+	for _, method := range []string{"GET", "HEAD"} {
+		app.AddHandler(method, fmt.Sprintf("%s/openstack", prefix), s.openstackVersions)
+		app.AddHandler(method, fmt.Sprintf("%s/openstack/<version:%s>", prefix, version), s.openstackVersionOnly)
+		app.AddHandler(method, fmt.Sprintf("%s/openstack/<version:%s>/<resource:%s>", prefix, version, `[a-z_.]+`), s.openstackData)
+	}
+}
+
+func (s *Service) openstackVersions(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	hostutils.Response(ctx, w, strings.Join(openstackVersions, "\n"))
+}
+
+func (s *Service) openstackVersionOnly(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	hostutils.Response(ctx, w, strings.Join([]string{
+		openstackMetaData, openstackNetworkData, openstackUserData, openstackVendorData,
+	}, "\n"))
+}
+
+func (s *Service) openstackData(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	guestDesc := s.getAuthorizedGuestDesc(ctx, w, r)
+	if guestDesc == nil {
+		return
+	}
+	params := appctx.AppContextParams(ctx)
+	switch params["<resource>"] {
+	case openstackMetaData:
+		hostutils.Response(ctx, w, getOpenstackMetaData(guestDesc))
+	case openstackNetworkData:
+		hostutils.Response(ctx, w, getOpenstackNetworkData(guestDesc))
+	case openstackUserData:
+		userData := getUserData(guestDesc)
+		if len(userData) == 0 {
+			hostutils.Response(ctx, w, httperrors.NewNotFoundError("user_data not found"))
+			return
+		}
+		hostutils.Response(ctx, w, userData)
+	case openstackVendorData:
+		hostutils.Response(ctx, w, jsonutils.NewDict())
+	default:
+		hostutils.Response(ctx, w, httperrors.NewNotFoundError("Resource not handled"))
+	}
+}
+
+func getOpenstackMetaData(guestDesc jsonutils.JSONObject) jsonutils.JSONObject {
+	ret := jsonutils.NewDict()
+	uuid, _ := guestDesc.GetString("uuid")
+	name, _ := guestDesc.GetString("name")
+	hostname, _ := guestDesc.GetString("hostname")
+	if len(hostname) == 0 {
+		hostname = name
+	}
+	zone, _ := guestDesc.GetString("zone")
+	projectId, _ := guestDesc.GetString("tenant_id")
+	ret.Add(jsonutils.NewString(uuid), "uuid")
+	ret.Add(jsonutils.NewString(name), "name")
+	ret.Add(jsonutils.NewString(hostname), "hostname")
+	ret.Add(jsonutils.NewString(zone), "availability_zone")
+	ret.Add(jsonutils.NewString(projectId), "project_id")
+	ret.Add(jsonutils.NewInt(0), "launch_index")
+
+	if pubkey, _ := guestDesc.GetString("pubkey"); len(pubkey) > 0 {
+		keyName := "my-public-key"
+		ret.Add(jsonutils.Marshal(map[string]string{keyName: pubkey}), "public_keys")
+		ret.Add(jsonutils.Marshal([]map[string]string{
+			{"name": keyName, "type": "ssh", "data": pubkey},
+		}), "keys")
+	}
+
+	// only the user tags are exposed to the guest
+	meta := jsonutils.NewDict()
+	metadata, _ := guestDesc.GetMap("metadata")
+	for k, v := range metadata {
+		if strings.HasPrefix(k, userTagPrefix) {
+			val, _ := v.GetString()
+			meta.Add(jsonutils.NewString(val), strings.TrimPrefix(k, userTagPrefix))
+		}
+	}
+	ret.Add(meta, "meta")
+	return ret
+}
+
+func getOpenstackNetworkData(guestDesc jsonutils.JSONObject) jsonutils.JSONObject {
+	links := jsonutils.NewArray()
+	networks := jsonutils.NewArray()
+	services := jsonutils.NewArray()
+
+	nics, _ := guestDesc.GetArray("nics")
+	mainNic, _ := netutils2.GetMainNic(nics)
+	mainIp := ""
+	if mainNic != nil {
+		mainIp, _ = mainNic.GetString("ip")
+	}
+	dnsSet := map[string]bool{}
+	for i, nic := range nics {
+		linkId := fmt.Sprintf("tap%d", i)
+		mac, _ := nic.GetString("mac")
+		link := jsonutils.NewDict()
+		link.Add(jsonutils.NewString(linkId), "id")
+		link.Add(jsonutils.NewString("phy"), "type")
+		link.Add(jsonutils.NewString(mac), "ethernet_mac_address")
+		if mtu, _ := nic.Int("mtu"); mtu > 0 {
+			link.Add(jsonutils.NewInt(mtu), "mtu")
+		}
+		links.Add(link)
+
+		ip, _ := nic.GetString("ip")
+		masklen, _ := nic.Int("masklen")
+		netId, _ := nic.GetString("net_id")
+		network := jsonutils.NewDict()
+		network.Add(jsonutils.NewString(fmt.Sprintf("network%d", i)), "id")
+		network.Add(jsonutils.NewString(linkId), "link")
+		network.Add(jsonutils.NewString(netId), "network_id")
+		network.Add(jsonutils.NewString("ipv4"), "type")
+		network.Add(jsonutils.NewString(ip), "ip_address")
+		network.Add(jsonutils.NewString(netutils2.Netlen2Mask(int(masklen))), "netmask")
+		routes := jsonutils.NewArray()
+		if gateway, _ := nic.GetString("gateway"); len(gateway) > 0 && ip == mainIp {
+			route := jsonutils.NewDict()
+			route.Add(jsonutils.NewString("0.0.0.0"), "network")
+			route.Add(jsonutils.NewString("0.0.0.0"), "netmask")
+			route.Add(jsonutils.NewString(gateway), "gateway")
+			routes.Add(route)
+		}
+		network.Add(routes, "routes")
+		networks.Add(network)
+
+		if network6 := getOpenstackNetwork6(nic, i, linkId, ip == mainIp); network6 != nil {
+			networks.Add(network6)
+		}
+
+		dns, _ := nic.GetString("dns")
+		dns6, _ := nic.GetString("dns6")
+		for _, addr := range strings.Split(dns+","+dns6, ",") {
+			addr = strings.TrimSpace(addr)
+			if len(addr) == 0 || dnsSet[addr] {
+				continue
+			}
+			dnsSet[addr] = true
+			service := jsonutils.NewDict()
+			service.Add(jsonutils.NewString("dns"), "type")
+			service.Add(jsonutils.NewString(addr), "address")
+			services.Add(service)
+		}
+	}
+
+	ret := jsonutils.NewDict()
+	ret.Add(links, "links")
+	ret.Add(networks, "networks")
+	ret.Add(services, "services")
+	return ret
+}
+
+// getOpenstackNetwork6 returns the ipv6 network of the nic, nil if the nic
+// has no ipv6 address
+func getOpenstackNetwork6(nic jsonutils.JSONObject, index int, linkId string, isMain bool) jsonutils.JSONObject {
+	ip6, _ := nic.GetString("ip6")
+	if len(ip6) == 0 {
+		return nil
+	}
+	netId, _ := nic.GetString("net_id")
+	network := jsonutils.NewDict()
+	network.Add(jsonutils.NewString(fmt.Sprintf("network%d-ipv6", index)), "id")
+	network.Add(jsonutils.NewString(linkId), "link")
+	network.Add(jsonutils.NewString(netId), "network_id")
+	switch mode, _ := nic.GetString("ip6_mode"); mode {
+	case api.NETWORK_IP6_MODE_SLAAC:
+		network.Add(jsonutils.NewString("ipv6_slaac"), "type")
+	case api.NETWORK_IP6_MODE_DHCPV6:
+		network.Add(jsonutils.NewString("ipv6_dhcpv6-stateful"), "type")
+	default:
+		masklen6, _ := nic.Int("masklen6")
+		network.Add(jsonutils.NewString("ipv6"), "type")
+		network.Add(jsonutils.NewString(ip6), "ip_address")
+		network.Add(jsonutils.NewString(net.IP(net.CIDRMask(int(masklen6), 128)).String()), "netmask")
+	}
+	routes := jsonutils.NewArray()
+	if gateway6, _ := nic.GetString("gateway6"); len(gateway6) > 0 && isMain {
+		route := jsonutils.NewDict()
+		route.Add(jsonutils.NewString("::"), "network")
+		route.Add(jsonutils.NewString("::"), "netmask")
+		route.Add(jsonutils.NewString(gateway6), "gateway")
+		routes.Add(route)
+	}
+	network.Add(routes, "routes")
+	return network
+}
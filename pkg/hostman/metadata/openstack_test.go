@@ -0,0 +1,88 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestGetOpenstackNetworkData(t *testing.T) {
+	guestDesc, err := jsonutils.ParseString(`{
+		"uuid": "guest-a",
+		"nics": [
+			{
+				"mac": "00:22:0a:00:00:02", "ip": "10.0.0.2", "masklen": 24, "gateway": "10.0.0.1",
+				"net_id": "net-a", "dns": "10.0.0.53,114.114.114.114", "mtu": 1500,
+				"ip6": "fd00::2", "masklen6": 64, "gateway6": "fd00::1", "dns6": "fd00::53"
+			},
+			{
+				"mac": "00:22:c0:a8:01:0a", "ip": "192.168.1.10", "masklen": 16,
+				"net_id": "net-b", "dns": "10.0.0.53",
+				"ip6": "2001:db8::10", "masklen6": 64, "ip6_mode": "slaac"
+			},
+			{
+				"mac": "00:22:ac:10:00:05", "ip": "172.16.0.5", "masklen": 12, "gateway": "172.16.0.1",
+				"net_id": "net-c"
+			}
+		]
+	}`)
+	if err != nil {
+		t.Fatalf("parse guest desc: %v", err)
+	}
+	want, err := jsonutils.ParseString(`{
+		"links": [
+			{"id": "tap0", "type": "phy", "ethernet_mac_address": "00:22:0a:00:00:02", "mtu": 1500},
+			{"id": "tap1", "type": "phy", "ethernet_mac_address": "00:22:c0:a8:01:0a"},
+			{"id": "tap2", "type": "phy", "ethernet_mac_address": "00:22:ac:10:00:05"}
+		],
+		"networks": [
+			{
+				"id": "network0", "link": "tap0", "network_id": "net-a", "type": "ipv4",
+				"ip_address": "10.0.0.2", "netmask": "255.255.255.0",
+				"routes": [{"network": "0.0.0.0", "netmask": "0.0.0.0", "gateway": "10.0.0.1"}]
+			},
+			{
+				"id": "network0-ipv6", "link": "tap0", "network_id": "net-a", "type": "ipv6",
+				"ip_address": "fd00::2", "netmask": "ffff:ffff:ffff:ffff::",
+				"routes": [{"network": "::", "netmask": "::", "gateway": "fd00::1"}]
+			},
+			{
+				"id": "network1", "link": "tap1", "network_id": "net-b", "type": "ipv4",
+				"ip_address": "192.168.1.10", "netmask": "255.255.0.0", "routes": []
+			},
+			{
+				"id": "network1-ipv6", "link": "tap1", "network_id": "net-b", "type": "ipv6_slaac", "routes": []
+			},
+			{
+				"id": "network2", "link": "tap2", "network_id": "net-c", "type": "ipv4",
+				"ip_address": "172.16.0.5", "netmask": "255.240.0.0", "routes": []
+			}
+		],
+		"services": [
+			{"type": "dns", "address": "10.0.0.53"},
+			{"type": "dns", "address": "114.114.114.114"},
+			{"type": "dns", "address": "fd00::53"}
+		]
+	}`)
+	if err != nil {
+		t.Fatalf("parse golden: %v", err)
+	}
+	got := getOpenstackNetworkData(guestDesc)
+	if got.String() != want.String() {
+		t.Errorf("network data\ngot:  %s\nwant: %s", got.String(), want.String())
+	}
+}
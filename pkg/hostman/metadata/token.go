@@ -0,0 +1,195 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	// EC2 compatible header names, so that cloud-init and the aws tools
+	// speak the token flow out of the box
+	METADATA_TOKEN_HEADER     = "X-Aws-Ec2-Metadata-Token"
+	METADATA_TOKEN_TTL_HEADER = "X-Aws-Ec2-Metadata-Token-Ttl-Seconds"
+
+	METADATA_TOKEN_MAX_TTL_SECONDS = 21600
+
+	// guest metadata keys, keep in sync with VM_METADATA_HTTP_* of apis/compute
+	guestMetaHttpTokens   = "metadata_http_tokens"
+	guestMetaHttpHopLimit = "metadata_http_put_response_hop_limit"
+
+	httpTokensOptional = "optional"
+	httpTokensRequired = "required"
+)
+
+// tokenManager issues stateless session tokens bound to a guest, a token
+// is the expire time signed with a secret generated at startup, so tokens
+// are invalidated when the host agent restarts
+type tokenManager struct {
+	secret []byte
+}
+
+func newTokenManager() *tokenManager {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("generate metadata token secret: %s", err)
+	}
+	return &tokenManager{secret: secret}
+}
+
+func (m *tokenManager) sign(guestId string, expire int64) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(fmt.Sprintf("%s.%d", guestId, expire)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (m *tokenManager) issue(guestId string, ttl time.Duration) string {
+	expire := time.Now().Add(ttl).Unix()
+	return fmt.Sprintf("%d.%s", expire, m.sign(guestId, expire))
+}
+
+func (m *tokenManager) verify(guestId string, token string) bool {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	expire, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Now().Unix() >= expire {
+		return false
+	}
+	return hmac.Equal([]byte(parts[1]), []byte(m.sign(guestId, expire)))
+}
+
+func (s *Service) isTokenRequired(guestDesc jsonutils.JSONObject) bool {
+	switch httpTokens, _ := guestDesc.GetString("metadata", guestMetaHttpTokens); httpTokens {
+	case httpTokensRequired:
+		return true
+	case httpTokensOptional:
+		return false
+	}
+	return s.HttpTokensRequired
+}
+
+func (s *Service) getHopLimit(guestDesc jsonutils.JSONObject) int {
+	hopLimit := s.HttpPutResponseHopLimit
+	if str, _ := guestDesc.GetString("metadata", guestMetaHttpHopLimit); len(str) > 0 {
+		if v, err := strconv.Atoi(str); err == nil {
+			hopLimit = v
+		}
+	}
+	if hopLimit < 1 {
+		hopLimit = 1
+	}
+	return hopLimit
+}
+
+// checkToken returns false if the request must be refused, a presented
+// token is always validated, an absent one is fine unless required
+func (s *Service) checkToken(r *http.Request, guestDesc jsonutils.JSONObject) bool {
+	token := r.Header.Get(METADATA_TOKEN_HEADER)
+	if len(token) == 0 {
+		return !s.isTokenRequired(guestDesc)
+	}
+	guestId, _ := guestDesc.GetString("uuid")
+	return s.tokens.verify(guestId, token)
+}
+
+func (s *Service) putToken(w http.ResponseWriter, r *http.Request) {
+	// a forwarded request is very likely an SSRF through a proxy in the guest
+	if len(r.Header.Get("X-Forwarded-For")) > 0 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	ttl, err := strconv.Atoi(r.Header.Get(METADATA_TOKEN_TTL_HEADER))
+	if err != nil || ttl < 1 || ttl > METADATA_TOKEN_MAX_TTL_SECONDS {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	guestDesc := s.getGuestNicDesc(r)
+	if guestDesc == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	guestId, _ := guestDesc.GetString("uuid")
+	token := s.tokens.issue(guestId, time.Duration(ttl)*time.Second)
+
+	if _, ok := w.(http.Hijacker); !ok {
+		w.Header().Set(METADATA_TOKEN_TTL_HEADER, strconv.Itoa(ttl))
+		w.Write([]byte(token))
+		return
+	}
+	err = writeWithHopLimit(w, s.getHopLimit(guestDesc), token, map[string]string{
+		METADATA_TOKEN_TTL_HEADER: strconv.Itoa(ttl),
+	})
+	if err != nil {
+		log.Errorf("response metadata token: %s", err)
+	}
+}
+
+// writeWithHopLimit sends the response on a connection whose IP TTL is
+// lowered to hopLimit, so that the token can not be relayed beyond the
+// guest, e.g. by a container network inside the guest
+func writeWithHopLimit(w http.ResponseWriter, hopLimit int, body string, headers map[string]string) error {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return errors.Wrap(err, "Hijack")
+	}
+	defer conn.Close()
+
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok && addr.IP.To4() == nil {
+		err = ipv6.NewConn(conn).SetHopLimit(hopLimit)
+	} else {
+		err = ipv4.NewConn(conn).SetTTL(hopLimit)
+	}
+	if err != nil {
+		return errors.Wrap(err, "set hop limit")
+	}
+
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		ContentLength: int64(len(body)),
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		Close:         true,
+	}
+	resp.Header.Set("Content-Type", "text/plain")
+	for k, v := range headers {
+		resp.Header.Set(k, v)
+	}
+	err = resp.Write(buf)
+	if err != nil {
+		return errors.Wrap(err, "write response")
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"yunion.io/x/jsonutils"
+)
+
+func newTestService(required bool) *Service {
+	descs := map[string]jsonutils.JSONObject{
+		"10.0.0.2": jsonutils.Marshal(map[string]string{"uuid": "guest-a"}),
+		"10.0.0.3": jsonutils.Marshal(map[string]string{"uuid": "guest-b"}),
+	}
+	return &Service{
+		DescGetter: DescGetterFunc(func(ip string) jsonutils.JSONObject {
+			return descs[ip]
+		}),
+		HttpTokensRequired:      required,
+		HttpPutResponseHopLimit: 1,
+		tokens:                  newTokenManager(),
+	}
+}
+
+func tamper(token string) string {
+	last := token[len(token)-1]
+	if last == 'A' {
+		return token[:len(token)-1] + "B"
+	}
+	return token[:len(token)-1] + "A"
+}
+
+func TestCheckToken(t *testing.T) {
+	s := newTestService(true)
+	valid := s.tokens.issue("guest-a", time.Minute)
+	expired := s.tokens.issue("guest-a", -time.Second)
+	parts := strings.SplitN(valid, ".", 2)
+	cases := []struct {
+		name       string
+		remoteAddr string
+		token      string
+		want       bool
+	}{
+		{name: "valid", remoteAddr: "10.0.0.2:40000", token: valid, want: true},
+		{name: "missing", remoteAddr: "10.0.0.2:40000", token: "", want: false},
+		{name: "expired", remoteAddr: "10.0.0.2:40000", token: expired, want: false},
+		{name: "other guest ip", remoteAddr: "10.0.0.3:40000", token: valid, want: false},
+		{name: "tampered hmac", remoteAddr: "10.0.0.2:40000", token: tamper(valid), want: false},
+		{name: "extended expire", remoteAddr: "10.0.0.2:40000", token: "9999999999." + parts[1], want: false},
+		{name: "malformed", remoteAddr: "10.0.0.2:40000", token: "garbage", want: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/latest/meta-data", nil)
+			r.RemoteAddr = c.remoteAddr
+			if len(c.token) > 0 {
+				r.Header.Set(METADATA_TOKEN_HEADER, c.token)
+			}
+			guestDesc := s.getGuestNicDesc(r)
+			if guestDesc == nil {
+				t.Fatalf("no guest of %s", c.remoteAddr)
+			}
+			if got := s.checkToken(r, guestDesc); got != c.want {
+				t.Errorf("checkToken = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckTokenOptional(t *testing.T) {
+	s := newTestService(false)
+	r := httptest.NewRequest("GET", "/latest/meta-data", nil)
+	r.RemoteAddr = "10.0.0.2:40000"
+	if !s.checkToken(r, s.getGuestNicDesc(r)) {
+		t.Errorf("request without token refused while tokens are optional")
+	}
+	r.Header.Set(METADATA_TOKEN_HEADER, tamper(s.tokens.issue("guest-a", time.Minute)))
+	if s.checkToken(r, s.getGuestNicDesc(r)) {
+		t.Errorf("invalid token accepted while tokens are optional")
+	}
+}
+
+func TestPutToken(t *testing.T) {
+	cases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		wantCode   int
+	}{
+		{
+			name:       "issued",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{METADATA_TOKEN_TTL_HEADER: "60"},
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{METADATA_TOKEN_TTL_HEADER: "60", "X-Forwarded-For": "192.168.1.10"},
+			wantCode:   http.StatusForbidden,
+		},
+		{
+			name:       "missing ttl",
+			remoteAddr: "10.0.0.2:40000",
+			wantCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "ttl too long",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{METADATA_TOKEN_TTL_HEADER: "21601"},
+			wantCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "unknown guest",
+			remoteAddr: "10.0.0.9:40000",
+			headers:    map[string]string{METADATA_TOKEN_TTL_HEADER: "60"},
+			wantCode:   http.StatusNotFound,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestService(true)
+			r := httptest.NewRequest("PUT", "/latest/api/token", nil)
+			r.RemoteAddr = c.remoteAddr
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			s.putToken(w, r)
+			if w.Code != c.wantCode {
+				t.Fatalf("code %d, want %d", w.Code, c.wantCode)
+			}
+			if c.wantCode != http.StatusOK {
+				return
+			}
+			if !s.tokens.verify("guest-a", w.Body.String()) {
+				t.Errorf("issued token %q not verified", w.Body.String())
+			}
+			if s.tokens.verify("guest-b", w.Body.String()) {
+				t.Errorf("issued token %q verified for another guest", w.Body.String())
+			}
+		})
+	}
+}
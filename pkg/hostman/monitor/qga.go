@@ -0,0 +1,182 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/rand"
+	"net"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+// https://qemu.readthedocs.io/en/latest/interop/qemu-ga-ref.html
+/*
+qemu-guest-agent speaks a qmp like protocol without greeting and capabilities
+negotiation. A session should start with guest-sync-delimited, whose response
+is prefixed by a 0xff byte, so that stale responses left by a previous session
+can be skipped.
+*/
+
+const (
+	QGA_FS_FREEZE_STATUS_THAWED = "thawed"
+	QGA_FS_FREEZE_STATUS_FROZEN = "frozen"
+
+	qgaDelimiter = 0xff
+)
+
+type QemuGuestAgent struct {
+	id      string
+	conn    net.Conn
+	rd      *bufio.Reader
+	timeout time.Duration
+}
+
+type qgaResponse struct {
+	Return json.RawMessage `json:"return"`
+	Error  *Error          `json:"error"`
+}
+
+// NewQemuGuestAgent connects to the unix socket of guest agent chardev and
+// synchronizes the session, every following command must finish in timeout
+func NewQemuGuestAgent(id, sockPath string, timeout time.Duration) (*QemuGuestAgent, error) {
+	conn, err := net.DialTimeout("unix", sockPath, timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial qga socket %s", sockPath)
+	}
+	qga := &QemuGuestAgent{
+		id:      id,
+		conn:    conn,
+		rd:      bufio.NewReader(conn),
+		timeout: timeout,
+	}
+	if err := qga.sync(); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "guest-sync-delimited")
+	}
+	return qga, nil
+}
+
+func (qga *QemuGuestAgent) Close() error {
+	return qga.conn.Close()
+}
+
+func (qga *QemuGuestAgent) write(data []byte) error {
+	qga.conn.SetDeadline(time.Now().Add(qga.timeout))
+	_, err := qga.conn.Write(data)
+	return err
+}
+
+func (qga *QemuGuestAgent) readResponse() (*qgaResponse, error) {
+	qga.conn.SetDeadline(time.Now().Add(qga.timeout))
+	line, err := qga.rd.ReadBytes('\n')
+	if err != nil {
+		return nil, errors.Wrap(err, "read response")
+	}
+	resp := &qgaResponse{}
+	if err := json.Unmarshal(line, resp); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal response %q", line)
+	}
+	return resp, nil
+}
+
+func (qga *QemuGuestAgent) sync() error {
+	id := rand.Int63n(1 << 31)
+	// leading delimiter makes guest agent drop any partial input it has buffered
+	if err := qga.write([]byte{qgaDelimiter}); err != nil {
+		return errors.Wrap(err, "write delimiter")
+	}
+	cmd := &Command{
+		Execute: "guest-sync-delimited",
+		Args:    map[string]int64{"id": id},
+	}
+	data, _ := json.Marshal(cmd)
+	if err := qga.write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "write guest-sync-delimited")
+	}
+	qga.conn.SetDeadline(time.Now().Add(qga.timeout))
+	if _, err := qga.rd.ReadBytes(qgaDelimiter); err != nil {
+		return errors.Wrap(err, "read delimiter")
+	}
+	for {
+		resp, err := qga.readResponse()
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		var ret int64
+		if err := json.Unmarshal(resp.Return, &ret); err == nil && ret == id {
+			return nil
+		}
+	}
+}
+
+func (qga *QemuGuestAgent) Exec(cmd *Command) (jsonutils.JSONObject, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshal command %s", cmd.Execute)
+	}
+	if err := qga.write(append(data, '\n')); err != nil {
+		return nil, errors.Wrapf(err, "write command %s", cmd.Execute)
+	}
+	resp, err := qga.readResponse()
+	if err != nil {
+		return nil, errors.Wrapf(err, "exec %s", cmd.Execute)
+	}
+	if resp.Error != nil {
+		return nil, errors.Wrapf(resp.Error, "exec %s", cmd.Execute)
+	}
+	if len(resp.Return) == 0 {
+		return jsonutils.NewDict(), nil
+	}
+	return jsonutils.Parse(resp.Return)
+}
+
+func (qga *QemuGuestAgent) execInt(execute string) (int, error) {
+	ret, err := qga.Exec(&Command{Execute: execute})
+	if err != nil {
+		return 0, err
+	}
+	cnt, err := ret.Int()
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s return %s", execute, ret)
+	}
+	return int(cnt), nil
+}
+
+// FsFreezeFreeze freezes all freezable guest filesystems, returns the number
+// of frozen filesystems
+func (qga *QemuGuestAgent) FsFreezeFreeze() (int, error) {
+	return qga.execInt("guest-fsfreeze-freeze")
+}
+
+// FsFreezeThaw unfreezes all frozen guest filesystems, returns the number of
+// thawed filesystems
+func (qga *QemuGuestAgent) FsFreezeThaw() (int, error) {
+	return qga.execInt("guest-fsfreeze-thaw")
+}
+
+func (qga *QemuGuestAgent) FsFreezeStatus() (string, error) {
+	ret, err := qga.Exec(&Command{Execute: "guest-fsfreeze-status"})
+	if err != nil {
+		return "", err
+	}
+	return ret.GetString()
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// fakeGuestAgent serves a single connection like qemu-ga does, a stale
+// response is written first to make sure it is skipped by sync
+func fakeGuestAgent(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte("{\"return\": 0}\n"))
+	status := QGA_FS_FREEZE_STATUS_THAWED
+	rd := bufio.NewReader(conn)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		// skip delimiter sent by client
+		for len(line) > 0 && line[0] == qgaDelimiter {
+			line = line[1:]
+		}
+		cmd := struct {
+			Execute string           `json:"execute"`
+			Args    map[string]int64 `json:"arguments"`
+		}{}
+		if err := json.Unmarshal(line, &cmd); err != nil {
+			t.Errorf("invalid command %q: %v", line, err)
+			return
+		}
+		var resp string
+		switch cmd.Execute {
+		case "guest-sync-delimited":
+			resp = fmt.Sprintf("\xff{\"return\": %d}", cmd.Args["id"])
+		case "guest-fsfreeze-freeze":
+			status = QGA_FS_FREEZE_STATUS_FROZEN
+			resp = `{"return": 2}`
+		case "guest-fsfreeze-thaw":
+			status = QGA_FS_FREEZE_STATUS_THAWED
+			resp = `{"return": 2}`
+		case "guest-fsfreeze-status":
+			resp = fmt.Sprintf(`{"return": %q}`, status)
+		default:
+			resp = fmt.Sprintf(`{"error": {"class": "CommandNotFound", "desc": "The command %s has not been found"}}`, cmd.Execute)
+		}
+		conn.Write([]byte(resp + "\n"))
+	}
+}
+
+func TestQemuGuestAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qga")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	sockPath := path.Join(dir, "qga.sock")
+	l, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	go fakeGuestAgent(t, l)
+
+	qga, err := NewQemuGuestAgent("test", sockPath, 3*time.Second)
+	if err != nil {
+		t.Fatalf("NewQemuGuestAgent: %v", err)
+	}
+	defer qga.Close()
+
+	cnt, err := qga.FsFreezeFreeze()
+	if err != nil || cnt != 2 {
+		t.Fatalf("FsFreezeFreeze want 2, got %d: %v", cnt, err)
+	}
+	status, err := qga.FsFreezeStatus()
+	if err != nil || status != QGA_FS_FREEZE_STATUS_FROZEN {
+		t.Fatalf("FsFreezeStatus want %s, got %s: %v", QGA_FS_FREEZE_STATUS_FROZEN, status, err)
+	}
+	cnt, err = qga.FsFreezeThaw()
+	if err != nil || cnt != 2 {
+		t.Fatalf("FsFreezeThaw want 2, got %d: %v", cnt, err)
+	}
+	if _, err := qga.Exec(&Command{Execute: "guest-unknown"}); err == nil {
+		t.Fatalf("unknown command should fail")
+	}
+}
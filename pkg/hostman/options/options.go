@@ -66,9 +66,11 @@ type SHostOptions struct {
 	HugepagesOption  string `help:"Hugepages option: disable|native|transparent" default:"transparent"`
 	EnableQmpMonitor bool   `help:"Enable qmp monitor" default:"true"`
 
-	PrivatePrefixes []string `help:"IPv4 private prefixes"`
-	LocalImagePath  []string `help:"Local image storage paths"`
-	SharedStorages  []string `help:"Path of shared storages"`
+	PrivatePrefixes  []string `help:"IPv4 private prefixes"`
+	LocalImagePath   []string `help:"Local image storage paths"`
+	SharedStorages   []string `help:"Path of shared storages"`
+	LocalLvmStorages []string `help:"Local thin lvm storages, in format of <vg_name>[/<thin_pool>], thin pool default is thinpool"`
+	LocalZfsStorages []string `help:"Local zfs storages, in format of <pool>[/<dataset>]"`
 
 	DefaultQemuVersion string `help:"Default qemu version" default:"2.12.1"`
 
@@ -81,12 +83,20 @@ type SHostOptions struct {
 	CheckSystemServices bool `help:"Check system services (ntpd, telegraf) on startup" default:"true"`
 
 	DhcpServerPort     int    `help:"Host dhcp server bind port" default:"67"`
+	EnableDhcp6Server  bool   `help:"Answer router solicitations and DHCPv6 requests of guests with ipv6 addresses" default:"false"`
 	DiskIsSsd          bool   `default:"false"`
 	FetcherfsPath      string `default:"/opt/yunion/fetchclient/bin/fetcherfs" help:"Fuse fetcherfs path"`
 	FetcherfsBlockSize int    `default:"16" help:"Fuse fetcherfs fetch chunk_size MB"`
 
 	DefaultImageSaveFormat string `default:"qcow2" help:"Default image save format, default is qcow2, canbe vmdk"`
 
+	ImageSignatureTrustedCaFile   string `help:"PEM bundle of CA certificates trusted to issue image signing certificates"`
+	ImageSignatureTrustedKeysFile string `help:"PEM bundle of public keys trusted to sign images"`
+
+	MetadataHttpTokensRequired      bool   `help:"Require session token for guest metadata requests unless overridden by server" default:"false"`
+	MetadataHttpPutResponseHopLimit int    `help:"Default IP TTL of guest metadata token responses" default:"1"`
+	ConfigDriveFormat               string `help:"Format of guest config drive ISO" default:"config-2" choices:"config-2|cidata"`
+
 	DefaultReadBpsPerCpu   int  `default:"163840000" help:"Default read bps per cpu for hard IO limit"`
 	DefaultReadIopsPerCpu  int  `default:"1250" help:"Default read iops per cpu for hard IO limit"`
 	DefaultWriteBpsPerCpu  int  `default:"54525952" help:"Default write bps per cpu for hard IO limit"`
@@ -146,6 +156,9 @@ type SHostOptions struct {
 	HostHealthTimeout    int    `help:"host health timeout" default:"30"`
 	HostLeaseTimeout     int    `help:"lease timeout" default:"10"`
 
+	StorageHealthCheckIntervalSecond int `help:"interval of checking shared storage mount health, unit is second" default:"30"`
+	StorageHealthCheckMaxFailures    int `help:"host is reported unhealthy after so many consecutive storage health check failures" default:"3"`
+
 	SyncStorageInfoDurationSecond int  `help:"sync storage size duration, unit is second" default:"60"`
 	StartHostIgnoreSysError       bool `help:"start host agent ignore sys error" default:"false"`
 
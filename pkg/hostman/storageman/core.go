@@ -57,6 +57,8 @@ type SStorageManager struct {
 
 	RbdStorageImagecacheManagers        map[string]IImageCacheManger
 	SharedFileStorageImagecacheManagers map[string]IImageCacheManger
+	SLVMStorageImagecacheManagers       map[string]IImageCacheManger
+	BlockStorageImagecacheManagers      map[string]IImageCacheManger
 }
 
 func NewStorageManager(host hostutils.IHost) (*SStorageManager, error) {
@@ -89,6 +91,8 @@ func NewStorageManager(host hostutils.IHost) (*SStorageManager, error) {
 		}
 	}
 
+	ret.initBlockStorages()
+
 	if allFull {
 		return nil, fmt.Errorf("Not enough storage space!")
 	}
@@ -105,6 +109,10 @@ func (s *SStorageManager) Remove(storage IStorage) {
 		delete(s.SharedFileStorageImagecacheManagers, storage.GetStoragecacheId())
 	} else if storage.StorageType() == api.STORAGE_RBD {
 		delete(s.RbdStorageImagecacheManagers, storage.GetStoragecacheId())
+	} else if storage.StorageType() == api.STORAGE_SLVM {
+		delete(s.SLVMStorageImagecacheManagers, storage.GetStoragecacheId())
+	} else if utils.IsInStringArray(storage.StorageType(), api.LOCAL_BLOCK_STORAGE) {
+		delete(s.BlockStorageImagecacheManagers, storage.GetStoragecacheId())
 	}
 	for index, iS := range s.Storages {
 		if iS.GetId() == storage.GetId() {
@@ -266,6 +274,12 @@ func (s *SStorageManager) GetStoragecacheById(scId string) IImageCacheManger {
 	if sc, ok := s.RbdStorageImagecacheManagers[scId]; ok {
 		return sc
 	}
+	if sc, ok := s.SLVMStorageImagecacheManagers[scId]; ok {
+		return sc
+	}
+	if sc, ok := s.BlockStorageImagecacheManagers[scId]; ok {
+		return sc
+	}
 	return nil
 }
 
@@ -280,6 +294,10 @@ func (s *SStorageManager) InitSharedStorageImageCache(storageType, storagecacheI
 		if rbdStorage := s.GetStoragecacheById(storagecacheId); rbdStorage == nil {
 			s.AddRbdStorageImagecache(imagecachePath, storage, storagecacheId)
 		}
+	} else if storageType == api.STORAGE_SLVM {
+		if sc := s.GetStoragecacheById(storagecacheId); sc == nil {
+			s.AddSLVMStorageImagecache(imagecachePath, storage, storagecacheId)
+		}
 	}
 }
 
@@ -308,6 +326,32 @@ func (s *SStorageManager) AddRbdStorageImagecache(imagecachePath string, storage
 	}
 }
 
+func (s *SStorageManager) AddSLVMStorageImagecache(imagecachePath string, storage IStorage, storagecacheId string) {
+	if s.SLVMStorageImagecacheManagers == nil {
+		s.SLVMStorageImagecacheManagers = map[string]IImageCacheManger{}
+	}
+	if _, ok := s.SLVMStorageImagecacheManagers[storagecacheId]; !ok {
+		if imagecache := NewImageCacheManager(s, imagecachePath, storage, storagecacheId, api.STORAGE_SLVM); imagecache != nil {
+			s.SLVMStorageImagecacheManagers[storagecacheId] = imagecache
+			return
+		}
+		log.Errorf("failed init storagecache %s for storage %s", storagecacheId, storage.GetStorageName())
+	}
+}
+
+func (s *SStorageManager) AddBlockStorageImagecache(imagecachePath string, storage IStorage, storagecacheId string) {
+	if s.BlockStorageImagecacheManagers == nil {
+		s.BlockStorageImagecacheManagers = map[string]IImageCacheManger{}
+	}
+	if _, ok := s.BlockStorageImagecacheManagers[storagecacheId]; !ok {
+		if imagecache := NewImageCacheManager(s, imagecachePath, storage, storagecacheId, storage.StorageType()); imagecache != nil {
+			s.BlockStorageImagecacheManagers[storagecacheId] = imagecache
+			return
+		}
+		log.Errorf("failed init storagecache %s for storage %s", storagecacheId, storage.GetStorageName())
+	}
+}
+
 var storageManager *SStorageManager
 
 func GetManager() *SStorageManager {
@@ -380,7 +424,7 @@ func StartSyncStorageSizeTask(interval time.Duration) {
 		manager := GetManager()
 		for i := 0; i < len(manager.Storages); i++ {
 			iS := manager.Storages[i]
-			if iS.StorageType() == api.STORAGE_LOCAL || iS.StorageType() == api.STORAGE_RBD {
+			if utils.IsInStringArray(iS.StorageType(), []string{api.STORAGE_LOCAL, api.STORAGE_RBD, api.STORAGE_SLVM, api.STORAGE_LVM, api.STORAGE_ZFS}) {
 				err := iS.SyncStorageSize()
 				if err != nil {
 					log.Errorf("sync storage %s size failed: %s", iS.GetStorageName(), err)
@@ -0,0 +1,291 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+)
+
+type SBlockDisk struct {
+	SBaseDisk
+}
+
+func NewBlockDisk(storage IStorage, id string) *SBlockDisk {
+	var ret = new(SBlockDisk)
+	ret.SBaseDisk = *NewBaseDisk(storage, id)
+	return ret
+}
+
+func (d *SBlockDisk) getDriver() IBlockVolumeDriver {
+	return d.Storage.(*SBlockStorage).driver
+}
+
+func (d *SBlockDisk) GetType() string {
+	return d.Storage.StorageType()
+}
+
+func (d *SBlockDisk) Probe() error {
+	exist, err := d.getDriver().IsVolumeExist(d.Id)
+	if err != nil {
+		return errors.Wrapf(err, "IsVolumeExist")
+	}
+	if !exist {
+		return cloudprovider.ErrNotFound
+	}
+	return nil
+}
+
+func (d *SBlockDisk) GetPath() string {
+	return d.getDriver().GetVolumePath(d.Id)
+}
+
+func (d *SBlockDisk) GetSnapshotDir() string {
+	return ""
+}
+
+func (d *SBlockDisk) GetDiskDesc() jsonutils.JSONObject {
+	sizeMb, _ := d.getDriver().GetVolumeSizeMb(d.Id)
+	desc := map[string]interface{}{
+		"disk_id":     d.Id,
+		"disk_format": "raw",
+		"disk_path":   d.GetPath(),
+		"disk_size":   sizeMb,
+	}
+	return jsonutils.Marshal(desc)
+}
+
+func (d *SBlockDisk) GetDiskSetupScripts(idx int) string {
+	return fmt.Sprintf("DISK_%d=%s\n", idx, d.GetPath())
+}
+
+func (d *SBlockDisk) DeleteAllSnapshot(skipRecycle bool) error {
+	return d.Storage.(*SBlockStorage).deleteDiskSnapshots(d.Id)
+}
+
+func (d *SBlockDisk) Delete(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	driver := d.getDriver()
+	if err := driver.ReleaseVolumeClones(d.Id); err != nil {
+		return nil, errors.Wrapf(err, "release clones of %s", d.Id)
+	}
+	if err := driver.RemoveVolume(d.Id); err != nil {
+		return nil, err
+	}
+	d.Storage.RemoveDisk(d)
+	return nil, nil
+}
+
+func (d *SBlockDisk) OnRebuildRoot(ctx context.Context, params api.DiskAllocateInput) error {
+	_, err := d.Delete(ctx, api.DiskDeleteInput{})
+	return err
+}
+
+func (d *SBlockDisk) Resize(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	diskInfo, ok := params.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	sizeMb, _ := diskInfo.Int("size")
+	if err := d.getDriver().ResizeVolume(d.Id, sizeMb); err != nil {
+		return nil, err
+	}
+	if err := d.ResizeFs(d.GetPath()); err != nil {
+		return nil, errors.Wrapf(err, "resize fs %s", d.GetPath())
+	}
+	return d.GetDiskDesc(), nil
+}
+
+// PrepareSaveToGlance clones a point-in-time copy of the disk, which is
+// taken as the cached image after it is uploaded
+func (d *SBlockDisk) PrepareSaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	if err := d.Probe(); err != nil {
+		return nil, err
+	}
+	driver := d.getDriver()
+	taskId := appctx.AppContextTaskId(ctx)
+	imageName := fmt.Sprintf("%s%s_%s", BLOCK_IMAGECACHE_PREFIX, d.Id, taskId)
+	snapshotName := fmt.Sprintf("save_%s", taskId)
+	if err := driver.CreateSnapshot(d.Id, snapshotName); err != nil {
+		return nil, errors.Wrapf(err, "create snapshot %s of %s", snapshotName, d.Id)
+	}
+	defer func() {
+		if err := driver.RemoveSnapshot(d.Id, snapshotName); err != nil {
+			log.Errorf("remove snapshot %s of %s error: %v", snapshotName, d.Id, err)
+		}
+	}()
+	if err := driver.CloneSnapshot(d.Id, snapshotName, imageName); err != nil {
+		return nil, errors.Wrapf(err, "clone snapshot %s of %s", snapshotName, d.Id)
+	}
+	return jsonutils.Marshal(map[string]string{"backup": imageName}), nil
+}
+
+func (d *SBlockDisk) CleanupSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, fmt.Errorf("Not support")
+}
+
+func (d *SBlockDisk) PrepareMigrate(liveMigrate bool) (string, error) {
+	if !liveMigrate {
+		return "", fmt.Errorf("%s disk only support live migrate", d.GetType())
+	}
+	return "", nil
+}
+
+func (d *SBlockDisk) CreateFromTemplate(ctx context.Context, imageId string, format string, size int64) (jsonutils.JSONObject, error) {
+	ret, err := d.createFromTemplate(ctx, imageId, format)
+	if err != nil {
+		return nil, err
+	}
+
+	retSize, _ := ret.Int("disk_size")
+	log.Infof("REQSIZE: %d, RETSIZE: %d", size, retSize)
+	if size > retSize {
+		params := jsonutils.NewDict()
+		params.Set("size", jsonutils.NewInt(size))
+		return d.Resize(ctx, params)
+	}
+
+	return ret, nil
+}
+
+func (d *SBlockDisk) createFromTemplate(ctx context.Context, imageId, format string) (jsonutils.JSONObject, error) {
+	var imageCacheManager = storageManager.GetStoragecacheById(d.Storage.GetStoragecacheId())
+	if imageCacheManager == nil {
+		return nil, fmt.Errorf("failed to find image cache manger for storage %s", d.Storage.GetStorageName())
+	}
+	input := api.CacheImageInput{
+		ImageId: imageId,
+		Zone:    d.GetZoneName(),
+	}
+	imageCache, err := imageCacheManager.AcquireImage(ctx, input, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "AcquireImage")
+	}
+
+	defer imageCacheManager.ReleaseImage(ctx, imageId)
+
+	driver := d.getDriver()
+	if err := driver.RemoveVolume(d.Id); err != nil {
+		return nil, errors.Wrapf(err, "remove old volume %s", d.Id)
+	}
+	if err := driver.CloneVolume(imageCache.GetName(), d.Id); err != nil {
+		return nil, errors.Wrapf(err, "CloneVolume(%s)", imageCache.GetName())
+	}
+	return d.GetDiskDesc(), nil
+}
+
+// createFromSnapshot clones the snapshot of a disk on the same storage
+func (d *SBlockDisk) createFromSnapshot(srcDiskId, snapshotId string, sizeMb int64) error {
+	driver := d.getDriver()
+	if err := driver.CloneSnapshot(srcDiskId, snapshotId, d.Id); err != nil {
+		return errors.Wrapf(err, "clone snapshot %s of %s", snapshotId, srcDiskId)
+	}
+	if err := d.resizeCloned(sizeMb); err != nil {
+		if err := driver.RemoveVolume(d.Id); err != nil {
+			log.Errorf("remove volume %s error: %v", d.Id, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// resizeCloned grows the volume cloned from a smaller snapshot
+func (d *SBlockDisk) resizeCloned(sizeMb int64) error {
+	if sizeMb <= 0 {
+		return nil
+	}
+	driver := d.getDriver()
+	curSizeMb, err := driver.GetVolumeSizeMb(d.Id)
+	if err != nil {
+		return errors.Wrapf(err, "GetVolumeSizeMb")
+	}
+	if sizeMb > curSizeMb {
+		if err := driver.ResizeVolume(d.Id, sizeMb); err != nil {
+			return errors.Wrapf(err, "resize volume %s", d.Id)
+		}
+	}
+	return nil
+}
+
+func (d *SBlockDisk) CreateFromImageFuse(ctx context.Context, url string, size int64) error {
+	return fmt.Errorf("Not support")
+}
+
+func (d *SBlockDisk) CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string, encryption bool, diskId string, back string) (jsonutils.JSONObject, error) {
+	if err := d.getDriver().CreateVolume(d.Id, int64(sizeMb)); err != nil {
+		return nil, err
+	}
+
+	if utils.IsInStringArray(fsFormat, []string{"swap", "ext2", "ext3", "ext4", "xfs"}) {
+		d.FormatFs(fsFormat, diskId, d.GetPath())
+	}
+
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SBlockDisk) PostCreateFromImageFuse() {
+	log.Errorf("Not support PostCreateFromImageFuse")
+}
+
+func (d *SBlockDisk) CreateSnapshot(snapshotId string) error {
+	return d.getDriver().CreateSnapshot(d.Id, snapshotId)
+}
+
+func (d *SBlockDisk) DeleteSnapshot(snapshotId, convertSnapshot string, pendingDelete bool) error {
+	return d.getDriver().RemoveSnapshot(d.Id, snapshotId)
+}
+
+func (d *SBlockDisk) DiskSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	snapshotId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	return nil, d.CreateSnapshot(snapshotId)
+}
+
+func (d *SBlockDisk) DiskDeleteSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	snapshotId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	err := d.DeleteSnapshot(snapshotId, "", false)
+	if err != nil {
+		return nil, err
+	} else {
+		res := jsonutils.NewDict()
+		res.Set("deleted", jsonutils.JSONTrue)
+		return res, nil
+	}
+}
+
+func (d *SBlockDisk) ResetFromSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	resetParams, ok := params.(*SDiskReset)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	if err := d.getDriver().RollbackSnapshot(d.Id, resetParams.SnapshotId); err != nil {
+		return nil, errors.Wrapf(err, "rollback %s to snapshot %s", d.Id, resetParams.SnapshotId)
+	}
+	return nil, nil
+}
@@ -0,0 +1,376 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+)
+
+type SSLVMDisk struct {
+	SBaseDisk
+}
+
+func NewSLVMDisk(storage IStorage, id string) *SSLVMDisk {
+	var ret = new(SSLVMDisk)
+	ret.SBaseDisk = *NewBaseDisk(storage, id)
+	return ret
+}
+
+func (d *SSLVMDisk) getStorage() *SSLVMStorage {
+	return d.Storage.(*SSLVMStorage)
+}
+
+func (d *SSLVMDisk) GetType() string {
+	return api.STORAGE_SLVM
+}
+
+func (d *SSLVMDisk) Probe() error {
+	exist, err := d.getStorage().isLvExist(d.Id)
+	if err != nil {
+		return errors.Wrapf(err, "isLvExist")
+	}
+	if !exist {
+		return cloudprovider.ErrNotFound
+	}
+	return nil
+}
+
+func (d *SSLVMDisk) GetPath() string {
+	return d.getStorage().getLvPath(d.Id)
+}
+
+func (d *SSLVMDisk) GetSnapshotDir() string {
+	return ""
+}
+
+func (d *SSLVMDisk) GetDiskDesc() jsonutils.JSONObject {
+	sizeMb, _ := d.getStorage().getLvSizeMb(d.Id)
+	desc := map[string]interface{}{
+		"disk_id":     d.Id,
+		"disk_format": "raw",
+		"disk_path":   d.GetPath(),
+		"disk_size":   sizeMb,
+	}
+	return jsonutils.Marshal(desc)
+}
+
+// GetDiskSetupScripts activates the logical volume exclusively before qemu
+// starts, qemu never starts if the volume is still active on another host
+func (d *SSLVMDisk) GetDiskSetupScripts(idx int) string {
+	cmd := ""
+	cmd += fmt.Sprintf("lvchange -aey %s || exit 1\n", d.getStorage().lvFullName(d.Id))
+	cmd += fmt.Sprintf("DISK_%d=%s\n", idx, d.GetPath())
+	return cmd
+}
+
+// GetMigrateDiskSetupScripts activates the logical volume in shared mode
+// before the incoming qemu of live migration starts, the source host has
+// converted its lock to shared in PrepareMigrate
+func (d *SSLVMDisk) GetMigrateDiskSetupScripts(idx int) string {
+	cmd := ""
+	cmd += fmt.Sprintf("lvchange -asy %s || exit 1\n", d.getStorage().lvFullName(d.Id))
+	cmd += fmt.Sprintf("DISK_%d=%s\n", idx, d.GetPath())
+	return cmd
+}
+
+// GetDiskTeardownScripts deactivates the logical volume after qemu exits
+func (d *SSLVMDisk) GetDiskTeardownScripts() string {
+	return fmt.Sprintf("lvchange -an %s\n", d.getStorage().lvFullName(d.Id))
+}
+
+// ActivateExclusive takes back the exclusive lock after live migration, it
+// fails while the other host still holds the shared lock
+func (d *SSLVMDisk) ActivateExclusive() error {
+	return d.getStorage().activateLv(d.Id, false)
+}
+
+// Deactivate releases the lock of the volume, after the guest is stopped or
+// migrated to another host
+func (d *SSLVMDisk) Deactivate() error {
+	return d.getStorage().deactivateLv(d.Id)
+}
+
+func (d *SSLVMDisk) DeleteAllSnapshot(skipRecycle bool) error {
+	return d.getStorage().deleteDiskSnapshots(d.Id)
+}
+
+func (d *SSLVMDisk) Delete(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	storage := d.getStorage()
+	if err := storage.deactivateLv(d.Id); err != nil {
+		log.Warningf("deactivate %s error: %v", d.Id, err)
+	}
+	if err := storage.removeLv(d.Id); err != nil {
+		return nil, err
+	}
+	storage.RemoveDisk(d)
+	return nil, nil
+}
+
+func (d *SSLVMDisk) OnRebuildRoot(ctx context.Context, params api.DiskAllocateInput) error {
+	// snapshots are full copies, so the old root can be removed directly
+	_, err := d.Delete(ctx, api.DiskDeleteInput{})
+	return err
+}
+
+func (d *SSLVMDisk) Resize(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	diskInfo, ok := params.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	storage := d.getStorage()
+	sizeMb, _ := diskInfo.Int("size")
+	if err := storage.resizeLv(d.Id, sizeMb); err != nil {
+		return nil, err
+	}
+
+	err := storage.withActiveLv(d.Id, false, func(lvPath string) error {
+		return d.ResizeFs(lvPath)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "resize fs %s", d.GetPath())
+	}
+
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SSLVMDisk) PrepareSaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	if err := d.Probe(); err != nil {
+		return nil, err
+	}
+	imageName := fmt.Sprintf("%s%s_%s", SLVM_IMAGECACHE_PREFIX, d.Id, appctx.AppContextTaskId(ctx))
+	if err := d.getStorage().copyLv(d.Id, imageName); err != nil {
+		log.Errorf("copy %s to %s error: %v", d.Id, imageName, err)
+		return nil, err
+	}
+	return jsonutils.Marshal(map[string]string{"backup": imageName}), nil
+}
+
+func (d *SSLVMDisk) CleanupSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, fmt.Errorf("Not support")
+}
+
+// PrepareMigrate converts the exclusive lock of the volume to shared on the
+// source host, so the destination host can open it for live migration. No
+// data is moved, the volume group is visible on all hosts
+func (d *SSLVMDisk) PrepareMigrate(liveMigrate bool) (string, error) {
+	if liveMigrate {
+		if err := d.getStorage().activateLv(d.Id, true); err != nil {
+			return "", errors.Wrapf(err, "activate %s shared", d.Id)
+		}
+	}
+	return "", nil
+}
+
+func (d *SSLVMDisk) CreateFromTemplate(ctx context.Context, imageId string, format string, size int64) (jsonutils.JSONObject, error) {
+	ret, err := d.createFromTemplate(ctx, imageId, format)
+	if err != nil {
+		return nil, err
+	}
+
+	retSize, _ := ret.Int("disk_size")
+	log.Infof("REQSIZE: %d, RETSIZE: %d", size, retSize)
+	if size > retSize {
+		params := jsonutils.NewDict()
+		params.Set("size", jsonutils.NewInt(size))
+		return d.Resize(ctx, params)
+	}
+
+	return ret, nil
+}
+
+func (d *SSLVMDisk) createFromTemplate(ctx context.Context, imageId, format string) (jsonutils.JSONObject, error) {
+	var imageCacheManager = storageManager.GetStoragecacheById(d.Storage.GetStoragecacheId())
+	if imageCacheManager == nil {
+		return nil, fmt.Errorf("failed to find image cache manger for storage %s", d.Storage.GetStorageName())
+	}
+	input := api.CacheImageInput{
+		ImageId: imageId,
+		Zone:    d.GetZoneName(),
+	}
+	imageCache, err := imageCacheManager.AcquireImage(ctx, input, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "AcquireImage")
+	}
+
+	defer imageCacheManager.ReleaseImage(ctx, imageId)
+
+	storage := d.getStorage()
+	if err := storage.removeLv(d.Id); err != nil {
+		return nil, errors.Wrapf(err, "remove old lv %s", d.Id)
+	}
+	if err := storage.copyLv(imageCache.GetName(), d.Id); err != nil {
+		return nil, errors.Wrapf(err, "copyLv(%s)", imageCache.GetName())
+	}
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SSLVMDisk) createFromSnapshot(srcVg, srcDiskId, snapshotId string, sizeMb int64) error {
+	storage := d.getStorage()
+	snapshotName := storage.getSnapshotName(srcDiskId, snapshotId)
+	if len(srcVg) == 0 || srcVg == storage.VgName {
+		if err := storage.copyLv(snapshotName, d.Id); err != nil {
+			return errors.Wrapf(err, "copyLv(%s)", snapshotName)
+		}
+	} else {
+		srcStorage := getSLVMStorageByVg(srcVg)
+		if srcStorage == nil {
+			return fmt.Errorf("shared lvm storage of vg %s not attached", srcVg)
+		}
+		snapSizeMb, err := srcStorage.getLvSizeMb(snapshotName)
+		if err != nil {
+			return errors.Wrapf(err, "get snapshot %s size", snapshotName)
+		}
+		if err := storage.createLv(d.Id, snapSizeMb); err != nil {
+			return errors.Wrapf(err, "create lv %s", d.Id)
+		}
+		err = srcStorage.withActiveLv(snapshotName, true, func(snapPath string) error {
+			return storage.convertToLv(snapPath, "raw", d.Id)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "copy snapshot %s from vg %s", snapshotName, srcVg)
+		}
+	}
+	if sizeMb > 0 {
+		curSizeMb, err := storage.getLvSizeMb(d.Id)
+		if err != nil {
+			return errors.Wrapf(err, "getLvSizeMb")
+		}
+		if sizeMb > curSizeMb {
+			return storage.resizeLv(d.Id, sizeMb)
+		}
+	}
+	return nil
+}
+
+func (d *SSLVMDisk) CreateFromImageFuse(ctx context.Context, url string, size int64) error {
+	return fmt.Errorf("Not support")
+}
+
+func (d *SSLVMDisk) CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string, encryption bool, diskId string, back string) (jsonutils.JSONObject, error) {
+	storage := d.getStorage()
+	if err := storage.createLv(d.Id, int64(sizeMb)); err != nil {
+		return nil, err
+	}
+
+	if utils.IsInStringArray(fsFormat, []string{"swap", "ext2", "ext3", "ext4", "xfs"}) {
+		err := storage.withActiveLv(d.Id, false, func(lvPath string) error {
+			d.FormatFs(fsFormat, diskId, lvPath)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SSLVMDisk) PostCreateFromImageFuse() {
+	log.Errorf("Not support PostCreateFromImageFuse")
+}
+
+// CreateSnapshot copies the disk to a new volume. The volume of a running guest
+// is exclusively active on this host, it is copied from a temporary
+// copy-on-write snapshot, so the guest keeps running and the copy is crash
+// consistent. Snapshots are not possible while the volume is shared by two
+// hosts during live migration
+func (d *SSLVMDisk) CreateSnapshot(snapshotId string) error {
+	storage := d.getStorage()
+	snapshotName := storage.getSnapshotName(d.Id, snapshotId)
+	active, err := storage.isLvActive(d.Id)
+	if err != nil {
+		return errors.Wrapf(err, "check %s active", d.Id)
+	}
+	if !active {
+		return storage.copyLv(d.Id, snapshotName)
+	}
+
+	cowName := storage.getCowSnapshotName(d.Id, snapshotId)
+	if err := storage.createCowSnapshotLv(d.Id, cowName); err != nil {
+		return errors.Wrapf(err, "create cow snapshot of %s, the volume must be exclusively active on this host", d.Id)
+	}
+	defer func() {
+		if err := storage.removeLv(cowName); err != nil {
+			log.Errorf("remove cow snapshot %s error: %v", cowName, err)
+		}
+	}()
+	return storage.copyLv(cowName, snapshotName)
+}
+
+func (d *SSLVMDisk) DeleteSnapshot(snapshotId, convertSnapshot string, pendingDelete bool) error {
+	storage := d.getStorage()
+	return storage.removeLv(storage.getSnapshotName(d.Id, snapshotId))
+}
+
+func (d *SSLVMDisk) DiskSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	snapshotId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	return nil, d.CreateSnapshot(snapshotId)
+}
+
+func (d *SSLVMDisk) DiskDeleteSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	snapshotId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	err := d.DeleteSnapshot(snapshotId, "", false)
+	if err != nil {
+		return nil, err
+	} else {
+		res := jsonutils.NewDict()
+		res.Set("deleted", jsonutils.JSONTrue)
+		return res, nil
+	}
+}
+
+// ResetFromSnapshot replaces the disk volume with a copy of the snapshot volume
+func (d *SSLVMDisk) ResetFromSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	resetParams, ok := params.(*SDiskReset)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	storage := d.getStorage()
+	snapshotName := storage.getSnapshotName(d.Id, resetParams.SnapshotId)
+	exist, err := storage.isLvExist(snapshotName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "isLvExist")
+	}
+	if !exist {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "snapshot %s", snapshotName)
+	}
+	if err := storage.deactivateLv(d.Id); err != nil {
+		return nil, errors.Wrapf(err, "deactivate %s", d.Id)
+	}
+	if err := storage.removeLv(d.Id); err != nil {
+		return nil, errors.Wrapf(err, "remove %s", d.Id)
+	}
+	if err := storage.copyLv(snapshotName, d.Id); err != nil {
+		return nil, errors.Wrapf(err, "copyLv(%s)", snapshotName)
+	}
+	return nil, nil
+}
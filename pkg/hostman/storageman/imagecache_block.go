@@ -0,0 +1,152 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/remotefile"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+type SBlockImageCache struct {
+	imageId   string
+	imageName string
+	cond      *sync.Cond
+	Manager   IImageCacheManger
+}
+
+func NewBlockImageCache(imageId string, imagecacheManager IImageCacheManger) *SBlockImageCache {
+	imageCache := new(SBlockImageCache)
+	imageCache.imageId = imageId
+	imageCache.Manager = imagecacheManager
+	imageCache.cond = sync.NewCond(new(sync.Mutex))
+	return imageCache
+}
+
+func (r *SBlockImageCache) getStorage() *SBlockStorage {
+	return r.Manager.(*SBlockImageCacheManager).storage
+}
+
+func (r *SBlockImageCache) getDriver() IBlockVolumeDriver {
+	return r.Manager.(*SBlockImageCacheManager).getDriver()
+}
+
+func (r *SBlockImageCache) GetName() string {
+	return fmt.Sprintf("%s%s", BLOCK_IMAGECACHE_PREFIX, r.imageId)
+}
+
+func (r *SBlockImageCache) GetPath() string {
+	return r.getDriver().GetVolumePath(r.GetName())
+}
+
+func (r *SBlockImageCache) Load() error {
+	log.Debugf("loading %s imagecache %s", r.getStorage().StorageType(), r.GetPath())
+	exist, err := r.getDriver().IsVolumeExist(r.GetName())
+	if err != nil {
+		return errors.Wrapf(err, "IsVolumeExist at host %s", options.HostOptions.Hostname)
+	}
+	if !exist {
+		return errors.Wrapf(cloudprovider.ErrNotFound, "imagecache %s at host %s", r.GetPath(), options.HostOptions.Hostname)
+	}
+	return nil
+}
+
+func (r *SBlockImageCache) Acquire(ctx context.Context, input api.CacheImageInput, callback func(progress, progressMbps float64, totalSizeMb int64)) error {
+	input.ImageId = r.imageId
+	localImageCache, err := storageManager.LocalStorageImagecacheManager.AcquireImage(ctx, input, func(progress, progressMbps float64, totalSizeMb int64) {
+		if len(input.ServerId) > 0 {
+			hostutils.UpdateServerProgress(context.Background(), input.ServerId, progress/1.2, progressMbps)
+		}
+	})
+	if err != nil {
+		return errors.Wrapf(err, "LocalStorage.AcquireImage")
+	}
+	r.imageName = localImageCache.GetName()
+	if r.Load() != nil {
+		storage, driver := r.getStorage(), r.getDriver()
+		log.Infof("convert local image %s to %s storage %s", r.imageId, storage.StorageType(), driver.GetPoolName())
+		origin, err := qemuimg.NewQemuImage(localImageCache.GetPath())
+		if err != nil {
+			return errors.Wrapf(err, "NewQemuImage(%s)", localImageCache.GetPath())
+		}
+		// create under a temporary name, a half converted volume must never be taken as cached image
+		tmpName := r.GetName() + "_tmp"
+		if err := driver.RemoveVolume(tmpName); err != nil {
+			return errors.Wrapf(err, "remove %s", tmpName)
+		}
+		if err := driver.CreateVolume(tmpName, int64(origin.GetSizeMB())); err != nil {
+			return errors.Wrapf(err, "create volume %s", tmpName)
+		}
+		if err := storage.convertToVolume(localImageCache.GetPath(), origin.Format.String(), tmpName); err != nil {
+			driver.RemoveVolume(tmpName)
+			return errors.Wrapf(err, "convert local image %s to %s at host %s", r.imageId, driver.GetPoolName(), options.HostOptions.Hostname)
+		}
+		if err := driver.RenameVolume(tmpName, r.GetName()); err != nil {
+			return errors.Wrapf(err, "rename %s", tmpName)
+		}
+		// base volumes are shared by disks cloned from them, never write them
+		if err := driver.SetVolumeReadonly(r.GetName()); err != nil {
+			return errors.Wrapf(err, "set %s readonly", r.GetName())
+		}
+		if len(input.ServerId) > 0 {
+			modules.Servers.Update(hostutils.GetComputeSession(context.Background()), input.ServerId, jsonutils.Marshal(map[string]float32{"progress": 100.0}))
+		}
+	}
+	return r.Load()
+}
+
+func (r *SBlockImageCache) Release() {
+	return
+}
+
+func (r *SBlockImageCache) Remove(ctx context.Context) error {
+	// zfs refuses to destroy the image while disks are still cloned from it
+	if err := r.getDriver().RemoveVolume(r.GetName()); err != nil {
+		return err
+	}
+
+	go func() {
+		_, err := modules.Storagecachedimages.Detach(hostutils.GetComputeSession(ctx),
+			r.Manager.GetId(), r.imageId, nil)
+		if err != nil {
+			log.Errorf("Fail to delete host cached image: %s", err)
+		}
+	}()
+	return nil
+}
+
+func (r *SBlockImageCache) GetDesc() *remotefile.SImageDesc {
+	size, _ := r.getDriver().GetVolumeSizeMb(r.GetName())
+	return &remotefile.SImageDesc{
+		Size: size,
+		Name: r.imageName,
+	}
+}
+
+func (r *SBlockImageCache) GetImageId() string {
+	return r.imageId
+}
@@ -30,12 +30,15 @@ import (
 
 	"yunion.io/x/onecloud/pkg/apis"
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	imageapi "yunion.io/x/onecloud/pkg/apis/image"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman/remotefile"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/imagesign"
 	"yunion.io/x/onecloud/pkg/util/qemuimg"
 )
 
@@ -169,25 +172,38 @@ func (l *SLocalImageCache) prepare(ctx context.Context, input api.CacheImageInpu
 	}
 
 	if l.remoteFile == nil && l.Desc != nil && (l.consumerCount > 0 || !l.needCheck()) {
+		// the image may have been cached for an owner of a looser policy
+		err := checkImageSignaturePolicy(l.imageId, input.SignaturePolicy, l.Desc.SignatureStatus)
+		if err != nil {
+			return false, err
+		}
 		l.consumerCount++
 		return true, nil
 	}
-	url, err := auth.GetServiceURL(apis.SERVICE_TYPE_IMAGE, "", input.Zone, "")
-	if err != nil {
-		return false, errors.Wrapf(err, "GetServiceURL(%s)", apis.SERVICE_TYPE_IMAGE)
-	}
-	url += fmt.Sprintf("/images/%s", l.imageId)
 	if len(input.Format) == 0 {
 		input.Format = "qcow2"
 	}
-	url += fmt.Sprintf("?format=%s&scope=system", input.Format)
+	url, err := l.getImageUrl(input.Zone, input.Format)
+	if err != nil {
+		return false, err
+	}
 
 	l.remoteFile = remotefile.NewRemoteFile(ctx, url,
 		l.GetPath(), false, input.Checksum, -1, nil, l.GetTmpPath(), input.SrcUrl)
 	return false, nil
 }
 
+func (l *SLocalImageCache) getImageUrl(zone, format string) (string, error) {
+	url, err := auth.GetServiceURL(apis.SERVICE_TYPE_IMAGE, "", zone, "")
+	if err != nil {
+		return "", errors.Wrapf(err, "GetServiceURL(%s)", apis.SERVICE_TYPE_IMAGE)
+	}
+	return url + fmt.Sprintf("/images/%s?format=%s&scope=system", l.imageId, format), nil
+}
+
 func (l *SLocalImageCache) fetch(ctx context.Context, input api.CacheImageInput, callback func(progress, progressMbps float64, totalSizeMb int64)) error {
+	// signature of the previously cached file, persisted in the .inf desc
+	cachedDesc := l.Desc
 	var _fetch = func() error {
 		if len(l.Manager.GetId()) > 0 {
 			_, err := hostutils.RemoteStoragecacheCacheImage(ctx,
@@ -204,6 +220,7 @@ func (l *SLocalImageCache) fetch(ctx context.Context, input api.CacheImageInput,
 		if err != nil {
 			return errors.Wrapf(err, "remoteFile.GetInfo")
 		}
+		mergeSignatureDesc(l.Desc, cachedDesc)
 
 		l.Size = l.GetSize() / 1024 / 1024
 		l.Desc.Id = l.imageId
@@ -223,14 +240,147 @@ func (l *SLocalImageCache) fetch(ctx context.Context, input api.CacheImageInput,
 		}
 		return nil
 	}
-	if fileutils2.Exists(l.GetPath()) && l.remoteFile.VerifyIntegrity(callback) == nil {
+	var _verify = func() error {
+		err := l.verifySignature(ctx, input, cachedDesc)
+		if err != nil {
+			l.cond.L.Lock()
+			defer l.cond.L.Unlock()
+
+			l.remoteFile = nil
+			l.cond.Broadcast()
+			if fileutils2.Exists(l.GetPath()) {
+				syscall.Unlink(l.GetPath())
+			}
+			return errors.Wrapf(err, "verifySignature")
+		}
 		return _fetch()
 	}
+	if fileutils2.Exists(l.GetPath()) && l.remoteFile.VerifyIntegrity(callback) == nil {
+		return _verify()
+	}
 	err := l.remoteFile.Fetch(callback)
 	if err != nil {
 		return errors.Wrapf(err, "remoteFile.Fetch")
 	}
-	return _fetch()
+	return _verify()
+}
+
+// mergeSignatureDesc fills the signature of desc from the cached desc of the
+// same content, when it could not be fetched from glance
+func mergeSignatureDesc(desc, cachedDesc *remotefile.SImageDesc) {
+	if len(desc.SignatureStatus) > 0 || cachedDesc == nil || cachedDesc.Chksum != desc.Chksum {
+		return
+	}
+	desc.Signature = cachedDesc.Signature
+	desc.SignatureCert = cachedDesc.SignatureCert
+	desc.SignatureStatus = cachedDesc.SignatureStatus
+	desc.SignatureDigest = cachedDesc.SignatureDigest
+	desc.SignatureFormat = cachedDesc.SignatureFormat
+}
+
+// isPermissiveSignaturePolicy tells whether images of any signature status
+// may be used, the same as region does for the empty default
+func isPermissiveSignaturePolicy(policy string) bool {
+	return len(policy) == 0 || policy == imageapi.IMAGE_SIGNATURE_POLICY_PERMISSIVE
+}
+
+// checkImageSignaturePolicy checks signature status against the policy of
+// disk owner, an unknown status is never taken as signed
+func checkImageSignaturePolicy(imageId, policy, status string) error {
+	switch policy {
+	case imageapi.IMAGE_SIGNATURE_POLICY_REQUIRE_SIGNED:
+		if status != imagesign.STATUS_VERIFIED && status != imagesign.STATUS_UNTRUSTED {
+			return errors.Errorf("image %s is not signed: %q", imageId, status)
+		}
+	case imageapi.IMAGE_SIGNATURE_POLICY_REQUIRE_TRUSTED:
+		if status != imagesign.STATUS_VERIFIED {
+			return errors.Errorf("image %s is not signed by a trusted signer: %q", imageId, status)
+		}
+	}
+	return nil
+}
+
+// verifySignature re-verifies the detached signature of a signed image
+// before any disk is created from it, unless the policy of disk owner is
+// permissive, which accepts invalid and unverified signatures as region does
+func (l *SLocalImageCache) verifySignature(ctx context.Context, input api.CacheImageInput, cachedDesc *remotefile.SImageDesc) error {
+	if isPermissiveSignaturePolicy(input.SignaturePolicy) {
+		return nil
+	}
+	desc, err := l.remoteFile.GetInfo()
+	if err != nil {
+		return errors.Wrapf(err, "remoteFile.GetInfo")
+	}
+	if len(desc.SignatureStatus) == 0 {
+		// properties are not fetched if the local file matches the checksum,
+		// or the file is downloaded from another host
+		if err := l.remoteFile.FetchProperties(); err != nil {
+			log.Warningf("fetch properties of image %s: %v", l.imageId, err)
+		}
+		desc, err = l.remoteFile.GetInfo()
+		if err != nil {
+			return errors.Wrapf(err, "remoteFile.GetInfo")
+		}
+		mergeSignatureDesc(desc, cachedDesc)
+	}
+	if err := checkImageSignaturePolicy(l.imageId, input.SignaturePolicy, desc.SignatureStatus); err != nil {
+		return err
+	}
+	switch desc.SignatureStatus {
+	case "", imagesign.STATUS_UNSIGNED:
+		return nil
+	case imagesign.STATUS_INVALID:
+		return errors.Errorf("image %s has invalid signature", l.imageId)
+	}
+	signedPath := l.GetPath()
+	if len(desc.SignatureFormat) > 0 && desc.SignatureFormat != desc.Format {
+		// the cached subformat is converted from the signed format by glance,
+		// so the signed file is fetched to verify it
+		signedPath, err = l.fetchSignedFormat(ctx, input.Zone, desc.SignatureFormat)
+		if len(signedPath) > 0 {
+			defer os.Remove(signedPath)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "image %s is signed in format %s", l.imageId, desc.SignatureFormat)
+		}
+	}
+	trustConfigured := len(options.HostOptions.ImageSignatureTrustedCaFile) > 0 || len(options.HostOptions.ImageSignatureTrustedKeysFile) > 0
+	var store *imagesign.STrustStore
+	if trustConfigured {
+		store, err = imagesign.NewTrustStore(options.HostOptions.ImageSignatureTrustedCaFile, options.HostOptions.ImageSignatureTrustedKeysFile)
+		if err != nil {
+			return errors.Wrap(err, "NewTrustStore")
+		}
+	}
+	result, err := imagesign.VerifyFile(signedPath, desc.Signature, desc.SignatureCert, store)
+	if err != nil {
+		return errors.Wrapf(err, "verify signature of image %s", l.imageId)
+	}
+	if len(desc.SignatureDigest) > 0 && result.Digest != desc.SignatureDigest {
+		return errors.Errorf("image %s digest %s mismatch with signed digest %s", l.imageId, result.Digest, desc.SignatureDigest)
+	}
+	// without local trust anchors, rely on the signer trust decided by glance
+	if trustConfigured && result.Status != desc.SignatureStatus {
+		return errors.Errorf("image %s signature is %s on host, but %s in glance", l.imageId, result.Status, desc.SignatureStatus)
+	}
+	return nil
+}
+
+// fetchSignedFormat downloads the image in the format it is signed in next
+// to the cached file, the caller removes it after verification
+func (l *SLocalImageCache) fetchSignedFormat(ctx context.Context, zone, format string) (string, error) {
+	url, err := l.getImageUrl(zone, format)
+	if err != nil {
+		return "", err
+	}
+	signedPath := fmt.Sprintf("%s.%s", l.GetPath(), format)
+	tmpPath := signedPath + _TMP_SUFFIX_
+	defer os.Remove(tmpPath)
+	remoteFile := remotefile.NewRemoteFile(ctx, url, signedPath, false, "", -1, nil, tmpPath, "")
+	if err := remoteFile.Fetch(nil); err != nil {
+		return signedPath, errors.Wrapf(err, "fetch %s", url)
+	}
+	return signedPath, nil
 }
 
 func (l *SLocalImageCache) Remove(ctx context.Context) error {
@@ -0,0 +1,71 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	imageapi "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/util/imagesign"
+)
+
+func TestCheckImageSignaturePolicy(t *testing.T) {
+	statuses := []string{
+		"",
+		imagesign.STATUS_UNSIGNED,
+		imageapi.IMAGE_SIGNATURE_STATUS_UNVERIFIED,
+		imagesign.STATUS_INVALID,
+		imagesign.STATUS_UNTRUSTED,
+		imagesign.STATUS_VERIFIED,
+	}
+	allowed := map[string][]string{
+		"": statuses,
+		imageapi.IMAGE_SIGNATURE_POLICY_PERMISSIVE: statuses,
+		imageapi.IMAGE_SIGNATURE_POLICY_REQUIRE_SIGNED: {
+			imagesign.STATUS_UNTRUSTED,
+			imagesign.STATUS_VERIFIED,
+		},
+		imageapi.IMAGE_SIGNATURE_POLICY_REQUIRE_TRUSTED: {
+			imagesign.STATUS_VERIFIED,
+		},
+	}
+	for policy, okStatuses := range allowed {
+		for _, status := range statuses {
+			wantOk := false
+			for _, ok := range okStatuses {
+				if ok == status {
+					wantOk = true
+				}
+			}
+			err := checkImageSignaturePolicy("img", policy, status)
+			if (err == nil) != wantOk {
+				t.Errorf("policy %q status %q got %v, want allowed %v", policy, status, err, wantOk)
+			}
+		}
+	}
+}
+
+func TestVerifySignaturePermissive(t *testing.T) {
+	// permissive owners are never refused, the remote file is not even looked at
+	l := &SLocalImageCache{imageId: "img"}
+	for _, policy := range []string{"", imageapi.IMAGE_SIGNATURE_POLICY_PERMISSIVE} {
+		input := api.CacheImageInput{SignaturePolicy: policy}
+		if err := l.verifySignature(context.Background(), input, nil); err != nil {
+			t.Errorf("policy %q got %v", policy, err)
+		}
+	}
+}
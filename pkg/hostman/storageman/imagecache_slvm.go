@@ -0,0 +1,143 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/remotefile"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+type SSLVMImageCache struct {
+	imageId   string
+	imageName string
+	cond      *sync.Cond
+	Manager   IImageCacheManger
+}
+
+func NewSLVMImageCache(imageId string, imagecacheManager IImageCacheManger) *SSLVMImageCache {
+	imageCache := new(SSLVMImageCache)
+	imageCache.imageId = imageId
+	imageCache.Manager = imagecacheManager
+	imageCache.cond = sync.NewCond(new(sync.Mutex))
+	return imageCache
+}
+
+func (r *SSLVMImageCache) getStorage() *SSLVMStorage {
+	return r.Manager.(*SSLVMImageCacheManager).getStorage()
+}
+
+func (r *SSLVMImageCache) GetName() string {
+	return fmt.Sprintf("%s%s", SLVM_IMAGECACHE_PREFIX, r.imageId)
+}
+
+func (r *SSLVMImageCache) GetPath() string {
+	return r.getStorage().getLvPath(r.GetName())
+}
+
+func (r *SSLVMImageCache) Load() error {
+	log.Debugf("loading shared lvm imagecache %s", r.GetPath())
+	exist, err := r.getStorage().isLvExist(r.GetName())
+	if err != nil {
+		return errors.Wrapf(err, "isLvExist at host %s", options.HostOptions.Hostname)
+	}
+	if !exist {
+		return errors.Wrapf(cloudprovider.ErrNotFound, "imagecache %s at host %s", r.GetPath(), options.HostOptions.Hostname)
+	}
+	return nil
+}
+
+func (r *SSLVMImageCache) Acquire(ctx context.Context, input api.CacheImageInput, callback func(progress, progressMbps float64, totalSizeMb int64)) error {
+	input.ImageId = r.imageId
+	localImageCache, err := storageManager.LocalStorageImagecacheManager.AcquireImage(ctx, input, func(progress, progressMbps float64, totalSizeMb int64) {
+		if len(input.ServerId) > 0 {
+			hostutils.UpdateServerProgress(context.Background(), input.ServerId, progress/1.2, progressMbps)
+		}
+	})
+	if err != nil {
+		return errors.Wrapf(err, "LocalStorage.AcquireImage")
+	}
+	r.imageName = localImageCache.GetName()
+	if r.Load() != nil {
+		storage := r.getStorage()
+		log.Infof("convert local image %s to shared lvm vg %s", r.imageId, storage.VgName)
+		origin, err := qemuimg.NewQemuImage(localImageCache.GetPath())
+		if err != nil {
+			return errors.Wrapf(err, "NewQemuImage(%s)", localImageCache.GetPath())
+		}
+		// create under a temporary name, a half converted volume must never be taken as cached image
+		tmpName := r.GetName() + "_tmp"
+		if err := storage.removeLv(tmpName); err != nil {
+			return errors.Wrapf(err, "remove %s", tmpName)
+		}
+		if err := storage.createLv(tmpName, int64(origin.GetSizeMB())); err != nil {
+			return errors.Wrapf(err, "create lv %s", tmpName)
+		}
+		if err := storage.convertToLv(localImageCache.GetPath(), origin.Format.String(), tmpName); err != nil {
+			storage.removeLv(tmpName)
+			return errors.Wrapf(err, "convert local image %s to vg %s at host %s", r.imageId, storage.VgName, options.HostOptions.Hostname)
+		}
+		if err := storage.renameLv(tmpName, r.GetName()); err != nil {
+			return errors.Wrapf(err, "rename %s", tmpName)
+		}
+		if len(input.ServerId) > 0 {
+			modules.Servers.Update(hostutils.GetComputeSession(context.Background()), input.ServerId, jsonutils.Marshal(map[string]float32{"progress": 100.0}))
+		}
+	}
+	return r.Load()
+}
+
+func (r *SSLVMImageCache) Release() {
+	return
+}
+
+func (r *SSLVMImageCache) Remove(ctx context.Context) error {
+	if err := r.getStorage().removeLv(r.GetName()); err != nil {
+		return err
+	}
+
+	go func() {
+		_, err := modules.Storagecachedimages.Detach(hostutils.GetComputeSession(ctx),
+			r.Manager.GetId(), r.imageId, nil)
+		if err != nil {
+			log.Errorf("Fail to delete host cached image: %s", err)
+		}
+	}()
+	return nil
+}
+
+func (r *SSLVMImageCache) GetDesc() *remotefile.SImageDesc {
+	size, _ := r.getStorage().getLvSizeMb(r.GetName())
+	return &remotefile.SImageDesc{
+		Size: size,
+		Name: r.imageName,
+	}
+}
+
+func (r *SSLVMImageCache) GetImageId() string {
+	return r.imageId
+}
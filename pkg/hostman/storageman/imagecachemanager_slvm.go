@@ -0,0 +1,178 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	SLVM_IMAGECACHE_PREFIX = "imagecache_"
+)
+
+// SSLVMImageCacheManager caches images as logical volumes in the volume
+// group of shared lvm storage, disks are copies of the cached image
+type SSLVMImageCacheManager struct {
+	SBaseImageCacheManager
+	storage IStorage
+}
+
+func NewSLVMImageCacheManager(manager IStorageManager, cachePath string, storage IStorage, storagecacheId string) *SSLVMImageCacheManager {
+	imageCacheManager := new(SSLVMImageCacheManager)
+
+	imageCacheManager.storageManager = manager
+	imageCacheManager.storagecacaheId = storagecacheId
+	imageCacheManager.storage = storage
+	// cachePath like `/dev/vg`
+	imageCacheManager.cachePath = cachePath
+	imageCacheManager.cachedImages = make(map[string]IImageCache, 0)
+	imageCacheManager.loadCache(context.Background())
+	return imageCacheManager
+}
+
+type SSLVMImageCacheManagerFactory struct {
+}
+
+func (factory *SSLVMImageCacheManagerFactory) NewImageCacheManager(manager *SStorageManager, cachePath string, storage IStorage, storagecacheId string) IImageCacheManger {
+	return NewSLVMImageCacheManager(manager, cachePath, storage, storagecacheId)
+}
+
+func (factory *SSLVMImageCacheManagerFactory) StorageType() string {
+	return api.STORAGE_SLVM
+}
+
+func init() {
+	registerimageCacheManagerFactory(&SSLVMImageCacheManagerFactory{})
+}
+
+func (c *SSLVMImageCacheManager) getStorage() *SSLVMStorage {
+	return c.storage.(*SSLVMStorage)
+}
+
+func (c *SSLVMImageCacheManager) loadCache(ctx context.Context) {
+	lockman.LockRawObject(ctx, "SLVM", "image-cache")
+	defer lockman.ReleaseRawObject(ctx, "SLVM", "image-cache")
+
+	lvs, err := c.getStorage().listLvs()
+	if err != nil {
+		log.Errorf("get storage %s logical volumes error; %v", c.storage.GetStorageName(), err)
+		return
+	}
+	for _, lv := range lvs {
+		if !strings.HasPrefix(lv, SLVM_IMAGECACHE_PREFIX) {
+			continue
+		}
+		imageId := strings.TrimPrefix(lv, SLVM_IMAGECACHE_PREFIX)
+		// skip volumes being converted or prepared for saving image
+		if strings.Contains(imageId, "_") {
+			continue
+		}
+		c.LoadImageCache(imageId)
+	}
+}
+
+func (c *SSLVMImageCacheManager) LoadImageCache(imageId string) {
+	imageCache := NewSLVMImageCache(imageId, c)
+	if imageCache.Load() == nil {
+		c.cachedImages[imageId] = imageCache
+	}
+}
+
+func (c *SSLVMImageCacheManager) PrefetchImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error) {
+	body, ok := data.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+
+	input := api.CacheImageInput{}
+	body.Unmarshal(&input)
+
+	if len(input.ImageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("image_id")
+	}
+
+	cache, err := c.AcquireImage(ctx, input, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "AcquireImage")
+	}
+
+	ret := struct {
+		ImageId string
+		Path    string
+		Name    string
+		Size    int64
+	}{
+		ImageId: input.ImageId,
+		Path:    cache.GetPath(),
+	}
+
+	if desc := cache.GetDesc(); desc != nil {
+		ret.Name = desc.Name
+		ret.Size = desc.Size
+	}
+	return jsonutils.Marshal(ret), nil
+}
+
+func (c *SSLVMImageCacheManager) DeleteImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error) {
+	body, ok := data.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+
+	imageId, _ := body.GetString("image_id")
+	return nil, c.removeImage(ctx, imageId)
+}
+
+func (c *SSLVMImageCacheManager) removeImage(ctx context.Context, imageId string) error {
+	lockman.LockRawObject(ctx, "image-cache", imageId)
+	defer lockman.ReleaseRawObject(ctx, "image-cache", imageId)
+
+	if img, ok := c.cachedImages[imageId]; ok {
+		delete(c.cachedImages, imageId)
+		return img.Remove(ctx)
+	}
+	return nil
+}
+
+func (c *SSLVMImageCacheManager) AcquireImage(ctx context.Context, input api.CacheImageInput, callback func(float64, float64, int64)) (IImageCache, error) {
+	lockman.LockRawObject(ctx, "image-cache", input.ImageId)
+	defer lockman.ReleaseRawObject(ctx, "image-cache", input.ImageId)
+
+	img, ok := c.cachedImages[input.ImageId]
+	if !ok {
+		img = NewSLVMImageCache(input.ImageId, c)
+		c.cachedImages[input.ImageId] = img
+	}
+
+	return img, img.Acquire(ctx, input, callback)
+}
+
+func (c *SSLVMImageCacheManager) ReleaseImage(ctx context.Context, imageId string) {
+	lockman.LockRawObject(ctx, "image-cache", imageId)
+	defer lockman.ReleaseRawObject(ctx, "image-cache", imageId)
+	if img, ok := c.cachedImages[imageId]; ok {
+		img.Release()
+	}
+}
@@ -42,6 +42,12 @@ type SImageDesc struct {
 	Chksum string `json:"chksum"`
 	Path   string `json:"path"`
 	Size   int64  `json:"size"`
+
+	Signature       string `json:"signature"`
+	SignatureCert   string `json:"signature_cert"`
+	SignatureStatus string `json:"signature_status"`
+	SignatureDigest string `json:"signature_digest"`
+	SignatureFormat string `json:"signature_disk_format"`
 }
 
 type SRemoteFile struct {
@@ -58,6 +64,12 @@ type SRemoteFile struct {
 	chksum string
 	format string
 	name   string
+
+	signature       string
+	signatureCert   string
+	signatureStatus string
+	signatureDigest string
+	signatureFormat string
 }
 
 func NewRemoteFile(
@@ -114,9 +126,26 @@ func (r *SRemoteFile) GetInfo() (*SImageDesc, error) {
 		Chksum: r.chksum,
 		Path:   r.localPath,
 		Size:   fi.Size(),
+
+		Signature:       r.signature,
+		SignatureCert:   r.signatureCert,
+		SignatureStatus: r.signatureStatus,
+		SignatureDigest: r.signatureDigest,
+		SignatureFormat: r.signatureFormat,
 	}, nil
 }
 
+// FetchProperties refreshes the image properties, signature included, from
+// glance.  Cached download url of other hosts does not carry them
+func (r *SRemoteFile) FetchProperties() error {
+	downloadUrl := r.downloadUrl
+	r.downloadUrl = ""
+	defer func() {
+		r.downloadUrl = downloadUrl
+	}()
+	return r.downloadInternal(false, "", nil)
+}
+
 func (r *SRemoteFile) VerifyIntegrity(callback func(progress, progressMbps float64, totalSizeMb int64)) error {
 	localChksum, err := fileutils2.MD5(r.localPath)
 	if err != nil {
@@ -283,4 +312,9 @@ func (r *SRemoteFile) setProperties(header http.Header) {
 	if name := header.Get("X-Image-Meta-Name"); len(name) > 0 {
 		r.name = name
 	}
+	r.signature = header.Get("X-Image-Meta-Property-Signature")
+	r.signatureCert = header.Get("X-Image-Meta-Property-Signature_cert")
+	r.signatureStatus = header.Get("X-Image-Meta-Property-Signature_status")
+	r.signatureDigest = header.Get("X-Image-Meta-Property-Signature_digest")
+	r.signatureFormat = header.Get("X-Image-Meta-Property-Signature_disk_format")
 }
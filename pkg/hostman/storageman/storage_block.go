@@ -0,0 +1,405 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/qemutils"
+)
+
+const (
+	BLOCK_IMAGECACHE_PREFIX = "imagecache_"
+)
+
+// execBlockCommand runs the volume management commands of block storages,
+// replaced by tests to check the command arguments
+var execBlockCommand = func(name string, args ...string) ([]byte, error) {
+	return procutils.NewRemoteCommandAsFarAsPossible(name, args...).Output()
+}
+
+// IBlockVolumeDriver manages raw volumes of a local block storage, volumes
+// are identified by name and snapshots by name of volume and snapshot
+type IBlockVolumeDriver interface {
+	StorageType() string
+	// GetPoolName returns name of the volume group or dataset, used for logging
+	GetPoolName() string
+	// GetStorageConf returns storage conf reported to region
+	GetStorageConf() *jsonutils.JSONDict
+	Accessible() error
+	// GetPoolSizeMb returns the size and the used size of the pool
+	GetPoolSizeMb() (int64, int64, error)
+
+	GetVolumePath(name string) string
+	ListVolumes() ([]string, error)
+	IsVolumeExist(name string) (bool, error)
+	GetVolumeSizeMb(name string) (int64, error)
+	CreateVolume(name string, sizeMb int64) error
+	ResizeVolume(name string, sizeMb int64) error
+	RenameVolume(src, dest string) error
+	// RemoveVolume fails if other volumes are cloned from it
+	RemoveVolume(name string) error
+	// ReleaseVolumeClones makes volumes cloned from snapshots of the volume
+	// independent of it, so the volume can be removed
+	ReleaseVolumeClones(name string) error
+	SetVolumeReadonly(name string) error
+	// CloneVolume creates a writable volume sharing data with src
+	CloneVolume(src, target string) error
+
+	CreateSnapshot(volume, snapshot string) error
+	IsSnapshotExist(volume, snapshot string) (bool, error)
+	ListSnapshots(volume string) ([]string, error)
+	RemoveSnapshot(volume, snapshot string) error
+	// CloneSnapshot creates a writable volume from snapshot without copying data
+	CloneSnapshot(volume, snapshot, target string) error
+	RollbackSnapshot(volume, snapshot string) error
+}
+
+// SBlockStorage is a local storage whose disks are raw volumes of a lvm thin
+// pool or zfs zvols, snapshots and clones are done natively by the volume driver
+type SBlockStorage struct {
+	SBaseStorage
+
+	Index  int
+	driver IBlockVolumeDriver
+}
+
+func NewBlockStorage(manager *SStorageManager, path string, index int, driver IBlockVolumeDriver) *SBlockStorage {
+	var ret = new(SBlockStorage)
+	ret.SBaseStorage = *NewBaseStorage(manager, path)
+	ret.Index = index
+	ret.driver = driver
+	return ret
+}
+
+func (s *SBlockStorage) StorageType() string {
+	return s.driver.StorageType()
+}
+
+func (s *SBlockStorage) GetComposedName() string {
+	return fmt.Sprintf("host_%s_%s_storage_%d", s.Manager.host.GetMasterIp(), s.StorageType(), s.Index)
+}
+
+func (s *SBlockStorage) GetSnapshotDir() string {
+	return ""
+}
+
+// snapshots are not files, they are only accessible by the volume driver
+func (s *SBlockStorage) GetSnapshotPathByIds(diskId, snapshotId string) string {
+	return ""
+}
+
+func (s *SBlockStorage) IsSnapshotExist(diskId, snapshotId string) (bool, error) {
+	return s.driver.IsSnapshotExist(diskId, snapshotId)
+}
+
+func (s *SBlockStorage) GetFuseTmpPath() string {
+	return ""
+}
+
+func (s *SBlockStorage) GetFuseMountPath() string {
+	return ""
+}
+
+func (s *SBlockStorage) GetImgsaveBackupPath() string {
+	return ""
+}
+
+func (s *SBlockStorage) GetBackupDir() string {
+	return ""
+}
+
+func (s *SBlockStorage) GetFreeSizeMb() int {
+	size, used, err := s.driver.GetPoolSizeMb()
+	if err != nil {
+		log.Errorf("failed get %s free size: %s", s.driver.GetPoolName(), err)
+		return -1
+	}
+	return int(size - used)
+}
+
+func (s *SBlockStorage) GetCapacity() int {
+	size, _, err := s.driver.GetPoolSizeMb()
+	if err != nil {
+		log.Errorf("failed get %s size: %s", s.driver.GetPoolName(), err)
+		return -1
+	}
+	return int(size)
+}
+
+func (s *SBlockStorage) GetStorageConf() *jsonutils.JSONDict {
+	return s.driver.GetStorageConf()
+}
+
+func (s *SBlockStorage) SetStorageInfo(storageId, storageName string, conf jsonutils.JSONObject) error {
+	s.StorageId = storageId
+	s.StorageName = storageName
+	if dconf, ok := conf.(*jsonutils.JSONDict); ok {
+		s.StorageConf = dconf
+	}
+	if len(s.StoragecacheId) > 0 {
+		s.Manager.AddBlockStorageImagecache(s.GetPath(), s, s.StoragecacheId)
+	}
+	return nil
+}
+
+func (s *SBlockStorage) SyncStorageSize() error {
+	size, used, err := s.driver.GetPoolSizeMb()
+	if err != nil {
+		return errors.Wrapf(err, "GetPoolSizeMb")
+	}
+	content := jsonutils.NewDict()
+	content.Set("capacity", jsonutils.NewInt(size))
+	content.Set("actual_capacity_used", jsonutils.NewInt(used))
+	_, err = modules.Storages.Put(
+		hostutils.GetComputeSession(context.Background()),
+		s.StorageId, content)
+	return errors.Wrapf(err, "storage update")
+}
+
+func (s *SBlockStorage) SyncStorageInfo() (jsonutils.JSONObject, error) {
+	size, used, err := s.driver.GetPoolSizeMb()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetPoolSizeMb")
+	}
+	content := jsonutils.NewDict()
+	name := s.GetName(s.GetComposedName)
+	content.Set("name", jsonutils.NewString(name))
+	content.Set("capacity", jsonutils.NewInt(size))
+	content.Set("actual_capacity_used", jsonutils.NewInt(used))
+	content.Set("storage_type", jsonutils.NewString(s.StorageType()))
+	content.Set("medium_type", jsonutils.NewString(s.GetMediumType()))
+	content.Set("zone", jsonutils.NewString(s.GetZoneName()))
+	content.Set("storage_conf", s.driver.GetStorageConf())
+
+	var res jsonutils.JSONObject
+	log.Infof("Sync storage info %s/%s", s.StorageId, name)
+	if len(s.StorageId) > 0 {
+		res, err = modules.Storages.Put(
+			hostutils.GetComputeSession(context.Background()),
+			s.StorageId, content)
+	} else {
+		res, err = modules.Storages.Create(
+			hostutils.GetComputeSession(context.Background()), content)
+	}
+	if err != nil {
+		log.Errorf("SyncStorageInfo Failed: %s: %s", content, err)
+		return nil, err
+	}
+	// storagecache is created by region along with the storage
+	if scId, _ := res.GetString("storagecache_id"); len(scId) > 0 && len(s.StoragecacheId) == 0 {
+		s.SetStoragecacheId(scId)
+	}
+	return res, nil
+}
+
+func (s *SBlockStorage) GetDiskById(diskId string) (IDisk, error) {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	for i := 0; i < len(s.Disks); i++ {
+		if s.Disks[i].GetId() == diskId {
+			err := s.Disks[i].Probe()
+			if err != nil {
+				return nil, errors.Wrapf(err, "disk.Probe")
+			}
+			return s.Disks[i], nil
+		}
+	}
+	var disk = NewBlockDisk(s, diskId)
+	if disk.Probe() == nil {
+		s.Disks = append(s.Disks, disk)
+		return disk, nil
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (s *SBlockStorage) CreateDisk(diskId string) IDisk {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	disk := NewBlockDisk(s, diskId)
+	s.Disks = append(s.Disks, disk)
+	return disk
+}
+
+func (s *SBlockStorage) Accessible() error {
+	return s.driver.Accessible()
+}
+
+func (s *SBlockStorage) Detach() error {
+	return nil
+}
+
+func (s *SBlockStorage) DeleteDiskfile(diskPath string, skipRecycle bool) error {
+	return s.driver.RemoveVolume(path.Base(diskPath))
+}
+
+func (s *SBlockStorage) SaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	data, ok := params.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+
+	imageCache := storageManager.GetStoragecacheById(s.GetStoragecacheId())
+	if imageCache == nil {
+		return nil, fmt.Errorf("failed to find storage image cache for storage %s", s.GetStorageName())
+	}
+
+	imagePath, _ := data.GetString("image_path")
+	compress := jsonutils.QueryBoolean(data, "compress", true)
+	format, _ := data.GetString("format")
+	imageId, _ := data.GetString("image_id")
+	imageName := BLOCK_IMAGECACHE_PREFIX + imageId
+	if err := s.driver.RenameVolume(path.Base(imagePath), imageName); err != nil {
+		return nil, err
+	}
+
+	err := saveRawDeviceToGlance(ctx, s.GetZoneName(), imageId, s.driver.GetVolumePath(imageName), compress, format)
+	if err != nil {
+		log.Errorf("Save to glance failed: %s", err)
+		onRawDeviceSaveToGlanceFailed(ctx, s.GetZoneName(), imageId)
+	}
+	if err := s.driver.SetVolumeReadonly(imageName); err != nil {
+		log.Errorf("set image cache %s readonly failed: %s", imageName, err)
+	}
+
+	imageCache.LoadImageCache(imageId)
+	_, err = hostutils.RemoteStoragecacheCacheImage(ctx, imageCache.GetId(), imageId, "active", s.driver.GetVolumePath(imageName))
+	if err != nil {
+		log.Errorf("Fail to remote cache image: %v", err)
+	}
+	return nil, nil
+}
+
+func (s *SBlockStorage) CreateSnapshotFormUrl(ctx context.Context, snapshotUrl, diskId, snapshotPath string) error {
+	return fmt.Errorf("Not support")
+}
+
+func (s *SBlockStorage) DeleteSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	diskId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	return nil, s.deleteDiskSnapshots(diskId)
+}
+
+func (s *SBlockStorage) deleteDiskSnapshots(diskId string) error {
+	snapshots, err := s.driver.ListSnapshots(diskId)
+	if err != nil {
+		return errors.Wrapf(err, "ListSnapshots")
+	}
+	for _, snapshot := range snapshots {
+		if err := s.driver.RemoveSnapshot(diskId, snapshot); err != nil {
+			return errors.Wrapf(err, "remove snapshot %s of %s", snapshot, diskId)
+		}
+	}
+	return nil
+}
+
+func (s *SBlockStorage) CreateDiskFromSnapshot(ctx context.Context, disk IDisk, input *SDiskCreateByDiskinfo) error {
+	info := input.DiskInfo
+	return disk.(*SBlockDisk).createFromSnapshot(info.SrcDiskId, info.SnapshotUrl, int64(info.DiskSizeMb))
+}
+
+func (s *SBlockStorage) CreateDiskFromBackup(ctx context.Context, disk IDisk, input *SDiskCreateByDiskinfo) error {
+	return fmt.Errorf("Not support")
+}
+
+func (s *SBlockStorage) GetCloneTargetDiskPath(ctx context.Context, targetDiskId string) string {
+	return s.driver.GetVolumePath(targetDiskId)
+}
+
+// CloneDiskFromStorage copies the source disk block by block into a new volume
+func (s *SBlockStorage) CloneDiskFromStorage(ctx context.Context, srcStorage IStorage, srcDisk IDisk, targetDiskId string) (*hostapi.ServerCloneDiskFromStorageResponse, error) {
+	srcDiskPath := srcDisk.GetPath()
+	srcImg, err := qemuimg.NewQemuImage(srcDiskPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Get source image %q info", srcDiskPath)
+	}
+	if err := s.driver.CreateVolume(targetDiskId, int64(srcImg.GetSizeMB())); err != nil {
+		return nil, errors.Wrapf(err, "create volume %s", targetDiskId)
+	}
+	if err := s.convertToVolume(srcDiskPath, srcImg.Format.String(), targetDiskId); err != nil {
+		s.driver.RemoveVolume(targetDiskId)
+		return nil, errors.Wrapf(err, "Clone source disk to target %s storage", s.StorageType())
+	}
+	return &hostapi.ServerCloneDiskFromStorageResponse{
+		TargetAccessPath: s.driver.GetVolumePath(targetDiskId),
+		TargetFormat:     qemuimg.RAW.String(),
+	}, nil
+}
+
+func (s *SBlockStorage) convertToVolume(srcPath, srcFormat, name string) error {
+	output, err := execBlockCommand(qemutils.GetQemuImg(),
+		"convert", "-W", "-m", "16", "-n", "-f", srcFormat, "-O", "raw", srcPath, s.driver.GetVolumePath(name))
+	if err != nil {
+		return errors.Wrapf(err, "qemu-img convert %s: %s", srcPath, output)
+	}
+	return nil
+}
+
+// DestinationPrepareMigrate only supports live migration, the disk data is
+// mirrored by qemu into an empty volume of the same size
+func (s *SBlockStorage) DestinationPrepareMigrate(
+	ctx context.Context, liveMigrate bool, disksUri string, snapshotsUri string,
+	disksBackingFile, srcSnapshots jsonutils.JSONObject, rebaseDisks bool, diskinfo jsonutils.JSONObject, serverId string, idx, totalDiskCount int) error {
+	diskId, _ := diskinfo.GetString("disk_id")
+	if !liveMigrate {
+		return fmt.Errorf("%s storage only support live migrate", s.StorageType())
+	}
+	if snapshots, _ := srcSnapshots.GetArray(diskId); len(snapshots) > 0 {
+		return fmt.Errorf("disk %s with snapshots can't migrate to %s storage", diskId, s.StorageType())
+	}
+	disk := s.CreateDisk(diskId)
+	size, _ := diskinfo.Int("size")
+	if _, err := disk.CreateRaw(ctx, int(size), "raw", "", false, "", ""); err != nil {
+		return errors.Wrapf(err, "create disk %s", diskId)
+	}
+	diskDesc, _ := diskinfo.(*jsonutils.JSONDict)
+	diskDesc.Set("path", jsonutils.NewString(disk.GetPath()))
+	return nil
+}
+
+func (s *SStorageManager) initBlockStorages() {
+	// the device directory of volumes is taken as the mount point of storage
+	for i, conf := range options.HostOptions.LocalLvmStorages {
+		driver := newLvmVolumeDriver(conf)
+		s.addBlockStorage(NewBlockStorage(s, driver.GetVolumePath(""), i, driver))
+	}
+	for i, conf := range options.HostOptions.LocalZfsStorages {
+		driver := newZfsVolumeDriver(conf)
+		s.addBlockStorage(NewBlockStorage(s, driver.GetVolumePath(""), i, driver))
+	}
+}
+
+func (s *SStorageManager) addBlockStorage(storage *SBlockStorage) {
+	if err := storage.Accessible(); err != nil {
+		log.Errorf("%s storage %s not accessible error: %v", storage.StorageType(), storage.driver.GetPoolName(), err)
+		return
+	}
+	s.Storages = append(s.Storages, storage)
+}
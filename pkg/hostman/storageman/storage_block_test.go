@@ -0,0 +1,125 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sFakeBlockCommands records the commands run by the block volume drivers,
+// outputs of a command are returned in turn and the last one is kept
+type sFakeBlockCommands struct {
+	cmds    []string
+	outputs map[string][]string
+	fails   map[string]bool
+}
+
+func mockBlockCommands(outputs map[string][]string, fails ...string) (*sFakeBlockCommands, func()) {
+	f := &sFakeBlockCommands{
+		outputs: outputs,
+		fails:   map[string]bool{},
+	}
+	for _, cmd := range fails {
+		f.fails[cmd] = true
+	}
+	origin := execBlockCommand
+	execBlockCommand = f.exec
+	return f, func() { execBlockCommand = origin }
+}
+
+func (f *sFakeBlockCommands) exec(name string, args ...string) ([]byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	f.cmds = append(f.cmds, cmd)
+	if f.fails[cmd] {
+		return []byte("failed"), fmt.Errorf("exit status 1")
+	}
+	outputs := f.outputs[cmd]
+	if len(outputs) == 0 {
+		return nil, nil
+	}
+	output := outputs[0]
+	if len(outputs) > 1 {
+		f.outputs[cmd] = outputs[1:]
+	}
+	return []byte(output), nil
+}
+
+func (f *sFakeBlockCommands) check(t *testing.T, want []string) {
+	if !reflect.DeepEqual(f.cmds, want) {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(f.cmds, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBlockStorageConvertToVolume(t *testing.T) {
+	cases := []struct {
+		driver IBlockVolumeDriver
+		want   string
+	}{
+		{
+			driver: newLvmVolumeDriver("vg0"),
+			want:   "convert -W -m 16 -n -f qcow2 -O raw /opt/cache/img /dev/vg0/disk1",
+		},
+		{
+			driver: newZfsVolumeDriver("tank/vols"),
+			want:   "convert -W -m 16 -n -f qcow2 -O raw /opt/cache/img /dev/zvol/tank/vols/disk1",
+		},
+	}
+	for _, c := range cases {
+		f, restore := mockBlockCommands(nil)
+		s := &SBlockStorage{driver: c.driver}
+		err := s.convertToVolume("/opt/cache/img", "qcow2", "disk1")
+		restore()
+		if err != nil {
+			t.Fatalf("convertToVolume: %v", err)
+		}
+		if len(f.cmds) != 1 || !strings.HasSuffix(f.cmds[0], " "+c.want) {
+			t.Errorf("%s commands %v, want qemu-img %s", c.driver.StorageType(), f.cmds, c.want)
+		}
+	}
+}
+
+func newTestBlockDisk(driver IBlockVolumeDriver, id string) *SBlockDisk {
+	return NewBlockDisk(NewBlockStorage(nil, "/dev/"+driver.StorageType(), 0, driver), id)
+}
+
+// sBlockDiskCase runs an operation of a block disk against faked commands,
+// wantErr tells whether the failure of the operation is expected
+type sBlockDiskCase struct {
+	name    string
+	outputs map[string][]string
+	fails   []string
+	do      func(d *SBlockDisk) error
+	wantErr bool
+	want    []string
+}
+
+func testBlockDiskCases(t *testing.T, driver IBlockVolumeDriver, cases []sBlockDiskCase) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, restore := mockBlockCommands(c.outputs, c.fails...)
+			defer restore()
+			err := c.do(newTestBlockDisk(driver, "disk2"))
+			if c.wantErr && err == nil {
+				t.Errorf("should fail")
+			} else if !c.wantErr && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			f.check(t, c.want)
+		})
+	}
+}
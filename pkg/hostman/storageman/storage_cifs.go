@@ -0,0 +1,250 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/host_health"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+func init() {
+	registerStorageFactory(&SCIFSStorageFactory{})
+}
+
+type SCIFSStorageFactory struct {
+}
+
+func (factory *SCIFSStorageFactory) NewStorage(manager *SStorageManager, mountPoint string) IStorage {
+	return NewCIFSStorage(manager, mountPoint)
+}
+
+func (factory *SCIFSStorageFactory) StorageType() string {
+	return api.STORAGE_CIFS
+}
+
+type SCIFSStorage struct {
+	SNasStorage
+}
+
+func NewCIFSStorage(manager *SStorageManager, path string) *SCIFSStorage {
+	ret := &SCIFSStorage{}
+	ret.SNasStorage = *NewNasStorage(manager, path, ret)
+	if !fileutils2.Exists(path) {
+		procutils.NewCommand("mkdir", "-p", path).Run()
+	}
+	return ret
+}
+
+func (s *SCIFSStorage) newDisk(diskId string) IDisk {
+	return NewCIFSDisk(s, diskId)
+}
+
+func (s *SCIFSStorage) StorageType() string {
+	return api.STORAGE_CIFS
+}
+
+func (s *SCIFSStorage) SetStorageInfo(storageId, storageName string, conf jsonutils.JSONObject) error {
+	s.StorageId = storageId
+	s.StorageName = storageName
+	if dconf, ok := conf.(*jsonutils.JSONDict); ok {
+		s.StorageConf = dconf
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cifsCheckTimeout)
+	defer cancel()
+	if err := s.checkAndMount(ctx); err != nil {
+		return errors.Errorf("Fail to mount storage to mountpoint: %s, %s", s.Path, err)
+	}
+	if !s.isSetStorageInfo && !strings.HasPrefix(s.Path, "/opt/cloud") {
+		err := s.bindMountTo(s.Path)
+		if err != nil {
+			return err
+		}
+		s.isSetStorageInfo = true
+	}
+	host_health.RegisterHealthChecker(s)
+	return nil
+}
+
+// getCredentialsPath returns a path visible to both host agent and host system,
+// mount.cifs reads the credentials from it instead of the command line
+func (s *SCIFSStorage) getCredentialsPath() string {
+	return path.Join(path.Dir(options.HostOptions.ServersPath), ".cifs", s.StorageId)
+}
+
+// cifsCredentials returns the content of mount.cifs credentials file,
+// empty if the share is mounted as guest
+func cifsCredentials(storageId string, conf *jsonutils.JSONDict) (string, error) {
+	username, _ := conf.GetString("cifs_username")
+	if len(username) == 0 {
+		return "", nil
+	}
+	password, _ := conf.GetString("cifs_password")
+	if len(password) > 0 {
+		// password is encrypted with storage id by region
+		passwd, err := utils.DescryptAESBase64(storageId, password)
+		if err != nil {
+			return "", errors.Wrap(err, "decrypt cifs password")
+		}
+		password = passwd
+	}
+	content := fmt.Sprintf("username=%s\npassword=%s\n", username, password)
+	if domain, _ := conf.GetString("cifs_domain"); len(domain) > 0 {
+		content += fmt.Sprintf("domain=%s\n", domain)
+	}
+	return content, nil
+}
+
+// cifsMountOptions joins the options passed to mount.cifs, credentials in
+// user supplied options are dropped so that only the credentials file is used
+func cifsMountOptions(credPath string, userOpts string) string {
+	mountOpts := []string{}
+	if len(credPath) > 0 {
+		mountOpts = append(mountOpts, "credentials="+credPath)
+	} else {
+		mountOpts = append(mountOpts, "guest")
+	}
+	for _, opt := range strings.Split(userOpts, ",") {
+		opt = strings.TrimSpace(opt)
+		if len(opt) == 0 {
+			continue
+		}
+		key := strings.ToLower(strings.SplitN(opt, "=", 2)[0])
+		if utils.IsInStringArray(key, []string{"username", "user", "password", "pass", "credentials", "cred", "domain", "dom", "guest"}) {
+			log.Warningf("ignore cifs mount option %s", key)
+			continue
+		}
+		mountOpts = append(mountOpts, opt)
+	}
+	return strings.Join(mountOpts, ",")
+}
+
+func (s *SCIFSStorage) writeCredentials() (string, error) {
+	content, err := cifsCredentials(s.StorageId, s.StorageConf)
+	if err != nil || len(content) == 0 {
+		return "", err
+	}
+	credPath := s.getCredentialsPath()
+	if err := os.MkdirAll(path.Dir(credPath), 0700); err != nil {
+		return "", errors.Wrapf(err, "mkdir %s", path.Dir(credPath))
+	}
+	if err := ioutil.WriteFile(credPath, []byte(content), 0600); err != nil {
+		return "", errors.Wrapf(err, "write credentials %s", credPath)
+	}
+	return credPath, nil
+}
+
+// checkAndMount mounts the share if it is not mounted, both the probe and
+// the mount are bounded by ctx as they hang on an unresponsive server
+func (s *SCIFSStorage) checkAndMount(ctx context.Context) error {
+	if err := procutils.NewRemoteCommandContextAsFarAsPossible(ctx, "mountpoint", s.Path).Run(); err == nil {
+		return nil
+	} else if ctx.Err() != nil {
+		return errors.Wrapf(ctx.Err(), "mountpoint %s", s.Path)
+	}
+	if s.StorageConf == nil {
+		return fmt.Errorf("Storage conf is nil")
+	}
+	host, err := s.StorageConf.GetString("cifs_host")
+	if err != nil {
+		return fmt.Errorf("Storage conf missing cifs_host")
+	}
+	sharedDir, err := s.StorageConf.GetString("cifs_shared_dir")
+	if err != nil {
+		return fmt.Errorf("Storage conf missing cifs_shared_dir")
+	}
+	credPath, err := s.writeCredentials()
+	if err != nil {
+		return err
+	}
+	if len(credPath) > 0 {
+		defer os.Remove(credPath)
+	}
+	userOpts, _ := s.StorageConf.GetString("cifs_mount_options")
+	out, err := procutils.NewRemoteCommandContextAsFarAsPossible(ctx,
+		"mount", "-t", "cifs", fmt.Sprintf("//%s/%s", host, strings.TrimPrefix(sharedDir, "/")), s.Path,
+		"-o", cifsMountOptions(credPath, userOpts)).Output()
+	if err != nil {
+		return errors.Wrapf(err, "mount cifs %s", out)
+	}
+	return nil
+}
+
+func (s *SCIFSStorage) GetName() string {
+	return fmt.Sprintf("cifs-storage-%s", s.StorageId)
+}
+
+// cifsCheckTimeout bounds probing, remounting and accessing the share
+const cifsCheckTimeout = 20 * time.Second
+
+// CheckHealth remounts the share if it was lost, then makes sure the mount
+// point responds, a hung cifs mount blocks any file access on it
+func (s *SCIFSStorage) CheckHealth() error {
+	ctx, cancel := context.WithTimeout(context.Background(), cifsCheckTimeout)
+	defer cancel()
+	if err := s.checkAndMount(ctx); err != nil {
+		return errors.Wrapf(err, "remount %s", s.Path)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := os.Stat(s.Path)
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return errors.Wrapf(err, "stat %s", s.Path)
+		}
+	case <-ctx.Done():
+		return errors.Errorf("stat %s timeout", s.Path)
+	}
+	return nil
+}
+
+func (s *SCIFSStorage) Detach() error {
+	host_health.UnregisterHealthChecker(s.GetName())
+	if !strings.HasPrefix(s.Path, "/opt/cloud") {
+		tmpPath := path.Join(TempBindMountPath, s.Path)
+		out, err := procutils.NewCommand("umount", s.Path).Output()
+		if err != nil {
+			return errors.Wrapf(err, "1. umount %s failed %s", s.Path, out)
+		}
+		out, err = procutils.NewRemoteCommandAsFarAsPossible("umount", tmpPath).Output()
+		if err != nil {
+			return errors.Wrapf(err, "2. umount %s failed %s", tmpPath, out)
+		}
+	}
+	out, err := procutils.NewRemoteCommandAsFarAsPossible("umount", s.Path).Output()
+	if err != nil {
+		return errors.Wrapf(err, "3. umount %s failed %s", s.Path, out)
+	}
+	log.Infof("cifs storage %s detached", s.StorageName)
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/utils"
+)
+
+func TestCifsCredentials(t *testing.T) {
+	storageId := "a5b2c4e1-7d3f-4b7a-9f1e-2c3d4e5f6a7b"
+	encrypted, err := utils.EncryptAESBase64(storageId, "s3cret")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	cases := []struct {
+		name    string
+		conf    map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "guest",
+			conf: map[string]string{"cifs_host": "10.0.0.1"},
+			want: "",
+		},
+		{
+			name: "password ignored without username",
+			conf: map[string]string{"cifs_password": encrypted},
+			want: "",
+		},
+		{
+			name: "username only",
+			conf: map[string]string{"cifs_username": "admin"},
+			want: "username=admin\npassword=\n",
+		},
+		{
+			name: "encrypted password and domain",
+			conf: map[string]string{"cifs_username": "admin", "cifs_password": encrypted, "cifs_domain": "WORKGROUP"},
+			want: "username=admin\npassword=s3cret\ndomain=WORKGROUP\n",
+		},
+		{
+			name:    "undecryptable password",
+			conf:    map[string]string{"cifs_username": "admin", "cifs_password": "plain"},
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conf := jsonutils.Marshal(c.conf).(*jsonutils.JSONDict)
+			got, err := cifsCredentials(storageId, conf)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCifsMountOptions(t *testing.T) {
+	cases := []struct {
+		name     string
+		credPath string
+		userOpts string
+		want     string
+	}{
+		{name: "guest", want: "guest"},
+		{name: "credentials", credPath: "/opt/cloud/.cifs/s1", want: "credentials=/opt/cloud/.cifs/s1"},
+		{name: "user options", credPath: "/opt/cloud/.cifs/s1", userOpts: "vers=3.0, uid=0,", want: "credentials=/opt/cloud/.cifs/s1,vers=3.0,uid=0"},
+		{
+			name:     "strip credentials",
+			credPath: "/opt/cloud/.cifs/s1",
+			userOpts: "username=root,vers=3.0,Password=x,credentials=/etc/shadow,dom=EVIL",
+			want:     "credentials=/opt/cloud/.cifs/s1,vers=3.0",
+		},
+		{name: "strip guest", credPath: "/opt/cloud/.cifs/s1", userOpts: "guest,user=nobody", want: "credentials=/opt/cloud/.cifs/s1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := cifsMountOptions(c.credPath, c.userOpts); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
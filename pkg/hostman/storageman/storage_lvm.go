@@ -0,0 +1,240 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// sLvmVolumeDriver manages thin logical volumes of a local volume group,
+// snapshots are thin snapshots named snap_<volume>_<snapshot> which keep the
+// activation skip flag, so they take no device node until they are cloned
+type sLvmVolumeDriver struct {
+	VgName   string
+	ThinPool string
+}
+
+// newLvmVolumeDriver parses conf in format of <vg_name>[/<thin_pool>]
+func newLvmVolumeDriver(conf string) *sLvmVolumeDriver {
+	driver := &sLvmVolumeDriver{}
+	segs := strings.SplitN(strings.Trim(conf, "/"), "/", 2)
+	driver.VgName = segs[0]
+	if len(segs) > 1 && len(segs[1]) > 0 {
+		driver.ThinPool = segs[1]
+	} else {
+		driver.ThinPool = api.LVM_DEFAULT_THIN_POOL
+	}
+	return driver
+}
+
+func (d *sLvmVolumeDriver) lvm(cmd string, args ...string) (string, error) {
+	output, err := execBlockCommand(cmd, args...)
+	if err != nil {
+		return "", errors.Wrapf(err, "%s %s: %s", cmd, strings.Join(args, " "), output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (d *sLvmVolumeDriver) lvFullName(name string) string {
+	return fmt.Sprintf("%s/%s", d.VgName, name)
+}
+
+func (d *sLvmVolumeDriver) getSnapshotName(volume, snapshot string) string {
+	return fmt.Sprintf("%s%s_%s", SLVM_SNAPSHOT_PREFIX, volume, snapshot)
+}
+
+func (d *sLvmVolumeDriver) StorageType() string {
+	return api.STORAGE_LVM
+}
+
+func (d *sLvmVolumeDriver) GetPoolName() string {
+	return d.lvFullName(d.ThinPool)
+}
+
+func (d *sLvmVolumeDriver) GetStorageConf() *jsonutils.JSONDict {
+	conf := jsonutils.NewDict()
+	conf.Set("vg_name", jsonutils.NewString(d.VgName))
+	conf.Set("thin_pool", jsonutils.NewString(d.ThinPool))
+	return conf
+}
+
+func (d *sLvmVolumeDriver) Accessible() error {
+	exist, err := d.IsVolumeExist(d.ThinPool)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return fmt.Errorf("thin pool %s not found", d.lvFullName(d.ThinPool))
+	}
+	return nil
+}
+
+func (d *sLvmVolumeDriver) GetPoolSizeMb() (int64, int64, error) {
+	output, err := d.lvm("lvs", "--noheadings", "--units", "m", "--nosuffix", "--separator", ",", "-o", "lv_size,data_percent", d.lvFullName(d.ThinPool))
+	if err != nil {
+		return 0, 0, err
+	}
+	info := strings.Split(output, ",")
+	if len(info) != 2 {
+		return 0, 0, fmt.Errorf("invalid thin pool info %q", output)
+	}
+	size, err := strconv.ParseFloat(strings.TrimSpace(info[0]), 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse thin pool size %q", info[0])
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSpace(info[1]), 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse thin pool data percent %q", info[1])
+	}
+	return int64(size), int64(size * percent / 100), nil
+}
+
+func (d *sLvmVolumeDriver) GetVolumePath(name string) string {
+	return path.Join("/dev", d.VgName, name)
+}
+
+func (d *sLvmVolumeDriver) ListVolumes() ([]string, error) {
+	output, err := d.lvm("lvs", "--noheadings", "-o", "lv_name", d.VgName)
+	if err != nil {
+		return nil, err
+	}
+	lvs := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if lv := strings.TrimSpace(line); len(lv) > 0 {
+			lvs = append(lvs, lv)
+		}
+	}
+	return lvs, nil
+}
+
+func (d *sLvmVolumeDriver) IsVolumeExist(name string) (bool, error) {
+	lvs, err := d.ListVolumes()
+	if err != nil {
+		return false, errors.Wrapf(err, "ListVolumes")
+	}
+	return utils.IsInStringArray(name, lvs), nil
+}
+
+func (d *sLvmVolumeDriver) GetVolumeSizeMb(name string) (int64, error) {
+	output, err := d.lvm("lvs", "--noheadings", "--units", "m", "--nosuffix", "-o", "lv_size", d.lvFullName(name))
+	if err != nil {
+		return 0, err
+	}
+	size, err := strconv.ParseFloat(output, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse lv size %q", output)
+	}
+	return int64(size), nil
+}
+
+func (d *sLvmVolumeDriver) CreateVolume(name string, sizeMb int64) error {
+	_, err := d.lvm("lvcreate", "-y", "-V", fmt.Sprintf("%dm", sizeMb), "-T", d.lvFullName(d.ThinPool), "-n", name)
+	return err
+}
+
+func (d *sLvmVolumeDriver) ResizeVolume(name string, sizeMb int64) error {
+	_, err := d.lvm("lvextend", "-L", fmt.Sprintf("%dm", sizeMb), d.lvFullName(name))
+	return err
+}
+
+func (d *sLvmVolumeDriver) RenameVolume(src, dest string) error {
+	_, err := d.lvm("lvrename", d.VgName, src, dest)
+	return err
+}
+
+func (d *sLvmVolumeDriver) RemoveVolume(name string) error {
+	exist, err := d.IsVolumeExist(name)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	_, err = d.lvm("lvremove", "-y", d.lvFullName(name))
+	return err
+}
+
+// ReleaseVolumeClones is a no-op, thin snapshots do not depend on the origin volume
+func (d *sLvmVolumeDriver) ReleaseVolumeClones(name string) error {
+	return nil
+}
+
+func (d *sLvmVolumeDriver) SetVolumeReadonly(name string) error {
+	_, err := d.lvm("lvchange", "-p", "r", d.lvFullName(name))
+	return err
+}
+
+func (d *sLvmVolumeDriver) CloneVolume(src, target string) error {
+	_, err := d.lvm("lvcreate", "-y", "-s", "-kn", "-ay", "-p", "rw", "-n", target, d.lvFullName(src))
+	return err
+}
+
+func (d *sLvmVolumeDriver) CreateSnapshot(volume, snapshot string) error {
+	_, err := d.lvm("lvcreate", "-y", "-s", "-n", d.getSnapshotName(volume, snapshot), d.lvFullName(volume))
+	return err
+}
+
+func (d *sLvmVolumeDriver) IsSnapshotExist(volume, snapshot string) (bool, error) {
+	return d.IsVolumeExist(d.getSnapshotName(volume, snapshot))
+}
+
+func (d *sLvmVolumeDriver) ListSnapshots(volume string) ([]string, error) {
+	lvs, err := d.ListVolumes()
+	if err != nil {
+		return nil, errors.Wrapf(err, "ListVolumes")
+	}
+	prefix := d.getSnapshotName(volume, "")
+	snapshots := []string{}
+	for _, lv := range lvs {
+		if strings.HasPrefix(lv, prefix) {
+			snapshots = append(snapshots, strings.TrimPrefix(lv, prefix))
+		}
+	}
+	return snapshots, nil
+}
+
+func (d *sLvmVolumeDriver) RemoveSnapshot(volume, snapshot string) error {
+	return d.RemoveVolume(d.getSnapshotName(volume, snapshot))
+}
+
+func (d *sLvmVolumeDriver) CloneSnapshot(volume, snapshot, target string) error {
+	return d.CloneVolume(d.getSnapshotName(volume, snapshot), target)
+}
+
+// RollbackSnapshot replaces the volume with a new thin snapshot of the snapshot
+func (d *sLvmVolumeDriver) RollbackSnapshot(volume, snapshot string) error {
+	snapshotName := d.getSnapshotName(volume, snapshot)
+	exist, err := d.IsVolumeExist(snapshotName)
+	if err != nil {
+		return errors.Wrapf(err, "IsVolumeExist")
+	}
+	if !exist {
+		return errors.Wrapf(cloudprovider.ErrNotFound, "snapshot %s", snapshotName)
+	}
+	if err := d.RemoveVolume(volume); err != nil {
+		return errors.Wrapf(err, "remove %s", volume)
+	}
+	return d.CloneVolume(snapshotName, volume)
+}
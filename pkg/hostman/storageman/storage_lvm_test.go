@@ -0,0 +1,285 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const testLvs = "lvs --noheadings -o lv_name vg0"
+
+func TestNewLvmVolumeDriver(t *testing.T) {
+	cases := []struct {
+		conf     string
+		vgName   string
+		thinPool string
+	}{
+		{"vg0", "vg0", "thinpool"},
+		{"/vg0/", "vg0", "thinpool"},
+		{"vg0/pool1", "vg0", "pool1"},
+	}
+	for _, c := range cases {
+		d := newLvmVolumeDriver(c.conf)
+		if d.VgName != c.vgName || d.ThinPool != c.thinPool {
+			t.Errorf("conf %q got %s/%s, want %s/%s", c.conf, d.VgName, d.ThinPool, c.vgName, c.thinPool)
+		}
+	}
+}
+
+func TestLvmVolumeDriverCommands(t *testing.T) {
+	d := newLvmVolumeDriver("vg0/pool1")
+	cases := []struct {
+		name string
+		do   func() error
+		want []string
+	}{
+		{
+			name: "CreateVolume",
+			do:   func() error { return d.CreateVolume("disk1", 10240) },
+			want: []string{"lvcreate -y -V 10240m -T vg0/pool1 -n disk1"},
+		},
+		{
+			name: "ResizeVolume",
+			do:   func() error { return d.ResizeVolume("disk1", 20480) },
+			want: []string{"lvextend -L 20480m vg0/disk1"},
+		},
+		{
+			name: "RenameVolume",
+			do:   func() error { return d.RenameVolume("disk1", "disk2") },
+			want: []string{"lvrename vg0 disk1 disk2"},
+		},
+		{
+			name: "SetVolumeReadonly",
+			do:   func() error { return d.SetVolumeReadonly("disk1") },
+			want: []string{"lvchange -p r vg0/disk1"},
+		},
+		{
+			name: "CloneVolume",
+			do:   func() error { return d.CloneVolume("imagecache_img1", "disk1") },
+			want: []string{"lvcreate -y -s -kn -ay -p rw -n disk1 vg0/imagecache_img1"},
+		},
+		{
+			name: "CreateSnapshot",
+			do:   func() error { return d.CreateSnapshot("disk1", "snap1") },
+			want: []string{"lvcreate -y -s -n snap_disk1_snap1 vg0/disk1"},
+		},
+		{
+			name: "CloneSnapshot",
+			do:   func() error { return d.CloneSnapshot("disk1", "snap1", "disk2") },
+			want: []string{"lvcreate -y -s -kn -ay -p rw -n disk2 vg0/snap_disk1_snap1"},
+		},
+		{
+			name: "RemoveVolume",
+			do:   func() error { return d.RemoveVolume("disk1") },
+			want: []string{testLvs, "lvremove -y vg0/disk1"},
+		},
+		{
+			name: "RemoveAbsentVolume",
+			do:   func() error { return d.RemoveVolume("disk3") },
+			want: []string{testLvs},
+		},
+		{
+			name: "ReleaseVolumeClones",
+			do:   func() error { return d.ReleaseVolumeClones("disk1") },
+			want: nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, restore := mockBlockCommands(map[string][]string{
+				testLvs: {"  pool1\n  disk1\n  snap_disk1_snap1\n"},
+			})
+			defer restore()
+			if err := c.do(); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			f.check(t, c.want)
+		})
+	}
+}
+
+func TestLvmVolumeDriverListSnapshots(t *testing.T) {
+	d := newLvmVolumeDriver("vg0")
+	_, restore := mockBlockCommands(map[string][]string{
+		testLvs: {"  thinpool\n  disk1\n  disk10\n  snap_disk1_snap1\n  snap_disk1_snap2\n  snap_disk10_snap3\n"},
+	})
+	defer restore()
+	snapshots, err := d.ListSnapshots("disk1")
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0] != "snap1" || snapshots[1] != "snap2" {
+		t.Errorf("ListSnapshots got %v, want [snap1 snap2]", snapshots)
+	}
+}
+
+func TestLvmVolumeDriverRollbackSnapshot(t *testing.T) {
+	d := newLvmVolumeDriver("vg0")
+	lvs := "  thinpool\n  disk1\n  snap_disk1_snap1\n"
+
+	f, restore := mockBlockCommands(map[string][]string{testLvs: {lvs}})
+	err := d.RollbackSnapshot("disk1", "snap1")
+	restore()
+	if err != nil {
+		t.Fatalf("RollbackSnapshot: %v", err)
+	}
+	f.check(t, []string{
+		testLvs,
+		testLvs,
+		"lvremove -y vg0/disk1",
+		"lvcreate -y -s -kn -ay -p rw -n disk1 vg0/snap_disk1_snap1",
+	})
+
+	f, restore = mockBlockCommands(map[string][]string{testLvs: {lvs}})
+	err = d.RollbackSnapshot("disk1", "snap2")
+	restore()
+	if errors.Cause(err) != cloudprovider.ErrNotFound {
+		t.Errorf("RollbackSnapshot of absent snapshot got %v, want ErrNotFound", err)
+	}
+	f.check(t, []string{testLvs})
+
+	f, restore = mockBlockCommands(map[string][]string{testLvs: {lvs}}, "lvremove -y vg0/disk1")
+	err = d.RollbackSnapshot("disk1", "snap1")
+	restore()
+	if err == nil {
+		t.Errorf("RollbackSnapshot should fail when the volume is not removed")
+	}
+	f.check(t, []string{testLvs, testLvs, "lvremove -y vg0/disk1"})
+}
+
+func TestLvmVolumeDriverGetPoolSizeMb(t *testing.T) {
+	d := newLvmVolumeDriver("vg0")
+	_, restore := mockBlockCommands(map[string][]string{
+		"lvs --noheadings --units m --nosuffix --separator , -o lv_size,data_percent vg0/thinpool": {"  102400.00,25.00\n"},
+	})
+	defer restore()
+	size, used, err := d.GetPoolSizeMb()
+	if err != nil {
+		t.Fatalf("GetPoolSizeMb: %v", err)
+	}
+	if size != 102400 || used != 25600 {
+		t.Errorf("GetPoolSizeMb got %d/%d, want 102400/25600", size, used)
+	}
+}
+
+func TestLvmBlockDiskSnapshots(t *testing.T) {
+	const (
+		snap      = "lvcreate -y -s -n snap_disk2_snap1 vg0/disk2"
+		saveSnap  = "lvcreate -y -s -n snap_disk2_save_ vg0/disk2"
+		saveClone = "lvcreate -y -s -kn -ay -p rw -n imagecache_disk2_ vg0/snap_disk2_save_"
+		clone     = "lvcreate -y -s -kn -ay -p rw -n disk2 vg0/snap_disk1_snap1"
+		size      = "lvs --noheadings --units m --nosuffix -o lv_size vg0/disk2"
+		extend    = "lvextend -L 20480m vg0/disk2"
+	)
+	lvs := map[string][]string{testLvs: {"  thinpool\n  disk1\n  disk2\n  snap_disk1_snap1\n  snap_disk2_snap1\n  snap_disk2_save_\n"}}
+	withLvs := func(cmd, output string) map[string][]string {
+		return map[string][]string{testLvs: lvs[testLvs], cmd: {output}}
+	}
+	createSnapshot := func(d *SBlockDisk) error { return d.CreateSnapshot("snap1") }
+	deleteSnapshot := func(d *SBlockDisk) error { return d.DeleteSnapshot("snap1", "", false) }
+	createFromSnapshot := func(d *SBlockDisk) error { return d.createFromSnapshot("disk1", "snap1", 20480) }
+	saveToGlance := func(d *SBlockDisk) error {
+		_, err := d.PrepareSaveToGlance(context.Background(), nil)
+		return err
+	}
+	testBlockDiskCases(t, newLvmVolumeDriver("vg0"), []sBlockDiskCase{
+		{
+			name: "create snapshot",
+			do:   createSnapshot,
+			want: []string{snap},
+		},
+		{
+			name:    "create snapshot failed",
+			fails:   []string{snap},
+			do:      createSnapshot,
+			wantErr: true,
+			want:    []string{snap},
+		},
+		{
+			name:    "delete snapshot",
+			outputs: lvs,
+			do:      deleteSnapshot,
+			want:    []string{testLvs, "lvremove -y vg0/snap_disk2_snap1"},
+		},
+		{
+			name:    "delete snapshot failed",
+			outputs: lvs,
+			fails:   []string{"lvremove -y vg0/snap_disk2_snap1"},
+			do:      deleteSnapshot,
+			wantErr: true,
+			want:    []string{testLvs, "lvremove -y vg0/snap_disk2_snap1"},
+		},
+		{
+			name:    "delete absent snapshot",
+			outputs: map[string][]string{testLvs: {"  thinpool\n  disk2\n"}},
+			do:      deleteSnapshot,
+			want:    []string{testLvs},
+		},
+		{
+			name:    "create from snapshot",
+			outputs: withLvs(size, "10240.00"),
+			do:      createFromSnapshot,
+			want:    []string{clone, size, extend},
+		},
+		{
+			name:    "create from snapshot of larger size",
+			outputs: withLvs(size, "30720.00"),
+			do:      createFromSnapshot,
+			want:    []string{clone, size},
+		},
+		{
+			name:    "create from snapshot clone failed",
+			fails:   []string{clone},
+			do:      createFromSnapshot,
+			wantErr: true,
+			want:    []string{clone},
+		},
+		{
+			name:    "create from snapshot resize failed",
+			outputs: withLvs(size, "10240.00"),
+			fails:   []string{extend},
+			do:      createFromSnapshot,
+			wantErr: true,
+			want:    []string{clone, size, extend, testLvs, "lvremove -y vg0/disk2"},
+		},
+		{
+			name:    "save to glance",
+			outputs: lvs,
+			do:      saveToGlance,
+			want:    []string{testLvs, saveSnap, saveClone, testLvs, "lvremove -y vg0/snap_disk2_save_"},
+		},
+		{
+			name:    "save to glance clone failed",
+			outputs: lvs,
+			fails:   []string{saveClone},
+			do:      saveToGlance,
+			wantErr: true,
+			want:    []string{testLvs, saveSnap, saveClone, testLvs, "lvremove -y vg0/snap_disk2_save_"},
+		},
+		{
+			name:    "save to glance snapshot failed",
+			outputs: lvs,
+			fails:   []string{saveSnap},
+			do:      saveToGlance,
+			wantErr: true,
+			want:    []string{testLvs, saveSnap},
+		},
+	})
+}
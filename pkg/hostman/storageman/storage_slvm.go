@@ -0,0 +1,598 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/gotypes"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/hostman/hostdeployer/deployclient"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/qemutils"
+)
+
+const (
+	SLVM_SNAPSHOT_PREFIX = "snap_"
+)
+
+type sSLVMStorageConf struct {
+	VgName string
+}
+
+// SSLVMStorage is a volume group on a LUN shared by all hosts over iSCSI/FC,
+// coordinated by lvmlockd. Disks, snapshots and cached images are thick
+// logical volumes, as thin pools can only be activated exclusively on one
+// host. A disk is activated exclusively on the host running its guest, and
+// shared by the source and destination host only during live migration, so
+// guests migrate without moving any data.
+type SSLVMStorage struct {
+	SBaseStorage
+	sSLVMStorageConf
+}
+
+func NewSLVMStorage(manager *SStorageManager, path string) *SSLVMStorage {
+	var ret = new(SSLVMStorage)
+	ret.SBaseStorage = *NewBaseStorage(manager, path)
+	ret.VgName = strings.TrimPrefix(path, "/dev/")
+	return ret
+}
+
+type SSLVMStorageFactory struct {
+}
+
+func (factory *SSLVMStorageFactory) NewStorage(manager *SStorageManager, mountPoint string) IStorage {
+	return NewSLVMStorage(manager, mountPoint)
+}
+
+func (factory *SSLVMStorageFactory) StorageType() string {
+	return api.STORAGE_SLVM
+}
+
+func init() {
+	registerStorageFactory(&SSLVMStorageFactory{})
+}
+
+func (s *SSLVMStorage) StorageType() string {
+	return api.STORAGE_SLVM
+}
+
+func (s *SSLVMStorage) lvm(cmd string, args ...string) (string, error) {
+	output, err := execBlockCommand(cmd, args...)
+	if err != nil {
+		return "", errors.Wrapf(err, "%s %s: %s", cmd, strings.Join(args, " "), output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (s *SSLVMStorage) lvFullName(name string) string {
+	return fmt.Sprintf("%s/%s", s.VgName, name)
+}
+
+func (s *SSLVMStorage) getLvPath(name string) string {
+	return path.Join("/dev", s.VgName, name)
+}
+
+func (s *SSLVMStorage) getSnapshotName(diskId, snapshotId string) string {
+	return fmt.Sprintf("%s%s_%s", SLVM_SNAPSHOT_PREFIX, diskId, snapshotId)
+}
+
+// getCowSnapshotName returns name of the temporary copy-on-write snapshot a
+// snapshot of running guest is copied from, it is removed together with the
+// snapshots of disk
+func (s *SSLVMStorage) getCowSnapshotName(diskId, snapshotId string) string {
+	return s.getSnapshotName(diskId, snapshotId) + "_cow"
+}
+
+func (s *SSLVMStorage) listLvs() ([]string, error) {
+	output, err := s.lvm("lvs", "--noheadings", "-o", "lv_name", s.VgName)
+	if err != nil {
+		return nil, err
+	}
+	lvs := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if lv := strings.TrimSpace(line); len(lv) > 0 {
+			lvs = append(lvs, lv)
+		}
+	}
+	return lvs, nil
+}
+
+func (s *SSLVMStorage) isLvExist(name string) (bool, error) {
+	lvs, err := s.listLvs()
+	if err != nil {
+		return false, errors.Wrapf(err, "listLvs")
+	}
+	return utils.IsInStringArray(name, lvs), nil
+}
+
+func (s *SSLVMStorage) getLvSizeMb(name string) (int64, error) {
+	output, err := s.lvm("lvs", "--noheadings", "--units", "m", "--nosuffix", "-o", "lv_size", s.lvFullName(name))
+	if err != nil {
+		return 0, err
+	}
+	size, err := strconv.ParseFloat(output, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse lv size %q", output)
+	}
+	return int64(size), nil
+}
+
+// getVgSizeMb returns the size and the used size of volume group
+func (s *SSLVMStorage) getVgSizeMb() (int64, int64, error) {
+	output, err := s.lvm("vgs", "--noheadings", "--units", "m", "--nosuffix", "--separator", ",", "-o", "vg_size,vg_free", s.VgName)
+	if err != nil {
+		return 0, 0, err
+	}
+	info := strings.Split(output, ",")
+	if len(info) != 2 {
+		return 0, 0, fmt.Errorf("invalid vg info %q", output)
+	}
+	size, err := strconv.ParseFloat(strings.TrimSpace(info[0]), 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse vg size %q", info[0])
+	}
+	free, err := strconv.ParseFloat(strings.TrimSpace(info[1]), 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse vg free %q", info[1])
+	}
+	return int64(size), int64(size - free), nil
+}
+
+// createLv creates a thick logical volume without activation, a volume not
+// activated can't be zeroed
+func (s *SSLVMStorage) createLv(name string, sizeMb int64) error {
+	_, err := s.lvm("lvcreate", "-y", "-an", "-Zn", "-L", fmt.Sprintf("%dm", sizeMb), "-n", name, s.VgName)
+	return err
+}
+
+// copyLv creates logical volume dest with the content of src, the copy is
+// independent of src, as snapshots of thick volumes in a shared volume group
+// can only be activated together with their origin
+func (s *SSLVMStorage) copyLv(src, dest string) error {
+	sizeMb, err := s.getLvSizeMb(src)
+	if err != nil {
+		return errors.Wrapf(err, "get lv %s size", src)
+	}
+	if err := s.createLv(dest, sizeMb); err != nil {
+		return errors.Wrapf(err, "create lv %s", dest)
+	}
+	err = s.withActiveLv(src, true, func(srcPath string) error {
+		return s.convertToLv(srcPath, "raw", dest)
+	})
+	if err != nil {
+		s.removeLv(dest)
+		return errors.Wrapf(err, "copy lv %s to %s", src, dest)
+	}
+	return nil
+}
+
+// createCowSnapshotLv creates a thick copy-on-write snapshot of origin, lvmlockd
+// only allows it while origin is exclusively active on this host. The snapshot
+// is as large as origin, so it never overflows however the guest writes
+func (s *SSLVMStorage) createCowSnapshotLv(origin, name string) error {
+	_, err := s.lvm("lvcreate", "-y", "-s", "-l", "100%ORIGIN", "-n", name, s.lvFullName(origin))
+	return err
+}
+
+func (s *SSLVMStorage) resizeLv(name string, sizeMb int64) error {
+	_, err := s.lvm("lvextend", "-L", fmt.Sprintf("%dm", sizeMb), s.lvFullName(name))
+	return err
+}
+
+func (s *SSLVMStorage) renameLv(src, dest string) error {
+	_, err := s.lvm("lvrename", s.VgName, src, dest)
+	return err
+}
+
+func (s *SSLVMStorage) removeLv(name string) error {
+	exist, err := s.isLvExist(name)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	_, err = s.lvm("lvremove", "-y", s.lvFullName(name))
+	return err
+}
+
+// activateLv takes the lvmlockd lock of logical volume, a shared lock allows the
+// volume being opened by guests of source and destination host during migration.
+// The lock of a volume already active on this host is converted
+func (s *SSLVMStorage) activateLv(name string, shared bool) error {
+	mode := "ey"
+	if shared {
+		mode = "sy"
+	}
+	_, err := s.lvm("lvchange", "-a", mode, s.lvFullName(name))
+	return err
+}
+
+func (s *SSLVMStorage) deactivateLv(name string) error {
+	_, err := s.lvm("lvchange", "-an", s.lvFullName(name))
+	return err
+}
+
+func (s *SSLVMStorage) isLvActive(name string) (bool, error) {
+	output, err := s.lvm("lvs", "--noheadings", "-o", "lv_active_locally", s.lvFullName(name))
+	if err != nil {
+		return false, err
+	}
+	return len(output) > 0, nil
+}
+
+// withActiveLv activates logical volume on this host while running f, volumes
+// only read by f may be activated shared by several hosts at the same time.
+// A volume already active on this host, e.g. opened by a running guest, is
+// left as it is
+func (s *SSLVMStorage) withActiveLv(name string, shared bool, f func(lvPath string) error) error {
+	active, err := s.isLvActive(name)
+	if err != nil {
+		return errors.Wrapf(err, "check %s active", name)
+	}
+	if active {
+		return f(s.getLvPath(name))
+	}
+	if err := s.activateLv(name, shared); err != nil {
+		return errors.Wrapf(err, "activate %s", name)
+	}
+	defer func() {
+		if err := s.deactivateLv(name); err != nil {
+			log.Errorf("deactivate %s error: %v", name, err)
+		}
+	}()
+	return f(s.getLvPath(name))
+}
+
+func (s *SSLVMStorage) convertToLv(srcPath, srcFormat, name string) error {
+	return s.withActiveLv(name, false, func(lvPath string) error {
+		output, err := execBlockCommand(qemutils.GetQemuImg(),
+			"convert", "-W", "-m", "16", "-n", "-f", srcFormat, "-O", "raw", srcPath, lvPath)
+		if err != nil {
+			return errors.Wrapf(err, "convert %s to %s: %s", srcPath, lvPath, output)
+		}
+		return nil
+	})
+}
+
+func (s *SSLVMStorage) GetSnapshotDir() string {
+	return ""
+}
+
+func (s *SSLVMStorage) GetSnapshotPathByIds(diskId, snapshotId string) string {
+	return s.getLvPath(s.getSnapshotName(diskId, snapshotId))
+}
+
+func (s *SSLVMStorage) IsSnapshotExist(diskId, snapshotId string) (bool, error) {
+	return s.isLvExist(s.getSnapshotName(diskId, snapshotId))
+}
+
+func (s *SSLVMStorage) GetFuseTmpPath() string {
+	return ""
+}
+
+func (s *SSLVMStorage) GetFuseMountPath() string {
+	return ""
+}
+
+func (s *SSLVMStorage) GetImgsaveBackupPath() string {
+	return ""
+}
+
+func (s *SSLVMStorage) GetBackupDir() string {
+	return ""
+}
+
+func (s *SSLVMStorage) GetFreeSizeMb() int {
+	size, used, err := s.getVgSizeMb()
+	if err != nil {
+		log.Errorf("failed get vg %s free size: %s", s.VgName, err)
+		return -1
+	}
+	return int(size - used)
+}
+
+func (s *SSLVMStorage) GetCapacity() int {
+	size, _, err := s.getVgSizeMb()
+	if err != nil {
+		log.Errorf("failed get vg %s size: %s", s.VgName, err)
+		return -1
+	}
+	return int(size)
+}
+
+func (s *SSLVMStorage) SetStorageInfo(storageId, storageName string, conf jsonutils.JSONObject) error {
+	s.StorageId = storageId
+	s.StorageName = storageName
+	if gotypes.IsNil(conf) {
+		return fmt.Errorf("empty storage conf for storage %s(%s)", storageName, storageId)
+	}
+	if dconf, ok := conf.(*jsonutils.JSONDict); ok {
+		s.StorageConf = dconf
+	}
+	conf.Unmarshal(&s.sSLVMStorageConf)
+	if len(s.VgName) == 0 {
+		return fmt.Errorf("empty vg_name for storage %s(%s)", storageName, storageId)
+	}
+	return s.Accessible()
+}
+
+func (s *SSLVMStorage) SyncStorageSize() error {
+	size, used, err := s.getVgSizeMb()
+	if err != nil {
+		return errors.Wrapf(err, "getVgSizeMb")
+	}
+	content := jsonutils.NewDict()
+	content.Set("capacity", jsonutils.NewInt(size))
+	content.Set("actual_capacity_used", jsonutils.NewInt(used))
+	_, err = modules.Storages.Put(
+		hostutils.GetComputeSession(context.Background()),
+		s.StorageId, content)
+	return errors.Wrapf(err, "storage update")
+}
+
+func (s *SSLVMStorage) SyncStorageInfo() (jsonutils.JSONObject, error) {
+	if len(s.StorageId) == 0 {
+		return nil, fmt.Errorf("Sync shared lvm storage without storage id")
+	}
+	size, used, err := s.getVgSizeMb()
+	if err != nil {
+		return modules.Storages.PerformAction(hostutils.GetComputeSession(context.Background()), s.StorageId, "offline", nil)
+	}
+	content := map[string]interface{}{
+		"name":                 s.StorageName,
+		"capacity":             size,
+		"actual_capacity_used": used,
+		"status":               api.STORAGE_ONLINE,
+		"zone":                 s.GetZoneName(),
+	}
+	return modules.Storages.Put(hostutils.GetComputeSession(context.Background()), s.StorageId, jsonutils.Marshal(content))
+}
+
+func (s *SSLVMStorage) GetDiskById(diskId string) (IDisk, error) {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	for i := 0; i < len(s.Disks); i++ {
+		if s.Disks[i].GetId() == diskId {
+			err := s.Disks[i].Probe()
+			if err != nil {
+				return nil, errors.Wrapf(err, "disk.Probe")
+			}
+			return s.Disks[i], nil
+		}
+	}
+	var disk = NewSLVMDisk(s, diskId)
+	if disk.Probe() == nil {
+		s.Disks = append(s.Disks, disk)
+		return disk, nil
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (s *SSLVMStorage) CreateDisk(diskId string) IDisk {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	disk := NewSLVMDisk(s, diskId)
+	s.Disks = append(s.Disks, disk)
+	return disk
+}
+
+// Accessible starts the lockspace of the shared volume group on this host, it
+// is a no-op if the lockspace has already been started
+func (s *SSLVMStorage) Accessible() error {
+	if _, err := s.lvm("vgchange", "--lock-start", s.VgName); err != nil {
+		return errors.Wrapf(err, "start lockspace of vg %s", s.VgName)
+	}
+	return nil
+}
+
+func (s *SSLVMStorage) Detach() error {
+	_, err := s.lvm("vgchange", "--lock-stop", s.VgName)
+	return err
+}
+
+func (s *SSLVMStorage) DeleteDiskfile(diskPath string, skipRecycle bool) error {
+	return s.removeLv(path.Base(diskPath))
+}
+
+func (s *SSLVMStorage) SaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	data, ok := params.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+
+	imageCache := storageManager.GetStoragecacheById(s.GetStoragecacheId())
+	if imageCache == nil {
+		return nil, fmt.Errorf("failed to find storage image cache for storage %s", s.GetStorageName())
+	}
+
+	imagePath, _ := data.GetString("image_path")
+	compress := jsonutils.QueryBoolean(data, "compress", true)
+	format, _ := data.GetString("format")
+	imageId, _ := data.GetString("image_id")
+	imageName := SLVM_IMAGECACHE_PREFIX + imageId
+	if err := s.renameLv(imagePath, imageName); err != nil {
+		return nil, err
+	}
+
+	err := s.withActiveLv(imageName, true, func(lvPath string) error {
+		return saveRawDeviceToGlance(ctx, s.GetZoneName(), imageId, lvPath, compress, format)
+	})
+	if err != nil {
+		log.Errorf("Save to glance failed: %s", err)
+		onRawDeviceSaveToGlanceFailed(ctx, s.GetZoneName(), imageId)
+	}
+
+	imageCache.LoadImageCache(imageId)
+	_, err = hostutils.RemoteStoragecacheCacheImage(ctx, imageCache.GetId(), imageId, "active", s.getLvPath(imageName))
+	if err != nil {
+		log.Errorf("Fail to remote cache image: %v", err)
+	}
+	return nil, nil
+}
+
+func onRawDeviceSaveToGlanceFailed(ctx context.Context, zoneName, imageId string) {
+	params := jsonutils.NewDict()
+	params.Set("status", jsonutils.NewString("killed"))
+	_, err := image.Images.Update(hostutils.GetImageSession(ctx, zoneName),
+		imageId, params)
+	if err != nil {
+		log.Errorln(err)
+	}
+}
+
+// saveRawDeviceToGlance uploads a raw block device, such as a logical volume or zvol, as image
+func saveRawDeviceToGlance(ctx context.Context, zoneName, imageId, imagePath string, compress bool, format string) error {
+	ret, err := deployclient.GetDeployClient().SaveToGlance(context.Background(),
+		&deployapi.SaveToGlanceParams{DiskPath: imagePath, Compress: compress})
+	if err != nil {
+		return err
+	}
+
+	tmpImageFile := fmt.Sprintf("/tmp/%s.img", imageId)
+	if len(format) == 0 {
+		format = options.HostOptions.DefaultImageSaveFormat
+	}
+
+	err = procutils.NewRemoteCommandAsFarAsPossible(qemutils.GetQemuImg(),
+		"convert", "-f", "raw", "-O", format, imagePath, tmpImageFile).Run()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(tmpImageFile)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpImageFile)
+	defer f.Close()
+
+	finfo, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := finfo.Size()
+
+	var params = jsonutils.NewDict()
+	if len(ret.OsInfo) > 0 {
+		params.Set("os_type", jsonutils.NewString(ret.OsInfo))
+	}
+	relInfo := ret.ReleaseInfo
+	if relInfo != nil {
+		params.Set("os_distribution", jsonutils.NewString(relInfo.Distro))
+		if len(relInfo.Version) > 0 {
+			params.Set("os_version", jsonutils.NewString(relInfo.Version))
+		}
+		if len(relInfo.Arch) > 0 {
+			params.Set("os_arch", jsonutils.NewString(relInfo.Arch))
+		}
+		if len(relInfo.Language) > 0 {
+			params.Set("os_language", jsonutils.NewString(relInfo.Language))
+		}
+	}
+	params.Set("image_id", jsonutils.NewString(imageId))
+
+	_, err = image.Images.Upload(hostutils.GetImageSession(ctx, zoneName),
+		params, f, size)
+	return err
+}
+
+func (s *SSLVMStorage) CreateSnapshotFormUrl(ctx context.Context, snapshotUrl, diskId, snapshotPath string) error {
+	return fmt.Errorf("Not support")
+}
+
+func (s *SSLVMStorage) DeleteSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	diskId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	return nil, s.deleteDiskSnapshots(diskId)
+}
+
+func (s *SSLVMStorage) deleteDiskSnapshots(diskId string) error {
+	lvs, err := s.listLvs()
+	if err != nil {
+		return errors.Wrapf(err, "listLvs")
+	}
+	prefix := s.getSnapshotName(diskId, "")
+	for _, lv := range lvs {
+		if strings.HasPrefix(lv, prefix) {
+			if err := s.removeLv(lv); err != nil {
+				return errors.Wrapf(err, "remove snapshot %s", lv)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SSLVMStorage) CreateDiskFromSnapshot(ctx context.Context, disk IDisk, input *SDiskCreateByDiskinfo) error {
+	info := input.DiskInfo
+	return disk.(*SSLVMDisk).createFromSnapshot(info.SrcPool, info.SrcDiskId, info.SnapshotUrl, int64(info.DiskSizeMb))
+}
+
+func (s *SSLVMStorage) CreateDiskFromBackup(ctx context.Context, disk IDisk, input *SDiskCreateByDiskinfo) error {
+	return fmt.Errorf("Not support")
+}
+
+func (s *SSLVMStorage) GetCloneTargetDiskPath(ctx context.Context, targetDiskId string) string {
+	return s.getLvPath(targetDiskId)
+}
+
+func (s *SSLVMStorage) CloneDiskFromStorage(ctx context.Context, srcStorage IStorage, srcDisk IDisk, targetDiskId string) (*hostapi.ServerCloneDiskFromStorageResponse, error) {
+	srcDiskPath := srcDisk.GetPath()
+	srcImg, err := qemuimg.NewQemuImage(srcDiskPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Get source image %q info", srcDiskPath)
+	}
+	if err := s.createLv(targetDiskId, int64(srcImg.GetSizeMB())); err != nil {
+		return nil, errors.Wrapf(err, "create lv %s", targetDiskId)
+	}
+	if err := s.convertToLv(srcDiskPath, srcImg.Format.String(), targetDiskId); err != nil {
+		return nil, errors.Wrap(err, "Clone source disk to target shared lvm storage")
+	}
+	return &hostapi.ServerCloneDiskFromStorageResponse{
+		TargetAccessPath: s.getLvPath(targetDiskId),
+		TargetFormat:     qemuimg.RAW.String(),
+	}, nil
+}
+
+func getSLVMStorageByVg(vgName string) *SSLVMStorage {
+	manager := GetManager()
+	for i := range manager.Storages {
+		if s, ok := manager.Storages[i].(*SSLVMStorage); ok && s.VgName == vgName {
+			return s
+		}
+	}
+	return nil
+}
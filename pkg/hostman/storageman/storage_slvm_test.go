@@ -0,0 +1,185 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"strings"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/util/qemutils"
+)
+
+const (
+	testSLVMSnap    = "snap_disk1_snap1"
+	testSLVMCow     = "snap_disk1_snap1_cow"
+	testSLVMActive  = "lvs --noheadings -o lv_active_locally vg0/"
+	testSLVMSize    = "lvs --noheadings --units m --nosuffix -o lv_size vg0/"
+	testSLVMConvert = " convert -W -m 16 -n -f raw -O raw /dev/vg0/"
+)
+
+func newTestSLVMDisk() *SSLVMDisk {
+	return NewSLVMDisk(NewSLVMStorage(nil, "/dev/vg0"), "disk1")
+}
+
+func TestSLVMDiskActivation(t *testing.T) {
+	cases := []struct {
+		name string
+		do   func(d *SSLVMDisk) error
+		want []string
+	}{
+		{
+			name: "activate exclusive",
+			do:   func(d *SSLVMDisk) error { return d.ActivateExclusive() },
+			want: []string{"lvchange -a ey vg0/disk1"},
+		},
+		{
+			name: "prepare live migrate",
+			do: func(d *SSLVMDisk) error {
+				_, err := d.PrepareMigrate(true)
+				return err
+			},
+			want: []string{"lvchange -a sy vg0/disk1"},
+		},
+		{
+			name: "prepare offline migrate",
+			do: func(d *SSLVMDisk) error {
+				_, err := d.PrepareMigrate(false)
+				return err
+			},
+			want: nil,
+		},
+		{
+			name: "deactivate",
+			do:   func(d *SSLVMDisk) error { return d.Deactivate() },
+			want: []string{"lvchange -an vg0/disk1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, restore := mockBlockCommands(nil)
+			defer restore()
+			if err := c.do(newTestSLVMDisk()); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			f.check(t, c.want)
+		})
+	}
+
+	d := newTestSLVMDisk()
+	if script := d.GetDiskSetupScripts(0); !strings.HasPrefix(script, "lvchange -aey vg0/disk1 || exit 1\n") {
+		t.Errorf("setup script %q should activate the volume exclusively", script)
+	}
+	if script := d.GetMigrateDiskSetupScripts(0); !strings.HasPrefix(script, "lvchange -asy vg0/disk1 || exit 1\n") {
+		t.Errorf("migrate setup script %q should activate the volume shared", script)
+	}
+	if script := d.GetDiskTeardownScripts(); script != "lvchange -an vg0/disk1\n" {
+		t.Errorf("teardown script %q should deactivate the volume", script)
+	}
+}
+
+func TestSLVMDiskCreateSnapshot(t *testing.T) {
+	qemuImg := qemutils.GetQemuImg()
+	lvs := "  disk1\n  " + testSLVMSnap + "\n  " + testSLVMCow + "\n"
+
+	t.Run("stopped guest", func(t *testing.T) {
+		f, restore := mockBlockCommands(map[string][]string{
+			testSLVMSize + "disk1": {"  10240.00\n"},
+		})
+		defer restore()
+		if err := newTestSLVMDisk().CreateSnapshot("snap1"); err != nil {
+			t.Fatalf("CreateSnapshot: %v", err)
+		}
+		f.check(t, []string{
+			testSLVMActive + "disk1",
+			testSLVMSize + "disk1",
+			"lvcreate -y -an -Zn -L 10240m -n " + testSLVMSnap + " vg0",
+			testSLVMActive + "disk1",
+			"lvchange -a sy vg0/disk1",
+			testSLVMActive + testSLVMSnap,
+			"lvchange -a ey vg0/" + testSLVMSnap,
+			qemuImg + testSLVMConvert + "disk1 /dev/vg0/" + testSLVMSnap,
+			"lvchange -an vg0/" + testSLVMSnap,
+			"lvchange -an vg0/disk1",
+		})
+	})
+
+	t.Run("running guest", func(t *testing.T) {
+		f, restore := mockBlockCommands(map[string][]string{
+			testSLVMActive + "disk1":     {"  active locally\n"},
+			testSLVMActive + testSLVMCow: {"  active locally\n"},
+			testSLVMSize + testSLVMCow:   {"  10240.00\n"},
+			testLvs:                      {lvs},
+		})
+		defer restore()
+		if err := newTestSLVMDisk().CreateSnapshot("snap1"); err != nil {
+			t.Fatalf("CreateSnapshot: %v", err)
+		}
+		f.check(t, []string{
+			testSLVMActive + "disk1",
+			"lvcreate -y -s -l 100%ORIGIN -n " + testSLVMCow + " vg0/disk1",
+			testSLVMSize + testSLVMCow,
+			"lvcreate -y -an -Zn -L 10240m -n " + testSLVMSnap + " vg0",
+			testSLVMActive + testSLVMCow,
+			testSLVMActive + testSLVMSnap,
+			"lvchange -a ey vg0/" + testSLVMSnap,
+			qemuImg + testSLVMConvert + testSLVMCow + " /dev/vg0/" + testSLVMSnap,
+			"lvchange -an vg0/" + testSLVMSnap,
+			testLvs,
+			"lvremove -y vg0/" + testSLVMCow,
+		})
+	})
+
+	t.Run("running guest copy failed", func(t *testing.T) {
+		convert := qemuImg + testSLVMConvert + testSLVMCow + " /dev/vg0/" + testSLVMSnap
+		f, restore := mockBlockCommands(map[string][]string{
+			testSLVMActive + "disk1":     {"  active locally\n"},
+			testSLVMActive + testSLVMCow: {"  active locally\n"},
+			testSLVMSize + testSLVMCow:   {"  10240.00\n"},
+			testLvs:                      {lvs},
+		}, convert)
+		defer restore()
+		if err := newTestSLVMDisk().CreateSnapshot("snap1"); err == nil {
+			t.Fatalf("CreateSnapshot should fail when the copy fails")
+		}
+		f.check(t, []string{
+			testSLVMActive + "disk1",
+			"lvcreate -y -s -l 100%ORIGIN -n " + testSLVMCow + " vg0/disk1",
+			testSLVMSize + testSLVMCow,
+			"lvcreate -y -an -Zn -L 10240m -n " + testSLVMSnap + " vg0",
+			testSLVMActive + testSLVMCow,
+			testSLVMActive + testSLVMSnap,
+			"lvchange -a ey vg0/" + testSLVMSnap,
+			convert,
+			"lvchange -an vg0/" + testSLVMSnap,
+			testLvs,
+			"lvremove -y vg0/" + testSLVMSnap,
+			testLvs,
+			"lvremove -y vg0/" + testSLVMCow,
+		})
+	})
+
+	t.Run("volume shared during migration", func(t *testing.T) {
+		cow := "lvcreate -y -s -l 100%ORIGIN -n " + testSLVMCow + " vg0/disk1"
+		f, restore := mockBlockCommands(map[string][]string{
+			testSLVMActive + "disk1": {"  active locally\n"},
+		}, cow)
+		defer restore()
+		err := newTestSLVMDisk().CreateSnapshot("snap1")
+		if err == nil || !strings.Contains(err.Error(), "exclusively active") {
+			t.Fatalf("CreateSnapshot of shared volume got %v, want exclusive activation error", err)
+		}
+		f.check(t, []string{testSLVMActive + "disk1", cow})
+	})
+}
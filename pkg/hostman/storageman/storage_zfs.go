@@ -0,0 +1,333 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	// ZFS_CLONE_BASE_SNAPSHOT is the snapshot of a volume which its clones
+	// are created from, e.g. the disks created from a cached image
+	ZFS_CLONE_BASE_SNAPSHOT = "clone_base"
+)
+
+// sZfsVolumeDriver manages sparse zvols of a zfs dataset, snapshots are native
+// zfs snapshots <dataset>/<volume>@<snapshot>
+type sZfsVolumeDriver struct {
+	Pool    string
+	Dataset string
+}
+
+// newZfsVolumeDriver parses conf in format of <pool>[/<dataset>]
+func newZfsVolumeDriver(conf string) *sZfsVolumeDriver {
+	dataset := strings.Trim(conf, "/")
+	return &sZfsVolumeDriver{
+		Pool:    strings.Split(dataset, "/")[0],
+		Dataset: dataset,
+	}
+}
+
+func (d *sZfsVolumeDriver) zfs(args ...string) (string, error) {
+	output, err := execBlockCommand("zfs", args...)
+	if err != nil {
+		return "", errors.Wrapf(err, "zfs %s: %s", strings.Join(args, " "), output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (d *sZfsVolumeDriver) volFullName(name string) string {
+	return fmt.Sprintf("%s/%s", d.Dataset, name)
+}
+
+func (d *sZfsVolumeDriver) snapFullName(volume, snapshot string) string {
+	return fmt.Sprintf("%s@%s", d.volFullName(volume), snapshot)
+}
+
+// waitVolumeDevice waits for udev creating the device node of zvol
+func (d *sZfsVolumeDriver) waitVolumeDevice(name string) error {
+	devPath := d.GetVolumePath(name)
+	output, err := execBlockCommand("udevadm", "settle", "--exit-if-exists="+devPath)
+	if err != nil {
+		return errors.Wrapf(err, "udevadm settle: %s", output)
+	}
+	if _, err := execBlockCommand("test", "-b", devPath); err != nil {
+		return errors.Wrapf(err, "device %s not found", devPath)
+	}
+	return nil
+}
+
+func (d *sZfsVolumeDriver) StorageType() string {
+	return api.STORAGE_ZFS
+}
+
+func (d *sZfsVolumeDriver) GetPoolName() string {
+	return d.Dataset
+}
+
+func (d *sZfsVolumeDriver) GetStorageConf() *jsonutils.JSONDict {
+	conf := jsonutils.NewDict()
+	conf.Set("pool", jsonutils.NewString(d.Pool))
+	conf.Set("dataset", jsonutils.NewString(d.Dataset))
+	return conf
+}
+
+func (d *sZfsVolumeDriver) Accessible() error {
+	if _, err := d.zfs("list", "-H", "-o", "name", d.Dataset); err != nil {
+		return errors.Wrapf(err, "dataset %s not found", d.Dataset)
+	}
+	return nil
+}
+
+func (d *sZfsVolumeDriver) GetPoolSizeMb() (int64, int64, error) {
+	output, err := d.zfs("list", "-H", "-p", "-o", "used,avail", d.Dataset)
+	if err != nil {
+		return 0, 0, err
+	}
+	info := strings.Fields(output)
+	if len(info) != 2 {
+		return 0, 0, fmt.Errorf("invalid dataset info %q", output)
+	}
+	used, err := strconv.ParseInt(info[0], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse dataset used %q", info[0])
+	}
+	avail, err := strconv.ParseInt(info[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse dataset avail %q", info[1])
+	}
+	return (used + avail) / 1024 / 1024, used / 1024 / 1024, nil
+}
+
+func (d *sZfsVolumeDriver) GetVolumePath(name string) string {
+	return path.Join("/dev/zvol", d.Dataset, name)
+}
+
+func (d *sZfsVolumeDriver) ListVolumes() ([]string, error) {
+	output, err := d.zfs("list", "-H", "-o", "name", "-t", "volume", "-d", "1", d.Dataset)
+	if err != nil {
+		return nil, err
+	}
+	vols := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if vol := strings.TrimSpace(line); len(vol) > 0 {
+			vols = append(vols, strings.TrimPrefix(vol, d.Dataset+"/"))
+		}
+	}
+	return vols, nil
+}
+
+func (d *sZfsVolumeDriver) IsVolumeExist(name string) (bool, error) {
+	vols, err := d.ListVolumes()
+	if err != nil {
+		return false, errors.Wrapf(err, "ListVolumes")
+	}
+	return utils.IsInStringArray(name, vols), nil
+}
+
+func (d *sZfsVolumeDriver) GetVolumeSizeMb(name string) (int64, error) {
+	output, err := d.zfs("get", "-H", "-p", "-o", "value", "volsize", d.volFullName(name))
+	if err != nil {
+		return 0, err
+	}
+	size, err := strconv.ParseInt(output, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse volsize %q", output)
+	}
+	return size / 1024 / 1024, nil
+}
+
+func (d *sZfsVolumeDriver) CreateVolume(name string, sizeMb int64) error {
+	if _, err := d.zfs("create", "-s", "-V", fmt.Sprintf("%dM", sizeMb), d.volFullName(name)); err != nil {
+		return err
+	}
+	if err := d.waitVolumeDevice(name); err != nil {
+		d.destroyUnusableVolume(name)
+		return err
+	}
+	return nil
+}
+
+// destroyUnusableVolume cleans up the volume just created whose device node
+// never shows up, so that a retry won't fail on an existing dataset
+func (d *sZfsVolumeDriver) destroyUnusableVolume(name string) {
+	if _, err := d.zfs("destroy", "-r", d.volFullName(name)); err != nil {
+		log.Errorf("destroy volume %s error: %v", d.volFullName(name), err)
+	}
+}
+
+func (d *sZfsVolumeDriver) ResizeVolume(name string, sizeMb int64) error {
+	_, err := d.zfs("set", fmt.Sprintf("volsize=%dM", sizeMb), d.volFullName(name))
+	return err
+}
+
+func (d *sZfsVolumeDriver) RenameVolume(src, dest string) error {
+	if _, err := d.zfs("rename", d.volFullName(src), d.volFullName(dest)); err != nil {
+		return err
+	}
+	return d.waitVolumeDevice(dest)
+}
+
+// RemoveVolume destroys the volume along with its snapshots
+func (d *sZfsVolumeDriver) RemoveVolume(name string) error {
+	exist, err := d.IsVolumeExist(name)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	_, err = d.zfs("destroy", "-r", d.volFullName(name))
+	return err
+}
+
+// ReleaseVolumeClones promotes the clones of volume snapshots, a promoted
+// clone takes over the snapshots it depends on from the volume
+func (d *sZfsVolumeDriver) ReleaseVolumeClones(name string) error {
+	originPrefix := d.volFullName(name) + "@"
+	for {
+		output, err := d.zfs("list", "-H", "-o", "name,origin", "-t", "volume", "-d", "1", d.Dataset)
+		if err != nil {
+			return err
+		}
+		clone := ""
+		for _, line := range strings.Split(output, "\n") {
+			info := strings.Fields(line)
+			if len(info) == 2 && strings.HasPrefix(info[1], originPrefix) {
+				clone = info[0]
+				break
+			}
+		}
+		if len(clone) == 0 {
+			return nil
+		}
+		log.Infof("promote zvol %s cloned from %s", clone, name)
+		if _, err := d.zfs("promote", clone); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *sZfsVolumeDriver) SetVolumeReadonly(name string) error {
+	_, err := d.zfs("set", "readonly=on", d.volFullName(name))
+	return err
+}
+
+// CloneVolume clones the base snapshot of src, the base snapshot is kept until src is removed
+func (d *sZfsVolumeDriver) CloneVolume(src, target string) error {
+	exist, err := d.IsSnapshotExist(src, ZFS_CLONE_BASE_SNAPSHOT)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		if err := d.CreateSnapshot(src, ZFS_CLONE_BASE_SNAPSHOT); err != nil {
+			return errors.Wrapf(err, "create base snapshot of %s", src)
+		}
+	}
+	return d.CloneSnapshot(src, ZFS_CLONE_BASE_SNAPSHOT, target)
+}
+
+func (d *sZfsVolumeDriver) CreateSnapshot(volume, snapshot string) error {
+	_, err := d.zfs("snapshot", d.snapFullName(volume, snapshot))
+	return err
+}
+
+func (d *sZfsVolumeDriver) listVolumeSnapshots(volume string) ([]string, error) {
+	output, err := d.zfs("list", "-H", "-o", "name", "-t", "snapshot", "-d", "1", d.volFullName(volume))
+	if err != nil {
+		return nil, err
+	}
+	prefix := d.snapFullName(volume, "")
+	snapshots := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if snap := strings.TrimSpace(line); strings.HasPrefix(snap, prefix) {
+			snapshots = append(snapshots, strings.TrimPrefix(snap, prefix))
+		}
+	}
+	return snapshots, nil
+}
+
+func (d *sZfsVolumeDriver) IsSnapshotExist(volume, snapshot string) (bool, error) {
+	snapshots, err := d.listVolumeSnapshots(volume)
+	if err != nil {
+		return false, errors.Wrapf(err, "listVolumeSnapshots")
+	}
+	return utils.IsInStringArray(snapshot, snapshots), nil
+}
+
+// ListSnapshots returns snapshots of the volume except the clone base snapshot
+func (d *sZfsVolumeDriver) ListSnapshots(volume string) ([]string, error) {
+	snapshots, err := d.listVolumeSnapshots(volume)
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, snapshot := range snapshots {
+		if snapshot != ZFS_CLONE_BASE_SNAPSHOT {
+			ret = append(ret, snapshot)
+		}
+	}
+	return ret, nil
+}
+
+// RemoveSnapshot destroys the snapshot deferred, it is destroyed
+// after the last clone of it is removed
+func (d *sZfsVolumeDriver) RemoveSnapshot(volume, snapshot string) error {
+	exist, err := d.IsSnapshotExist(volume, snapshot)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	_, err = d.zfs("destroy", "-d", d.snapFullName(volume, snapshot))
+	return err
+}
+
+func (d *sZfsVolumeDriver) CloneSnapshot(volume, snapshot, target string) error {
+	if _, err := d.zfs("clone", d.snapFullName(volume, snapshot), d.volFullName(target)); err != nil {
+		return err
+	}
+	if err := d.waitVolumeDevice(target); err != nil {
+		d.destroyUnusableVolume(target)
+		return err
+	}
+	return nil
+}
+
+// RollbackSnapshot rolls the volume back in place, snapshots taken after
+// the snapshot are destroyed by zfs
+func (d *sZfsVolumeDriver) RollbackSnapshot(volume, snapshot string) error {
+	exist, err := d.IsSnapshotExist(volume, snapshot)
+	if err != nil {
+		return errors.Wrapf(err, "IsSnapshotExist")
+	}
+	if !exist {
+		return errors.Wrapf(cloudprovider.ErrNotFound, "snapshot %s", d.snapFullName(volume, snapshot))
+	}
+	_, err = d.zfs("rollback", "-r", d.snapFullName(volume, snapshot))
+	return err
+}
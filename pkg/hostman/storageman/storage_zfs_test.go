@@ -0,0 +1,327 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	testZfsVolumes   = "zfs list -H -o name -t volume -d 1 tank/vols"
+	testZfsOrigins   = "zfs list -H -o name,origin -t volume -d 1 tank/vols"
+	testZfsSnapshots = "zfs list -H -o name -t snapshot -d 1 tank/vols/disk1"
+)
+
+func testZfsWaitDevice(name string) []string {
+	devPath := "/dev/zvol/tank/vols/" + name
+	return []string{"udevadm settle --exit-if-exists=" + devPath, "test -b " + devPath}
+}
+
+func TestZfsVolumeDriverCommands(t *testing.T) {
+	d := newZfsVolumeDriver("/tank/vols/")
+	cases := []struct {
+		name string
+		do   func() error
+		want []string
+	}{
+		{
+			name: "CreateVolume",
+			do:   func() error { return d.CreateVolume("disk1", 10240) },
+			want: append([]string{"zfs create -s -V 10240M tank/vols/disk1"}, testZfsWaitDevice("disk1")...),
+		},
+		{
+			name: "ResizeVolume",
+			do:   func() error { return d.ResizeVolume("disk1", 20480) },
+			want: []string{"zfs set volsize=20480M tank/vols/disk1"},
+		},
+		{
+			name: "RenameVolume",
+			do:   func() error { return d.RenameVolume("disk1", "disk2") },
+			want: append([]string{"zfs rename tank/vols/disk1 tank/vols/disk2"}, testZfsWaitDevice("disk2")...),
+		},
+		{
+			name: "SetVolumeReadonly",
+			do:   func() error { return d.SetVolumeReadonly("disk1") },
+			want: []string{"zfs set readonly=on tank/vols/disk1"},
+		},
+		{
+			name: "RemoveVolume",
+			do:   func() error { return d.RemoveVolume("disk1") },
+			want: []string{testZfsVolumes, "zfs destroy -r tank/vols/disk1"},
+		},
+		{
+			name: "CreateSnapshot",
+			do:   func() error { return d.CreateSnapshot("disk1", "snap1") },
+			want: []string{"zfs snapshot tank/vols/disk1@snap1"},
+		},
+		{
+			name: "RemoveSnapshot",
+			do:   func() error { return d.RemoveSnapshot("disk1", "snap1") },
+			want: []string{testZfsSnapshots, "zfs destroy -d tank/vols/disk1@snap1"},
+		},
+		{
+			name: "CloneSnapshot",
+			do:   func() error { return d.CloneSnapshot("disk1", "snap1", "disk2") },
+			want: append([]string{"zfs clone tank/vols/disk1@snap1 tank/vols/disk2"}, testZfsWaitDevice("disk2")...),
+		},
+		{
+			name: "CloneVolumeWithBase",
+			do:   func() error { return d.CloneVolume("disk1", "disk2") },
+			want: append([]string{testZfsSnapshots, "zfs clone tank/vols/disk1@clone_base tank/vols/disk2"}, testZfsWaitDevice("disk2")...),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, restore := mockBlockCommands(map[string][]string{
+				testZfsVolumes:   {"tank/vols/disk1\ntank/vols/imagecache_img1\n"},
+				testZfsSnapshots: {"tank/vols/disk1@clone_base\ntank/vols/disk1@snap1\n"},
+			})
+			defer restore()
+			if err := c.do(); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			f.check(t, c.want)
+		})
+	}
+}
+
+func TestZfsVolumeDriverCloneVolume(t *testing.T) {
+	d := newZfsVolumeDriver("tank/vols")
+	f, restore := mockBlockCommands(map[string][]string{
+		testZfsSnapshots: {"tank/vols/disk1@snap1\n"},
+	})
+	defer restore()
+	if err := d.CloneVolume("disk1", "disk2"); err != nil {
+		t.Fatalf("CloneVolume: %v", err)
+	}
+	f.check(t, append([]string{
+		testZfsSnapshots,
+		"zfs snapshot tank/vols/disk1@clone_base",
+		"zfs clone tank/vols/disk1@clone_base tank/vols/disk2",
+	}, testZfsWaitDevice("disk2")...))
+}
+
+func TestZfsVolumeDriverListSnapshots(t *testing.T) {
+	d := newZfsVolumeDriver("tank/vols")
+	_, restore := mockBlockCommands(map[string][]string{
+		testZfsSnapshots: {"tank/vols/disk1@clone_base\ntank/vols/disk1@snap1\ntank/vols/disk1@snap2\n"},
+	})
+	defer restore()
+	snapshots, err := d.ListSnapshots("disk1")
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0] != "snap1" || snapshots[1] != "snap2" {
+		t.Errorf("ListSnapshots got %v, want [snap1 snap2]", snapshots)
+	}
+}
+
+func TestZfsVolumeDriverRollbackSnapshot(t *testing.T) {
+	d := newZfsVolumeDriver("tank/vols")
+	snapshots := "tank/vols/disk1@snap1\ntank/vols/disk1@snap2\n"
+
+	f, restore := mockBlockCommands(map[string][]string{testZfsSnapshots: {snapshots}})
+	err := d.RollbackSnapshot("disk1", "snap1")
+	restore()
+	if err != nil {
+		t.Fatalf("RollbackSnapshot: %v", err)
+	}
+	f.check(t, []string{testZfsSnapshots, "zfs rollback -r tank/vols/disk1@snap1"})
+
+	f, restore = mockBlockCommands(map[string][]string{testZfsSnapshots: {snapshots}})
+	err = d.RollbackSnapshot("disk1", "snap3")
+	restore()
+	if errors.Cause(err) != cloudprovider.ErrNotFound {
+		t.Errorf("RollbackSnapshot of absent snapshot got %v, want ErrNotFound", err)
+	}
+	f.check(t, []string{testZfsSnapshots})
+}
+
+func TestZfsVolumeDriverReleaseVolumeClones(t *testing.T) {
+	d := newZfsVolumeDriver("tank/vols")
+	f, restore := mockBlockCommands(map[string][]string{
+		testZfsOrigins: {
+			"tank/vols/disk1\t-\ntank/vols/disk10\t-\ntank/vols/disk2\ttank/vols/disk1@clone_base\ntank/vols/disk3\ttank/vols/disk1@snap1\ntank/vols/disk4\ttank/vols/disk10@snap1\n",
+			"tank/vols/disk1\ttank/vols/disk2@clone_base\ntank/vols/disk10\t-\ntank/vols/disk2\t-\ntank/vols/disk3\ttank/vols/disk1@snap1\ntank/vols/disk4\ttank/vols/disk10@snap1\n",
+			"tank/vols/disk1\ttank/vols/disk3@snap1\ntank/vols/disk10\t-\ntank/vols/disk2\ttank/vols/disk3@clone_base\ntank/vols/disk3\t-\ntank/vols/disk4\ttank/vols/disk10@snap1\n",
+		},
+	})
+	defer restore()
+	if err := d.ReleaseVolumeClones("disk1"); err != nil {
+		t.Fatalf("ReleaseVolumeClones: %v", err)
+	}
+	f.check(t, []string{
+		testZfsOrigins,
+		"zfs promote tank/vols/disk2",
+		testZfsOrigins,
+		"zfs promote tank/vols/disk3",
+		testZfsOrigins,
+	})
+}
+
+func TestZfsVolumeDriverGetPoolSizeMb(t *testing.T) {
+	d := newZfsVolumeDriver("tank")
+	_, restore := mockBlockCommands(map[string][]string{
+		"zfs list -H -p -o used,avail tank": {"1073741824\t3221225472\n"},
+	})
+	defer restore()
+	size, used, err := d.GetPoolSizeMb()
+	if err != nil {
+		t.Fatalf("GetPoolSizeMb: %v", err)
+	}
+	if size != 4096 || used != 1024 {
+		t.Errorf("GetPoolSizeMb got %d/%d, want 4096/1024", size, used)
+	}
+}
+
+func TestZfsVolumeDriverPartialFailure(t *testing.T) {
+	d := newZfsVolumeDriver("tank/vols")
+	wait := testZfsWaitDevice("disk2")
+
+	f, restore := mockBlockCommands(nil, wait[1])
+	err := d.CreateVolume("disk2", 10240)
+	restore()
+	if err == nil {
+		t.Errorf("CreateVolume should fail without device node")
+	}
+	f.check(t, append(append([]string{"zfs create -s -V 10240M tank/vols/disk2"}, wait...), "zfs destroy -r tank/vols/disk2"))
+
+	f, restore = mockBlockCommands(nil, "zfs snapshot tank/vols/disk1@clone_base")
+	err = d.CloneVolume("disk1", "disk2")
+	restore()
+	if err == nil {
+		t.Errorf("CloneVolume should fail when the base snapshot is not created")
+	}
+	f.check(t, []string{testZfsSnapshots, "zfs snapshot tank/vols/disk1@clone_base"})
+
+	f, restore = mockBlockCommands(map[string][]string{
+		testZfsSnapshots: {"tank/vols/disk1@clone_base\n"},
+	}, wait[1])
+	err = d.CloneVolume("disk1", "disk2")
+	restore()
+	if err == nil {
+		t.Errorf("CloneVolume should fail without device node")
+	}
+	f.check(t, append(append([]string{
+		testZfsSnapshots,
+		"zfs clone tank/vols/disk1@clone_base tank/vols/disk2",
+	}, wait...), "zfs destroy -r tank/vols/disk2"))
+}
+
+func TestZfsBlockDiskSnapshots(t *testing.T) {
+	const (
+		snapshots = "zfs list -H -o name -t snapshot -d 1 tank/vols/disk2"
+		snap      = "zfs snapshot tank/vols/disk2@snap1"
+		saveSnap  = "zfs snapshot tank/vols/disk2@save_"
+		saveClone = "zfs clone tank/vols/disk2@save_ tank/vols/imagecache_disk2_"
+		clone     = "zfs clone tank/vols/disk1@snap1 tank/vols/disk2"
+		size      = "zfs get -H -p -o value volsize tank/vols/disk2"
+		setSize   = "zfs set volsize=20480M tank/vols/disk2"
+	)
+	wait := testZfsWaitDevice("disk2")
+	saveWait := testZfsWaitDevice("imagecache_disk2_")
+	outputs := func(extra ...string) map[string][]string {
+		ret := map[string][]string{
+			testZfsVolumes: {"tank/vols/disk1\ntank/vols/disk2\n"},
+			snapshots:      {"tank/vols/disk2@snap1\ntank/vols/disk2@save_\n"},
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			ret[extra[i]] = []string{extra[i+1]}
+		}
+		return ret
+	}
+	cmds := func(groups ...[]string) []string {
+		ret := []string{}
+		for _, g := range groups {
+			ret = append(ret, g...)
+		}
+		return ret
+	}
+	createSnapshot := func(d *SBlockDisk) error { return d.CreateSnapshot("snap1") }
+	deleteSnapshot := func(d *SBlockDisk) error { return d.DeleteSnapshot("snap1", "", false) }
+	createFromSnapshot := func(d *SBlockDisk) error { return d.createFromSnapshot("disk1", "snap1", 20480) }
+	saveToGlance := func(d *SBlockDisk) error {
+		_, err := d.PrepareSaveToGlance(context.Background(), nil)
+		return err
+	}
+	testBlockDiskCases(t, newZfsVolumeDriver("tank/vols"), []sBlockDiskCase{
+		{
+			name: "create snapshot",
+			do:   createSnapshot,
+			want: []string{snap},
+		},
+		{
+			name:    "create snapshot failed",
+			fails:   []string{snap},
+			do:      createSnapshot,
+			wantErr: true,
+			want:    []string{snap},
+		},
+		{
+			name:    "delete snapshot",
+			outputs: outputs(),
+			do:      deleteSnapshot,
+			want:    []string{snapshots, "zfs destroy -d tank/vols/disk2@snap1"},
+		},
+		{
+			name:    "delete snapshot failed",
+			outputs: outputs(),
+			fails:   []string{"zfs destroy -d tank/vols/disk2@snap1"},
+			do:      deleteSnapshot,
+			wantErr: true,
+			want:    []string{snapshots, "zfs destroy -d tank/vols/disk2@snap1"},
+		},
+		{
+			name:    "create from snapshot",
+			outputs: outputs(size, "10737418240"),
+			do:      createFromSnapshot,
+			want:    cmds([]string{clone}, wait, []string{size, setSize}),
+		},
+		{
+			name:    "create from snapshot clone failed",
+			fails:   []string{clone},
+			do:      createFromSnapshot,
+			wantErr: true,
+			want:    []string{clone},
+		},
+		{
+			name:    "create from snapshot resize failed",
+			outputs: outputs(size, "10737418240"),
+			fails:   []string{setSize},
+			do:      createFromSnapshot,
+			wantErr: true,
+			want:    cmds([]string{clone}, wait, []string{size, setSize, testZfsVolumes, "zfs destroy -r tank/vols/disk2"}),
+		},
+		{
+			name:    "save to glance",
+			outputs: outputs(),
+			do:      saveToGlance,
+			want:    cmds([]string{testZfsVolumes, saveSnap, saveClone}, saveWait, []string{snapshots, "zfs destroy -d tank/vols/disk2@save_"}),
+		},
+		{
+			name:    "save to glance clone failed",
+			outputs: outputs(),
+			fails:   []string{saveClone},
+			do:      saveToGlance,
+			wantErr: true,
+			want:    []string{testZfsVolumes, saveSnap, saveClone, snapshots, "zfs destroy -d tank/vols/disk2@save_"},
+		},
+	})
+}
@@ -137,6 +137,39 @@ func Get(ctx context.Context, fileName string) (int64, io.ReadCloser, error) {
 	return result.Objects[0].GetSizeBytes(), rc, err
 }
 
+// GetRange reads the object from offset by a ranged request, the whole object
+// is returned with start 0 if offset is beyond the object size
+func GetRange(ctx context.Context, fileName string, offset int64) (int64, io.ReadCloser, int64, error) {
+	if client == nil {
+		return 0, nil, 0, ErrClientNotInit
+	}
+
+	bucket, err := client.getBucket()
+	if err != nil {
+		return 0, nil, 0, errors.Wrap(err, "client.getBucket")
+	}
+	result, err := bucket.ListObjects(fileName, "", "", 1)
+	if err != nil {
+		return 0, nil, 0, errors.Wrap(err, "bucket.ListObject")
+	}
+	if len(result.Objects) == 0 {
+		return 0, nil, 0, errors.Wrapf(cloudprovider.ErrNotFound, "object %s", fileName)
+	}
+	size := result.Objects[0].GetSizeBytes()
+
+	var objRange *cloudprovider.SGetObjectRange
+	if offset > 0 && offset < size {
+		objRange = &cloudprovider.SGetObjectRange{Start: offset, End: size - 1}
+	} else {
+		offset = 0
+	}
+	rc, err := bucket.GetObject(ctx, fileName, objRange)
+	if err != nil {
+		return 0, nil, 0, errors.Wrap(err, "bucket.GetObject")
+	}
+	return size, rc, offset, nil
+}
+
 func Remove(ctx context.Context, fileName string) error {
 	if client == nil {
 		return ErrClientNotInit
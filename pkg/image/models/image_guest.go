@@ -442,6 +442,7 @@ func (self *SGuestImage) genUpdateImage(ctx context.Context, userCred mcclient.T
 		}
 		if dict.Contains("properties") {
 			props, _ := dict.Get("properties")
+			sanitizeReplicaProperties(props)
 			err := ImagePropertyManager.SaveProperties(ctx, userCred, image.GetId(), props)
 			if err != nil {
 				return errors.Wrap(err, "save properties error")
@@ -0,0 +1,653 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/streamutils"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SImageReplicaManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+var ImageReplicaManager *SImageReplicaManager
+
+func init() {
+	ImageReplicaManager = &SImageReplicaManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SImageReplica{},
+			"image_replicas_tbl",
+			"image_replica",
+			"image_replicas",
+		),
+	}
+	ImageReplicaManager.SetVirtualObject(ImageReplicaManager)
+}
+
+// SImageReplica tracks the copy of a source image in one target region
+type SImageReplica struct {
+	db.SStatusStandaloneResourceBase
+
+	// 源镜像Id
+	ImageId string `width:"36" charset:"ascii" nullable:"false" list:"user" index:"true"`
+	// 复制策略Id
+	PolicyId string `width:"36" charset:"ascii" nullable:"false" list:"user" index:"true"`
+	// 目标区域
+	TargetRegion string `width:"128" charset:"utf8" nullable:"false" list:"user"`
+	// 目标区域镜像Id
+	TargetImageId string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+	// 已复制的源镜像校验和
+	Checksum string `width:"32" charset:"ascii" nullable:"true" list:"user"`
+	// 上次同步完成时间
+	LastSyncAt time.Time `nullable:"true" list:"user"`
+}
+
+func (manager *SImageReplicaManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageReplicaListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ImageId) > 0 {
+		img, err := ImageManager.FetchByIdOrName(userCred, query.ImageId)
+		if err != nil {
+			if errors.Cause(err) == sqlchemy.ErrEmptyQuery {
+				return nil, httperrors.NewResourceNotFoundError2(ImageManager.Keyword(), query.ImageId)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		q = q.Equals("image_id", img.GetId())
+	}
+	if len(query.PolicyId) > 0 {
+		policy, err := ImageReplicationPolicyManager.FetchByIdOrName(userCred, query.PolicyId)
+		if err != nil {
+			if errors.Cause(err) == sqlchemy.ErrEmptyQuery {
+				return nil, httperrors.NewResourceNotFoundError2(ImageReplicationPolicyManager.Keyword(), query.PolicyId)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		q = q.Equals("policy_id", policy.GetId())
+	}
+	if len(query.TargetRegion) > 0 {
+		q = q.Equals("target_region", query.TargetRegion)
+	}
+	return q, nil
+}
+
+func (manager *SImageReplicaManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageReplicaListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SImageReplicaManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ImageReplicaDetails {
+	rows := make([]api.ImageReplicaDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	imageIds := make([]string, len(objs))
+	policyIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i] = api.ImageReplicaDetails{
+			StatusStandaloneResourceDetails: stdRows[i],
+		}
+		replica := objs[i].(*SImageReplica)
+		imageIds[i] = replica.ImageId
+		policyIds[i] = replica.PolicyId
+	}
+	images := make(map[string]SImage)
+	err := db.FetchStandaloneObjectsByIds(ImageManager, imageIds, &images)
+	if err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds images fail %s", err)
+		return rows
+	}
+	policies := make(map[string]SImageReplicationPolicy)
+	err = db.FetchStandaloneObjectsByIds(ImageReplicationPolicyManager, policyIds, &policies)
+	if err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds policies fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		if img, ok := images[imageIds[i]]; ok {
+			rows[i].Image = img.Name
+		}
+		if policy, ok := policies[policyIds[i]]; ok {
+			rows[i].Policy = policy.Name
+		}
+	}
+	return rows
+}
+
+func (manager *SImageReplicaManager) newReplica(ctx context.Context, userCred mcclient.TokenCredential, policy *SImageReplicationPolicy, image *SImage, region string) (*SImageReplica, error) {
+	replica := &SImageReplica{}
+	replica.SetModelManager(manager, replica)
+	replica.Name = fmt.Sprintf("%s-%s", image.Name, region)
+	replica.ImageId = image.Id
+	replica.PolicyId = policy.Id
+	replica.TargetRegion = region
+	replica.Status = api.IMAGE_REPLICA_STATUS_PENDING
+	err := manager.TableSpec().Insert(ctx, replica)
+	if err != nil {
+		return nil, errors.Wrap(err, "Insert")
+	}
+	return replica, nil
+}
+
+func (manager *SImageReplicaManager) getReplicasByImageId(imageId string) ([]SImageReplica, error) {
+	replicas := []SImageReplica{}
+	q := manager.Query().Equals("image_id", imageId)
+	err := db.FetchModelObjects(manager, q, &replicas)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return replicas, nil
+}
+
+func (replica *SImageReplica) GetImage() (*SImage, error) {
+	img, err := ImageManager.FetchById(replica.ImageId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch image %s", replica.ImageId)
+	}
+	return img.(*SImage), nil
+}
+
+// isUpToDate returns false if the replica should be (re)started
+func (replica *SImageReplica) isUpToDate(image *SImage) bool {
+	switch replica.Status {
+	case api.IMAGE_REPLICA_STATUS_REPLICATING:
+		return true
+	case api.IMAGE_REPLICA_STATUS_READY:
+		return len(replica.TargetImageId) > 0 && replica.Checksum == image.Checksum
+	}
+	return false
+}
+
+func (replica *SImageReplica) getTargetSession(ctx context.Context) *mcclient.ClientSession {
+	return auth.GetAdminSession(ctx, replica.TargetRegion, "")
+}
+
+// GetTargetImage fetches the meta of replicated image from target region
+func (replica *SImageReplica) GetTargetImage(ctx context.Context) (jsonutils.JSONObject, error) {
+	if len(replica.TargetImageId) == 0 {
+		return nil, errors.Wrap(httperrors.ErrNotFound, "empty target image id")
+	}
+	return modules.Images.GetById(replica.getTargetSession(ctx), replica.TargetImageId, nil)
+}
+
+func (replica *SImageReplica) createTargetImage(ctx context.Context, image *SImage) (string, error) {
+	props, err := ImagePropertyManager.GetProperties(image.Id)
+	if err != nil {
+		return "", errors.Wrap(err, "GetProperties")
+	}
+	properties := jsonutils.NewDict()
+	for k, v := range props {
+		if len(v) > 0 {
+			properties.Set(k, jsonutils.NewString(v))
+		}
+	}
+	sanitizeSignatureProperties(properties)
+	properties.Set(api.IMAGE_REPLICA_SOURCE_REGION, jsonutils.NewString(options.Options.Region))
+	properties.Set(api.IMAGE_REPLICA_SOURCE_IMAGE_ID, jsonutils.NewString(image.Id))
+
+	params := jsonutils.NewDict()
+	params.Set("name", jsonutils.NewString(image.Name))
+	params.Set("disk_format", jsonutils.NewString(image.DiskFormat))
+	params.Set("min_disk", jsonutils.NewInt(int64(image.MinDiskMB)))
+	params.Set("min_ram", jsonutils.NewInt(int64(image.MinRamMB)))
+	params.Set("is_data", jsonutils.NewBool(image.IsData.IsTrue()))
+	params.Set("protected", jsonutils.JSONFalse)
+	if len(image.OsArch) > 0 {
+		params.Set("os_arch", jsonutils.NewString(image.OsArch))
+	}
+	params.Set("project", jsonutils.NewString(image.ProjectId))
+	params.Set("properties", properties)
+	target, err := modules.Images.Create(replica.getTargetSession(ctx), params)
+	if err != nil {
+		return "", errors.Wrap(err, "Images.Create")
+	}
+	return target.GetString("id")
+}
+
+// Replicate makes sure the target image exists and asks the target region
+// to pull the current content of the source image
+func (replica *SImageReplica) Replicate(ctx context.Context, userCred mcclient.TokenCredential) error {
+	image, err := replica.GetImage()
+	if err != nil {
+		return errors.Wrap(err, "GetImage")
+	}
+	if image.Status != api.IMAGE_STATUS_ACTIVE {
+		return errors.Wrapf(httperrors.ErrInvalidStatus, "source image in status %s", image.Status)
+	}
+
+	targetImageId := replica.TargetImageId
+	if len(targetImageId) > 0 {
+		target, err := replica.GetTargetImage(ctx)
+		if err != nil {
+			if errors.Cause(err) != httperrors.ErrNotFound && !isClientNotFound(err) {
+				return errors.Wrap(err, "GetTargetImage")
+			}
+			targetImageId = ""
+		} else if pendingDeleted, _ := target.Bool("pending_deleted"); pendingDeleted {
+			targetImageId = ""
+		}
+	}
+	if len(targetImageId) == 0 {
+		targetImageId, err = replica.createTargetImage(ctx, image)
+		if err != nil {
+			return errors.Wrap(err, "createTargetImage")
+		}
+	}
+	_, err = db.Update(replica, func() error {
+		replica.TargetImageId = targetImageId
+		replica.Checksum = image.Checksum
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Update")
+	}
+
+	input := api.ImageReplicateFromInput{
+		SourceRegion:  options.Options.Region,
+		SourceImageId: image.Id,
+		Checksum:      image.Checksum,
+		Size:          image.Size,
+	}
+	_, err = modules.Images.PerformAction(replica.getTargetSession(ctx), targetImageId, "replicate-from", jsonutils.Marshal(input))
+	if err != nil {
+		return errors.Wrap(err, "replicate-from")
+	}
+	return nil
+}
+
+func isClientNotFound(err error) bool {
+	je, ok := errors.Cause(err).(*httputils.JSONClientError)
+	return ok && je.Code == http.StatusNotFound
+}
+
+func (replica *SImageReplica) StartReplicateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	replica.SetStatus(userCred, api.IMAGE_REPLICA_STATUS_REPLICATING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ImageReplicateTask", replica, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// 重新复制镜像到目标区域
+func (replica *SImageReplica) PerformSync(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if replica.Status == api.IMAGE_REPLICA_STATUS_REPLICATING {
+		return nil, httperrors.NewInvalidStatusError("replica is replicating")
+	}
+	err := replica.StartReplicateTask(ctx, userCred, "")
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
+
+// syncStatus refreshes a replicating replica from its target image
+func (replica *SImageReplica) syncStatus(ctx context.Context, userCred mcclient.TokenCredential) error {
+	target, err := replica.GetTargetImage(ctx)
+	if err != nil {
+		return errors.Wrap(err, "GetTargetImage")
+	}
+	status, _ := target.GetString("status")
+	switch status {
+	case api.IMAGE_STATUS_ACTIVE:
+		chksum, _ := target.GetString("properties", api.IMAGE_REPLICA_SOURCE_CHECKSUM)
+		if chksum != replica.Checksum {
+			return replica.onChecksumMismatch(ctx, userCred, chksum)
+		}
+		_, err := db.Update(replica, func() error {
+			replica.Status = api.IMAGE_REPLICA_STATUS_READY
+			replica.Progress = 100
+			replica.LastSyncAt = time.Now()
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "Update")
+		}
+		db.OpsLog.LogEvent(replica, db.ACT_SYNC_STATUS, "replica ready", userCred)
+	case api.IMAGE_STATUS_KILLED, api.IMAGE_STATUS_SAVE_FAIL:
+		replica.SetStatus(userCred, api.IMAGE_REPLICA_STATUS_FAILED, fmt.Sprintf("target image %s", status))
+	default:
+		progress, _ := target.Float("progress")
+		_, err := db.Update(replica, func() error {
+			replica.Progress = float32(progress)
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "Update")
+		}
+	}
+	return nil
+}
+
+// onChecksumMismatch handles a target image which turns active with other
+// content than the replica is waiting for. The pull is queued again if the
+// source image has changed since, otherwise the replica fails as the target
+// region will never pull the expected content by itself
+func (replica *SImageReplica) onChecksumMismatch(ctx context.Context, userCred mcclient.TokenCredential, chksum string) error {
+	image, err := replica.GetImage()
+	if err != nil {
+		return errors.Wrap(err, "GetImage")
+	}
+	if image.Status == api.IMAGE_STATUS_ACTIVE && image.Checksum != replica.Checksum {
+		return replica.StartReplicateTask(ctx, userCred, "")
+	}
+	replica.SetStatus(userCred, api.IMAGE_REPLICA_STATUS_FAILED, fmt.Sprintf("target image checksum %s mismatch, expect %s", chksum, replica.Checksum))
+	return nil
+}
+
+func (manager *SImageReplicaManager) syncReplicaStatus(ctx context.Context, userCred mcclient.TokenCredential) {
+	replicas := []SImageReplica{}
+	q := manager.Query().Equals("status", api.IMAGE_REPLICA_STATUS_REPLICATING).IsNotEmpty("target_image_id")
+	err := db.FetchModelObjects(manager, q, &replicas)
+	if err != nil {
+		log.Errorf("FetchModelObjects replicas fail %s", err)
+		return
+	}
+	for i := range replicas {
+		if taskman.TaskManager.IsInTask(&replicas[i]) {
+			// target image is not asked to pull yet
+			continue
+		}
+		err := replicas[i].syncStatus(ctx, userCred)
+		if err != nil {
+			log.Errorf("sync status of replica %s fail %s", replicas[i].Name, err)
+		}
+	}
+}
+
+func (self *SImage) getReplicaInfos() []api.ImageReplicaInfo {
+	replicas, err := ImageReplicaManager.getReplicasByImageId(self.Id)
+	if err != nil {
+		log.Errorf("getReplicasByImageId fail %s", err)
+		return nil
+	}
+	ret := make([]api.ImageReplicaInfo, len(replicas))
+	for i := range replicas {
+		ret[i] = api.ImageReplicaInfo{
+			TargetRegion:  replicas[i].TargetRegion,
+			TargetImageId: replicas[i].TargetImageId,
+			Status:        replicas[i].Status,
+			Progress:      replicas[i].Progress,
+			LastSyncAt:    replicas[i].LastSyncAt,
+		}
+	}
+	return ret
+}
+
+// getReplicaPartialPath returns the file the replica content is downloaded to,
+// it is named after the source checksum so that an interrupted download is
+// only resumed for the same source content
+func (self *SImage) getReplicaPartialPath(checksum string) string {
+	return self.GetPath("replica-" + checksum)
+}
+
+func (self *SImage) removeReplicaPartialFiles() {
+	files, _ := filepath.Glob(self.GetPath("replica-*"))
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
+
+// PullReplica downloads the content of the source image from the source
+// region, resuming from a previously interrupted download if any
+func (self *SImage) PullReplica(ctx context.Context, userCred mcclient.TokenCredential, input api.ImageReplicateFromInput) error {
+	partialPath := self.getReplicaPartialPath(input.Checksum)
+	offset := int64(0)
+	if stat, err := os.Stat(partialPath); err == nil {
+		offset = stat.Size()
+	}
+	if input.Size <= 0 || offset < input.Size {
+		s := auth.GetAdminSession(ctx, input.SourceRegion, "")
+		rc, _, start, err := modules.Images.DownloadRange(s, input.SourceImageId, offset)
+		if err != nil {
+			return errors.Wrapf(err, "download image %s from %s", input.SourceImageId, input.SourceRegion)
+		}
+		defer rc.Close()
+		if start != offset {
+			log.Warningf("source image %s of %s doesn't serve from offset %d, download %s from start", input.SourceImageId, input.SourceRegion, offset, partialPath)
+		}
+		flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if start == 0 {
+			flag = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		}
+		fp, err := os.OpenFile(partialPath, flag, 0644)
+		if err != nil {
+			return errors.Wrapf(err, "open %s", partialPath)
+		}
+		defer fp.Close()
+		lastSaveTime := time.Now()
+		_, err = streamutils.StreamPipe(rc, fp, false, func(saved int64) {
+			now := time.Now()
+			if now.Sub(lastSaveTime) > 5*time.Second {
+				self.saveSize(start+saved, input.Size)
+				lastSaveTime = now
+			}
+		})
+		if err != nil {
+			return errors.Wrap(err, "StreamPipe")
+		}
+	}
+
+	chksum, err := fileutils2.MD5(partialPath)
+	if err != nil {
+		return errors.Wrap(err, "MD5")
+	}
+	if len(input.Checksum) > 0 && chksum != input.Checksum {
+		os.Remove(partialPath)
+		return errors.Errorf("checksum mismatch, expect %s got %s", input.Checksum, chksum)
+	}
+	localPath := self.GetPath("")
+	err = os.Rename(partialPath, localPath)
+	if err != nil {
+		return errors.Wrapf(err, "rename %s", partialPath)
+	}
+	self.removeReplicaPartialFiles()
+
+	img, err := qemuimg.NewQemuImage(localPath)
+	if err != nil {
+		return errors.Wrap(err, "NewQemuImage")
+	}
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return errors.Wrap(err, "Stat")
+	}
+	fastChksum, err := fileutils2.FastCheckSum(localPath)
+	if err != nil {
+		return errors.Wrap(err, "FastCheckSum")
+	}
+	_, err = db.Update(self, func() error {
+		self.Size = stat.Size()
+		self.Checksum = chksum
+		self.FastHash = fastChksum
+		self.Location = fmt.Sprintf("%s%s", LocalFilePrefix, localPath)
+		if len(img.Format) > 0 {
+			self.DiskFormat = string(img.Format)
+		}
+		if img.SizeBytes > 0 {
+			self.MinDiskMB = int32(math.Ceil(float64(img.SizeBytes) / 1024 / 1024))
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Update")
+	}
+	_, err = ImagePropertyManager.SaveProperty(ctx, userCred, self.Id, api.IMAGE_REPLICA_SOURCE_CHECKSUM, chksum)
+	if err != nil {
+		return errors.Wrap(err, "SaveProperty")
+	}
+	return nil
+}
+
+// sanitizeReplicaProperties removes the replica properties from user input,
+// otherwise an image could claim to be the replica of any image, or hide
+// itself from replication policies
+func sanitizeReplicaProperties(props jsonutils.JSONObject) {
+	dict, ok := props.(*jsonutils.JSONDict)
+	if !ok {
+		return
+	}
+	for _, k := range dict.SortedKeys() {
+		if strings.HasPrefix(k, api.IMAGE_REPLICA_PROPERTY_PREFIX) {
+			dict.Remove(k)
+		}
+	}
+}
+
+// findReplicaRecord returns the replica of source region which is
+// replicating the source image to the target image
+func findReplicaRecord(replicas []jsonutils.JSONObject, sourceImageId, targetRegion, targetImageId string) (jsonutils.JSONObject, error) {
+	for _, replica := range replicas {
+		imageId, _ := replica.GetString("image_id")
+		region, _ := replica.GetString("target_region")
+		targetId, _ := replica.GetString("target_image_id")
+		if imageId != sourceImageId || region != targetRegion || targetId != targetImageId {
+			continue
+		}
+		if status, _ := replica.GetString("status"); status != api.IMAGE_REPLICA_STATUS_REPLICATING {
+			return nil, httperrors.NewInvalidStatusError("replica of %s to %s is in status %s", sourceImageId, targetRegion, status)
+		}
+		return replica, nil
+	}
+	return nil, httperrors.NewForbiddenError("no replica of image %s to %s/%s", sourceImageId, targetRegion, targetImageId)
+}
+
+// checkReplicationPolicy makes sure the policy is still replicating to the target region
+func checkReplicationPolicy(policy jsonutils.JSONObject, targetRegion string) error {
+	name, _ := policy.GetString("name")
+	if !jsonutils.QueryBoolean(policy, "enabled", false) {
+		return httperrors.NewForbiddenError("replication policy %s is disabled", name)
+	}
+	regions := api.TRegionList{}
+	policy.Unmarshal(&regions, "target_regions")
+	for _, region := range regions {
+		if region == targetRegion {
+			return nil
+		}
+	}
+	return httperrors.NewForbiddenError("replication policy %s does not replicate to %s", name, targetRegion)
+}
+
+// validateReplicaSource makes sure the caller is allowed to read the source
+// image, and the source image is being replicated to this image by a policy
+// of source region, before it is pulled with the admin session of source region
+func (self *SImage) validateReplicaSource(ctx context.Context, userCred mcclient.TokenCredential, input api.ImageReplicateFromInput) error {
+	_, err := modules.Images.GetById(auth.GetSession(ctx, userCred, input.SourceRegion, ""), input.SourceImageId, nil)
+	if err != nil {
+		return httperrors.NewForbiddenError("source image %s of region %s is not accessible: %v", input.SourceImageId, input.SourceRegion, err)
+	}
+	s := auth.GetAdminSession(ctx, input.SourceRegion, "")
+	params := jsonutils.NewDict()
+	params.Set("image_id", jsonutils.NewString(input.SourceImageId))
+	params.Set("target_region", jsonutils.NewString(options.Options.Region))
+	params.Set("scope", jsonutils.NewString("system"))
+	result, err := modules.ImageReplicas.List(s, params)
+	if err != nil {
+		return errors.Wrap(err, "list replicas of source region")
+	}
+	replica, err := findReplicaRecord(result.Data, input.SourceImageId, options.Options.Region, self.Id)
+	if err != nil {
+		return err
+	}
+	policyId, _ := replica.GetString("policy_id")
+	policy, err := modules.ImageReplicationPolicies.GetById(s, policyId, nil)
+	if err != nil {
+		return errors.Wrapf(err, "get replication policy %s", policyId)
+	}
+	return checkReplicationPolicy(policy, options.Options.Region)
+}
+
+// PerformReplicateFrom is called on the image of target region to pull the
+// content of the source image from the source region
+func (self *SImage) PerformReplicateFrom(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageReplicateFromInput) (jsonutils.JSONObject, error) {
+	if len(input.SourceRegion) == 0 {
+		return nil, httperrors.NewMissingParameterError("source_region")
+	}
+	if len(input.SourceImageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("source_image_id")
+	}
+	props, err := ImagePropertyManager.GetProperties(self.Id)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if props[api.IMAGE_REPLICA_SOURCE_IMAGE_ID] != input.SourceImageId || props[api.IMAGE_REPLICA_SOURCE_REGION] != input.SourceRegion {
+		return nil, httperrors.NewConflictError("image %s is not a replica of %s/%s", self.Name, input.SourceRegion, input.SourceImageId)
+	}
+	err = self.validateReplicaSource(ctx, userCred, input)
+	if err != nil {
+		return nil, err
+	}
+	if self.Status == api.IMAGE_STATUS_SAVING {
+		return nil, httperrors.NewInvalidStatusError("image is saving")
+	}
+	if self.Status == api.IMAGE_STATUS_ACTIVE && props[api.IMAGE_REPLICA_SOURCE_CHECKSUM] == input.Checksum {
+		// already up to date
+		return nil, nil
+	}
+	params := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+	self.SetStatus(userCred, api.IMAGE_STATUS_SAVING, "replicate from "+input.SourceRegion)
+	task, err := taskman.TaskManager.NewTask(ctx, "ImageReplicaPullTask", self, userCred, params, "", "", nil)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	task.ScheduleRun(nil)
+	return nil, nil
+}
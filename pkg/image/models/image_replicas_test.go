@@ -0,0 +1,129 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+)
+
+func TestSanitizeReplicaProperties(t *testing.T) {
+	props := jsonutils.NewDict()
+	props.Set("os_type", jsonutils.NewString("Linux"))
+	props.Set(api.IMAGE_REPLICA_SOURCE_REGION, jsonutils.NewString("region1"))
+	props.Set(api.IMAGE_REPLICA_SOURCE_IMAGE_ID, jsonutils.NewString("img1"))
+	props.Set(api.IMAGE_REPLICA_SOURCE_CHECKSUM, jsonutils.NewString("abc"))
+	sanitizeReplicaProperties(props)
+	if keys := props.SortedKeys(); len(keys) != 1 || keys[0] != "os_type" {
+		t.Fatalf("replica properties should be removed, got %s", props)
+	}
+}
+
+func TestFindReplicaRecord(t *testing.T) {
+	replicas := []jsonutils.JSONObject{
+		jsonutils.Marshal(map[string]string{"image_id": "img1", "target_region": "region2", "target_image_id": "other", "status": "replicating", "policy_id": "p0"}),
+		jsonutils.Marshal(map[string]string{"image_id": "img1", "target_region": "region2", "target_image_id": "timg1", "status": "replicating", "policy_id": "p1"}),
+		jsonutils.Marshal(map[string]string{"image_id": "img1", "target_region": "region3", "target_image_id": "timg3", "status": "ready", "policy_id": "p1"}),
+	}
+	cases := []struct {
+		imageId  string
+		region   string
+		targetId string
+		policyId string
+	}{
+		{"img1", "region2", "timg1", "p1"},
+		// the source image is not replicated to this image
+		{"img2", "region2", "timg1", ""},
+		{"img1", "region4", "timg1", ""},
+		// not in replicating
+		{"img1", "region3", "timg3", ""},
+	}
+	for i, c := range cases {
+		replica, err := findReplicaRecord(replicas, c.imageId, c.region, c.targetId)
+		if len(c.policyId) == 0 {
+			if err == nil {
+				t.Errorf("case %d: should fail", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: %v", i, err)
+			continue
+		}
+		if policyId, _ := replica.GetString("policy_id"); policyId != c.policyId {
+			t.Errorf("case %d: want policy %s, got %s", i, c.policyId, policyId)
+		}
+	}
+}
+
+func TestCheckReplicationPolicy(t *testing.T) {
+	cases := []struct {
+		policy string
+		region string
+		ok     bool
+	}{
+		{`{"name":"p1","enabled":true,"target_regions":["region2","region3"]}`, "region2", true},
+		{`{"name":"p1","enabled":true,"target_regions":["region3"]}`, "region2", false},
+		{`{"name":"p1","enabled":false,"target_regions":["region2"]}`, "region2", false},
+		{`{"name":"p1","enabled":true}`, "region2", false},
+	}
+	for i, c := range cases {
+		policy, _ := jsonutils.ParseString(c.policy)
+		err := checkReplicationPolicy(policy, c.region)
+		if (err == nil) != c.ok {
+			t.Errorf("case %d: want ok %v, got %v", i, c.ok, err)
+		}
+	}
+}
+
+func TestLocalStorageGetImageRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "image-range")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "img")
+	if err := ioutil.WriteFile(path, []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	cases := []struct {
+		offset int64
+		start  int64
+		data   string
+	}{
+		{0, 0, "0123456789"},
+		{4, 4, "456789"},
+		{10, 0, "0123456789"},
+		{20, 0, "0123456789"},
+	}
+	for _, c := range cases {
+		size, rc, start, err := GetImageRange(context.Background(), LocalFilePrefix+path, c.offset)
+		if err != nil {
+			t.Fatalf("offset %d: %v", c.offset, err)
+		}
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if size != 10 || start != c.start || string(data) != c.data {
+			t.Errorf("offset %d got size %d start %d data %q, want start %d data %q", c.offset, size, start, data, c.start, c.data)
+		}
+	}
+}
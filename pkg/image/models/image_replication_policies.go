@@ -0,0 +1,370 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+	"yunion.io/x/onecloud/pkg/util/tagutils"
+)
+
+type SImageReplicationPolicyManager struct {
+	db.SEnabledStatusStandaloneResourceBaseManager
+}
+
+var ImageReplicationPolicyManager *SImageReplicationPolicyManager
+
+func init() {
+	ImageReplicationPolicyManager = &SImageReplicationPolicyManager{
+		SEnabledStatusStandaloneResourceBaseManager: db.NewEnabledStatusStandaloneResourceBaseManager(
+			SImageReplicationPolicy{},
+			"image_replication_policies_tbl",
+			"image_replication_policy",
+			"image_replication_policies",
+		),
+	}
+	ImageReplicationPolicyManager.SetVirtualObject(ImageReplicationPolicyManager)
+}
+
+// SImageReplicationPolicy replicates the images of source region matching
+// the tag selector to each of the target regions
+type SImageReplicationPolicy struct {
+	db.SEnabledStatusStandaloneResourceBase
+
+	// 源区域
+	SourceRegion string `width:"128" charset:"utf8" nullable:"false" list:"user" create:"optional"`
+	// 目标区域列表
+	TargetRegions *api.TRegionList `nullable:"true" list:"user" create:"required" update:"user"`
+	// 镜像标签选择器
+	Tags tagutils.TTagSet `nullable:"true" list:"user" create:"optional" update:"user"`
+	// 复制周期, 单位分钟
+	IntervalMinutes int `nullable:"false" default:"60" list:"user" create:"optional" update:"user"`
+	// 上次执行时间
+	LastRunAt time.Time `nullable:"true" list:"user"`
+}
+
+func validateTargetRegions(sourceRegion string, regions api.TRegionList) (api.TRegionList, error) {
+	ret := api.TRegionList{}
+	for _, region := range regions {
+		if len(region) == 0 || utils.IsInStringArray(region, ret) {
+			continue
+		}
+		if region == sourceRegion {
+			return nil, httperrors.NewInputParameterError("target region %s is the source region", region)
+		}
+		_, err := auth.GetServiceURL(api.SERVICE_TYPE, region, "", "")
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("no image service found in region %s: %v", region, err)
+		}
+		ret = append(ret, region)
+	}
+	if len(ret) == 0 {
+		return nil, httperrors.NewMissingParameterError("target_regions")
+	}
+	return ret, nil
+}
+
+func (manager *SImageReplicationPolicyManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.ImageReplicationPolicyCreateInput) (api.ImageReplicationPolicyCreateInput, error) {
+	var err error
+	input.EnabledStatusStandaloneResourceCreateInput, err = manager.SEnabledStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusStandaloneResourceCreateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.ValidateCreateData")
+	}
+	if len(input.SourceRegion) == 0 {
+		input.SourceRegion = options.Options.Region
+	}
+	input.TargetRegions, err = validateTargetRegions(input.SourceRegion, input.TargetRegions)
+	if err != nil {
+		return input, err
+	}
+	if input.IntervalMinutes < 0 {
+		return input, httperrors.NewInputParameterError("invalid interval_minutes %d", input.IntervalMinutes)
+	}
+	if input.IntervalMinutes == 0 {
+		input.IntervalMinutes = api.DEFAULT_IMAGE_REPLICATION_INTERVAL_MINUTES
+	}
+	input.Status = api.IMAGE_REPLICATION_POLICY_STATUS_READY
+	return input, nil
+}
+
+func (policy *SImageReplicationPolicy) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageReplicationPolicyUpdateInput) (api.ImageReplicationPolicyUpdateInput, error) {
+	var err error
+	input.EnabledStatusStandaloneResourceBaseUpdateInput, err = policy.SEnabledStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusStandaloneResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusStandaloneResourceBase.ValidateUpdateData")
+	}
+	if input.TargetRegions != nil {
+		input.TargetRegions, err = validateTargetRegions(policy.SourceRegion, input.TargetRegions)
+		if err != nil {
+			return input, err
+		}
+	}
+	if input.IntervalMinutes < 0 {
+		return input, httperrors.NewInputParameterError("invalid interval_minutes %d", input.IntervalMinutes)
+	}
+	return input, nil
+}
+
+func (manager *SImageReplicationPolicyManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageReplicationPolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.SourceRegion) > 0 {
+		q = q.Equals("source_region", query.SourceRegion)
+	}
+	if len(query.TargetRegion) > 0 {
+		sq := ImageReplicaManager.Query("policy_id").Equals("target_region", query.TargetRegion).SubQuery()
+		q = q.Filter(sqlchemy.OR(
+			sqlchemy.Contains(q.Field("target_regions"), query.TargetRegion),
+			sqlchemy.In(q.Field("id"), sq),
+		))
+	}
+	return q, nil
+}
+
+func (manager *SImageReplicationPolicyManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageReplicationPolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SImageReplicationPolicyManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ImageReplicationPolicyDetails {
+	rows := make([]api.ImageReplicationPolicyDetails, len(objs))
+	stdRows := manager.SEnabledStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	policyIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i] = api.ImageReplicationPolicyDetails{
+			EnabledStatusStandaloneResourceDetails: stdRows[i],
+		}
+		policyIds[i] = objs[i].(*SImageReplicationPolicy).Id
+	}
+	replicas := []SImageReplica{}
+	q := ImageReplicaManager.Query().In("policy_id", policyIds)
+	err := db.FetchModelObjects(ImageReplicaManager, q, &replicas)
+	if err != nil {
+		log.Errorf("FetchModelObjects replicas fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		for j := range replicas {
+			if replicas[j].PolicyId != policyIds[i] {
+				continue
+			}
+			rows[i].ReplicaCount += 1
+			if replicas[j].Status == api.IMAGE_REPLICA_STATUS_READY {
+				rows[i].ReadyReplicaCount += 1
+			}
+		}
+	}
+	return rows
+}
+
+func (policy *SImageReplicationPolicy) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	// replicated images in target regions are kept, only the records are removed
+	replicas, err := policy.getReplicas()
+	if err != nil {
+		return errors.Wrap(err, "getReplicas")
+	}
+	for i := range replicas {
+		err := replicas[i].Delete(ctx, userCred)
+		if err != nil {
+			return errors.Wrapf(err, "delete replica %s", replicas[i].Id)
+		}
+	}
+	return policy.SEnabledStatusStandaloneResourceBase.CustomizeDelete(ctx, userCred, query, data)
+}
+
+func (policy *SImageReplicationPolicy) getReplicas() ([]SImageReplica, error) {
+	replicas := []SImageReplica{}
+	q := ImageReplicaManager.Query().Equals("policy_id", policy.Id)
+	err := db.FetchModelObjects(ImageReplicaManager, q, &replicas)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return replicas, nil
+}
+
+func (policy *SImageReplicationPolicy) getTargetRegions() []string {
+	if policy.TargetRegions == nil {
+		return nil
+	}
+	return []string(*policy.TargetRegions)
+}
+
+// getImages returns the active images in source region selected by the policy,
+// images that are replicas of other regions are never replicated again
+func (policy *SImageReplicationPolicy) getImages() ([]SImage, error) {
+	q := ImageManager.Query()
+	q = q.Equals("status", api.IMAGE_STATUS_ACTIVE)
+	q = q.Filter(sqlchemy.OR(sqlchemy.IsNull(q.Field("pending_deleted")), sqlchemy.IsFalse(q.Field("pending_deleted"))))
+	q = q.IsFalse("is_guest_image")
+	if !policy.Tags.IsZero() {
+		filters := tagutils.STagFilters{}
+		filters.AddFilter(policy.Tags)
+		q = db.ObjectIdQueryWithTagFilters(q, "id", ImageManager.Keyword(), filters)
+	}
+	replicaSq := ImagePropertyManager.Query("image_id").Equals("name", api.IMAGE_REPLICA_SOURCE_REGION).IsNotEmpty("value").SubQuery()
+	q = q.Filter(sqlchemy.NotIn(q.Field("id"), replicaSq))
+	images := []SImage{}
+	err := db.FetchModelObjects(ImageManager, q, &images)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return images, nil
+}
+
+func (policy *SImageReplicationPolicy) isDue() bool {
+	interval := policy.IntervalMinutes
+	if interval <= 0 {
+		interval = api.DEFAULT_IMAGE_REPLICATION_INTERVAL_MINUTES
+	}
+	return policy.LastRunAt.IsZero() || time.Now().Sub(policy.LastRunAt) >= time.Duration(interval)*time.Minute
+}
+
+// doReplicate makes sure every selected image has an up-to-date replica in
+// every target region, replicas of removed images and regions are dropped
+func (policy *SImageReplicationPolicy) doReplicate(ctx context.Context, userCred mcclient.TokenCredential) error {
+	lockman.LockObject(ctx, policy)
+	defer lockman.ReleaseObject(ctx, policy)
+
+	images, err := policy.getImages()
+	if err != nil {
+		return errors.Wrap(err, "getImages")
+	}
+	replicas, err := policy.getReplicas()
+	if err != nil {
+		return errors.Wrap(err, "getReplicas")
+	}
+	replicaMap := map[string]*SImageReplica{}
+	for i := range replicas {
+		replicaMap[replicas[i].ImageId+"/"+replicas[i].TargetRegion] = &replicas[i]
+	}
+	regions := policy.getTargetRegions()
+	for i := range images {
+		for _, region := range regions {
+			key := images[i].Id + "/" + region
+			replica, ok := replicaMap[key]
+			if ok {
+				delete(replicaMap, key)
+			} else {
+				replica, err = ImageReplicaManager.newReplica(ctx, userCred, policy, &images[i], region)
+				if err != nil {
+					log.Errorf("create replica of image %s to %s fail %s", images[i].Name, region, err)
+					continue
+				}
+			}
+			if !replica.isUpToDate(&images[i]) {
+				err = replica.StartReplicateTask(ctx, userCred, "")
+				if err != nil {
+					log.Errorf("StartReplicateTask %s fail %s", replica.Name, err)
+				}
+			}
+		}
+	}
+	for _, replica := range replicaMap {
+		if replica.Status == api.IMAGE_REPLICA_STATUS_REPLICATING {
+			continue
+		}
+		err := replica.Delete(ctx, userCred)
+		if err != nil {
+			log.Errorf("delete stale replica %s fail %s", replica.Name, err)
+		}
+	}
+	_, err = db.Update(policy, func() error {
+		policy.LastRunAt = time.Now()
+		return nil
+	})
+	return err
+}
+
+// 立即执行镜像复制策略
+func (policy *SImageReplicationPolicy) PerformSync(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageReplicationPolicySyncInput) (jsonutils.JSONObject, error) {
+	if !policy.GetEnabled() {
+		return nil, httperrors.NewInvalidStatusError("policy is disabled")
+	}
+	if policy.SourceRegion != options.Options.Region {
+		return nil, httperrors.NewInvalidStatusError("policy of region %s should be synced in its source region", policy.SourceRegion)
+	}
+	err := policy.doReplicate(ctx, userCred)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
+
+func (manager *SImageReplicationPolicyManager) getActivePolicies() ([]SImageReplicationPolicy, error) {
+	q := manager.Query().IsTrue("enabled").Equals("source_region", options.Options.Region)
+	policies := []SImageReplicationPolicy{}
+	err := db.FetchModelObjects(manager, q, &policies)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return policies, nil
+}
+
+// ReplicateImages runs the due replication policies of current region and
+// refreshes the status of replicas in progress
+func (manager *SImageReplicationPolicyManager) ReplicateImages(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	policies, err := manager.getActivePolicies()
+	if err != nil {
+		log.Errorf("getActivePolicies fail %s", err)
+		return
+	}
+	for i := range policies {
+		if !policies[i].isDue() {
+			continue
+		}
+		err := policies[i].doReplicate(ctx, userCred)
+		if err != nil {
+			log.Errorf("replicate images of policy %s fail %s", policies[i].Name, err)
+		}
+	}
+	ImageReplicaManager.syncReplicaStatus(ctx, userCred)
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/imagesign"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// properties computed by signature verification, never accepted from user input
+var imageSignatureResultProperties = []string{
+	api.IMAGE_SIGNATURE_STATUS,
+	api.IMAGE_SIGNATURE_SIGNER,
+	api.IMAGE_SIGNATURE_DIGEST,
+	api.IMAGE_SIGNATURE_DISK_FORMAT,
+}
+
+func getImageSignatureTrustStore() *imagesign.STrustStore {
+	store, err := imagesign.NewTrustStore(options.Options.ImageSignatureTrustedCaFile, options.Options.ImageSignatureTrustedKeysFile)
+	if err != nil {
+		// without trust anchors every signature is treated as untrusted
+		log.Errorf("load image signature trust store fail %s", err)
+		return nil
+	}
+	return store
+}
+
+// sanitizeSignatureProperties drops verification results from input
+// properties and normalizes the signer material to a single line, so that
+// it can be passed around in X-Image-Meta-Property headers
+func sanitizeSignatureProperties(props jsonutils.JSONObject) bool {
+	dict, ok := props.(*jsonutils.JSONDict)
+	if !ok {
+		return false
+	}
+	for _, k := range imageSignatureResultProperties {
+		dict.Remove(k)
+	}
+	if cert, _ := dict.GetString(api.IMAGE_SIGNATURE_CERT); len(cert) > 0 {
+		dict.Set(api.IMAGE_SIGNATURE_CERT, jsonutils.NewString(imagesign.EncodeBlob(cert)))
+	}
+	return dict.Contains(api.IMAGE_SIGNATURE) || dict.Contains(api.IMAGE_SIGNATURE_CERT)
+}
+
+func (self *SImage) resetSignatureStatus(ctx context.Context, userCred mcclient.TokenCredential) error {
+	for _, k := range imageSignatureResultProperties {
+		_, err := ImagePropertyManager.SaveProperty(ctx, userCred, self.Id, k, "")
+		if err != nil {
+			return errors.Wrapf(err, "SaveProperty %s", k)
+		}
+	}
+	return nil
+}
+
+// IsSignatureVerifyNeeded returns true if the image has not been verified
+// since its signature was attached or replaced
+func (self *SImage) IsSignatureVerifyNeeded() bool {
+	props, err := ImagePropertyManager.GetProperties(self.Id)
+	if err != nil {
+		return false
+	}
+	return len(props[api.IMAGE_SIGNATURE_STATUS]) == 0
+}
+
+// VerifySignature checks the detached signature attached to the image
+// against the image file and records the result as image properties.
+// If the signature fails to be checked, the image is recorded as unverified
+// and the error is returned
+func (self *SImage) VerifySignature(ctx context.Context, userCred mcclient.TokenCredential, imagePath string) (string, error) {
+	props, err := ImagePropertyManager.GetProperties(self.Id)
+	if err != nil {
+		return "", errors.Wrap(err, "GetProperties")
+	}
+	result, verr := imagesign.VerifyFile(imagePath, props[api.IMAGE_SIGNATURE], props[api.IMAGE_SIGNATURE_CERT], getImageSignatureTrustStore())
+	var failure error
+	if result == nil {
+		failure = errors.Wrap(verr, "VerifyFile")
+		result = &imagesign.SResult{Status: api.IMAGE_SIGNATURE_STATUS_UNVERIFIED}
+		if len(props[api.IMAGE_SIGNATURE]) == 0 {
+			result.Status = api.IMAGE_SIGNATURE_STATUS_UNSIGNED
+		}
+	}
+	signProps := jsonutils.NewDict()
+	signProps.Set(api.IMAGE_SIGNATURE_STATUS, jsonutils.NewString(result.Status))
+	signProps.Set(api.IMAGE_SIGNATURE_SIGNER, jsonutils.NewString(result.Signer))
+	signProps.Set(api.IMAGE_SIGNATURE_DIGEST, jsonutils.NewString(result.Digest))
+	signProps.Set(api.IMAGE_SIGNATURE_DISK_FORMAT, jsonutils.NewString(self.DiskFormat))
+	err = ImagePropertyManager.SaveProperties(ctx, userCred, self.Id, signProps)
+	if err != nil {
+		return "", errors.Wrap(err, "SaveProperties")
+	}
+
+	if result.Status == api.IMAGE_SIGNATURE_STATUS_UNSIGNED {
+		return result.Status, failure
+	}
+	notes := jsonutils.NewDict()
+	notes.Set("status", jsonutils.NewString(result.Status))
+	notes.Set("signer", jsonutils.NewString(result.Signer))
+	if verr != nil {
+		notes.Set("reason", jsonutils.NewString(verr.Error()))
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_IMAGE_VERIFY_SIGNATURE, notes, userCred, result.Status == api.IMAGE_SIGNATURE_STATUS_VERIFIED)
+	return result.Status, failure
+}
+
+// 重新校验镜像签名
+func (self *SImage) PerformVerifySignature(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.Status != api.IMAGE_STATUS_ACTIVE {
+		return nil, httperrors.NewInvalidStatusError("cannot verify signature in status %s", self.Status)
+	}
+	imagePath := self.GetLocalLocation()
+	if len(imagePath) == 0 {
+		return nil, httperrors.NewInvalidStatusError("image file not found")
+	}
+	status, err := self.VerifySignature(ctx, userCred, imagePath)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set(api.IMAGE_SIGNATURE_STATUS, jsonutils.NewString(status))
+	return ret, nil
+}
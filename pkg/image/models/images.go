@@ -235,14 +235,22 @@ func (self *SImage) CustomizedGetDetailsBody(ctx context.Context, userCred mccli
 		return nil, httperrors.NewInvalidStatusError("empty file path")
 	}
 
-	size, rc, err := GetImage(ctx, filePath)
+	appParams := appsrv.AppContextGetParams(ctx)
+	// resumable download of "Range: bytes=<offset>-", used by replication
+	offset := parseRangeOffset(appParams.Request.Header.Get("Range"))
+	size, rc, start, err := GetImageRange(ctx, filePath, offset)
 	if err != nil {
 		return nil, errors.Wrap(err, "get image")
 	}
 	defer rc.Close()
 
-	appParams := appsrv.AppContextGetParams(ctx)
-	appParams.Response.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if start > 0 {
+		appParams.Response.Header().Set("Content-Length", strconv.FormatInt(size-start, 10))
+		appParams.Response.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, size-1, size))
+		appParams.Response.WriteHeader(http.StatusPartialContent)
+	} else {
+		appParams.Response.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
 
 	_, err = streamutils.StreamPipe(rc, appParams.Response, false, nil)
 	if err != nil {
@@ -252,6 +260,17 @@ func (self *SImage) CustomizedGetDetailsBody(ctx context.Context, userCred mccli
 	return nil, nil
 }
 
+func parseRangeOffset(rangeStr string) int64 {
+	if !strings.HasPrefix(rangeStr, "bytes=") || !strings.HasSuffix(rangeStr, "-") {
+		return 0
+	}
+	offset, err := strconv.ParseInt(rangeStr[len("bytes="):len(rangeStr)-1], 10, 64)
+	if err != nil || offset < 0 {
+		return 0
+	}
+	return offset
+}
+
 func (self *SImage) getMoreDetails(out api.ImageDetails) api.ImageDetails {
 	properties, err := ImagePropertyManager.GetProperties(self.Id)
 	if err != nil {
@@ -270,6 +289,7 @@ func (self *SImage) getMoreDetails(out api.ImageDetails) api.ImageDetails {
 	}
 	out.OssChecksum = ossChksum
 	out.DisableDelete = self.Protected.Bool()
+	out.Replicas = self.getReplicaInfos()
 	return out
 }
 
@@ -302,7 +322,7 @@ func (self *SImage) GetExtraDetailsHeaders(ctx context.Context, userCred mcclien
 	details := ImageManager.FetchCustomizeColumns(ctx, userCred, query, []interface{}{self}, nil, false)
 	extra := jsonutils.Marshal(details[0]).(*jsonutils.JSONDict)
 	for _, k := range extra.SortedKeys() {
-		if k == "properties" {
+		if k == "properties" || k == "replicas" {
 			continue
 		}
 		val, _ := extra.GetString(k)
@@ -563,6 +583,11 @@ func (self *SImage) PostCreate(ctx context.Context, userCred mcclient.TokenCrede
 	if data.Contains("properties") {
 		// update properties
 		props, _ := data.Get("properties")
+		sanitizeSignatureProperties(props)
+		if !userCred.HasSystemAdminPrivilege() {
+			// replica target images are created by system admin of source region
+			sanitizeReplicaProperties(props)
+		}
 		err := ImagePropertyManager.SaveProperties(ctx, userCred, self.Id, props)
 		if err != nil {
 			log.Warningf("save properties error %s", err)
@@ -690,10 +715,21 @@ func (self *SImage) PostUpdate(ctx context.Context, userCred mcclient.TokenCrede
 	if data.Contains("properties") {
 		// update properties
 		props, _ := data.Get("properties")
+		signatureChanged := sanitizeSignatureProperties(props)
+		sanitizeReplicaProperties(props)
 		err := ImagePropertyManager.SaveProperties(ctx, userCred, self.Id, props)
 		if err != nil {
 			log.Errorf("save properties error %s", err)
 		}
+		if signatureChanged {
+			// signature replaced, verify it again against the image file
+			err := self.resetSignatureStatus(ctx, userCred)
+			if err != nil {
+				log.Errorf("reset signature status error %s", err)
+			} else if self.Status == api.IMAGE_STATUS_ACTIVE {
+				self.StartImageCheckTask(ctx, userCred, "")
+			}
+		}
 	}
 }
 
@@ -732,6 +768,17 @@ func (self *SImage) Delete(ctx context.Context, userCred mcclient.TokenCredentia
 }
 
 func (self *SImage) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	// replicated images in target regions are kept, only the records are removed
+	replicas, err := ImageReplicaManager.getReplicasByImageId(self.Id)
+	if err != nil {
+		return errors.Wrap(err, "getReplicasByImageId")
+	}
+	for i := range replicas {
+		err := replicas[i].Delete(ctx, userCred)
+		if err != nil {
+			return errors.Wrapf(err, "delete replica %s", replicas[i].Id)
+		}
+	}
 	return self.SSharableVirtualResourceBase.Delete(ctx, userCred)
 }
 
@@ -1166,6 +1213,8 @@ func (self *SImage) Remove(ctx context.Context) error {
 		}
 	}
 
+	self.removeReplicaPartialFiles()
+
 	// 考虑镜像下载中断情况
 	if len(self.Location) == 0 || strings.HasPrefix(self.Location, LocalFilePrefix) {
 		return self.RemoveFile()
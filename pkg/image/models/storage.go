@@ -48,6 +48,19 @@ func GetImage(ctx context.Context, location string) (int64, io.ReadCloser, error
 	}
 }
 
+// GetImageRange returns the content of image from offset, start is the offset
+// the content actually begins at, which is 0 if offset is beyond the image size
+func GetImageRange(ctx context.Context, location string, offset int64) (int64, io.ReadCloser, int64, error) {
+	switch {
+	case strings.HasPrefix(location, image.S3Prefix):
+		return s3Instance.GetImageRange(ctx, location[len(image.S3Prefix):], offset)
+	case strings.HasPrefix(location, image.LocalFilePrefix):
+		return local.GetImageRange(ctx, location[len(image.LocalFilePrefix):], offset)
+	default:
+		return local.GetImageRange(ctx, location, offset)
+	}
+}
+
 func RemoveImage(ctx context.Context, location string) error {
 	switch {
 	case strings.HasPrefix(location, image.S3Prefix):
@@ -86,6 +99,7 @@ type Storage interface {
 	SaveImage(context.Context, string) (string, error)
 	CleanTempfile(string) error
 	GetImage(context.Context, string) (int64, io.ReadCloser, error)
+	GetImageRange(context.Context, string, int64) (int64, io.ReadCloser, int64, error)
 	RemoveImage(context.Context, string) error
 
 	IsCheckStatusEnabled() bool
@@ -117,6 +131,22 @@ func (s *LocalStorage) GetImage(ctx context.Context, imagePath string) (int64, i
 	return fstat.Size(), f, nil
 }
 
+func (s *LocalStorage) GetImageRange(ctx context.Context, imagePath string, offset int64) (int64, io.ReadCloser, int64, error) {
+	size, rc, err := s.GetImage(ctx, imagePath)
+	if err != nil {
+		return -1, nil, 0, err
+	}
+	if offset <= 0 || offset >= size {
+		return size, rc, 0, nil
+	}
+	_, err = rc.(*os.File).Seek(offset, io.SeekStart)
+	if err != nil {
+		rc.Close()
+		return -1, nil, 0, errors.Wrapf(err, "seek file %s to %d", imagePath, offset)
+	}
+	return size, rc, offset, nil
+}
+
 func (s *LocalStorage) IsCheckStatusEnabled() bool {
 	return true
 }
@@ -152,6 +182,10 @@ func (s *S3Storage) GetImage(ctx context.Context, imagePath string) (int64, io.R
 	return s3.Get(ctx, imagePathToName(imagePath))
 }
 
+func (s *S3Storage) GetImageRange(ctx context.Context, imagePath string, offset int64) (int64, io.ReadCloser, int64, error) {
+	return s3.GetRange(ctx, imagePathToName(imagePath), offset)
+}
+
 func (s *S3Storage) IsCheckStatusEnabled() bool {
 	return options.Options.S3CheckImageStatus
 }
@@ -51,6 +51,11 @@ type SImageOptions struct {
 	S3BucketName       string `help:"s3 bucket name" default:"onecloud-images"`
 	S3MountPoint       string `help:"s3fs mount point" default:"/opt/cloud/workspace/data/glance/s3images"`
 	S3CheckImageStatus bool   `help:"Enable s3 check image status"`
+
+	ImageSignatureTrustedCaFile   string `help:"PEM bundle of CA certificates trusted to issue image signing certificates"`
+	ImageSignatureTrustedKeysFile string `help:"PEM bundle of public keys trusted to sign images"`
+
+	ImageReplicationSyncIntervalSeconds int `help:"interval to run image replication policies and refresh replica status" default:"60"`
 }
 
 var (
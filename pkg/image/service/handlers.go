@@ -71,6 +71,9 @@ func InitHandlers(app *appsrv.Application) {
 		models.ImageManager,
 
 		models.GuestImageManager,
+
+		models.ImageReplicationPolicyManager,
+		models.ImageReplicaManager,
 	} {
 		db.RegisterModelManager(manager)
 		handler := db.NewModelHandler(manager)
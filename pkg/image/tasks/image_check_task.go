@@ -18,7 +18,9 @@ import (
 	"context"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
 
+	api "yunion.io/x/onecloud/pkg/apis/image"
 	"yunion.io/x/onecloud/pkg/appsrv"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
@@ -41,6 +43,12 @@ func (self *ImageCheckTask) OnInit(ctx context.Context, obj db.IStandaloneModel,
 
 	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
 		image.DoCheckStatus(ctx, self.UserCred, true)
+		if image.Status == api.IMAGE_STATUS_ACTIVE && image.IsSignatureVerifyNeeded() {
+			_, err := image.VerifySignature(ctx, self.UserCred, image.GetLocalLocation())
+			if err != nil {
+				return nil, errors.Wrap(err, "VerifySignature")
+			}
+		}
 		return nil, nil
 	})
 }
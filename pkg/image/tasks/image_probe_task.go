@@ -146,6 +146,15 @@ func (self *ImageProbeTask) updateImageMetadata(
 		return errors.Wrap(err, "Update image")
 	}
 
+	// verify the detached signature against the final content of the image,
+	// a failed verification is recorded on the image and doesn't fail the probe
+	status, err := image.VerifySignature(ctx, self.UserCred, imagePath)
+	if err != nil {
+		log.Errorf("verify signature of image %s fail, recorded as %s: %s", image.Name, status, err)
+	} else if status == api.IMAGE_SIGNATURE_STATUS_INVALID {
+		log.Warningf("image %s signature is invalid", image.Name)
+	}
+
 	// also update the corresponding subformats
 	subimg := models.ImageSubformatManager.FetchSubImage(image.Id, image.DiskFormat)
 	if subimg != nil {
@@ -0,0 +1,92 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/image/models"
+)
+
+// ImageReplicateTask runs in the source region, it creates the target image
+// and asks the target region to pull the content
+type ImageReplicateTask struct {
+	taskman.STask
+}
+
+// ImageReplicaPullTask runs in the target region, it downloads the content
+// of the source image and probes the replicated image
+type ImageReplicaPullTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(ImageReplicateTask{})
+	taskman.RegisterTask(ImageReplicaPullTask{})
+}
+
+func (self *ImageReplicateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	replica := obj.(*models.SImageReplica)
+
+	self.SetStage("OnReplicateComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, replica.Replicate(ctx, self.UserCred)
+	})
+}
+
+func (self *ImageReplicateTask) OnReplicateComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	// the replica turns ready once the target image becomes active
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *ImageReplicateTask) OnReplicateCompleteFailed(ctx context.Context, obj db.IStandaloneModel, err jsonutils.JSONObject) {
+	replica := obj.(*models.SImageReplica)
+	replica.SetStatus(self.UserCred, api.IMAGE_REPLICA_STATUS_FAILED, err.String())
+	self.SetStageFailed(ctx, err)
+}
+
+func (self *ImageReplicaPullTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	image := obj.(*models.SImage)
+
+	input := api.ImageReplicateFromInput{}
+	self.GetParams().Unmarshal(&input)
+
+	self.SetStage("OnPullComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, image.PullReplica(ctx, self.UserCred, input)
+	})
+}
+
+func (self *ImageReplicaPullTask) OnPullComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	image := obj.(*models.SImage)
+	image.OnSaveTaskSuccess(self, self.UserCred, "replicate success")
+	image.ImageProbeAndCustomization(ctx, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *ImageReplicaPullTask) OnPullCompleteFailed(ctx context.Context, obj db.IStandaloneModel, err jsonutils.JSONObject) {
+	image := obj.(*models.SImage)
+	sourceRegion, _ := self.Params.GetString("source_region")
+	msg := jsonutils.NewDict()
+	msg.Add(err, "reason")
+	msg.Add(jsonutils.NewString(sourceRegion), "source_region")
+	image.OnSaveTaskFailed(self, self.UserCred, msg)
+	self.SetStageFailed(ctx, msg)
+}
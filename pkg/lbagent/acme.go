@@ -0,0 +1,329 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lbagent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/lbagent/models"
+	agentutils "yunion.io/x/onecloud/pkg/lbagent/utils"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/models"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+)
+
+// AcmeHelper obtains and renews acme certificates of loadbalancer listeners
+// served by this cluster.  Only the master does it.  It also responds to
+// http-01 challenges routed by haproxy frontends
+type AcmeHelper struct {
+	opts *Options
+
+	// http-01 challenge token => key authorization
+	tokens sync.Map
+
+	mu       sync.Mutex
+	inflight map[string]struct{}
+	lastTry  map[string]time.Time
+}
+
+func NewAcmeHelper(opts *Options) (*AcmeHelper, error) {
+	helper := &AcmeHelper{
+		opts:     opts,
+		inflight: map[string]struct{}{},
+		lastTry:  map[string]time.Time{},
+	}
+	return helper, nil
+}
+
+func (h *AcmeHelper) Run(ctx context.Context) {
+	defer func() {
+		log.Infof("acme helper bye")
+		wg := ctx.Value("wg").(*sync.WaitGroup)
+		wg.Done()
+	}()
+
+	srv := &http.Server{
+		Addr:    agentutils.AcmeHttpChallengeBind,
+		Handler: h,
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Errorf("acme challenge responder: %s", err)
+	}
+}
+
+func (h *AcmeHelper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, agentutils.AcmeHttpChallengePath)
+	if token == r.URL.Path || token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	v, ok := h.tokens.Load(token)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(v.(string)))
+}
+
+// claim marks the certificate in flight.  Certificates tried recently are
+// skipped, whether it succeeded or not, until the result is reflected in the
+// corpus or the retry interval passed
+func (h *AcmeHelper) claim(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.inflight[id]; ok {
+		return false
+	}
+	if t, ok := h.lastTry[id]; ok && time.Since(t) < time.Duration(h.opts.AcmeRetryInterval)*time.Second {
+		return false
+	}
+	h.inflight[id] = struct{}{}
+	return true
+}
+
+func (h *AcmeHelper) release(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.inflight, id)
+	h.lastTry[id] = time.Now()
+}
+
+// Update starts issuing due certificates found in corpus.  It must be called
+// from where corpus is owned.  Certificates are copied before handing to the
+// issuing goroutines
+func (h *AcmeHelper) Update(ctx context.Context, s *mcclient.ClientSession, corpus *agentmodels.LoadbalancerCorpus, clusterId string) {
+	for _, lbcert := range corpus.AcmeDueCertificates(clusterId, time.Now()) {
+		if !h.claim(lbcert.Id) {
+			continue
+		}
+		cert := *lbcert.LoadbalancerCertificate
+		go func() {
+			defer h.release(cert.Id)
+			h.issueAndReport(ctx, s, &cert)
+		}()
+	}
+}
+
+func (h *AcmeHelper) issueAndReport(ctx context.Context, s *mcclient.ClientSession, lbcert *models.LoadbalancerCertificate) {
+	log.Infof("acme: issuing certificate %s(%s) for %s", lbcert.Name, lbcert.Id, lbcert.AcmeDomains)
+	input := &api.LoadbalancerCertificateAcmeCertificateInput{}
+	certPem, keyPem, err := h.issue(ctx, s, lbcert)
+	if err != nil {
+		log.Errorf("acme: certificate %s(%s): %v", lbcert.Name, lbcert.Id, err)
+		input.Error = err.Error()
+	} else {
+		log.Infof("acme: certificate %s(%s) issued", lbcert.Name, lbcert.Id)
+		input.Certificate = string(certPem)
+		input.PrivateKey = string(keyPem)
+	}
+	params := jsonutils.Marshal(input)
+	if _, err := modules.LoadbalancerCertificates.PerformAction(s, lbcert.Id, "acme-certificate", params); err != nil {
+		log.Errorf("acme: report certificate %s(%s): %v", lbcert.Name, lbcert.Id, err)
+	}
+}
+
+func (h *AcmeHelper) issue(ctx context.Context, s *mcclient.ClientSession, lbcert *models.LoadbalancerCertificate) ([]byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.opts.AcmeIssueTimeout)*time.Second)
+	defer cancel()
+
+	accountKey, err := parseAcmeAccountKey(lbcert.AcmeAccountKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "account key")
+	}
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: lbcert.AcmeDirectoryUrl,
+	}
+	account := &acme.Account{}
+	if lbcert.AcmeEmail != "" {
+		account.Contact = []string{"mailto:" + lbcert.AcmeEmail}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, nil, errors.Wrap(err, "register account")
+	}
+
+	domains := strings.Split(lbcert.AcmeDomains, ",")
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "authorize order")
+	}
+	for _, u := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, u)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "get authorization")
+		}
+		if authz.Status != acme.StatusPending {
+			continue
+		}
+		if err := h.authorize(ctx, s, client, authz, lbcert); err != nil {
+			return nil, nil, errors.Wrapf(err, "authorize %s", authz.Identifier.Value)
+		}
+	}
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "wait order")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generate key")
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create csr")
+	}
+	ders, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create cert")
+	}
+	var certPem []byte
+	for _, der := range ders {
+		certPem = append(certPem, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "marshal key")
+	}
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return certPem, keyPem, nil
+}
+
+func (h *AcmeHelper) authorize(ctx context.Context, s *mcclient.ClientSession, client *acme.Client, authz *acme.Authorization, lbcert *models.LoadbalancerCertificate) error {
+	challengeType := lbcert.AcmeChallenge
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == challengeType {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return errors.Errorf("no %s challenge offered", challengeType)
+	}
+
+	switch challengeType {
+	case api.LB_ACME_CHALLENGE_HTTP01:
+		keyAuth, err := client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return errors.Wrap(err, "http-01 response")
+		}
+		h.tokens.Store(chal.Token, keyAuth)
+		defer h.tokens.Delete(chal.Token)
+	case api.LB_ACME_CHALLENGE_DNS01:
+		value, err := client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return errors.Wrap(err, "dns-01 record")
+		}
+		recordId, err := h.addDnsRecord(s, lbcert.DomainId, authz.Identifier.Value, value)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if _, err := modules.DnsRecordSets.Delete(s, recordId, nil); err != nil {
+				log.Errorf("acme: delete dns record %s: %v", recordId, err)
+			}
+		}()
+		select {
+		case <-time.After(time.Duration(h.opts.AcmeDnsPropagationWait) * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	default:
+		return errors.Errorf("unsupported challenge %s", challengeType)
+	}
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return errors.Wrap(err, "accept challenge")
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return errors.Wrap(err, "wait authorization")
+	}
+	return nil
+}
+
+// addDnsRecord adds TXT record for dns-01 challenge of domain to the public
+// dns zone with the longest matching name, among those accessible by the
+// domain of certificate owner
+func (h *AcmeHelper) addDnsRecord(s *mcclient.ClientSession, ownerDomainId, domain, value string) (string, error) {
+	params := jsonutils.NewDict()
+	params.Set("name", jsonutils.NewStringArray(agentutils.AcmeDnsZoneCandidates(domain)))
+	params.Set("zone_type", jsonutils.NewString("PublicZone"))
+	params.Set("scope", jsonutils.NewString("system"))
+	result, err := modules.DnsZones.List(s, params)
+	if err != nil {
+		return "", errors.Wrap(err, "list dns zones")
+	}
+	var zoneId, zoneName string
+	for _, data := range result.Data {
+		if !agentutils.AcmeDnsZoneAccessible(data, ownerDomainId) {
+			continue
+		}
+		name, _ := data.GetString("name")
+		if len(name) > len(zoneName) {
+			zoneId, _ = data.GetString("id")
+			zoneName = name
+		}
+	}
+	if zoneId == "" {
+		return "", errors.Errorf("no public dns zone for %s", domain)
+	}
+
+	input := &api.DnsRecordSetCreateInput{
+		DnsZoneId: zoneId,
+		DnsType:   "TXT",
+		DnsValue:  value,
+		TTL:       60,
+	}
+	input.Name = agentutils.AcmeDnsRecordName(domain, zoneName)
+	record, err := modules.DnsRecordSets.Create(s, jsonutils.Marshal(input))
+	if err != nil {
+		return "", errors.Wrapf(err, "create dns record %s in zone %s", input.Name, zoneName)
+	}
+	recordId, _ := record.GetString("id")
+	return recordId, nil
+}
+
+func parseAcmeAccountKey(s string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.Error("invalid pem")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
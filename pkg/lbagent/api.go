@@ -45,6 +45,7 @@ type ApiHelper struct {
 
 	haState         string
 	haStateProvider HaStateProvider
+	acmeHelper      *AcmeHelper
 
 	mcclientSession *mcclient.ClientSession
 }
@@ -83,6 +84,7 @@ func (h *ApiHelper) Run(ctx context.Context) {
 				log.Infof("things changed: params: %v, data %v", agentParamsChanged, apiDataChanged)
 				h.doUseCorpus(ctx)
 			}
+			h.doAcme(ctx)
 		case state := <-h.haStateProvider.StateChannel():
 			switch state {
 			case api.LB_HA_STATE_BACKUP:
@@ -104,6 +106,10 @@ func (h *ApiHelper) SetHaStateProvider(hsp HaStateProvider) {
 	h.haStateProvider = hsp
 }
 
+func (h *ApiHelper) SetAcmeHelper(acmeHelper *AcmeHelper) {
+	h.acmeHelper = acmeHelper
+}
+
 func (h *ApiHelper) adminClientSession(ctx context.Context) *mcclient.ClientSession {
 	s := h.mcclientSession
 	if s != nil {
@@ -427,6 +433,17 @@ func (h *ApiHelper) doUseCorpus(ctx context.Context) {
 	}
 }
 
+func (h *ApiHelper) doAcme(ctx context.Context) {
+	if h.acmeHelper == nil || h.haState != api.LB_HA_STATE_MASTER {
+		return
+	}
+	if h.corpus == nil || h.agentParams == nil {
+		return
+	}
+	s := h.adminClientSession(ctx)
+	h.acmeHelper.Update(ctx, s, h.corpus, h.agentParams.AgentModel.ClusterId)
+}
+
 func (h *ApiHelper) doStopDaemons(ctx context.Context) {
 	cmd := &LbagentCmd{
 		Type: LbagentCmdStopDaemons,
@@ -0,0 +1,115 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gobetween
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+// ApiBind is the address of the rest api used by lbagent to query stats
+const ApiBind = "localhost:777"
+
+// apiBasicAuth is generated for each agent run.  gobetween is restarted
+// with it on the first config generation since the generated config differs
+var apiBasicAuth = newApiBasicAuth()
+
+func newApiBasicAuth() ApiBasicAuthConfig {
+	randHex := func(n int) string {
+		b := make([]byte, n)
+		if _, err := rand.Read(b); err != nil {
+			log.Fatalf("generate gobetween api credential: %v", err)
+		}
+		return hex.EncodeToString(b)
+	}
+	return ApiBasicAuthConfig{
+		Login:    "lbagent-" + randHex(4),
+		Password: randHex(16),
+	}
+}
+
+// ApiBasicAuth returns the credential of the rest api of local gobetween
+func ApiBasicAuth() *ApiBasicAuthConfig {
+	auth := apiBasicAuth
+	return &auth
+}
+
+/**
+ * Stats of a backend as returned by GET /servers/:name/stats
+ */
+type BackendStats struct {
+	Live               bool   `json:"live"`
+	Discovered         bool   `json:"discovered"`
+	TotalConnections   int64  `json:"total_connections"`
+	ActiveConnections  int64  `json:"active_connections"`
+	RefusedConnections int64  `json:"refused_connections"`
+	RxBytes            uint64 `json:"rx"`
+	TxBytes            uint64 `json:"tx"`
+	RxSecond           uint64 `json:"rx_second"`
+	TxSecond           uint64 `json:"tx_second"`
+}
+
+type Backend struct {
+	Host  string       `json:"host"`
+	Port  string       `json:"port"`
+	Stats BackendStats `json:"stats"`
+}
+
+/**
+ * Stats of a server as returned by GET /servers/:name/stats
+ */
+type ServerStats struct {
+	ActiveConnections uint      `json:"active_connections"`
+	RxTotal           uint64    `json:"rx_total"`
+	TxTotal           uint64    `json:"tx_total"`
+	RxSecond          uint64    `json:"rx_second"`
+	TxSecond          uint64    `json:"tx_second"`
+	Backends          []Backend `json:"backends"`
+}
+
+// GetServerStats fetches stats of server name from the rest api of local
+// gobetween
+func GetServerStats(ctx context.Context, client *http.Client, name string) (*ServerStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	url := fmt.Sprintf("http://%s/servers/%s/stats", ApiBind, name)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "new request")
+	}
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(apiBasicAuth.Login, apiBasicAuth.Password)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("get %s: %s", url, resp.Status)
+	}
+	stats := &ServerStats{}
+	if err := json.NewDecoder(resp.Body).Decode(stats); err != nil {
+		return nil, errors.Wrapf(err, "decode stats of server %s", name)
+	}
+	return stats, nil
+}
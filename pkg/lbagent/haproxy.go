@@ -38,6 +38,7 @@ type HaproxyHelper struct {
 	opts *Options
 
 	configDirMan *agentutils.ConfigDirManager
+	stats        *StatsCollector
 }
 
 func NewHaproxyHelper(opts *Options) (*HaproxyHelper, error) {
@@ -45,6 +46,7 @@ func NewHaproxyHelper(opts *Options) (*HaproxyHelper, error) {
 		opts:         opts,
 		configDirMan: agentutils.NewConfigDirManager(opts.haproxyConfigDir),
 	}
+	helper.stats = NewStatsCollector(opts, helper.haproxyStatsSocketFile())
 	{
 		// sysctl
 		args := []string{
@@ -65,6 +67,7 @@ func (h *HaproxyHelper) Run(ctx context.Context) {
 		wg.Done()
 	}()
 	cmdChan := ctx.Value("cmdChan").(chan *LbagentCmd)
+	go h.stats.Run(ctx)
 	for {
 		for {
 			select {
@@ -123,6 +126,7 @@ func (h *HaproxyHelper) handleStopDaemonsCmd(ctx context.Context) {
 		}(pf)
 	}
 	wg.Wait()
+	h.stats.Update(nil, nil)
 }
 
 func (h *HaproxyHelper) handleUseCorpusCmd(ctx context.Context, cmd *LbagentCmd) {
@@ -212,6 +216,12 @@ func (h *HaproxyHelper) handleUseCorpusCmd(ctx context.Context, cmd *LbagentCmd)
 	if err := h.useConfigs(ctx, dir); err != nil {
 		log.Errorf("useConfigs: %s", err)
 	}
+	{
+		cmdData := cmd.Data.(*LbagentCmdUseCorpusData)
+		agentParams := cmdData.AgentParams
+		index := newStatsIndex(cmdData.Corpus, agentParams.AgentModel.ClusterId)
+		h.stats.Update(index, agentParams)
+	}
 }
 
 func (h *HaproxyHelper) useConfigs(ctx context.Context, d string) error {
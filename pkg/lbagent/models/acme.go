@@ -0,0 +1,156 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	agentutils "yunion.io/x/onecloud/pkg/lbagent/utils"
+)
+
+func (lbcert *LoadbalancerCertificate) IsComplete() bool {
+	return lbcert.Certificate != "" && lbcert.PrivateKey != ""
+}
+
+func (lbcert *LoadbalancerCertificate) IsAcme() bool {
+	return lbcert.CertificateType == computeapi.LB_CERT_TYPE_ACME
+}
+
+func (lbcert *LoadbalancerCertificate) AcmeDomainList() []string {
+	var r []string
+	for _, domain := range strings.Split(lbcert.AcmeDomains, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			r = append(r, domain)
+		}
+	}
+	return r
+}
+
+// AcmeDue returns true if the acme certificate needs to be issued or renewed
+func (lbcert *LoadbalancerCertificate) AcmeDue(now time.Time) bool {
+	if !lbcert.IsAcme() {
+		return false
+	}
+	if lbcert.Status == computeapi.LB_CERT_STATUS_ACME_PENDING || !lbcert.IsComplete() {
+		return true
+	}
+	renewAt := lbcert.NotAfter.AddDate(0, 0, -computeapi.LB_ACME_RENEW_BEFORE_DAYS)
+	return now.After(renewAt)
+}
+
+// acmeCertificates returns acme certificates used by enabled https
+// listeners of loadbalancers in the cluster
+func (b *LoadbalancerCorpus) acmeCertificates(clusterId string) []*LoadbalancerCertificate {
+	var (
+		r    []*LoadbalancerCertificate
+		seen = map[string]struct{}{}
+	)
+	for _, listener := range b.LoadbalancerListeners {
+		lb := listener.loadbalancer
+		lbcert := listener.certificate
+		if lb == nil || lbcert == nil {
+			continue
+		}
+		if lb.ClusterId != clusterId || lb.Status != computeapi.LB_STATUS_ENABLED {
+			continue
+		}
+		if listener.ListenerType != computeapi.LB_LISTENER_TYPE_HTTPS || listener.Status != computeapi.LB_STATUS_ENABLED {
+			continue
+		}
+		if !lbcert.IsAcme() {
+			continue
+		}
+		if _, ok := seen[lbcert.Id]; ok {
+			continue
+		}
+		seen[lbcert.Id] = struct{}{}
+		r = append(r, lbcert)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Id < r[j].Id
+	})
+	return r
+}
+
+// AcmeDueCertificates returns acme certificates the cluster is responsible
+// for issuing or renewing now
+func (b *LoadbalancerCorpus) AcmeDueCertificates(clusterId string, now time.Time) []*LoadbalancerCertificate {
+	var r []*LoadbalancerCertificate
+	for _, lbcert := range b.acmeCertificates(clusterId) {
+		if lbcert.AcmeDue(now) {
+			r = append(r, lbcert)
+		}
+	}
+	return r
+}
+
+// acmeHttpChallengeDomains returns domains validated through http-01
+// challenge by the cluster
+func (b *LoadbalancerCorpus) acmeHttpChallengeDomains(clusterId string) []string {
+	var (
+		r    []string
+		seen = map[string]struct{}{}
+	)
+	for _, lbcert := range b.acmeCertificates(clusterId) {
+		if lbcert.AcmeChallenge != computeapi.LB_ACME_CHALLENGE_HTTP01 {
+			continue
+		}
+		for _, domain := range lbcert.AcmeDomainList() {
+			if _, ok := seen[domain]; ok {
+				continue
+			}
+			seen[domain] = struct{}{}
+			r = append(r, domain)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+// haproxyAcmeRuleLines returns frontend rules handing http-01 challenge
+// requests for domains to the responder of lbagent.  They bypass other
+// http-request rules, e.g. redirects to https
+func haproxyAcmeRuleLines(domains []string) []string {
+	if len(domains) == 0 {
+		return nil
+	}
+	cond := fmt.Sprintf("{ path_beg %s } { req.hdr(host),field(1,:),lower -m str %s }",
+		agentutils.AcmeHttpChallengePath, strings.Join(domains, " "))
+	return []string{
+		"http-request allow if " + cond,
+		fmt.Sprintf("use_backend %s if %s", agentutils.AcmeHttpChallengeBackend, cond),
+	}
+}
+
+func (b *LoadbalancerCorpus) genHaproxyConfigAcme(dir string) error {
+	lines := []string{
+		"# acme http-01 challenge responder",
+		fmt.Sprintf("backend %s", agentutils.AcmeHttpChallengeBackend),
+		"	mode http",
+		fmt.Sprintf("	server acme %s", agentutils.AcmeHttpChallengeBind),
+		"",
+	}
+	p := filepath.Join(dir, "02-acme.cfg")
+	if err := ioutil.WriteFile(p, []byte(strings.Join(lines, "\n")), agentutils.FileModeFile); err != nil {
+		return fmt.Errorf("write 02-acme.cfg: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/models"
+)
+
+func TestLoadbalancerCorpus_Acme(t *testing.T) {
+	now := time.Now()
+	newCert := func(id, challenge, domains, status string, notAfter time.Time) *LoadbalancerCertificate {
+		lbcert := &LoadbalancerCertificate{
+			LoadbalancerCertificate: &models.LoadbalancerCertificate{
+				CertificateType: computeapi.LB_CERT_TYPE_ACME,
+				AcmeChallenge:   challenge,
+				AcmeDomains:     domains,
+				NotAfter:        notAfter,
+			},
+		}
+		lbcert.Id = id
+		lbcert.Status = status
+		if !notAfter.IsZero() {
+			lbcert.Certificate = "cert"
+			lbcert.PrivateKey = "key"
+		}
+		return lbcert
+	}
+	newListener := func(id, lbId, certId string) *LoadbalancerListener {
+		listener := &LoadbalancerListener{
+			LoadbalancerListener: &models.LoadbalancerListener{},
+		}
+		listener.Id = id
+		listener.Status = computeapi.LB_STATUS_ENABLED
+		listener.LoadbalancerId = lbId
+		listener.ListenerType = computeapi.LB_LISTENER_TYPE_HTTPS
+		listener.CertificateId = certId
+		return listener
+	}
+	newLb := func(id, clusterId string) *Loadbalancer {
+		lb := &Loadbalancer{
+			Loadbalancer: &models.Loadbalancer{},
+		}
+		lb.Id = id
+		lb.Status = computeapi.LB_STATUS_ENABLED
+		lb.ClusterId = clusterId
+		return lb
+	}
+
+	b := NewEmptyLoadbalancerCorpus()
+	mss := b.ModelSets
+	mss.Loadbalancers["lb0"] = newLb("lb0", "c0")
+	mss.Loadbalancers["lb1"] = newLb("lb1", "c1")
+	mss.LoadbalancerCertificates["pending"] = newCert("pending", computeapi.LB_ACME_CHALLENGE_HTTP01, "a.com,www.a.com", computeapi.LB_CERT_STATUS_ACME_PENDING, time.Time{})
+	mss.LoadbalancerCertificates["valid"] = newCert("valid", computeapi.LB_ACME_CHALLENGE_HTTP01, "b.com", computeapi.LB_STATUS_ENABLED, now.AddDate(0, 0, 60))
+	mss.LoadbalancerCertificates["expiring"] = newCert("expiring", computeapi.LB_ACME_CHALLENGE_DNS01, "*.c.com", computeapi.LB_STATUS_ENABLED, now.AddDate(0, 0, 7))
+	mss.LoadbalancerCertificates["other"] = newCert("other", computeapi.LB_ACME_CHALLENGE_HTTP01, "d.com", computeapi.LB_CERT_STATUS_ACME_PENDING, time.Time{})
+	mss.LoadbalancerListeners["l0"] = newListener("l0", "lb0", "pending")
+	mss.LoadbalancerListeners["l1"] = newListener("l1", "lb0", "valid")
+	mss.LoadbalancerListeners["l2"] = newListener("l2", "lb0", "expiring")
+	mss.LoadbalancerListeners["l3"] = newListener("l3", "lb1", "other")
+	if !mss.join() {
+		t.Fatalf("join failed")
+	}
+
+	var ids []string
+	for _, lbcert := range b.AcmeDueCertificates("c0", now) {
+		ids = append(ids, lbcert.Id)
+	}
+	if want := []string{"expiring", "pending"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("due certificates: got %v, want %v", ids, want)
+	}
+
+	domains := b.acmeHttpChallengeDomains("c0")
+	if want := []string{"a.com", "b.com", "www.a.com"}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("http-01 domains: got %v, want %v", domains, want)
+	}
+	if lines := haproxyAcmeRuleLines(nil); len(lines) != 0 {
+		t.Errorf("rule lines without domains: %v", lines)
+	}
+}
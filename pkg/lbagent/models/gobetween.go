@@ -36,19 +36,15 @@ type GenGobetweenConfigOptions struct {
 func (b *LoadbalancerCorpus) GenGobetweenConfigs(dir string, opts *GenGobetweenConfigOptions) error {
 	//agentParams := opts.AgentParams
 	// TODO
-	//  - dynamic password
 	//  - log to remote
 	//  - log to local syslog unix sock
 	//  - respawn
 	opts.Config = &gobetween.Config{
 		Servers: map[string]gobetween.Server{},
 		Api: gobetween.ApiConfig{
-			Enabled: true,
-			Bind:    "localhost:777",
-			BasicAuth: &gobetween.ApiBasicAuthConfig{
-				Login:    "Yunion",
-				Password: "LBStats",
-			},
+			Enabled:   true,
+			Bind:      gobetween.ApiBind,
+			BasicAuth: gobetween.ApiBasicAuth(),
 		},
 	}
 	for _, lb := range opts.LoadbalancersEnabled {
@@ -169,7 +165,7 @@ func (b *LoadbalancerCorpus) GenGobetweenConfigs(dir string, opts *GenGobetweenC
 			return err
 		}
 		p := filepath.Join(dir, "gobetween.json")
-		err = ioutil.WriteFile(p, d, agentutils.FileModeFile)
+		err = ioutil.WriteFile(p, d, agentutils.FileModeFileSensitive)
 		if err != nil {
 			return err
 		}
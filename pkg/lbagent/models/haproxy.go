@@ -75,6 +75,10 @@ func (b *LoadbalancerCorpus) GenHaproxyConfigs(dir string, opts *AgentParams) (*
 			}
 		}
 		for _, lbcert := range b.LoadbalancerCertificates {
+			if !lbcert.IsComplete() {
+				// e.g. acme certificate not issued yet
+				continue
+			}
 			d := []byte(lbcert.Certificate)
 			if len(d) > 0 && d[len(d)-1] != '\n' {
 				d = append(d, '\n')
@@ -107,6 +111,13 @@ func (b *LoadbalancerCorpus) GenHaproxyConfigs(dir string, opts *AgentParams) (*
 		}
 	}
 
+	acmeDomains := b.acmeHttpChallengeDomains(opts.AgentModel.ClusterId)
+	if len(acmeDomains) > 0 {
+		if err := b.genHaproxyConfigAcme(dir); err != nil {
+			return nil, err
+		}
+	}
+
 	r := &GenHaproxyConfigsResult{
 		LoadbalancersEnabled: []*Loadbalancer{},
 	}
@@ -132,7 +143,7 @@ func (b *LoadbalancerCorpus) GenHaproxyConfigs(dir string, opts *AgentParams) (*
 			var err error
 			switch listener.ListenerType {
 			case "http", "https":
-				err = b.genHaproxyConfigHttp(buf, listener, opts)
+				err = b.genHaproxyConfigHttp(buf, listener, opts, acmeDomains)
 			case "tcp":
 				err = b.genHaproxyConfigTcp(buf, listener, opts)
 			case "udp":
@@ -423,10 +434,14 @@ func (b *LoadbalancerCorpus) haproxyRedirectLine(r *models.LoadbalancerHTTPRedir
 	return line
 }
 
-func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *LoadbalancerListener, opts *AgentParams) error {
+func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *LoadbalancerListener, opts *AgentParams, acmeDomains []string) error {
 	var (
 		lb = listener.loadbalancer
 	)
+	if listener.certificate != nil && !listener.certificate.IsComplete() {
+		// wait for the certificate to be issued
+		return haproxyConfigErrNop
+	}
 
 	data := b.genHaproxyConfigCommon(lb, listener, opts)
 	{
@@ -530,6 +545,9 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 		// nothing to serve
 		return haproxyConfigErrNop
 	}
+	if listener.ListenerType == computeapi.LB_LISTENER_TYPE_HTTP {
+		data["rules"] = append(haproxyAcmeRuleLines(acmeDomains), ruleLines...)
+	}
 	err := haproxyConfigTmpl.ExecuteTemplate(buf, "httpListen", data)
 	return err
 }
@@ -32,6 +32,11 @@ type LbagentOptions struct {
 	ApiListBatchSize int `default:"1024"`
 
 	DataPreserveN int `default:"8" help:"number of recent data to preserve on disk"`
+	StatsInterval int `default:"10" help:"interval in seconds of pushing listener and backend stats to influxdb, 0 to disable"`
+
+	AcmeRetryInterval      int `default:"3600" help:"interval in seconds before retrying acme certificate of the same id"`
+	AcmeIssueTimeout       int `default:"600" help:"timeout in seconds of issuing one acme certificate"`
+	AcmeDnsPropagationWait int `default:"60" help:"seconds to wait for dns-01 challenge record to propagate"`
 
 	BaseDataDir      string // `required:"true"`
 	apiDataStoreDir  string
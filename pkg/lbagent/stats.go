@@ -0,0 +1,419 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lbagent
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/lbagent/gobetween"
+	agentmodels "yunion.io/x/onecloud/pkg/lbagent/models"
+	agentutils "yunion.io/x/onecloud/pkg/lbagent/utils"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/influxdb"
+)
+
+const (
+	// haproxy proxy name prefixes of backend sections, as generated in
+	// agentmodels
+	haproxyPxListener        = "backends_listener-"
+	haproxyPxListenerDefault = "backends_listener_default-"
+	haproxyPxRule            = "backends_rule-"
+)
+
+type statsListener struct {
+	Id             string
+	LoadbalancerId string
+	ListenerType   string
+	BackendGroupId string
+}
+
+type statsBackend struct {
+	Id             string
+	BackendGroupId string
+	Address        string
+	Port           int
+}
+
+func (backend *statsBackend) HostPort() string {
+	return net.JoinHostPort(backend.Address, strconv.Itoa(backend.Port))
+}
+
+// statsIndex keeps what is needed for tagging metrics.  It's made when the
+// corpus is handed over so that the collector does not race with api sync
+// updating the corpus
+type statsIndex struct {
+	listeners    map[string]*statsListener
+	ruleListener map[string]string
+	backends     map[string]*statsBackend
+	udpListeners []*statsListener
+}
+
+func newStatsIndex(corpus *agentmodels.LoadbalancerCorpus, clusterId string) *statsIndex {
+	idx := &statsIndex{
+		listeners:    map[string]*statsListener{},
+		ruleListener: map[string]string{},
+		backends:     map[string]*statsBackend{},
+	}
+	for _, listener := range corpus.LoadbalancerListeners {
+		lis := &statsListener{
+			Id:             listener.Id,
+			LoadbalancerId: listener.LoadbalancerId,
+			ListenerType:   listener.ListenerType,
+			BackendGroupId: listener.BackendGroupId,
+		}
+		idx.listeners[lis.Id] = lis
+		if lis.ListenerType != api.LB_LISTENER_TYPE_UDP ||
+			listener.Status != api.LB_STATUS_ENABLED ||
+			lis.BackendGroupId == "" {
+			continue
+		}
+		lb, ok := corpus.Loadbalancers[lis.LoadbalancerId]
+		if !ok || lb.ClusterId != clusterId || lb.Status != api.LB_STATUS_ENABLED {
+			continue
+		}
+		idx.udpListeners = append(idx.udpListeners, lis)
+	}
+	for _, rule := range corpus.LoadbalancerListenerRules {
+		idx.ruleListener[rule.Id] = rule.ListenerId
+	}
+	for _, backend := range corpus.LoadbalancerBackends {
+		idx.backends[backend.Id] = &statsBackend{
+			Id:             backend.Id,
+			BackendGroupId: backend.BackendGroupId,
+			Address:        backend.Address,
+			Port:           backend.Port,
+		}
+	}
+	return idx
+}
+
+// StatsCollector periodically gathers per-listener and per-backend stats
+// from haproxy and gobetween, then writes them to influxdb configured in
+// telegraf params of the agent
+type StatsCollector struct {
+	opts        *Options
+	statsSocket string
+	client      *http.Client
+
+	mu     sync.Mutex
+	index  *statsIndex
+	dbUrl  string
+	dbName string
+
+	db           *influxdb.SInfluxdb
+	dbSet        string
+	counters     map[string]statsCounter
+	countersNext map[string]statsCounter
+}
+
+type statsCounter struct {
+	value int64
+	time  time.Time
+}
+
+func NewStatsCollector(opts *Options, statsSocket string) *StatsCollector {
+	return &StatsCollector{
+		opts:        opts,
+		statsSocket: statsSocket,
+		client:      httputils.GetDefaultClient(),
+		counters:    map[string]statsCounter{},
+	}
+}
+
+// Update sets things to collect.  Nil index stops the collection, e.g. when
+// daemons are stopped on backup node
+func (sc *StatsCollector) Update(index *statsIndex, agentParams *agentmodels.AgentParams) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.index = index
+	if agentParams != nil {
+		params := agentParams.AgentModel.Params.Telegraf
+		sc.dbUrl = params.InfluxDbOutputUrl
+		sc.dbName = params.InfluxDbOutputName
+	}
+}
+
+func (sc *StatsCollector) Run(ctx context.Context) {
+	if sc.opts.StatsInterval <= 0 {
+		log.Infof("stats collection disabled")
+		return
+	}
+	ticker := time.NewTicker(time.Duration(sc.opts.StatsInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := sc.collect(ctx); err != nil {
+				log.Errorf("collect stats: %v", err)
+			}
+		case <-ctx.Done():
+			log.Infof("stats collector bye")
+			return
+		}
+	}
+}
+
+func (sc *StatsCollector) collect(ctx context.Context) error {
+	sc.mu.Lock()
+	index, dbUrl, dbName := sc.index, sc.dbUrl, sc.dbName
+	sc.mu.Unlock()
+	if index == nil || dbUrl == "" || dbName == "" {
+		return nil
+	}
+
+	var (
+		now     = time.Now()
+		metrics []influxdb.SMetricData
+		errs    []error
+	)
+	sc.countersNext = map[string]statsCounter{}
+	defer func() {
+		sc.counters = sc.countersNext
+	}()
+	// the socket is not there when no tcp/http listeners are served
+	if fi, err := os.Stat(sc.statsSocket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		haproxyStats, err := agentutils.HaproxyShowStat(sc.statsSocket, 3*time.Second)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "haproxy"))
+		} else {
+			metrics = append(metrics, sc.haproxyMetrics(index, haproxyStats, now)...)
+		}
+	}
+	for _, lis := range index.udpListeners {
+		stats, err := gobetween.GetServerStats(ctx, sc.client, lis.Id)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "gobetween"))
+			continue
+		}
+		metrics = append(metrics, sc.gobetweenMetrics(index, lis, stats, now)...)
+	}
+	if len(metrics) > 0 {
+		if err := sc.send(dbUrl, dbName, metrics); err != nil {
+			errs = append(errs, errors.Wrap(err, "send metrics"))
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (sc *StatsCollector) send(dbUrl, dbName string, metrics []influxdb.SMetricData) error {
+	if sc.db == nil || sc.dbSet != dbUrl+"/"+dbName {
+		db := influxdb.NewInfluxdb(dbUrl)
+		if err := db.SetDatabase(dbName); err != nil {
+			return errors.Wrapf(err, "set database %s", dbName)
+		}
+		sc.db = db
+		sc.dbSet = dbUrl + "/" + dbName
+	}
+	lines := make([]string, len(metrics))
+	for i := range metrics {
+		lines[i] = metrics[i].Line()
+	}
+	return sc.db.Write(strings.Join(lines, "\n"), "ms")
+}
+
+// rate returns per-second increase of counter since last sample
+func (sc *StatsCollector) rate(key string, value int64, now time.Time) (float64, bool) {
+	prev, ok := sc.counters[key]
+	sc.countersNext[key] = statsCounter{value: value, time: now}
+	if !ok || value < prev.value {
+		return 0, false
+	}
+	secs := now.Sub(prev.time).Seconds()
+	if secs <= 0 {
+		return 0, false
+	}
+	return float64(value-prev.value) / secs, true
+}
+
+func (sc *StatsCollector) listenerTags(lis *statsListener) []influxdb.SKeyValue {
+	return []influxdb.SKeyValue{
+		{Key: "lbagent_id", Value: sc.opts.ApiLbagentId},
+		{Key: "loadbalancer_id", Value: lis.LoadbalancerId},
+		{Key: "listener_id", Value: lis.Id},
+		{Key: "listener_type", Value: lis.ListenerType},
+	}
+}
+
+func (sc *StatsCollector) backendTags(lis *statsListener, backend *statsBackend, ruleId string) []influxdb.SKeyValue {
+	tags := sc.listenerTags(lis)
+	tags = append(tags,
+		influxdb.SKeyValue{Key: "backend_group_id", Value: backend.BackendGroupId},
+		influxdb.SKeyValue{Key: "backend_id", Value: backend.Id},
+		influxdb.SKeyValue{Key: "address", Value: backend.HostPort()},
+	)
+	if ruleId != "" {
+		tags = append(tags, influxdb.SKeyValue{Key: "listener_rule_id", Value: ruleId})
+	}
+	return tags
+}
+
+func statsValue(k string, v interface{}) influxdb.SKeyValue {
+	var s string
+	switch vv := v.(type) {
+	case int64:
+		s = strconv.FormatInt(vv, 10) + "i"
+	case float64:
+		s = strconv.FormatFloat(vv, 'f', 3, 64)
+	case bool:
+		s = "0i"
+		if vv {
+			s = "1i"
+		}
+	}
+	return influxdb.SKeyValue{Key: k, Value: s}
+}
+
+// haproxyStatsListener finds listener of the proxy and the rule, if any
+func (index *statsIndex) haproxyStatsListener(pxname string) (*statsListener, string) {
+	var lisId, ruleId string
+	switch {
+	case strings.HasPrefix(pxname, haproxyPxListener):
+		lisId = pxname[len(haproxyPxListener):]
+	case strings.HasPrefix(pxname, haproxyPxListenerDefault):
+		lisId = pxname[len(haproxyPxListenerDefault):]
+	case strings.HasPrefix(pxname, haproxyPxRule):
+		ruleId = pxname[len(haproxyPxRule):]
+		lisId = index.ruleListener[ruleId]
+	default:
+		lisId = pxname
+	}
+	return index.listeners[lisId], ruleId
+}
+
+func (sc *StatsCollector) haproxyMetrics(index *statsIndex, stats []agentutils.HaproxyStat, now time.Time) []influxdb.SMetricData {
+	var metrics []influxdb.SMetricData
+	for _, stat := range stats {
+		var (
+			pxname = stat.PxName()
+			svname = stat.SvName()
+			key    = pxname + "/" + svname
+		)
+		lis, ruleId := index.haproxyStatsListener(pxname)
+		if lis == nil {
+			continue
+		}
+		fields := []influxdb.SKeyValue{
+			statsValue("conn_cur", stat.Int("scur")),
+			statsValue("conn_total", stat.Int("stot")),
+			statsValue("conn_rate", stat.Int("rate")),
+			statsValue("bytes_in", stat.Int("bin")),
+			statsValue("bytes_out", stat.Int("bout")),
+			statsValue("hrsp_4xx", stat.Int("hrsp_4xx")),
+			statsValue("hrsp_5xx", stat.Int("hrsp_5xx")),
+		}
+		for _, k := range []string{"hrsp_4xx", "hrsp_5xx"} {
+			if r, ok := sc.rate(key+"/"+k, stat.Int(k), now); ok {
+				fields = append(fields, statsValue(k+"_rate", r))
+			}
+		}
+		switch svname {
+		case "FRONTEND":
+			if ruleId != "" || pxname != lis.Id {
+				continue
+			}
+			fields = append(fields,
+				statsValue("req_rate", stat.Int("req_rate")),
+				statsValue("req_total", stat.Int("req_tot")),
+				statsValue("req_denied", stat.Int("dreq")),
+				statsValue("req_error", stat.Int("ereq")),
+			)
+			metrics = append(metrics, influxdb.SMetricData{
+				Name:      api.LB_STATS_MEASUREMENT_LISTENER,
+				Tags:      sc.listenerTags(lis),
+				Metrics:   fields,
+				Timestamp: now,
+			})
+		case "BACKEND":
+		default:
+			backend, ok := index.backends[svname]
+			if !ok {
+				continue
+			}
+			fields = append(fields,
+				statsValue("queue_cur", stat.Int("qcur")),
+				statsValue("conn_error", stat.Int("econ")),
+				statsValue("resp_error", stat.Int("eresp")),
+				statsValue("queue_time", stat.Int("qtime")),
+				statsValue("connect_time", stat.Int("ctime")),
+				statsValue("response_time", stat.Int("rtime")),
+				statsValue("total_time", stat.Int("ttime")),
+				statsValue("check_code", stat.Int("check_code")),
+				statsValue("healthy", stat.IsUp()),
+			)
+			metrics = append(metrics, influxdb.SMetricData{
+				Name:      api.LB_STATS_MEASUREMENT_BACKEND,
+				Tags:      sc.backendTags(lis, backend, ruleId),
+				Metrics:   fields,
+				Timestamp: now,
+			})
+		}
+	}
+	return metrics
+}
+
+func (sc *StatsCollector) gobetweenMetrics(index *statsIndex, lis *statsListener, stats *gobetween.ServerStats, now time.Time) []influxdb.SMetricData {
+	metrics := []influxdb.SMetricData{
+		{
+			Name: api.LB_STATS_MEASUREMENT_LISTENER,
+			Tags: sc.listenerTags(lis),
+			Metrics: []influxdb.SKeyValue{
+				statsValue("conn_cur", int64(stats.ActiveConnections)),
+				statsValue("bytes_in", int64(stats.RxTotal)),
+				statsValue("bytes_out", int64(stats.TxTotal)),
+				statsValue("bytes_in_rate", int64(stats.RxSecond)),
+				statsValue("bytes_out_rate", int64(stats.TxSecond)),
+			},
+			Timestamp: now,
+		},
+	}
+	backends := map[string]*statsBackend{}
+	for _, backend := range index.backends {
+		if backend.BackendGroupId == lis.BackendGroupId {
+			backends[backend.HostPort()] = backend
+		}
+	}
+	for i := range stats.Backends {
+		b := &stats.Backends[i]
+		backend, ok := backends[net.JoinHostPort(b.Host, b.Port)]
+		if !ok {
+			continue
+		}
+		metrics = append(metrics, influxdb.SMetricData{
+			Name: api.LB_STATS_MEASUREMENT_BACKEND,
+			Tags: sc.backendTags(lis, backend, ""),
+			Metrics: []influxdb.SKeyValue{
+				statsValue("conn_cur", b.Stats.ActiveConnections),
+				statsValue("conn_total", b.Stats.TotalConnections),
+				statsValue("conn_refused", b.Stats.RefusedConnections),
+				statsValue("bytes_in", int64(b.Stats.RxBytes)),
+				statsValue("bytes_out", int64(b.Stats.TxBytes)),
+				statsValue("healthy", b.Stats.Live),
+			},
+			Timestamp: now,
+		})
+	}
+	return metrics
+}
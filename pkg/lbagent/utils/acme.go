@@ -0,0 +1,75 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"strings"
+
+	"yunion.io/x/jsonutils"
+)
+
+// Settings of acme http-01 challenge responder.  Haproxy http frontends
+// route challenge requests for acme domains to it
+const (
+	AcmeHttpChallengeBind    = "127.0.0.1:7781"
+	AcmeHttpChallengePath    = "/.well-known/acme-challenge/"
+	AcmeHttpChallengeBackend = "backends_acme_challenge"
+)
+
+// AcmeDnsRecordName returns name, relative to zone, of the TXT record for
+// dns-01 challenge of domain.  Domain must be in the zone
+func AcmeDnsRecordName(domain, zone string) string {
+	domain = strings.TrimPrefix(domain, "*.")
+	name := "_acme-challenge"
+	if sub := strings.TrimSuffix(domain, "."+zone); sub != domain {
+		name += "." + sub
+	}
+	return name
+}
+
+// AcmeDnsZoneCandidates returns domain and its parent domains, excluding top
+// level ones, as names of dns zones that may contain domain
+func AcmeDnsZoneCandidates(domain string) []string {
+	var r []string
+	domain = strings.TrimPrefix(domain, "*.")
+	for name := domain; strings.Contains(name, "."); name = name[strings.Index(name, ".")+1:] {
+		r = append(r, name)
+	}
+	return r
+}
+
+// AcmeDnsZoneAccessible returns true if the dns zone, as listed from region,
+// is owned by or shared with the domain of certificate owner.  Zones of other
+// domains must never be used to prove control of the acme domains
+func AcmeDnsZoneAccessible(zone jsonutils.JSONObject, domainId string) bool {
+	isPublic := jsonutils.QueryBoolean(zone, "is_public", false)
+	publicScope, _ := zone.GetString("public_scope")
+	if isPublic && publicScope == "system" {
+		return true
+	}
+	if zoneDomainId, _ := zone.GetString("domain_id"); len(domainId) > 0 && zoneDomainId == domainId {
+		return true
+	}
+	if !isPublic {
+		return false
+	}
+	sharedDomains, _ := zone.GetArray("shared_domains")
+	for _, shared := range sharedDomains {
+		if id, _ := shared.GetString("id"); len(domainId) > 0 && id == domainId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestAcmeDnsRecordName(t *testing.T) {
+	cases := []struct {
+		domain string
+		zone   string
+		want   string
+	}{
+		{"example.com", "example.com", "_acme-challenge"},
+		{"*.example.com", "example.com", "_acme-challenge"},
+		{"www.example.com", "example.com", "_acme-challenge.www"},
+		{"*.a.b.example.com", "example.com", "_acme-challenge.a.b"},
+		{"www.b.example.com", "b.example.com", "_acme-challenge.www"},
+	}
+	for _, c := range cases {
+		got := AcmeDnsRecordName(c.domain, c.zone)
+		if got != c.want {
+			t.Errorf("%s in %s: got %q, want %q", c.domain, c.zone, got, c.want)
+		}
+	}
+}
+
+func TestAcmeDnsZoneCandidates(t *testing.T) {
+	got := AcmeDnsZoneCandidates("*.a.example.com")
+	want := []string{"a.example.com", "example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAcmeDnsZoneAccessible(t *testing.T) {
+	cases := []struct {
+		zone string
+		want bool
+	}{
+		{`{"domain_id":"d1","is_public":false}`, true},
+		{`{"domain_id":"d2","is_public":false}`, false},
+		{`{"domain_id":"d2","is_public":true,"public_scope":"system"}`, true},
+		{`{"domain_id":"d2","is_public":true,"public_scope":"domain","shared_domains":[{"id":"d1"}]}`, true},
+		{`{"domain_id":"d2","is_public":true,"public_scope":"domain","shared_domains":[{"id":"d3"}]}`, false},
+		{`{"domain_id":"d2","is_public":false,"shared_domains":[{"id":"d1"}]}`, false},
+	}
+	for i, c := range cases {
+		zone, _ := jsonutils.ParseString(c.zone)
+		if got := AcmeDnsZoneAccessible(zone, "d1"); got != c.want {
+			t.Errorf("case %d: got %v, want %v", i, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// HaproxyStat is one line of "show stat" output, keyed by csv header field
+// names, e.g. pxname, svname, scur, hrsp_5xx, rtime
+type HaproxyStat map[string]string
+
+func (s HaproxyStat) PxName() string {
+	return s["pxname"]
+}
+
+func (s HaproxyStat) SvName() string {
+	return s["svname"]
+}
+
+// Int returns value of numeric field k.  Empty or invalid ones are taken as 0
+func (s HaproxyStat) Int(k string) int64 {
+	v, _ := strconv.ParseInt(s[k], 10, 64)
+	return v
+}
+
+// IsUp reports whether the server is in service.  Servers without health
+// check are considered up unless they are put into maintenance
+func (s HaproxyStat) IsUp() bool {
+	status := s["status"]
+	return strings.HasPrefix(status, "UP") || status == "OPEN" || status == "no check"
+}
+
+func ParseHaproxyStats(r io.Reader) ([]HaproxyStat, error) {
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil {
+		return nil, errors.Wrap(err, "read header")
+	}
+	if !strings.HasPrefix(header, "# ") {
+		return nil, errors.Errorf("unexpected header: %q", header)
+	}
+	keys := strings.Split(strings.TrimRight(header[2:], ",\r\n"), ",")
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	stats := []HaproxyStat{}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read stat")
+		}
+		stat := HaproxyStat{}
+		for i, k := range keys {
+			if i < len(rec) {
+				stat[k] = rec[i]
+			}
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// HaproxyShowStat queries haproxy stats socket for counters of all proxies
+// and servers
+func HaproxyShowStat(sockPath string, timeout time.Duration) ([]HaproxyStat, error) {
+	conn, err := net.DialTimeout("unix", sockPath, timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial %s", sockPath)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte("show stat\n")); err != nil {
+		return nil, errors.Wrap(err, "write show stat")
+	}
+	return ParseHaproxyStats(conn)
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHaproxyStats(t *testing.T) {
+	out := `# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,
+lis-1,FRONTEND,,,3,10,2000,120,1000,2000,0,0,1,,,,,OPEN,,,,,,,,,1,2,0,,,,0,5,0,9,,,,0,100,0,7,2,
+backends_listener_default-lis-1,be-1,0,0,1,4,,60,500,1000,,0,,0,0,0,0,UP 1/3,1,1,0,0,0,30,0,,1,3,1,,60,,2,2,,4,L7OK,200,1,0,50,0,3,1,
+backends_listener_default-lis-1,be-2,0,0,0,0,,0,0,0,,0,,0,0,0,0,DOWN,1,1,0,1,1,30,30,,1,3,2,,0,,2,0,,0,L4CON,,0,0,0,0,0,0,
+
+`
+	stats, err := ParseHaproxyStats(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("want 3 stats, got %d", len(stats))
+	}
+	fe := stats[0]
+	if fe.PxName() != "lis-1" || fe.SvName() != "FRONTEND" {
+		t.Errorf("frontend: got %s/%s", fe.PxName(), fe.SvName())
+	}
+	if v := fe.Int("scur"); v != 3 {
+		t.Errorf("frontend scur: want 3, got %d", v)
+	}
+	if v := fe.Int("hrsp_5xx"); v != 2 {
+		t.Errorf("frontend hrsp_5xx: want 2, got %d", v)
+	}
+	if !stats[1].IsUp() {
+		t.Errorf("be-1 should be up")
+	}
+	if stats[2].IsUp() {
+		t.Errorf("be-2 should be down")
+	}
+	if v := stats[2].Int("check_code"); v != 0 {
+		t.Errorf("be-2 check_code: want 0, got %d", v)
+	}
+
+	if _, err := ParseHaproxyStats(strings.NewReader("Unknown command\n")); err == nil {
+		t.Errorf("want error for invalid output")
+	}
+}
@@ -196,6 +196,13 @@ type LoadbalancerCertificate struct {
 	NotAfter                time.Time
 	CommonName              string
 	SubjectAlternativeNames string
+
+	CertificateType  string
+	AcmeDomains      string
+	AcmeChallenge    string
+	AcmeDirectoryUrl string
+	AcmeEmail        string
+	AcmeAccountKey   string
 }
 
 type LoadbalancerCluster struct {
@@ -20,8 +20,9 @@ import (
 )
 
 var (
-	Cloudevents    modulebase.ResourceManager
-	CloudeventLogs modulebase.ResourceManager
+	Cloudevents     modulebase.ResourceManager
+	CloudeventLogs  modulebase.ResourceManager
+	CloudeventHooks modulebase.ResourceManager
 )
 
 func init() {
@@ -32,6 +33,13 @@ func init() {
 
 	modules.Register(&Cloudevents)
 
+	CloudeventHooks = modules.NewCloudeventManager("cloudeventhook", "cloudeventhooks",
+		[]string{"Id", "Name", "Enabled", "Status", "Filter", "Hook_Action",
+			"Trigger_Count", "Last_Trigger_At", "Last_Trigger_Status", "Last_Trigger_Reason", "Domain"},
+		[]string{"Hook_Action_Params"})
+
+	modules.Register(&CloudeventHooks)
+
 	CloudeventLogs = modules.NewCloudeventManager("event", "events",
 		[]string{"id", "ops_time", "obj_id", "obj_type", "obj_name", "user", "user_id", "tenant", "tenant_id", "owner_tenant_id", "action", "notes"},
 		[]string{})
@@ -23,6 +23,9 @@ var (
 	ScalingGroup    modulebase.ResourceManager
 	ScalingPolicy   modulebase.ResourceManager
 	ScalingActivity modulebase.ResourceManager
+
+	ScalingLifecycleHook   modulebase.ResourceManager
+	ScalingInstanceRefresh modulebase.ResourceManager
 )
 
 func init() {
@@ -41,7 +44,19 @@ func init() {
 			"End_Time", "Reason"},
 		[]string{},
 	)
+	ScalingLifecycleHook = modules.NewComputeManager("scalinglifecyclehook", "scalinglifecyclehooks",
+		[]string{"ID", "Name", "Scaling_Group_ID", "Hook_Type", "Action_Type", "Webhook_Url",
+			"Ansible_Playbook_Reference", "Timeout", "Default_Result", "Enabled"},
+		[]string{},
+	)
+	ScalingInstanceRefresh = modules.NewComputeManager("scalinginstancerefresh", "scalinginstancerefreshes",
+		[]string{"ID", "Scaling_Group_ID", "Status", "Min_Healthy_Percentage", "Batch_Size", "Total_Number",
+			"Replaced_Number", "Start_Time", "End_Time", "Reason"},
+		[]string{},
+	)
 	modules.RegisterCompute(&ScalingGroup)
 	modules.RegisterCompute(&ScalingPolicy)
 	modules.RegisterCompute(&ScalingActivity)
+	modules.RegisterCompute(&ScalingLifecycleHook)
+	modules.RegisterCompute(&ScalingInstanceRefresh)
 }
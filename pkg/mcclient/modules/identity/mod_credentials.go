@@ -44,6 +44,7 @@ const (
 	TOTP_TYPE             = api.TOTP_TYPE
 	RECOVERY_SECRETS_TYPE = api.RECOVERY_SECRETS_TYPE
 	OIDC_CREDENTIAL_TYPE  = api.OIDC_CREDENTIAL_TYPE
+	DNSSEC_KEY_TYPE       = api.DNSSEC_KEY_TYPE
 )
 
 type STotpSecret struct {
@@ -106,6 +107,10 @@ func (manager *SCredentialManager) FetchOIDCSecrets(s *mcclient.ClientSession, u
 	return manager.fetchCredentials(s, OIDC_CREDENTIAL_TYPE, uid, pid)
 }
 
+func (manager *SCredentialManager) FetchDnssecKeys(s *mcclient.ClientSession) ([]jsonutils.JSONObject, error) {
+	return manager.fetchCredentials(s, DNSSEC_KEY_TYPE, "", "")
+}
+
 func (manager *SCredentialManager) GetTotpSecret(s *mcclient.ClientSession, uid string) (string, error) {
 	secrets, err := manager.FetchTotpSecrets(s, uid)
 	if err != nil {
@@ -330,6 +335,18 @@ func (manager *SCredentialManager) CreateOIDCSecret(s *mcclient.ClientSession, u
 	return oidcCred, nil
 }
 
+func (manager *SCredentialManager) CreateDnssecKey(s *mcclient.ClientSession, key api.SDnssecKeyBlob, name string) (string, error) {
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(DNSSEC_KEY_TYPE), "type")
+	params.Add(jsonutils.NewString(jsonutils.Marshal(&key).String()), "blob")
+	params.Add(jsonutils.NewString(name), "name")
+	result, err := manager.Create(s, params)
+	if err != nil {
+		return "", err
+	}
+	return result.GetString("id")
+}
+
 func (manager *SCredentialManager) CreateTotpSecret(s *mcclient.ClientSession, uid string) (string, error) {
 	_, err := manager.GetTotpSecret(s, uid)
 	if err == nil {
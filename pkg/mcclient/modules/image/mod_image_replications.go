@@ -0,0 +1,37 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	ImageReplicationPolicies modulebase.ResourceManager
+	ImageReplicas            modulebase.ResourceManager
+)
+
+func init() {
+	ImageReplicationPolicies = modules.NewImageManager("image_replication_policy", "image_replication_policies",
+		[]string{"ID", "Name", "Enabled", "Status", "Source_region", "Target_regions", "Tags", "Interval_minutes", "Last_run_at", "Replica_count", "Ready_replica_count"},
+		[]string{})
+	modules.Register(&ImageReplicationPolicies)
+
+	ImageReplicas = modules.NewImageManager("image_replica", "image_replicas",
+		[]string{"ID", "Name", "Status", "Progress", "Image_id", "Image", "Policy_id", "Policy", "Target_region", "Target_image_id", "Checksum", "Last_sync_at"},
+		[]string{})
+	modules.Register(&ImageReplicas)
+}
@@ -577,6 +577,30 @@ func (this *ImageManager) Download2(s *mcclient.ClientSession, id string, format
 	}
 }
 
+// DownloadRange downloads the content of an image starting at offset, the
+// returned offset is where the content actually starts, that is 0 if the
+// server does not support range requests
+func (this *ImageManager) DownloadRange(s *mcclient.ClientSession, id string, offset int64) (io.ReadCloser, int64, int64, error) {
+	path := fmt.Sprintf("/%s/%s", this.URLPath(), url.PathEscape(id))
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := modulebase.RawRequest(this.ResourceManager, s, "GET", path, header, nil)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _, err = s.ParseJSONResponse("", resp, err)
+		return nil, -1, 0, err
+	}
+	sizeBytes, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		sizeBytes = -1
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		offset = 0
+	}
+	return resp.Body, sizeBytes, offset, nil
+}
+
 var (
 	Images ImageManager
 )
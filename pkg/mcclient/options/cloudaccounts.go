@@ -55,6 +55,20 @@ type SNutanixCredentialWithEnvironment struct {
 	Port string `help:"Nutanix host port" default:"9440"`
 }
 
+type SProxmoxCredentialWithEnvironment struct {
+	SAccessKeyCredential
+
+	Host string `help:"Proxmox VE host" positional:"true"`
+	Port string `help:"Proxmox VE host port" default:"8006"`
+}
+
+type SOVirtCredentialWithEnvironment struct {
+	SUserPasswordCredential
+
+	Host string `help:"oVirt engine host" positional:"true"`
+	Port string `help:"oVirt engine port" default:"443"`
+}
+
 type SAzureCredential struct {
 	ClientID     string `help:"Azure client_id" positional:"true"`
 	ClientSecret string `help:"Azure clinet_secret" positional:"true"`
@@ -1095,3 +1109,51 @@ type SBingoCloudAccountUpdateCredentialOptions struct {
 func (opts *SBingoCloudAccountUpdateCredentialOptions) Params() (jsonutils.JSONObject, error) {
 	return jsonutils.Marshal(opts.SAccessKeyCredential), nil
 }
+
+type SProxmoxCloudAccountCreateOptions struct {
+	SCloudAccountCreateBaseOptions
+	SProxmoxCredentialWithEnvironment
+}
+
+func (opts *SProxmoxCloudAccountCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts)
+	params.(*jsonutils.JSONDict).Add(jsonutils.NewString("Proxmox"), "provider")
+	return params, nil
+}
+
+type SProxmoxCloudAccountUpdateOptions struct {
+	SCloudAccountUpdateBaseOptions
+}
+
+type SProxmoxCloudAccountUpdateCredentialOptions struct {
+	SCloudAccountIdOptions
+	SAccessKeyCredential
+}
+
+func (opts *SProxmoxCloudAccountUpdateCredentialOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts.SAccessKeyCredential), nil
+}
+
+type SOVirtCloudAccountCreateOptions struct {
+	SCloudAccountCreateBaseOptions
+	SOVirtCredentialWithEnvironment
+}
+
+func (opts *SOVirtCloudAccountCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts)
+	params.(*jsonutils.JSONDict).Add(jsonutils.NewString("oVirt"), "provider")
+	return params, nil
+}
+
+type SOVirtCloudAccountUpdateOptions struct {
+	SCloudAccountUpdateBaseOptions
+}
+
+type SOVirtCloudAccountUpdateCredentialOptions struct {
+	SCloudAccountIdOptions
+	SUserPasswordCredential
+}
+
+func (opts *SOVirtCloudAccountUpdateCredentialOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts.SUserPasswordCredential), nil
+}
@@ -53,6 +53,10 @@ type RouterGetOptions struct {
 	ID string `json:"-"`
 }
 
+type RouterPeerStatusOptions struct {
+	ID string `json:"-"`
+}
+
 type RouterUpdateOptions struct {
 	ID   string `json:"-"`
 	Name string
@@ -95,6 +99,7 @@ type RouterActionJoinMeshNetworkOptions struct {
 
 	MeshNetwork      string
 	AdvertiseSubnets string `help:"cidr concatenated by comma"`
+	MappedSubnets    string `help:"cidr concatenated by comma, 1:1 NAT mapping of advertise subnets seen by other members"`
 }
 
 type RouterActionLeaveMeshNetworkOptions struct {
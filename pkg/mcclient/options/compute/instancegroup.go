@@ -29,6 +29,9 @@ type InstanceGroupListOptions struct {
 	ParentId    string `help:"Parent ID"`
 	ZoneId      string `help:"Zone ID"`
 	Server      string `help:"Guest ID or Name"`
+
+	Policy      []string `help:"filter by policy" choices:"anti_affinity|affinity|spread"`
+	TopologyKey []string `help:"filter by topology key" choices:"host|rack|schedtag|zone"`
 }
 
 func (opts *InstanceGroupListOptions) Params() (jsonutils.JSONObject, error) {
@@ -48,6 +51,12 @@ type InstanceGroupCreateOptions struct {
 	SchedStrategy   string `help:"scheduler strategy"`
 	Granularity     string `help:"the upper limit number of guests with this group in a host"`
 	ForceDispersion bool   `help:"force to make guest dispersion"`
+
+	Policy                 string `help:"placement policy of guests" choices:"anti_affinity|affinity|spread"`
+	TopologyKey            string `help:"topology level of the policy" choices:"host|rack|schedtag|zone"`
+	TopologySchedtagPrefix string `help:"host schedtags with this prefix divide topology domains, required by topology key schedtag"`
+	MaxSkew                string `help:"max difference of guest counts between topology domains of spread policy"`
+	Weight                 string `help:"weight of the soft policy in scheduling"`
 }
 
 func (opts *InstanceGroupCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -64,6 +73,12 @@ type InstanceGroupUpdateOptions struct {
 	Name            string `help:"New name to change"`
 	Granularity     string `help:"the upper limit number of guests with this group in a host"`
 	ForceDispersion string `help:"force to make guest dispersion" choices:"yes|no" json:"-"`
+
+	Policy                 string `help:"placement policy of guests" choices:"anti_affinity|affinity|spread"`
+	TopologyKey            string `help:"topology level of the policy" choices:"host|rack|schedtag|zone"`
+	TopologySchedtagPrefix string `help:"host schedtags with this prefix divide topology domains"`
+	MaxSkew                string `help:"max difference of guest counts between topology domains of spread policy"`
+	Weight                 string `help:"weight of the soft policy in scheduling"`
 }
 
 func (opts *InstanceGroupUpdateOptions) Params() (jsonutils.JSONObject, error) {
@@ -73,7 +88,7 @@ func (opts *InstanceGroupUpdateOptions) Params() (jsonutils.JSONObject, error) {
 	}
 	if opts.ForceDispersion == "yes" {
 		params.Set("force_dispersion", jsonutils.JSONTrue)
-	} else {
+	} else if opts.ForceDispersion == "no" {
 		params.Set("force_dispersion", jsonutils.JSONFalse)
 	}
 	return params, nil
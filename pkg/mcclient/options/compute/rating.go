@@ -0,0 +1,178 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type PricePlanListOptions struct {
+	options.BaseListOptions
+
+	Hypervisor []string `help:"filter by hypervisor" choices:"kvm|esxi"`
+	Currency   []string `help:"filter by currency"`
+}
+
+func (opts *PricePlanListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type PricePlanPriceOptions struct {
+	CpuPrice       *float64 `help:"price per vCPU per hour"`
+	MemPrice       *float64 `help:"price per GB memory per hour"`
+	DiskPrice      *float64 `help:"default price per GB disk per hour"`
+	StoragePrice   []string `help:"price per GB disk of storage type per hour, e.g. rbd=0.0002" json:"-"`
+	GpuPrice       *float64 `help:"price per GPU per hour"`
+	EipPrice       *float64 `help:"price per eip per hour"`
+	BandwidthPrice *float64 `help:"price per Mbps bandwidth per hour"`
+}
+
+func (opts *PricePlanPriceOptions) params(params *jsonutils.JSONDict) error {
+	if len(opts.StoragePrice) == 0 {
+		return nil
+	}
+	prices := jsonutils.NewDict()
+	for _, sp := range opts.StoragePrice {
+		parts := strings.SplitN(sp, "=", 2)
+		if len(parts) != 2 {
+			return errors.Errorf("invalid storage price %s, should be <storage_type>=<price>", sp)
+		}
+		price, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid price of storage type %s", parts[0])
+		}
+		prices.Set(parts[0], jsonutils.NewFloat64(price))
+	}
+	params.Set("storage_type_prices", prices)
+	return nil
+}
+
+type PricePlanCreateOptions struct {
+	options.EnabledStatusCreateOptions
+	PricePlanPriceOptions
+
+	Currency   string `help:"currency of prices" default:"CNY"`
+	Hypervisor string `help:"hypervisor the plan applies to, all hypervisors if not specified" choices:"kvm|esxi"`
+}
+
+func (opts *PricePlanCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	err := opts.PricePlanPriceOptions.params(params)
+	if err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+type PricePlanUpdateOptions struct {
+	options.BaseUpdateOptions
+	PricePlanPriceOptions
+}
+
+func (opts *PricePlanUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	params.Remove("id")
+	err := opts.PricePlanPriceOptions.params(params)
+	if err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+type RatingBillListOptions struct {
+	options.BaseListOptions
+
+	StartDate    string   `help:"start date of bills, e.g. 2006-01-02"`
+	EndDate      string   `help:"end date of bills, e.g. 2006-01-02"`
+	ResourceType []string `help:"filter by resource type" choices:"server|disk|eip"`
+	ResourceId   []string `help:"filter by resource id"`
+	Item         []string `help:"filter by rating item" choices:"cpu|mem|disk|gpu|eip|bandwidth"`
+	PricePlanId  []string `help:"filter by price plan"`
+}
+
+func (opts *RatingBillListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type RatingBillSummaryOptions struct {
+	RatingBillListOptions
+
+	GroupBy string `help:"summarize bills by" choices:"project|domain|item|bill_date" default:"project"`
+}
+
+func (opts *RatingBillSummaryOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := opts.RatingBillListOptions.Params()
+	if err != nil {
+		return nil, err
+	}
+	params.(*jsonutils.JSONDict).Set("group_by", jsonutils.NewString(opts.GroupBy))
+	params.(*jsonutils.JSONDict).Remove("limit")
+	params.(*jsonutils.JSONDict).Remove("offset")
+	params.(*jsonutils.JSONDict).Remove("export_keys")
+	return params, nil
+}
+
+type RatingBillRegenerateOptions struct {
+	BillDate string `help:"date of bills to regenerate, e.g. 2006-01-02, default is yesterday"`
+}
+
+func (opts *RatingBillRegenerateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type BudgetListOptions struct {
+	options.BaseListOptions
+
+	BudgetScope []string `help:"filter by budget scope" choices:"project|domain"`
+	Period      []string `help:"filter by period" choices:"day|month"`
+}
+
+func (opts *BudgetListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type BudgetCreateOptions struct {
+	options.BaseCreateOptions
+
+	AMOUNT       float64 `help:"amount of budget in a period"`
+	BudgetScope  string  `help:"bills of the project or the domain of budget are counted" choices:"project|domain" default:"project"`
+	Period       string  `help:"period of budget" choices:"day|month" default:"month"`
+	Currency     string  `help:"currency of budget" default:"CNY"`
+	AlertPercent int     `help:"raise an event when the cost reaches the percent of amount" default:"100"`
+	Project      string  `help:"project of budget" json:"project_id"`
+}
+
+func (opts *BudgetCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type BudgetUpdateOptions struct {
+	options.BaseUpdateOptions
+
+	Amount       *float64 `help:"amount of budget in a period"`
+	AlertPercent *int     `help:"raise an event when the cost reaches the percent of amount"`
+}
+
+func (opts *BudgetUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	params.Remove("id")
+	return params, nil
+}
@@ -44,6 +44,8 @@ type SecGroupRulesCreateOptions struct {
 	Priority       int64  `help:"priority of Rule" default:"50"`
 	Desc           string `help:"Description" json:"description"`
 	PeerSecgroupId string `help:"Peer Secgroup Id" json:"peer_secgroup_id"`
+
+	VpcPeeringConnectionId string `help:"Accepted vpc peering connection, cidr of rule defaults to and must be in peer vpc cidr" json:"vpc_peering_connection_id"`
 }
 
 func (opts *SecGroupRulesCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -51,16 +53,22 @@ func (opts *SecGroupRulesCreateOptions) Params() (jsonutils.JSONObject, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "invalid rule %s", opts.RULE)
 	}
+	cidr := rule.IPNet.String()
+	if len(opts.VpcPeeringConnectionId) > 0 && cidr == "0.0.0.0/0" {
+		// let region fill in the cidr of peer vpc
+		cidr = ""
+	}
 	return jsonutils.Marshal(map[string]interface{}{
-		"direction":        rule.Direction,
-		"action":           rule.Action,
-		"protocol":         rule.Protocol,
-		"cidr":             rule.IPNet.String(),
-		"ports":            rule.GetPortsString(),
-		"priority":         opts.Priority,
-		"description":      opts.Desc,
-		"secgroup_id":      opts.SECGROUP,
-		"peer_secgroup_id": opts.PeerSecgroupId,
+		"direction":                 rule.Direction,
+		"action":                    rule.Action,
+		"protocol":                  rule.Protocol,
+		"cidr":                      cidr,
+		"ports":                     rule.GetPortsString(),
+		"priority":                  opts.Priority,
+		"description":               opts.Desc,
+		"secgroup_id":               opts.SECGROUP,
+		"peer_secgroup_id":          opts.PeerSecgroupId,
+		"vpc_peering_connection_id": opts.VpcPeeringConnectionId,
 	}), nil
 }
 
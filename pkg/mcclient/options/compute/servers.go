@@ -376,31 +376,33 @@ type ServerCreateOptionalOptions struct {
 
 	MemSpec string `help:"Memory size Or Instance Type" metavar:"MEMSPEC" json:"-"`
 
-	Keypair          string   `help:"SSH Keypair"`
-	Password         string   `help:"Default user password"`
-	LoginAccount     string   `help:"Guest login account"`
-	Iso              string   `help:"ISO image ID" metavar:"IMAGE_ID" json:"cdrom"`
-	VcpuCount        int      `help:"#CPU cores of VM server, default 1" default:"1" metavar:"<SERVER_CPU_COUNT>" json:"vcpu_count" token:"ncpu"`
-	InstanceType     string   `help:"instance flavor"`
-	Vga              string   `help:"VGA driver" choices:"std|vmware|cirrus|qxl"`
-	Vdi              string   `help:"VDI protocool" choices:"vnc|spice"`
-	Bios             string   `help:"BIOS" choices:"BIOS|UEFI"`
-	Machine          string   `help:"Machine type" choices:"pc|q35"`
-	Desc             string   `help:"Description" metavar:"<DESCRIPTION>" json:"description"`
-	Boot             string   `help:"Boot device" metavar:"<BOOT_DEVICE>" choices:"disk|cdrom" json:"-"`
-	EnableCloudInit  bool     `help:"Enable cloud-init service"`
-	NoAccountInit    *bool    `help:"Not reset account password"`
-	AllowDelete      *bool    `help:"Unlock server to allow deleting" json:"-"`
-	ShutdownBehavior string   `help:"Behavior after VM server shutdown" metavar:"<SHUTDOWN_BEHAVIOR>" choices:"stop|terminate"`
-	AutoStart        bool     `help:"Auto start server after it is created"`
-	Deploy           []string `help:"Specify deploy files in virtual server file system" json:"-"`
-	Group            []string `help:"Group ID or Name of virtual server"`
-	System           bool     `help:"Create a system VM, sysadmin ONLY option" json:"is_system"`
-	TaskNotify       *bool    `help:"Setup task notify" json:"-"`
-	DryRun           *bool    `help:"Dry run to test scheduler" json:"-"`
-	UserDataFile     string   `help:"user_data file path" json:"-"`
-	InstanceSnapshot string   `help:"instance snapshot" json:"instance_snapshot"`
-	Secgroups        []string `help:"secgroups" json:"secgroups"`
+	Keypair            string   `help:"SSH Keypair"`
+	Password           string   `help:"Default user password"`
+	LoginAccount       string   `help:"Guest login account"`
+	Iso                string   `help:"ISO image ID" metavar:"IMAGE_ID" json:"cdrom"`
+	VcpuCount          int      `help:"#CPU cores of VM server, default 1" default:"1" metavar:"<SERVER_CPU_COUNT>" json:"vcpu_count" token:"ncpu"`
+	InstanceType       string   `help:"instance flavor"`
+	Vga                string   `help:"VGA driver" choices:"std|vmware|cirrus|qxl"`
+	Vdi                string   `help:"VDI protocool" choices:"vnc|spice"`
+	Bios               string   `help:"BIOS" choices:"BIOS|UEFI"`
+	Machine            string   `help:"Machine type" choices:"pc|q35"`
+	Desc               string   `help:"Description" metavar:"<DESCRIPTION>" json:"description"`
+	Boot               string   `help:"Boot device" metavar:"<BOOT_DEVICE>" choices:"disk|cdrom" json:"-"`
+	EnableCloudInit    bool     `help:"Enable cloud-init service"`
+	NoAccountInit      *bool    `help:"Not reset account password"`
+	AllowDelete        *bool    `help:"Unlock server to allow deleting" json:"-"`
+	ShutdownBehavior   string   `help:"Behavior after VM server shutdown" metavar:"<SHUTDOWN_BEHAVIOR>" choices:"stop|terminate"`
+	AutoStart          bool     `help:"Auto start server after it is created"`
+	Deploy             []string `help:"Specify deploy files in virtual server file system" json:"-"`
+	Group              []string `help:"Group ID or Name of virtual server"`
+	System             bool     `help:"Create a system VM, sysadmin ONLY option" json:"is_system"`
+	TaskNotify         *bool    `help:"Setup task notify" json:"-"`
+	DryRun             *bool    `help:"Dry run to test scheduler" json:"-"`
+	UserDataFile       string   `help:"user_data file path" json:"-"`
+	MetadataHttpTokens string   `help:"whether session token is required by metadata service" choices:"optional|required" json:"-"`
+	ConfigDrive        bool     `help:"Deliver metadata through config drive ISO, KVM only" json:"-"`
+	InstanceSnapshot   string   `help:"instance snapshot" json:"instance_snapshot"`
+	Secgroups          []string `help:"secgroups" json:"secgroups"`
 
 	OsType string `help:"os type, e.g. Linux, Windows, etc."`
 
@@ -545,6 +547,8 @@ func (opts *ServerCreateOptionalOptions) OptionalParams() (*computeapi.ServerCre
 		}
 		params.UserData = string(userdata)
 	}
+	params.MetadataHttpTokens = opts.MetadataHttpTokens
+	params.ConfigDrive = opts.ConfigDrive
 
 	if options.BoolV(opts.AllowDelete) {
 		disableDelete := false
@@ -1141,6 +1145,16 @@ type ServerHaveAgentOptions struct {
 	options.BaseIdOptions
 }
 
+type ServerSetMetadataOptionsOptions struct {
+	options.BaseIdOptions
+
+	computeapi.ServerSetMetadataOptionsInput
+}
+
+func (opts *ServerSetMetadataOptionsOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts.ServerSetMetadataOptionsInput), nil
+}
+
 type ServerMigrateNetworkOptions struct {
 	options.BaseIdOptions
 
@@ -66,7 +66,7 @@ type StorageCreateOptions struct {
 	ZONE                  string `help:"Zone id of storage"`
 	Capacity              int64  `help:"Capacity of the Storage"`
 	MediumType            string `help:"Medium type" choices:"ssd|rotate" default:"ssd"`
-	StorageType           string `help:"Storage type" choices:"local|nas|vsan|rbd|nfs|gpfs|baremetal"`
+	StorageType           string `help:"Storage type" choices:"local|nas|vsan|rbd|nfs|gpfs|cifs|slvm|baremetal"`
 	RbdMonHost            string `help:"Ceph mon_host config"`
 	RbdRadosMonOpTimeout  int64  `help:"ceph rados_mon_op_timeout"`
 	RbdRadosOsdOpTimeout  int64  `help:"ceph rados_osd_op_timeout"`
@@ -75,6 +75,13 @@ type StorageCreateOptions struct {
 	RbdPool               string `help:"Ceph Pool Name"`
 	NfsHost               string `help:"NFS host"`
 	NfsSharedDir          string `help:"NFS shared dir"`
+	CifsHost              string `help:"CIFS/SMB server host"`
+	CifsSharedDir         string `help:"CIFS/SMB share name, may contain sub directory"`
+	CifsUsername          string `help:"CIFS/SMB username, mount as guest if not given"`
+	CifsPassword          string `help:"CIFS/SMB password"`
+	CifsDomain            string `help:"CIFS/SMB domain"`
+	CifsMountOptions      string `help:"Extra CIFS mount options, separated by comma, e.g. vers=3.0"`
+	SlvmVgName            string `help:"Shared LVM volume group name"`
 }
 
 func (opts *StorageCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -86,6 +93,14 @@ func (opts *StorageCreateOptions) Params() (jsonutils.JSONObject, error) {
 		if len(opts.NfsHost) == 0 || len(opts.NfsSharedDir) == 0 {
 			return nil, fmt.Errorf("Storage type nfs missing conf host or shared dir")
 		}
+	} else if opts.StorageType == "cifs" {
+		if len(opts.CifsHost) == 0 || len(opts.CifsSharedDir) == 0 {
+			return nil, fmt.Errorf("Storage type cifs missing conf host or shared dir")
+		}
+	} else if opts.StorageType == "slvm" {
+		if len(opts.SlvmVgName) == 0 {
+			return nil, fmt.Errorf("Storage type slvm missing volume group name")
+		}
 	}
 	return options.StructToParams(opts)
 }
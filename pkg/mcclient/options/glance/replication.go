@@ -0,0 +1,129 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glance
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type ImageReplicationPolicyListOptions struct {
+	options.BaseListOptions
+
+	SourceRegion string `help:"filter by source region"`
+	TargetRegion string `help:"filter by target region"`
+}
+
+func (opts *ImageReplicationPolicyListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type ImageReplicationPolicyIdOptions struct {
+	ID string `help:"ID or name of image replication policy"`
+}
+
+func (opts *ImageReplicationPolicyIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *ImageReplicationPolicyIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type ImageReplicationPolicyCreateOptions struct {
+	NAME string `help:"name of image replication policy"`
+
+	SourceRegion    string   `help:"source region, default is current region"`
+	TargetRegion    []string `help:"target regions" required:"true"`
+	Tag             string   `help:"image tags selector, key and value separated by \"=\", keyvalue pairs separated by \";\", eg: \"user:replicate=true\""`
+	IntervalMinutes int      `help:"replication interval in minutes"`
+	Disabled        bool     `help:"create the policy disabled"`
+	Desc            string   `help:"description"`
+}
+
+func (opts *ImageReplicationPolicyCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	params.Set("name", jsonutils.NewString(opts.NAME))
+	if len(opts.SourceRegion) > 0 {
+		params.Set("source_region", jsonutils.NewString(opts.SourceRegion))
+	}
+	params.Set("target_regions", jsonutils.NewStringArray(opts.TargetRegion))
+	if len(opts.Tag) > 0 {
+		params.Set("tags", jsonutils.Marshal(options.SplitTag(opts.Tag)))
+	}
+	if opts.IntervalMinutes > 0 {
+		params.Set("interval_minutes", jsonutils.NewInt(int64(opts.IntervalMinutes)))
+	}
+	params.Set("enabled", jsonutils.NewBool(!opts.Disabled))
+	if len(opts.Desc) > 0 {
+		params.Set("description", jsonutils.NewString(opts.Desc))
+	}
+	return params, nil
+}
+
+type ImageReplicationPolicyUpdateOptions struct {
+	ImageReplicationPolicyIdOptions
+
+	Name            string   `help:"new name of image replication policy"`
+	TargetRegion    []string `help:"target regions"`
+	Tag             string   `help:"image tags selector, key and value separated by \"=\", keyvalue pairs separated by \";\""`
+	IntervalMinutes int      `help:"replication interval in minutes"`
+	Desc            string   `help:"description"`
+}
+
+func (opts *ImageReplicationPolicyUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	if len(opts.Name) > 0 {
+		params.Set("name", jsonutils.NewString(opts.Name))
+	}
+	if len(opts.TargetRegion) > 0 {
+		params.Set("target_regions", jsonutils.NewStringArray(opts.TargetRegion))
+	}
+	if len(opts.Tag) > 0 {
+		params.Set("tags", jsonutils.Marshal(options.SplitTag(opts.Tag)))
+	}
+	if opts.IntervalMinutes > 0 {
+		params.Set("interval_minutes", jsonutils.NewInt(int64(opts.IntervalMinutes)))
+	}
+	if len(opts.Desc) > 0 {
+		params.Set("description", jsonutils.NewString(opts.Desc))
+	}
+	return params, nil
+}
+
+type ImageReplicaListOptions struct {
+	options.BaseListOptions
+
+	ImageId      string `help:"filter by source image"`
+	PolicyId     string `help:"filter by replication policy"`
+	TargetRegion string `help:"filter by target region"`
+}
+
+func (opts *ImageReplicaListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type ImageReplicaIdOptions struct {
+	ID string `help:"ID or name of image replica"`
+}
+
+func (opts *ImageReplicaIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *ImageReplicaIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
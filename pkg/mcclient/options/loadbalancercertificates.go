@@ -52,8 +52,14 @@ type LoadbalancerCertificateCreateOptions struct {
 
 	NAME string
 
-	Cert string `required:"true" json:"-" help:"path to certificate file"`
-	Pkey string `required:"true" json:"-" help:"path to private key file"`
+	Cert string `json:"-" help:"path to certificate file, required for upload type"`
+	Pkey string `json:"-" help:"path to private key file, required for upload type"`
+
+	CertificateType  string `choices:"upload|acme" help:"certificate type, default upload"`
+	AcmeDomains      string `help:"comma separated domains to issue acme certificate for"`
+	AcmeChallenge    string `choices:"http-01|dns-01" help:"acme challenge type, default http-01"`
+	AcmeDirectoryUrl string `help:"acme directory url, default letsencrypt"`
+	AcmeEmail        string `help:"contact email of acme account"`
 }
 
 func (opts *LoadbalancerCertificateCreateOptions) Params() (*jsonutils.JSONDict, error) {
@@ -69,6 +75,9 @@ func (opts *LoadbalancerCertificateCreateOptions) Params() (*jsonutils.JSONDict,
 
 	params.Update(sp)
 
+	if opts.CertificateType == "acme" {
+		return params, nil
+	}
 	paramsCertKey, err := loadbalancerCertificateLoadFiles(opts.Cert, opts.Pkey, false)
 	if err != nil {
 		return nil, err
@@ -92,7 +101,8 @@ type LoadbalancerCertificateListOptions struct {
 	PublicKeyBitLen    *int
 	SignatureAlgorithm string
 	Cloudregion        string
-	Usable             *bool `help:"List certificates are usable"`
+	Usable             *bool    `help:"List certificates are usable"`
+	CertificateType    []string `help:"filter by certificate type" choices:"upload|acme"`
 }
 
 type LoadbalancerCertificateUpdateOptions struct {
@@ -101,6 +111,8 @@ type LoadbalancerCertificateUpdateOptions struct {
 
 	Cert string `json:"-" help:"path to certificate file"`
 	Pkey string `json:"-" help:"path to private key file"`
+
+	AcmeEmail string `help:"contact email of acme account"`
 }
 
 func (opts *LoadbalancerCertificateUpdateOptions) Params() (*jsonutils.JSONDict, error) {
@@ -108,6 +120,9 @@ func (opts *LoadbalancerCertificateUpdateOptions) Params() (*jsonutils.JSONDict,
 	if err != nil {
 		return nil, err
 	}
+	if opts.AcmeEmail != "" {
+		paramsCertKey.Set("acme_email", jsonutils.NewString(opts.AcmeEmail))
+	}
 
 	return paramsCertKey, nil
 }
@@ -121,3 +136,7 @@ type LoadbalancerCertificatePublicOptions struct {
 type LoadbalancerCertificatePrivateOptions struct {
 	ID string `json:"-"`
 }
+
+type LoadbalancerCertificateAcmeRenewOptions struct {
+	ID string `json:"-"`
+}
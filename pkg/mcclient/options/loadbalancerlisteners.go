@@ -206,6 +206,10 @@ type LoadbalancerListenerGetBackendStatusOptions struct {
 	ID string `json:"-"`
 }
 
+type LoadbalancerListenerGetStatsOptions struct {
+	ID string `json:"-"`
+}
+
 type LoadbalancerListenerActionSyncStatusOptions struct {
 	ID string `json:"-"`
 }
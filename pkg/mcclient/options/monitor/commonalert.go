@@ -70,3 +70,13 @@ func (o *CommonAlertDeleteOptions) GetIds() []string {
 func (o *CommonAlertDeleteOptions) Params() (jsonutils.JSONObject, error) {
 	return options.StructToParams(o)
 }
+
+type CommonAlertTemplatesOptions struct{}
+
+func (o *CommonAlertTemplatesOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.NewDict(), nil
+}
+
+func (o *CommonAlertTemplatesOptions) Property() string {
+	return "templates"
+}
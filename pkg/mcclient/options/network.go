@@ -72,6 +72,13 @@ type NetworkUpdateOptions struct {
 	ExternalId  string `help:"External ID"`
 	AllocPolicy string `help:"Address allocation policy" choices:"none|stepdown|stepup|random"`
 	IsAutoAlloc *bool  `help:"Add network into auto-allocation pool" negative:"no_auto_alloc"`
+
+	StartIp6 string `help:"Start ipv6"`
+	EndIp6   string `help:"End ipv6"`
+	NetMask6 int64  `help:"IPv6 netmask"`
+	Gateway6 string `help:"IPv6 of gateway"`
+	Dns6     string `help:"IPv6 of DNS server"`
+	Ip6Mode  string `help:"IPv6 address mode" choices:"static|slaac|dhcpv6"`
 }
 
 func (opts *NetworkUpdateOptions) Params() (jsonutils.JSONObject, error) {
@@ -134,6 +141,24 @@ func (opts *NetworkUpdateOptions) Params() (jsonutils.JSONObject, error) {
 	if opts.IsAutoAlloc != nil {
 		params.Add(jsonutils.NewBool(*opts.IsAutoAlloc), "is_auto_alloc")
 	}
+	if len(opts.StartIp6) > 0 {
+		params.Add(jsonutils.NewString(opts.StartIp6), "guest_ip6_start")
+	}
+	if len(opts.EndIp6) > 0 {
+		params.Add(jsonutils.NewString(opts.EndIp6), "guest_ip6_end")
+	}
+	if opts.NetMask6 > 0 {
+		params.Add(jsonutils.NewInt(opts.NetMask6), "guest_ip6_mask")
+	}
+	if len(opts.Gateway6) > 0 {
+		params.Add(jsonutils.NewString(opts.Gateway6), "guest_gateway6")
+	}
+	if len(opts.Dns6) > 0 {
+		params.Add(jsonutils.NewString(opts.Dns6), "guest_dns6")
+	}
+	if len(opts.Ip6Mode) > 0 {
+		params.Add(jsonutils.NewString(opts.Ip6Mode), "ip6_mode")
+	}
 	if params.Size() == 0 {
 		return nil, shell.InvalidUpdateError()
 	}
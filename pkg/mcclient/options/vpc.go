@@ -112,6 +112,15 @@ func (opts *VpcStatusOptions) Params() (jsonutils.JSONObject, error) {
 	return jsonutils.Marshal(map[string]string{"status": opts.STATUS}), nil
 }
 
+type VpcPeeringConnectionAcceptOptions struct {
+	VpcIdOptions
+	PEERING string `help:"ID of the vpc peering connection requested to this vpc"`
+}
+
+func (opts *VpcPeeringConnectionAcceptOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string]string{"vpc_peering_connection_id": opts.PEERING}), nil
+}
+
 type VpcChangeOwnerOptions struct {
 	VpcIdOptions
 	ProjectDomain string `json:"project_domain" help:"target domain"`
@@ -0,0 +1,74 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbinit
+
+import (
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+)
+
+var alertTemplates []monitor.CommonAlertCreateInput
+
+// RegistryAlertTemplate registers a commonalert template which alerts when
+// reduced value of field over the period compares true with threshold, for
+// each value of the groupBy tag.  Recipients are to be filled when creating
+// alerts out of it
+func RegistryAlertTemplate(name, description, level, measurement, field, groupBy, reduce, comparator string, threshold float64) {
+	sels := []monitor.MetricQuerySelect{
+		monitor.NewMetricQuerySelect(monitor.MetricQueryPart{Type: "field", Params: []string{field}}),
+	}
+	input := monitor.CommonAlertCreateInput{
+		CommonMetricInputQuery: monitor.CommonMetricInputQuery{
+			MetricQuery: []*monitor.CommonAlertQuery{
+				{
+					AlertQuery: &monitor.AlertQuery{
+						Model: monitor.MetricQuery{
+							Database:    monitor.METRIC_DATABASE_TELE,
+							Measurement: measurement,
+							Selects:     sels,
+							GroupBy: []monitor.MetricQueryPart{
+								{Type: "tag", Params: []string{groupBy}},
+							},
+						},
+					},
+					Reduce:     reduce,
+					Comparator: comparator,
+					Threshold:  threshold,
+				},
+			},
+		},
+		Period:        "5m",
+		AlertDuration: 1,
+		AlertType:     monitor.CommonAlertNomalAlertType,
+		Description:   description,
+	}
+	input.Name = name
+	input.Level = level
+	alertTemplates = append(alertTemplates, input)
+}
+
+func GetRegistryAlertTemplates() []monitor.CommonAlertCreateInput {
+	return alertTemplates
+}
+
+func init() {
+	RegistryAlertTemplate("lb-listener-5xx", "Loadbalancer listener responds too many 5xx errors", "important",
+		"lb_listener", "hrsp_5xx_rate", "listener_id", "avg", ">", 1)
+	RegistryAlertTemplate("lb-listener-4xx", "Loadbalancer listener responds too many 4xx errors", "normal",
+		"lb_listener", "hrsp_4xx_rate", "listener_id", "avg", ">", 10)
+	RegistryAlertTemplate("lb-backend-unhealthy", "Loadbalancer backend fails health check", "important",
+		"lb_backend", "healthy", "backend_id", "last", "<", 1)
+	RegistryAlertTemplate("lb-backend-latency", "Loadbalancer backend responds slowly", "normal",
+		"lb_backend", "response_time", "backend_id", "avg", ">", 1000)
+}
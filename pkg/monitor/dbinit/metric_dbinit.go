@@ -296,6 +296,31 @@ func init() {
 			newMetricFieldCreateInput("snat_conn_count", "SNAT connection count", monitor.METRIC_UNIT_COUNT, 1),
 		})
 
+	// lb_listener
+	RegistryMetricCreateInput("lb_listener", "Loadbalancer listener", monitor.METRIC_RES_TYPE_ELB,
+		monitor.METRIC_DATABASE_TELE, 2, []monitor.MetricFieldCreateInput{
+			newMetricFieldCreateInput("conn_cur", "Current connections", monitor.METRIC_UNIT_COUNT, 1),
+			newMetricFieldCreateInput("conn_rate", "New connections per second", "count/s", 2),
+			newMetricFieldCreateInput("req_rate", "HTTP requests per second", "count/s", 3),
+			newMetricFieldCreateInput("hrsp_4xx_rate", "HTTP 4xx responses per second", "count/s", 4),
+			newMetricFieldCreateInput("hrsp_5xx_rate", "HTTP 5xx responses per second", "count/s", 5),
+			newMetricFieldCreateInput("bytes_in", "Bytes received", monitor.METRIC_UNIT_BYTE, 6),
+			newMetricFieldCreateInput("bytes_out", "Bytes sent", monitor.METRIC_UNIT_BYTE, 7),
+		})
+
+	// lb_backend
+	RegistryMetricCreateInput("lb_backend", "Loadbalancer backend", monitor.METRIC_RES_TYPE_ELB,
+		monitor.METRIC_DATABASE_TELE, 3, []monitor.MetricFieldCreateInput{
+			newMetricFieldCreateInput("healthy", "Health check passed", monitor.METRIC_UNIT_COUNT, 1),
+			newMetricFieldCreateInput("conn_cur", "Current connections", monitor.METRIC_UNIT_COUNT, 2),
+			newMetricFieldCreateInput("conn_rate", "New connections per second", "count/s", 3),
+			newMetricFieldCreateInput("hrsp_4xx_rate", "HTTP 4xx responses per second", "count/s", 4),
+			newMetricFieldCreateInput("hrsp_5xx_rate", "HTTP 5xx responses per second", "count/s", 5),
+			newMetricFieldCreateInput("response_time", "Average response time", monitor.METRIC_UNIT_MS, 6),
+			newMetricFieldCreateInput("queue_time", "Average queue time", monitor.METRIC_UNIT_MS, 7),
+			newMetricFieldCreateInput("connect_time", "Average connect time", monitor.METRIC_UNIT_MS, 8),
+		})
+
 	// agent_cpu
 	RegistryMetricCreateInput("agent_cpu", "CPU usage", monitor.METRIC_RES_TYPE_AGENT, monitor.METRIC_DATABASE_TELE, 1,
 		[]monitor.MetricFieldCreateInput{
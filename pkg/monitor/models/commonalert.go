@@ -42,6 +42,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/notify"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/yunionconf"
+	"yunion.io/x/onecloud/pkg/monitor/dbinit"
 	merrors "yunion.io/x/onecloud/pkg/monitor/errors"
 	"yunion.io/x/onecloud/pkg/monitor/options"
 	"yunion.io/x/onecloud/pkg/monitor/validators"
@@ -225,6 +226,13 @@ func (man *SCommonAlertManager) ValidateCreateData(
 
 }
 
+// GetPropertyTemplates returns default commonalert templates, e.g. those for
+// loadbalancer listener and backend stats
+func (man *SCommonAlertManager) GetPropertyTemplates(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(dbinit.GetRegistryAlertTemplates()), nil
+}
+
 func (man *SCommonAlertManager) genName(ctx context.Context, ownerId mcclient.IIdentityProvider, name string) (string,
 	error) {
 	lockman.LockRawObject(ctx, man.Keyword(), "name")
@@ -0,0 +1,52 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"fmt"
+)
+
+type SClusterCpu struct {
+	Architecture string `json:"architecture"`
+	Type         string `json:"type"`
+}
+
+// SCluster is the group of hosts of a data center, the vms can be migrated between the hosts of cluster
+type SCluster struct {
+	Id          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Cpu         SClusterCpu `json:"cpu"`
+	DataCenter  SIdRef      `json:"data_center"`
+	BiosType    string      `json:"bios_type"`
+}
+
+func (self *SRegion) GetClusters() ([]SCluster, error) {
+	clusters := []SCluster{}
+	err := self.list("clusters", nil, "cluster", &clusters)
+	if err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+func (self *SRegion) GetCluster(id string) (*SCluster, error) {
+	cluster := &SCluster{}
+	err := self.get(fmt.Sprintf("clusters/%s", id), nil, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
@@ -0,0 +1,305 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+const (
+	DISK_STATUS_OK      = "ok"
+	DISK_STATUS_LOCKED  = "locked"
+	DISK_STATUS_ILLEGAL = "illegal"
+
+	DISK_FORMAT_COW = "cow"
+	DISK_FORMAT_RAW = "raw"
+
+	DISK_INTERFACE_VIRTIO_SCSI = "virtio_scsi"
+	DISK_INTERFACE_VIRTIO      = "virtio"
+	DISK_INTERFACE_IDE         = "ide"
+	DISK_INTERFACE_SATA        = "sata"
+)
+
+type SStorageDomainRefs struct {
+	StorageDomain []SIdRef `json:"storage_domain"`
+}
+
+// SDiskAttachment is the attachment of the disk to the vm, the id of attachment is the id of disk
+type SDiskAttachment struct {
+	Id          string `json:"id"`
+	Active      bool   `json:"active"`
+	Bootable    bool   `json:"bootable"`
+	Interface   string `json:"interface"`
+	LogicalName string `json:"logical_name"`
+	Disk        SIdRef `json:"disk"`
+	Vm          SIdRef `json:"vm"`
+}
+
+type SDisk struct {
+	multicloud.SDisk
+	multicloud.STagBase
+
+	storage    *SStorage
+	attachment *SDiskAttachment
+
+	Id              string             `json:"id"`
+	Name            string             `json:"name"`
+	Alias           string             `json:"alias"`
+	Description     string             `json:"description"`
+	ProvisionedSize int64              `json:"provisioned_size"`
+	ActualSize      int64              `json:"actual_size"`
+	Format          string             `json:"format"`
+	Status          string             `json:"status"`
+	Sparse          bool               `json:"sparse"`
+	Shareable       bool               `json:"shareable"`
+	StorageType     string             `json:"storage_type"`
+	ContentType     string             `json:"content_type"`
+	StorageDomains  SStorageDomainRefs `json:"storage_domains"`
+}
+
+func (self *SDisk) GetName() string {
+	if len(self.Name) > 0 {
+		return self.Name
+	}
+	return self.Alias
+}
+
+func (self *SDisk) GetId() string {
+	return self.Id
+}
+
+func (self *SDisk) GetGlobalId() string {
+	return self.Id
+}
+
+func (self *SDisk) getStorageId() string {
+	if len(self.StorageDomains.StorageDomain) > 0 {
+		return self.StorageDomains.StorageDomain[0].Id
+	}
+	return ""
+}
+
+func (self *SDisk) getRegion() *SRegion {
+	return self.storage.zone.region
+}
+
+func (self *SDisk) CreateISnapshot(ctx context.Context, name, desc string) (cloudprovider.ICloudSnapshot, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) Delete(ctx context.Context) error {
+	return self.getRegion().DeleteDisk(self.Id)
+}
+
+func (self *SDisk) GetAccessPath() string {
+	return ""
+}
+
+func (self *SDisk) GetCacheMode() string {
+	return "none"
+}
+
+func (self *SDisk) GetFsFormat() string {
+	return ""
+}
+
+func (self *SDisk) GetIsNonPersistent() bool {
+	return false
+}
+
+func (self *SDisk) GetDriver() string {
+	if self.attachment == nil {
+		return "scsi"
+	}
+	switch self.attachment.Interface {
+	case DISK_INTERFACE_VIRTIO:
+		return "virtio"
+	case DISK_INTERFACE_IDE:
+		return "ide"
+	case DISK_INTERFACE_SATA:
+		return "sata"
+	}
+	return "scsi"
+}
+
+func (self *SDisk) GetDiskType() string {
+	if self.attachment != nil && self.attachment.Bootable {
+		return api.DISK_TYPE_SYS
+	}
+	return api.DISK_TYPE_DATA
+}
+
+// GetDiskFormat returns qcow2 for the cow disk, which is the thin provisioned disk of oVirt
+func (self *SDisk) GetDiskFormat() string {
+	if self.Format == DISK_FORMAT_COW {
+		return "qcow2"
+	}
+	return "raw"
+}
+
+func (self *SDisk) GetDiskSizeMB() int {
+	return int(self.ProvisionedSize / 1024 / 1024)
+}
+
+func (self *SDisk) GetIsAutoDelete() bool {
+	return true
+}
+
+func (self *SDisk) GetMountpoint() string {
+	if self.attachment != nil {
+		return self.attachment.LogicalName
+	}
+	return ""
+}
+
+func (self *SDisk) GetStatus() string {
+	switch self.Status {
+	case DISK_STATUS_OK:
+		return api.DISK_READY
+	case DISK_STATUS_LOCKED:
+		return api.DISK_ALLOCATING
+	case DISK_STATUS_ILLEGAL:
+		return api.DISK_ALLOC_FAILED
+	}
+	return api.DISK_UNKNOWN
+}
+
+func (self *SDisk) Refresh() error {
+	disk, err := self.getRegion().GetDisk(self.Id)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, disk)
+}
+
+func (self *SDisk) Rebuild(ctx context.Context) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) Reset(ctx context.Context, snapshotId string) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) Resize(ctx context.Context, sizeMb int64) error {
+	return self.getRegion().ResizeDisk(self.Id, sizeMb)
+}
+
+func (self *SDisk) GetTemplateId() string {
+	return ""
+}
+
+func (self *SDisk) GetIStorage() (cloudprovider.ICloudStorage, error) {
+	return self.storage, nil
+}
+
+func (self *SDisk) GetISnapshot(snapshotId string) (cloudprovider.ICloudSnapshot, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) GetISnapshots() ([]cloudprovider.ICloudSnapshot, error) {
+	return []cloudprovider.ICloudSnapshot{}, nil
+}
+
+func (self *SRegion) GetDisks(storageId string) ([]SDisk, error) {
+	disks := []SDisk{}
+	err := self.list(fmt.Sprintf("storagedomains/%s/disks", storageId), nil, "disk", &disks)
+	if err != nil {
+		return nil, err
+	}
+	return disks, nil
+}
+
+func (self *SRegion) GetDisk(id string) (*SDisk, error) {
+	disk := &SDisk{}
+	err := self.get(fmt.Sprintf("disks/%s", id), nil, disk)
+	if err != nil {
+		return nil, err
+	}
+	return disk, nil
+}
+
+// getDiskAttachments returns the disk attachments of all vms, which is keyed by the disk id
+func (self *SRegion) getDiskAttachments() (map[string]SDiskAttachment, error) {
+	vms := []struct {
+		Id              string `json:"id"`
+		DiskAttachments struct {
+			DiskAttachment []SDiskAttachment `json:"disk_attachment"`
+		} `json:"disk_attachments"`
+	}{}
+	params := url.Values{}
+	params.Set("follow", "disk_attachments")
+	err := self.list("vms", params, "vm", &vms)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list vms")
+	}
+	ret := map[string]SDiskAttachment{}
+	for _, vm := range vms {
+		for _, attachment := range vm.DiskAttachments.DiskAttachment {
+			attachment.Vm.Id = vm.Id
+			ret[attachment.Id] = attachment
+		}
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetDiskAttachments(vmId string) ([]SDiskAttachment, error) {
+	attachments := []SDiskAttachment{}
+	err := self.list(fmt.Sprintf("vms/%s/diskattachments", vmId), nil, "disk_attachment", &attachments)
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// waitDiskReady waits the disk unlocked, the disk is locked while it is being created, copied or resized
+func (self *SRegion) waitDiskReady(id string) error {
+	return cloudprovider.Wait(time.Second*5, time.Minute*20, func() (bool, error) {
+		disk, err := self.GetDisk(id)
+		if err != nil {
+			return false, errors.Wrapf(err, "GetDisk(%s)", id)
+		}
+		switch disk.Status {
+		case DISK_STATUS_OK:
+			return true, nil
+		case DISK_STATUS_ILLEGAL:
+			return false, errors.Errorf("disk %s is illegal", id)
+		}
+		return false, nil
+	})
+}
+
+// ResizeDisk grows the disk to sizeMb, the disk can not be shrunk
+func (self *SRegion) ResizeDisk(id string, sizeMb int64) error {
+	body := jsonutils.Marshal(map[string]int64{"provisioned_size": sizeMb * 1024 * 1024})
+	err := self.put(fmt.Sprintf("disks/%s", id), body, nil)
+	if err != nil {
+		return err
+	}
+	return self.waitDiskReady(id)
+}
+
+func (self *SRegion) DeleteDisk(id string) error {
+	return self.delete(fmt.Sprintf("disks/%s", id), nil)
+}
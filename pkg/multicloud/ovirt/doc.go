@@ -0,0 +1 @@
+package ovirt // import "yunion.io/x/onecloud/pkg/multicloud/ovirt"
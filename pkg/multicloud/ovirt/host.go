@@ -0,0 +1,272 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+const (
+	HOST_STATUS_UP          = "up"
+	HOST_STATUS_MAINTENANCE = "maintenance"
+)
+
+type STopology struct {
+	Cores   int `json:"cores"`
+	Sockets int `json:"sockets"`
+	Threads int `json:"threads"`
+}
+
+type SHostCpu struct {
+	Name     string    `json:"name"`
+	Speed    int       `json:"speed"`
+	Type     string    `json:"type"`
+	Topology STopology `json:"topology"`
+}
+
+type SHardwareInformation struct {
+	Manufacturer string `json:"manufacturer"`
+	ProductName  string `json:"product_name"`
+	SerialNumber string `json:"serial_number"`
+	Uuid         string `json:"uuid"`
+	Version      string `json:"version"`
+}
+
+type SHost struct {
+	multicloud.STagBase
+	multicloud.SHostBase
+
+	zone *SZone
+
+	Id                  string               `json:"id"`
+	Name                string               `json:"name"`
+	Address             string               `json:"address"`
+	Status              string               `json:"status"`
+	Type                string               `json:"type"`
+	Memory              int64                `json:"memory"`
+	Cpu                 SHostCpu             `json:"cpu"`
+	Cluster             SIdRef               `json:"cluster"`
+	HardwareInformation SHardwareInformation `json:"hardware_information"`
+	Version             SVersion             `json:"version"`
+}
+
+// GetHosts returns the hosts of the clusters of the data center, all hosts are returned if dcId is empty
+func (self *SRegion) GetHosts(dcId string) ([]SHost, error) {
+	hosts := []SHost{}
+	err := self.list("hosts", nil, "host", &hosts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list hosts")
+	}
+	if len(dcId) == 0 {
+		return hosts, nil
+	}
+	clusters, err := self.GetClusters()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetClusters")
+	}
+	clusterIds := []string{}
+	for i := range clusters {
+		if clusters[i].DataCenter.Id == dcId {
+			clusterIds = append(clusterIds, clusters[i].Id)
+		}
+	}
+	ret := []SHost{}
+	for i := range hosts {
+		if utils.IsInStringArray(hosts[i].Cluster.Id, clusterIds) {
+			ret = append(ret, hosts[i])
+		}
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetHost(id string) (*SHost, error) {
+	host := &SHost{}
+	err := self.get(fmt.Sprintf("hosts/%s", id), nil, host)
+	if err != nil {
+		return nil, err
+	}
+	return host, nil
+}
+
+func (self *SHost) GetName() string {
+	return self.Name
+}
+
+func (self *SHost) GetId() string {
+	return self.Id
+}
+
+func (self *SHost) GetGlobalId() string {
+	return self.Id
+}
+
+func (self *SHost) CreateVM(opts *cloudprovider.SManagedVMCreateConfig) (cloudprovider.ICloudVM, error) {
+	vm, err := self.zone.region.CreateInstance(self.Id, opts)
+	if err != nil {
+		return nil, err
+	}
+	vm.host = self
+	return vm, nil
+}
+
+func (self *SHost) GetAccessIp() string {
+	return self.Address
+}
+
+func (self *SHost) GetAccessMac() string {
+	return ""
+}
+
+func (self *SHost) GetCpuCmtbound() float32 {
+	return 8.0
+}
+
+func (self *SHost) GetMemCmtbound() float32 {
+	return 1.0
+}
+
+func (self *SHost) GetCpuCount() int {
+	topology := self.Cpu.Topology
+	if topology.Threads <= 0 {
+		topology.Threads = 1
+	}
+	return topology.Sockets * topology.Cores * topology.Threads
+}
+
+func (self *SHost) GetNodeCount() int8 {
+	return int8(self.Cpu.Topology.Sockets)
+}
+
+func (self *SHost) GetEnabled() bool {
+	return self.Status != HOST_STATUS_MAINTENANCE
+}
+
+func (self *SHost) GetCpuDesc() string {
+	return self.Cpu.Name
+}
+
+func (self *SHost) GetCpuMhz() int {
+	return self.Cpu.Speed
+}
+
+func (self *SHost) GetMemSizeMB() int {
+	return int(self.Memory / 1024 / 1024)
+}
+
+func (self *SHost) GetStorageSizeMB() int {
+	return 0
+}
+
+func (self *SHost) GetStorageType() string {
+	return api.DISK_TYPE_HYBRID
+}
+
+func (self *SHost) GetHostType() string {
+	return api.HOST_TYPE_OVIRT
+}
+
+func (self *SHost) GetHostStatus() string {
+	if self.Status == HOST_STATUS_UP {
+		return api.HOST_ONLINE
+	}
+	return api.HOST_OFFLINE
+}
+
+func (self *SHost) GetIHostNics() ([]cloudprovider.ICloudHostNetInterface, error) {
+	return []cloudprovider.ICloudHostNetInterface{}, nil
+}
+
+func (self *SHost) GetIsMaintenance() bool {
+	return self.Status == HOST_STATUS_MAINTENANCE
+}
+
+// GetVersion returns the version of vdsm, e.g. vdsm-4.50.3.4-1.el8
+func (self *SHost) GetVersion() string {
+	return self.Version.FullVersion
+}
+
+func (self *SHost) GetStatus() string {
+	if self.Status == HOST_STATUS_UP {
+		return api.HOST_STATUS_RUNNING
+	}
+	return api.HOST_STATUS_UNKNOWN
+}
+
+func (self *SHost) GetSN() string {
+	return self.HardwareInformation.SerialNumber
+}
+
+func (self *SHost) GetSysInfo() jsonutils.JSONObject {
+	info := jsonutils.NewDict()
+	info.Add(jsonutils.NewString(CLOUD_PROVIDER_OVIRT), "manufacture")
+	if len(self.HardwareInformation.ProductName) > 0 {
+		info.Add(jsonutils.NewString(self.HardwareInformation.ProductName), "model")
+	}
+	return info
+}
+
+func (self *SHost) IsEmulated() bool {
+	return false
+}
+
+func (self *SHost) Refresh() error {
+	host, err := self.zone.region.GetHost(self.Id)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, host)
+}
+
+func (self *SHost) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	return self.zone.GetIStorages()
+}
+
+func (self *SHost) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	return self.zone.GetIStorageById(id)
+}
+
+func (self *SHost) GetIVMs() ([]cloudprovider.ICloudVM, error) {
+	vms, err := self.zone.region.GetInstances(self.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetInstances")
+	}
+	ret := []cloudprovider.ICloudVM{}
+	for i := range vms {
+		vms[i].host = self
+		ret = append(ret, &vms[i])
+	}
+	return ret, nil
+}
+
+func (self *SHost) GetIVMById(id string) (cloudprovider.ICloudVM, error) {
+	vm, err := self.zone.region.GetInstance(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetInstance")
+	}
+	vm.host = self
+	return vm, nil
+}
+
+func (self *SHost) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	return self.zone.getVpc().GetIWires()
+}
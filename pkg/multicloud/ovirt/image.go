@@ -0,0 +1,248 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+	"yunion.io/x/onecloud/pkg/util/imagetools"
+)
+
+const (
+	// BLANK_TEMPLATE_ID is the id of the builtin Blank template, which has no disk
+	BLANK_TEMPLATE_ID = "00000000-0000-0000-0000-000000000000"
+
+	TEMPLATE_STATUS_OK     = "ok"
+	TEMPLATE_STATUS_LOCKED = "locked"
+)
+
+type SOs struct {
+	Type string `json:"type"`
+}
+
+type SBios struct {
+	Type string `json:"type"`
+}
+
+type SCpu struct {
+	Architecture string    `json:"architecture"`
+	Topology     STopology `json:"topology"`
+}
+
+// SImage is the vm template of oVirt, the vm is created from the template
+type SImage struct {
+	multicloud.STagBase
+	multicloud.SImageBase
+
+	cache *SStoragecache
+
+	// disks is the disks of template, which is loaded on demand
+	disks []SDisk
+
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Status       string `json:"status"`
+	Memory       int64  `json:"memory"`
+	Cpu          SCpu   `json:"cpu"`
+	Os           SOs    `json:"os"`
+	Bios         SBios  `json:"bios"`
+	CreationTime int64  `json:"creation_time"`
+	Cluster      SIdRef `json:"cluster"`
+}
+
+func (self *SImage) GetName() string {
+	return self.Name
+}
+
+func (self *SImage) GetId() string {
+	return self.Id
+}
+
+func (self *SImage) GetGlobalId() string {
+	return self.Id
+}
+
+func (self *SImage) Refresh() error {
+	image, err := self.cache.region.GetImage(self.Id)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, image)
+}
+
+func (self *SImage) GetIStoragecache() cloudprovider.ICloudStoragecache {
+	return self.cache
+}
+
+func (self *SImage) getDisks() []SDisk {
+	if self.disks == nil {
+		disks, err := self.cache.region.GetTemplateDisks(self.Id)
+		if err != nil {
+			log.Errorf("GetTemplateDisks(%s) error: %v", self.Id, err)
+			return []SDisk{}
+		}
+		self.disks = disks
+	}
+	return self.disks
+}
+
+func (self *SImage) GetImageFormat() string {
+	for _, disk := range self.getDisks() {
+		return disk.GetDiskFormat()
+	}
+	return "raw"
+}
+
+func (self *SImage) GetStatus() string {
+	switch self.Status {
+	case TEMPLATE_STATUS_OK:
+		return api.CACHED_IMAGE_STATUS_ACTIVE
+	case TEMPLATE_STATUS_LOCKED:
+		return api.CACHED_IMAGE_STATUS_SAVING
+	}
+	return api.CACHED_IMAGE_STATUS_CACHE_FAILED
+}
+
+func (self *SImage) GetImageStatus() string {
+	switch self.Status {
+	case TEMPLATE_STATUS_OK:
+		return cloudprovider.IMAGE_STATUS_ACTIVE
+	case TEMPLATE_STATUS_LOCKED:
+		return cloudprovider.IMAGE_STATUS_SAVING
+	}
+	return cloudprovider.IMAGE_STATUS_KILLED
+}
+
+func (self *SImage) GetImageType() cloudprovider.TImageType {
+	return cloudprovider.ImageTypeCustomized
+}
+
+func (self *SImage) GetCreatedAt() time.Time {
+	if self.CreationTime > 0 {
+		return time.Unix(self.CreationTime/1000, 0)
+	}
+	return time.Time{}
+}
+
+func (self *SImage) Delete(ctx context.Context) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SImage) GetSizeByte() int64 {
+	size := int64(0)
+	for _, disk := range self.getDisks() {
+		size += disk.ProvisionedSize
+	}
+	return size
+}
+
+func (self *SImage) GetMinOsDiskSizeGb() int {
+	for _, disk := range self.getDisks() {
+		if disk.attachment != nil && disk.attachment.Bootable {
+			return int(disk.ProvisionedSize / 1024 / 1024 / 1024)
+		}
+	}
+	return int(self.GetSizeByte() / 1024 / 1024 / 1024)
+}
+
+// GetOsType returns the os type by the os of template, e.g. rhel_8x64, windows_2019x64
+func (self *SImage) GetOsType() cloudprovider.TOsType {
+	if strings.HasPrefix(self.Os.Type, "windows") {
+		return cloudprovider.OsTypeWindows
+	}
+	return cloudprovider.OsTypeLinux
+}
+
+func (self *SImage) GetOsDist() string {
+	return imagetools.NormalizeImageInfo(self.Name, self.GetOsArch(), string(self.GetOsType()), "", "").OsDistro
+}
+
+func (self *SImage) GetOsVersion() string {
+	return imagetools.NormalizeImageInfo(self.Name, self.GetOsArch(), string(self.GetOsType()), "", "").OsVersion
+}
+
+func (self *SImage) UEFI() bool {
+	return strings.HasSuffix(self.Bios.Type, "ovmf")
+}
+
+func (self *SImage) GetOsArch() string {
+	if self.Cpu.Architecture == "aarch64" {
+		return "aarch64"
+	}
+	return "x86_64"
+}
+
+func (self *SImage) GetMinRamSizeMb() int {
+	return 0
+}
+
+// GetImages returns the templates of oVirt, the Blank template is skipped
+func (self *SRegion) GetImages() ([]SImage, error) {
+	images := []SImage{}
+	err := self.list("templates", nil, "template", &images)
+	if err != nil {
+		return nil, err
+	}
+	ret := []SImage{}
+	for i := range images {
+		if images[i].Id == BLANK_TEMPLATE_ID {
+			continue
+		}
+		ret = append(ret, images[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetImage(id string) (*SImage, error) {
+	if id == BLANK_TEMPLATE_ID {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "template %s", id)
+	}
+	image := &SImage{}
+	err := self.get(fmt.Sprintf("templates/%s", id), nil, image)
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// GetTemplateDisks returns the disks of template, the bootable disk is the system disk
+func (self *SRegion) GetTemplateDisks(id string) ([]SDisk, error) {
+	attachments := []SDiskAttachment{}
+	err := self.list(fmt.Sprintf("templates/%s/diskattachments", id), nil, "disk_attachment", &attachments)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list disk attachments")
+	}
+	ret := []SDisk{}
+	for i := range attachments {
+		disk, err := self.GetDisk(attachments[i].Id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetDisk(%s)", attachments[i].Id)
+		}
+		disk.attachment = &attachments[i]
+		ret = append(ret, *disk)
+	}
+	return ret, nil
+}
@@ -0,0 +1,747 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/apis/webconsole"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+const (
+	VM_STATUS_UP              = "up"
+	VM_STATUS_DOWN            = "down"
+	VM_STATUS_POWERING_UP     = "powering_up"
+	VM_STATUS_WAIT_FOR_LAUNCH = "wait_for_launch"
+	VM_STATUS_REBOOTING       = "reboot_in_progress"
+	VM_STATUS_POWERING_DOWN   = "powering_down"
+	VM_STATUS_MIGRATING       = "migrating"
+	VM_STATUS_SUSPENDED       = "suspended"
+	VM_STATUS_PAUSED          = "paused"
+	VM_STATUS_SAVING_STATE    = "saving_state"
+	VM_STATUS_RESTORING_STATE = "restoring_state"
+	VM_STATUS_IMAGE_LOCKED    = "image_locked"
+
+	GRAPHICS_PROTOCOL_VNC = "vnc"
+
+	// MEMORY_HOTPLUG_UNIT_MB is the default HotPlugMemoryMultiplicationSizeMb of engine,
+	// the memory of running vm is increased by the multiples of it
+	MEMORY_HOTPLUG_UNIT_MB = 256
+)
+
+type SHostRefs struct {
+	Host []SIdRef `json:"host"`
+}
+
+type SMemoryPolicy struct {
+	Guaranteed int64 `json:"guaranteed"`
+	Max        int64 `json:"max"`
+}
+
+type SPlacementPolicy struct {
+	Affinity string    `json:"affinity"`
+	Hosts    SHostRefs `json:"hosts"`
+}
+
+type SInstance struct {
+	multicloud.STagBase
+	multicloud.SInstanceBase
+
+	host *SHost
+
+	Id              string           `json:"id"`
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+	Status          string           `json:"status"`
+	Type            string           `json:"type"`
+	Memory          int64            `json:"memory"`
+	MemoryPolicy    SMemoryPolicy    `json:"memory_policy"`
+	Cpu             SCpu             `json:"cpu"`
+	Os              SOs              `json:"os"`
+	Bios            SBios            `json:"bios"`
+	Host            SIdRef           `json:"host"`
+	Cluster         SIdRef           `json:"cluster"`
+	Template        SIdRef           `json:"template"`
+	PlacementPolicy SPlacementPolicy `json:"placement_policy"`
+	CreationTime    int64            `json:"creation_time"`
+	Fqdn            string           `json:"fqdn"`
+}
+
+// selectHost returns the host of vm, the vm which is not running is placed on
+// the preferred host of placement policy, or the first host of the cluster
+func (self *SInstance) selectHost(hosts []SHost) string {
+	if len(self.Host.Id) > 0 {
+		return self.Host.Id
+	}
+	for _, ref := range self.PlacementPolicy.Hosts.Host {
+		for i := range hosts {
+			if hosts[i].Id == ref.Id {
+				return ref.Id
+			}
+		}
+	}
+	hostId := ""
+	for i := range hosts {
+		if hosts[i].Cluster.Id != self.Cluster.Id {
+			continue
+		}
+		if hosts[i].Status == HOST_STATUS_UP {
+			return hosts[i].Id
+		}
+		if len(hostId) == 0 {
+			hostId = hosts[i].Id
+		}
+	}
+	return hostId
+}
+
+func (self *SRegion) getInstanceHostId(vm *SInstance) (string, error) {
+	hosts, err := self.GetHosts("")
+	if err != nil {
+		return "", errors.Wrapf(err, "GetHosts")
+	}
+	hostId := vm.selectHost(hosts)
+	if len(hostId) == 0 {
+		return "", errors.Wrapf(cloudprovider.ErrNotFound, "no host for vm %s of cluster %s", vm.Id, vm.Cluster.Id)
+	}
+	return hostId, nil
+}
+
+// GetInstances returns the vms of host, all vms are returned if hostId is empty
+func (self *SRegion) GetInstances(hostId string) ([]SInstance, error) {
+	vms := []SInstance{}
+	err := self.list("vms", nil, "vm", &vms)
+	if err != nil {
+		return nil, err
+	}
+	if len(hostId) == 0 {
+		return vms, nil
+	}
+	hosts, err := self.GetHosts("")
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetHosts")
+	}
+	ret := []SInstance{}
+	for i := range vms {
+		if vms[i].selectHost(hosts) == hostId {
+			ret = append(ret, vms[i])
+		}
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetInstance(id string) (*SInstance, error) {
+	vm := &SInstance{}
+	err := self.get(fmt.Sprintf("vms/%s", id), nil, vm)
+	if err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+func (self *SInstance) GetName() string {
+	return self.Name
+}
+
+func (self *SInstance) GetId() string {
+	return self.Id
+}
+
+func (self *SInstance) GetGlobalId() string {
+	return self.Id
+}
+
+func (self *SInstance) getRegion() *SRegion {
+	return self.host.zone.region
+}
+
+func (self *SInstance) getResource(res string) string {
+	if len(res) == 0 {
+		return fmt.Sprintf("vms/%s", self.Id)
+	}
+	return fmt.Sprintf("vms/%s/%s", self.Id, res)
+}
+
+func (self *SInstance) Refresh() error {
+	ins, err := self.getRegion().GetInstance(self.Id)
+	if err != nil {
+		return err
+	}
+	// the host is removed from the vm after it is stopped
+	self.Host = SIdRef{}
+	return jsonutils.Update(self, ins)
+}
+
+func (self *SInstance) AssignSecurityGroup(id string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func getDiskInterface(driver string) string {
+	switch driver {
+	case "virtio":
+		return DISK_INTERFACE_VIRTIO
+	case "ide":
+		return DISK_INTERFACE_IDE
+	case "sata":
+		return DISK_INTERFACE_SATA
+	}
+	return DISK_INTERFACE_VIRTIO_SCSI
+}
+
+func (self *SInstance) CreateDisk(ctx context.Context, opts *cloudprovider.GuestDiskCreateOptions) (string, error) {
+	return self.getRegion().CreateDisk(self.Id, opts.StorageId, int64(opts.SizeMb), getDiskInterface(opts.Driver))
+}
+
+func (self *SInstance) AttachDisk(ctx context.Context, diskId string) error {
+	body := jsonutils.Marshal(map[string]interface{}{
+		"active":    true,
+		"bootable":  false,
+		"interface": DISK_INTERFACE_VIRTIO_SCSI,
+		"disk":      map[string]string{"id": diskId},
+	})
+	return self.getRegion().post(self.getResource("diskattachments"), nil, body, nil)
+}
+
+// DetachDisk detaches the disk from vm and keeps the disk in the storage domain
+func (self *SInstance) DetachDisk(ctx context.Context, diskId string) error {
+	params := url.Values{}
+	params.Set("detach_only", "true")
+	err := self.getRegion().delete(self.getResource(fmt.Sprintf("diskattachments/%s", diskId)), params)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// ChangeConfig hot plugs the cpu sockets and memory of the running vm, the cores and threads
+// of socket are kept, and the memory can only be increased up to the max memory of vm
+func (self *SInstance) ChangeConfig(ctx context.Context, opts *cloudprovider.SManagedVMChangeConfig) error {
+	running := self.Status == VM_STATUS_UP
+	params := map[string]interface{}{}
+	if opts.Cpu > 0 && opts.Cpu != self.GetVcpuCount() {
+		topology := STopology{Cores: opts.Cpu, Sockets: 1, Threads: 1}
+		if running {
+			topology = self.Cpu.Topology
+			if topology.Threads <= 0 {
+				topology.Threads = 1
+			}
+			socketCpu := topology.Cores * topology.Threads
+			if socketCpu <= 0 || opts.Cpu%socketCpu != 0 {
+				return errors.Wrapf(cloudprovider.ErrNotSupported, "hot plug %d cpus to the vm with %d cpus per socket", opts.Cpu, socketCpu)
+			}
+			topology.Sockets = opts.Cpu / socketCpu
+		}
+		params["cpu"] = map[string]interface{}{"topology": topology}
+	}
+	if opts.MemoryMB > 0 && opts.MemoryMB != self.GetVmemSizeMB() {
+		memory := int64(opts.MemoryMB) * 1024 * 1024
+		if running {
+			if memory < self.Memory {
+				return errors.Wrapf(cloudprovider.ErrNotSupported, "reduce memory of running vm")
+			}
+			if (memory-self.Memory)%(MEMORY_HOTPLUG_UNIT_MB*1024*1024) != 0 {
+				return errors.Wrapf(cloudprovider.ErrNotSupported, "hot plug memory by the multiples of %dMB", MEMORY_HOTPLUG_UNIT_MB)
+			}
+			if self.MemoryPolicy.Max > 0 && memory > self.MemoryPolicy.Max {
+				return errors.Wrapf(cloudprovider.ErrNotSupported, "hot plug memory exceeds the max memory %dMB", self.MemoryPolicy.Max/1024/1024)
+			}
+			params["memory"] = memory
+		} else {
+			params["memory"] = memory
+			params["memory_policy"] = map[string]int64{"guaranteed": memory, "max": memory * 4}
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return self.getRegion().put(self.getResource(""), jsonutils.Marshal(params), nil)
+}
+
+func (self *SInstance) DeleteVM(ctx context.Context) error {
+	return self.getRegion().DeleteVM(self.Id)
+}
+
+// DeployVM updates the name and description of vm, the password and ssh keys
+// can be set only by cloud-init when the vm is started at the first time
+func (self *SInstance) DeployVM(ctx context.Context, name string, username string, password string, publicKey string, deleteKeypair bool, description string) error {
+	if len(password) > 0 || len(publicKey) > 0 || deleteKeypair {
+		return errors.Wrapf(cloudprovider.ErrNotSupported, "reset password or keypair")
+	}
+	params := map[string]string{}
+	if len(name) > 0 {
+		params["name"] = name
+	}
+	if len(description) > 0 {
+		params["description"] = description
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return self.getRegion().put(self.getResource(""), jsonutils.Marshal(params), nil)
+}
+
+func (self *SInstance) GetBios() string {
+	if strings.HasSuffix(self.Bios.Type, "ovmf") {
+		return "UEFI"
+	}
+	return "BIOS"
+}
+
+func (self *SInstance) GetBootOrder() string {
+	return "dcn"
+}
+
+func (self *SInstance) GetError() error {
+	return nil
+}
+
+func (self *SInstance) GetHostname() string {
+	if len(self.Fqdn) > 0 {
+		return strings.Split(self.Fqdn, ".")[0]
+	}
+	return self.Name
+}
+
+func (self *SInstance) GetHypervisor() string {
+	return api.HYPERVISOR_OVIRT
+}
+
+func (self *SInstance) GetIDisks() ([]cloudprovider.ICloudDisk, error) {
+	attachments, err := self.getRegion().GetDiskAttachments(self.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetDiskAttachments")
+	}
+	// the system disk is the first disk
+	sort.SliceStable(attachments, func(i, j int) bool {
+		return attachments[i].Bootable && !attachments[j].Bootable
+	})
+	storages := map[string]*SStorage{}
+	ret := []cloudprovider.ICloudDisk{}
+	for i := range attachments {
+		disk, err := self.getRegion().GetDisk(attachments[i].Id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetDisk(%s)", attachments[i].Id)
+		}
+		disk.attachment = &attachments[i]
+		storageId := disk.getStorageId()
+		if _, ok := storages[storageId]; !ok {
+			istorage, err := self.host.zone.GetIStorageById(storageId)
+			if err != nil {
+				log.Errorf("can not found storage %s of disk %s: %v", storageId, disk.Id, err)
+				continue
+			}
+			storages[storageId] = istorage.(*SStorage)
+		}
+		disk.storage = storages[storageId]
+		ret = append(ret, disk)
+	}
+	return ret, nil
+}
+
+func (self *SInstance) GetIEIP() (cloudprovider.ICloudEIP, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) GetIHost() cloudprovider.ICloudHost {
+	return self.host
+}
+
+func (self *SInstance) GetINics() ([]cloudprovider.ICloudNic, error) {
+	nics, err := self.getRegion().GetInstanceNics(self.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetInstanceNics")
+	}
+	addrs := map[string][]string{}
+	if self.Status == VM_STATUS_UP {
+		devices, err := self.getRegion().GetReportedDevices(self.Id)
+		if err != nil {
+			log.Warningf("GetReportedDevices(%s) error: %v", self.Id, err)
+		}
+		for _, device := range devices {
+			mac := strings.ToLower(device.Mac.Address)
+			for _, ip := range device.Ips.Ip {
+				if ip.Version == IP_VERSION_V4 {
+					addrs[mac] = append(addrs[mac], ip.Address)
+				}
+			}
+		}
+	}
+	ret := []cloudprovider.ICloudNic{}
+	for i := range nics {
+		nics[i].ins = self
+		nics[i].IpAddresses = addrs[nics[i].GetMAC()]
+		ret = append(ret, &nics[i])
+	}
+	return ret, nil
+}
+
+func (self *SInstance) GetInstanceType() string {
+	return fmt.Sprintf("ecs.g1.c%dm%d", self.GetVcpuCount(), self.GetVmemSizeMB()/1024)
+}
+
+func (self *SInstance) GetMachine() string {
+	if strings.HasPrefix(self.Bios.Type, "q35") {
+		return "q35"
+	}
+	return "pc"
+}
+
+func (self *SInstance) GetStatus() string {
+	switch self.Status {
+	case VM_STATUS_UP:
+		return api.VM_RUNNING
+	case VM_STATUS_DOWN:
+		return api.VM_READY
+	case VM_STATUS_POWERING_UP, VM_STATUS_WAIT_FOR_LAUNCH, VM_STATUS_REBOOTING:
+		return api.VM_STARTING
+	case VM_STATUS_POWERING_DOWN:
+		return api.VM_STOPPING
+	case VM_STATUS_MIGRATING:
+		return api.VM_MIGRATING
+	case VM_STATUS_SUSPENDED, VM_STATUS_PAUSED, VM_STATUS_SAVING_STATE, VM_STATUS_RESTORING_STATE:
+		return api.VM_SUSPEND
+	case VM_STATUS_IMAGE_LOCKED:
+		return api.VM_DEPLOYING
+	}
+	return api.VM_UNKNOWN
+}
+
+func (self *SInstance) GetOSName() string {
+	return self.Os.Type
+}
+
+// GetOsType returns the os type by the os of vm, e.g. rhel_8x64, windows_2019x64
+func (self *SInstance) GetOsType() cloudprovider.TOsType {
+	if strings.HasPrefix(self.Os.Type, "windows") {
+		return cloudprovider.OsTypeWindows
+	}
+	return cloudprovider.OsTypeLinux
+}
+
+func (self *SInstance) GetProjectId() string {
+	return ""
+}
+
+func (self *SInstance) GetSecurityGroupIds() ([]string, error) {
+	return []string{}, nil
+}
+
+type SGraphicsConsole struct {
+	Id       string `json:"id"`
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     int64  `json:"port"`
+	TlsPort  int64  `json:"tls_port"`
+}
+
+type STicket struct {
+	Value  string `json:"value"`
+	Expiry int    `json:"expiry"`
+}
+
+// GetVNCInfo returns the vnc console of the running vm with a temporary ticket as the password
+func (self *SInstance) GetVNCInfo(input *cloudprovider.ServerVncInput) (*cloudprovider.ServerVncOutput, error) {
+	consoles := []SGraphicsConsole{}
+	params := url.Values{}
+	params.Set("current", "true")
+	err := self.getRegion().list(self.getResource("graphicsconsoles"), params, "graphics_console", &consoles)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list graphics consoles")
+	}
+	for _, console := range consoles {
+		if console.Protocol != GRAPHICS_PROTOCOL_VNC {
+			continue
+		}
+		ret := struct {
+			Ticket STicket `json:"ticket"`
+		}{}
+		err = self.getRegion().post(self.getResource(fmt.Sprintf("graphicsconsoles/%s/ticket", console.Id)), nil, nil, &ret)
+		if err != nil {
+			return nil, errors.Wrapf(err, "set console ticket")
+		}
+		return &cloudprovider.ServerVncOutput{
+			Host:         console.Address,
+			Port:         console.Port,
+			Password:     ret.Ticket.Value,
+			Protocol:     webconsole.VNC,
+			InstanceId:   self.GetGlobalId(),
+			InstanceName: self.GetName(),
+			Hypervisor:   api.HYPERVISOR_OVIRT,
+		}, nil
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "no vnc console of vm %s", self.Name)
+}
+
+func (self *SInstance) GetVcpuCount() int {
+	topology := self.Cpu.Topology
+	if topology.Threads <= 0 {
+		topology.Threads = 1
+	}
+	return topology.Sockets * topology.Cores * topology.Threads
+}
+
+func (self *SInstance) GetVmemSizeMB() int {
+	return int(self.Memory / 1024 / 1024)
+}
+
+func (self *SInstance) GetVga() string {
+	return "std"
+}
+
+func (self *SInstance) GetVdi() string {
+	return "vnc"
+}
+
+func (self *SInstance) RebuildRoot(ctx context.Context, desc *cloudprovider.SManagedVMRebuildRootConfig) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) SetSecurityGroups(secgroupIds []string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) StartVM(ctx context.Context) error {
+	err := self.getRegion().post(self.getResource("start"), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return cloudprovider.WaitStatus(self, api.VM_RUNNING, 5*time.Second, 5*time.Minute)
+}
+
+func (self *SInstance) StopVM(ctx context.Context, opts *cloudprovider.ServerStopOptions) error {
+	action := "shutdown"
+	if opts.IsForce {
+		action = "stop"
+	}
+	err := self.getRegion().post(self.getResource(action), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return cloudprovider.WaitStatus(self, api.VM_READY, 5*time.Second, 5*time.Minute)
+}
+
+func (self *SInstance) UpdateUserData(userData string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) UpdateVM(ctx context.Context, name string) error {
+	return self.getRegion().put(self.getResource(""), jsonutils.Marshal(map[string]string{"name": name}), nil)
+}
+
+// DeleteVM deletes the vm with its disks and waits the vm removed
+func (self *SRegion) DeleteVM(id string) error {
+	err := self.delete(fmt.Sprintf("vms/%s", id), nil)
+	if err != nil {
+		if errors.Cause(err) == cloudprovider.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	return cloudprovider.Wait(time.Second*5, time.Minute*10, func() (bool, error) {
+		_, err := self.GetInstance(id)
+		if err != nil {
+			if errors.Cause(err) == cloudprovider.ErrNotFound {
+				return true, nil
+			}
+			return false, err
+		}
+		return false, nil
+	})
+}
+
+// CreateDisk creates the disk on the storage domain and attaches it to the vm, the id of disk is returned
+func (self *SRegion) CreateDisk(vmId, storageId string, sizeMb int64, diskInterface string) (string, error) {
+	body := jsonutils.Marshal(map[string]interface{}{
+		"active":    true,
+		"bootable":  false,
+		"interface": diskInterface,
+		"disk": map[string]interface{}{
+			"format":           DISK_FORMAT_COW,
+			"sparse":           true,
+			"provisioned_size": sizeMb * 1024 * 1024,
+			"storage_domains": map[string]interface{}{
+				"storage_domain": []map[string]string{{"id": storageId}},
+			},
+		},
+	})
+	attachment := &SDiskAttachment{}
+	err := self.post(fmt.Sprintf("vms/%s/diskattachments", vmId), nil, body, attachment)
+	if err != nil {
+		return "", errors.Wrapf(err, "create disk")
+	}
+	return attachment.Id, self.waitDiskReady(attachment.Id)
+}
+
+// decodeUserData returns the cloud-config of the base64 encoded user data
+func decodeUserData(userData string) string {
+	data, err := base64.StdEncoding.DecodeString(userData)
+	if err != nil {
+		return userData
+	}
+	return string(data)
+}
+
+// CreateInstance creates the vm from the template on the host, the system disk is cloned
+// to the storage domain of system disk, the vm is initialized by cloud-init at the first start
+func (self *SRegion) CreateInstance(hostId string, opts *cloudprovider.SManagedVMCreateConfig) (*SInstance, error) {
+	host, err := self.GetHost(hostId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetHost(%s)", hostId)
+	}
+	zone, err := self.getZoneByCluster(host.Cluster.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZoneByCluster")
+	}
+	// the template should be in the data center of host
+	cache := &SStoragecache{region: self, zone: zone}
+	iImage, err := cache.GetIImageById(opts.ExternalImageId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetIImageById(%s)", opts.ExternalImageId)
+	}
+	image := iImage.(*SImage)
+	memory := int64(opts.MemoryMB) * 1024 * 1024
+	params := map[string]interface{}{
+		"name":        opts.NameEn,
+		"description": opts.Description,
+		"cluster":     map[string]string{"id": host.Cluster.Id},
+		"template":    map[string]string{"id": image.Id},
+		"memory":      memory,
+		"memory_policy": map[string]int64{
+			"guaranteed": memory,
+			"max":        memory * 4,
+		},
+		"cpu": map[string]interface{}{
+			"topology": STopology{Cores: opts.Cpu, Sockets: 1, Threads: 1},
+		},
+		"placement_policy": map[string]interface{}{
+			"affinity": "migratable",
+			"hosts": map[string]interface{}{
+				"host": []map[string]string{{"id": hostId}},
+			},
+		},
+	}
+	if len(opts.SysDisk.StorageExternalId) > 0 {
+		attachments := []map[string]interface{}{}
+		for _, disk := range image.getDisks() {
+			attachments = append(attachments, map[string]interface{}{
+				"disk": map[string]interface{}{
+					"id":     disk.Id,
+					"format": DISK_FORMAT_COW,
+					"storage_domains": map[string]interface{}{
+						"storage_domain": []map[string]string{{"id": opts.SysDisk.StorageExternalId}},
+					},
+				},
+			})
+		}
+		params["disk_attachments"] = map[string]interface{}{"disk_attachment": attachments}
+	}
+	initialization := map[string]string{}
+	if len(opts.Hostname) > 0 {
+		initialization["host_name"] = opts.Hostname
+	}
+	if len(opts.Account) > 0 {
+		initialization["user_name"] = opts.Account
+	}
+	if len(opts.Password) > 0 {
+		initialization["root_password"] = opts.Password
+	}
+	if len(opts.PublicKey) > 0 {
+		initialization["authorized_ssh_keys"] = opts.PublicKey
+	}
+	if len(opts.UserData) > 0 {
+		initialization["custom_script"] = decodeUserData(opts.UserData)
+	}
+	if len(initialization) > 0 {
+		params["initialization"] = initialization
+	}
+	query := url.Values{}
+	query.Set("clone", "true")
+	vm := &SInstance{}
+	err = self.post("vms", query, jsonutils.Marshal(params), vm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create vm")
+	}
+	err = cloudprovider.Wait(time.Second*5, time.Minute*30, func() (bool, error) {
+		vm, err = self.GetInstance(vm.Id)
+		if err != nil {
+			return false, errors.Wrapf(err, "GetInstance")
+		}
+		return vm.Status != VM_STATUS_IMAGE_LOCKED, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "wait vm %s created", vm.Id)
+	}
+
+	// the nics of template are replaced by the nic of the network
+	if len(opts.ExternalNetworkId) > 0 {
+		nics, err := self.GetInstanceNics(vm.Id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetInstanceNics")
+		}
+		for _, nic := range nics {
+			err = self.delete(fmt.Sprintf("vms/%s/nics/%s", vm.Id, nic.Id), nil)
+			if err != nil {
+				return nil, errors.Wrapf(err, "delete nic %s", nic.Name)
+			}
+		}
+		err = self.CreateInstanceNic(vm.Id, "nic1", opts.ExternalNetworkId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "CreateInstanceNic")
+		}
+	}
+
+	if opts.SysDisk.SizeGB > 0 {
+		attachments, err := self.GetDiskAttachments(vm.Id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetDiskAttachments")
+		}
+		for _, attachment := range attachments {
+			if !attachment.Bootable {
+				continue
+			}
+			disk, err := self.GetDisk(attachment.Id)
+			if err != nil {
+				return nil, errors.Wrapf(err, "GetDisk(%s)", attachment.Id)
+			}
+			if sizeMb := int64(opts.SysDisk.SizeGB) * 1024; sizeMb > int64(disk.GetDiskSizeMB()) {
+				err = self.ResizeDisk(disk.Id, sizeMb)
+				if err != nil {
+					return nil, errors.Wrapf(err, "resize system disk")
+				}
+			}
+			break
+		}
+	}
+	for _, disk := range opts.DataDisks {
+		_, err = self.CreateDisk(vm.Id, disk.StorageExternalId, int64(disk.SizeGB)*1024, DISK_INTERFACE_VIRTIO_SCSI)
+		if err != nil {
+			return nil, errors.Wrapf(err, "add data disk")
+		}
+	}
+	return self.GetInstance(vm.Id)
+}
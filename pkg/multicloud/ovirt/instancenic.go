@@ -0,0 +1,131 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	IP_VERSION_V4 = "v4"
+
+	NIC_INTERFACE_VIRTIO = "virtio"
+)
+
+type SMac struct {
+	Address string `json:"address"`
+}
+
+type SIp struct {
+	Address string `json:"address"`
+	Version string `json:"version"`
+}
+
+type SIps struct {
+	Ip []SIp `json:"ip"`
+}
+
+// SReportedDevice is the nic reported by the guest agent of the running vm
+type SReportedDevice struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Mac  SMac   `json:"mac"`
+	Ips  SIps   `json:"ips"`
+}
+
+type SInstanceNic struct {
+	cloudprovider.DummyICloudNic
+	ins *SInstance
+
+	// IpAddresses is the ipv4 addresses reported by the guest agent
+	IpAddresses []string
+
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Interface   string `json:"interface"`
+	Plugged     bool   `json:"plugged"`
+	Mac         SMac   `json:"mac"`
+	VnicProfile SIdRef `json:"vnic_profile"`
+}
+
+func (self *SInstanceNic) GetId() string {
+	return self.Id
+}
+
+func (self *SInstanceNic) GetIP() string {
+	if len(self.IpAddresses) > 0 {
+		return self.IpAddresses[0]
+	}
+	return ""
+}
+
+func (self *SInstanceNic) GetMAC() string {
+	return strings.ToLower(self.Mac.Address)
+}
+
+func (self *SInstanceNic) GetDriver() string {
+	if self.Interface == NIC_INTERFACE_VIRTIO {
+		return "virtio"
+	}
+	return self.Interface
+}
+
+func (self *SInstanceNic) GetSubAddress() ([]string, error) {
+	if len(self.IpAddresses) > 1 {
+		return self.IpAddresses[1:], nil
+	}
+	return []string{}, nil
+}
+
+func (self *SInstanceNic) GetINetworkId() string {
+	return self.VnicProfile.Id
+}
+
+func (self *SInstanceNic) AssignAddress(ipAddrs []string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetInstanceNics(id string) ([]SInstanceNic, error) {
+	nics := []SInstanceNic{}
+	err := self.list(fmt.Sprintf("vms/%s/nics", id), nil, "nic", &nics)
+	if err != nil {
+		return nil, err
+	}
+	return nics, nil
+}
+
+func (self *SRegion) GetReportedDevices(id string) ([]SReportedDevice, error) {
+	devices := []SReportedDevice{}
+	err := self.list(fmt.Sprintf("vms/%s/reporteddevices", id), nil, "reported_device", &devices)
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// CreateInstanceNic adds the virtio nic connected to the vnic profile to the vm
+func (self *SRegion) CreateInstanceNic(vmId, name, profileId string) error {
+	body := jsonutils.Marshal(map[string]interface{}{
+		"name":         name,
+		"interface":    NIC_INTERFACE_VIRTIO,
+		"vnic_profile": map[string]string{"id": profileId},
+	})
+	return self.post(fmt.Sprintf("vms/%s/nics", vmId), nil, body, nil)
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"fmt"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+const (
+	NETWORK_USAGE_VM = "vm"
+)
+
+type SVlan struct {
+	Id int `json:"id"`
+}
+
+type SNetworkUsages struct {
+	Usage []string `json:"usage"`
+}
+
+// SLogicalNetwork is the logical network of the data center, e.g. ovirtmgmt
+type SLogicalNetwork struct {
+	Id         string         `json:"id"`
+	Name       string         `json:"name"`
+	DataCenter SIdRef         `json:"data_center"`
+	Vlan       SVlan          `json:"vlan"`
+	Mtu        int            `json:"mtu"`
+	Usages     SNetworkUsages `json:"usages"`
+}
+
+// SNetwork is the vnic profile of the logical network, the nic of vm is connected to the vnic profile,
+// the ip range of network is unknown to oVirt, so the network covers all ipv4 addresses
+type SNetwork struct {
+	multicloud.SResourceBase
+	multicloud.STagBase
+
+	wire *SWire
+
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Network     SIdRef `json:"network"`
+
+	LogicalNetwork SLogicalNetwork
+}
+
+func (self *SNetwork) GetName() string {
+	if self.Name == self.LogicalNetwork.Name || len(self.LogicalNetwork.Name) == 0 {
+		return self.Name
+	}
+	return fmt.Sprintf("%s-%s", self.LogicalNetwork.Name, self.Name)
+}
+
+func (self *SNetwork) GetId() string {
+	return self.Id
+}
+
+func (self *SNetwork) GetGlobalId() string {
+	return self.Id
+}
+
+func (self *SNetwork) IsEmulated() bool {
+	return true
+}
+
+func (self *SNetwork) Refresh() error {
+	return nil
+}
+
+func (self *SNetwork) Delete() error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SNetwork) GetAllocTimeoutSeconds() int {
+	return 120 // 2 minutes
+}
+
+func (self *SNetwork) GetGateway() string {
+	return ""
+}
+
+func (self *SNetwork) GetIWire() cloudprovider.ICloudWire {
+	return self.wire
+}
+
+func (self *SNetwork) getPrefix() netutils.IPV4Prefix {
+	prefix, _ := netutils.NewIPV4Prefix("0.0.0.0/0")
+	return prefix
+}
+
+func (self *SNetwork) GetIpStart() string {
+	return self.getPrefix().ToIPRange().StartIp().StepUp().String()
+}
+
+func (self *SNetwork) GetIpEnd() string {
+	return self.getPrefix().ToIPRange().EndIp().StepDown().String()
+}
+
+func (self *SNetwork) GetIpMask() int8 {
+	return self.getPrefix().MaskLen
+}
+
+func (self *SNetwork) GetProjectId() string {
+	return ""
+}
+
+func (self *SNetwork) GetPublicScope() rbacutils.TRbacScope {
+	return rbacutils.ScopeDomain
+}
+
+func (self *SNetwork) GetServerType() string {
+	return api.NETWORK_TYPE_GUEST
+}
+
+func (self *SNetwork) GetStatus() string {
+	return api.NETWORK_STATUS_AVAILABLE
+}
+
+func (self *SRegion) GetLogicalNetworks() ([]SLogicalNetwork, error) {
+	networks := []SLogicalNetwork{}
+	err := self.list("networks", nil, "network", &networks)
+	if err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
+
+// GetNetworks returns the vnic profiles of the vm networks of the data center
+func (self *SRegion) GetNetworks(dcId string) ([]SNetwork, error) {
+	networks, err := self.GetLogicalNetworks()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetLogicalNetworks")
+	}
+	logicalNetworks := map[string]SLogicalNetwork{}
+	for i := range networks {
+		if len(dcId) > 0 && networks[i].DataCenter.Id != dcId {
+			continue
+		}
+		if !utils.IsInStringArray(NETWORK_USAGE_VM, networks[i].Usages.Usage) {
+			continue
+		}
+		logicalNetworks[networks[i].Id] = networks[i]
+	}
+	profiles := []SNetwork{}
+	err = self.list("vnicprofiles", nil, "vnic_profile", &profiles)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list vnic profiles")
+	}
+	ret := []SNetwork{}
+	for i := range profiles {
+		network, ok := logicalNetworks[profiles[i].Network.Id]
+		if !ok {
+			continue
+		}
+		profiles[i].LogicalNetwork = network
+		ret = append(ret, profiles[i])
+	}
+	return ret, nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	CLOUD_PROVIDER_OVIRT = api.CLOUD_PROVIDER_OVIRT
+
+	OVIRT_DEFAULT_PORT = 443
+	OVIRT_API_PATH     = "ovirt-engine/api"
+	OVIRT_API_VERSION  = "4"
+)
+
+type OVirtClientConfig struct {
+	cpcfg    cloudprovider.ProviderConfig
+	username string
+	password string
+	host     string
+	port     int
+	debug    bool
+}
+
+// NewOVirtClientConfig creates the config of the client of oVirt engine,
+// username is in the form of user@domain, e.g. admin@internal
+func NewOVirtClientConfig(host, username, password string, port int) *OVirtClientConfig {
+	cfg := &OVirtClientConfig{
+		host:     host,
+		username: username,
+		password: password,
+		port:     port,
+	}
+	return cfg
+}
+
+func (cfg *OVirtClientConfig) CloudproviderConfig(cpcfg cloudprovider.ProviderConfig) *OVirtClientConfig {
+	cfg.cpcfg = cpcfg
+	return cfg
+}
+
+func (cfg *OVirtClientConfig) Debug(debug bool) *OVirtClientConfig {
+	cfg.debug = debug
+	return cfg
+}
+
+func (cfg OVirtClientConfig) Copy() OVirtClientConfig {
+	return cfg
+}
+
+type SOVirtClient struct {
+	*OVirtClientConfig
+
+	info *SApiInfo
+}
+
+type SVersion struct {
+	Build       string `json:"build"`
+	FullVersion string `json:"full_version"`
+	Major       string `json:"major"`
+	Minor       string `json:"minor"`
+	Revision    string `json:"revision"`
+}
+
+type SProductInfo struct {
+	Name    string   `json:"name"`
+	Vendor  string   `json:"vendor"`
+	Version SVersion `json:"version"`
+}
+
+type SApiInfo struct {
+	ProductInfo SProductInfo `json:"product_info"`
+}
+
+// SIdRef is the reference to other resource, e.g. the cluster of host
+type SIdRef struct {
+	Id   string `json:"id"`
+	Href string `json:"href"`
+}
+
+func NewOVirtClient(cfg *OVirtClientConfig) (*SOVirtClient, error) {
+	client := &SOVirtClient{
+		OVirtClientConfig: cfg,
+	}
+	return client, client.auth()
+}
+
+func (self *SOVirtClient) auth() error {
+	info := &SApiInfo{}
+	err := self.get("", nil, info)
+	if err != nil {
+		return errors.Wrapf(err, "get api info")
+	}
+	self.info = info
+	return nil
+}
+
+func (self *SOVirtClient) GetRegion() (*SRegion, error) {
+	return &SRegion{cli: self}, nil
+}
+
+func (self *SOVirtClient) GetAccountId() string {
+	return self.host
+}
+
+func (self *SOVirtClient) GetVersion() string {
+	if self.info != nil {
+		return self.info.ProductInfo.Version.FullVersion
+	}
+	return ""
+}
+
+func (self *SOVirtClient) GetCapabilities() []string {
+	return []string{
+		cloudprovider.CLOUD_CAPABILITY_COMPUTE,
+		cloudprovider.CLOUD_CAPABILITY_NETWORK,
+	}
+}
+
+func (self *SOVirtClient) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
+	subAccount := cloudprovider.SSubAccount{
+		Account:      self.username,
+		Name:         self.cpcfg.Name,
+		HealthStatus: api.CLOUD_PROVIDER_HEALTH_NORMAL,
+	}
+	return []cloudprovider.SSubAccount{subAccount}, nil
+}
+
+func (self *SOVirtClient) GetIRegions() []cloudprovider.ICloudRegion {
+	region := &SRegion{cli: self}
+	return []cloudprovider.ICloudRegion{region}
+}
+
+func (self *SOVirtClient) getBaseUrl() string {
+	if self.port == OVIRT_DEFAULT_PORT || self.port == 0 {
+		return fmt.Sprintf("https://%s/%s", self.host, OVIRT_API_PATH)
+	}
+	return fmt.Sprintf("https://%s:%d/%s", self.host, self.port, OVIRT_API_PATH)
+}
+
+func (self *SOVirtClient) getDefaultClient() *http.Client {
+	client := httputils.GetDefaultClient()
+	proxy := func(req *http.Request) (*url.URL, error) {
+		if self.cpcfg.ProxyFunc != nil {
+			return self.cpcfg.ProxyFunc(req)
+		}
+		return nil, nil
+	}
+	httputils.SetClientProxyFunc(client, proxy)
+	return client
+}
+
+// sOVirtFault is the error of oVirt api, e.g.
+// {"reason":"Operation Failed","detail":"[Cannot run VM. There is no host that satisfies current scheduling constraints.]"}
+type sOVirtFault struct {
+	Method string
+	Url    string
+	Code   int
+	Reason string `json:"reason"`
+	Detail string `json:"detail"`
+}
+
+func (self *sOVirtFault) Error() string {
+	msg := fmt.Sprintf("%s %s: %d", self.Method, self.Url, self.Code)
+	if len(self.Reason) > 0 {
+		msg = fmt.Sprintf("%s %s", msg, self.Reason)
+	}
+	if len(self.Detail) > 0 {
+		msg = fmt.Sprintf("%s %s", msg, self.Detail)
+	}
+	return msg
+}
+
+func (self *sOVirtFault) parseError(resp *http.Response, body jsonutils.JSONObject) error {
+	self.Code = resp.StatusCode
+	if body != nil {
+		body.Unmarshal(self)
+	}
+	if self.Code == http.StatusNotFound {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.Error())
+	}
+	if self.Code == http.StatusUnauthorized {
+		return errors.Wrapf(httperrors.ErrInvalidAccessKey, self.Error())
+	}
+	if self.Code == http.StatusForbidden {
+		return errors.Wrapf(httperrors.ErrForbidden, self.Error())
+	}
+	return self
+}
+
+func (self *SOVirtClient) request(method httputils.THttpMethod, res string, params url.Values, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	u := self.getBaseUrl()
+	if len(res) > 0 {
+		u = fmt.Sprintf("%s/%s", u, strings.TrimPrefix(res, "/"))
+	}
+	if len(params) > 0 {
+		u = fmt.Sprintf("%s?%s", u, params.Encode())
+	}
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	header.Set("Version", OVIRT_API_VERSION)
+	req := strings.NewReader("")
+	if body != nil {
+		header.Set("Content-Type", "application/json")
+		req = strings.NewReader(body.String())
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", self.username, self.password)))
+	header.Set("Authorization", fmt.Sprintf("Basic %s", auth))
+	resp, err := httputils.Request(self.getDefaultClient(), context.Background(), method, u, header, req, self.debug)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s %s", method, u)
+	}
+	defer httputils.CloseResponse(resp)
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read response body")
+	}
+	if self.debug {
+		log.Debugf("%s %s response: %s", method, u, string(data))
+	}
+	var obj jsonutils.JSONObject
+	if len(data) > 0 {
+		obj, err = jsonutils.Parse(data)
+		if err != nil && resp.StatusCode < 300 {
+			return nil, errors.Wrapf(err, "parse response %s", string(data))
+		}
+	}
+	if resp.StatusCode >= 300 {
+		fault := &sOVirtFault{Method: string(method), Url: u}
+		return nil, fault.parseError(resp, obj)
+	}
+	if obj == nil {
+		return jsonutils.NewDict(), nil
+	}
+	return obj, nil
+}
+
+func (self *SOVirtClient) get(res string, params url.Values, retVal interface{}) error {
+	resp, err := self.request(httputils.GET, res, params, nil)
+	if err != nil {
+		return err
+	}
+	if retVal != nil {
+		return resp.Unmarshal(retVal)
+	}
+	return nil
+}
+
+// list returns the collection of resources, the collection is wrapped by the
+// name of resource, e.g. {"vm":[...]}, and {} is returned for the empty collection
+func (self *SOVirtClient) list(res string, params url.Values, key string, retVal interface{}) error {
+	resp, err := self.request(httputils.GET, res, params, nil)
+	if err != nil {
+		return err
+	}
+	if !resp.Contains(key) {
+		return jsonutils.NewArray().Unmarshal(retVal)
+	}
+	return resp.Unmarshal(retVal, key)
+}
+
+func (self *SOVirtClient) post(res string, params url.Values, body jsonutils.JSONObject, retVal interface{}) error {
+	if body == nil {
+		body = jsonutils.NewDict()
+	}
+	resp, err := self.request(httputils.POST, res, params, body)
+	if err != nil {
+		return err
+	}
+	if retVal != nil {
+		return resp.Unmarshal(retVal)
+	}
+	return nil
+}
+
+func (self *SOVirtClient) put(res string, body jsonutils.JSONObject, retVal interface{}) error {
+	resp, err := self.request(httputils.PUT, res, nil, body)
+	if err != nil {
+		return err
+	}
+	if retVal != nil {
+		return resp.Unmarshal(retVal)
+	}
+	return nil
+}
+
+func (self *SOVirtClient) delete(res string, params url.Values) error {
+	_, err := self.request(httputils.DELETE, res, params, nil)
+	return err
+}
@@ -0,0 +1,529 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	testUsername = "admin@internal"
+	testPassword = "password"
+
+	testApiPath = "/" + OVIRT_API_PATH
+)
+
+// testResponses are the recorded responses of oVirt engine 4.5, the numbers and booleans are sent as strings
+var testResponses = map[string]string{
+	"GET ": `{"product_info":{"name":"oVirt Engine","vendor":"ovirt.org","version":{"build":"1","full_version":"4.5.4-1.el8","major":"4","minor":"5","revision":"0"}}}`,
+	"GET datacenters": `{"data_center":[
+		{"id":"dc1","name":"Default","description":"The default Data Center","status":"up","local":"false"},
+		{"id":"dc2","name":"Backup","status":"uninitialized","local":"false"}]}`,
+	"GET datacenters/dc1": `{"id":"dc1","name":"Default","description":"The default Data Center","status":"up","local":"false"}`,
+	"GET clusters": `{"cluster":[
+		{"id":"cl1","name":"Default","cpu":{"architecture":"x86_64","type":"Intel Cascadelake Server Family"},"data_center":{"id":"dc1"},"bios_type":"q35_sea_bios"}]}`,
+	"GET clusters/cl1": `{"id":"cl1","name":"Default","cpu":{"architecture":"x86_64","type":"Intel Cascadelake Server Family"},"data_center":{"id":"dc1"},"bios_type":"q35_sea_bios"}`,
+	"GET hosts": `{"host":[
+		{"id":"h1","name":"node1","address":"10.0.0.11","status":"up","type":"rhel","memory":"34359738368","cluster":{"id":"cl1"},
+			"cpu":{"name":"Intel(R) Xeon(R) Silver 4210 CPU @ 2.20GHz","speed":"2200","topology":{"cores":"4","sockets":"2","threads":"2"}},
+			"hardware_information":{"manufacturer":"Dell Inc.","product_name":"PowerEdge R640","serial_number":"SN0001"},
+			"version":{"full_version":"vdsm-4.50.3.4-1.el8"}},
+		{"id":"h2","name":"node2","address":"10.0.0.12","status":"maintenance","type":"rhel","memory":"34359738368","cluster":{"id":"cl1"},
+			"cpu":{"topology":{"cores":"4","sockets":"1","threads":"1"}}}]}`,
+	"GET hosts/h1": `{"id":"h1","name":"node1","address":"10.0.0.11","status":"up","type":"rhel","memory":"34359738368","cluster":{"id":"cl1"},
+		"cpu":{"name":"Intel(R) Xeon(R) Silver 4210 CPU @ 2.20GHz","speed":"2200","topology":{"cores":"4","sockets":"2","threads":"2"}}}`,
+	"GET datacenters/dc1/storagedomains": `{"storage_domain":[
+		{"id":"sd1","name":"data","type":"data","status":"active","available":"992137445376","used":"107374182400","committed":"53687091200","master":"true",
+			"storage":{"type":"nfs","address":"10.0.0.2","path":"/exports/data"},"storage_format":"v5","data_centers":{"data_center":[{"id":"dc1"}]}},
+		{"id":"sd2","name":"iso","type":"iso","status":"active","storage":{"type":"nfs","address":"10.0.0.2","path":"/exports/iso"}}]}`,
+	"GET vms": `{"vm":[
+		{"id":"vm1","name":"web","status":"up","memory":"2147483648","cpu":{"architecture":"x86_64","topology":{"cores":"2","sockets":"1","threads":"1"}},
+			"os":{"type":"rhel_8x64"},"bios":{"type":"q35_sea_bios"},"host":{"id":"h1"},"cluster":{"id":"cl1"},"template":{"id":"t1"},"fqdn":"web.example.com"},
+		{"id":"vm2","name":"db","status":"down","memory":"4294967296","cpu":{"topology":{"cores":"1","sockets":"2","threads":"1"}},
+			"os":{"type":"windows_2019x64"},"bios":{"type":"q35_ovmf"},"cluster":{"id":"cl1"},"placement_policy":{"affinity":"migratable","hosts":{"host":[{"id":"h2"}]}}},
+		{"id":"vm3","name":"idle","status":"down","memory":"1073741824","cluster":{"id":"cl1"}}]}`,
+	"GET vms/vm1": `{"id":"vm1","name":"web","status":"down","memory":"2147483648","cpu":{"topology":{"cores":"2","sockets":"1","threads":"1"}},
+		"os":{"type":"rhel_8x64"},"bios":{"type":"q35_sea_bios"},"cluster":{"id":"cl1"},"template":{"id":"t1"}}`,
+	"GET vms/vm1/diskattachments": `{"disk_attachment":[
+		{"id":"d2","active":"true","bootable":"false","interface":"virtio_scsi","disk":{"id":"d2"},"vm":{"id":"vm1"}},
+		{"id":"d1","active":"true","bootable":"true","interface":"virtio","disk":{"id":"d1"},"vm":{"id":"vm1"}}]}`,
+	"GET disks/d1": `{"id":"d1","name":"web_Disk1","provisioned_size":"32212254720","actual_size":"4294967296","format":"cow","status":"ok","sparse":"true",
+		"storage_type":"image","content_type":"data","storage_domains":{"storage_domain":[{"id":"sd1"}]}}`,
+	"GET disks/d2": `{"id":"d2","name":"web_Disk2","provisioned_size":"107374182400","format":"raw","status":"locked","sparse":"false",
+		"storage_type":"image","content_type":"data","storage_domains":{"storage_domain":[{"id":"sd1"}]}}`,
+	"GET vms/vm1/nics": `{"nic":[{"id":"n1","name":"nic1","interface":"virtio","plugged":"true","mac":{"address":"56:6F:3A:B2:00:01"},"vnic_profile":{"id":"p1"}}]}`,
+	"GET vms/vm1/reporteddevices": `{"reported_device":[{"id":"r1","name":"eth0","mac":{"address":"56:6f:3a:b2:00:01"},
+		"ips":{"ip":[{"address":"10.0.0.100","version":"v4"},{"address":"fe80::546f:3aff:feb2:1","version":"v6"},{"address":"10.0.0.101","version":"v4"}]}}]}`,
+	"GET networks": `{"network":[
+		{"id":"net1","name":"ovirtmgmt","data_center":{"id":"dc1"},"vlan":{"id":"0"},"mtu":"0","usages":{"usage":["vm","management"]}},
+		{"id":"net2","name":"storage","data_center":{"id":"dc1"},"usages":{"usage":["migration"]}},
+		{"id":"net3","name":"ovirtmgmt","data_center":{"id":"dc2"},"usages":{"usage":["vm"]}}]}`,
+	"GET vnicprofiles": `{"vnic_profile":[
+		{"id":"p1","name":"ovirtmgmt","network":{"id":"net1"}},
+		{"id":"p2","name":"passthrough","network":{"id":"net1"}},
+		{"id":"p3","name":"storage","network":{"id":"net2"}},
+		{"id":"p4","name":"ovirtmgmt","network":{"id":"net3"}}]}`,
+	"GET templates": `{"template":[
+		{"id":"00000000-0000-0000-0000-000000000000","name":"Blank","status":"ok"},
+		{"id":"t1","name":"CentOS-8-x86_64","status":"ok","memory":"1073741824","os":{"type":"rhel_8x64"},"bios":{"type":"q35_ovmf"},"cpu":{"architecture":"x86_64"},"creation_time":"1688318659000","cluster":{"id":"cl1"}},
+		{"id":"t2","name":"Debian-12","status":"ok","memory":"1073741824","os":{"type":"debian_12"},"bios":{"type":"q35_sea_bios"},"creation_time":"1688318660000"}]}`,
+	"GET templates/t1":                       `{"id":"t1","name":"CentOS-8-x86_64","status":"ok","memory":"1073741824","os":{"type":"rhel_8x64"},"bios":{"type":"q35_ovmf"},"cluster":{"id":"cl1"}}`,
+	"GET templates/t2":                       `{"id":"t2","name":"Debian-12","status":"ok","memory":"1073741824","os":{"type":"debian_12"},"bios":{"type":"q35_sea_bios"}}`,
+	"GET templates/t1/diskattachments":       `{"disk_attachment":[{"id":"td1","active":"true","bootable":"true","interface":"virtio","disk":{"id":"td1"}}]}`,
+	"GET templates/t2/diskattachments":       `{"disk_attachment":[{"id":"td2","active":"true","bootable":"true","interface":"virtio_scsi","disk":{"id":"td2"}}]}`,
+	"GET disks/td1":                          `{"id":"td1","name":"CentOS-8-x86_64","provisioned_size":"10737418240","format":"cow","status":"ok","storage_domains":{"storage_domain":[{"id":"sd1"}]}}`,
+	"GET disks/td2":                          `{"id":"td2","name":"Debian-12","provisioned_size":"8589934592","format":"raw","status":"ok","storage_domains":{"storage_domain":[{"id":"sd3"}]}}`,
+	"GET storagedomains/sd3":                 `{"id":"sd3","name":"backup","type":"data","data_centers":{"data_center":[{"id":"dc2"}]}}`,
+	"GET datacenters/dc2/storagedomains/sd3": `{"id":"sd3","name":"backup","type":"data","status":"active","data_centers":{"data_center":[{"id":"dc2"}]}}`,
+	"POST vms/vm1/start":                     `{"status":"complete"}`,
+	"PUT vms/vm1":                            `{"id":"vm1"}`,
+	"POST vms?clone=true":                    `{"id":"vm4","name":"new","status":"image_locked","cluster":{"id":"cl1"}}`,
+	"GET vms/vm4":                            `{"id":"vm4","name":"new","status":"down","cluster":{"id":"cl1"},"template":{"id":"t1"}}`,
+}
+
+// sTestEngine serves the recorded responses as an oVirt engine, and keeps the
+// requests and their bodies to check what is sent
+type sTestEngine struct {
+	responses map[string]string
+	// updates replaces the responses after the request is served, e.g. the
+	// vm is up after it is started
+	updates  map[string]map[string]string
+	requests []string
+	bodies   map[string]jsonutils.JSONObject
+}
+
+func (e *sTestEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || user != testUsername || password != testPassword {
+		http.Error(w, `{"detail":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Version") != OVIRT_API_VERSION {
+		http.Error(w, "unsupported version", http.StatusBadRequest)
+		return
+	}
+	key := fmt.Sprintf("%s %s", r.Method, strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, testApiPath), "/"))
+	if len(r.URL.RawQuery) > 0 {
+		key = fmt.Sprintf("%s?%s", key, r.URL.RawQuery)
+	}
+	e.requests = append(e.requests, key)
+	if data, _ := ioutil.ReadAll(r.Body); len(data) > 0 {
+		e.bodies[key], _ = jsonutils.Parse(data)
+	}
+	resp, ok := e.responses[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"reason":"Operation Failed","detail":"Entity not found"}`))
+		return
+	}
+	for k, v := range e.updates[key] {
+		e.responses[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(resp))
+}
+
+func (e *sTestEngine) requested(key string) bool {
+	for _, req := range e.requests {
+		if req == key {
+			return true
+		}
+	}
+	return false
+}
+
+// body returns the body of the last request of key, and forgets it
+func (e *sTestEngine) body(t *testing.T, key string) jsonutils.JSONObject {
+	body, ok := e.bodies[key]
+	if !ok {
+		t.Fatalf("no request %s", key)
+	}
+	delete(e.bodies, key)
+	return body
+}
+
+func newTestRegion(t *testing.T, username string) (*SRegion, *sTestEngine) {
+	engine := &sTestEngine{
+		responses: map[string]string{},
+		updates:   map[string]map[string]string{},
+		bodies:    map[string]jsonutils.JSONObject{},
+	}
+	for k, v := range testResponses {
+		engine.responses[k] = v
+	}
+	server := httptest.NewTLSServer(engine)
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	cli, err := NewOVirtClient(NewOVirtClientConfig(u.Hostname(), username, testPassword, port))
+	if err != nil {
+		return nil, engine
+	}
+	region, _ := cli.GetRegion()
+	return region, engine
+}
+
+// getTestInstance returns the vm placed on the first host
+func getTestInstance(t *testing.T, region *SRegion, id string) *SInstance {
+	vm, err := region.GetInstance(id)
+	if err != nil {
+		t.Fatalf("GetInstance(%s) error: %v", id, err)
+	}
+	hosts, err := region.GetHosts("")
+	if err != nil {
+		t.Fatalf("GetHosts error: %v", err)
+	}
+	vm.host = &hosts[0]
+	vm.host.zone = &SZone{region: region}
+	return vm
+}
+
+func TestAuth(t *testing.T) {
+	region, _ := newTestRegion(t, "admin@other")
+	if region != nil {
+		t.Fatalf("auth should fail with wrong username")
+	}
+	cli := &SOVirtClient{OVirtClientConfig: NewOVirtClientConfig("127.0.0.1", testUsername, testPassword, 0)}
+	if base := cli.getBaseUrl(); base != "https://127.0.0.1/"+OVIRT_API_PATH {
+		t.Errorf("unexpected base url %s", base)
+	}
+	fault := &sOVirtFault{Method: "GET", Url: cli.getBaseUrl()}
+	if err := fault.parseError(&http.Response{StatusCode: http.StatusUnauthorized}, nil); errors.Cause(err) != httperrors.ErrInvalidAccessKey {
+		t.Errorf("401 should be ErrInvalidAccessKey, got %v", err)
+	}
+
+	region, _ = newTestRegion(t, testUsername)
+	if region == nil {
+		t.Fatalf("auth failed")
+	}
+	if version := region.cli.GetVersion(); version != "4.5.4-1.el8" {
+		t.Errorf("unexpected version %s", version)
+	}
+	if _, err := region.GetInstance("vm404"); errors.Cause(err) != cloudprovider.ErrNotFound {
+		t.Errorf("GetInstance should return ErrNotFound, got %v", err)
+	}
+}
+
+func TestZonesAndHosts(t *testing.T) {
+	region, _ := newTestRegion(t, testUsername)
+	zones, err := region.GetIZones()
+	if err != nil {
+		t.Fatalf("GetIZones error: %v", err)
+	}
+	if len(zones) != 2 || zones[0].GetStatus() != api.ZONE_ENABLE || zones[1].GetStatus() != api.ZONE_DISABLE {
+		t.Fatalf("unexpected zones %d", len(zones))
+	}
+	hosts, err := zones[0].GetIHosts()
+	if err != nil {
+		t.Fatalf("GetIHosts error: %v", err)
+	}
+	if len(hosts) != 2 {
+		t.Fatalf("expect 2 hosts, got %d", len(hosts))
+	}
+	if hosts[0].GetCpuCount() != 16 || hosts[0].GetMemSizeMB() != 32768 || hosts[0].GetHostStatus() != api.HOST_ONLINE || hosts[0].GetSN() != "SN0001" {
+		t.Errorf("unexpected host %s cpu %d mem %d status %s", hosts[0].GetName(), hosts[0].GetCpuCount(), hosts[0].GetMemSizeMB(), hosts[0].GetHostStatus())
+	}
+	if !hosts[1].GetIsMaintenance() || hosts[1].GetEnabled() {
+		t.Errorf("host %s should be in maintenance", hosts[1].GetName())
+	}
+	hosts, err = zones[1].GetIHosts()
+	if err != nil || len(hosts) != 0 {
+		t.Errorf("zone %s should have no host", zones[1].GetName())
+	}
+
+	storages, err := zones[0].GetIStorages()
+	if err != nil {
+		t.Fatalf("GetIStorages error: %v", err)
+	}
+	if len(storages) != 1 {
+		t.Fatalf("expect 1 data storage domain, got %d", len(storages))
+	}
+	if storages[0].GetCapacityMB() != 1024*1024 || storages[0].GetMountPoint() != "10.0.0.2:/exports/data" || storages[0].GetStatus() != api.STORAGE_ONLINE {
+		t.Errorf("unexpected storage capacity %d mount point %s", storages[0].GetCapacityMB(), storages[0].GetMountPoint())
+	}
+}
+
+func TestInstances(t *testing.T) {
+	region, _ := newTestRegion(t, testUsername)
+	vms, err := region.GetInstances("h2")
+	if err != nil {
+		t.Fatalf("GetInstances error: %v", err)
+	}
+	if len(vms) != 1 || vms[0].Id != "vm2" {
+		t.Fatalf("the vm2 should be placed on h2 by placement policy")
+	}
+	if vms[0].GetVcpuCount() != 2 || vms[0].GetVmemSizeMB() != 4096 || vms[0].GetOsType() != cloudprovider.OsTypeWindows || vms[0].GetBios() != "UEFI" {
+		t.Errorf("unexpected vm %s", vms[0].Name)
+	}
+	vms, err = region.GetInstances("h1")
+	if err != nil {
+		t.Fatalf("GetInstances error: %v", err)
+	}
+	if len(vms) != 2 || vms[0].Id != "vm1" || vms[1].Id != "vm3" {
+		t.Fatalf("the vm1 and vm3 should be on h1, got %d vms", len(vms))
+	}
+	if vms[0].GetStatus() != api.VM_RUNNING || vms[0].GetHostname() != "web" || vms[1].GetStatus() != api.VM_READY {
+		t.Errorf("unexpected status %s %s", vms[0].GetStatus(), vms[1].GetStatus())
+	}
+
+	ivm, err := region.GetIVMById("vm1")
+	if err != nil {
+		t.Fatalf("GetIVMById error: %v", err)
+	}
+	if ivm.GetIHost().GetGlobalId() != "h1" {
+		t.Errorf("vm1 should be on h1")
+	}
+	disks, err := ivm.GetIDisks()
+	if err != nil {
+		t.Fatalf("GetIDisks error: %v", err)
+	}
+	if len(disks) != 2 {
+		t.Fatalf("expect 2 disks, got %d", len(disks))
+	}
+	if disks[0].GetGlobalId() != "d1" || disks[0].GetDiskType() != api.DISK_TYPE_SYS || disks[0].GetDiskFormat() != "qcow2" || disks[0].GetDiskSizeMB() != 30720 || disks[0].GetDriver() != "virtio" {
+		t.Errorf("unexpected system disk %s type %s format %s size %d", disks[0].GetGlobalId(), disks[0].GetDiskType(), disks[0].GetDiskFormat(), disks[0].GetDiskSizeMB())
+	}
+	if disks[1].GetDiskType() != api.DISK_TYPE_DATA || disks[1].GetStatus() != api.DISK_ALLOCATING || disks[1].GetDriver() != "scsi" {
+		t.Errorf("unexpected data disk %s type %s status %s", disks[1].GetGlobalId(), disks[1].GetDiskType(), disks[1].GetStatus())
+	}
+	storage, err := disks[0].GetIStorage()
+	if err != nil || storage.GetGlobalId() != "sd1" {
+		t.Errorf("the storage of disk should be sd1")
+	}
+}
+
+func TestInstanceNics(t *testing.T) {
+	region, _ := newTestRegion(t, testUsername)
+	// the ips are reported by the guest agent of running vm
+	vms, err := region.GetInstances("")
+	if err != nil {
+		t.Fatalf("GetInstances error: %v", err)
+	}
+	vm := vms[0]
+	vm.host = &SHost{zone: &SZone{region: region}}
+	nics, err := vm.GetINics()
+	if err != nil {
+		t.Fatalf("GetINics error: %v", err)
+	}
+	if len(nics) != 1 {
+		t.Fatalf("expect 1 nic, got %d", len(nics))
+	}
+	if nics[0].GetMAC() != "56:6f:3a:b2:00:01" || nics[0].GetIP() != "10.0.0.100" || nics[0].GetINetworkId() != "p1" {
+		t.Errorf("unexpected nic mac %s ip %s network %s", nics[0].GetMAC(), nics[0].GetIP(), nics[0].GetINetworkId())
+	}
+	subAddrs, _ := nics[0].GetSubAddress()
+	if len(subAddrs) != 1 || subAddrs[0] != "10.0.0.101" {
+		t.Errorf("unexpected sub address %v", subAddrs)
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	region, _ := newTestRegion(t, testUsername)
+	networks, err := region.GetNetworks("dc1")
+	if err != nil {
+		t.Fatalf("GetNetworks error: %v", err)
+	}
+	if len(networks) != 2 {
+		t.Fatalf("expect 2 vnic profiles of vm networks, got %d", len(networks))
+	}
+	if networks[0].GetName() != "ovirtmgmt" || networks[1].GetName() != "ovirtmgmt-passthrough" {
+		t.Errorf("unexpected network names %s %s", networks[0].GetName(), networks[1].GetName())
+	}
+	if networks[0].GetIpStart() != "0.0.0.1" || networks[0].GetIpMask() != 0 {
+		t.Errorf("unexpected ip range %s/%d", networks[0].GetIpStart(), networks[0].GetIpMask())
+	}
+}
+
+func TestImages(t *testing.T) {
+	region, _ := newTestRegion(t, testUsername)
+	images, err := region.GetImages()
+	if err != nil {
+		t.Fatalf("GetImages error: %v", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("the Blank template should be skipped, got %d images", len(images))
+	}
+	image := images[0]
+	image.cache = &SStoragecache{region: region}
+	if image.GetMinOsDiskSizeGb() != 10 || image.GetImageFormat() != "qcow2" || !image.UEFI() || image.GetStatus() != api.CACHED_IMAGE_STATUS_ACTIVE {
+		t.Errorf("unexpected image %s min disk %d format %s", image.Name, image.GetMinOsDiskSizeGb(), image.GetImageFormat())
+	}
+	if image.GetCreatedAt().Unix() != 1688318659 {
+		t.Errorf("unexpected created at %s", image.GetCreatedAt())
+	}
+}
+
+func TestStartVM(t *testing.T) {
+	region, engine := newTestRegion(t, testUsername)
+	engine.updates["POST vms/vm1/start"] = map[string]string{
+		"GET vms/vm1": `{"id":"vm1","name":"web","status":"up","host":{"id":"h1"},"cluster":{"id":"cl1"}}`,
+	}
+	vm := getTestInstance(t, region, "vm1")
+	if vm.GetStatus() != api.VM_READY {
+		t.Fatalf("vm should be ready before started")
+	}
+	if err := vm.StartVM(context.Background()); err != nil {
+		t.Fatalf("StartVM error: %v", err)
+	}
+	if !engine.requested("POST vms/vm1/start") {
+		t.Errorf("the vm is not started")
+	}
+	if vm.GetStatus() != api.VM_RUNNING || vm.Host.Id != "h1" {
+		t.Errorf("vm should be running on h1, got %s", vm.GetStatus())
+	}
+}
+
+func TestTemplatesOfDataCenter(t *testing.T) {
+	region, engine := newTestRegion(t, testUsername)
+	caches, err := region.GetIStoragecaches()
+	if err != nil {
+		t.Fatalf("GetIStoragecaches error: %v", err)
+	}
+	// t1 is in the data center of its cluster, t2 has no cluster and is in
+	// the data center of its storage domain, the Blank template is skipped
+	want := map[string]string{
+		region.GetGlobalId() + "/dc1/templates": "t1",
+		region.GetGlobalId() + "/dc2/templates": "t2",
+	}
+	if len(caches) != len(want) {
+		t.Fatalf("want %d storagecaches, got %d", len(want), len(caches))
+	}
+	for _, cache := range caches {
+		images, err := cache.GetICloudImages()
+		if err != nil {
+			t.Fatalf("GetICloudImages of %s error: %v", cache.GetGlobalId(), err)
+		}
+		ids := []string{}
+		for _, image := range images {
+			ids = append(ids, image.GetGlobalId())
+		}
+		if strings.Join(ids, ",") != want[cache.GetGlobalId()] {
+			t.Errorf("storagecache %s images %v, want %s", cache.GetGlobalId(), ids, want[cache.GetGlobalId()])
+		}
+	}
+
+	// h1 is in dc1, the template of dc2 is refused before any vm is created
+	_, err = region.CreateInstance("h1", &cloudprovider.SManagedVMCreateConfig{ExternalImageId: "t2"})
+	if errors.Cause(err) != cloudprovider.ErrNotFound {
+		t.Errorf("template of other data center should not be used, got %v", err)
+	}
+	if engine.requested("POST vms?clone=true") {
+		t.Fatalf("vm should not be created from template of other data center")
+	}
+
+	opts := &cloudprovider.SManagedVMCreateConfig{ExternalImageId: "t1", NameEn: "new", Cpu: 2, MemoryMB: 2048}
+	opts.SysDisk.StorageExternalId = "sd1"
+	vm, err := region.CreateInstance("h1", opts)
+	if err != nil {
+		t.Fatalf("CreateInstance error: %v", err)
+	}
+	if vm.Id != "vm4" || vm.GetStatus() != api.VM_READY {
+		t.Errorf("unexpected vm %s status %s", vm.Id, vm.GetStatus())
+	}
+	body := engine.body(t, "POST vms?clone=true")
+	template, _ := body.GetString("template", "id")
+	cluster, _ := body.GetString("cluster", "id")
+	hosts, _ := body.GetArray("placement_policy", "hosts", "host")
+	attachments, _ := body.GetArray("disk_attachments", "disk_attachment")
+	if template != "t1" || cluster != "cl1" || len(hosts) != 1 || len(attachments) != 1 {
+		t.Fatalf("unexpected create vm body %s", body)
+	}
+	diskId, _ := attachments[0].GetString("disk", "id")
+	domains, _ := attachments[0].GetArray("disk", "storage_domains", "storage_domain")
+	if diskId != "td1" || len(domains) != 1 || domains[0].String() != `{"id":"sd1"}` {
+		t.Errorf("the template disk should be cloned to sd1, got %s", attachments[0])
+	}
+}
+
+func TestChangeConfig(t *testing.T) {
+	region, engine := newTestRegion(t, testUsername)
+	vm := getTestInstance(t, region, "vm1")
+
+	// the stopped vm is reconfigured with a single socket, the max memory follows the memory
+	err := vm.ChangeConfig(context.Background(), &cloudprovider.SManagedVMChangeConfig{Cpu: 3, MemoryMB: 3072})
+	if err != nil {
+		t.Fatalf("ChangeConfig error: %v", err)
+	}
+	body := engine.body(t, "PUT vms/vm1")
+	if topology, _ := body.Get("cpu", "topology"); topology.String() != `{"cores":3,"sockets":1,"threads":1}` {
+		t.Errorf("unexpected cpu topology of stopped vm %s", body)
+	}
+	memory, _ := body.Int("memory")
+	guaranteed, _ := body.Int("memory_policy", "guaranteed")
+	max, _ := body.Int("memory_policy", "max")
+	if memory != 3072<<20 || guaranteed != memory || max != 4*memory {
+		t.Errorf("unexpected memory of stopped vm %s", body)
+	}
+
+	// the running vm with 2 cores per socket is hot plugged by sockets, the
+	// memory policy is kept as it can't be changed while running
+	vm.Status = VM_STATUS_UP
+	vm.MemoryPolicy.Max = 8 << 30
+	err = vm.ChangeConfig(context.Background(), &cloudprovider.SManagedVMChangeConfig{Cpu: 4, MemoryMB: 4096})
+	if err != nil {
+		t.Fatalf("ChangeConfig error: %v", err)
+	}
+	body = engine.body(t, "PUT vms/vm1")
+	if topology, _ := body.Get("cpu", "topology"); topology.String() != `{"cores":2,"sockets":2,"threads":1}` {
+		t.Errorf("unexpected hot plugged cpu topology %s", body)
+	}
+	if memory, _ := body.Int("memory"); memory != 4096<<20 || body.Contains("memory_policy") {
+		t.Errorf("unexpected hot plugged memory %s", body)
+	}
+
+	// only cpu is sent if the memory is unchanged
+	err = vm.ChangeConfig(context.Background(), &cloudprovider.SManagedVMChangeConfig{Cpu: 6, MemoryMB: 2048})
+	if err != nil {
+		t.Fatalf("ChangeConfig error: %v", err)
+	}
+	if body = engine.body(t, "PUT vms/vm1"); body.Contains("memory") {
+		t.Errorf("unchanged memory should not be sent %s", body)
+	}
+
+	cases := []struct {
+		name string
+		opts cloudprovider.SManagedVMChangeConfig
+	}{
+		{name: "cpus not multiple of socket", opts: cloudprovider.SManagedVMChangeConfig{Cpu: 3}},
+		{name: "reduce memory", opts: cloudprovider.SManagedVMChangeConfig{MemoryMB: 1024}},
+		{name: "memory not multiple of 256MB", opts: cloudprovider.SManagedVMChangeConfig{MemoryMB: 2100}},
+		{name: "memory exceeds max", opts: cloudprovider.SManagedVMChangeConfig{MemoryMB: 16384}},
+	}
+	for _, c := range cases {
+		err = vm.ChangeConfig(context.Background(), &c.opts)
+		if errors.Cause(err) != cloudprovider.ErrNotSupported {
+			t.Errorf("%s: hot plug should not be supported, got %v", c.name, err)
+		}
+		if _, ok := engine.bodies["PUT vms/vm1"]; ok {
+			t.Errorf("%s: the vm should not be updated", c.name)
+		}
+	}
+}
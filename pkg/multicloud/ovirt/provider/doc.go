@@ -0,0 +1 @@
+package provider // import "yunion.io/x/onecloud/pkg/multicloud/ovirt/provider"
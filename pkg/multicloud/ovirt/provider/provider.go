@@ -0,0 +1,201 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/regutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/multicloud/ovirt"
+)
+
+type SOVirtProviderFactory struct {
+	cloudprovider.SPrivateCloudBaseProviderFactory
+}
+
+func (self *SOVirtProviderFactory) GetId() string {
+	return ovirt.CLOUD_PROVIDER_OVIRT
+}
+
+func (self *SOVirtProviderFactory) GetName() string {
+	return ovirt.CLOUD_PROVIDER_OVIRT
+}
+
+func (self *SOVirtProviderFactory) ValidateChangeBandwidth(instanceId string, bandwidth int64) error {
+	return fmt.Errorf("Changing %s bandwidth is not supported", ovirt.CLOUD_PROVIDER_OVIRT)
+}
+
+// ValidateCreateCloudaccountData validates the user of oVirt engine, the username is in the form of user@profile, e.g. admin@internal
+func (self *SOVirtProviderFactory) ValidateCreateCloudaccountData(ctx context.Context, userCred mcclient.TokenCredential, input cloudprovider.SCloudaccountCredential) (cloudprovider.SCloudaccount, error) {
+	output := cloudprovider.SCloudaccount{}
+	if len(input.Username) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "username")
+	}
+	if len(input.Password) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "password")
+	}
+	if len(input.Host) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "host")
+	}
+	if !regutils.MatchIPAddr(input.Host) && !regutils.MatchDomainName(input.Host) {
+		return output, errors.Wrap(httperrors.ErrInputParameter, "host should be ip or domain name")
+	}
+	if input.Port == 0 {
+		input.Port = ovirt.OVIRT_DEFAULT_PORT
+	}
+	output.AccessUrl = fmt.Sprintf("https://%s:%d", input.Host, input.Port)
+	output.Account = input.Username
+	output.Secret = input.Password
+	return output, nil
+}
+
+func (self *SOVirtProviderFactory) ValidateUpdateCloudaccountCredential(ctx context.Context, userCred mcclient.TokenCredential, input cloudprovider.SCloudaccountCredential, cloudaccount string) (cloudprovider.SCloudaccount, error) {
+	output := cloudprovider.SCloudaccount{}
+	if len(input.Username) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "username")
+	}
+	if len(input.Password) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "password")
+	}
+	output = cloudprovider.SCloudaccount{
+		Account: input.Username,
+		Secret:  input.Password,
+	}
+	return output, nil
+}
+
+func parseHostPort(_url string) (string, int, error) {
+	urlParse, err := url.Parse(_url)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "parse %s", _url)
+	}
+	port := func() int {
+		if len(urlParse.Port()) > 0 {
+			_port, _ := strconv.Atoi(urlParse.Port())
+			return _port
+		}
+		return ovirt.OVIRT_DEFAULT_PORT
+	}()
+	return strings.TrimSuffix(urlParse.Host, fmt.Sprintf(":%d", port)), port, nil
+}
+
+func (self *SOVirtProviderFactory) GetProvider(cfg cloudprovider.ProviderConfig) (cloudprovider.ICloudProvider, error) {
+	host, port, err := parseHostPort(cfg.URL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parseHostPort")
+	}
+
+	client, err := ovirt.NewOVirtClient(
+		ovirt.NewOVirtClientConfig(
+			host, cfg.Account, cfg.Secret, port,
+		).CloudproviderConfig(cfg),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &SOVirtProvider{
+		SBaseProvider: cloudprovider.NewBaseProvider(self),
+		client:        client,
+	}, nil
+}
+
+func (self *SOVirtProviderFactory) GetClientRC(info cloudprovider.SProviderInfo) (map[string]string, error) {
+	host, port, err := parseHostPort(info.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"OVIRT_HOST":     host,
+		"OVIRT_PORT":     fmt.Sprintf("%d", port),
+		"OVIRT_USERNAME": info.Account,
+		"OVIRT_PASSWORD": info.Secret,
+	}, nil
+}
+
+func init() {
+	factory := SOVirtProviderFactory{}
+	cloudprovider.RegisterFactory(&factory)
+}
+
+type SOVirtProvider struct {
+	cloudprovider.SBaseProvider
+	client *ovirt.SOVirtClient
+}
+
+func (self *SOVirtProvider) GetSysInfo() (jsonutils.JSONObject, error) {
+	return jsonutils.NewDict(), nil
+}
+
+func (self *SOVirtProvider) GetVersion() string {
+	return self.client.GetVersion()
+}
+
+func (self *SOVirtProvider) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
+	return self.client.GetSubAccounts()
+}
+
+func (self *SOVirtProvider) GetAccountId() string {
+	return self.client.GetAccountId()
+}
+
+func (self *SOVirtProvider) GetIRegions() []cloudprovider.ICloudRegion {
+	return self.client.GetIRegions()
+}
+
+func (self *SOVirtProvider) GetIRegionById(id string) (cloudprovider.ICloudRegion, error) {
+	regions := self.GetIRegions()
+	for i := range regions {
+		if regions[i].GetGlobalId() == id {
+			return regions[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SOVirtProvider) GetBalance() (float64, string, error) {
+	return 0.0, api.CLOUD_PROVIDER_HEALTH_NORMAL, cloudprovider.ErrNotSupported
+}
+
+func (self *SOVirtProvider) GetIProjects() ([]cloudprovider.ICloudProject, error) {
+	return []cloudprovider.ICloudProject{}, nil
+}
+
+func (self *SOVirtProvider) GetStorageClasses(regionId string) []string {
+	return nil
+}
+
+func (self *SOVirtProvider) GetBucketCannedAcls(regionId string) []string {
+	return nil
+}
+
+func (self *SOVirtProvider) GetObjectCannedAcls(regionId string) []string {
+	return nil
+}
+
+func (self *SOVirtProvider) GetCapabilities() []string {
+	return self.client.GetCapabilities()
+}
@@ -0,0 +1,256 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"fmt"
+	"net/url"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SRegion struct {
+	multicloud.SRegion
+	multicloud.SNoObjectStorageRegion
+	multicloud.SNoLbRegion
+
+	cli *SOVirtClient
+}
+
+func (self *SRegion) GetId() string {
+	return self.cli.cpcfg.Id
+}
+
+func (self *SRegion) GetGlobalId() string {
+	return fmt.Sprintf("%s/%s", api.CLOUD_PROVIDER_OVIRT, self.cli.cpcfg.Id)
+}
+
+func (self *SRegion) GetName() string {
+	return self.cli.cpcfg.Name
+}
+
+func (self *SRegion) GetI18n() cloudprovider.SModelI18nTable {
+	table := cloudprovider.SModelI18nTable{}
+	table["name"] = cloudprovider.NewSModelI18nEntry(self.GetName()).CN(self.GetName())
+	return table
+}
+
+func (self *SRegion) CreateEIP(opts *cloudprovider.SEip) (cloudprovider.ICloudEIP, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetISecurityGroupById(secgroupId string) (cloudprovider.ICloudSecurityGroup, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetISecurityGroupByName(opts *cloudprovider.SecurityGroupFilterOptions) (cloudprovider.ICloudSecurityGroup, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) CreateISecurityGroup(conf *cloudprovider.SecurityGroupCreateInput) (cloudprovider.ICloudSecurityGroup, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) CreateIVpc(opts *cloudprovider.VpcCreateOptions) (cloudprovider.ICloudVpc, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetCapabilities() []string {
+	return self.cli.GetCapabilities()
+}
+
+func (self *SRegion) GetCloudEnv() string {
+	return ""
+}
+
+func (self *SRegion) GetProvider() string {
+	return api.CLOUD_PROVIDER_OVIRT
+}
+
+func (self *SRegion) GetStatus() string {
+	return api.CLOUD_REGION_STATUS_INSERVER
+}
+
+func (self *SRegion) GetGeographicInfo() cloudprovider.SGeographicInfo {
+	return cloudprovider.SGeographicInfo{}
+}
+
+func (self *SRegion) GetIEipById(id string) (cloudprovider.ICloudEIP, error) {
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SRegion) GetIEips() ([]cloudprovider.ICloudEIP, error) {
+	return []cloudprovider.ICloudEIP{}, nil
+}
+
+func (self *SRegion) GetIVpcById(id string) (cloudprovider.ICloudVpc, error) {
+	zone, err := self.getZone(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZone(%s)", id)
+	}
+	return zone.getVpc(), nil
+}
+
+func (self *SRegion) GetIVpcs() ([]cloudprovider.ICloudVpc, error) {
+	zones, err := self.getZones()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZones")
+	}
+	ret := []cloudprovider.ICloudVpc{}
+	for i := range zones {
+		ret = append(ret, zones[i].getVpc())
+	}
+	return ret, nil
+}
+
+func (self *SRegion) getZones() ([]SZone, error) {
+	dcs, err := self.GetDataCenters()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetDataCenters")
+	}
+	ret := []SZone{}
+	for i := range dcs {
+		ret = append(ret, SZone{region: self, SDataCenter: dcs[i]})
+	}
+	return ret, nil
+}
+
+// getZone returns the zone of the data center
+func (self *SRegion) getZone(dcId string) (*SZone, error) {
+	dc, err := self.GetDataCenter(dcId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetDataCenter(%s)", dcId)
+	}
+	return &SZone{region: self, SDataCenter: *dc}, nil
+}
+
+// getZoneByCluster returns the zone of the data center which the cluster belongs to
+func (self *SRegion) getZoneByCluster(clusterId string) (*SZone, error) {
+	cluster, err := self.GetCluster(clusterId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetCluster(%s)", clusterId)
+	}
+	return self.getZone(cluster.DataCenter.Id)
+}
+
+func (self *SRegion) GetIZones() ([]cloudprovider.ICloudZone, error) {
+	zones, err := self.getZones()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudZone{}
+	for i := range zones {
+		ret = append(ret, &zones[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIZoneById(id string) (cloudprovider.ICloudZone, error) {
+	zones, err := self.GetIZones()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetIZones")
+	}
+	for i := range zones {
+		if zones[i].GetGlobalId() == id {
+			return zones[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetIHosts() ([]cloudprovider.ICloudHost, error) {
+	zones, err := self.getZones()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZones")
+	}
+	ret := []cloudprovider.ICloudHost{}
+	for i := range zones {
+		hosts, err := zones[i].GetIHosts()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetIHosts")
+		}
+		ret = append(ret, hosts...)
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIHostById(id string) (cloudprovider.ICloudHost, error) {
+	host, err := self.GetHost(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetHost(%s)", id)
+	}
+	host.zone, err = self.getZoneByCluster(host.Cluster.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZoneByCluster")
+	}
+	return host, nil
+}
+
+func (self *SRegion) GetIVMById(id string) (cloudprovider.ICloudVM, error) {
+	vm, err := self.GetInstance(id)
+	if err != nil {
+		return nil, err
+	}
+	hostId, err := self.getInstanceHostId(vm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getInstanceHostId")
+	}
+	host, err := self.GetIHostById(hostId)
+	if err != nil {
+		return nil, err
+	}
+	vm.host = host.(*SHost)
+	return vm, nil
+}
+
+func (self *SRegion) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	storage, err := self.GetStorage(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetStorage(%s)", id)
+	}
+	if len(storage.DataCenters.DataCenter) == 0 {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "storage domain %s is not attached to any data center", id)
+	}
+	storage.zone, err = self.getZone(storage.DataCenters.DataCenter[0].Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZone")
+	}
+	return storage, nil
+}
+
+func (self *SRegion) get(res string, params url.Values, retVal interface{}) error {
+	return self.cli.get(res, params, retVal)
+}
+
+func (self *SRegion) list(res string, params url.Values, key string, retVal interface{}) error {
+	return self.cli.list(res, params, key, retVal)
+}
+
+func (self *SRegion) post(res string, params url.Values, body jsonutils.JSONObject, retVal interface{}) error {
+	return self.cli.post(res, params, body, retVal)
+}
+
+func (self *SRegion) put(res string, body jsonutils.JSONObject, retVal interface{}) error {
+	return self.cli.put(res, body, retVal)
+}
+
+func (self *SRegion) delete(res string, params url.Values) error {
+	return self.cli.delete(res, params)
+}
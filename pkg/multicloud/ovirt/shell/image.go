@@ -0,0 +1,46 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/onecloud/pkg/multicloud/ovirt"
+	"yunion.io/x/onecloud/pkg/util/shellutils"
+)
+
+func init() {
+	type ImageListOptions struct {
+	}
+	shellutils.R(&ImageListOptions{}, "image-list", "list templates", func(cli *ovirt.SRegion, args *ImageListOptions) error {
+		images, err := cli.GetImages()
+		if err != nil {
+			return err
+		}
+		printList(images, 0, 0, 0, []string{})
+		return nil
+	})
+
+	type ImageIdOptions struct {
+		ID string `help:"id of template"`
+	}
+	shellutils.R(&ImageIdOptions{}, "image-disk-list", "list disks of template", func(cli *ovirt.SRegion, args *ImageIdOptions) error {
+		disks, err := cli.GetTemplateDisks(args.ID)
+		if err != nil {
+			return err
+		}
+		printList(disks, 0, 0, 0, []string{})
+		return nil
+	})
+
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"context"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud/ovirt"
+	"yunion.io/x/onecloud/pkg/util/shellutils"
+)
+
+func init() {
+	type InstanceListOptions struct {
+		Host string `help:"host id of instances"`
+	}
+	shellutils.R(&InstanceListOptions{}, "instance-list", "list instances", func(cli *ovirt.SRegion, args *InstanceListOptions) error {
+		vms, err := cli.GetInstances(args.Host)
+		if err != nil {
+			return err
+		}
+		printList(vms, 0, 0, 0, []string{})
+		return nil
+	})
+
+	type InstanceIdOptions struct {
+		ID string `help:"id of instance"`
+	}
+
+	shellutils.R(&InstanceIdOptions{}, "instance-show", "show instance", func(cli *ovirt.SRegion, args *InstanceIdOptions) error {
+		vm, err := cli.GetInstance(args.ID)
+		if err != nil {
+			return err
+		}
+		printObject(vm)
+		return nil
+	})
+
+	shellutils.R(&InstanceIdOptions{}, "instance-start", "start instance", func(cli *ovirt.SRegion, args *InstanceIdOptions) error {
+		vm, err := cli.GetIVMById(args.ID)
+		if err != nil {
+			return err
+		}
+		return vm.StartVM(context.Background())
+	})
+
+	type InstanceStopOptions struct {
+		InstanceIdOptions
+		Force bool `help:"force stop instance"`
+	}
+
+	shellutils.R(&InstanceStopOptions{}, "instance-stop", "stop instance", func(cli *ovirt.SRegion, args *InstanceStopOptions) error {
+		vm, err := cli.GetIVMById(args.ID)
+		if err != nil {
+			return err
+		}
+		return vm.StopVM(context.Background(), &cloudprovider.ServerStopOptions{IsForce: args.Force})
+	})
+
+	shellutils.R(&InstanceIdOptions{}, "instance-delete", "delete instance", func(cli *ovirt.SRegion, args *InstanceIdOptions) error {
+		vm, err := cli.GetIVMById(args.ID)
+		if err != nil {
+			return err
+		}
+		return vm.DeleteVM(context.Background())
+	})
+
+	shellutils.R(&InstanceIdOptions{}, "instance-nic-list", "list instance nics", func(cli *ovirt.SRegion, args *InstanceIdOptions) error {
+		vm, err := cli.GetIVMById(args.ID)
+		if err != nil {
+			return err
+		}
+		nics, err := vm.GetINics()
+		if err != nil {
+			return err
+		}
+		printList(nics, 0, 0, 0, []string{})
+		return nil
+	})
+
+}
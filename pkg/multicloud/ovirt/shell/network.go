@@ -0,0 +1,46 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/onecloud/pkg/multicloud/ovirt"
+	"yunion.io/x/onecloud/pkg/util/shellutils"
+)
+
+func init() {
+	type NetworkListOptions struct {
+		Datacenter string `help:"datacenter id of networks"`
+	}
+	shellutils.R(&NetworkListOptions{}, "network-list", "list vnic profiles of vm networks", func(cli *ovirt.SRegion, args *NetworkListOptions) error {
+		networks, err := cli.GetNetworks(args.Datacenter)
+		if err != nil {
+			return err
+		}
+		printList(networks, 0, 0, 0, []string{})
+		return nil
+	})
+
+	type LogicalNetworkListOptions struct {
+	}
+	shellutils.R(&LogicalNetworkListOptions{}, "logical-network-list", "list logical networks", func(cli *ovirt.SRegion, args *LogicalNetworkListOptions) error {
+		networks, err := cli.GetLogicalNetworks()
+		if err != nil {
+			return err
+		}
+		printList(networks, 0, 0, 0, []string{})
+		return nil
+	})
+
+}
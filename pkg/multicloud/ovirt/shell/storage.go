@@ -0,0 +1,47 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/onecloud/pkg/multicloud/ovirt"
+	"yunion.io/x/onecloud/pkg/util/shellutils"
+)
+
+func init() {
+	type StorageListOptions struct {
+		Datacenter string `help:"datacenter id of storage domains"`
+	}
+	shellutils.R(&StorageListOptions{}, "storage-list", "list storage domains", func(cli *ovirt.SRegion, args *StorageListOptions) error {
+		storages, err := cli.GetStorages(args.Datacenter)
+		if err != nil {
+			return err
+		}
+		printList(storages, 0, 0, 0, []string{})
+		return nil
+	})
+
+	type DiskListOptions struct {
+		STORAGE string `help:"id of storage domain"`
+	}
+	shellutils.R(&DiskListOptions{}, "disk-list", "list disks of storage domain", func(cli *ovirt.SRegion, args *DiskListOptions) error {
+		disks, err := cli.GetDisks(args.STORAGE)
+		if err != nil {
+			return err
+		}
+		printList(disks, 0, 0, 0, []string{})
+		return nil
+	})
+
+}
@@ -0,0 +1,213 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+const (
+	STORAGE_DOMAIN_TYPE_DATA = "data"
+
+	STORAGE_DOMAIN_STATUS_ACTIVE = "active"
+)
+
+type SStorageInfo struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Path    string `json:"path"`
+}
+
+type SDataCenterRefs struct {
+	DataCenter []SIdRef `json:"data_center"`
+}
+
+// SStorage is the data storage domain of oVirt, which is shared by the hosts of the data center
+type SStorage struct {
+	multicloud.SStorageBase
+	multicloud.STagBase
+
+	zone *SZone
+
+	Id             string          `json:"id"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	Type           string          `json:"type"`
+	Status         string          `json:"status"`
+	ExternalStatus string          `json:"external_status"`
+	Available      int64           `json:"available"`
+	Used           int64           `json:"used"`
+	Committed      int64           `json:"committed"`
+	Master         bool            `json:"master"`
+	Storage        SStorageInfo    `json:"storage"`
+	StorageFormat  string          `json:"storage_format"`
+	DataCenters    SDataCenterRefs `json:"data_centers"`
+}
+
+func (self *SStorage) GetName() string {
+	return self.Name
+}
+
+func (self *SStorage) GetId() string {
+	return self.Id
+}
+
+func (self *SStorage) GetGlobalId() string {
+	return self.Id
+}
+
+func (self *SStorage) GetIDisks() ([]cloudprovider.ICloudDisk, error) {
+	disks, err := self.zone.region.GetDisks(self.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetDisks")
+	}
+	attachments, err := self.zone.region.getDiskAttachments()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getDiskAttachments")
+	}
+	ret := []cloudprovider.ICloudDisk{}
+	for i := range disks {
+		disks[i].storage = self
+		if attachment, ok := attachments[disks[i].Id]; ok {
+			disks[i].attachment = &attachment
+		}
+		ret = append(ret, &disks[i])
+	}
+	return ret, nil
+}
+
+func (self *SStorage) CreateIDisk(conf *cloudprovider.DiskCreateConfig) (cloudprovider.ICloudDisk, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SStorage) GetCapacityMB() int64 {
+	return (self.Available + self.Used) / 1024 / 1024
+}
+
+func (self *SStorage) GetCapacityUsedMB() int64 {
+	return self.Used / 1024 / 1024
+}
+
+func (self *SStorage) GetEnabled() bool {
+	return true
+}
+
+func (self *SStorage) GetIDiskById(id string) (cloudprovider.ICloudDisk, error) {
+	disk, err := self.zone.region.GetDisk(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetDisk(%s)", id)
+	}
+	if disk.getStorageId() != self.Id {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "disk %s is not in storage domain %s", id, self.Id)
+	}
+	disk.storage = self
+	return disk, nil
+}
+
+func (self *SStorage) GetIStoragecache() cloudprovider.ICloudStoragecache {
+	return &SStoragecache{region: self.zone.region, zone: self.zone}
+}
+
+// GetStorages returns the data storage domains attached to the data center,
+// the status of storage domain is only available in the data center
+func (self *SRegion) GetStorages(dcId string) ([]SStorage, error) {
+	storages := []SStorage{}
+	res := "storagedomains"
+	if len(dcId) > 0 {
+		res = fmt.Sprintf("datacenters/%s/storagedomains", dcId)
+	}
+	err := self.list(res, nil, "storage_domain", &storages)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list storage domains")
+	}
+	ret := []SStorage{}
+	for i := range storages {
+		if storages[i].Type != STORAGE_DOMAIN_TYPE_DATA {
+			continue
+		}
+		ret = append(ret, storages[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetStorage(id string) (*SStorage, error) {
+	storage := &SStorage{}
+	err := self.get(fmt.Sprintf("storagedomains/%s", id), nil, storage)
+	if err != nil {
+		return nil, err
+	}
+	if len(storage.DataCenters.DataCenter) > 0 {
+		res := fmt.Sprintf("datacenters/%s/storagedomains/%s", storage.DataCenters.DataCenter[0].Id, id)
+		err = self.get(res, nil, storage)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return storage, nil
+}
+
+func (self *SStorage) GetIZone() cloudprovider.ICloudZone {
+	return self.zone
+}
+
+func (self *SStorage) GetMediumType() string {
+	return api.DISK_TYPE_ROTATE
+}
+
+func (self *SStorage) GetMountPoint() string {
+	if len(self.Storage.Address) > 0 {
+		return fmt.Sprintf("%s:%s", self.Storage.Address, self.Storage.Path)
+	}
+	return self.Storage.Path
+}
+
+func (self *SStorage) GetStatus() string {
+	if self.Status == STORAGE_DOMAIN_STATUS_ACTIVE {
+		return api.STORAGE_ONLINE
+	}
+	return api.STORAGE_OFFLINE
+}
+
+func (self *SStorage) Refresh() error {
+	storage, err := self.zone.region.GetStorage(self.Id)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, storage)
+}
+
+func (self *SStorage) GetStorageConf() jsonutils.JSONObject {
+	conf := jsonutils.NewDict()
+	conf.Add(jsonutils.NewString(self.Storage.Type), "storage_type")
+	conf.Add(jsonutils.NewString(self.StorageFormat), "storage_format")
+	return conf
+}
+
+// GetStorageType returns the storage type of the storage domain, e.g. nfs, iscsi, fcp, glusterfs
+func (self *SStorage) GetStorageType() string {
+	return strings.ToLower(self.Storage.Type)
+}
+
+func (self *SStorage) IsSysDiskStore() bool {
+	return true
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+// SStoragecache is the storagecache of data center, which contains the vm templates of
+// the data center, a vm can be created only from the template of its data center
+type SStoragecache struct {
+	multicloud.SResourceBase
+	multicloud.STagBase
+
+	region *SRegion
+	zone   *SZone
+}
+
+func (self *SStoragecache) GetName() string {
+	return fmt.Sprintf("%s-templates", self.zone.GetName())
+}
+
+func (self *SStoragecache) GetId() string {
+	return self.GetGlobalId()
+}
+
+func (self *SStoragecache) GetGlobalId() string {
+	return fmt.Sprintf("%s/templates", self.zone.GetGlobalId())
+}
+
+func (self *SStoragecache) GetStatus() string {
+	return "available"
+}
+
+func (self *SStoragecache) GetICloudImages() ([]cloudprovider.ICloudImage, error) {
+	images, err := self.region.GetImages()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetImages")
+	}
+	clusters, err := self.region.GetClusters()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetClusters")
+	}
+	clusterDcs := map[string]string{}
+	for i := range clusters {
+		clusterDcs[clusters[i].Id] = clusters[i].DataCenter.Id
+	}
+	ret := []cloudprovider.ICloudImage{}
+	for i := range images {
+		dcId, err := self.region.getTemplateDataCenterId(&images[i], clusterDcs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getTemplateDataCenterId(%s)", images[i].Id)
+		}
+		if dcId != self.zone.Id {
+			continue
+		}
+		images[i].cache = self
+		ret = append(ret, &images[i])
+	}
+	return ret, nil
+}
+
+func (self *SStoragecache) GetICustomizedCloudImages() ([]cloudprovider.ICloudImage, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SStoragecache) GetIImageById(id string) (cloudprovider.ICloudImage, error) {
+	image, err := self.region.GetImage(id)
+	if err != nil {
+		return nil, err
+	}
+	dcId, err := self.region.getTemplateDataCenterId(image, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getTemplateDataCenterId(%s)", id)
+	}
+	if dcId != self.zone.Id {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "template %s is not in data center %s", id, self.zone.Id)
+	}
+	image.cache = self
+	return image, nil
+}
+
+func (self *SStoragecache) GetPath() string {
+	return ""
+}
+
+func (self *SStoragecache) CreateIImage(snapshotId, imageName, osType, imageDesc string) (cloudprovider.ICloudImage, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SStoragecache) DownloadImage(userCred mcclient.TokenCredential, imageId string, extId string, path string) (jsonutils.JSONObject, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+// UploadImage is not supported, the vm can be created only from the templates of oVirt
+func (self *SStoragecache) UploadImage(ctx context.Context, userCred mcclient.TokenCredential, opts *cloudprovider.SImageCreateOption, callback func(float32)) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+// getTemplateDataCenterId returns the data center of the cluster of template, or the data
+// center of the storage domain of template disks if the template has no cluster
+func (self *SRegion) getTemplateDataCenterId(image *SImage, clusterDcs map[string]string) (string, error) {
+	if len(image.Cluster.Id) > 0 {
+		if dcId, ok := clusterDcs[image.Cluster.Id]; ok {
+			return dcId, nil
+		}
+		cluster, err := self.GetCluster(image.Cluster.Id)
+		if err != nil {
+			return "", errors.Wrapf(err, "GetCluster(%s)", image.Cluster.Id)
+		}
+		return cluster.DataCenter.Id, nil
+	}
+	disks, err := self.GetTemplateDisks(image.Id)
+	if err != nil {
+		return "", errors.Wrapf(err, "GetTemplateDisks")
+	}
+	for i := range disks {
+		if len(disks[i].getStorageId()) == 0 {
+			continue
+		}
+		storage, err := self.GetStorage(disks[i].getStorageId())
+		if err != nil {
+			return "", errors.Wrapf(err, "GetStorage(%s)", disks[i].getStorageId())
+		}
+		if len(storage.DataCenters.DataCenter) > 0 {
+			return storage.DataCenters.DataCenter[0].Id, nil
+		}
+	}
+	return "", nil
+}
+
+func (self *SRegion) GetIStoragecaches() ([]cloudprovider.ICloudStoragecache, error) {
+	zones, err := self.getZones()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZones")
+	}
+	ret := []cloudprovider.ICloudStoragecache{}
+	for i := range zones {
+		ret = append(ret, &SStoragecache{region: self, zone: &zones[i]})
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIStoragecacheById(id string) (cloudprovider.ICloudStoragecache, error) {
+	caches, err := self.GetIStoragecaches()
+	if err != nil {
+		return nil, err
+	}
+	for i := range caches {
+		if caches[i].GetGlobalId() == id {
+			return caches[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
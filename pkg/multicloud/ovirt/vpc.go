@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+// SVpc is the emulated vpc of the data center, which contains the logical networks of the data center
+type SVpc struct {
+	multicloud.SVpc
+	multicloud.STagBase
+
+	zone *SZone
+}
+
+func (self *SVpc) GetName() string {
+	return self.zone.GetName()
+}
+
+func (self *SVpc) GetId() string {
+	return self.zone.GetId()
+}
+
+func (self *SVpc) GetGlobalId() string {
+	return self.zone.GetId()
+}
+
+func (self *SVpc) IsEmulated() bool {
+	return true
+}
+
+func (self *SVpc) Delete() error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SVpc) GetCidrBlock() string {
+	return ""
+}
+
+func (self *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
+	return []cloudprovider.ICloudRouteTable{}, nil
+}
+
+func (self *SVpc) GetIRouteTableById(routeTableId string) (cloudprovider.ICloudRouteTable, error) {
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SVpc) GetISecurityGroups() ([]cloudprovider.ICloudSecurityGroup, error) {
+	return []cloudprovider.ICloudSecurityGroup{}, nil
+}
+
+func (self *SVpc) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	return []cloudprovider.ICloudWire{&SWire{vpc: self}}, nil
+}
+
+func (self *SVpc) GetIWireById(wireId string) (cloudprovider.ICloudWire, error) {
+	wires, err := self.GetIWires()
+	if err != nil {
+		return nil, err
+	}
+	for i := range wires {
+		if wires[i].GetGlobalId() == wireId {
+			return wires[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SVpc) GetIsDefault() bool {
+	return false
+}
+
+func (self *SVpc) GetRegion() cloudprovider.ICloudRegion {
+	return self.zone.region
+}
+
+func (self *SVpc) GetStatus() string {
+	return api.VPC_STATUS_AVAILABLE
+}
+
+func (self *SVpc) Refresh() error {
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovirt
+
+import (
+	"fmt"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+const (
+	DATA_CENTER_STATUS_UP = "up"
+)
+
+type SDataCenter struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Local       bool   `json:"local"`
+}
+
+func (self *SRegion) GetDataCenters() ([]SDataCenter, error) {
+	dcs := []SDataCenter{}
+	err := self.list("datacenters", nil, "data_center", &dcs)
+	if err != nil {
+		return nil, err
+	}
+	return dcs, nil
+}
+
+func (self *SRegion) GetDataCenter(id string) (*SDataCenter, error) {
+	dc := &SDataCenter{}
+	err := self.get(fmt.Sprintf("datacenters/%s", id), nil, dc)
+	if err != nil {
+		return nil, err
+	}
+	return dc, nil
+}
+
+// SZone is the data center of oVirt
+type SZone struct {
+	multicloud.STagBase
+	multicloud.SResourceBase
+	SDataCenter
+
+	region *SRegion
+}
+
+func (self *SZone) GetName() string {
+	return self.Name
+}
+
+func (self *SZone) GetId() string {
+	return self.Id
+}
+
+func (self *SZone) GetGlobalId() string {
+	return fmt.Sprintf("%s/%s", self.region.GetGlobalId(), self.Id)
+}
+
+func (self *SZone) GetI18n() cloudprovider.SModelI18nTable {
+	table := cloudprovider.SModelI18nTable{}
+	table["name"] = cloudprovider.NewSModelI18nEntry(self.GetName()).CN(self.GetName())
+	return table
+}
+
+func (self *SZone) GetIRegion() cloudprovider.ICloudRegion {
+	return self.region
+}
+
+func (self *SZone) GetStatus() string {
+	if self.Status == DATA_CENTER_STATUS_UP {
+		return api.ZONE_ENABLE
+	}
+	return api.ZONE_DISABLE
+}
+
+func (self *SZone) getVpc() *SVpc {
+	return &SVpc{zone: self}
+}
+
+func (self *SZone) GetIHosts() ([]cloudprovider.ICloudHost, error) {
+	hosts, err := self.region.GetHosts(self.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetHosts")
+	}
+	ret := []cloudprovider.ICloudHost{}
+	for i := range hosts {
+		hosts[i].zone = self
+		ret = append(ret, &hosts[i])
+	}
+	return ret, nil
+}
+
+func (self *SZone) GetIHostById(id string) (cloudprovider.ICloudHost, error) {
+	hosts, err := self.GetIHosts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range hosts {
+		if hosts[i].GetGlobalId() == id {
+			return hosts[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SZone) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	storages, err := self.region.GetStorages(self.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetStorages")
+	}
+	ret := []cloudprovider.ICloudStorage{}
+	for i := range storages {
+		storages[i].zone = self
+		ret = append(ret, &storages[i])
+	}
+	return ret, nil
+}
+
+func (self *SZone) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	storages, err := self.GetIStorages()
+	if err != nil {
+		return nil, err
+	}
+	for i := range storages {
+		if storages[i].GetGlobalId() == id {
+			return storages[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+// SDisk is the volume of storage, whose id is the volume id, e.g. local-lvm:vm-100-disk-0
+type SDisk struct {
+	multicloud.SDisk
+	multicloud.STagBase
+
+	storage *SStorage
+
+	VolId  string `json:"volid"`
+	Format string `json:"format"`
+	Size   int64  `json:"size"`
+	Used   int64  `json:"used"`
+	VmId   int    `json:"vmid"`
+
+	SizeMb int64
+
+	key   string
+	isSys bool
+}
+
+// getStorageName returns the storage name of the volume id or the storage id, e.g.
+// local-lvm of local-lvm:vm-100-disk-0, or local-lvm of pve1/local-lvm
+func getStorageName(id string) string {
+	if idx := strings.Index(id, ":"); idx >= 0 {
+		return id[:idx]
+	}
+	if idx := strings.LastIndex(id, "/"); idx >= 0 {
+		return id[idx+1:]
+	}
+	return id
+}
+
+func (self *SDisk) GetName() string {
+	if idx := strings.Index(self.VolId, ":"); idx >= 0 {
+		return self.VolId[idx+1:]
+	}
+	return self.VolId
+}
+
+func (self *SDisk) GetId() string {
+	return self.VolId
+}
+
+func (self *SDisk) GetGlobalId() string {
+	return self.VolId
+}
+
+func (self *SDisk) CreateISnapshot(ctx context.Context, name, desc string) (cloudprovider.ICloudSnapshot, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) Delete(ctx context.Context) error {
+	return self.storage.zone.region.DeleteDisk(self.storage.Node, self.storage.Storage, self.VolId)
+}
+
+func (self *SDisk) GetAccessPath() string {
+	return ""
+}
+
+func (self *SDisk) GetCacheMode() string {
+	return "none"
+}
+
+func (self *SDisk) GetFsFormat() string {
+	return ""
+}
+
+func (self *SDisk) GetIsNonPersistent() bool {
+	return false
+}
+
+func (self *SDisk) GetDriver() string {
+	if matches := diskKeyRegexp.FindStringSubmatch(self.key); len(matches) > 1 {
+		return matches[1]
+	}
+	return "scsi"
+}
+
+func (self *SDisk) getInstance() (*SInstance, error) {
+	if self.VmId <= 0 {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "disk %s is not attached", self.VolId)
+	}
+	return self.storage.zone.region.GetInstance(strconv.Itoa(self.VmId))
+}
+
+// getDiskKey returns the key of the disk in the config of vm, e.g. scsi0
+func (self *SDisk) getDiskKey(ins *SInstance) string {
+	for _, key := range ins.getDiskKeys() {
+		if volId, _ := parseOptions(ins.Config[key], "file"); volId == self.VolId {
+			return key
+		}
+	}
+	return ""
+}
+
+func (self *SDisk) GetDiskType() string {
+	if len(self.key) > 0 {
+		if self.isSys {
+			return api.DISK_TYPE_SYS
+		}
+		return api.DISK_TYPE_DATA
+	}
+	ins, err := self.getInstance()
+	if err != nil {
+		return api.DISK_TYPE_DATA
+	}
+	if keys := ins.getDiskKeys(); len(keys) > 0 && keys[0] == self.getDiskKey(ins) {
+		return api.DISK_TYPE_SYS
+	}
+	return api.DISK_TYPE_DATA
+}
+
+func (self *SDisk) GetDiskFormat() string {
+	if len(self.Format) > 0 {
+		return self.Format
+	}
+	if strings.HasSuffix(self.VolId, ".qcow2") {
+		return "qcow2"
+	}
+	if strings.HasSuffix(self.VolId, ".vmdk") {
+		return "vmdk"
+	}
+	return "raw"
+}
+
+func (self *SDisk) GetDiskSizeMB() int {
+	if self.SizeMb > 0 {
+		return int(self.SizeMb)
+	}
+	return int(self.Size / 1024 / 1024)
+}
+
+func (self *SDisk) GetIsAutoDelete() bool {
+	return true
+}
+
+func (self *SDisk) GetMountpoint() string {
+	return ""
+}
+
+func (self *SDisk) GetStatus() string {
+	return api.DISK_READY
+}
+
+func (self *SDisk) Rebuild(ctx context.Context) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) Reset(ctx context.Context, snapshotId string) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) Resize(ctx context.Context, sizeMb int64) error {
+	ins, err := self.getInstance()
+	if err != nil {
+		return errors.Wrapf(err, "getInstance")
+	}
+	key := self.getDiskKey(ins)
+	if len(key) == 0 {
+		return errors.Wrapf(cloudprovider.ErrNotFound, "disk %s of vm %d", self.VolId, self.VmId)
+	}
+	return self.storage.zone.region.ResizeDisk(ins.Node, ins.VmId, key, int((sizeMb+1023)/1024))
+}
+
+func (self *SDisk) GetTemplateId() string {
+	return ""
+}
+
+func (self *SDisk) GetIStorage() (cloudprovider.ICloudStorage, error) {
+	return self.storage, nil
+}
+
+func (self *SDisk) GetISnapshot(snapshotId string) (cloudprovider.ICloudSnapshot, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) GetISnapshots() ([]cloudprovider.ICloudSnapshot, error) {
+	return []cloudprovider.ICloudSnapshot{}, nil
+}
+
+func (self *SRegion) GetDisks(node, storage string) ([]SDisk, error) {
+	disks := []SDisk{}
+	params := url.Values{}
+	params.Set("content", "images")
+	err := self.get(fmt.Sprintf("nodes/%s/storage/%s/content", node, storage), params, &disks)
+	if err != nil {
+		return nil, err
+	}
+	return disks, nil
+}
+
+// ResizeDisk grows the disk of vm to sizeGb, the disk can not be shrunk
+func (self *SRegion) ResizeDisk(node string, vmId int, key string, sizeGb int) error {
+	params := url.Values{}
+	params.Set("disk", key)
+	params.Set("size", fmt.Sprintf("%dG", sizeGb))
+	resp, err := self.put(fmt.Sprintf("nodes/%s/qemu/%d/resize", node, vmId), params)
+	if err != nil {
+		return err
+	}
+	return self.cli.waitResult(resp)
+}
+
+func (self *SRegion) DeleteDisk(node, storage, volId string) error {
+	resp, err := self.delete(fmt.Sprintf("nodes/%s/storage/%s/content/%s", node, storage, url.PathEscape(volId)), nil)
+	if err != nil {
+		return err
+	}
+	return self.cli.waitResult(resp)
+}
@@ -0,0 +1 @@
+package proxmox // import "yunion.io/x/onecloud/pkg/multicloud/proxmox"
@@ -0,0 +1,320 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+const (
+	NODE_STATUS_ONLINE = "online"
+)
+
+type SCpuInfo struct {
+	Model   string `json:"model"`
+	Cpus    int    `json:"cpus"`
+	Sockets int    `json:"sockets"`
+	Cores   int    `json:"cores"`
+	Mhz     string `json:"mhz"`
+}
+
+type SMemoryInfo struct {
+	Total int64 `json:"total"`
+	Used  int64 `json:"used"`
+	Free  int64 `json:"free"`
+}
+
+type SNodeStatus struct {
+	CpuInfo    SCpuInfo    `json:"cpuinfo"`
+	Memory     SMemoryInfo `json:"memory"`
+	PveVersion string      `json:"pveversion"`
+	KVersion   string      `json:"kversion"`
+	Uptime     int64       `json:"uptime"`
+}
+
+type SHost struct {
+	multicloud.STagBase
+	multicloud.SHostBase
+
+	zone *SZone
+
+	Id      string  `json:"id"`
+	Node    string  `json:"node"`
+	Status  string  `json:"status"`
+	MaxCpu  int     `json:"maxcpu"`
+	MaxMem  int64   `json:"maxmem"`
+	MaxDisk int64   `json:"maxdisk"`
+	Cpu     float64 `json:"cpu"`
+	Mem     int64   `json:"mem"`
+	Disk    int64   `json:"disk"`
+
+	Ip         string      `json:"ip"`
+	NodeStatus SNodeStatus `json:"node_status"`
+}
+
+// GetHosts returns the nodes of cluster with the status and the address of nodes
+func (self *SRegion) GetHosts() ([]SHost, error) {
+	hosts := []SHost{}
+	err := self.cli.getClusterResources("node", &hosts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get nodes")
+	}
+	status, err := self.GetClusterStatus()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetClusterStatus")
+	}
+	ips := map[string]string{}
+	for _, s := range status {
+		if s.Type == CLUSTER_STATUS_TYPE_NODE {
+			ips[s.Name] = s.Ip
+		}
+	}
+	for i := range hosts {
+		hosts[i].Ip = ips[hosts[i].Node]
+		if hosts[i].Status != NODE_STATUS_ONLINE {
+			continue
+		}
+		nodeStatus, err := self.GetNodeStatus(hosts[i].Node)
+		if err != nil {
+			log.Errorf("GetNodeStatus(%s) error: %v", hosts[i].Node, err)
+			continue
+		}
+		hosts[i].NodeStatus = *nodeStatus
+	}
+	return hosts, nil
+}
+
+func (self *SRegion) GetHost(node string) (*SHost, error) {
+	hosts, err := self.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range hosts {
+		if hosts[i].Node == node {
+			return &hosts[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, "node %s", node)
+}
+
+func (self *SRegion) GetNodeStatus(node string) (*SNodeStatus, error) {
+	status := &SNodeStatus{}
+	return status, self.get(fmt.Sprintf("nodes/%s/status", node), nil, status)
+}
+
+func (self *SHost) GetName() string {
+	return self.Node
+}
+
+func (self *SHost) GetId() string {
+	return self.Node
+}
+
+func (self *SHost) GetGlobalId() string {
+	return self.Node
+}
+
+func (self *SHost) CreateVM(opts *cloudprovider.SManagedVMCreateConfig) (cloudprovider.ICloudVM, error) {
+	vm, err := self.zone.region.CreateInstance(self.Node, opts)
+	if err != nil {
+		return nil, err
+	}
+	vm.host = self
+	return vm, nil
+}
+
+func (self *SHost) GetAccessIp() string {
+	return self.Ip
+}
+
+func (self *SHost) GetAccessMac() string {
+	return ""
+}
+
+func (self *SHost) GetCpuCmtbound() float32 {
+	return 8.0
+}
+
+func (self *SHost) GetMemCmtbound() float32 {
+	return 1.0
+}
+
+func (self *SHost) GetCpuCount() int {
+	if self.NodeStatus.CpuInfo.Cpus > 0 {
+		return self.NodeStatus.CpuInfo.Cpus
+	}
+	return self.MaxCpu
+}
+
+func (self *SHost) GetNodeCount() int8 {
+	return int8(self.NodeStatus.CpuInfo.Sockets)
+}
+
+func (self *SHost) GetEnabled() bool {
+	return true
+}
+
+func (self *SHost) GetCpuDesc() string {
+	return self.NodeStatus.CpuInfo.Model
+}
+
+func (self *SHost) GetCpuMhz() int {
+	mhz, _ := strconv.ParseFloat(self.NodeStatus.CpuInfo.Mhz, 64)
+	return int(mhz)
+}
+
+func (self *SHost) GetMemSizeMB() int {
+	return int(self.MaxMem / 1024 / 1024)
+}
+
+func (self *SHost) GetStorageSizeMB() int {
+	return int(self.MaxDisk / 1024 / 1024)
+}
+
+func (self *SHost) GetStorageType() string {
+	return api.DISK_TYPE_HYBRID
+}
+
+func (self *SHost) GetHostType() string {
+	return api.HOST_TYPE_PROXMOX
+}
+
+func (self *SHost) GetHostStatus() string {
+	if self.Status == NODE_STATUS_ONLINE {
+		return api.HOST_ONLINE
+	}
+	return api.HOST_OFFLINE
+}
+
+func (self *SHost) GetIHostNics() ([]cloudprovider.ICloudHostNetInterface, error) {
+	return []cloudprovider.ICloudHostNetInterface{}, nil
+}
+
+func (self *SHost) GetIsMaintenance() bool {
+	return false
+}
+
+// GetVersion returns the version of pve-manager, e.g. 7.4-3 of pve-manager/7.4-3/9002ab8a
+func (self *SHost) GetVersion() string {
+	if parts := strings.Split(self.NodeStatus.PveVersion, "/"); len(parts) > 1 {
+		return parts[1]
+	}
+	return self.NodeStatus.PveVersion
+}
+
+func (self *SHost) GetStatus() string {
+	if self.Status == NODE_STATUS_ONLINE {
+		return api.HOST_STATUS_RUNNING
+	}
+	return api.HOST_STATUS_UNKNOWN
+}
+
+func (self *SHost) GetSN() string {
+	return ""
+}
+
+func (self *SHost) GetSysInfo() jsonutils.JSONObject {
+	info := jsonutils.NewDict()
+	info.Add(jsonutils.NewString(CLOUD_PROVIDER_PROXMOX), "manufacture")
+	return info
+}
+
+func (self *SHost) IsEmulated() bool {
+	return false
+}
+
+func (self *SHost) Refresh() error {
+	host, err := self.zone.region.GetHost(self.Node)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, host)
+}
+
+func (self *SHost) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	storages, err := self.zone.region.GetStorages(self.Node)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetStorages")
+	}
+	ret := []cloudprovider.ICloudStorage{}
+	for i := range storages {
+		storages[i].zone = self.zone
+		ret = append(ret, &storages[i])
+	}
+	return ret, nil
+}
+
+func (self *SHost) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	storages, err := self.GetIStorages()
+	if err != nil {
+		return nil, err
+	}
+	for i := range storages {
+		if storages[i].GetGlobalId() == id {
+			return storages[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SHost) GetIVMs() ([]cloudprovider.ICloudVM, error) {
+	vms, err := self.zone.region.GetInstances(self.Node)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetInstances")
+	}
+	ret := []cloudprovider.ICloudVM{}
+	for i := range vms {
+		vms[i].host = self
+		ret = append(ret, &vms[i])
+	}
+	return ret, nil
+}
+
+func (self *SHost) GetIVMById(id string) (cloudprovider.ICloudVM, error) {
+	vm, err := self.zone.region.GetInstance(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetInstance")
+	}
+	if vm.Node != self.Node {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "vm %s is on node %s, not %s", id, vm.Node, self.Node)
+	}
+	vm.host = self
+	return vm, nil
+}
+
+func (self *SHost) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	vpcs, err := self.zone.region.GetVpcs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetVpcs")
+	}
+	ret := []cloudprovider.ICloudWire{}
+	for i := range vpcs {
+		if !vpcs[i].isOnNode(self.Node) {
+			continue
+		}
+		ret = append(ret, vpcs[i].getWire())
+	}
+	return ret, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+	"yunion.io/x/onecloud/pkg/util/imagetools"
+)
+
+// SImage is the vm template of proxmox, the vm is created by cloning the template
+type SImage struct {
+	multicloud.STagBase
+	multicloud.SImageBase
+
+	cache *SStoragecache
+
+	VmId     int    `json:"vmid"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Node     string `json:"node"`
+	MaxDisk  int64  `json:"maxdisk"`
+	Template int    `json:"template"`
+
+	Config map[string]string `json:"config"`
+}
+
+func (self *SImage) GetName() string {
+	return self.Name
+}
+
+func (self *SImage) GetId() string {
+	return strconv.Itoa(self.VmId)
+}
+
+func (self *SImage) GetGlobalId() string {
+	return self.GetId()
+}
+
+func (self *SImage) Refresh() error {
+	image, err := self.cache.region.GetImage(self.GetGlobalId())
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, image)
+}
+
+func (self *SImage) GetIStoragecache() cloudprovider.ICloudStoragecache {
+	return self.cache
+}
+
+// isOnStorages returns true if all disks of the template are on the storages
+func (self *SImage) isOnStorages(storages []string) bool {
+	found := false
+	for k, v := range self.Config {
+		if !diskKeyRegexp.MatchString(k) {
+			continue
+		}
+		volId, opts := parseOptions(v, "file")
+		if opts["media"] == "cdrom" || volId == "none" {
+			continue
+		}
+		if !utils.IsInStringArray(getStorageName(volId), storages) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+func (self *SImage) GetImageFormat() string {
+	return "raw"
+}
+
+func (self *SImage) GetStatus() string {
+	return api.CACHED_IMAGE_STATUS_ACTIVE
+}
+
+func (self *SImage) GetImageStatus() string {
+	return cloudprovider.IMAGE_STATUS_ACTIVE
+}
+
+func (self *SImage) GetImageType() cloudprovider.TImageType {
+	return cloudprovider.ImageTypeCustomized
+}
+
+func (self *SImage) GetCreatedAt() time.Time {
+	return time.Time{}
+}
+
+func (self *SImage) Delete(ctx context.Context) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SImage) GetMinOsDiskSizeGb() int {
+	return int(self.MaxDisk / 1024 / 1024 / 1024)
+}
+
+func (self *SImage) GetSizeByte() int64 {
+	return self.MaxDisk
+}
+
+func (self *SImage) GetOsType() cloudprovider.TOsType {
+	if ostype := self.Config["ostype"]; strings.HasPrefix(ostype, "win") || strings.HasPrefix(ostype, "w2k") {
+		return cloudprovider.OsTypeWindows
+	}
+	return cloudprovider.OsTypeLinux
+}
+
+func (self *SImage) GetOsDist() string {
+	return imagetools.NormalizeImageInfo(self.Name, "x86_64", string(self.GetOsType()), "", "").OsDistro
+}
+
+func (self *SImage) GetOsVersion() string {
+	return imagetools.NormalizeImageInfo(self.Name, "x86_64", string(self.GetOsType()), "", "").OsVersion
+}
+
+func (self *SImage) UEFI() bool {
+	return self.Config["bios"] == "ovmf"
+}
+
+func (self *SImage) GetOsArch() string {
+	return "x86_64"
+}
+
+func (self *SImage) GetMinRamSizeMb() int {
+	return 0
+}
+
+func (self *SRegion) GetImages() ([]SImage, error) {
+	vms := []SImage{}
+	err := self.cli.getClusterResources("vm", &vms)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get vms")
+	}
+	ret := []SImage{}
+	for i := range vms {
+		if vms[i].Type != VM_TYPE_QEMU || vms[i].Template == 0 {
+			continue
+		}
+		vms[i].Config, err = self.GetInstanceConfig(vms[i].Node, vms[i].VmId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetInstanceConfig(%d)", vms[i].VmId)
+		}
+		ret = append(ret, vms[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetImage(id string) (*SImage, error) {
+	images, err := self.GetImages()
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		if images[i].GetGlobalId() == id {
+			return &images[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, "template %s", id)
+}
@@ -0,0 +1,718 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/apis/webconsole"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	VM_TYPE_QEMU = "qemu"
+
+	VM_STATUS_RUNNING = "running"
+	VM_STATUS_STOPPED = "stopped"
+
+	VM_LOCK_MIGRATE = "migrate"
+)
+
+var (
+	diskKeyRegexp = regexp.MustCompile(`^(ide|sata|scsi|virtio)(\d+)$`)
+	netKeyRegexp  = regexp.MustCompile(`^net(\d+)$`)
+)
+
+type SInstance struct {
+	multicloud.STagBase
+	multicloud.SInstanceBase
+
+	host *SHost
+
+	Id       string `json:"id"`
+	Type     string `json:"type"`
+	VmId     int    `json:"vmid"`
+	Name     string `json:"name"`
+	Node     string `json:"node"`
+	Status   string `json:"status"`
+	MaxCpu   int    `json:"maxcpu"`
+	MaxMem   int64  `json:"maxmem"`
+	MaxDisk  int64  `json:"maxdisk"`
+	Template int    `json:"template"`
+	Lock     string `json:"lock"`
+
+	// Config is the configuration of qemu vm, e.g. cores, memory, scsi0, net0
+	Config map[string]string `json:"config"`
+}
+
+// parseOptions parses the property string of proxmox, e.g.
+// local-lvm:vm-100-disk-0,size=32G or virtio=BC:24:11:2A:3B:4C,bridge=vmbr0,
+// the first item without the key is returned as the default value
+func parseOptions(value, defaultKey string) (string, map[string]string) {
+	ret, def := map[string]string{}, ""
+	for i, opt := range strings.Split(value, ",") {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) == 1 {
+			if i == 0 {
+				def = kv[0]
+			}
+			continue
+		}
+		ret[kv[0]] = kv[1]
+	}
+	if len(def) == 0 && len(defaultKey) > 0 {
+		def = ret[defaultKey]
+	}
+	return def, ret
+}
+
+// parseSizeMb parses the disk size of proxmox, e.g. 32G, 512M
+func parseSizeMb(size string) int64 {
+	if len(size) == 0 {
+		return 0
+	}
+	unit := size[len(size)-1]
+	num, err := strconv.ParseFloat(strings.TrimRight(size, "KMGTkmgt"), 64)
+	if err != nil {
+		return 0
+	}
+	switch unit {
+	case 'K', 'k':
+		return int64(num / 1024)
+	case 'M', 'm':
+		return int64(num)
+	case 'G', 'g':
+		return int64(num * 1024)
+	case 'T', 't':
+		return int64(num * 1024 * 1024)
+	}
+	return int64(num / 1024 / 1024)
+}
+
+func (self *SRegion) getVmResources() ([]SInstance, error) {
+	vms := []SInstance{}
+	err := self.cli.getClusterResources("vm", &vms)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get vms")
+	}
+	return vms, nil
+}
+
+func (self *SRegion) GetInstanceConfig(node string, vmId int) (map[string]string, error) {
+	res := fmt.Sprintf("nodes/%s/qemu/%d/config", node, vmId)
+	resp, err := self.cli.request(httputils.GET, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]string{}
+	dict, ok := resp.(*jsonutils.JSONDict)
+	if !ok {
+		return config, nil
+	}
+	for k, v := range dict.Value() {
+		config[k], _ = v.GetString()
+	}
+	return config, nil
+}
+
+// GetInstances returns the qemu vms of node, the templates are listed as images
+func (self *SRegion) GetInstances(node string) ([]SInstance, error) {
+	vms, err := self.getVmResources()
+	if err != nil {
+		return nil, err
+	}
+	ret := []SInstance{}
+	for i := range vms {
+		if vms[i].Type != VM_TYPE_QEMU || vms[i].Template > 0 {
+			continue
+		}
+		if len(node) > 0 && vms[i].Node != node {
+			continue
+		}
+		vms[i].Config, err = self.GetInstanceConfig(vms[i].Node, vms[i].VmId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetInstanceConfig(%d)", vms[i].VmId)
+		}
+		ret = append(ret, vms[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetInstance(id string) (*SInstance, error) {
+	vms, err := self.getVmResources()
+	if err != nil {
+		return nil, err
+	}
+	for i := range vms {
+		if vms[i].Type != VM_TYPE_QEMU || vms[i].Template > 0 || strconv.Itoa(vms[i].VmId) != id {
+			continue
+		}
+		vms[i].Config, err = self.GetInstanceConfig(vms[i].Node, vms[i].VmId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetInstanceConfig(%s)", id)
+		}
+		return &vms[i], nil
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, "vm %s", id)
+}
+
+func (self *SInstance) GetName() string {
+	if name, ok := self.Config["name"]; ok {
+		return name
+	}
+	return self.Name
+}
+
+func (self *SInstance) GetId() string {
+	return strconv.Itoa(self.VmId)
+}
+
+func (self *SInstance) GetGlobalId() string {
+	return self.GetId()
+}
+
+func (self *SInstance) getRegion() *SRegion {
+	return self.host.zone.region
+}
+
+func (self *SInstance) getResource(res string) string {
+	return fmt.Sprintf("nodes/%s/qemu/%d/%s", self.Node, self.VmId, res)
+}
+
+func (self *SInstance) setConfig(params url.Values) error {
+	return self.getRegion().postAndWait(self.getResource("config"), params)
+}
+
+func (self *SInstance) Refresh() error {
+	ins, err := self.getRegion().GetInstance(self.GetGlobalId())
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, ins)
+}
+
+func (self *SInstance) AssignSecurityGroup(id string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+// getDiskKeys returns the keys of disks in the boot order, the cdroms are skipped
+func (self *SInstance) getDiskKeys() []string {
+	keys := []string{}
+	for k, v := range self.Config {
+		if !diskKeyRegexp.MatchString(k) {
+			continue
+		}
+		volId, opts := parseOptions(v, "file")
+		if opts["media"] == "cdrom" || volId == "none" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	bootDisk := self.Config["bootdisk"]
+	if boot := self.Config["boot"]; strings.HasPrefix(boot, "order=") {
+		for _, dev := range strings.Split(strings.TrimPrefix(boot, "order="), ";") {
+			if utils.IsInStringArray(dev, keys) {
+				bootDisk = dev
+				break
+			}
+		}
+	}
+	ret := []string{}
+	if utils.IsInStringArray(bootDisk, keys) {
+		ret = append(ret, bootDisk)
+	}
+	for _, k := range keys {
+		if k != bootDisk {
+			ret = append(ret, k)
+		}
+	}
+	return ret
+}
+
+// getFreeDiskKey returns the first unused key of the bus, e.g. scsi1
+func (self *SInstance) getFreeDiskKey(bus string) string {
+	if !utils.IsInStringArray(bus, []string{"ide", "sata", "scsi", "virtio"}) {
+		bus = "scsi"
+	}
+	for i := 0; ; i++ {
+		key := fmt.Sprintf("%s%d", bus, i)
+		if _, ok := self.Config[key]; !ok {
+			return key
+		}
+	}
+}
+
+func (self *SInstance) CreateDisk(ctx context.Context, opts *cloudprovider.GuestDiskCreateOptions) (string, error) {
+	key := self.getFreeDiskKey(opts.Driver)
+	sizeGb := (opts.SizeMb + 1023) / 1024
+	params := url.Values{}
+	params.Set(key, fmt.Sprintf("%s:%d", getStorageName(opts.StorageId), sizeGb))
+	err := self.setConfig(params)
+	if err != nil {
+		return "", errors.Wrapf(err, "add disk %s", key)
+	}
+	err = self.Refresh()
+	if err != nil {
+		return "", errors.Wrapf(err, "Refresh")
+	}
+	volId, _ := parseOptions(self.Config[key], "file")
+	return volId, nil
+}
+
+func (self *SInstance) AttachDisk(ctx context.Context, diskId string) error {
+	params := url.Values{}
+	params.Set(self.getFreeDiskKey("scsi"), diskId)
+	return self.setConfig(params)
+}
+
+func (self *SInstance) DetachDisk(ctx context.Context, diskId string) error {
+	for _, key := range self.getDiskKeys() {
+		volId, _ := parseOptions(self.Config[key], "file")
+		if volId == diskId {
+			params := url.Values{}
+			params.Set("delete", key)
+			return self.setConfig(params)
+		}
+	}
+	return nil
+}
+
+func (self *SInstance) ChangeConfig(ctx context.Context, opts *cloudprovider.SManagedVMChangeConfig) error {
+	params := url.Values{}
+	if opts.Cpu > 0 {
+		sockets, _ := strconv.Atoi(self.Config["sockets"])
+		if sockets <= 0 || opts.Cpu%sockets != 0 {
+			sockets = 1
+		}
+		params.Set("sockets", strconv.Itoa(sockets))
+		params.Set("cores", strconv.Itoa(opts.Cpu/sockets))
+	}
+	if opts.MemoryMB > 0 {
+		params.Set("memory", strconv.Itoa(opts.MemoryMB))
+	}
+	return self.setConfig(params)
+}
+
+func (self *SInstance) DeleteVM(ctx context.Context) error {
+	return self.getRegion().DeleteVM(self.Node, self.VmId)
+}
+
+// DeployVM resets the password and ssh keys by cloud-init, which is effective
+// only for the vm with the cloud-init drive
+func (self *SInstance) DeployVM(ctx context.Context, name string, username string, password string, publicKey string, deleteKeypair bool, description string) error {
+	params := url.Values{}
+	if len(name) > 0 {
+		params.Set("name", name)
+	}
+	if len(description) > 0 {
+		params.Set("description", description)
+	}
+	if len(username) > 0 {
+		params.Set("ciuser", username)
+	}
+	if len(password) > 0 {
+		params.Set("cipassword", password)
+	}
+	if len(publicKey) > 0 {
+		params.Set("sshkeys", url.PathEscape(publicKey))
+	} else if deleteKeypair {
+		params.Set("delete", "sshkeys")
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return self.setConfig(params)
+}
+
+func (self *SInstance) GetBios() string {
+	if self.Config["bios"] == "ovmf" {
+		return "UEFI"
+	}
+	return "BIOS"
+}
+
+func (self *SInstance) GetBootOrder() string {
+	return "dcn"
+}
+
+func (self *SInstance) GetError() error {
+	return nil
+}
+
+func (self *SInstance) GetHostname() string {
+	return self.GetName()
+}
+
+func (self *SInstance) GetHypervisor() string {
+	return api.HYPERVISOR_PROXMOX
+}
+
+func (self *SInstance) GetIDisks() ([]cloudprovider.ICloudDisk, error) {
+	storages, err := self.host.GetIStorages()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetIStorages")
+	}
+	ret := []cloudprovider.ICloudDisk{}
+	for i, key := range self.getDiskKeys() {
+		volId, opts := parseOptions(self.Config[key], "file")
+		disk := &SDisk{
+			VolId:  volId,
+			VmId:   self.VmId,
+			SizeMb: parseSizeMb(opts["size"]),
+			key:    key,
+			isSys:  i == 0,
+		}
+		for j := range storages {
+			if storages[j].GetName() == getStorageName(volId) {
+				disk.storage = storages[j].(*SStorage)
+				break
+			}
+		}
+		if disk.storage == nil {
+			log.Errorf("can not found storage of disk %s", volId)
+			continue
+		}
+		ret = append(ret, disk)
+	}
+	return ret, nil
+}
+
+func (self *SInstance) GetIEIP() (cloudprovider.ICloudEIP, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) GetIHost() cloudprovider.ICloudHost {
+	return self.host
+}
+
+func (self *SInstance) GetINics() ([]cloudprovider.ICloudNic, error) {
+	keys := []string{}
+	for k := range self.Config {
+		if netKeyRegexp.MatchString(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	addrs := map[string][]string{}
+	if self.Status == VM_STATUS_RUNNING && self.isAgentEnabled() {
+		ifaces, err := self.getRegion().GetInstanceInterfaces(self.Node, self.VmId)
+		if err != nil {
+			log.Warningf("GetInstanceInterfaces(%d) error: %v", self.VmId, err)
+		}
+		for _, iface := range ifaces {
+			mac := strings.ToLower(iface.HardwareAddress)
+			for _, addr := range iface.IpAddresses {
+				if addr.IpAddressType == "ipv4" {
+					addrs[mac] = append(addrs[mac], addr.IpAddress)
+				}
+			}
+		}
+	}
+	ret := []cloudprovider.ICloudNic{}
+	for _, key := range keys {
+		nic := &SInstanceNic{ins: self, key: key}
+		_, opts := parseOptions(self.Config[key], "")
+		for k, v := range opts {
+			switch k {
+			case "bridge":
+				nic.Bridge = v
+			case "tag":
+				nic.Tag, _ = strconv.Atoi(v)
+			case "firewall", "link_down", "mtu", "queues", "rate", "trunks":
+			default:
+				nic.Model, nic.MacAddress = k, strings.ToLower(v)
+			}
+		}
+		nic.IpAddresses = addrs[nic.MacAddress]
+		ret = append(ret, nic)
+	}
+	return ret, nil
+}
+
+func (self *SInstance) isAgentEnabled() bool {
+	enabled, opts := parseOptions(self.Config["agent"], "enabled")
+	return enabled == "1" || opts["enabled"] == "1"
+}
+
+func (self *SInstance) GetInstanceType() string {
+	return fmt.Sprintf("ecs.g1.c%dm%d", self.GetVcpuCount(), self.GetVmemSizeMB()/1024)
+}
+
+func (self *SInstance) GetMachine() string {
+	if strings.Contains(self.Config["machine"], "q35") {
+		return "q35"
+	}
+	return "pc"
+}
+
+func (self *SInstance) GetStatus() string {
+	if self.Lock == VM_LOCK_MIGRATE {
+		return api.VM_MIGRATING
+	}
+	switch self.Status {
+	case VM_STATUS_RUNNING:
+		return api.VM_RUNNING
+	case VM_STATUS_STOPPED:
+		return api.VM_READY
+	}
+	return api.VM_UNKNOWN
+}
+
+func (self *SInstance) GetOSName() string {
+	return ""
+}
+
+// GetOsType returns the os type by the ostype of config, e.g. l26, win10
+func (self *SInstance) GetOsType() cloudprovider.TOsType {
+	if strings.HasPrefix(self.Config["ostype"], "win") || strings.HasPrefix(self.Config["ostype"], "w2k") {
+		return cloudprovider.OsTypeWindows
+	}
+	return cloudprovider.OsTypeLinux
+}
+
+func (self *SInstance) GetProjectId() string {
+	return ""
+}
+
+func (self *SInstance) GetSecurityGroupIds() ([]string, error) {
+	return []string{}, nil
+}
+
+// GetVNCInfo starts the vnc proxy of the vm, the console connects to the
+// vncwebsocket api with the port and ticket of the proxy, the ticket is also
+// the password of vnc authentication
+func (self *SInstance) GetVNCInfo(input *cloudprovider.ServerVncInput) (*cloudprovider.ServerVncOutput, error) {
+	params := url.Values{}
+	params.Set("websocket", "1")
+	resp, err := self.getRegion().post(self.getResource("vncproxy"), params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "vncproxy")
+	}
+	ticket, err := resp.GetString("ticket")
+	if err != nil {
+		return nil, errors.Wrapf(err, "get vnc ticket")
+	}
+	port, err := resp.Int("port")
+	if err != nil {
+		return nil, errors.Wrapf(err, "get vnc port")
+	}
+	query := url.Values{}
+	query.Set("port", fmt.Sprintf("%d", port))
+	query.Set("vncticket", ticket)
+	cli := self.getRegion().cli
+	ret := &cloudprovider.ServerVncOutput{
+		Url:          fmt.Sprintf("wss://%s:%d/%s/%s?%s", cli.host, cli.port, PROXMOX_API_VERSION, self.getResource("vncwebsocket"), query.Encode()),
+		Host:         cli.host,
+		Port:         port,
+		Password:     ticket,
+		Protocol:     webconsole.PROXMOX,
+		InstanceId:   self.GetGlobalId(),
+		InstanceName: self.GetName(),
+		Hypervisor:   api.HYPERVISOR_PROXMOX,
+	}
+	return ret, nil
+}
+
+func (self *SInstance) GetVcpuCount() int {
+	cores, _ := strconv.Atoi(self.Config["cores"])
+	sockets, _ := strconv.Atoi(self.Config["sockets"])
+	if cores <= 0 {
+		cores = 1
+	}
+	if sockets <= 0 {
+		sockets = 1
+	}
+	return cores * sockets
+}
+
+func (self *SInstance) GetVmemSizeMB() int {
+	if memory, _ := strconv.Atoi(self.Config["memory"]); memory > 0 {
+		return memory
+	}
+	return int(self.MaxMem / 1024 / 1024)
+}
+
+func (self *SInstance) GetVga() string {
+	return "std"
+}
+
+func (self *SInstance) GetVdi() string {
+	return "vnc"
+}
+
+func (self *SInstance) RebuildRoot(ctx context.Context, desc *cloudprovider.SManagedVMRebuildRootConfig) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) SetSecurityGroups(secgroupIds []string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) StartVM(ctx context.Context) error {
+	return self.getRegion().postAndWait(self.getResource("status/start"), nil)
+}
+
+func (self *SInstance) StopVM(ctx context.Context, opts *cloudprovider.ServerStopOptions) error {
+	action := "status/shutdown"
+	if opts.IsForce {
+		action = "status/stop"
+	}
+	return self.getRegion().postAndWait(self.getResource(action), nil)
+}
+
+func (self *SInstance) UpdateUserData(userData string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) UpdateVM(ctx context.Context, name string) error {
+	params := url.Values{}
+	params.Set("name", name)
+	return self.setConfig(params)
+}
+
+func (self *SRegion) DeleteVM(node string, vmId int) error {
+	params := url.Values{}
+	params.Set("purge", "1")
+	params.Set("destroy-unreferenced-disks", "1")
+	resp, err := self.delete(fmt.Sprintf("nodes/%s/qemu/%d", node, vmId), params)
+	if err != nil {
+		return err
+	}
+	return self.cli.waitResult(resp)
+}
+
+func (self *SRegion) getNextVmId() (int, error) {
+	resp, err := self.cli.request(httputils.GET, "cluster/nextid", nil)
+	if err != nil {
+		return 0, errors.Wrapf(err, "get next vmid")
+	}
+	id, _ := resp.GetString()
+	return strconv.Atoi(id)
+}
+
+// CreateInstance clones the vm from the template and customizes it by cloud-init
+func (self *SRegion) CreateInstance(node string, opts *cloudprovider.SManagedVMCreateConfig) (*SInstance, error) {
+	image, err := self.GetImage(opts.ExternalImageId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetImage(%s)", opts.ExternalImageId)
+	}
+	if image.Node != node {
+		shared, err := self.getSharedStorageNames()
+		if err != nil {
+			return nil, err
+		}
+		if !image.isOnStorages(shared) {
+			return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "template %d uses local storages of node %s, can not be cloned to node %s", image.VmId, image.Node, node)
+		}
+	}
+	vmId, err := self.getNextVmId()
+	if err != nil {
+		return nil, err
+	}
+	name := opts.Hostname
+	if len(name) == 0 {
+		name = opts.NameEn
+	}
+	params := url.Values{}
+	params.Set("newid", strconv.Itoa(vmId))
+	params.Set("name", name)
+	params.Set("target", node)
+	params.Set("full", "1")
+	if len(opts.SysDisk.StorageExternalId) > 0 {
+		params.Set("storage", getStorageName(opts.SysDisk.StorageExternalId))
+	}
+	err = self.postAndWait(fmt.Sprintf("nodes/%s/qemu/%d/clone", image.Node, image.VmId), params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "clone template %d", image.VmId)
+	}
+
+	params = url.Values{}
+	params.Set("cores", strconv.Itoa(opts.Cpu))
+	params.Set("sockets", "1")
+	params.Set("memory", strconv.Itoa(opts.MemoryMB))
+	if len(opts.Description) > 0 {
+		params.Set("description", opts.Description)
+	}
+	if len(opts.ExternalNetworkId) > 0 {
+		params.Set("net0", fmt.Sprintf("virtio,bridge=%s", opts.ExternalNetworkId))
+		ipConfig := "ip=dhcp"
+		if len(opts.IpAddr) > 0 {
+			vpc, err := self.GetVpc(opts.ExternalNetworkId)
+			if err != nil {
+				return nil, errors.Wrapf(err, "GetVpc(%s)", opts.ExternalNetworkId)
+			}
+			ipConfig = fmt.Sprintf("ip=%s/%d", opts.IpAddr, vpc.getMaskLen())
+			if len(vpc.Gateway) > 0 {
+				ipConfig = fmt.Sprintf("%s,gw=%s", ipConfig, vpc.Gateway)
+			}
+		}
+		params.Set("ipconfig0", ipConfig)
+	}
+	if len(opts.Account) > 0 {
+		params.Set("ciuser", opts.Account)
+	}
+	if len(opts.Password) > 0 {
+		params.Set("cipassword", opts.Password)
+	}
+	if len(opts.PublicKey) > 0 {
+		params.Set("sshkeys", url.PathEscape(opts.PublicKey))
+	}
+	err = self.postAndWait(fmt.Sprintf("nodes/%s/qemu/%d/config", node, vmId), params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "set config of vm %d", vmId)
+	}
+
+	vm, err := self.GetInstance(strconv.Itoa(vmId))
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetInstance(%d)", vmId)
+	}
+	if keys := vm.getDiskKeys(); len(keys) > 0 && opts.SysDisk.SizeGB > 0 {
+		_, diskOpts := parseOptions(vm.Config[keys[0]], "file")
+		if int64(opts.SysDisk.SizeGB*1024) > parseSizeMb(diskOpts["size"]) {
+			err = self.ResizeDisk(node, vmId, keys[0], opts.SysDisk.SizeGB)
+			if err != nil {
+				return nil, errors.Wrapf(err, "resize system disk")
+			}
+		}
+	}
+	for _, disk := range opts.DataDisks {
+		params = url.Values{}
+		params.Set(vm.getFreeDiskKey("scsi"), fmt.Sprintf("%s:%d", getStorageName(disk.StorageExternalId), disk.SizeGB))
+		err = self.postAndWait(fmt.Sprintf("nodes/%s/qemu/%d/config", node, vmId), params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "add data disk")
+		}
+		vm, err = self.GetInstance(strconv.Itoa(vmId))
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetInstance(%d)", vmId)
+		}
+	}
+	return vm, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SIpAddress struct {
+	IpAddress     string `json:"ip-address"`
+	IpAddressType string `json:"ip-address-type"`
+	Prefix        int    `json:"prefix"`
+}
+
+// SInterface is the network interface reported by qemu guest agent
+type SInterface struct {
+	Name            string       `json:"name"`
+	HardwareAddress string       `json:"hardware-address"`
+	IpAddresses     []SIpAddress `json:"ip-addresses"`
+}
+
+type SInstanceNic struct {
+	cloudprovider.DummyICloudNic
+	ins *SInstance
+
+	key string
+
+	Model       string
+	MacAddress  string
+	Bridge      string
+	Tag         int
+	IpAddresses []string
+}
+
+func (self *SInstanceNic) GetId() string {
+	return fmt.Sprintf("%s/%s", self.ins.GetGlobalId(), self.key)
+}
+
+func (self *SInstanceNic) GetIP() string {
+	if len(self.IpAddresses) > 0 {
+		return self.IpAddresses[0]
+	}
+	return ""
+}
+
+func (self *SInstanceNic) GetMAC() string {
+	return self.MacAddress
+}
+
+func (self *SInstanceNic) GetDriver() string {
+	return self.Model
+}
+
+func (self *SInstanceNic) GetSubAddress() ([]string, error) {
+	if len(self.IpAddresses) > 1 {
+		return self.IpAddresses[1:], nil
+	}
+	return []string{}, nil
+}
+
+// GetINetworkId returns the id of the emulated network of the bridge
+func (self *SInstanceNic) GetINetworkId() string {
+	return self.Bridge
+}
+
+func (self *SInstanceNic) AssignAddress(ipAddrs []string) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (self *SRegion) GetInstanceInterfaces(node string, vmId int) ([]SInterface, error) {
+	ret := struct {
+		Result []SInterface `json:"result"`
+	}{}
+	res := fmt.Sprintf("nodes/%s/qemu/%d/agent/network-get-interfaces", node, vmId)
+	err := self.get(res, nil, &ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret.Result, nil
+}
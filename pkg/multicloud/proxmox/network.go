@@ -0,0 +1,105 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"yunion.io/x/pkg/util/netutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+// SNetwork is the emulated network of bridge, which covers the cidr of bridge
+type SNetwork struct {
+	multicloud.SResourceBase
+	multicloud.STagBase
+
+	wire *SWire
+}
+
+func (self *SNetwork) GetName() string {
+	return self.wire.GetName()
+}
+
+func (self *SNetwork) GetId() string {
+	return self.wire.GetId()
+}
+
+func (self *SNetwork) GetGlobalId() string {
+	return self.wire.GetGlobalId()
+}
+
+func (self *SNetwork) IsEmulated() bool {
+	return true
+}
+
+func (self *SNetwork) Refresh() error {
+	return nil
+}
+
+func (self *SNetwork) Delete() error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SNetwork) GetAllocTimeoutSeconds() int {
+	return 120 // 2 minutes
+}
+
+func (self *SNetwork) GetGateway() string {
+	return self.wire.vpc.Gateway
+}
+
+func (self *SNetwork) GetIWire() cloudprovider.ICloudWire {
+	return self.wire
+}
+
+func (self *SNetwork) getPrefix() netutils.IPV4Prefix {
+	cidr := self.wire.vpc.GetCidrBlock()
+	if len(cidr) == 0 {
+		cidr = "0.0.0.0/0"
+	}
+	prefix, _ := netutils.NewIPV4Prefix(cidr)
+	return prefix
+}
+
+func (self *SNetwork) GetIpStart() string {
+	return self.getPrefix().ToIPRange().StartIp().StepUp().String()
+}
+
+func (self *SNetwork) GetIpEnd() string {
+	return self.getPrefix().ToIPRange().EndIp().StepDown().String()
+}
+
+func (self *SNetwork) GetIpMask() int8 {
+	return self.getPrefix().MaskLen
+}
+
+func (self *SNetwork) GetProjectId() string {
+	return ""
+}
+
+func (self *SNetwork) GetPublicScope() rbacutils.TRbacScope {
+	return rbacutils.ScopeDomain
+}
+
+func (self *SNetwork) GetServerType() string {
+	return api.NETWORK_TYPE_GUEST
+}
+
+func (self *SNetwork) GetStatus() string {
+	return api.NETWORK_STATUS_AVAILABLE
+}
@@ -0,0 +1 @@
+package provider // import "yunion.io/x/onecloud/pkg/multicloud/proxmox/provider"
@@ -0,0 +1,206 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/regutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/multicloud/proxmox"
+)
+
+type SProxmoxProviderFactory struct {
+	cloudprovider.SPrivateCloudBaseProviderFactory
+}
+
+func (self *SProxmoxProviderFactory) GetId() string {
+	return proxmox.CLOUD_PROVIDER_PROXMOX
+}
+
+func (self *SProxmoxProviderFactory) GetName() string {
+	return proxmox.CLOUD_PROVIDER_PROXMOX
+}
+
+func (self *SProxmoxProviderFactory) ValidateChangeBandwidth(instanceId string, bandwidth int64) error {
+	return fmt.Errorf("Changing %s bandwidth is not supported", proxmox.CLOUD_PROVIDER_PROXMOX)
+}
+
+// ValidateCreateCloudaccountData validates the credential of proxmox, the access_key_id is
+// the api token id in the form of user@realm!tokenname with the token secret as access_key_secret,
+// or the user in the form of user@realm with the password as access_key_secret
+func (self *SProxmoxProviderFactory) ValidateCreateCloudaccountData(ctx context.Context, userCred mcclient.TokenCredential, input cloudprovider.SCloudaccountCredential) (cloudprovider.SCloudaccount, error) {
+	output := cloudprovider.SCloudaccount{}
+	if len(input.AccessKeyId) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "access_key_id")
+	}
+	if !strings.Contains(input.AccessKeyId, "@") {
+		return output, errors.Wrap(httperrors.ErrInputParameter, "access_key_id should be in the form of user@realm or user@realm!tokenname")
+	}
+	if len(input.AccessKeySecret) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "access_key_secret")
+	}
+	if len(input.Host) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "host")
+	}
+	if !regutils.MatchIPAddr(input.Host) && !regutils.MatchDomainName(input.Host) {
+		return output, errors.Wrap(httperrors.ErrInputParameter, "host should be ip or domain name")
+	}
+	if input.Port == 0 {
+		input.Port = proxmox.PROXMOX_DEFAULT_PORT
+	}
+	output.AccessUrl = fmt.Sprintf("https://%s:%d", input.Host, input.Port)
+	output.Account = input.AccessKeyId
+	output.Secret = input.AccessKeySecret
+	return output, nil
+}
+
+func (self *SProxmoxProviderFactory) ValidateUpdateCloudaccountCredential(ctx context.Context, userCred mcclient.TokenCredential, input cloudprovider.SCloudaccountCredential, cloudaccount string) (cloudprovider.SCloudaccount, error) {
+	output := cloudprovider.SCloudaccount{}
+	if len(input.AccessKeyId) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "access_key_id")
+	}
+	if len(input.AccessKeySecret) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "access_key_secret")
+	}
+	output = cloudprovider.SCloudaccount{
+		Account: input.AccessKeyId,
+		Secret:  input.AccessKeySecret,
+	}
+	return output, nil
+}
+
+func parseHostPort(_url string) (string, int, error) {
+	urlParse, err := url.Parse(_url)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "parse %s", _url)
+	}
+	port := func() int {
+		if len(urlParse.Port()) > 0 {
+			_port, _ := strconv.Atoi(urlParse.Port())
+			return _port
+		}
+		return proxmox.PROXMOX_DEFAULT_PORT
+	}()
+	return strings.TrimSuffix(urlParse.Host, fmt.Sprintf(":%d", port)), port, nil
+}
+
+func (self *SProxmoxProviderFactory) GetProvider(cfg cloudprovider.ProviderConfig) (cloudprovider.ICloudProvider, error) {
+	host, port, err := parseHostPort(cfg.URL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parseHostPort")
+	}
+
+	client, err := proxmox.NewProxmoxClient(
+		proxmox.NewProxmoxClientConfig(
+			host, cfg.Account, cfg.Secret, port,
+		).CloudproviderConfig(cfg),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &SProxmoxProvider{
+		SBaseProvider: cloudprovider.NewBaseProvider(self),
+		client:        client,
+	}, nil
+}
+
+func (self *SProxmoxProviderFactory) GetClientRC(info cloudprovider.SProviderInfo) (map[string]string, error) {
+	host, port, err := parseHostPort(info.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"PROXMOX_HOST":     host,
+		"PROXMOX_PORT":     fmt.Sprintf("%d", port),
+		"PROXMOX_USERNAME": info.Account,
+		"PROXMOX_PASSWORD": info.Secret,
+	}, nil
+}
+
+func init() {
+	factory := SProxmoxProviderFactory{}
+	cloudprovider.RegisterFactory(&factory)
+}
+
+type SProxmoxProvider struct {
+	cloudprovider.SBaseProvider
+	client *proxmox.SProxmoxClient
+}
+
+func (self *SProxmoxProvider) GetSysInfo() (jsonutils.JSONObject, error) {
+	return jsonutils.NewDict(), nil
+}
+
+func (self *SProxmoxProvider) GetVersion() string {
+	return self.client.GetVersion()
+}
+
+func (self *SProxmoxProvider) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
+	return self.client.GetSubAccounts()
+}
+
+func (self *SProxmoxProvider) GetAccountId() string {
+	return self.client.GetAccountId()
+}
+
+func (self *SProxmoxProvider) GetIRegions() []cloudprovider.ICloudRegion {
+	return self.client.GetIRegions()
+}
+
+func (self *SProxmoxProvider) GetIRegionById(id string) (cloudprovider.ICloudRegion, error) {
+	regions := self.GetIRegions()
+	for i := range regions {
+		if regions[i].GetGlobalId() == id {
+			return regions[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SProxmoxProvider) GetBalance() (float64, string, error) {
+	return 0.0, api.CLOUD_PROVIDER_HEALTH_NORMAL, cloudprovider.ErrNotSupported
+}
+
+func (self *SProxmoxProvider) GetIProjects() ([]cloudprovider.ICloudProject, error) {
+	return []cloudprovider.ICloudProject{}, nil
+}
+
+func (self *SProxmoxProvider) GetStorageClasses(regionId string) []string {
+	return nil
+}
+
+func (self *SProxmoxProvider) GetBucketCannedAcls(regionId string) []string {
+	return nil
+}
+
+func (self *SProxmoxProvider) GetObjectCannedAcls(regionId string) []string {
+	return nil
+}
+
+func (self *SProxmoxProvider) GetCapabilities() []string {
+	return self.client.GetCapabilities()
+}
@@ -0,0 +1,378 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	CLOUD_PROVIDER_PROXMOX = api.CLOUD_PROVIDER_PROXMOX
+
+	PROXMOX_DEFAULT_PORT = 8006
+	PROXMOX_API_VERSION  = "api2/json"
+
+	// PROXMOX_TICKET_RENEW_INTERVAL is less than the 2 hours lifetime of the ticket
+	PROXMOX_TICKET_RENEW_INTERVAL = time.Hour
+)
+
+type ProxmoxClientConfig struct {
+	cpcfg    cloudprovider.ProviderConfig
+	username string
+	secret   string
+	host     string
+	port     int
+	debug    bool
+}
+
+// NewProxmoxClientConfig creates the config of the client, username is either
+// the api token id in the form of user@realm!tokenname with the token secret,
+// or the user in the form of user@realm with the password
+func NewProxmoxClientConfig(host, username, secret string, port int) *ProxmoxClientConfig {
+	cfg := &ProxmoxClientConfig{
+		host:     host,
+		username: username,
+		secret:   secret,
+		port:     port,
+	}
+	return cfg
+}
+
+func (cfg *ProxmoxClientConfig) CloudproviderConfig(cpcfg cloudprovider.ProviderConfig) *ProxmoxClientConfig {
+	cfg.cpcfg = cpcfg
+	return cfg
+}
+
+func (cfg *ProxmoxClientConfig) Debug(debug bool) *ProxmoxClientConfig {
+	cfg.debug = debug
+	return cfg
+}
+
+func (cfg ProxmoxClientConfig) Copy() ProxmoxClientConfig {
+	return cfg
+}
+
+// isApiToken returns true if the client is authenticated by the api token,
+// otherwise the client logins with password and is authenticated by the ticket
+func (cfg *ProxmoxClientConfig) isApiToken() bool {
+	return strings.Contains(cfg.username, "!")
+}
+
+type SProxmoxClient struct {
+	*ProxmoxClientConfig
+
+	version *SVersion
+
+	ticketLock sync.Mutex
+	ticket     *STicket
+	ticketAt   time.Time
+}
+
+// STicket is the result of password login, the ticket is sent as the cookie
+// and the csrf token is required by the apis other than GET
+type STicket struct {
+	Username            string `json:"username"`
+	Ticket              string `json:"ticket"`
+	CSRFPreventionToken string `json:"CSRFPreventionToken"`
+}
+
+type SVersion struct {
+	Version string `json:"version"`
+	Release string `json:"release"`
+	RepoId  string `json:"repoid"`
+}
+
+func NewProxmoxClient(cfg *ProxmoxClientConfig) (*SProxmoxClient, error) {
+	client := &SProxmoxClient{
+		ProxmoxClientConfig: cfg,
+	}
+	return client, client.auth()
+}
+
+func (self *SProxmoxClient) auth() error {
+	if _, err := self.getTicket(); err != nil {
+		return err
+	}
+	version := &SVersion{}
+	err := self.get("version", nil, version)
+	if err != nil {
+		return errors.Wrapf(err, "get version")
+	}
+	self.version = version
+	return nil
+}
+
+func (self *SProxmoxClient) GetRegion() (*SRegion, error) {
+	return &SRegion{cli: self}, nil
+}
+
+func (self *SProxmoxClient) GetAccountId() string {
+	return self.host
+}
+
+func (self *SProxmoxClient) GetVersion() string {
+	if self.version != nil {
+		return self.version.Version
+	}
+	return ""
+}
+
+func (self *SProxmoxClient) GetCapabilities() []string {
+	return []string{
+		cloudprovider.CLOUD_CAPABILITY_COMPUTE,
+		cloudprovider.CLOUD_CAPABILITY_NETWORK,
+	}
+}
+
+func (self *SProxmoxClient) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
+	subAccount := cloudprovider.SSubAccount{
+		Account:      self.username,
+		Name:         self.cpcfg.Name,
+		HealthStatus: api.CLOUD_PROVIDER_HEALTH_NORMAL,
+	}
+	return []cloudprovider.SSubAccount{subAccount}, nil
+}
+
+func (self *SProxmoxClient) GetIRegions() []cloudprovider.ICloudRegion {
+	region := &SRegion{cli: self}
+	return []cloudprovider.ICloudRegion{region}
+}
+
+func (self *SProxmoxClient) getBaseUrl() string {
+	return fmt.Sprintf("https://%s:%d/%s", self.host, self.port, PROXMOX_API_VERSION)
+}
+
+func (self *SProxmoxClient) getDefaultClient() *http.Client {
+	client := httputils.GetDefaultClient()
+	proxy := func(req *http.Request) (*url.URL, error) {
+		if self.cpcfg.ProxyFunc != nil {
+			return self.cpcfg.ProxyFunc(req)
+		}
+		return nil, nil
+	}
+	httputils.SetClientProxyFunc(client, proxy)
+	return client
+}
+
+type sProxmoxError struct {
+	Method  string
+	Url     string
+	Code    int
+	Status  string
+	Details jsonutils.JSONObject
+}
+
+func (self *sProxmoxError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", self.Method, self.Url, self.Status)
+	if self.Details != nil {
+		msg = fmt.Sprintf("%s %s", msg, self.Details.String())
+	}
+	return msg
+}
+
+// parseError converts the error response of proxmox, whose message is in the
+// status line of http response and the invalid parameters are in errors
+func (self *sProxmoxError) parseError(resp *http.Response, body jsonutils.JSONObject) error {
+	self.Code = resp.StatusCode
+	self.Status = resp.Status
+	if body != nil && body.Contains("errors") {
+		self.Details, _ = body.Get("errors")
+	}
+	status := strings.ToLower(resp.Status)
+	if self.Code == http.StatusNotFound || strings.Contains(status, "does not exist") || strings.Contains(status, "no such") {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.Error())
+	}
+	if self.Code == http.StatusUnauthorized {
+		return errors.Wrapf(httperrors.ErrInvalidAccessKey, self.Error())
+	}
+	if self.Code == http.StatusForbidden {
+		return errors.Wrapf(httperrors.ErrForbidden, self.Error())
+	}
+	return self
+}
+
+// getTicket returns the ticket of password login, it is renewed by logging in
+// again before it is expired
+func (self *SProxmoxClient) getTicket() (*STicket, error) {
+	if self.isApiToken() {
+		return nil, nil
+	}
+	self.ticketLock.Lock()
+	defer self.ticketLock.Unlock()
+	if self.ticket != nil && time.Since(self.ticketAt) < PROXMOX_TICKET_RENEW_INTERVAL {
+		return self.ticket, nil
+	}
+	params := url.Values{}
+	params.Set("username", self.username)
+	params.Set("password", self.secret)
+	resp, err := self.rawRequest(httputils.POST, "access/ticket", params, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "login")
+	}
+	ticket := &STicket{}
+	err = resp.Unmarshal(ticket)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unmarshal ticket")
+	}
+	self.ticket, self.ticketAt = ticket, time.Now()
+	return ticket, nil
+}
+
+func (self *SProxmoxClient) request(method httputils.THttpMethod, res string, params url.Values) (jsonutils.JSONObject, error) {
+	ticket, err := self.getTicket()
+	if err != nil {
+		return nil, err
+	}
+	return self.rawRequest(method, res, params, ticket)
+}
+
+func (self *SProxmoxClient) rawRequest(method httputils.THttpMethod, res string, params url.Values, ticket *STicket) (jsonutils.JSONObject, error) {
+	u := fmt.Sprintf("%s/%s", self.getBaseUrl(), strings.TrimPrefix(res, "/"))
+	header := http.Header{}
+	if self.isApiToken() {
+		header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", self.username, self.secret))
+	} else if ticket != nil {
+		header.Set("Cookie", fmt.Sprintf("PVEAuthCookie=%s", ticket.Ticket))
+		if method != httputils.GET {
+			header.Set("CSRFPreventionToken", ticket.CSRFPreventionToken)
+		}
+	}
+	var body *strings.Reader
+	switch method {
+	case httputils.GET, httputils.DELETE:
+		if len(params) > 0 {
+			u = fmt.Sprintf("%s?%s", u, params.Encode())
+		}
+		body = strings.NewReader("")
+	default:
+		header.Set("Content-Type", "application/x-www-form-urlencoded")
+		body = strings.NewReader(params.Encode())
+	}
+	resp, err := httputils.Request(self.getDefaultClient(), context.Background(), method, u, header, body, self.debug)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s %s", method, u)
+	}
+	defer httputils.CloseResponse(resp)
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read response body")
+	}
+	if self.debug {
+		log.Debugf("%s %s response: %s", method, u, string(data))
+	}
+	var obj jsonutils.JSONObject
+	if len(data) > 0 {
+		obj, err = jsonutils.Parse(data)
+		if err != nil && resp.StatusCode < 300 {
+			return nil, errors.Wrapf(err, "parse response %s", string(data))
+		}
+	}
+	if resp.StatusCode >= 300 {
+		pe := &sProxmoxError{Method: string(method), Url: u}
+		return nil, pe.parseError(resp, obj)
+	}
+	if obj == nil || !obj.Contains("data") {
+		return jsonutils.JSONNull, nil
+	}
+	return obj.Get("data")
+}
+
+func (self *SProxmoxClient) get(res string, params url.Values, retVal interface{}) error {
+	resp, err := self.request(httputils.GET, res, params)
+	if err != nil {
+		return err
+	}
+	if retVal != nil {
+		return resp.Unmarshal(retVal)
+	}
+	return nil
+}
+
+// post returns the result of the api, which is the task id (UPID) for the
+// asynchronous apis, e.g. starting a vm
+func (self *SProxmoxClient) post(res string, params url.Values) (jsonutils.JSONObject, error) {
+	return self.request(httputils.POST, res, params)
+}
+
+func (self *SProxmoxClient) put(res string, params url.Values) (jsonutils.JSONObject, error) {
+	return self.request(httputils.PUT, res, params)
+}
+
+func (self *SProxmoxClient) delete(res string, params url.Values) (jsonutils.JSONObject, error) {
+	return self.request(httputils.DELETE, res, params)
+}
+
+// waitResult waits the task if the result of api is a task id
+func (self *SProxmoxClient) waitResult(resp jsonutils.JSONObject) error {
+	if resp == nil || resp == jsonutils.JSONNull {
+		return nil
+	}
+	upid, _ := resp.GetString()
+	if !strings.HasPrefix(upid, "UPID:") {
+		return nil
+	}
+	return self.waitTask(upid)
+}
+
+func (self *SProxmoxClient) waitTask(upid string) error {
+	task, err := parseUPID(upid)
+	if err != nil {
+		return err
+	}
+	return cloudprovider.Wait(time.Second*3, time.Minute*20, func() (bool, error) {
+		status, err := self.getTaskStatus(task.Node, upid)
+		if err != nil {
+			return false, errors.Wrapf(err, "getTaskStatus")
+		}
+		log.Debugf("task %s %s of %s status: %s %s", task.Type, task.Id, task.Node, status.Status, status.ExitStatus)
+		if status.Status != TASK_STATUS_STOPPED {
+			return false, nil
+		}
+		if status.ExitStatus != TASK_EXIT_STATUS_OK {
+			return false, errors.Errorf("task %s %s failed: %s", task.Type, task.Id, status.ExitStatus)
+		}
+		return true, nil
+	})
+}
+
+func (self *SProxmoxClient) getTaskStatus(node, upid string) (*STaskStatus, error) {
+	status := &STaskStatus{}
+	res := fmt.Sprintf("nodes/%s/tasks/%s/status", node, url.PathEscape(upid))
+	return status, self.get(res, nil, status)
+}
+
+func (self *SProxmoxClient) getClusterResources(resType string, retVal interface{}) error {
+	params := url.Values{}
+	if len(resType) > 0 {
+		params.Set("type", resType)
+	}
+	return self.get("cluster/resources", params, retVal)
+}
@@ -0,0 +1,506 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	testTokenId  = "root@pam!test"
+	testSecret   = "5f6a7b8c-0000-1111-2222-333344445555"
+	testUsername = "root@pam"
+	testPassword = "password"
+	testTicket   = "PVE:root@pam:64A1B2C3::c2lnbmF0dXJl"
+	testCSRF     = "64A1B2C3:Y3NyZg"
+
+	testStartUPID = "UPID:pve1:00001234:00ABCDEF:64A1B2C3:qmstart:100:root@pam!test:"
+	testStopUPID  = "UPID:pve1:00001235:00ABCDF0:64A1B2C4:qmstop:100:root@pam!test:"
+)
+
+// testResponses are the recorded responses of proxmox ve 7.4
+var testResponses = map[string]string{
+	"GET /api2/json/version": `{"data":{"version":"7.4-3","release":"7.4","repoid":"9002ab8a"}}`,
+	"GET /api2/json/cluster/status": `{"data":[
+		{"type":"cluster","id":"cluster","name":"pvec","nodes":2,"quorate":1,"version":2},
+		{"type":"node","id":"node/pve1","name":"pve1","ip":"10.0.0.11","online":1,"local":1,"nodeid":1},
+		{"type":"node","id":"node/pve2","name":"pve2","ip":"10.0.0.12","online":0,"local":0,"nodeid":2}]}`,
+	"GET /api2/json/cluster/resources?type=node": `{"data":[
+		{"id":"node/pve1","type":"node","node":"pve1","status":"online","maxcpu":8,"maxmem":34359738368,"maxdisk":100861726720,"cpu":0.05,"mem":4294967296,"disk":5368709120,"uptime":86400},
+		{"id":"node/pve2","type":"node","node":"pve2","status":"offline"}]}`,
+	"GET /api2/json/cluster/resources?type=vm": `{"data":[
+		{"id":"qemu/100","type":"qemu","vmid":100,"name":"web","node":"pve1","status":"running","maxcpu":2,"maxmem":2147483648,"maxdisk":34359738368,"template":0},
+		{"id":"qemu/9000","type":"qemu","vmid":9000,"name":"ubuntu-22.04","node":"pve1","status":"stopped","maxcpu":1,"maxmem":1073741824,"maxdisk":2361393152,"template":1},
+		{"id":"qemu/9001","type":"qemu","vmid":9001,"name":"debian-12","node":"pve2","status":"stopped","maxcpu":1,"maxmem":1073741824,"maxdisk":2147483648,"template":1},
+		{"id":"lxc/101","type":"lxc","vmid":101,"name":"ct","node":"pve1","status":"running","template":0}]}`,
+	"GET /api2/json/cluster/resources?type=storage": `{"data":[
+		{"id":"storage/pve1/local","type":"storage","node":"pve1","storage":"local","plugintype":"dir","content":"iso,vztmpl,backup","shared":0,"status":"available"},
+		{"id":"storage/pve1/local-lvm","type":"storage","node":"pve1","storage":"local-lvm","plugintype":"lvmthin","content":"images,rootdir","shared":0,"status":"available","maxdisk":68719476736,"disk":8589934592},
+		{"id":"storage/pve1/ceph","type":"storage","node":"pve1","storage":"ceph","plugintype":"rbd","content":"images","shared":1,"status":"available","maxdisk":1099511627776,"disk":10737418240},
+		{"id":"storage/pve2/ceph","type":"storage","node":"pve2","storage":"ceph","plugintype":"rbd","content":"images","shared":1,"status":"unknown"},
+		{"id":"storage/pve2/local-lvm","type":"storage","node":"pve2","storage":"local-lvm","plugintype":"lvmthin","content":"images,rootdir","shared":0,"status":"unknown"}]}`,
+	"GET /api2/json/nodes/pve1/status": `{"data":{"cpuinfo":{"model":"Intel(R) Xeon(R) Silver 4210 CPU @ 2.20GHz","cpus":8,"sockets":1,"cores":8,"mhz":"2194.843"},"memory":{"total":34359738368,"used":4294967296,"free":30064771072},"pveversion":"pve-manager/7.4-3/9002ab8a","kversion":"Linux 5.15.102-1-pve","uptime":86400}}`,
+	"GET /api2/json/nodes/pve1/qemu/100/config": `{"data":{"name":"web","cores":2,"sockets":1,"memory":"2048","ostype":"l26","agent":"1","boot":"order=scsi1;ide2;scsi0;net0",
+		"scsi0":"ceph:vm-100-disk-1,size=10G","scsi1":"local-lvm:vm-100-disk-0,iothread=1,size=32G","ide2":"none,media=cdrom",
+		"net0":"virtio=BC:24:11:2A:3B:4C,bridge=vmbr0,firewall=1","net1":"e1000=BC:24:11:2A:3B:4D,bridge=vmbr1,tag=10","digest":"8d1f0b4c"}}`,
+	"GET /api2/json/nodes/pve1/qemu/9000/config": `{"data":{"name":"ubuntu-22.04","cores":1,"memory":1024,"ostype":"l26","template":1,"scsi0":"local-lvm:base-9000-disk-0,size=2252M"}}`,
+	"GET /api2/json/nodes/pve2/qemu/9001/config": `{"data":{"name":"debian-12","cores":1,"memory":1024,"ostype":"l26","template":1,"scsi0":"ceph:base-9001-disk-0,size=2G","ide2":"ceph:vm-9001-cloudinit,media=cdrom"}}`,
+	"GET /api2/json/nodes/pve1/qemu/100/agent/network-get-interfaces": `{"data":{"result":[
+		{"name":"lo","hardware-address":"00:00:00:00:00:00","ip-addresses":[{"ip-address":"127.0.0.1","ip-address-type":"ipv4","prefix":8}]},
+		{"name":"eth0","hardware-address":"bc:24:11:2a:3b:4c","ip-addresses":[{"ip-address":"10.0.0.100","ip-address-type":"ipv4","prefix":24},{"ip-address":"fe80::be24:11ff:fe2a:3b4c","ip-address-type":"ipv6","prefix":64}]}]}}`,
+	"GET /api2/json/nodes/pve1/network?type=any_bridge": `{"data":[
+		{"iface":"vmbr0","type":"bridge","cidr":"10.0.0.11/24","address":"10.0.0.11","netmask":"24","gateway":"10.0.0.1","active":1},
+		{"iface":"vmbr1","type":"bridge","active":1}]}`,
+	"GET /api2/json/nodes/pve1/storage/local-lvm/content?content=images": `{"data":[
+		{"volid":"local-lvm:base-9000-disk-0","format":"raw","size":2361393152,"vmid":9000},
+		{"volid":"local-lvm:vm-100-disk-0","format":"raw","size":34359738368,"vmid":100}]}`,
+	"POST /api2/json/nodes/pve1/qemu/100/status/start":             `{"data":"` + testStartUPID + `"}`,
+	"POST /api2/json/nodes/pve1/qemu/100/status/shutdown":          `{"data":"` + testStopUPID + `"}`,
+	"POST /api2/json/nodes/pve1/qemu/100/vncproxy":                 `{"data":{"port":"5901","ticket":"PVEVNC:64A1B2C3::sig+/=","user":"root@pam!test","cert":"","upid":"UPID:pve1:00001A2B:0001E240:64A1B2C3:vncproxy:100:root@pam!test:"}}`,
+	"GET /api2/json/nodes/pve1/tasks/" + testStartUPID + "/status": `{"data":{"upid":"` + testStartUPID + `","node":"pve1","type":"qmstart","id":"100","user":"root@pam!test","status":"stopped","exitstatus":"OK","starttime":1688318659}}`,
+	"GET /api2/json/nodes/pve1/tasks/" + testStopUPID + "/status":  `{"data":{"upid":"` + testStopUPID + `","node":"pve1","type":"qmstop","id":"100","user":"root@pam!test","status":"stopped","exitstatus":"VM quit/powerdown failed","starttime":1688318660}}`,
+}
+
+// testAuthorized checks the api token, or the ticket cookie and the csrf token of the apis other than GET
+func testAuthorized(r *http.Request) bool {
+	if r.Header.Get("Authorization") == fmt.Sprintf("PVEAPIToken=%s=%s", testTokenId, testSecret) {
+		return true
+	}
+	cookie, err := r.Cookie("PVEAuthCookie")
+	if err != nil || cookie.Value != testTicket {
+		return false
+	}
+	return r.Method == http.MethodGet || r.Header.Get("CSRFPreventionToken") == testCSRF
+}
+
+func newTestRegion(t *testing.T, username, secret string) (*SRegion, *[]string) {
+	requests := []string{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/api2/json/access/ticket" {
+			requests = append(requests, "POST "+r.URL.Path)
+			if r.PostFormValue("username") != testUsername || r.PostFormValue("password") != testPassword {
+				http.Error(w, "authentication failure", http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"data":{"username":"%s","ticket":"%s","CSRFPreventionToken":"%s","cap":{}}}`, testUsername, testTicket, testCSRF)
+			return
+		}
+		if !testAuthorized(r) {
+			http.Error(w, "authentication failure", http.StatusUnauthorized)
+			return
+		}
+		key := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
+		if len(r.URL.RawQuery) > 0 {
+			key = fmt.Sprintf("%s?%s", key, r.URL.RawQuery)
+		}
+		requests = append(requests, key)
+		resp, ok := testResponses[key]
+		if !ok {
+			http.Error(w, "no such resource", http.StatusNotImplemented)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	cli, err := NewProxmoxClient(NewProxmoxClientConfig(u.Hostname(), username, secret, port))
+	if err != nil {
+		return nil, &requests
+	}
+	region, _ := cli.GetRegion()
+	return region, &requests
+}
+
+func TestParseUPID(t *testing.T) {
+	task, err := parseUPID(testStartUPID)
+	if err != nil {
+		t.Fatalf("parseUPID error: %v", err)
+	}
+	if task.Node != "pve1" || task.Type != "qmstart" || task.Id != "100" || task.User != "root@pam!test" {
+		t.Errorf("unexpected task %#v", task)
+	}
+	for _, upid := range []string{"", "UPID:pve1:qmstart", "TASK:pve1:00001234:00ABCDEF:64A1B2C3:qmstart:100:root@pam:"} {
+		if _, err := parseUPID(upid); err == nil {
+			t.Errorf("parseUPID(%q) should fail", upid)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	cases := []struct {
+		value      string
+		defaultKey string
+		want       string
+		opts       map[string]string
+	}{
+		{"local-lvm:vm-100-disk-0,iothread=1,size=32G", "file", "local-lvm:vm-100-disk-0", map[string]string{"iothread": "1", "size": "32G"}},
+		{"file=ceph:vm-100-disk-1,size=10G", "file", "ceph:vm-100-disk-1", map[string]string{"file": "ceph:vm-100-disk-1", "size": "10G"}},
+		{"virtio=BC:24:11:2A:3B:4C,bridge=vmbr0", "", "", map[string]string{"virtio": "BC:24:11:2A:3B:4C", "bridge": "vmbr0"}},
+		{"1", "enabled", "1", map[string]string{}},
+		{"enabled=1,fstrim_cloned_disks=1", "enabled", "1", map[string]string{"enabled": "1", "fstrim_cloned_disks": "1"}},
+	}
+	for _, c := range cases {
+		def, opts := parseOptions(c.value, c.defaultKey)
+		if def != c.want {
+			t.Errorf("parseOptions(%q) default = %q, want %q", c.value, def, c.want)
+		}
+		if len(opts) != len(c.opts) {
+			t.Errorf("parseOptions(%q) options = %v, want %v", c.value, opts, c.opts)
+			continue
+		}
+		for k, v := range c.opts {
+			if opts[k] != v {
+				t.Errorf("parseOptions(%q) option %s = %q, want %q", c.value, k, opts[k], v)
+			}
+		}
+	}
+}
+
+func TestParseSizeMb(t *testing.T) {
+	cases := map[string]int64{
+		"":           0,
+		"32G":        32 * 1024,
+		"2252M":      2252,
+		"1T":         1024 * 1024,
+		"4194304K":   4096,
+		"1073741824": 1024,
+		"G":          0,
+	}
+	for size, want := range cases {
+		if got := parseSizeMb(size); got != want {
+			t.Errorf("parseSizeMb(%q) = %d, want %d", size, got, want)
+		}
+	}
+}
+
+func TestGetStorageName(t *testing.T) {
+	cases := map[string]string{
+		"local-lvm:vm-100-disk-0": "local-lvm",
+		"pve1/local-lvm":          "local-lvm",
+		"ceph":                    "ceph",
+	}
+	for id, want := range cases {
+		if got := getStorageName(id); got != want {
+			t.Errorf("getStorageName(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	region, _ := newTestRegion(t, "root@pam!invalid", testSecret)
+	if region != nil {
+		t.Fatalf("client with invalid token should fail")
+	}
+	region, _ = newTestRegion(t, testUsername, "invalid")
+	if region != nil {
+		t.Fatalf("client with invalid password should fail")
+	}
+	cli, err := NewProxmoxClient(NewProxmoxClientConfig("127.0.0.1", "root@pam!invalid", testSecret, 1))
+	if err == nil {
+		t.Fatalf("client of unreachable host should fail")
+	}
+	if cli.GetAccountId() != "127.0.0.1" {
+		t.Errorf("unexpected account id %s", cli.GetAccountId())
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "authentication failure", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	_, err = NewProxmoxClient(NewProxmoxClientConfig(u.Hostname(), testTokenId, testSecret, port))
+	if errors.Cause(err) != httperrors.ErrInvalidAccessKey {
+		t.Errorf("want ErrInvalidAccessKey, got %v", err)
+	}
+}
+
+func TestHosts(t *testing.T) {
+	region, _ := newTestRegion(t, testTokenId, testSecret)
+	if region == nil {
+		t.Fatalf("newTestRegion failed")
+	}
+	zones, err := region.GetIZones()
+	if err != nil {
+		t.Fatalf("GetIZones error: %v", err)
+	}
+	if len(zones) != 1 || zones[0].GetName() != "pvec" {
+		t.Fatalf("unexpected zones %v", zones)
+	}
+	hosts, err := zones[0].GetIHosts()
+	if err != nil {
+		t.Fatalf("GetIHosts error: %v", err)
+	}
+	if len(hosts) != 2 {
+		t.Fatalf("want 2 hosts, got %d", len(hosts))
+	}
+	host := hosts[0]
+	if host.GetGlobalId() != "pve1" || host.GetAccessIp() != "10.0.0.11" || host.GetHostStatus() != api.HOST_ONLINE {
+		t.Errorf("unexpected host %s %s %s", host.GetGlobalId(), host.GetAccessIp(), host.GetHostStatus())
+	}
+	if host.GetCpuCount() != 8 || host.GetCpuMhz() != 2194 || host.GetMemSizeMB() != 32768 || host.GetVersion() != "7.4-3" {
+		t.Errorf("unexpected host spec %d %d %d %s", host.GetCpuCount(), host.GetCpuMhz(), host.GetMemSizeMB(), host.GetVersion())
+	}
+	if hosts[1].GetHostStatus() != api.HOST_OFFLINE || hosts[1].GetAccessIp() != "10.0.0.12" {
+		t.Errorf("unexpected offline host %s %s", hosts[1].GetHostStatus(), hosts[1].GetAccessIp())
+	}
+
+	storages, err := host.GetIStorages()
+	if err != nil {
+		t.Fatalf("GetIStorages error: %v", err)
+	}
+	storageIds := []string{}
+	for _, storage := range storages {
+		storageIds = append(storageIds, storage.GetGlobalId())
+	}
+	if strings.Join(storageIds, ",") != "pve1/local-lvm,ceph" {
+		t.Errorf("unexpected storages %v", storageIds)
+	}
+
+	wires, err := host.GetIWires()
+	if err != nil {
+		t.Fatalf("GetIWires error: %v", err)
+	}
+	if len(wires) != 2 {
+		t.Fatalf("want 2 wires, got %d", len(wires))
+	}
+	networks, err := wires[0].GetINetworks()
+	if err != nil {
+		t.Fatalf("GetINetworks error: %v", err)
+	}
+	network := networks[0]
+	if network.GetGlobalId() != "vmbr0" || network.GetIpStart() != "10.0.0.1" || network.GetIpEnd() != "10.0.0.254" || network.GetGateway() != "10.0.0.1" || network.GetIpMask() != 24 {
+		t.Errorf("unexpected network %s %s-%s %s/%d", network.GetGlobalId(), network.GetIpStart(), network.GetIpEnd(), network.GetGateway(), network.GetIpMask())
+	}
+}
+
+func TestInstances(t *testing.T) {
+	region, _ := newTestRegion(t, testTokenId, testSecret)
+	if region == nil {
+		t.Fatalf("newTestRegion failed")
+	}
+	host, err := region.GetIHostById("pve1")
+	if err != nil {
+		t.Fatalf("GetIHostById error: %v", err)
+	}
+	vms, err := host.GetIVMs()
+	if err != nil {
+		t.Fatalf("GetIVMs error: %v", err)
+	}
+	if len(vms) != 1 {
+		t.Fatalf("want 1 vm, got %d", len(vms))
+	}
+	vm := vms[0]
+	if vm.GetGlobalId() != "100" || vm.GetName() != "web" || vm.GetStatus() != api.VM_RUNNING || vm.GetVcpuCount() != 2 || vm.GetVmemSizeMB() != 2048 {
+		t.Errorf("unexpected vm %s %s %s %d %d", vm.GetGlobalId(), vm.GetName(), vm.GetStatus(), vm.GetVcpuCount(), vm.GetVmemSizeMB())
+	}
+
+	disks, err := vm.GetIDisks()
+	if err != nil {
+		t.Fatalf("GetIDisks error: %v", err)
+	}
+	if len(disks) != 2 {
+		t.Fatalf("want 2 disks, got %d", len(disks))
+	}
+	sys, data := disks[0], disks[1]
+	if sys.GetGlobalId() != "local-lvm:vm-100-disk-0" || sys.GetDiskType() != api.DISK_TYPE_SYS || sys.GetDiskSizeMB() != 32*1024 {
+		t.Errorf("unexpected system disk %s %s %d", sys.GetGlobalId(), sys.GetDiskType(), sys.GetDiskSizeMB())
+	}
+	if data.GetGlobalId() != "ceph:vm-100-disk-1" || data.GetDiskType() != api.DISK_TYPE_DATA || data.GetDiskSizeMB() != 10*1024 {
+		t.Errorf("unexpected data disk %s %s %d", data.GetGlobalId(), data.GetDiskType(), data.GetDiskSizeMB())
+	}
+	if storage, _ := sys.GetIStorage(); storage.GetGlobalId() != "pve1/local-lvm" {
+		t.Errorf("unexpected storage %s of system disk", storage.GetGlobalId())
+	}
+
+	nics, err := vm.GetINics()
+	if err != nil {
+		t.Fatalf("GetINics error: %v", err)
+	}
+	if len(nics) != 2 {
+		t.Fatalf("want 2 nics, got %d", len(nics))
+	}
+	if nics[0].GetMAC() != "bc:24:11:2a:3b:4c" || nics[0].GetIP() != "10.0.0.100" || nics[0].GetINetworkId() != "vmbr0" || nics[0].GetDriver() != "virtio" {
+		t.Errorf("unexpected nic %s %s %s %s", nics[0].GetMAC(), nics[0].GetIP(), nics[0].GetINetworkId(), nics[0].GetDriver())
+	}
+	if nics[1].GetIP() != "" || nics[1].GetINetworkId() != "vmbr1" || nics[1].GetDriver() != "e1000" {
+		t.Errorf("unexpected nic %s %s %s", nics[1].GetIP(), nics[1].GetINetworkId(), nics[1].GetDriver())
+	}
+
+	vnc, err := vm.GetVNCInfo(nil)
+	if err != nil {
+		t.Fatalf("GetVNCInfo error: %v", err)
+	}
+	if vnc.Port != 5901 || vnc.Password != "PVEVNC:64A1B2C3::sig+/=" || vnc.Protocol != "proxmox" {
+		t.Errorf("unexpected vnc port %d password %s protocol %s", vnc.Port, vnc.Password, vnc.Protocol)
+	}
+	u, err := url.Parse(vnc.Url)
+	if err != nil {
+		t.Fatalf("parse vnc url %s: %v", vnc.Url, err)
+	}
+	if u.Scheme != "wss" || u.Path != "/api2/json/nodes/pve1/qemu/100/vncwebsocket" || u.Query().Get("port") != "5901" || u.Query().Get("vncticket") != vnc.Password {
+		t.Errorf("unexpected vnc url %s", vnc.Url)
+	}
+}
+
+func TestStorageDisks(t *testing.T) {
+	region, _ := newTestRegion(t, testTokenId, testSecret)
+	if region == nil {
+		t.Fatalf("newTestRegion failed")
+	}
+	storage, err := region.GetIStorageById("pve1/local-lvm")
+	if err != nil {
+		t.Fatalf("GetIStorageById error: %v", err)
+	}
+	if storage.GetCapacityMB() != 65536 || storage.GetStorageType() != "lvmthin" || storage.GetStatus() != api.STORAGE_ONLINE {
+		t.Errorf("unexpected storage %d %s %s", storage.GetCapacityMB(), storage.GetStorageType(), storage.GetStatus())
+	}
+	disks, err := storage.GetIDisks()
+	if err != nil {
+		t.Fatalf("GetIDisks error: %v", err)
+	}
+	if len(disks) != 2 {
+		t.Fatalf("want 2 disks, got %d", len(disks))
+	}
+	if disks[1].GetDiskType() != api.DISK_TYPE_SYS || disks[1].GetDiskSizeMB() != 32768 {
+		t.Errorf("unexpected disk %s %s %d", disks[1].GetGlobalId(), disks[1].GetDiskType(), disks[1].GetDiskSizeMB())
+	}
+
+	images, err := storage.GetIStoragecache().GetICloudImages()
+	if err != nil {
+		t.Fatalf("GetICloudImages error: %v", err)
+	}
+	if len(images) != 2 || images[0].GetGlobalId() != "9000" || images[0].GetName() != "ubuntu-22.04" || images[1].GetGlobalId() != "9001" {
+		t.Errorf("unexpected images %v", images)
+	}
+}
+
+func TestStoragecaches(t *testing.T) {
+	region, _ := newTestRegion(t, testTokenId, testSecret)
+	if region == nil {
+		t.Fatalf("newTestRegion failed")
+	}
+	caches, err := region.GetIStoragecaches()
+	if err != nil {
+		t.Fatalf("GetIStoragecaches error: %v", err)
+	}
+	// the template 9000 on local-lvm can be cloned only on pve1, the template 9001 on ceph can be cloned everywhere
+	want := map[string]string{
+		region.GetGlobalId() + "/templates":      "9001",
+		region.GetGlobalId() + "/pve1/templates": "9000,9001",
+		region.GetGlobalId() + "/pve2/templates": "9001",
+	}
+	if len(caches) != len(want) {
+		t.Fatalf("want %d storagecaches, got %d", len(want), len(caches))
+	}
+	for _, cache := range caches {
+		images, err := cache.GetICloudImages()
+		if err != nil {
+			t.Fatalf("GetICloudImages of %s error: %v", cache.GetGlobalId(), err)
+		}
+		ids := []string{}
+		for _, image := range images {
+			ids = append(ids, image.GetGlobalId())
+		}
+		if strings.Join(ids, ",") != want[cache.GetGlobalId()] {
+			t.Errorf("storagecache %s images %v, want %s", cache.GetGlobalId(), ids, want[cache.GetGlobalId()])
+		}
+	}
+
+	storage, err := region.GetIStorageById("ceph")
+	if err != nil {
+		t.Fatalf("GetIStorageById error: %v", err)
+	}
+	cache := storage.GetIStoragecache()
+	if cache.GetGlobalId() != region.GetGlobalId()+"/templates" {
+		t.Errorf("unexpected storagecache %s of shared storage", cache.GetGlobalId())
+	}
+	if _, err := cache.GetIImageById("9000"); errors.Cause(err) != cloudprovider.ErrNotFound {
+		t.Errorf("template on local storage should not be in the storagecache of shared storage, got %v", err)
+	}
+
+	_, err = region.CreateInstance("pve2", &cloudprovider.SManagedVMCreateConfig{ExternalImageId: "9000"})
+	if errors.Cause(err) != cloudprovider.ErrNotSupported {
+		t.Errorf("template on local storage should not be cloned to other node, got %v", err)
+	}
+}
+
+func TestTicketAuth(t *testing.T) {
+	region, requests := newTestRegion(t, testUsername, testPassword)
+	if region == nil {
+		t.Fatalf("newTestRegion failed")
+	}
+	vm, err := region.GetIVMById("100")
+	if err != nil {
+		t.Fatalf("GetIVMById error: %v", err)
+	}
+	err = vm.StartVM(context.Background())
+	if err != nil {
+		t.Fatalf("StartVM error: %v", err)
+	}
+	logins := 0
+	for _, req := range *requests {
+		if req == "POST /api2/json/access/ticket" {
+			logins++
+		}
+	}
+	if logins != 1 || (*requests)[0] != "POST /api2/json/access/ticket" {
+		t.Errorf("want login once before other requests, got %v", *requests)
+	}
+	subAccounts, _ := region.cli.GetSubAccounts()
+	if subAccounts[0].Account != testUsername {
+		t.Errorf("unexpected sub account %s", subAccounts[0].Account)
+	}
+}
+
+func TestPowerTask(t *testing.T) {
+	region, requests := newTestRegion(t, testTokenId, testSecret)
+	if region == nil {
+		t.Fatalf("newTestRegion failed")
+	}
+	vm, err := region.GetIVMById("100")
+	if err != nil {
+		t.Fatalf("GetIVMById error: %v", err)
+	}
+	err = vm.StartVM(context.Background())
+	if err != nil {
+		t.Fatalf("StartVM error: %v", err)
+	}
+	last := (*requests)[len(*requests)-1]
+	if last != "GET /api2/json/nodes/pve1/tasks/"+testStartUPID+"/status" {
+		t.Errorf("start task is not waited, last request: %s", last)
+	}
+	err = vm.StopVM(context.Background(), &cloudprovider.ServerStopOptions{})
+	if err == nil || !strings.Contains(err.Error(), "powerdown failed") {
+		t.Errorf("want failed stop task, got %v", err)
+	}
+}
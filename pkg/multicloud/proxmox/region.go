@@ -0,0 +1,232 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"fmt"
+	"net/url"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SRegion struct {
+	multicloud.SRegion
+	multicloud.SNoObjectStorageRegion
+	multicloud.SNoLbRegion
+
+	cli *SProxmoxClient
+}
+
+func (self *SRegion) GetId() string {
+	return self.cli.cpcfg.Id
+}
+
+func (self *SRegion) GetGlobalId() string {
+	return fmt.Sprintf("%s/%s", api.CLOUD_PROVIDER_PROXMOX, self.cli.cpcfg.Id)
+}
+
+func (self *SRegion) GetName() string {
+	return self.cli.cpcfg.Name
+}
+
+func (self *SRegion) GetI18n() cloudprovider.SModelI18nTable {
+	table := cloudprovider.SModelI18nTable{}
+	table["name"] = cloudprovider.NewSModelI18nEntry(self.GetName()).CN(self.GetName())
+	return table
+}
+
+func (self *SRegion) CreateEIP(opts *cloudprovider.SEip) (cloudprovider.ICloudEIP, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetISecurityGroupById(secgroupId string) (cloudprovider.ICloudSecurityGroup, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetISecurityGroupByName(opts *cloudprovider.SecurityGroupFilterOptions) (cloudprovider.ICloudSecurityGroup, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) CreateISecurityGroup(conf *cloudprovider.SecurityGroupCreateInput) (cloudprovider.ICloudSecurityGroup, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) CreateIVpc(opts *cloudprovider.VpcCreateOptions) (cloudprovider.ICloudVpc, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetCapabilities() []string {
+	return self.cli.GetCapabilities()
+}
+
+func (self *SRegion) GetCloudEnv() string {
+	return ""
+}
+
+func (self *SRegion) GetProvider() string {
+	return api.CLOUD_PROVIDER_PROXMOX
+}
+
+func (self *SRegion) GetStatus() string {
+	return api.CLOUD_REGION_STATUS_INSERVER
+}
+
+func (self *SRegion) GetGeographicInfo() cloudprovider.SGeographicInfo {
+	return cloudprovider.SGeographicInfo{}
+}
+
+func (self *SRegion) GetIEipById(id string) (cloudprovider.ICloudEIP, error) {
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SRegion) GetIEips() ([]cloudprovider.ICloudEIP, error) {
+	return []cloudprovider.ICloudEIP{}, nil
+}
+
+func (self *SRegion) GetIVpcById(id string) (cloudprovider.ICloudVpc, error) {
+	vpc, err := self.GetVpc(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetVpc(%s)", id)
+	}
+	return vpc, nil
+}
+
+func (self *SRegion) GetIVpcs() ([]cloudprovider.ICloudVpc, error) {
+	vpcs, err := self.GetVpcs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetVpcs")
+	}
+	ret := []cloudprovider.ICloudVpc{}
+	for i := range vpcs {
+		ret = append(ret, &vpcs[i])
+	}
+	return ret, nil
+}
+
+// getZone returns the only zone of region, which is the proxmox cluster
+func (self *SRegion) getZone() (*SZone, error) {
+	status, err := self.GetClusterStatus()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetClusterStatus")
+	}
+	zone := &SZone{region: self}
+	for _, s := range status {
+		if s.Type == CLUSTER_STATUS_TYPE_CLUSTER {
+			zone.Name = s.Name
+			return zone, nil
+		}
+	}
+	// a standalone node is not in any cluster
+	for _, s := range status {
+		if s.Type == CLUSTER_STATUS_TYPE_NODE {
+			zone.Name = s.Name
+			return zone, nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, "cluster")
+}
+
+func (self *SRegion) GetIZones() ([]cloudprovider.ICloudZone, error) {
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, err
+	}
+	return []cloudprovider.ICloudZone{zone}, nil
+}
+
+func (self *SRegion) GetIZoneById(id string) (cloudprovider.ICloudZone, error) {
+	zones, err := self.GetIZones()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetIZones")
+	}
+	for i := range zones {
+		if zones[i].GetGlobalId() == id {
+			return zones[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetIHosts() ([]cloudprovider.ICloudHost, error) {
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZone")
+	}
+	return zone.GetIHosts()
+}
+
+func (self *SRegion) GetIHostById(id string) (cloudprovider.ICloudHost, error) {
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZone")
+	}
+	return zone.GetIHostById(id)
+}
+
+func (self *SRegion) GetIVMById(id string) (cloudprovider.ICloudVM, error) {
+	vm, err := self.GetInstance(id)
+	if err != nil {
+		return nil, err
+	}
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZone")
+	}
+	host, err := self.GetHost(vm.Node)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetHost(%s)", vm.Node)
+	}
+	host.zone = zone
+	vm.host = host
+	return vm, nil
+}
+
+func (self *SRegion) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getZone")
+	}
+	return zone.GetIStorageById(id)
+}
+
+func (self *SRegion) get(res string, params url.Values, retVal interface{}) error {
+	return self.cli.get(res, params, retVal)
+}
+
+func (self *SRegion) post(res string, params url.Values) (jsonutils.JSONObject, error) {
+	return self.cli.post(res, params)
+}
+
+func (self *SRegion) put(res string, params url.Values) (jsonutils.JSONObject, error) {
+	return self.cli.put(res, params)
+}
+
+func (self *SRegion) delete(res string, params url.Values) (jsonutils.JSONObject, error) {
+	return self.cli.delete(res, params)
+}
+
+// postAndWait posts to the asynchronous api and waits the task
+func (self *SRegion) postAndWait(res string, params url.Values) error {
+	resp, err := self.post(res, params)
+	if err != nil {
+		return err
+	}
+	return self.cli.waitResult(resp)
+}
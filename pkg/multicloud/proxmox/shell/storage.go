@@ -0,0 +1,49 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/onecloud/pkg/multicloud/proxmox"
+	"yunion.io/x/onecloud/pkg/util/shellutils"
+)
+
+func init() {
+	type StorageListOptions struct {
+		Node string `help:"node of storages"`
+	}
+	shellutils.R(&StorageListOptions{}, "storage-list", "list storages", func(cli *proxmox.SRegion, args *StorageListOptions) error {
+		storages, err := cli.GetStorages(args.Node)
+		if err != nil {
+			return err
+		}
+		printList(storages, 0, 0, 0, []string{})
+		return nil
+	})
+
+	type DiskListOptions struct {
+		NODE    string
+		STORAGE string
+	}
+
+	shellutils.R(&DiskListOptions{}, "disk-list", "list disks of storage", func(cli *proxmox.SRegion, args *DiskListOptions) error {
+		disks, err := cli.GetDisks(args.NODE, args.STORAGE)
+		if err != nil {
+			return err
+		}
+		printList(disks, 0, 0, 0, []string{})
+		return nil
+	})
+
+}
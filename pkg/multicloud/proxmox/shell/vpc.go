@@ -0,0 +1,47 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/onecloud/pkg/multicloud/proxmox"
+	"yunion.io/x/onecloud/pkg/util/shellutils"
+)
+
+func init() {
+	type VpcListOptions struct {
+	}
+	shellutils.R(&VpcListOptions{}, "vpc-list", "list bridges as vpcs", func(cli *proxmox.SRegion, args *VpcListOptions) error {
+		vpcs, err := cli.GetVpcs()
+		if err != nil {
+			return err
+		}
+		printList(vpcs, 0, 0, 0, []string{})
+		return nil
+	})
+
+	type BridgeListOptions struct {
+		NODE string
+	}
+
+	shellutils.R(&BridgeListOptions{}, "bridge-list", "list bridges of node", func(cli *proxmox.SRegion, args *BridgeListOptions) error {
+		bridges, err := cli.GetBridges(args.NODE)
+		if err != nil {
+			return err
+		}
+		printList(bridges, 0, 0, 0, []string{})
+		return nil
+	})
+
+}
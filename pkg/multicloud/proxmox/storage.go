@@ -0,0 +1,212 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+const (
+	STORAGE_STATUS_AVAILABLE = "available"
+
+	STORAGE_CONTENT_IMAGES = "images"
+)
+
+type SStorage struct {
+	multicloud.SStorageBase
+	multicloud.STagBase
+
+	zone *SZone
+
+	Id         string `json:"id"`
+	Node       string `json:"node"`
+	Storage    string `json:"storage"`
+	PluginType string `json:"plugintype"`
+	Content    string `json:"content"`
+	Shared     int    `json:"shared"`
+	Status     string `json:"status"`
+	MaxDisk    int64  `json:"maxdisk"`
+	Disk       int64  `json:"disk"`
+}
+
+func (self *SStorage) GetName() string {
+	return self.Storage
+}
+
+func (self *SStorage) GetId() string {
+	return self.GetGlobalId()
+}
+
+// GetGlobalId returns the storage name for the shared storage, and the
+// node/storage for the local storage, which has the same name on every node
+func (self *SStorage) GetGlobalId() string {
+	if self.Shared > 0 {
+		return self.Storage
+	}
+	return fmt.Sprintf("%s/%s", self.Node, self.Storage)
+}
+
+func (self *SStorage) GetIDisks() ([]cloudprovider.ICloudDisk, error) {
+	disks, err := self.zone.region.GetDisks(self.Node, self.Storage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetDisks")
+	}
+	ret := []cloudprovider.ICloudDisk{}
+	for i := range disks {
+		disks[i].storage = self
+		ret = append(ret, &disks[i])
+	}
+	return ret, nil
+}
+
+func (self *SStorage) CreateIDisk(conf *cloudprovider.DiskCreateConfig) (cloudprovider.ICloudDisk, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SStorage) GetCapacityMB() int64 {
+	return self.MaxDisk / 1024 / 1024
+}
+
+func (self *SStorage) GetCapacityUsedMB() int64 {
+	return self.Disk / 1024 / 1024
+}
+
+func (self *SStorage) GetEnabled() bool {
+	return true
+}
+
+func (self *SStorage) GetIDiskById(id string) (cloudprovider.ICloudDisk, error) {
+	disks, err := self.GetIDisks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range disks {
+		if disks[i].GetGlobalId() == id {
+			return disks[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SStorage) GetIStoragecache() cloudprovider.ICloudStoragecache {
+	if self.Shared > 0 {
+		return &SStoragecache{region: self.zone.region}
+	}
+	return &SStoragecache{region: self.zone.region, node: self.Node}
+}
+
+// GetStorages returns the storages for the disk images, the storages of
+// node and the shared storages are returned if node is not empty
+func (self *SRegion) GetStorages(node string) ([]SStorage, error) {
+	storages := []SStorage{}
+	err := self.cli.getClusterResources("storage", &storages)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get storages")
+	}
+	ret, shared := []SStorage{}, []string{}
+	for i := range storages {
+		if !utils.IsInStringArray(STORAGE_CONTENT_IMAGES, strings.Split(storages[i].Content, ",")) {
+			continue
+		}
+		if storages[i].Shared > 0 {
+			if utils.IsInStringArray(storages[i].Storage, shared) {
+				continue
+			}
+			shared = append(shared, storages[i].Storage)
+		} else if len(node) > 0 && storages[i].Node != node {
+			continue
+		}
+		ret = append(ret, storages[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) getSharedStorageNames() ([]string, error) {
+	storages, err := self.GetStorages("")
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetStorages")
+	}
+	ret := []string{}
+	for i := range storages {
+		if storages[i].Shared > 0 {
+			ret = append(ret, storages[i].Storage)
+		}
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetStorage(id string) (*SStorage, error) {
+	storages, err := self.GetStorages("")
+	if err != nil {
+		return nil, err
+	}
+	for i := range storages {
+		if storages[i].GetGlobalId() == id {
+			return &storages[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, "storage %s", id)
+}
+
+func (self *SStorage) GetIZone() cloudprovider.ICloudZone {
+	return self.zone
+}
+
+func (self *SStorage) GetMediumType() string {
+	return api.DISK_TYPE_ROTATE
+}
+
+func (self *SStorage) GetMountPoint() string {
+	return ""
+}
+
+func (self *SStorage) GetStatus() string {
+	if self.Status == STORAGE_STATUS_AVAILABLE {
+		return api.STORAGE_ONLINE
+	}
+	return api.STORAGE_OFFLINE
+}
+
+func (self *SStorage) Refresh() error {
+	storage, err := self.zone.region.GetStorage(self.GetGlobalId())
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, storage)
+}
+
+func (self *SStorage) GetStorageConf() jsonutils.JSONObject {
+	conf := jsonutils.NewDict()
+	conf.Add(jsonutils.NewString(self.PluginType), "plugin_type")
+	return conf
+}
+
+// GetStorageType returns the storage plugin of proxmox, e.g. lvmthin, zfspool, rbd, nfs
+func (self *SStorage) GetStorageType() string {
+	return strings.ToLower(self.PluginType)
+}
+
+func (self *SStorage) IsSysDiskStore() bool {
+	return true
+}
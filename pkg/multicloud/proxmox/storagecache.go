@@ -0,0 +1,155 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+// SStoragecache contains the vm templates which can be cloned to its storages.
+// A template can be cloned to other nodes only if all of its disks are on the
+// shared storages, so the local storages of a node share the storagecache of
+// the node, and the shared storages share the storagecache of region
+type SStoragecache struct {
+	multicloud.SResourceBase
+	multicloud.STagBase
+
+	region *SRegion
+	// node is empty for the storagecache of shared storages
+	node string
+}
+
+func (self *SStoragecache) GetName() string {
+	if len(self.node) > 0 {
+		return fmt.Sprintf("%s-templates", self.node)
+	}
+	return fmt.Sprintf("%s-templates", self.region.GetName())
+}
+
+func (self *SStoragecache) GetId() string {
+	return self.GetGlobalId()
+}
+
+func (self *SStoragecache) GetGlobalId() string {
+	if len(self.node) > 0 {
+		return fmt.Sprintf("%s/%s/templates", self.region.GetGlobalId(), self.node)
+	}
+	return fmt.Sprintf("%s/templates", self.region.GetGlobalId())
+}
+
+func (self *SStoragecache) GetStatus() string {
+	return "available"
+}
+
+// isImageCloneable returns true if the template can be cloned to the storages of cache
+func (self *SStoragecache) isImageCloneable(image *SImage, sharedStorages []string) bool {
+	if len(self.node) > 0 && image.Node == self.node {
+		return true
+	}
+	return image.isOnStorages(sharedStorages)
+}
+
+func (self *SStoragecache) GetICloudImages() ([]cloudprovider.ICloudImage, error) {
+	images, err := self.region.GetImages()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetImages")
+	}
+	shared, err := self.region.getSharedStorageNames()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudImage{}
+	for i := range images {
+		if !self.isImageCloneable(&images[i], shared) {
+			continue
+		}
+		images[i].cache = self
+		ret = append(ret, &images[i])
+	}
+	return ret, nil
+}
+
+func (self *SStoragecache) GetICustomizedCloudImages() ([]cloudprovider.ICloudImage, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SStoragecache) GetIImageById(id string) (cloudprovider.ICloudImage, error) {
+	image, err := self.region.GetImage(id)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := self.region.getSharedStorageNames()
+	if err != nil {
+		return nil, err
+	}
+	if !self.isImageCloneable(image, shared) {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "template %s of node %s", id, image.Node)
+	}
+	image.cache = self
+	return image, nil
+}
+
+func (self *SStoragecache) GetPath() string {
+	return ""
+}
+
+func (self *SStoragecache) CreateIImage(snapshotId, imageName, osType, imageDesc string) (cloudprovider.ICloudImage, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SStoragecache) DownloadImage(userCred mcclient.TokenCredential, imageId string, extId string, path string) (jsonutils.JSONObject, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+// UploadImage is not supported, the vm can be created only from the templates of proxmox
+func (self *SStoragecache) UploadImage(ctx context.Context, userCred mcclient.TokenCredential, opts *cloudprovider.SImageCreateOption, callback func(float32)) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+// GetIStoragecaches returns the storagecache of shared storages and the storagecaches of nodes
+func (self *SRegion) GetIStoragecaches() ([]cloudprovider.ICloudStoragecache, error) {
+	status, err := self.GetClusterStatus()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetClusterStatus")
+	}
+	ret := []cloudprovider.ICloudStoragecache{&SStoragecache{region: self}}
+	for i := range status {
+		if status[i].Type == CLUSTER_STATUS_TYPE_NODE {
+			ret = append(ret, &SStoragecache{region: self, node: status[i].Name})
+		}
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIStoragecacheById(id string) (cloudprovider.ICloudStoragecache, error) {
+	caches, err := self.GetIStoragecaches()
+	if err != nil {
+		return nil, err
+	}
+	for i := range caches {
+		if caches[i].GetGlobalId() == id {
+			return caches[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
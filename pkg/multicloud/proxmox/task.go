@@ -0,0 +1,90 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	TASK_STATUS_RUNNING = "running"
+	TASK_STATUS_STOPPED = "stopped"
+
+	TASK_EXIT_STATUS_OK = "OK"
+)
+
+// SUPID is the parsed task id of proxmox, in the form of
+// UPID:$node:$pid:$pstart:$starttime:$type:$id:$user:
+type SUPID struct {
+	Node string
+	Type string
+	Id   string
+	User string
+}
+
+func parseUPID(upid string) (*SUPID, error) {
+	parts := strings.Split(upid, ":")
+	if len(parts) < 8 || parts[0] != "UPID" {
+		return nil, errors.Errorf("invalid task id %s", upid)
+	}
+	return &SUPID{
+		Node: parts[1],
+		Type: parts[5],
+		Id:   parts[6],
+		User: parts[7],
+	}, nil
+}
+
+type STaskStatus struct {
+	UPID       string `json:"upid"`
+	Node       string `json:"node"`
+	Type       string `json:"type"`
+	Id         string `json:"id"`
+	User       string `json:"user"`
+	Status     string `json:"status"`
+	ExitStatus string `json:"exitstatus"`
+	StartTime  int64  `json:"starttime"`
+}
+
+type STask struct {
+	UPID      string `json:"upid"`
+	Node      string `json:"node"`
+	Type      string `json:"type"`
+	Id        string `json:"id"`
+	User      string `json:"user"`
+	Status    string `json:"status"`
+	StartTime int64  `json:"starttime"`
+	EndTime   int64  `json:"endtime"`
+}
+
+func (self *SRegion) GetTasks(node string) ([]STask, error) {
+	tasks := []STask{}
+	err := self.get(fmt.Sprintf("nodes/%s/tasks", node), nil, &tasks)
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (self *SRegion) GetTaskStatus(upid string) (*STaskStatus, error) {
+	task, err := parseUPID(upid)
+	if err != nil {
+		return nil, err
+	}
+	return self.cli.getTaskStatus(task.Node, upid)
+}
@@ -0,0 +1,210 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxmox
+
+import (
+	"fmt"
+	"net/url"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SBridge struct {
+	Iface   string `json:"iface"`
+	Type    string `json:"type"`
+	Cidr    string `json:"cidr"`
+	Address string `json:"address"`
+	Netmask string `json:"netmask"`
+	Gateway string `json:"gateway"`
+	Active  int    `json:"active"`
+}
+
+// SVpc is the bridge of nodes, e.g. vmbr0, the bridges with the same name
+// of different nodes are regarded as the same network
+type SVpc struct {
+	multicloud.SVpc
+	multicloud.STagBase
+
+	region *SRegion
+
+	Iface   string
+	Cidr    string
+	Gateway string
+
+	nodes []string
+}
+
+func (self *SVpc) GetName() string {
+	return self.Iface
+}
+
+func (self *SVpc) GetId() string {
+	return self.Iface
+}
+
+func (self *SVpc) GetGlobalId() string {
+	return self.Iface
+}
+
+func (self *SVpc) Delete() error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SVpc) GetCidrBlock() string {
+	if len(self.Cidr) == 0 {
+		return ""
+	}
+	prefix, err := netutils.NewIPV4Prefix(self.Cidr)
+	if err != nil {
+		return ""
+	}
+	return prefix.String()
+}
+
+func (self *SVpc) getMaskLen() int8 {
+	prefix, err := netutils.NewIPV4Prefix(self.GetCidrBlock())
+	if err != nil {
+		return 24
+	}
+	return prefix.MaskLen
+}
+
+func (self *SVpc) isOnNode(node string) bool {
+	return utils.IsInStringArray(node, self.nodes)
+}
+
+func (self *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
+	return []cloudprovider.ICloudRouteTable{}, nil
+}
+
+func (self *SVpc) GetIRouteTableById(routeTableId string) (cloudprovider.ICloudRouteTable, error) {
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SVpc) GetISecurityGroups() ([]cloudprovider.ICloudSecurityGroup, error) {
+	return []cloudprovider.ICloudSecurityGroup{}, nil
+}
+
+func (self *SVpc) getWire() *SWire {
+	return &SWire{vpc: self}
+}
+
+func (self *SVpc) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	return []cloudprovider.ICloudWire{self.getWire()}, nil
+}
+
+func (self *SVpc) GetIWireById(wireId string) (cloudprovider.ICloudWire, error) {
+	wires, err := self.GetIWires()
+	if err != nil {
+		return nil, err
+	}
+	for i := range wires {
+		if wires[i].GetGlobalId() == wireId {
+			return wires[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SVpc) GetIsDefault() bool {
+	return false
+}
+
+func (self *SVpc) GetRegion() cloudprovider.ICloudRegion {
+	return self.region
+}
+
+func (self *SVpc) GetStatus() string {
+	return api.VPC_STATUS_AVAILABLE
+}
+
+func (self *SRegion) GetBridges(node string) ([]SBridge, error) {
+	bridges := []SBridge{}
+	params := url.Values{}
+	params.Set("type", "any_bridge")
+	err := self.get(fmt.Sprintf("nodes/%s/network", node), params, &bridges)
+	if err != nil {
+		return nil, err
+	}
+	return bridges, nil
+}
+
+func (self *SRegion) getOnlineNodes() ([]string, error) {
+	hosts := []SHost{}
+	err := self.cli.getClusterResources("node", &hosts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get nodes")
+	}
+	nodes := []string{}
+	for i := range hosts {
+		if hosts[i].Status == NODE_STATUS_ONLINE {
+			nodes = append(nodes, hosts[i].Node)
+		}
+	}
+	return nodes, nil
+}
+
+func (self *SRegion) GetVpcs() ([]SVpc, error) {
+	nodes, err := self.getOnlineNodes()
+	if err != nil {
+		return nil, err
+	}
+	vpcs := []SVpc{}
+	for _, node := range nodes {
+		bridges, err := self.GetBridges(node)
+		if err != nil {
+			log.Errorf("GetBridges(%s) error: %v", node, err)
+			continue
+		}
+		for _, bridge := range bridges {
+			var vpc *SVpc
+			for i := range vpcs {
+				if vpcs[i].Iface == bridge.Iface {
+					vpc = &vpcs[i]
+					break
+				}
+			}
+			if vpc == nil {
+				vpcs = append(vpcs, SVpc{region: self, Iface: bridge.Iface})
+				vpc = &vpcs[len(vpcs)-1]
+			}
+			vpc.nodes = append(vpc.nodes, node)
+			if len(vpc.Cidr) == 0 && len(bridge.Cidr) > 0 {
+				vpc.Cidr, vpc.Gateway = bridge.Cidr, bridge.Gateway
+			}
+		}
+	}
+	return vpcs, nil
+}
+
+func (self *SRegion) GetVpc(id string) (*SVpc, error) {
+	vpcs, err := self.GetVpcs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range vpcs {
+		if vpcs[i].GetGlobalId() == id {
+			return &vpcs[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, "bridge %s", id)
+}
@@ -417,6 +417,11 @@ func init() {
 			"loadbalancer backendgroup",
 			"负载均衡服务器组",
 		},
+		sI18nElme{
+			api.TOPIC_RESOURCE_BUDGET,
+			"budget",
+			"预算",
+		},
 		sI18nElme{
 			api.TOPIC_RESOURCE_BUCKET,
 			"object storage bucket",
@@ -537,6 +542,16 @@ func init() {
 			"expired and released",
 			"到期释放",
 		},
+		sI18nElme{
+			string(api.ActionExpire),
+			"about to expire",
+			"即将到期",
+		},
+		sI18nElme{
+			string(api.ActionBudgetExceed),
+			"exceeded budget",
+			"超出预算",
+		},
 		sI18nElme{
 			string(api.ActionExecute),
 			"executed",
@@ -91,6 +91,12 @@ const (
 	DefaultSnapshotPolicyExecute   = "snapshot policy execute"
 	DefaultResourceOperationFailed = "resource operation failed"
 	DefaultResourceSync            = "resource sync"
+
+	DefaultCertificateExpireDue1Day  = "certificate expire due 1 day"
+	DefaultCertificateExpireDue3Day  = "certificate expire due 3 day"
+	DefaultCertificateExpireDue30Day = "certificate expire due 30 day"
+
+	DefaultBudgetExceed = "budget exceed"
 )
 
 func (sm *STopicManager) InitializeData() error {
@@ -106,6 +112,10 @@ func (sm *STopicManager) InitializeData() error {
 		DefaultSnapshotPolicyExecute,
 		DefaultResourceOperationFailed,
 		DefaultResourceSync,
+		DefaultCertificateExpireDue1Day,
+		DefaultCertificateExpireDue3Day,
+		DefaultCertificateExpireDue30Day,
+		DefaultBudgetExceed,
 	)
 	q := sm.Query()
 	topics := make([]STopic, 0, initSNames.Len())
@@ -281,6 +291,25 @@ func (sm *STopicManager) InitializeData() error {
 			)
 			t.Type = notify.TOPIC_TYPE_RESOURCE
 			t.WebconsoleDisable = tristate.True
+		case DefaultCertificateExpireDue1Day:
+			t.addResources(notify.TOPIC_RESOURCE_LOADBALANCERCERTIFICATE)
+			t.addAction(notify.ActionExpire)
+			t.Type = notify.TOPIC_TYPE_RESOURCE
+			t.AdvanceDays = 1
+		case DefaultCertificateExpireDue3Day:
+			t.addResources(notify.TOPIC_RESOURCE_LOADBALANCERCERTIFICATE)
+			t.addAction(notify.ActionExpire)
+			t.Type = notify.TOPIC_TYPE_RESOURCE
+			t.AdvanceDays = 3
+		case DefaultCertificateExpireDue30Day:
+			t.addResources(notify.TOPIC_RESOURCE_LOADBALANCERCERTIFICATE)
+			t.addAction(notify.ActionExpire)
+			t.Type = notify.TOPIC_TYPE_RESOURCE
+			t.AdvanceDays = 30
+		case DefaultBudgetExceed:
+			t.addResources(notify.TOPIC_RESOURCE_BUDGET)
+			t.addAction(notify.ActionBudgetExceed)
+			t.Type = notify.TOPIC_TYPE_RESOURCE
 		}
 		if topic == nil {
 			err := sm.TableSpec().Insert(ctx, t)
@@ -490,6 +519,7 @@ func init() {
 			notify.TOPIC_RESOURCE_DNSRECORDSET:             29,
 			notify.TOPIC_RESOURCE_LOADBALANCERLISTENER:     30,
 			notify.TOPIC_RESOURCE_LOADBALANCERBACKEDNGROUP: 31,
+			notify.TOPIC_RESOURCE_BUDGET:                   32,
 		},
 	)
 	converter.registerAction(
@@ -512,6 +542,8 @@ func init() {
 			notify.ActionSyncCreate:         15,
 			notify.ActionSyncUpdate:         16,
 			notify.ActionSyncDelete:         17,
+			notify.ActionExpire:             18,
+			notify.ActionBudgetExceed:       19,
 		},
 	)
 }
@@ -38,6 +38,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/devtool"
 	"yunion.io/x/onecloud/pkg/mcclient/options"
 	sop "yunion.io/x/onecloud/pkg/scheduledtask/options"
 	"yunion.io/x/onecloud/pkg/util/httputils"
@@ -77,6 +78,13 @@ type SScheduledTask struct {
 	ResourceType string `width:"32" charset:"ascii" create:"required" list:"user" get:"user"`
 	Operation    string `width:"32" charset:"ascii" create:"required" list:"user" get:"user"`
 	LabelType    string `width:"4" charset:"ascii" create:"required" list:"user" get:"user"`
+
+	// 操作参数
+	OperationParams jsonutils.JSONObject `nullable:"true" create:"optional" list:"user" get:"user" update:"user"`
+	// 单次执行时同时操作资源的最大数量
+	MaxConcurrency int `nullable:"false" default:"20" create:"optional" list:"user" get:"user" update:"user"`
+	// 错过执行时间后的补偿策略
+	CatchUp string `width:"8" charset:"ascii" default:"skip" create:"optional" list:"user" get:"user" update:"user"`
 }
 
 func (stm *SScheduledTaskManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, input api.ScheduledTaskListInput) (*sqlchemy.SQuery, error) {
@@ -140,6 +148,11 @@ func (st *SScheduledTask) getMoreDetails(ctx context.Context, userCred mcclient.
 		out.Timer = st.STimer.TimerDetails()
 	case api.ST_TYPE_CYCLE:
 		out.CycleTimer = st.STimer.CycleTimerDetails()
+	case api.ST_TYPE_CRON:
+		out.CronTimer = st.STimer.CronTimerDetails()
+	}
+	if !st.IsExpired {
+		out.NextTime = st.NextTime
 	}
 	out.TimerDesc = st.Description(ctx, zone)
 	// fill label
@@ -163,23 +176,43 @@ func (stm *SScheduledTaskManager) ValidateCreateData(ctx context.Context, userCr
 	if err != nil {
 		return input, err
 	}
-	if !utils.IsInStringArray(input.ScheduledType, []string{api.ST_TYPE_TIMING, api.ST_TYPE_CYCLE}) {
+	if !utils.IsInStringArray(input.ScheduledType, []string{api.ST_TYPE_TIMING, api.ST_TYPE_CYCLE, api.ST_TYPE_CRON}) {
 		return input, httperrors.NewInputParameterError("unkown scheduled type '%s'", input.ScheduledType)
 	}
-	if !utils.IsInStringArray(input.ResourceType, []string{api.ST_RESOURCE_SERVER, api.ST_RESOURCE_CLOUDACCOUNT}) {
+	if _, ok := Modules[Resource(input.ResourceType)]; !ok {
 		return input, httperrors.NewInputParameterError("unkown resource type '%s'", input.ResourceType)
 	}
-	if !utils.IsInStringArray(input.Operation, []string{api.ST_RESOURCE_OPERATION_RESTART, api.ST_RESOURCE_OPERATION_STOP, api.ST_RESOURCE_OPERATION_START, api.ST_RESOURCE_OPERATION_SYNC}) {
-		return input, httperrors.NewInputParameterError("unkown resource operation '%s'", input.Operation)
+	oper, ok := ResourceOperationMap[fmt.Sprintf("%s.%s", input.ResourceType, input.Operation)]
+	if !ok {
+		return input, httperrors.NewInputParameterError("unkown resource operation '%s' for %s", input.Operation, input.ResourceType)
 	}
 	if !utils.IsInStringArray(input.LabelType, []string{api.ST_LABEL_ID, api.ST_LABEL_TAG}) {
 		return input, httperrors.NewInputParameterError("unkown label type '%s'", input.LabelType)
 	}
+	input.OperationParams, err = checkOperationParams(oper, input.OperationParams)
+	if err != nil {
+		return input, err
+	}
+	if input.MaxConcurrency < 0 {
+		return input, httperrors.NewInputParameterError("max_concurrency should not be negative")
+	}
+	if input.MaxConcurrency == 0 {
+		input.MaxConcurrency = api.ST_MAX_CONCURRENCY_DEFAULT
+	}
+	if len(input.CatchUp) == 0 {
+		input.CatchUp = api.ST_CATCH_UP_SKIP
+	}
+	if !utils.IsInStringArray(input.CatchUp, []string{api.ST_CATCH_UP_SKIP, api.ST_CATCH_UP_ONCE}) {
+		return input, httperrors.NewInputParameterError("unkown catch_up '%s'", input.CatchUp)
+	}
 	// check timer or cycletimer
-	if input.ScheduledType == api.ST_TYPE_TIMING {
+	switch input.ScheduledType {
+	case api.ST_TYPE_TIMING:
 		input.Timer, err = checkTimerCreateInput(input.Timer)
-	} else {
+	case api.ST_TYPE_CYCLE:
 		input.CycleTimer, err = checkCycleTimerCreateInput(input.CycleTimer)
+	case api.ST_TYPE_CRON:
+		input.CronTimer, err = checkCronTimerCreateInput(input.CronTimer)
 	}
 	if err != nil {
 		return input, httperrors.NewInputParameterError("%v", err)
@@ -187,12 +220,58 @@ func (stm *SScheduledTaskManager) ValidateCreateData(ctx context.Context, userCr
 	return input, nil
 }
 
+func checkOperationParams(oper ResourceOperation, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	dict := jsonutils.NewDict()
+	if params != nil {
+		d, ok := params.(*jsonutils.JSONDict)
+		if !ok {
+			return nil, httperrors.NewInputParameterError("operation_params should be a dict")
+		}
+		dict = d
+	}
+	if oper.ValidateParams != nil {
+		if err := oper.ValidateParams(dict); err != nil {
+			return nil, httperrors.NewInputParameterError("invalid operation_params for %s %s: %v", oper.Resource, oper.Operation, err)
+		}
+	}
+	return dict, nil
+}
+
+func (st *SScheduledTask) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ScheduledTaskUpdateInput) (api.ScheduledTaskUpdateInput, error) {
+	var err error
+	input.VirtualResourceBaseUpdateInput, err = st.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.OperationParams != nil {
+		input.OperationParams, err = checkOperationParams(st.ResourceOperation(), input.OperationParams)
+		if err != nil {
+			return input, err
+		}
+	}
+	if input.MaxConcurrency != nil && *input.MaxConcurrency <= 0 {
+		return input, httperrors.NewInputParameterError("max_concurrency should be positive")
+	}
+	if len(input.CatchUp) > 0 && !utils.IsInStringArray(input.CatchUp, []string{api.ST_CATCH_UP_SKIP, api.ST_CATCH_UP_ONCE}) {
+		return input, httperrors.NewInputParameterError("unkown catch_up '%s'", input.CatchUp)
+	}
+	return input, nil
+}
+
 func (st *SScheduledTask) PerformEnable(ctx context.Context, userCred mcclient.TokenCredential,
 	query jsonutils.JSONObject, input apis.PerformEnableInput) (jsonutils.JSONObject, error) {
 	err := db.EnabledPerformEnable(st, ctx, userCred, true)
 	if err != nil {
 		return nil, errors.Wrap(err, "EnabledPerformEnable")
 	}
+	// runs missed while disabled should not be caught up
+	_, err = db.Update(st, func() error {
+		st.STimer.Update(time.Time{})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update next time")
+	}
 	return nil, nil
 }
 
@@ -247,6 +326,15 @@ func (st *SScheduledTask) PostCreate(ctx context.Context, userCred mcclient.Toke
 		}
 		st.SetWeekDays(input.CycleTimer.WeekDays)
 		st.SetMonthDays(input.CycleTimer.MonthDays)
+	case api.ST_TYPE_CRON:
+		st.STimer = STimer{
+			Type:           api.TIMER_TYPE_CRON,
+			CronExpression: input.CronTimer.Expression,
+			TimeZone:       input.CronTimer.TimeZone,
+			StartTime:      input.CronTimer.StartTime,
+			EndTime:        input.CronTimer.EndTime,
+			NextTime:       time.Time{},
+		}
 	}
 	st.Update(time.Time{})
 	st.Status = api.ST_STATUS_READY
@@ -375,7 +463,11 @@ func (st *SScheduledTask) CustomizeDelete(ctx context.Context, userCred mcclient
 
 func (st *SScheduledTask) Action(ctx context.Context, userCred mcclient.TokenCredential) SAction {
 	session := auth.GetSession(ctx, userCred, "", "")
-	return Action.ResourceOperation(st.ResourceOperation()).Session(session)
+	action := Action.ResourceOperation(st.ResourceOperation()).Session(session)
+	if params, ok := st.OperationParams.(*jsonutils.JSONDict); ok {
+		action = action.Params(params)
+	}
+	return action
 }
 
 func (st *SScheduledTask) ExecuteNotify(ctx context.Context, userCred mcclient.TokenCredential, name string) {
@@ -445,7 +537,10 @@ func (st *SScheduledTask) Execute(ctx context.Context, userCred mcclient.TokenCr
 		ids = append(ids, id)
 	}
 
-	maxLimit := 20
+	maxLimit := st.MaxConcurrency
+	if maxLimit <= 0 {
+		maxLimit = api.ST_MAX_CONCURRENCY_DEFAULT
+	}
 	type result struct {
 		id      string
 		succeed bool
@@ -453,11 +548,11 @@ func (st *SScheduledTask) Execute(ctx context.Context, userCred mcclient.TokenCr
 	}
 	workerQueue := make(chan struct{}, maxLimit)
 	results := make([]result, len(ids))
-	log.Infof("servers to scheduledtask: %v", ids)
+	log.Infof("%ss to scheduledtask: %v", st.ResourceType, ids)
 	for i, id := range ids {
 		workerQueue <- struct{}{}
 		go func(n int, id string) {
-			ok, reason := action.Apply(id)
+			ok, reason := action.Apply(id, res[id])
 			log.Infof("exec successfully: %t, reason: %s", ok, reason)
 			if ok {
 				st.ExecuteNotify(ctx, userCred, res[id])
@@ -562,7 +657,10 @@ func (stm *SScheduledTaskManager) Timer(ctx context.Context, userCred mcclient.T
 				<-timerQueue
 				<-waitQueue
 			}()
-			if st.NextTime.Before(timeScope.Start) {
+			if st.NextTime.Before(timeScope.Start) && st.CatchUp == api.ST_CATCH_UP_ONCE {
+				// Missed runs, e.g. while the service was down, are caught up once
+				log.Infof("scheduled task '%s' missed its run at %s, catch up once", st.Id, st.NextTime)
+			} else if st.NextTime.Before(timeScope.Start) {
 				// For unknown reasons, the scalingTimer did not execute at the specified time
 				st.Update(timeScope.Start)
 				// scalingTimer should not exec for now.
@@ -594,6 +692,10 @@ func (stm *SScheduledTaskManager) Timer(ctx context.Context, userCred mcclient.T
 func init() {
 	Register(ResourceServer, compute.Servers.ResourceManager)
 	Register(ResourceCloudAccount, compute.Cloudaccounts)
+	Register(ResourceDisk, compute.Disks)
+	Register(ResourceSnapshot, compute.Snapshots)
+	Register(ResourceScalingGroup, compute.ScalingGroup)
+	Register(ResourceDBInstance, compute.DBInstance)
 }
 
 // Modules describe the correspondence between Resource and modulebase.ResourceManager,
@@ -611,6 +713,10 @@ type Resource string
 const (
 	ResourceServer       Resource = api.ST_RESOURCE_SERVER
 	ResourceCloudAccount Resource = api.ST_RESOURCE_CLOUDACCOUNT
+	ResourceDisk         Resource = api.ST_RESOURCE_DISK
+	ResourceSnapshot     Resource = api.ST_RESOURCE_SNAPSHOT
+	ResourceScalingGroup Resource = api.ST_RESOURCE_SCALINGGROUP
+	ResourceDBInstance   Resource = api.ST_RESOURCE_DBINSTANCE
 )
 
 // ResourceOperation describe the operation for onecloud resource like create, update, delete and so on.
@@ -620,6 +726,14 @@ type ResourceOperation struct {
 	StatusSuccess []string
 	Fail          []ResourceOperationFail
 	Params        *jsonutils.JSONDict
+
+	// Action is the perform action of resource, Operation is used if empty
+	Action string
+	// Request replaces the perform action if the operation is not one,
+	// e.g. creating snapshot of a disk
+	Request func(session *mcclient.ClientSession, id, name string, params *jsonutils.JSONDict) error
+	// ValidateParams checks operation params given by user
+	ValidateParams func(params *jsonutils.JSONDict) error
 }
 
 type ResourceOperationFail struct {
@@ -662,22 +776,154 @@ func init() {
 		Operation: api.ST_RESOURCE_OPERATION_SYNC,
 		Params:    paramsAccoutSync,
 	}
-	ResourceOperationMap = map[string]ResourceOperation{
-		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_START):      ServerStart,
-		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_STOP):       ServerStop,
-		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_RESTART):    ServerRestart,
-		fmt.Sprintf("%s.%s", ResourceCloudAccount, api.ST_RESOURCE_OPERATION_SYNC): CloudAccountSync,
+	ServerSnapshot = ResourceOperation{
+		Resource:  ResourceServer,
+		Operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+		Request: func(session *mcclient.ClientSession, id, name string, params *jsonutils.JSONDict) error {
+			setSnapshotName(params, name)
+			_, err := compute.Servers.PerformAction(session, id, "instance-snapshot", params)
+			return err
+		},
+	}
+	ServerChangeConfig = ResourceOperation{
+		Resource:      ResourceServer,
+		Operation:     api.ST_RESOURCE_OPERATION_CHANGE_CONFIG,
+		StatusSuccess: []string{comapi.VM_READY, comapi.VM_RUNNING},
+		Fail: []ResourceOperationFail{
+			{comapi.VM_CHANGE_FLAVOR_FAIL, db.ACT_CHANGE_FLAVOR_FAIL},
+		},
+		ValidateParams: requireParams(),
+	}
+	ServerRunPlaybook = ResourceOperation{
+		Resource:  ResourceServer,
+		Operation: api.ST_RESOURCE_OPERATION_RUN_PLAYBOOK,
+		Request: func(session *mcclient.ClientSession, id, name string, params *jsonutils.JSONDict) error {
+			script, _ := params.GetString("script")
+			_, err := devtool.DevToolScripts.PerformAction(session, script, "apply", jsonutils.Marshal(map[string]string{"server_id": id}))
+			return err
+		},
+		ValidateParams: requireParams("script"),
+	}
+	DiskSnapshot = ResourceOperation{
+		Resource:  ResourceDisk,
+		Operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+		Request: func(session *mcclient.ClientSession, id, name string, params *jsonutils.JSONDict) error {
+			setSnapshotName(params, name)
+			params.Set("disk_id", jsonutils.NewString(id))
+			_, err := compute.Snapshots.Create(session, params)
+			return err
+		},
+	}
+	DiskResize = ResourceOperation{
+		Resource:      ResourceDisk,
+		Operation:     api.ST_RESOURCE_OPERATION_RESIZE,
+		StatusSuccess: []string{comapi.DISK_READY},
+		Fail: []ResourceOperationFail{
+			{comapi.DISK_RESIZE_FAILED, db.ACT_RESIZE_FAIL},
+		},
+		ValidateParams: requireParams("size"),
+	}
+	SnapshotDelete = ResourceOperation{
+		Resource:  ResourceSnapshot,
+		Operation: api.ST_RESOURCE_OPERATION_DELETE,
+		Request: func(session *mcclient.ClientSession, id, name string, params *jsonutils.JSONDict) error {
+			_, err := compute.Snapshots.Delete(session, id, nil)
+			return err
+		},
+	}
+	ScalingGroupEnable = ResourceOperation{
+		Resource:  ResourceScalingGroup,
+		Operation: api.ST_RESOURCE_OPERATION_ENABLE,
+	}
+	ScalingGroupDisable = ResourceOperation{
+		Resource:  ResourceScalingGroup,
+		Operation: api.ST_RESOURCE_OPERATION_DISABLE,
+	}
+	DBInstanceRestart = ResourceOperation{
+		Resource:      ResourceDBInstance,
+		Operation:     api.ST_RESOURCE_OPERATION_RESTART,
+		Action:        "reboot",
+		StatusSuccess: []string{comapi.DBINSTANCE_RUNNING},
+		Fail: []ResourceOperationFail{
+			{comapi.DBINSTANCE_REBOOT_FAILED, db.ACT_REBOOT},
+		},
+	}
+	DBInstanceSnapshot = ResourceOperation{
+		Resource:  ResourceDBInstance,
+		Operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+		Request: func(session *mcclient.ClientSession, id, name string, params *jsonutils.JSONDict) error {
+			setSnapshotName(params, name)
+			params.Set("dbinstance_id", jsonutils.NewString(id))
+			_, err := compute.DBInstanceBackups.Create(session, params)
+			return err
+		},
+	}
+	DBInstanceChangeConfig = ResourceOperation{
+		Resource:      ResourceDBInstance,
+		Operation:     api.ST_RESOURCE_OPERATION_CHANGE_CONFIG,
+		StatusSuccess: []string{comapi.DBINSTANCE_RUNNING},
+		Fail: []ResourceOperationFail{
+			{comapi.DBINSTANCE_CHANGE_CONFIG_FAILED, db.ACT_CHANGE_CONFIG},
+		},
+		ValidateParams: requireParams(),
+	}
+	ResourceOperationMap = map[string]ResourceOperation{}
+	for _, oper := range []ResourceOperation{
+		ServerStart, ServerStop, ServerRestart, ServerSnapshot, ServerChangeConfig, ServerRunPlaybook,
+		CloudAccountSync,
+		DiskSnapshot, DiskResize,
+		SnapshotDelete,
+		ScalingGroupEnable, ScalingGroupDisable,
+		DBInstanceRestart, DBInstanceSnapshot, DBInstanceChangeConfig,
+	} {
+		ResourceOperationMap[fmt.Sprintf("%s.%s", oper.Resource, oper.Operation)] = oper
 	}
 }
 
 var (
-	ServerStart          ResourceOperation
-	ServerStop           ResourceOperation
-	ServerRestart        ResourceOperation
-	CloudAccountSync     ResourceOperation
-	ResourceOperationMap map[string]ResourceOperation
+	ServerStart            ResourceOperation
+	ServerStop             ResourceOperation
+	ServerRestart          ResourceOperation
+	ServerSnapshot         ResourceOperation
+	ServerChangeConfig     ResourceOperation
+	ServerRunPlaybook      ResourceOperation
+	CloudAccountSync       ResourceOperation
+	DiskSnapshot           ResourceOperation
+	DiskResize             ResourceOperation
+	SnapshotDelete         ResourceOperation
+	ScalingGroupEnable     ResourceOperation
+	ScalingGroupDisable    ResourceOperation
+	DBInstanceRestart      ResourceOperation
+	DBInstanceSnapshot     ResourceOperation
+	DBInstanceChangeConfig ResourceOperation
+	ResourceOperationMap   map[string]ResourceOperation
 )
 
+// requireParams returns ValidateParams requiring the keys, or any param if
+// no key is given
+func requireParams(keys ...string) func(params *jsonutils.JSONDict) error {
+	return func(params *jsonutils.JSONDict) error {
+		if len(keys) == 0 && params.Length() == 0 {
+			return errors.Error("params should not be empty")
+		}
+		for _, key := range keys {
+			if !params.Contains(key) {
+				return errors.Errorf("missing %s", key)
+			}
+		}
+		return nil
+	}
+}
+
+// setSnapshotName names snapshots after the resource and the time taken
+// unless user specified
+func setSnapshotName(params *jsonutils.JSONDict, name string) {
+	if params.Contains("name") || params.Contains("generate_name") {
+		return
+	}
+	params.Set("generate_name", jsonutils.NewString(fmt.Sprintf("%s-%s", name, time.Now().Format("20060102150405"))))
+}
+
 // Action itself is meaningless, a meaningful Action is generated by
 // calling Resource, Operation, Session and DefaultParams.
 // A example:
@@ -689,6 +935,7 @@ type SAction struct {
 	operation ResourceOperation
 	session   *mcclient.ClientSession
 	timeout   time.Duration
+	params    *jsonutils.JSONDict
 }
 
 func (r SAction) ResourceOperation(oper ResourceOperation) SAction {
@@ -701,6 +948,13 @@ func (r SAction) Session(session *mcclient.ClientSession) SAction {
 	return r
 }
 
+// Params sets params given by user, which override the default ones of
+// the operation
+func (r SAction) Params(params *jsonutils.JSONDict) SAction {
+	r.params = params
+	return r
+}
+
 func (r SAction) Timeout(time time.Duration) SAction {
 	r.timeout = time
 	return r
@@ -732,26 +986,38 @@ func (r SAction) List(opts *WrapperListOptions) (map[string]string, error) {
 	return out, nil
 }
 
-func (r SAction) Apply(id string) (success bool, failReason string) {
+func (r SAction) Apply(id, name string) (success bool, failReason string) {
 	success = true
 	resourceManager, ok := Modules[r.operation.Resource]
 	if !ok {
 		return false, fmt.Sprintf("no such resource '%s' in Modules", r.operation.Resource)
 	}
-	var requestFunc func(session *mcclient.ClientSession, id string, params *jsonutils.JSONDict) error
+	var requestFunc func(session *mcclient.ClientSession, id, name string, params *jsonutils.JSONDict) error
 
-	action := utils.CamelSplit(r.operation.Operation, "-")
-	requestFunc = func(session *mcclient.ClientSession, id string, params *jsonutils.JSONDict) error {
-		if params == nil {
-			params = jsonutils.NewDict()
+	action := r.operation.Action
+	if len(action) == 0 {
+		action = utils.CamelSplit(r.operation.Operation, "-")
+	}
+	requestFunc = r.operation.Request
+	if requestFunc == nil {
+		requestFunc = func(session *mcclient.ClientSession, id, name string, params *jsonutils.JSONDict) error {
+			_, err := resourceManager.PerformAction(session, id, action, params)
+			return err
 		}
-		_, err := resourceManager.PerformAction(session, id, action, params)
-		return err
 	}
-	err := requestFunc(r.session, id, r.operation.Params)
+	params := jsonutils.NewDict()
+	if r.operation.Params != nil {
+		params.Update(r.operation.Params)
+	}
+	if r.params != nil {
+		params.Update(r.params)
+	}
+	err := requestFunc(r.session, id, name, params)
 	if err != nil {
-		clientErr, _ := err.(*httputils.JSONClientError)
-		return false, clientErr.Details
+		if clientErr, ok := err.(*httputils.JSONClientError); ok {
+			return false, clientErr.Details
+		}
+		return false, err.Error()
 	}
 	if len(r.operation.StatusSuccess) == 0 {
 		return true, ""
@@ -27,6 +27,7 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/scheduledtask"
 	"yunion.io/x/onecloud/pkg/i18n"
 	"yunion.io/x/onecloud/pkg/util/bitmap"
+	"yunion.io/x/onecloud/pkg/util/cronexpr"
 )
 
 type STimer struct {
@@ -40,6 +41,10 @@ type STimer struct {
 	WeekDays uint8 `nullable:"false"`
 	// 0-31 0 is unlimited
 	MonthDays uint32 `nullable:"false"`
+	// Cron expression, valid when Type is cron
+	CronExpression string `width:"128" charset:"ascii"`
+	// Time zone of cron expression, UTC if empty
+	TimeZone string `width:"64" charset:"ascii"`
 
 	// StartTime represent the start time of this timer
 	StartTime time.Time
@@ -55,7 +60,7 @@ func (st *STimer) Update(now time.Time) {
 	if now.IsZero() {
 		now = time.Now()
 	}
-	if !now.Before(st.EndTime) {
+	if !st.EndTime.IsZero() && !now.Before(st.EndTime) {
 		st.IsExpired = true
 		return
 	}
@@ -65,6 +70,10 @@ func (st *STimer) Update(now time.Time) {
 	if !st.NextTime.Before(now) {
 		return
 	}
+	if st.Type == api.TIMER_TYPE_CRON {
+		st.updateCron(now)
+		return
+	}
 
 	newNextTime := time.Date(now.Year(), now.Month(), now.Day(), st.Hour, st.Minute, 0, 0, now.Location())
 	if now.After(newNextTime) {
@@ -109,6 +118,40 @@ func (st *STimer) Update(now time.Time) {
 	}
 }
 
+// updateCron sets NextTime to the first time matching the cron expression
+// that is not before now
+func (st *STimer) updateCron(now time.Time) {
+	expr, err := cronexpr.Parse(st.CronExpression)
+	if err != nil {
+		log.Errorf("invalid cron expression %q: %v", st.CronExpression, err)
+		st.IsExpired = true
+		return
+	}
+	newNextTime := expr.Next(now.In(st.Location()).Add(-time.Nanosecond))
+	log.Debugf("The final NextTime: %s", newNextTime)
+	if newNextTime.IsZero() {
+		st.IsExpired = true
+		return
+	}
+	st.NextTime = newNextTime
+	if !st.EndTime.IsZero() && st.NextTime.After(st.EndTime) {
+		st.IsExpired = true
+	}
+}
+
+// Location returns the time zone the cron expression is evaluated in
+func (st *STimer) Location() *time.Location {
+	if st.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(st.TimeZone)
+	if err != nil {
+		log.Errorf("unable to load time zone %s: %v", st.TimeZone, err)
+		return time.UTC
+	}
+	return loc
+}
+
 // MonthDaySum calculate the number of month's days
 func (st *STimer) MonthDaySum(t time.Time) int {
 	year, month := t.Year(), t.Month()
@@ -155,6 +198,15 @@ func (st *STimer) CycleTimerDetails() api.CycleTimerDetails {
 	return out
 }
 
+func (st *STimer) CronTimerDetails() api.CronTimerDetails {
+	return api.CronTimerDetails{
+		Expression: st.CronExpression,
+		TimeZone:   st.TimeZone,
+		StartTime:  st.StartTime,
+		EndTime:    st.EndTime,
+	}
+}
+
 func checkTimerCreateInput(in api.TimerCreateInput) (api.TimerCreateInput, error) {
 	now := time.Now()
 	if now.After(in.ExecTime) {
@@ -194,6 +246,12 @@ func (st *STimer) descChinese(zone *time.Location) string {
 	switch st.Type {
 	case api.TIMER_TYPE_ONCE:
 		return fmt.Sprintf("单次 %s触发", st.StartTime.In(zone).Format(format))
+	case api.TIMER_TYPE_CRON:
+		desc := fmt.Sprintf("Cron表达式 【%s】(时区%s)触发", st.CronExpression, st.Location())
+		if st.EndTime.IsZero() {
+			return desc
+		}
+		return fmt.Sprintf("%s 有效时间为%s至%s", desc, st.StartTime.In(zone).Format(format), st.EndTime.In(zone).Format(format))
 	case api.TIMER_TYPE_DAY:
 		prefix = "每天"
 	case api.TIMER_TYPE_WEEK:
@@ -220,6 +278,8 @@ func (st *STimer) descEnglish(zone *time.Location) string {
 	switch st.Type {
 	case api.TIMER_TYPE_ONCE:
 		return st.EndTime.In(zone).Format(format)
+	case api.TIMER_TYPE_CRON:
+		detail = fmt.Sprintf("cron %q in time zone %s", st.CronExpression, st.Location())
 	case api.TIMER_TYPE_DAY:
 		detail = fmt.Sprintf("%d:%d every day", st.Hour, st.Minute)
 	case api.TIMER_TYPE_WEEK:
@@ -319,3 +379,22 @@ func checkCycleTimerCreateInput(in api.CycleTimerCreateInput) (api.CycleTimerCre
 	}
 	return in, nil
 }
+
+func checkCronTimerCreateInput(in api.CronTimerCreateInput) (api.CronTimerCreateInput, error) {
+	in.Expression = strings.TrimSpace(in.Expression)
+	if len(in.Expression) == 0 {
+		return in, fmt.Errorf("expression should not be empty")
+	}
+	if _, err := cronexpr.Parse(in.Expression); err != nil {
+		return in, fmt.Errorf("invalid cron expression %q: %v", in.Expression, err)
+	}
+	if len(in.TimeZone) > 0 {
+		if _, err := time.LoadLocation(in.TimeZone); err != nil {
+			return in, fmt.Errorf("invalid time_zone %q: %v", in.TimeZone, err)
+		}
+	}
+	if !in.EndTime.IsZero() && time.Now().After(in.EndTime) {
+		return in, fmt.Errorf("end_time is earlier than now")
+	}
+	return in, nil
+}
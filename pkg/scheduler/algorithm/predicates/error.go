@@ -43,6 +43,7 @@ const (
 	ErrBaremetalHasAlreadyBeenOccupied        = `baremetal has already been occupied`
 	ErrPrepaidHostOccupied                    = `prepaid host occupied`
 	ErrHostCpuArchitectureNotMatch            = `host cpu architecture not match`
+	ErrInstanceGroupTopologyNotMatch          = `instance group topology not match`
 
 	ErrUnknown = `unknown error`
 )
@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// InstanceGroupPredicate filters out the candidates breaking the hard affinity,
+// anti-affinity or spread constraints of instance groups at topology level.
+// The classic host anti-affinity groups are limited while selecting hosts.
+type InstanceGroupPredicate struct {
+	predicates.BasePredicate
+
+	topologies map[string]*core.InstanceGroupTopology
+}
+
+func (p *InstanceGroupPredicate) Name() string {
+	return "instance_group"
+}
+
+func (p *InstanceGroupPredicate) Clone() core.FitPredicate {
+	return &InstanceGroupPredicate{}
+}
+
+func (p *InstanceGroupPredicate) PreExecute(u *core.Unit, cs []core.Candidater) (bool, error) {
+	schedData := u.SchedData()
+	if len(schedData.InstanceGroupsDetail) == 0 {
+		return false, nil
+	}
+	topologies, err := core.BuildInstanceGroupTopologies(cs, schedData)
+	if err != nil {
+		return false, err
+	}
+	p.topologies = make(map[string]*core.InstanceGroupTopology)
+	for id, t := range topologies {
+		if !t.Group.IsForced() {
+			continue
+		}
+		// in a batch schedule, a domain crowded now may fit after guests are
+		// placed in other domains, so spread is only checked while selecting hosts
+		if t.Group.GetPolicy() == compute.INSTANCE_GROUP_POLICY_SPREAD && schedData.Count > 1 {
+			continue
+		}
+		p.topologies[id] = t
+	}
+	return len(p.topologies) > 0, nil
+}
+
+func (p *InstanceGroupPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+
+	for _, t := range p.topologies {
+		if !t.Fit(c.IndexKey()) {
+			h.Exclude(fmt.Sprintf("%s: group %s %s by %s", predicates.ErrInstanceGroupTopologyNotMatch, t.Group.Name, t.Group.GetPolicy(), t.Group.GetTopologyKey()))
+			break
+		}
+	}
+	return h.GetResult()
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+)
+
+// InstanceGroupPriority prefers the candidates fitting the affinity,
+// anti-affinity or spread policies of instance groups better, the scores
+// of groups are averaged with their weights
+type InstanceGroupPriority struct {
+	priorities.BasePriority
+
+	topologies map[string]*core.InstanceGroupTopology
+}
+
+func (p *InstanceGroupPriority) Name() string {
+	return "guest_instance_group"
+}
+
+func (p *InstanceGroupPriority) Clone() core.Priority {
+	return &InstanceGroupPriority{}
+}
+
+func (p *InstanceGroupPriority) PreExecute(u *core.Unit, cs []core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	if len(u.SchedData().InstanceGroupsDetail) == 0 {
+		return false, nil, nil
+	}
+	topologies, err := core.BuildInstanceGroupTopologies(cs, u.SchedData())
+	if err != nil {
+		return false, nil, err
+	}
+	p.topologies = topologies
+	return len(p.topologies) > 0, nil, nil
+}
+
+func (p *InstanceGroupPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	// weighted average of the scores of groups, in range [0, 100]
+	val, weights := 0, 0
+	for _, t := range p.topologies {
+		val += t.Group.Weight * t.Score(c.IndexKey())
+		weights += t.Group.Weight
+	}
+	if weights > 0 {
+		h.SetScore(val / weights)
+	}
+	return h.GetResult()
+}
+
+func (p *InstanceGroupPriority) ScoreIntervals() score.Intervals {
+	return score.NewIntervals(0, 34, 67)
+}
@@ -34,6 +34,13 @@ func transToInstanceGroupSchedResult(result *SchedResultItemList, schedInfo *api
 	}
 	guestInfos, backGuestInfos, groups := generateGuestInfo(schedInfo)
 	hosts := buildHosts(result, groups)
+	topologies := buildTopologies(result, schedInfo)
+	for i := range guestInfos {
+		guestInfos[i].topologies = topologies
+	}
+	for i := range backGuestInfos {
+		backGuestInfos[i].topologies = topologies
+	}
 	if len(backGuestInfos) > 0 {
 		return getBackupSchedResult(hosts, guestInfos, backGuestInfos, schedInfo.SessionId)
 	}
@@ -44,6 +51,18 @@ type sGuestInfo struct {
 	schedInfo            *api.SchedInfo
 	instanceGroupsDetail map[string]*models.SGroup
 	preferHost           string
+	// topologies of the groups which are not host anti-affinity ones, shared by all guests
+	topologies map[string]*InstanceGroupTopology
+}
+
+func (info *sGuestInfo) topologyScore(hostId string) int64 {
+	var score int64
+	for id := range info.instanceGroupsDetail {
+		if t, ok := info.topologies[id]; ok {
+			score += int64(t.Group.Weight * t.Score(hostId))
+		}
+	}
+	return score
 }
 
 type sSchedResultItem struct {
@@ -72,6 +91,9 @@ func buildHosts(result *SchedResultItemList, groups map[string]*models.SGroup) [
 		getter := result.Data[i].Candidater.Getter()
 		igCapacity := make(map[string]int64)
 		for id, group := range groups {
+			if group != nil && !group.IsHostAntiAffinity() {
+				continue
+			}
 			c, err := getter.GetFreeGroupCount(id)
 			if err != nil {
 				if errors.Cause(err) == ErrInstanceGroupNotFound {
@@ -92,10 +114,25 @@ func buildHosts(result *SchedResultItemList, groups map[string]*models.SGroup) [
 	return hosts
 }
 
+// buildTopologies builds the topologies of instance groups, the classic host
+// anti-affinity groups are still limited by instanceGroupCapacity
+func buildTopologies(result *SchedResultItemList, schedInfo *api.SchedInfo) map[string]*InstanceGroupTopology {
+	candidates := make([]Candidater, len(result.Data))
+	for i := range result.Data {
+		candidates[i] = result.Data[i].Candidater
+	}
+	topologies, err := BuildInstanceGroupTopologies(candidates, schedInfo)
+	if err != nil {
+		log.Errorf("BuildInstanceGroupTopologies: %s", err.Error())
+		return map[string]*InstanceGroupTopology{}
+	}
+	return topologies
+}
+
 // sortHost sorts the host for guest that is the backup one of the high-availability guest
 // if isBackup is true and the master one if isBackup is false.
 func sortHosts(hosts []*sSchedResultItem, guestInfo *sGuestInfo, isBackup *bool) {
-	sortIndexi, sortIndexj := make([]int64, 5), make([]int64, 5)
+	sortIndexi, sortIndexj := make([]int64, 6), make([]int64, 6)
 	sort.Slice(hosts, func(i, j int) bool {
 		switch {
 		case isBackup == nil:
@@ -108,8 +145,11 @@ func sortHosts(hosts []*sSchedResultItem, guestInfo *sGuestInfo, isBackup *bool)
 		sortIndexi[1], sortIndexj[1] = hosts[i].Count, hosts[j].Count
 		sortIndexi[2], sortIndexj[2] = -(hosts[i].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail)), -(hosts[j].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail))
 		sortIndexi[3], sortIndexj[3] = scoreNormalization(hosts[i].Score, hosts[j].Score)
-		sortIndexi[4], sortIndexj[4] = -(hosts[i].Capacity), -(hosts[j].Capacity)
-		for i := 0; i < 5; i++ {
+		// topology only breaks ties of scores, which have taken it into
+		// account by guest_instance_group priority
+		sortIndexi[4], sortIndexj[4] = -(guestInfo.topologyScore(hosts[i].ID)), -(guestInfo.topologyScore(hosts[j].ID))
+		sortIndexi[5], sortIndexj[5] = -(hosts[i].Capacity), -(hosts[j].Capacity)
+		for i := 0; i < 6; i++ {
 			if sortIndexi[i] == sortIndexj[i] {
 				continue
 			}
@@ -291,6 +331,10 @@ func getBackupSchedResult(hosts []*sSchedResultItem, guestInfos, backGuestInfos
 
 func markHostUsed(host *sSchedResultItem, guestInfo sGuestInfo, isBackup *bool) {
 	for gid := range guestInfo.instanceGroupsDetail {
+		if t, ok := guestInfo.topologies[gid]; ok {
+			t.Add(host.ID, 1)
+			continue
+		}
 		host.instanceGroupCapacity[gid] = host.instanceGroupCapacity[gid] - 1
 	}
 	host.Capacity--
@@ -308,6 +352,10 @@ func markHostUsed(host *sSchedResultItem, guestInfo sGuestInfo, isBackup *bool)
 // unMarkHostUsed is the reverse operation of markHostUsed
 func unMarkHostUsed(host *sSchedResultItem, guestInfo sGuestInfo, isBackup *bool) {
 	for gid := range guestInfo.instanceGroupsDetail {
+		if t, ok := guestInfo.topologies[gid]; ok {
+			t.Add(host.ID, -1)
+			continue
+		}
 		host.instanceGroupCapacity[gid] = host.instanceGroupCapacity[gid] + 1
 	}
 	host.Capacity++
@@ -344,6 +392,12 @@ Loop:
 		}
 		// check forced instanceGroup
 		for id, group := range guestInfo.instanceGroupsDetail {
+			if t, ok := guestInfo.topologies[id]; ok {
+				if (forced || t.Group.IsForced()) && !t.Fit(host.ID) {
+					continue Loop
+				}
+				continue
+			}
 			capacity := host.instanceGroupCapacity[id]
 			checkCapacity := forced || group.ForceDispersion.IsTrue()
 			if checkCapacity && capacity <= 0 {
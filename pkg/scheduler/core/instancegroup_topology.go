@@ -0,0 +1,140 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"yunion.io/x/pkg/errors"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+)
+
+// InstanceGroupTopology tracks the guest counts of an instance group in the
+// topology domains of candidates during a schedule
+type InstanceGroupTopology struct {
+	Group *models.SGroup
+
+	// hostDomains maps candidate id to its topology domain
+	hostDomains map[string]string
+	// counts records guest counts of all domains which have guests of group
+	// and all domains of candidates
+	counts map[string]int
+}
+
+// NewInstanceGroupTopology counts the guests of group in database and the
+// pending ones of candidates, the guests being scheduled are excluded
+func NewInstanceGroupTopology(group *models.SGroup, candidates []Candidater, schedInfo *api.SchedInfo) (*InstanceGroupTopology, error) {
+	counts, err := group.GetTopologyGuestCounts(scheduledGuestIds(schedInfo))
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetTopologyGuestCounts of group %s", group.Id)
+	}
+	t := &InstanceGroupTopology{
+		Group:       group,
+		hostDomains: make(map[string]string, len(candidates)),
+		counts:      counts,
+	}
+	for _, c := range candidates {
+		getter := c.Getter()
+		var schedtags []models.SSchedtag
+		if group.GetTopologyKey() == computeapi.INSTANCE_GROUP_TOPOLOGY_SCHEDTAG {
+			schedtags = getter.HostSchedtags()
+		}
+		domain := group.GetTopologyDomain(getter.Host(), schedtags)
+		t.hostDomains[c.IndexKey()] = domain
+		if len(domain) == 0 {
+			continue
+		}
+		if _, ok := t.counts[domain]; !ok {
+			t.counts[domain] = 0
+		}
+		if usage, ok := getter.GetPendingUsage().InstanceGroupUsage[group.Id]; ok {
+			t.counts[domain] += usage.ReferCount
+		}
+	}
+	return t, nil
+}
+
+func scheduledGuestIds(schedInfo *api.SchedInfo) []string {
+	ids := []string{}
+	if len(schedInfo.Id) > 0 {
+		ids = append(ids, schedInfo.Id)
+	}
+	for _, guest := range schedInfo.ForGuests {
+		if len(guest.Id) > 0 {
+			ids = append(ids, guest.Id)
+		}
+	}
+	return ids
+}
+
+// BuildInstanceGroupTopologies builds topologies of the instance groups which
+// are not the classic host anti-affinity ones
+func BuildInstanceGroupTopologies(candidates []Candidater, schedInfo *api.SchedInfo) (map[string]*InstanceGroupTopology, error) {
+	ret := make(map[string]*InstanceGroupTopology)
+	for id, group := range schedInfo.InstanceGroupsDetail {
+		if group == nil || group.IsHostAntiAffinity() {
+			continue
+		}
+		t, err := NewInstanceGroupTopology(group, candidates, schedInfo)
+		if err != nil {
+			return nil, err
+		}
+		ret[id] = t
+	}
+	return ret, nil
+}
+
+func (t *InstanceGroupTopology) Domain(hostId string) string {
+	return t.hostDomains[hostId]
+}
+
+// candidateCounts returns guest counts of the domains of candidates
+func (t *InstanceGroupTopology) candidateCounts() map[string]int {
+	ret := make(map[string]int)
+	for _, domain := range t.hostDomains {
+		if len(domain) > 0 {
+			ret[domain] = t.counts[domain]
+		}
+	}
+	return ret
+}
+
+func (t *InstanceGroupTopology) countsForPolicy() map[string]int {
+	// skew of spread is calculated among the reachable domains
+	if t.Group.GetPolicy() == computeapi.INSTANCE_GROUP_POLICY_SPREAD {
+		return t.candidateCounts()
+	}
+	return t.counts
+}
+
+// Fit tells whether one more guest of group could be placed on host
+func (t *InstanceGroupTopology) Fit(hostId string) bool {
+	return t.Group.CheckTopologyFit(t.Domain(hostId), t.countsForPolicy())
+}
+
+// Score scores host in range [0, 100] for one more guest of group
+func (t *InstanceGroupTopology) Score(hostId string) int {
+	return t.Group.GetTopologyScore(t.Domain(hostId), t.countsForPolicy())
+}
+
+// Add changes the guest count of the domain of host
+func (t *InstanceGroupTopology) Add(hostId string, delta int) {
+	domain := t.Domain(hostId)
+	if len(domain) == 0 {
+		return
+	}
+	t.counts[domain] += delta
+}
@@ -0,0 +1,254 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cronexpr
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// Expression is a parsed standard 5-field cron expression
+//
+//	minute hour day-of-month month day-of-week
+//
+// Each field accepts '*', single values, ranges 'a-b', lists 'a,b' and
+// steps '*/n' or 'a-b/n'.  Months and week days also accept three letter
+// english names.  Week day 0 and 7 are both Sunday.  Like vixie cron, when
+// both day-of-month and day-of-week are restricted, either matching is
+// enough.  Macros @yearly, @annually, @monthly, @weekly, @daily, @midnight
+// and @hourly are also accepted
+type Expression struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	domStar bool
+	dowStar bool
+}
+
+type fieldBounds struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	boundsMinute = fieldBounds{name: "minute", min: 0, max: 59}
+	boundsHour   = fieldBounds{name: "hour", min: 0, max: 23}
+	boundsDom    = fieldBounds{name: "day-of-month", min: 1, max: 31}
+	boundsMonth  = fieldBounds{
+		name: "month",
+		min:  1,
+		max:  12,
+		names: map[string]int{
+			"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+			"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+		},
+	}
+	boundsDow = fieldBounds{
+		name: "day-of-week",
+		min:  0,
+		max:  7,
+		names: map[string]int{
+			"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+		},
+	}
+
+	macros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// Parse parses the cron expression
+func Parse(spec string) (*Expression, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := macros[strings.ToLower(spec)]
+		if !ok {
+			return nil, errors.Errorf("unknown macro %s", spec)
+		}
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("expect 5 fields, got %d", len(fields))
+	}
+	var (
+		e   = &Expression{}
+		err error
+	)
+	if e.minute, err = parseField(fields[0], boundsMinute); err != nil {
+		return nil, err
+	}
+	if e.hour, err = parseField(fields[1], boundsHour); err != nil {
+		return nil, err
+	}
+	if e.dom, err = parseField(fields[2], boundsDom); err != nil {
+		return nil, err
+	}
+	if e.month, err = parseField(fields[3], boundsMonth); err != nil {
+		return nil, err
+	}
+	if e.dow, err = parseField(fields[4], boundsDow); err != nil {
+		return nil, err
+	}
+	// 7 is an alias of Sunday
+	if e.dow&(1<<7) != 0 {
+		e.dow = (e.dow | 1) &^ (1 << 7)
+	}
+	e.domStar = strings.HasPrefix(fields[2], "*")
+	e.dowStar = strings.HasPrefix(fields[4], "*")
+	return e, nil
+}
+
+func parseField(field string, b fieldBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		r, err := parseRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= r
+	}
+	return bits, nil
+}
+
+func parseRange(part string, b fieldBounds) (uint64, error) {
+	var (
+		start, end int
+		step       = 1
+		err        error
+	)
+	rangeStr := part
+	if i := strings.Index(part, "/"); i >= 0 {
+		rangeStr = part[:i]
+		step, err = strconv.Atoi(part[i+1:])
+		if err != nil || step <= 0 {
+			return 0, errors.Errorf("invalid step in %s field: %s", b.name, part)
+		}
+	}
+	switch {
+	case rangeStr == "*":
+		start, end = b.min, b.max
+	case strings.Contains(rangeStr, "-"):
+		i := strings.Index(rangeStr, "-")
+		if start, err = parseValue(rangeStr[:i], b); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(rangeStr[i+1:], b); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, errors.Errorf("invalid range in %s field: %s", b.name, part)
+		}
+	default:
+		if start, err = parseValue(rangeStr, b); err != nil {
+			return 0, err
+		}
+		end = start
+		if step > 1 {
+			// 'a/n' means from a to the max
+			end = b.max
+		}
+	}
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b fieldBounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Errorf("invalid value in %s field: %s", b.name, s)
+	}
+	if v < b.min || v > b.max {
+		return 0, errors.Errorf("%s field value %d out of range [%d, %d]", b.name, v, b.min, b.max)
+	}
+	return v, nil
+}
+
+func has(bits uint64, i int) bool {
+	return bits&(1<<uint(i)) != 0
+}
+
+func (e *Expression) dayMatches(t time.Time) bool {
+	domMatch := has(e.dom, t.Day())
+	dowMatch := has(e.dow, int(t.Weekday()))
+	if e.domStar || e.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the earliest time matching the expression that is strictly
+// after t, in the location of t.  Zero time is returned if there is no such
+// time in the following 5 years, e.g. "0 0 30 2 *"
+func (e *Expression) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for !has(e.month, int(t.Month())) {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+	for !e.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+	for !has(e.hour, t.Hour()) {
+		prev := t
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if !t.After(prev) {
+			// stuck in daylight saving transition
+			t = prev.Add(time.Hour).Truncate(time.Hour)
+		}
+		if t.Hour() == 0 || t.Day() != prev.Day() {
+			goto WRAP
+		}
+	}
+	for !has(e.minute, t.Minute()) {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	return t
+}
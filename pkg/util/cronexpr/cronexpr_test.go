@@ -0,0 +1,101 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cronexpr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"@every",
+		"* * * foo *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expect error for %q", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	cases := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{
+			spec: "*/15 * * * *",
+			from: time.Date(2021, 3, 1, 10, 7, 30, 0, time.UTC),
+			want: time.Date(2021, 3, 1, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 2 * * *",
+			from: time.Date(2021, 3, 1, 2, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 3, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "30 8 * * mon-fri",
+			from: time.Date(2021, 3, 5, 9, 0, 0, 0, shanghai),
+			want: time.Date(2021, 3, 8, 8, 30, 0, 0, shanghai),
+		},
+		{
+			spec: "0 0 31 * *",
+			from: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 5, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 0 29 2 *",
+			from: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// day-of-month or day-of-week when both restricted
+			spec: "0 12 1 * 7",
+			from: time.Date(2021, 3, 1, 13, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 3, 7, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "@monthly",
+			from: time.Date(2021, 12, 15, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 0 30 2 *",
+			from: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Time{},
+		},
+	}
+	for _, c := range cases {
+		e, err := Parse(c.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", c.spec, err)
+		}
+		got := e.Next(c.from)
+		if !got.Equal(c.want) {
+			t.Errorf("%q next of %s: want %s, got %s", c.spec, c.from, c.want, got)
+		}
+	}
+}
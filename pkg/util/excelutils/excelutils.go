@@ -16,6 +16,7 @@ package excelutils
 
 import (
 	"bytes"
+	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
@@ -82,20 +83,24 @@ func exportHeader(xlsx *excelize.File, texts []string, rowIndex int) {
 	}
 }
 
+func getCellValue(data jsonutils.JSONObject, key string) string {
+	var valStr string
+	var val jsonutils.JSONObject
+	if strings.Contains(key, ".") {
+		val, _ = data.GetIgnoreCases(strings.Split(key, ".")...)
+	} else {
+		val, _ = data.GetIgnoreCases(key)
+	}
+	if val != nil {
+		valStr, _ = val.GetString()
+	}
+	return valStr
+}
+
 func exportRow(xlsx *excelize.File, data jsonutils.JSONObject, keys []string, rowIndex int) {
 	for i := 0; i < len(keys); i += 1 {
-		var valStr string
-		var val jsonutils.JSONObject
-		if strings.Contains(keys[i], ".") {
-			val, _ = data.GetIgnoreCases(strings.Split(keys[i], ".")...)
-		} else {
-			val, _ = data.GetIgnoreCases(keys[i])
-		}
-		if val != nil {
-			valStr, _ = val.GetString()
-		}
 		cell := fmt.Sprintf("%s%d", decimal2Alphabet(i), rowIndex)
-		xlsx.SetCellValue(DEFAULT_SHEET, cell, valStr)
+		xlsx.SetCellValue(DEFAULT_SHEET, cell, getCellValue(data, keys[i]))
 	}
 }
 
@@ -119,3 +124,34 @@ func ExportFile(data []jsonutils.JSONObject, keys []string, texts []string, file
 
 	return Export(data, keys, texts, writer)
 }
+
+// ExportCsv exports data in the same layout of Export in CSV format
+func ExportCsv(data []jsonutils.JSONObject, keys []string, texts []string, writer io.Writer) error {
+	w := csv.NewWriter(writer)
+	err := w.Write(texts)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(data); i += 1 {
+		row := make([]string, len(keys))
+		for j := 0; j < len(keys); j += 1 {
+			row[j] = getCellValue(data[i], keys[j])
+		}
+		err = w.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func ExportCsvFile(data []jsonutils.JSONObject, keys []string, texts []string, filename string) error {
+	writer, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	return ExportCsv(data, keys, texts, writer)
+}
@@ -14,7 +14,12 @@
 
 package excelutils
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
 
 func arrayEqual(a1, a2 []int) bool {
 	if len(a1) != len(a2) {
@@ -105,3 +110,19 @@ func TestDecimalBaseMaxWidth(t *testing.T) {
 		}
 	}
 }
+
+func TestExportCsv(t *testing.T) {
+	data := []jsonutils.JSONObject{
+		jsonutils.Marshal(map[string]interface{}{"name": "vm1", "amount": 1.5, "metadata": map[string]string{"owner": "a,b"}}),
+		jsonutils.Marshal(map[string]interface{}{"Name": "vm2"}),
+	}
+	buf := &bytes.Buffer{}
+	err := ExportCsv(data, []string{"name", "amount", "metadata.owner"}, []string{"Name", "Amount", "Owner"}, buf)
+	if err != nil {
+		t.Fatalf("ExportCsv: %v", err)
+	}
+	want := "Name,Amount,Owner\nvm1,1.5,\"a,b\"\nvm2,,\n"
+	if buf.String() != want {
+		t.Errorf("ExportCsv = %q != %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firewalld
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"yunion.io/x/pkg/util/secrules"
+)
+
+func NewIP6Rule(prio int, table, chain, body string) *Rule {
+	r := NewIP4Rule(prio, table, chain, body)
+	r.IPv = "ipv6"
+	return r
+}
+
+// NewSecurityRule translates a security group rule into a filter rule of
+// chain.  Rules with ipv6 address go to ip6tables and icmp is matched as
+// icmpv6 there
+func NewSecurityRule(prio int, chain string, rule *secrules.SecurityRule) *Rule {
+	var (
+		isIp6 bool
+		body  []string
+	)
+	ipnet := rule.IPNet
+	if ipnet != nil && ipnet.IP.To4() == nil && len(ipnet.IP) == net.IPv6len {
+		isIp6 = true
+		if ones, bits := ipnet.Mask.Size(); bits != 8*net.IPv6len {
+			// single address parsed with an ipv4 sized mask
+			if ones == bits {
+				ones = 8 * net.IPv6len
+			}
+			ipnet = &net.IPNet{
+				IP:   ipnet.IP,
+				Mask: net.CIDRMask(ones, 8*net.IPv6len),
+			}
+		}
+	}
+	if ipnet != nil {
+		if ones, _ := ipnet.Mask.Size(); ones > 0 {
+			opt := "-s"
+			if rule.Direction == secrules.DIR_OUT {
+				opt = "-d"
+			}
+			body = append(body, opt, ipnet.String())
+		}
+	}
+	switch rule.Protocol {
+	case secrules.PROTO_TCP, secrules.PROTO_UDP:
+		body = append(body, "-p", rule.Protocol)
+		if len(rule.Ports) > 0 {
+			ports := make([]string, len(rule.Ports))
+			for i, port := range rule.Ports {
+				ports[i] = fmt.Sprintf("%d", port)
+			}
+			body = append(body, "-m", "multiport", "--dports", strings.Join(ports, ","))
+		} else if rule.PortStart > 0 && rule.PortEnd > 0 {
+			body = append(body, "--dport", fmt.Sprintf("%d:%d", rule.PortStart, rule.PortEnd))
+		}
+	case secrules.PROTO_ICMP:
+		if isIp6 {
+			body = append(body, "-p", "ipv6-icmp")
+		} else {
+			body = append(body, "-p", "icmp")
+		}
+	}
+	target := "ACCEPT"
+	if rule.Action == secrules.SecurityRuleDeny {
+		target = "DROP"
+	}
+	body = append(body, "-j", target)
+	if isIp6 {
+		return NewIP6Rule(prio, "filter", chain, strings.Join(body, " "))
+	}
+	return NewIP4Rule(prio, "filter", chain, strings.Join(body, " "))
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firewalld
+
+import (
+	"net"
+	"testing"
+
+	"yunion.io/x/pkg/util/secrules"
+)
+
+func TestNewSecurityRule(t *testing.T) {
+	_, v4net, _ := net.ParseCIDR("10.0.0.0/8")
+	_, v6net, _ := net.ParseCIDR("2001:db8::/32")
+	cases := []struct {
+		rule *secrules.SecurityRule
+		want string
+	}{
+		{
+			rule: &secrules.SecurityRule{
+				Action:    secrules.SecurityRuleAllow,
+				Direction: secrules.DIR_IN,
+				IPNet:     v4net,
+				Protocol:  secrules.PROTO_TCP,
+				PortStart: 22,
+				PortEnd:   22,
+			},
+			want: `<rule priority="1" table="filter" ipv="ipv4" chain="IN">-s 10.0.0.0/8 -p tcp --dport 22:22 -j ACCEPT</rule>`,
+		},
+		{
+			rule: &secrules.SecurityRule{
+				Action:    secrules.SecurityRuleDeny,
+				Direction: secrules.DIR_OUT,
+				IPNet:     v6net,
+				Protocol:  secrules.PROTO_ICMP,
+			},
+			want: `<rule priority="1" table="filter" ipv="ipv6" chain="IN">-d 2001:db8::/32 -p ipv6-icmp -j DROP</rule>`,
+		},
+		{
+			rule: &secrules.SecurityRule{
+				Action:    secrules.SecurityRuleAllow,
+				Direction: secrules.DIR_IN,
+				IPNet: &net.IPNet{
+					IP:   net.ParseIP("2001:db8::1"),
+					Mask: net.CIDRMask(32, 32),
+				},
+				Protocol: secrules.PROTO_UDP,
+				Ports:    []int{53, 5353},
+			},
+			want: `<rule priority="1" table="filter" ipv="ipv6" chain="IN">-s 2001:db8::1/128 -p udp -m multiport --dports 53,5353 -j ACCEPT</rule>`,
+		},
+	}
+	for i, c := range cases {
+		got := NewSecurityRule(1, "IN", c.rule).String()
+		if got != c.want {
+			t.Errorf("rule %d\n  got\n    %s\n  want\n    %s", i, got, c.want)
+		}
+	}
+}
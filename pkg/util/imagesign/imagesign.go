@@ -0,0 +1,304 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagesign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	// signature is valid and signed by a trusted certificate or key
+	STATUS_VERIFIED = "verified"
+	// no signature attached
+	STATUS_UNSIGNED = "unsigned"
+	// signature is valid, but the signer is not trusted
+	STATUS_UNTRUSTED = "untrusted"
+	// signature does not match the content
+	STATUS_INVALID = "invalid"
+
+	ErrInvalidSignature = errors.Error("invalid signature")
+	ErrNoSigner         = errors.Error("no certificate or public key")
+	ErrUnsupportedKey   = errors.Error("unsupported public key type")
+)
+
+// STrustStore holds the CA certificates and bare public keys that are
+// trusted to sign images
+type STrustStore struct {
+	roots *x509.CertPool
+	keys  [][]byte
+}
+
+// SResult is the outcome of a signature verification
+type SResult struct {
+	Status string
+	// subject of the signing certificate, or fingerprint of the public key
+	Signer string
+	// hex encoded sha256 digest of the signed content
+	Digest string
+}
+
+// NewTrustStore loads trusted CA certificates and public keys from the
+// given PEM bundles, empty paths are ignored
+func NewTrustStore(caFile, keysFile string) (*STrustStore, error) {
+	store := &STrustStore{
+		roots: x509.NewCertPool(),
+	}
+	if len(caFile) > 0 {
+		cont, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s", caFile)
+		}
+		if !store.roots.AppendCertsFromPEM(cont) {
+			return nil, errors.Errorf("no certificate found in %s", caFile)
+		}
+	}
+	if len(keysFile) > 0 {
+		cont, err := ioutil.ReadFile(keysFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s", keysFile)
+		}
+		for {
+			var blk *pem.Block
+			blk, cont = pem.Decode(cont)
+			if blk == nil {
+				break
+			}
+			pub, err := x509.ParsePKIXPublicKey(blk.Bytes)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse public key in %s", keysFile)
+			}
+			der, _ := x509.MarshalPKIXPublicKey(pub)
+			store.keys = append(store.keys, der)
+		}
+	}
+	return store, nil
+}
+
+func (store *STrustStore) isKeyTrusted(pub crypto.PublicKey) bool {
+	if store == nil {
+		return false
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return false
+	}
+	for i := range store.keys {
+		if bytes.Equal(store.keys[i], der) {
+			return true
+		}
+	}
+	return false
+}
+
+func (store *STrustStore) isCertTrusted(cert *x509.Certificate, intermediates []*x509.Certificate) bool {
+	if store == nil {
+		return false
+	}
+	if store.isKeyTrusted(cert.PublicKey) {
+		return true
+	}
+	pool := x509.NewCertPool()
+	for i := range intermediates {
+		pool.AddCert(intermediates[i])
+	}
+	// short-lived signing certificates (e.g. cosign keyless) expire shortly
+	// after signing, so validate the chain at the time of issuance
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         store.roots,
+		Intermediates: pool,
+		CurrentTime:   cert.NotBefore,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
+
+// DecodeBlob accepts either raw PEM content or its base64 encoding, the
+// latter is how the signer material is stored in image properties
+func DecodeBlob(str string) []byte {
+	str = strings.TrimSpace(str)
+	if strings.HasPrefix(str, "-----BEGIN") {
+		return []byte(str)
+	}
+	cont, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return []byte(str)
+	}
+	return cont
+}
+
+// EncodeBlob returns the single-line form of a PEM certificate or key
+func EncodeBlob(str string) string {
+	return base64.StdEncoding.EncodeToString(DecodeBlob(str))
+}
+
+func parseSigner(signer []byte) (crypto.PublicKey, *x509.Certificate, []*x509.Certificate, error) {
+	var (
+		pub   crypto.PublicKey
+		leaf  *x509.Certificate
+		chain []*x509.Certificate
+	)
+	for {
+		var blk *pem.Block
+		blk, signer = pem.Decode(signer)
+		if blk == nil {
+			break
+		}
+		switch blk.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(blk.Bytes)
+			if err != nil {
+				return nil, nil, nil, errors.Wrap(err, "ParseCertificate")
+			}
+			if leaf == nil {
+				leaf = cert
+				pub = cert.PublicKey
+			} else {
+				chain = append(chain, cert)
+			}
+		case "PUBLIC KEY":
+			if pub == nil {
+				key, err := x509.ParsePKIXPublicKey(blk.Bytes)
+				if err != nil {
+					return nil, nil, nil, errors.Wrap(err, "ParsePKIXPublicKey")
+				}
+				pub = key
+			}
+		}
+	}
+	if pub == nil {
+		return nil, nil, nil, ErrNoSigner
+	}
+	return pub, leaf, chain, nil
+}
+
+func verifyDigest(pub crypto.PublicKey, digest, sig []byte) error {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		var esig struct {
+			R, S *big.Int
+		}
+		if _, err := asn1.Unmarshal(sig, &esig); err != nil {
+			return errors.Wrap(ErrInvalidSignature, err.Error())
+		}
+		if !ecdsa.Verify(key, digest, esig.R, esig.S) {
+			return ErrInvalidSignature
+		}
+	case *rsa.PublicKey:
+		err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig)
+		if err != nil {
+			err = rsa.VerifyPSS(key, crypto.SHA256, digest, sig, nil)
+		}
+		if err != nil {
+			return errors.Wrap(ErrInvalidSignature, err.Error())
+		}
+	default:
+		return ErrUnsupportedKey
+	}
+	return nil
+}
+
+func keyFingerprint(pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + hex.EncodeToString(sum[:])
+}
+
+// Verify checks a detached signature, in the cosign sign-blob format
+// (base64 of a signature over the sha256 digest of the content), against
+// the certificate or public key of the signer
+func Verify(digest []byte, signature string, signer string, store *STrustStore) (*SResult, error) {
+	result := &SResult{
+		Digest: hex.EncodeToString(digest),
+	}
+	if len(strings.TrimSpace(signature)) == 0 {
+		result.Status = STATUS_UNSIGNED
+		return result, nil
+	}
+	result.Status = STATUS_INVALID
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return result, errors.Wrap(err, "decode signature")
+	}
+	pub, leaf, chain, err := parseSigner(DecodeBlob(signer))
+	if err != nil {
+		return result, errors.Wrap(err, "parse signer")
+	}
+	if leaf != nil {
+		result.Signer = leaf.Subject.String()
+		if len(result.Signer) == 0 && len(leaf.EmailAddresses) > 0 {
+			result.Signer = leaf.EmailAddresses[0]
+		}
+	} else {
+		result.Signer = keyFingerprint(pub)
+	}
+	err = verifyDigest(pub, digest, sig)
+	if err != nil {
+		return result, err
+	}
+	trusted := false
+	if leaf != nil {
+		trusted = store.isCertTrusted(leaf, chain)
+	} else {
+		trusted = store.isKeyTrusted(pub)
+	}
+	if trusted {
+		result.Status = STATUS_VERIFIED
+	} else {
+		result.Status = STATUS_UNTRUSTED
+	}
+	return result, nil
+}
+
+// FileDigest returns the sha256 digest of a file
+func FileDigest(filePath string) ([]byte, error) {
+	fp, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", filePath)
+	}
+	defer fp.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, fp); err != nil {
+		return nil, errors.Wrapf(err, "read %s", filePath)
+	}
+	return hash.Sum(nil), nil
+}
+
+// VerifyFile is a shortcut of FileDigest followed by Verify
+func VerifyFile(filePath string, signature string, signer string, store *STrustStore) (*SResult, error) {
+	digest, err := FileDigest(filePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "FileDigest")
+	}
+	return Verify(digest, signature, signer, store)
+}
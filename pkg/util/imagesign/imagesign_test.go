@@ -0,0 +1,107 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagesign
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imagesign")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "image signing ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, _ := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	caCert, _ := x509.ParseCertificate(caDer)
+	caFile := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDer}), 0644)
+
+	signKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "release"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Minute),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	signDer, _ := x509.CreateCertificate(rand.Reader, signTmpl, caCert, &signKey.PublicKey, caKey)
+	signCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: signDer}))
+	pubDer, _ := x509.MarshalPKIXPublicKey(&signKey.PublicKey)
+	signPub := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}))
+	keysFile := filepath.Join(dir, "keys.pem")
+	ioutil.WriteFile(keysFile, []byte(signPub), 0644)
+
+	content := []byte("image content")
+	digest := sha256.Sum256(content)
+	sigBytes, _ := ecdsa.SignASN1(rand.Reader, signKey, digest[:])
+	sig := base64.StdEncoding.EncodeToString(sigBytes)
+
+	caStore, err := NewTrustStore(caFile, "")
+	if err != nil {
+		t.Fatalf("NewTrustStore: %s", err)
+	}
+	keyStore, err := NewTrustStore("", keysFile)
+	if err != nil {
+		t.Fatalf("NewTrustStore: %s", err)
+	}
+
+	cases := []struct {
+		name      string
+		signature string
+		signer    string
+		store     *STrustStore
+		want      string
+	}{
+		{"unsigned", "", "", caStore, STATUS_UNSIGNED},
+		{"cert trusted", sig, signCert, caStore, STATUS_VERIFIED},
+		{"cert base64 trusted", sig, EncodeBlob(signCert), caStore, STATUS_VERIFIED},
+		{"cert untrusted", sig, signCert, nil, STATUS_UNTRUSTED},
+		{"key trusted", sig, signPub, keyStore, STATUS_VERIFIED},
+		{"key untrusted", sig, signPub, caStore, STATUS_UNTRUSTED},
+		{"tampered", base64.StdEncoding.EncodeToString(append(sigBytes[:len(sigBytes)-1], sigBytes[len(sigBytes)-1]^1)), signCert, caStore, STATUS_INVALID},
+		{"no signer", sig, "", caStore, STATUS_INVALID},
+	}
+	for _, c := range cases {
+		result, _ := Verify(digest[:], c.signature, c.signer, c.store)
+		if result.Status != c.want {
+			t.Errorf("%s: want %s got %s", c.name, c.want, result.Status)
+		}
+	}
+}
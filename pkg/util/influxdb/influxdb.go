@@ -176,7 +176,6 @@ func (db *SInfluxdb) SetDatabase(dbName string) error {
 		if err != nil {
 			return err
 		}
-		return nil
 	}
 	db.dbName = dbName
 	return nil
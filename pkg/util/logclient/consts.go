@@ -112,8 +112,9 @@ const (
 	ACT_OPEN_PUBLIC_CONNECTION  = "open_public_connection"
 	ACT_CLOSE_PUBLIC_CONNECTION = "close_public_connection"
 
-	ACT_IMAGE_SAVE  = "image_save"
-	ACT_IMAGE_PROBE = "image_probe"
+	ACT_IMAGE_SAVE             = "image_save"
+	ACT_IMAGE_PROBE            = "image_probe"
+	ACT_IMAGE_VERIFY_SIGNATURE = "image_verify_signature"
 
 	ACT_AUTHENTICATE = "authenticate"
 
@@ -392,6 +392,10 @@ func init() {
 		EN("Image Probe").
 		CN("镜像检测"),
 	)
+	t.Set(ACT_IMAGE_VERIFY_SIGNATURE, i18n.NewTableEntry().
+		EN("Image Verify Signature").
+		CN("镜像签名校验"),
+	)
 
 	t.Set(ACT_AUTHENTICATE, i18n.NewTableEntry().
 		EN("Authenticate").
@@ -62,6 +62,9 @@ type EthernetConfig struct {
 	Match       *EthernetConfigMatch `json:"match"`
 	MacAddress  string               `json:"macaddress"`
 	Gateway4    string               `json:"gateway4"`
+	DHCP6       *bool                `json:"dhcp6,omitempty"`
+	AcceptRa    *bool                `json:"accept-ra,omitempty"`
+	Gateway6    string               `json:"gateway6,omitempty"`
 	Routes      []*Route             `json:"routes"`
 	Nameservers *Nameservers         `json:"nameservers"`
 	Mtu         int                  `json:"mtu,omitzero"`
@@ -208,6 +211,28 @@ func NewStaticEthernetConfig(
 	}
 }
 
+// SetIPv6 adds the ipv6 config of mode to the interface, addr is only
+// used by static mode
+func (c *EthernetConfig) SetIPv6(mode string, addr string, gateway string, nameservers []string) {
+	enabled := true
+	switch mode {
+	case "slaac":
+		c.AcceptRa = &enabled
+	case "dhcpv6":
+		c.AcceptRa = &enabled
+		c.DHCP6 = &enabled
+	default:
+		c.Addresses = append(c.Addresses, addr)
+	}
+	c.Gateway6 = gateway
+	if len(nameservers) > 0 {
+		if c.Nameservers == nil {
+			c.Nameservers = &Nameservers{}
+		}
+		c.Nameservers.Addresses = append(c.Nameservers.Addresses, nameservers...)
+	}
+}
+
 func (c *EthernetConfig) YAMLString() string {
 	return toYAMLString(c)
 }
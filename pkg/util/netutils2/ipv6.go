@@ -0,0 +1,89 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netutils2
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+)
+
+// ParseIPv6 parses an IPv6 address, IPv4 and IPv4-mapped addresses are
+// rejected
+func ParseIPv6(ipStr string) (net.IP, error) {
+	ip := net.ParseIP(ipStr)
+	if ip == nil || ip.To4() != nil || !strings.Contains(ipStr, ":") {
+		return nil, fmt.Errorf("invalid ipv6 address %q", ipStr)
+	}
+	return ip.To16(), nil
+}
+
+// IPv6Prefix returns the network address of ip with masklen
+func IPv6Prefix(ip net.IP, masklen int) net.IP {
+	return ip.Mask(net.CIDRMask(masklen, 128))
+}
+
+// IPv6InSubnet tells whether ip is in the subnet of prefix/masklen
+func IPv6InSubnet(ip net.IP, prefix net.IP, masklen int) bool {
+	return IPv6Prefix(ip, masklen).Equal(IPv6Prefix(prefix, masklen))
+}
+
+// IPv6EUI64 derives the SLAAC address of mac in the /64 subnet of prefix
+// as defined in RFC 4291 appendix A
+func IPv6EUI64(prefix net.IP, mac string) (net.IP, error) {
+	hw, err := ParseMac(mac)
+	if err != nil {
+		return nil, err
+	}
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, IPv6Prefix(prefix, 64))
+	ip[8] = hw[0] ^ 0x02
+	ip[9] = hw[1]
+	ip[10] = hw[2]
+	ip[11] = 0xff
+	ip[12] = 0xfe
+	ip[13] = hw[3]
+	ip[14] = hw[4]
+	ip[15] = hw[5]
+	return ip, nil
+}
+
+// IPv6Compare compares two ipv6 addresses as big endian numbers
+func IPv6Compare(a, b net.IP) int {
+	return ipv6ToInt(a).Cmp(ipv6ToInt(b))
+}
+
+// IPv6RangeSize returns the number of addresses in [start, end]
+func IPv6RangeSize(start, end net.IP) *big.Int {
+	size := new(big.Int).Sub(ipv6ToInt(end), ipv6ToInt(start))
+	return size.Add(size, big.NewInt(1))
+}
+
+// IPv6Add returns ip + n
+func IPv6Add(ip net.IP, n *big.Int) net.IP {
+	v := new(big.Int).Add(ipv6ToInt(ip), n)
+	buf := v.Bytes()
+	ret := make(net.IP, net.IPv6len)
+	if len(buf) > net.IPv6len {
+		buf = buf[len(buf)-net.IPv6len:]
+	}
+	copy(ret[net.IPv6len-len(buf):], buf)
+	return ret
+}
+
+func ipv6ToInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip.To16())
+}
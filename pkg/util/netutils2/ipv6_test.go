@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netutils2
+
+import (
+	"math/big"
+	"net"
+	"testing"
+)
+
+func TestIPv6EUI64(t *testing.T) {
+	cases := []struct {
+		Prefix string
+		Mac    string
+		Want   string
+	}{
+		{
+			Prefix: "2001:db8:1:2::",
+			Mac:    "00:22:39:a1:b2:c3",
+			Want:   "2001:db8:1:2:222:39ff:fea1:b2c3",
+		},
+		{
+			Prefix: "2001:db8::1234",
+			Mac:    "02:00:00:00:00:01",
+			Want:   "2001:db8::ff:fe00:1",
+		},
+	}
+	for _, c := range cases {
+		ip, err := IPv6EUI64(net.ParseIP(c.Prefix), c.Mac)
+		if err != nil {
+			t.Errorf("IPv6EUI64 %s %s: %v", c.Prefix, c.Mac, err)
+			continue
+		}
+		if ip.String() != c.Want {
+			t.Errorf("IPv6EUI64 %s %s: want %s, got %s", c.Prefix, c.Mac, c.Want, ip)
+		}
+	}
+}
+
+func TestIPv6Range(t *testing.T) {
+	start := net.ParseIP("2001:db8::ff")
+	end := net.ParseIP("2001:db8::1:0")
+	if size := IPv6RangeSize(start, end); size.Int64() != 0xff02 {
+		t.Errorf("range size want %d, got %s", 0xff02, size)
+	}
+	if ip := IPv6Add(start, big.NewInt(1)); ip.String() != "2001:db8::100" {
+		t.Errorf("add want 2001:db8::100, got %s", ip)
+	}
+	if IPv6Compare(start, end) >= 0 {
+		t.Errorf("%s should be less than %s", start, end)
+	}
+	if !IPv6InSubnet(end, start, 64) || IPv6InSubnet(net.ParseIP("2001:db8:0:1::"), start, 64) {
+		t.Errorf("IPv6InSubnet wrong")
+	}
+	if _, err := ParseIPv6("10.0.0.1"); err == nil {
+		t.Errorf("ipv4 address should be rejected")
+	}
+}
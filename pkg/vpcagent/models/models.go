@@ -27,8 +27,12 @@ type Vpc struct {
 
 	RouteTable *RouteTable `json:"-"`
 
-	Wire     *Wire    `json:"-"`
-	Networks Networks `json:"-"`
+	Wire        *Wire       `json:"-"`
+	Networks    Networks    `json:"-"`
+	NatGateways NatGateways `json:"-"`
+
+	// both requested and accepted ones
+	PeeringConnections VpcPeeringConnections `json:"-"`
 }
 
 func (el *Vpc) Copy() *Vpc {
@@ -69,6 +73,7 @@ type Network struct {
 	Guestnetworks Guestnetworks `json:"-"`
 	Groupnetworks Groupnetworks `json:"-"`
 	Elasticips    Elasticips    `json:"-"`
+	Loadbalancers Loadbalancers `json:"-"`
 }
 
 func (el *Network) Copy() *Network {
@@ -270,3 +275,117 @@ func (el *Group) Copy() *Group {
 		SGroup: el.SGroup,
 	}
 }
+
+type Loadbalancer struct {
+	compute_models.SLoadbalancer
+
+	Network       *Network                  `json:"-"`
+	Listeners     LoadbalancerListeners     `json:"-"`
+	BackendGroups LoadbalancerBackendGroups `json:"-"`
+}
+
+func (el *Loadbalancer) Copy() *Loadbalancer {
+	return &Loadbalancer{
+		SLoadbalancer: el.SLoadbalancer,
+	}
+}
+
+type LoadbalancerListener struct {
+	compute_models.SLoadbalancerListener
+
+	Loadbalancer *Loadbalancer             `json:"-"`
+	BackendGroup *LoadbalancerBackendGroup `json:"-"`
+}
+
+func (el *LoadbalancerListener) Copy() *LoadbalancerListener {
+	return &LoadbalancerListener{
+		SLoadbalancerListener: el.SLoadbalancerListener,
+	}
+}
+
+type LoadbalancerBackendGroup struct {
+	compute_models.SLoadbalancerBackendGroup
+
+	Loadbalancer *Loadbalancer        `json:"-"`
+	Backends     LoadbalancerBackends `json:"-"`
+}
+
+func (el *LoadbalancerBackendGroup) Copy() *LoadbalancerBackendGroup {
+	return &LoadbalancerBackendGroup{
+		SLoadbalancerBackendGroup: el.SLoadbalancerBackendGroup,
+	}
+}
+
+type LoadbalancerBackend struct {
+	compute_models.SLoadbalancerBackend
+
+	BackendGroup *LoadbalancerBackendGroup `json:"-"`
+}
+
+func (el *LoadbalancerBackend) Copy() *LoadbalancerBackend {
+	return &LoadbalancerBackend{
+		SLoadbalancerBackend: el.SLoadbalancerBackend,
+	}
+}
+
+type NatGateway struct {
+	compute_models.SNatGateway
+
+	Vpc        *Vpc        `json:"-"`
+	Elasticips Elasticips  `json:"-"`
+	SEntries   NatSEntries `json:"-"`
+	DEntries   NatDEntries `json:"-"`
+}
+
+func (el *NatGateway) Copy() *NatGateway {
+	return &NatGateway{
+		SNatGateway: el.SNatGateway,
+	}
+}
+
+// GetElasticip returns eip associated to the nat gateway with the ip address
+func (el *NatGateway) GetElasticip(ipAddr string) *Elasticip {
+	for _, eip := range el.Elasticips {
+		if eip.IpAddr == ipAddr {
+			return eip
+		}
+	}
+	return nil
+}
+
+type NatSEntry struct {
+	compute_models.SNatSEntry
+
+	NatGateway *NatGateway `json:"-"`
+}
+
+func (el *NatSEntry) Copy() *NatSEntry {
+	return &NatSEntry{
+		SNatSEntry: el.SNatSEntry,
+	}
+}
+
+type NatDEntry struct {
+	compute_models.SNatDEntry
+
+	NatGateway *NatGateway `json:"-"`
+}
+
+func (el *NatDEntry) Copy() *NatDEntry {
+	return &NatDEntry{
+		SNatDEntry: el.SNatDEntry,
+	}
+}
+
+type VpcPeeringConnection struct {
+	compute_models.SVpcPeeringConnection
+
+	Vpc     *Vpc `json:"-"`
+	PeerVpc *Vpc `json:"-"`
+}
+
+func (el *VpcPeeringConnection) Copy() *VpcPeeringConnection {
+	return &VpcPeeringConnection{
+		SVpcPeeringConnection: el.SVpcPeeringConnection,
+	}
+}
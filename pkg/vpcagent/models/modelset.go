@@ -47,6 +47,17 @@ type (
 	Groupguests   map[string]*Groupguest
 	Groupnetworks map[string]*Groupnetwork
 	Groups        map[string]*Group
+
+	Loadbalancers             map[string]*Loadbalancer
+	LoadbalancerListeners     map[string]*LoadbalancerListener
+	LoadbalancerBackendGroups map[string]*LoadbalancerBackendGroup
+	LoadbalancerBackends      map[string]*LoadbalancerBackend
+
+	NatGateways map[string]*NatGateway
+	NatSEntries map[string]*NatSEntry
+	NatDEntries map[string]*NatDEntry
+
+	VpcPeeringConnections map[string]*VpcPeeringConnection
 )
 
 func (set Vpcs) ModelManager() mcclient_modulebase.IBaseManager {
@@ -335,6 +346,7 @@ func (ms Networks) joinGuestnetworks(subEntries Guestnetworks) bool {
 	for _, m := range ms {
 		m.Guestnetworks = Guestnetworks{}
 		m.Groupnetworks = Groupnetworks{}
+		m.Loadbalancers = Loadbalancers{}
 	}
 	for _, subEntry := range subEntries {
 		netId := subEntry.NetworkId
@@ -822,3 +834,352 @@ func (set Groups) joinGroupnetworks(subEntries Groupnetworks, networks Networks)
 	}
 	return true
 }
+
+func (set Loadbalancers) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.Loadbalancers
+}
+
+func (set Loadbalancers) NewModel() db.IModel {
+	return &Loadbalancer{}
+}
+
+func (set Loadbalancers) AddModel(i db.IModel) {
+	m := i.(*Loadbalancer)
+	set[m.Id] = m
+}
+
+func (set Loadbalancers) Copy() apihelper.IModelSet {
+	setCopy := Loadbalancers{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms Loadbalancers) initJoin() {
+	for _, m := range ms {
+		m.Listeners = LoadbalancerListeners{}
+		m.BackendGroups = LoadbalancerBackendGroups{}
+	}
+}
+
+func (ms Loadbalancers) joinNetworks(networks Networks) bool {
+	for _, m := range ms {
+		network, ok := networks[m.NetworkId]
+		if !ok {
+			// classic or managed loadbalancers
+			continue
+		}
+		m.Network = network
+		network.Loadbalancers[m.Id] = m
+	}
+	return true
+}
+
+func (ms Loadbalancers) joinListeners(subEntries LoadbalancerListeners) bool {
+	correct := true
+	for subId, subEntry := range subEntries {
+		m, ok := ms[subEntry.LoadbalancerId]
+		if !ok {
+			log.Warningf("loadbalancer %s of listener %s(%s) is not present",
+				subEntry.LoadbalancerId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.Loadbalancer = m
+		m.Listeners[subId] = subEntry
+	}
+	return correct
+}
+
+func (ms Loadbalancers) joinBackendGroups(subEntries LoadbalancerBackendGroups) bool {
+	correct := true
+	for subId, subEntry := range subEntries {
+		m, ok := ms[subEntry.LoadbalancerId]
+		if !ok {
+			log.Warningf("loadbalancer %s of backend group %s(%s) is not present",
+				subEntry.LoadbalancerId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.Loadbalancer = m
+		m.BackendGroups[subId] = subEntry
+	}
+	return correct
+}
+
+func (set LoadbalancerListeners) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerListeners
+}
+
+func (set LoadbalancerListeners) NewModel() db.IModel {
+	return &LoadbalancerListener{}
+}
+
+func (set LoadbalancerListeners) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerListener)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerListeners) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerListeners{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms LoadbalancerListeners) joinBackendGroups(subEntries LoadbalancerBackendGroups) bool {
+	correct := true
+	for _, m := range ms {
+		m.BackendGroup = nil
+		if m.BackendGroupId == "" {
+			continue
+		}
+		subEntry, ok := subEntries[m.BackendGroupId]
+		if !ok {
+			log.Warningf("backend group %s of listener %s(%s) is not present",
+				m.BackendGroupId, m.Name, m.Id)
+			correct = false
+			continue
+		}
+		m.BackendGroup = subEntry
+	}
+	return correct
+}
+
+func (set LoadbalancerBackendGroups) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerBackendGroups
+}
+
+func (set LoadbalancerBackendGroups) NewModel() db.IModel {
+	return &LoadbalancerBackendGroup{}
+}
+
+func (set LoadbalancerBackendGroups) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerBackendGroup)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerBackendGroups) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerBackendGroups{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms LoadbalancerBackendGroups) joinBackends(subEntries LoadbalancerBackends) bool {
+	for _, m := range ms {
+		m.Backends = LoadbalancerBackends{}
+	}
+	correct := true
+	for subId, subEntry := range subEntries {
+		m, ok := ms[subEntry.BackendGroupId]
+		if !ok {
+			log.Warningf("backend group %s of backend %s(%s) is not present",
+				subEntry.BackendGroupId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.BackendGroup = m
+		m.Backends[subId] = subEntry
+	}
+	return correct
+}
+
+func (set LoadbalancerBackends) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerBackends
+}
+
+func (set LoadbalancerBackends) NewModel() db.IModel {
+	return &LoadbalancerBackend{}
+}
+
+func (set LoadbalancerBackends) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerBackend)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerBackends) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerBackends{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatGateways) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatGateways
+}
+
+func (set NatGateways) NewModel() db.IModel {
+	return &NatGateway{}
+}
+
+func (set NatGateways) AddModel(i db.IModel) {
+	m := i.(*NatGateway)
+	set[m.Id] = m
+}
+
+func (set NatGateways) Copy() apihelper.IModelSet {
+	setCopy := NatGateways{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms NatGateways) joinVpcs(vpcs Vpcs) bool {
+	for _, vpc := range vpcs {
+		vpc.NatGateways = NatGateways{}
+	}
+	correct := true
+	for _, m := range ms {
+		m.Elasticips = Elasticips{}
+		m.SEntries = NatSEntries{}
+		m.DEntries = NatDEntries{}
+		vpc, ok := vpcs[m.VpcId]
+		if !ok {
+			log.Warningf("natgateway %s(%s): vpc %s not found", m.Name, m.Id, m.VpcId)
+			correct = false
+			continue
+		}
+		m.Vpc = vpc
+		vpc.NatGateways[m.Id] = m
+	}
+	return correct
+}
+
+func (ms NatGateways) joinElasticips(subEntries Elasticips) bool {
+	for _, subEntry := range subEntries {
+		if subEntry.AssociateType != computeapis.EIP_ASSOCIATE_TYPE_NAT_GATEWAY {
+			continue
+		}
+		m, ok := ms[subEntry.AssociateId]
+		if !ok {
+			continue
+		}
+		m.Elasticips[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (ms NatGateways) joinSEntries(subEntries NatSEntries) bool {
+	correct := true
+	for subId, subEntry := range subEntries {
+		m, ok := ms[subEntry.NatgatewayId]
+		if !ok {
+			log.Warningf("natgateway %s of snat entry %s(%s) is not present",
+				subEntry.NatgatewayId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.NatGateway = m
+		m.SEntries[subId] = subEntry
+	}
+	return correct
+}
+
+func (ms NatGateways) joinDEntries(subEntries NatDEntries) bool {
+	correct := true
+	for subId, subEntry := range subEntries {
+		m, ok := ms[subEntry.NatgatewayId]
+		if !ok {
+			log.Warningf("natgateway %s of dnat entry %s(%s) is not present",
+				subEntry.NatgatewayId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.NatGateway = m
+		m.DEntries[subId] = subEntry
+	}
+	return correct
+}
+
+func (set NatSEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatSTable
+}
+
+func (set NatSEntries) NewModel() db.IModel {
+	return &NatSEntry{}
+}
+
+func (set NatSEntries) AddModel(i db.IModel) {
+	m := i.(*NatSEntry)
+	set[m.Id] = m
+}
+
+func (set NatSEntries) Copy() apihelper.IModelSet {
+	setCopy := NatSEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatDEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatDTable
+}
+
+func (set NatDEntries) NewModel() db.IModel {
+	return &NatDEntry{}
+}
+
+func (set NatDEntries) AddModel(i db.IModel) {
+	m := i.(*NatDEntry)
+	set[m.Id] = m
+}
+
+func (set NatDEntries) Copy() apihelper.IModelSet {
+	setCopy := NatDEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set VpcPeeringConnections) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.VpcPeeringConnections
+}
+
+func (set VpcPeeringConnections) NewModel() db.IModel {
+	return &VpcPeeringConnection{}
+}
+
+func (set VpcPeeringConnections) AddModel(i db.IModel) {
+	m := i.(*VpcPeeringConnection)
+	set[m.Id] = m
+}
+
+func (set VpcPeeringConnections) Copy() apihelper.IModelSet {
+	setCopy := VpcPeeringConnections{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms VpcPeeringConnections) joinVpcs(vpcs Vpcs) bool {
+	for _, vpc := range vpcs {
+		vpc.PeeringConnections = VpcPeeringConnections{}
+	}
+	for _, m := range ms {
+		m.Vpc = nil
+		m.PeerVpc = nil
+		vpc, ok := vpcs[m.VpcId]
+		if !ok {
+			// peering of public cloud vpcs being created
+			continue
+		}
+		peerVpc, ok := vpcs[m.PeerVpcId]
+		if !ok {
+			continue
+		}
+		m.Vpc = vpc
+		m.PeerVpc = peerVpc
+		vpc.PeeringConnections[m.Id] = m
+		peerVpc.PeeringConnections[m.Id] = m
+	}
+	return true
+}
@@ -39,6 +39,17 @@ type ModelSetsMaxUpdatedAt struct {
 
 	Groupguests   time.Time
 	Groupnetworks time.Time
+
+	Loadbalancers             time.Time
+	LoadbalancerListeners     time.Time
+	LoadbalancerBackendGroups time.Time
+	LoadbalancerBackends      time.Time
+
+	NatGateways time.Time
+	NatSEntries time.Time
+	NatDEntries time.Time
+
+	VpcPeeringConnections time.Time
 }
 
 func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
@@ -61,6 +72,17 @@ func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
 
 		Groupguests:   apihelper.PseudoZeroTime,
 		Groupnetworks: apihelper.PseudoZeroTime,
+
+		Loadbalancers:             apihelper.PseudoZeroTime,
+		LoadbalancerListeners:     apihelper.PseudoZeroTime,
+		LoadbalancerBackendGroups: apihelper.PseudoZeroTime,
+		LoadbalancerBackends:      apihelper.PseudoZeroTime,
+
+		NatGateways: apihelper.PseudoZeroTime,
+		NatSEntries: apihelper.PseudoZeroTime,
+		NatDEntries: apihelper.PseudoZeroTime,
+
+		VpcPeeringConnections: apihelper.PseudoZeroTime,
 	}
 }
 
@@ -84,6 +106,17 @@ type ModelSets struct {
 	Groupguests   Groupguests
 	Groupnetworks Groupnetworks
 	Groups        Groups
+
+	Loadbalancers             Loadbalancers
+	LoadbalancerListeners     LoadbalancerListeners
+	LoadbalancerBackendGroups LoadbalancerBackendGroups
+	LoadbalancerBackends      LoadbalancerBackends
+
+	NatGateways NatGateways
+	NatSEntries NatSEntries
+	NatDEntries NatDEntries
+
+	VpcPeeringConnections VpcPeeringConnections
 }
 
 func NewModelSets() *ModelSets {
@@ -107,6 +140,17 @@ func NewModelSets() *ModelSets {
 		Groupguests:   Groupguests{},
 		Groupnetworks: Groupnetworks{},
 		Groups:        Groups{},
+
+		Loadbalancers:             Loadbalancers{},
+		LoadbalancerListeners:     LoadbalancerListeners{},
+		LoadbalancerBackendGroups: LoadbalancerBackendGroups{},
+		LoadbalancerBackends:      LoadbalancerBackends{},
+
+		NatGateways: NatGateways{},
+		NatSEntries: NatSEntries{},
+		NatDEntries: NatDEntries{},
+
+		VpcPeeringConnections: VpcPeeringConnections{},
 	}
 }
 
@@ -131,6 +175,17 @@ func (mss *ModelSets) ModelSetList() []apihelper.IModelSet {
 
 		mss.Groupguests,
 		mss.Groupnetworks,
+
+		mss.Loadbalancers,
+		mss.LoadbalancerListeners,
+		mss.LoadbalancerBackendGroups,
+		mss.LoadbalancerBackends,
+
+		mss.NatGateways,
+		mss.NatSEntries,
+		mss.NatDEntries,
+
+		mss.VpcPeeringConnections,
 	}
 }
 
@@ -158,6 +213,17 @@ func (mss *ModelSets) copy_() *ModelSets {
 
 		Groupguests:   mss.Groupguests.Copy().(Groupguests),
 		Groupnetworks: mss.Groupnetworks.Copy().(Groupnetworks),
+
+		Loadbalancers:             mss.Loadbalancers.Copy().(Loadbalancers),
+		LoadbalancerListeners:     mss.LoadbalancerListeners.Copy().(LoadbalancerListeners),
+		LoadbalancerBackendGroups: mss.LoadbalancerBackendGroups.Copy().(LoadbalancerBackendGroups),
+		LoadbalancerBackends:      mss.LoadbalancerBackends.Copy().(LoadbalancerBackends),
+
+		NatGateways: mss.NatGateways.Copy().(NatGateways),
+		NatSEntries: mss.NatSEntries.Copy().(NatSEntries),
+		NatDEntries: mss.NatDEntries.Copy().(NatDEntries),
+
+		VpcPeeringConnections: mss.VpcPeeringConnections.Copy().(VpcPeeringConnections),
 	}
 	return mssCopy
 }
@@ -194,6 +260,7 @@ func (mss *ModelSets) ApplyUpdates(mssNews apihelper.IModelSets) apihelper.Model
 
 func (mss *ModelSets) join() bool {
 	mss.Guests.initJoin()
+	mss.Loadbalancers.initJoin()
 	mss.Groups = Groups{}
 	var p []bool
 	p = append(p, mss.Vpcs.joinWires(mss.Wires))
@@ -213,6 +280,16 @@ func (mss *ModelSets) join() bool {
 	p = append(p, mss.Guestnetworks.joinNetworkAddresses(mss.NetworkAddresses))
 	p = append(p, mss.Groups.joinGroupnetworks(mss.Groupnetworks, mss.Networks))
 	p = append(p, mss.Groupnetworks.joinElasticips(mss.Elasticips))
+	p = append(p, mss.Loadbalancers.joinNetworks(mss.Networks))
+	p = append(p, mss.Loadbalancers.joinListeners(mss.LoadbalancerListeners))
+	p = append(p, mss.Loadbalancers.joinBackendGroups(mss.LoadbalancerBackendGroups))
+	p = append(p, mss.LoadbalancerListeners.joinBackendGroups(mss.LoadbalancerBackendGroups))
+	p = append(p, mss.LoadbalancerBackendGroups.joinBackends(mss.LoadbalancerBackends))
+	p = append(p, mss.NatGateways.joinVpcs(mss.Vpcs))
+	p = append(p, mss.NatGateways.joinElasticips(mss.Elasticips))
+	p = append(p, mss.NatGateways.joinSEntries(mss.NatSEntries))
+	p = append(p, mss.NatGateways.joinDEntries(mss.NatDEntries))
+	p = append(p, mss.VpcPeeringConnections.joinVpcs(mss.Vpcs))
 	for _, b := range p {
 		if !b {
 			return false
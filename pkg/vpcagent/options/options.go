@@ -40,6 +40,7 @@ type VpcAgentOptions struct {
 	OvnWorkerCheckInterval int    `default:"180"`
 	OvnNorthDatabase       string `help:"address for accessing ovn north database.  Default to local unix socket"`
 	OvnUnderlayMtu         int    `help:"mtu of ovn underlay network" default:"1500"`
+	OvnNatGatewayChassis   string `help:"ovn chassis where snat/dnat of vpc nat gateways happens"`
 }
 
 type Options struct {
@@ -17,6 +17,7 @@ package ovn
 import (
 	"context"
 	"fmt"
+	"net"
 	"sort"
 	"strings"
 
@@ -56,11 +57,21 @@ func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthbou
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.NAT,
+		&db.AddressSet,
+	}
+	// columns added by newer ovn releases are not in the vendored schema
+	columns := map[string]string{
+		db.LoadBalancer.OvsdbTableName(): "_uuid,_version,external_ids,name,protocol,vips",
+		db.NAT.OvsdbTableName():          "_uuid,_version,external_ids,external_ip,external_mac,logical_ip,logical_port,type",
 	}
-	args := []string{"--format=json", "list", "<tbl>"}
 	for _, itbl := range itbls {
 		tbl := itbl.OvsdbTableName()
-		args[2] = tbl
+		args := []string{"--format=json", "list", tbl}
+		if cols, ok := columns[tbl]; ok {
+			args = []string{"--format=json", "--columns=" + cols, "list", tbl}
+		}
 		res := cli.Must(ctx, "List "+tbl, args)
 		if err := cli_util.UnmarshalJSON([]byte(res.Output), itbl); err != nil {
 			return nil, errors.Wrapf(err, "Unmarshal %s:\n%s",
@@ -357,17 +368,56 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 		}
 	}
 
+	var dhcp6opts *ovn_nb.DHCPOptions
+	if network.IsIPv6Enabled() && network.GuestGateway6 != "" {
+		netRnp.Networks = append(netRnp.Networks, fmt.Sprintf("%s/%d", network.GuestGateway6, network.GuestIp6Mask))
+		// static addresses are configured in guests at deploy time, along
+		// with the gateway, so router advertisements are only for the
+		// other modes
+		switch network.Ip6Mode {
+		case apis.NETWORK_IP6_MODE_SLAAC:
+			netRnp.Ipv6RaConfigs = map[string]string{
+				"address_mode":  "slaac",
+				"send_periodic": "true",
+				"mtu":           fmt.Sprintf("%d", mtu),
+			}
+		case apis.NETWORK_IP6_MODE_DHCPV6:
+			netRnp.Ipv6RaConfigs = map[string]string{
+				"address_mode":  "dhcpv6_stateful",
+				"send_periodic": "true",
+				"mtu":           fmt.Sprintf("%d", mtu),
+			}
+			prefix := net.ParseIP(network.GuestIp6Start).Mask(net.CIDRMask(int(network.GuestIp6Mask), 128))
+			dhcp6opts = &ovn_nb.DHCPOptions{
+				Cidr: fmt.Sprintf("%s/%d", prefix, network.GuestIp6Mask),
+				Options: map[string]string{
+					"server_id": dhcpMac,
+				},
+				ExternalIds: map[string]string{
+					externalKeyOcRef: netDhcp6Ref(network.Id),
+				},
+			}
+			if network.GuestDns6 != "" {
+				dhcp6opts.Options["dns_server"] = "{" + network.GuestDns6 + "}"
+			}
+		}
+	}
+
 	var (
 		args      []string
 		ocVersion = fmt.Sprintf("%s.%d", network.UpdatedAt, network.UpdateVersion)
 	)
-	allFound, args := cmp(&keeper.DB, ocVersion,
+	irows := []types.IRow{
 		netLs,
 		netRnp,
 		netNrp,
 		netMdp,
 		dhcpopts,
-	)
+	}
+	if dhcp6opts != nil {
+		irows = append(irows, dhcp6opts)
+	}
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
 	if allFound {
 		return nil
 	}
@@ -376,6 +426,9 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 	args = append(args, ovnCreateArgs(netNrp, netNrp.Name)...)
 	args = append(args, ovnCreateArgs(netMdp, netMdp.Name)...)
 	args = append(args, ovnCreateArgs(dhcpopts, "dhcpopts")...)
+	if dhcp6opts != nil {
+		args = append(args, ovnCreateArgs(dhcp6opts, "dhcp6opts")...)
+	}
 	args = append(args, "--", "add", "Logical_Switch", netLs.Name, "ports", "@"+netNrp.Name, "@"+netMdp.Name)
 	args = append(args, "--", "add", "Logical_Router", vpcLrName(network.Vpc.Id), "ports", "@"+netRnp.Name)
 	return keeper.cli.Must(ctx, "ClaimNetwork", args)
@@ -477,6 +530,30 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		}
 	}
 
+	var dhcp6Opt *string
+	if guestnetwork.Ip6Addr != "" && network.Ip6Mode == apis.NETWORK_IP6_MODE_DHCPV6 {
+		dhcp6OptQuery := &ovn_nb.DHCPOptions{
+			ExternalIds: map[string]string{
+				externalKeyOcRef: netDhcp6Ref(guestnetwork.NetworkId),
+			},
+		}
+		if m := keeper.DB.DHCPOptions.FindOneMatchNonZeros(dhcp6OptQuery); m != nil {
+			dhcp6Opt = ptr(m.OvsdbUuid())
+		} else {
+			args := []string{
+				"--bare", "--columns=_uuid", "find", "DHCP_Options",
+				fmt.Sprintf("external_ids:%s=%q", externalKeyOcRef, netDhcp6Ref(guestnetwork.NetworkId)),
+			}
+			res := keeper.cli.Must(ctx, "find dhcp6opt", args)
+			if uuid := strings.TrimSpace(res.Output); uuid != "" {
+				dhcp6Opt = ptr(uuid)
+			}
+		}
+		if dhcp6Opt == nil {
+			return fmt.Errorf("cannot find dhcp6opt for subnet %s", guestnetwork.NetworkId)
+		}
+	}
+
 	var (
 		subIPs  = []string{guestnetwork.IpAddr}
 		subIPms = []string{fmt.Sprintf("%s/%d", guestnetwork.IpAddr, guestnetwork.Network.GuestIpMask)}
@@ -488,10 +565,15 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	subIPms = append(subIPms, guestnetwork.Guest.GetVips()...)
 	sort.Strings(subIPs[1:])
 	sort.Strings(subIPms[1:])
+	if guestnetwork.Ip6Addr != "" {
+		subIPs = append(subIPs, guestnetwork.Ip6Addr)
+		subIPms = append(subIPms, fmt.Sprintf("%s/%d", guestnetwork.Ip6Addr, network.GuestIp6Mask))
+	}
 	gnp := &ovn_nb.LogicalSwitchPort{
 		Name:          lportName,
 		Addresses:     []string{fmt.Sprintf("%s %s", guestnetwork.MacAddr, strings.Join(subIPs, " "))},
 		Dhcpv4Options: &dhcpOpt,
+		Dhcpv6Options: dhcp6Opt,
 		Options:       map[string]string{},
 	}
 	if guest.SrcMacCheck.IsFalse() {
@@ -613,10 +695,6 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	{
 		sgrs := guest.OrderedSecurityGroupRules()
 		for _, sgr := range sgrs {
-			// kvm not support peer secgroup
-			if len(sgr.PeerSecgroupId) > 0 {
-				continue
-			}
 			acl, err := ruleToAcl(lportName, sgr)
 			if err != nil {
 				log.Errorf("converting security group rule to acl: %v", err)
@@ -677,11 +755,15 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 func (keeper *OVNNorthboundKeeper) ClaimRoutes(ctx context.Context, vpc *agentmodels.Vpc, routes resolvedRoutes) error {
 	var irows []types.IRow
 	for _, route := range routes {
-		irows = append(irows, &ovn_nb.LogicalRouterStaticRoute{
+		irow := &ovn_nb.LogicalRouterStaticRoute{
 			Policy:   ptr("dst-ip"),
 			IpPrefix: route.Cidr,
 			Nexthop:  route.NextHop,
-		})
+		}
+		if route.OutputPort != "" {
+			irow.OutputPort = ptr(route.OutputPort)
+		}
+		irows = append(irows, irow)
 	}
 	ocVersion := fmt.Sprintf("%s.%d", vpc.UpdatedAt, vpc.UpdateVersion)
 	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
@@ -951,6 +1033,9 @@ func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.NAT,
+		&db.AddressSet,
 	}
 	for _, itbl := range itbls {
 		for _, irow := range itbl.Rows() {
@@ -969,6 +1054,7 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 		&db.LogicalRouter,
 		&db.DHCPOptions,
 		&db.DNS,
+		&db.AddressSet,
 	}
 	var irows []types.IRow
 	for _, itbl := range itbls {
@@ -1026,5 +1112,38 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 			keeper.cli.Must(ctx, "Sweep qos", args)
 		}
 	}
+	{
+		var args []string
+		for _, irow := range db.NAT.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				for _, lr := range db.LogicalRouter.FindNATReferrer_nat(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router", lr.Name, "nat", irow.OvsdbUuid())
+				}
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep nat", args)
+		}
+	}
+	{ // remove unused load balancers.  Health checks go with them
+		var args []string
+		for _, irow := range db.LoadBalancer.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				lbUuid := irow.OvsdbUuid()
+				for _, ls := range db.LogicalSwitch.FindLoadBalancerReferrer_load_balancer(lbUuid) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Switch", ls.Name, "load_balancer", lbUuid)
+				}
+				for _, lr := range db.LogicalRouter.FindLoadBalancerReferrer_load_balancer(lbUuid) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router", lr.Name, "load_balancer", lbUuid)
+				}
+				args = append(args, "--", "--if-exists", "destroy", irow.OvsdbTableName(), lbUuid)
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep load balancers", args)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,260 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+const (
+	externalKeyOcLbHc = "oc-lb-hc"
+)
+
+type lbHealthCheck struct {
+	vip     string
+	options map[string]string
+}
+
+// lbProtoConf is what will be programmed into one Load_Balancer row
+type lbProtoConf struct {
+	vips           map[string]string
+	healthChecks   []lbHealthCheck
+	ipPortMappings map[string]string
+}
+
+// hcDigest summarizes health check settings.  They are not dumped from
+// Load_Balancer_Health_Check and the digest is stored in external_ids to
+// detect changes
+func (conf *lbProtoConf) hcDigest() string {
+	if len(conf.healthChecks) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(conf.healthChecks)+len(conf.ipPortMappings))
+	for _, hc := range conf.healthChecks {
+		opts := make([]string, 0, len(hc.options))
+		for k, v := range hc.options {
+			opts = append(opts, k+"="+v)
+		}
+		sort.Strings(opts)
+		lines = append(lines, hc.vip+" "+strings.Join(opts, ","))
+	}
+	for k, v := range conf.ipPortMappings {
+		lines = append(lines, k+"="+v)
+	}
+	sort.Strings(lines)
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(lines, "\n"))))
+}
+
+func lbHealthCheckOptions(listener *agentmodels.LoadbalancerListener) map[string]string {
+	opts := map[string]string{}
+	if v := listener.HealthCheckInterval; v > 0 {
+		opts["interval"] = fmt.Sprintf("%d", v)
+	}
+	if v := listener.HealthCheckTimeout; v > 0 {
+		opts["timeout"] = fmt.Sprintf("%d", v)
+	}
+	if v := listener.HealthCheckRise; v > 0 {
+		opts["success_count"] = fmt.Sprintf("%d", v)
+	}
+	if v := listener.HealthCheckFall; v > 0 {
+		opts["failure_count"] = fmt.Sprintf("%d", v)
+	}
+	return opts
+}
+
+// lbProtoConfs builds per-protocol OVN load balancer config for enabled
+// tcp/udp listeners of lb.  lsps maps guest address in the vpc to its
+// logical switch port name.  Backends not found there are ignored
+func lbProtoConfs(lb *agentmodels.Loadbalancer, lsps map[string]string) map[string]*lbProtoConf {
+	confs := map[string]*lbProtoConf{}
+	for _, listener := range lb.Listeners {
+		if listener.Status != api.LB_STATUS_ENABLED {
+			continue
+		}
+		proto := listener.ListenerType
+		if !utils.IsInStringArray(proto, []string{api.LB_LISTENER_TYPE_TCP, api.LB_LISTENER_TYPE_UDP}) {
+			continue
+		}
+		backendGroup := listener.BackendGroup
+		if backendGroup == nil {
+			backendGroup = lb.BackendGroups[lb.BackendGroupId]
+		}
+		if backendGroup == nil {
+			continue
+		}
+		var (
+			backends []string
+			mappings = map[string]string{}
+		)
+		for _, backend := range backendGroup.Backends {
+			if backend.BackendType != api.LB_BACKEND_GUEST || backend.Weight == 0 {
+				continue
+			}
+			lsp, ok := lsps[backend.Address]
+			if !ok {
+				continue
+			}
+			backends = append(backends, fmt.Sprintf("%s:%d", backend.Address, backend.Port))
+			// health check probes are sourced from the vip
+			mappings[backend.Address] = fmt.Sprintf("%s:%s", lsp, lb.Address)
+		}
+		if len(backends) == 0 {
+			continue
+		}
+		sort.Strings(backends)
+
+		conf, ok := confs[proto]
+		if !ok {
+			conf = &lbProtoConf{
+				vips:           map[string]string{},
+				ipPortMappings: map[string]string{},
+			}
+			confs[proto] = conf
+		}
+		vip := fmt.Sprintf("%s:%d", lb.Address, listener.ListenerPort)
+		conf.vips[vip] = strings.Join(backends, ",")
+		if listener.HealthCheck == api.LB_BOOL_ON {
+			conf.healthChecks = append(conf.healthChecks, lbHealthCheck{
+				vip:     vip,
+				options: lbHealthCheckOptions(listener),
+			})
+			for k, v := range mappings {
+				conf.ipPortMappings[k] = v
+			}
+		}
+	}
+	return confs
+}
+
+// ClaimLoadbalancer realizes L4 listeners of vpc loadbalancer as OVN
+// Load_Balancer rows, one for each protocol, attached to the vpc router and
+// switches of all vpc networks
+func (keeper *OVNNorthboundKeeper) ClaimLoadbalancer(ctx context.Context, lb *agentmodels.Loadbalancer) error {
+	var (
+		network   = lb.Network
+		vpc       = network.Vpc
+		ocVersion = fmt.Sprintf("%s.%d", lb.UpdatedAt, lb.UpdateVersion)
+		lsps      = map[string]string{}
+		lsNames   []string
+	)
+	if lb.Address == "" {
+		return nil
+	}
+	for _, network := range vpc.Networks {
+		lsNames = append(lsNames, netLsName(network.Id))
+		for _, guestnetwork := range network.Guestnetworks {
+			if guestnetwork.Guest == nil {
+				continue
+			}
+			lsps[guestnetwork.IpAddr] = gnpName(guestnetwork.NetworkId, guestnetwork.Ifname)
+		}
+	}
+	sort.Strings(lsNames)
+
+	confs := lbProtoConfs(lb, lsps)
+	protos := make([]string, 0, len(confs))
+	for proto := range confs {
+		protos = append(protos, proto)
+	}
+	sort.Strings(protos)
+
+	var args []string
+	for _, proto := range protos {
+		conf := confs[proto]
+		ovnLb := &ovn_nb.LoadBalancer{
+			Name:     lbName(lb.Id, proto),
+			Protocol: ptr(proto),
+			Vips:     conf.vips,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: lb.Id,
+			},
+		}
+		if digest := conf.hcDigest(); digest != "" {
+			ovnLb.ExternalIds[externalKeyOcLbHc] = digest
+		}
+		// look it up before cmp marks it with oc-version
+		found := keeper.DB.LoadBalancer.FindOneMatchNonZeros(ovnLb)
+		allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, ovnLb)
+		if allFound {
+			args = append(args, keeper.lbAttachArgs(found.Uuid, vpc.Id, lsNames)...)
+			continue
+		}
+		args = append(args, cleanupArgs...)
+
+		var (
+			lbRef  = "lb" + proto
+			hcRefs = make([]string, len(conf.healthChecks))
+		)
+		for i, hc := range conf.healthChecks {
+			hcRefs[i] = fmt.Sprintf("@%shc%d", lbRef, i)
+			args = append(args, "--", "--id="+hcRefs[i], "create", "Load_Balancer_Health_Check")
+			args = append(args, types.OvsdbCmdArgsString("vip", hc.vip)...)
+			args = append(args, types.OvsdbCmdArgsMapStringString("options", hc.options)...)
+		}
+		args = append(args, ovnCreateArgs(ovnLb, lbRef)...)
+		if len(hcRefs) > 0 {
+			args = append(args, fmt.Sprintf("health_check=[%s]", strings.Join(hcRefs, ",")))
+			args = append(args, types.OvsdbCmdArgsMapStringString("ip_port_mappings", conf.ipPortMappings)...)
+		}
+		args = append(args, "--", "add", "Logical_Router", vpcLrName(vpc.Id), "load_balancer", "@"+lbRef)
+		for _, lsName := range lsNames {
+			args = append(args, "--", "add", "Logical_Switch", lsName, "load_balancer", "@"+lbRef)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return keeper.cli.Must(ctx, "ClaimLoadbalancer", args)
+}
+
+// lbAttachArgs returns args for attaching existing load balancer to those
+// vpc router and switches missing the reference, e.g. newly added networks
+func (keeper *OVNNorthboundKeeper) lbAttachArgs(lbUuid string, vpcId string, lsNames []string) []string {
+	var (
+		args     []string
+		attached = map[string]struct{}{}
+		lrName   = vpcLrName(vpcId)
+		lrFound  bool
+	)
+	for _, ls := range keeper.DB.LogicalSwitch.FindLoadBalancerReferrer_load_balancer(lbUuid) {
+		attached[ls.Name] = struct{}{}
+	}
+	for _, lsName := range lsNames {
+		if _, ok := attached[lsName]; !ok {
+			args = append(args, "--", "add", "Logical_Switch", lsName, "load_balancer", lbUuid)
+		}
+	}
+	for _, lr := range keeper.DB.LogicalRouter.FindLoadBalancerReferrer_load_balancer(lbUuid) {
+		if lr.Name == lrName {
+			lrFound = true
+			break
+		}
+	}
+	if !lrFound {
+		args = append(args, "--", "add", "Logical_Router", lrName, "load_balancer", lbUuid)
+	}
+	return args
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestLbProtoConfs(t *testing.T) {
+	newBackend := func(id, addr string, port, weight int) *agentmodels.LoadbalancerBackend {
+		b := &agentmodels.LoadbalancerBackend{}
+		b.Id = id
+		b.BackendType = api.LB_BACKEND_GUEST
+		b.Address = addr
+		b.Port = port
+		b.Weight = weight
+		return b
+	}
+	newListener := func(id, typ string, port int, hc string) *agentmodels.LoadbalancerListener {
+		l := &agentmodels.LoadbalancerListener{
+			SLoadbalancerListener: models.SLoadbalancerListener{
+				ListenerType: typ,
+				ListenerPort: port,
+			},
+		}
+		l.Id = id
+		l.Status = api.LB_STATUS_ENABLED
+		l.HealthCheck = hc
+		l.HealthCheckInterval = 5
+		l.HealthCheckRise = 2
+		return l
+	}
+
+	bg := &agentmodels.LoadbalancerBackendGroup{
+		Backends: agentmodels.LoadbalancerBackends{
+			"b0": newBackend("b0", "10.0.0.10", 8080, 1),
+			"b1": newBackend("b1", "10.0.0.11", 8080, 1),
+			"b2": newBackend("b2", "10.0.0.12", 8080, 0),
+			"b3": newBackend("b3", "10.9.0.13", 8080, 1),
+		},
+	}
+	bg.Id = "bg0"
+	lb := &agentmodels.Loadbalancer{
+		Listeners: agentmodels.LoadbalancerListeners{
+			"l0": newListener("l0", api.LB_LISTENER_TYPE_TCP, 80, api.LB_BOOL_ON),
+			"l1": newListener("l1", api.LB_LISTENER_TYPE_UDP, 53, api.LB_BOOL_OFF),
+			"l2": newListener("l2", api.LB_LISTENER_TYPE_HTTP, 8000, api.LB_BOOL_OFF),
+		},
+		BackendGroups: agentmodels.LoadbalancerBackendGroups{
+			"bg0": bg,
+		},
+	}
+	lb.Address = "10.0.0.2"
+	lb.BackendGroupId = "bg0"
+	lsps := map[string]string{
+		"10.0.0.10": "iface-n0-vnet0",
+		"10.0.0.11": "iface-n0-vnet1",
+		"10.0.0.12": "iface-n0-vnet2",
+	}
+
+	want := map[string]*lbProtoConf{
+		api.LB_LISTENER_TYPE_TCP: {
+			vips: map[string]string{
+				"10.0.0.2:80": "10.0.0.10:8080,10.0.0.11:8080",
+			},
+			healthChecks: []lbHealthCheck{
+				{
+					vip: "10.0.0.2:80",
+					options: map[string]string{
+						"interval":      "5",
+						"success_count": "2",
+					},
+				},
+			},
+			ipPortMappings: map[string]string{
+				"10.0.0.10": "iface-n0-vnet0:10.0.0.2",
+				"10.0.0.11": "iface-n0-vnet1:10.0.0.2",
+			},
+		},
+		api.LB_LISTENER_TYPE_UDP: {
+			vips: map[string]string{
+				"10.0.0.2:53": "10.0.0.10:8080,10.0.0.11:8080",
+			},
+			ipPortMappings: map[string]string{},
+		},
+	}
+	got := lbProtoConfs(lb, lsps)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	if got[api.LB_LISTENER_TYPE_TCP].hcDigest() == "" {
+		t.Errorf("expecting non-empty health check digest")
+	}
+	if got[api.LB_LISTENER_TYPE_UDP].hcDigest() != "" {
+		t.Errorf("expecting empty health check digest")
+	}
+}
@@ -50,3 +50,7 @@ func HashSubnetDhcpMac(netId string) string {
 func HashSubnetMetadataMac(netId string) string {
 	return HashMac(netId, "md")
 }
+
+func HashVpcPeeringRouterPortMac(peerId string, vpcId string) string {
+	return HashMac(peerId, vpcId, "rp")
+}
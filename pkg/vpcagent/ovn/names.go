@@ -16,6 +16,7 @@ package ovn
 
 import (
 	"fmt"
+	"strings"
 )
 
 func vpcLrName(vpcId string) string {
@@ -97,6 +98,12 @@ func netMdpName(netId string) string {
 	return fmt.Sprintf("subnet-md/%s", netId)
 }
 
+// netDhcp6Ref is the oc-ref of dhcpv6 options, apart from the dhcpv4 one
+// referred to by network id
+func netDhcp6Ref(netId string) string {
+	return fmt.Sprintf("%s/dhcp6", netId)
+}
+
 // gnpName returns Logical_Switch_Port name for guestnetwork
 //
 // The name must match what's going to be set on each chassis
@@ -107,3 +114,30 @@ func gnpName(netId string, ifname string) string {
 func vipName(netId string, groupId string, ipaddr string) string {
 	return fmt.Sprintf("vip-%s-%s-%s", netId, groupId, ipaddr)
 }
+
+func lbName(lbId string, proto string) string {
+	return fmt.Sprintf("lb/%s/%s", lbId, proto)
+}
+
+func natLbName(natId string, proto string) string {
+	return fmt.Sprintf("nat/%s/%s", natId, proto)
+}
+
+// peering
+func vpcPeerLsName(peerId string) string {
+	return fmt.Sprintf("vpc-p/%s", peerId)
+}
+
+func vpcRppName(peerId string, vpcId string) string {
+	return fmt.Sprintf("vpc-rp/%s/%s", peerId, vpcId)
+}
+
+func vpcPrpName(peerId string, vpcId string) string {
+	return fmt.Sprintf("vpc-pr/%s/%s", peerId, vpcId)
+}
+
+// sgAddrSetName returns name of Address_Set holding ipv4 addresses of
+// guests in the security group.  Hyphens are not allowed in the name
+func sgAddrSetName(secgroupId string) string {
+	return "sg_" + strings.ReplaceAll(secgroupId, "-", "_")
+}
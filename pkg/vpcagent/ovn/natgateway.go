@@ -0,0 +1,249 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+	"yunion.io/x/onecloud/pkg/vpcagent/options"
+)
+
+// natSEntryLogicalIp returns the source cidr translated by snat entry
+func natSEntryLogicalIp(nat *agentmodels.NatGateway, sentry *agentmodels.NatSEntry) (string, bool) {
+	cidr := sentry.SourceCIDR
+	if cidr == "" {
+		network, ok := nat.Vpc.Networks[sentry.NetworkId]
+		if !ok {
+			return "", false
+		}
+		cidr = fmt.Sprintf("%s/%d", network.GuestIpStart, network.GuestIpMask)
+	}
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil || ipnet.IP.To4() == nil {
+		return "", false
+	}
+	return ipnet.String(), true
+}
+
+// natDEntryVips returns per-protocol load balancer vips for port forwarding
+// of dnat entries
+func natDEntryVips(nat *agentmodels.NatGateway) map[string]map[string]string {
+	r := map[string]map[string]string{}
+	for _, dentry := range nat.DEntries {
+		if dentry.Status != apis.NAT_STAUTS_AVAILABLE {
+			continue
+		}
+		proto := strings.ToLower(dentry.IpProtocol)
+		switch proto {
+		case apis.LB_LISTENER_TYPE_TCP, apis.LB_LISTENER_TYPE_UDP:
+		default:
+			continue
+		}
+		vips, ok := r[proto]
+		if !ok {
+			vips = map[string]string{}
+			r[proto] = vips
+		}
+		vip := fmt.Sprintf("%s:%d", dentry.ExternalIP, dentry.ExternalPort)
+		vips[vip] = fmt.Sprintf("%s:%d", dentry.InternalIP, dentry.InternalPort)
+	}
+	return r
+}
+
+// ClaimNatGateway realizes on-premise nat gateway on the vpc external router.
+// Snat entries become NAT rows, dnat entries become load balancers doing
+// port forwarding.  Translated traffic goes through the eip gateway port,
+// which is made a distributed gateway port on the configured chassis
+func (keeper *OVNNorthboundKeeper) ClaimNatGateway(ctx context.Context, nat *agentmodels.NatGateway, opts *options.Options) error {
+	var (
+		vpc       = nat.Vpc
+		ocVersion = fmt.Sprintf("%s.%d", nat.UpdatedAt, nat.UpdateVersion)
+		ocNatRef  = fmt.Sprintf("nat/%s", nat.Id)
+		eipgwVip  = apis.VpcEipGatewayIP3().String()
+
+		nats     []*ovn_nb.NAT
+		routes   []*ovn_nb.LogicalRouterStaticRoute
+		qoses    []*ovn_nb.QoS
+		srcCidrs = map[string]struct{}{}
+		eipIps   = map[string]struct{}{}
+	)
+	if !vpcHasEipgw(vpc) {
+		if len(nat.SEntries) > 0 || len(nat.DEntries) > 0 {
+			log.Warningf("natgateway %s(%s): vpc %s has no eip gateway", nat.Name, nat.Id, vpc.Id)
+		}
+		return nil
+	}
+
+	for _, sentry := range nat.SEntries {
+		if sentry.Status != apis.NAT_STAUTS_AVAILABLE {
+			continue
+		}
+		logicalIp, ok := natSEntryLogicalIp(nat, sentry)
+		if !ok {
+			log.Warningf("natgateway %s(%s): snat entry %s has no valid source", nat.Name, nat.Id, sentry.Id)
+			continue
+		}
+		nats = append(nats, &ovn_nb.NAT{
+			Type:       "snat",
+			ExternalIp: sentry.IP,
+			LogicalIp:  logicalIp,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocNatRef,
+			},
+		})
+		srcCidrs[logicalIp] = struct{}{}
+		eipIps[sentry.IP] = struct{}{}
+	}
+	dnatVips := natDEntryVips(nat)
+	for _, dentry := range nat.DEntries {
+		if dentry.Status != apis.NAT_STAUTS_AVAILABLE {
+			continue
+		}
+		// return traffic of port forwarding also goes to eipgw
+		srcCidrs[dentry.InternalIP+"/32"] = struct{}{}
+		eipIps[dentry.ExternalIP] = struct{}{}
+	}
+	for cidr := range srcCidrs {
+		routes = append(routes, &ovn_nb.LogicalRouterStaticRoute{
+			Policy:     ptr("src-ip"),
+			IpPrefix:   cidr,
+			Nexthop:    eipgwVip,
+			OutputPort: ptr(vpcRepName(vpc.Id)),
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocNatRef,
+			},
+		})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].IpPrefix < routes[j].IpPrefix
+	})
+	for eipIp := range eipIps {
+		eip := nat.GetElasticip(eipIp)
+		if eip == nil || eip.Bandwidth <= 0 {
+			continue
+		}
+		var (
+			kbps = int64(eip.Bandwidth * 1000)
+			kbur = int64(kbps * 2)
+		)
+		qoses = append(qoses,
+			&ovn_nb.QoS{
+				Priority:  2000,
+				Direction: "from-lport",
+				Match:     fmt.Sprintf("inport == %q && ip4 && ip4.dst == %s", vpcEipLspName(vpc.Id, eipgwVip), eipIp),
+				Bandwidth: map[string]int64{
+					"rate":  kbps,
+					"burst": kbur,
+				},
+				ExternalIds: map[string]string{
+					externalKeyOcRef: ocNatRef,
+				},
+			},
+			&ovn_nb.QoS{
+				Priority:  3000,
+				Direction: "from-lport",
+				Match:     fmt.Sprintf("inport == %q && ip4 && ip4.src == %s", vpcErpName(vpc.Id), eipIp),
+				Bandwidth: map[string]int64{
+					"rate":  kbps,
+					"burst": kbur,
+				},
+				ExternalIds: map[string]string{
+					externalKeyOcRef: ocNatRef,
+				},
+			},
+		)
+	}
+	sort.Slice(qoses, func(i, j int) bool {
+		return qoses[i].Match < qoses[j].Match
+	})
+
+	var args []string
+	if len(routes) > 0 && opts.OvnNatGatewayChassis != "" {
+		vpcRep := keeper.DB.LogicalRouterPort.FindOneMatchNonZeros(&ovn_nb.LogicalRouterPort{
+			Name: vpcRepName(vpc.Id),
+		})
+		if vpcRep == nil || len(vpcRep.GatewayChassis) == 0 {
+			args = append(args, "--", "lrp-set-gateway-chassis", vpcRepName(vpc.Id), opts.OvnNatGatewayChassis, "100")
+		}
+	}
+
+	irows := make([]types.IRow, 0, len(nats)+len(routes)+len(qoses))
+	for _, irow := range nats {
+		irows = append(irows, irow)
+	}
+	for _, irow := range routes {
+		irows = append(irows, irow)
+	}
+	for _, irow := range qoses {
+		irows = append(irows, irow)
+	}
+	if allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, irows...); !allFound {
+		args = append(args, cleanupArgs...)
+		lrName := vpcExtLrName(vpc.Id)
+		for i, irow := range nats {
+			ref := fmt.Sprintf("nat%d", i)
+			args = append(args, ovnCreateArgs(irow, ref)...)
+			args = append(args, "--", "add", "Logical_Router", lrName, "nat", "@"+ref)
+		}
+		for i, irow := range routes {
+			ref := fmt.Sprintf("natRoute%d", i)
+			args = append(args, ovnCreateArgs(irow, ref)...)
+			args = append(args, "--", "add", "Logical_Router", lrName, "static_routes", "@"+ref)
+		}
+		for i, irow := range qoses {
+			ref := fmt.Sprintf("natQos%d", i)
+			args = append(args, ovnCreateArgs(irow, ref)...)
+			args = append(args, "--", "add", "Logical_Switch", vpcEipLsName(vpc.Id), "qos_rules", "@"+ref)
+		}
+	}
+
+	protos := make([]string, 0, len(dnatVips))
+	for proto := range dnatVips {
+		protos = append(protos, proto)
+	}
+	sort.Strings(protos)
+	for _, proto := range protos {
+		ovnLb := &ovn_nb.LoadBalancer{
+			Name:     natLbName(nat.Id, proto),
+			Protocol: ptr(proto),
+			Vips:     dnatVips[proto],
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocNatRef,
+			},
+		}
+		allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, ovnLb)
+		if allFound {
+			continue
+		}
+		ref := "natLb" + proto
+		args = append(args, cleanupArgs...)
+		args = append(args, ovnCreateArgs(ovnLb, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "load_balancer", "@"+ref)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return keeper.cli.Must(ctx, "ClaimNatGateway", args)
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestNatGatewayEntries(t *testing.T) {
+	network := &agentmodels.Network{}
+	network.Id = "net0"
+	network.GuestIpStart = "192.168.1.2"
+	network.GuestIpMask = 24
+	nat := &agentmodels.NatGateway{
+		Vpc: &agentmodels.Vpc{
+			Networks: agentmodels.Networks{
+				"net0": network,
+			},
+		},
+	}
+
+	t.Run("snat", func(t *testing.T) {
+		cases := []struct {
+			networkId  string
+			sourceCidr string
+			want       string
+			ok         bool
+		}{
+			{networkId: "net0", want: "192.168.1.0/24", ok: true},
+			{sourceCidr: "192.168.1.16/28", want: "192.168.1.16/28", ok: true},
+			{networkId: "net1", ok: false},
+			{sourceCidr: "fd00::/64", ok: false},
+		}
+		for _, c := range cases {
+			sentry := &agentmodels.NatSEntry{}
+			sentry.NetworkId = c.networkId
+			sentry.SourceCIDR = c.sourceCidr
+			got, ok := natSEntryLogicalIp(nat, sentry)
+			if got != c.want || ok != c.ok {
+				t.Errorf("%s%s: got %q,%v want %q,%v", c.networkId, c.sourceCidr, got, ok, c.want, c.ok)
+			}
+		}
+	})
+
+	t.Run("dnat", func(t *testing.T) {
+		newDEntry := func(id, proto string, extPort int, intIp string, intPort int, status string) *agentmodels.NatDEntry {
+			dentry := &agentmodels.NatDEntry{}
+			dentry.Id = id
+			dentry.Status = status
+			dentry.IpProtocol = proto
+			dentry.ExternalIP = "10.0.0.100"
+			dentry.ExternalPort = extPort
+			dentry.InternalIP = intIp
+			dentry.InternalPort = intPort
+			return dentry
+		}
+		nat.DEntries = agentmodels.NatDEntries{
+			"d0": newDEntry("d0", "TCP", 2222, "192.168.1.10", 22, apis.NAT_STAUTS_AVAILABLE),
+			"d1": newDEntry("d1", "udp", 53, "192.168.1.11", 53, apis.NAT_STAUTS_AVAILABLE),
+			"d2": newDEntry("d2", "tcp", 80, "192.168.1.12", 80, apis.NAT_STATUS_ALLOCATE),
+			"d3": newDEntry("d3", "any", 81, "192.168.1.12", 81, apis.NAT_STAUTS_AVAILABLE),
+		}
+		want := map[string]map[string]string{
+			"tcp": {"10.0.0.100:2222": "192.168.1.10:22"},
+			"udp": {"10.0.0.100:53": "192.168.1.11:53"},
+		}
+		got := natDEntryVips(nat)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+	"yunion.io/x/onecloud/pkg/vpcagent/ovn/mac"
+)
+
+func vpcPeeringActive(peer *agentmodels.VpcPeeringConnection) bool {
+	return peer.Vpc != nil && peer.PeerVpc != nil &&
+		peer.Enabled.IsTrue() &&
+		peer.Status == apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE
+}
+
+// vpcPeeringSlots assigns each active peering connection a link subnet in
+// VpcPeeringCidr.  Slots are hashed from the id and collisions are resolved
+// by linear probing in creation order, so that existing links are kept
+// stable when new ones come
+func vpcPeeringSlots(peers agentmodels.VpcPeeringConnections) map[string]int {
+	var actives []*agentmodels.VpcPeeringConnection
+	for _, peer := range peers {
+		if vpcPeeringActive(peer) {
+			actives = append(actives, peer)
+		}
+	}
+	sort.Slice(actives, func(i, j int) bool {
+		if !actives[i].CreatedAt.Equal(actives[j].CreatedAt) {
+			return actives[i].CreatedAt.Before(actives[j].CreatedAt)
+		}
+		return actives[i].Id < actives[j].Id
+	})
+
+	var (
+		r    = map[string]int{}
+		used = map[int]struct{}{}
+	)
+	for _, peer := range actives {
+		if len(used) >= apis.VpcPeeringSlots {
+			log.Errorf("vpc peering %s(%s): no free link address", peer.Name, peer.Id)
+			continue
+		}
+		h := fnv.New32a()
+		h.Write([]byte(peer.Id))
+		slot := int(h.Sum32() % uint32(apis.VpcPeeringSlots))
+		for {
+			if _, ok := used[slot]; !ok {
+				break
+			}
+			slot = (slot + 1) % apis.VpcPeeringSlots
+		}
+		used[slot] = struct{}{}
+		r[peer.Id] = slot
+	}
+	return r
+}
+
+// vpcPeeringIP returns address of the router port on vpc side of the link
+func vpcPeeringIP(peer *agentmodels.VpcPeeringConnection, slot int, vpcId string) string {
+	ip1, ip2 := apis.VpcPeeringIPs(slot)
+	if vpcId == peer.VpcId {
+		return ip1.String()
+	}
+	return ip2.String()
+}
+
+// resolvePeeringRoutes returns routes to cidr blocks of peer vpcs through
+// the peering links
+func resolvePeeringRoutes(vpc *agentmodels.Vpc, peerSlots map[string]int) resolvedRoutes {
+	var r resolvedRoutes
+	for _, peer := range vpc.PeeringConnections {
+		slot, ok := peerSlots[peer.Id]
+		if !ok {
+			continue
+		}
+		peerVpc := peer.PeerVpc
+		if peerVpc.Id == vpc.Id {
+			peerVpc = peer.Vpc
+		}
+		for _, cidr := range strings.Split(peerVpc.CidrBlock, ",") {
+			cidr = strings.TrimSpace(cidr)
+			if cidr == "" {
+				continue
+			}
+			r = append(r, resolvedRoute{
+				Cidr:       cidr,
+				NextHop:    vpcPeeringIP(peer, slot, peerVpc.Id),
+				OutputPort: vpcRppName(peer.Id, vpc.Id),
+			})
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Cidr < r[j].Cidr
+	})
+	return r
+}
+
+// ClaimVpcPeeringConnection connects routers of the two vpcs with a
+// logical switch.  Routes through it are installed by ClaimRoutes
+func (keeper *OVNNorthboundKeeper) ClaimVpcPeeringConnection(ctx context.Context, peer *agentmodels.VpcPeeringConnection, slot int) error {
+	var (
+		ocVersion = fmt.Sprintf("%s.%d", peer.UpdatedAt, peer.UpdateVersion)
+		vpcIds    = []string{peer.VpcId, peer.PeerVpcId}
+		rpps      = make([]*ovn_nb.LogicalRouterPort, len(vpcIds))
+		prps      = make([]*ovn_nb.LogicalSwitchPort, len(vpcIds))
+	)
+	peerLs := &ovn_nb.LogicalSwitch{
+		Name: vpcPeerLsName(peer.Id),
+	}
+	irows := []types.IRow{peerLs}
+	for i, vpcId := range vpcIds {
+		rpps[i] = &ovn_nb.LogicalRouterPort{
+			Name:     vpcRppName(peer.Id, vpcId),
+			Mac:      mac.HashVpcPeeringRouterPortMac(peer.Id, vpcId),
+			Networks: []string{fmt.Sprintf("%s/%d", vpcPeeringIP(peer, slot, vpcId), apis.VpcPeeringMask)},
+		}
+		prps[i] = &ovn_nb.LogicalSwitchPort{
+			Name:      vpcPrpName(peer.Id, vpcId),
+			Type:      "router",
+			Addresses: []string{"router"},
+			Options: map[string]string{
+				"router-port": rpps[i].Name,
+			},
+		}
+		irows = append(irows, rpps[i], prps[i])
+	}
+
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
+	if allFound {
+		return nil
+	}
+	args = append(args, ovnCreateArgs(peerLs, peerLs.Name)...)
+	for i, vpcId := range vpcIds {
+		args = append(args, ovnCreateArgs(rpps[i], rpps[i].Name)...)
+		args = append(args, ovnCreateArgs(prps[i], prps[i].Name)...)
+		args = append(args, "--", "add", "Logical_Switch", peerLs.Name, "ports", "@"+prps[i].Name)
+		args = append(args, "--", "add", "Logical_Router", vpcLrName(vpcId), "ports", "@"+rpps[i].Name)
+	}
+	return keeper.cli.Must(ctx, "ClaimVpcPeeringConnection", args)
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"yunion.io/x/pkg/tristate"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestVpcPeering(t *testing.T) {
+	newVpc := func(id, cidr string) *agentmodels.Vpc {
+		vpc := &agentmodels.Vpc{
+			PeeringConnections: agentmodels.VpcPeeringConnections{},
+		}
+		vpc.Id = id
+		vpc.CidrBlock = cidr
+		return vpc
+	}
+	newPeer := func(id string, vpc, peerVpc *agentmodels.Vpc, status string, createdAt time.Time) *agentmodels.VpcPeeringConnection {
+		peer := &agentmodels.VpcPeeringConnection{
+			Vpc:     vpc,
+			PeerVpc: peerVpc,
+		}
+		peer.Id = id
+		peer.VpcId = vpc.Id
+		peer.PeerVpcId = peerVpc.Id
+		peer.Status = status
+		peer.Enabled = tristate.True
+		peer.CreatedAt = createdAt
+		vpc.PeeringConnections[id] = peer
+		peerVpc.PeeringConnections[id] = peer
+		return peer
+	}
+	var (
+		now  = time.Now()
+		vpc0 = newVpc("vpc0", "192.168.0.0/16")
+		vpc1 = newVpc("vpc1", "10.1.0.0/16,10.2.0.0/16")
+		vpc2 = newVpc("vpc2", "172.16.0.0/12")
+
+		peers = agentmodels.VpcPeeringConnections{
+			"p0": newPeer("p0", vpc0, vpc1, apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE, now),
+			"p1": newPeer("p1", vpc2, vpc0, apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE, now.Add(time.Second)),
+			"p2": newPeer("p2", vpc1, vpc2, apis.VPC_PEERING_CONNECTION_STATUS_PENDING_ACCEPT, now),
+		}
+	)
+
+	slots := vpcPeeringSlots(peers)
+	if len(slots) != 2 {
+		t.Fatalf("want 2 slots, got %v", slots)
+	}
+	if slots["p0"] == slots["p1"] {
+		t.Fatalf("slot collision: %v", slots)
+	}
+	if !reflect.DeepEqual(slots, vpcPeeringSlots(peers)) {
+		t.Errorf("slots not stable")
+	}
+
+	ip0, ip1 := apis.VpcPeeringIPs(slots["p0"])
+	got := resolvePeeringRoutes(vpc0, slots)
+	want := resolvedRoutes{
+		{Cidr: "10.1.0.0/16", NextHop: ip1.String(), OutputPort: vpcRppName("p0", "vpc0")},
+		{Cidr: "10.2.0.0/16", NextHop: ip1.String(), OutputPort: vpcRppName("p0", "vpc0")},
+		{Cidr: "172.16.0.0/12", NextHop: vpcPeeringIP(peers["p1"], slots["p1"], "vpc2"), OutputPort: vpcRppName("p1", "vpc0")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("vpc0 routes: got %v, want %v", got, want)
+	}
+
+	got = resolvePeeringRoutes(vpc1, slots)
+	want = resolvedRoutes{
+		{Cidr: "192.168.0.0/16", NextHop: ip0.String(), OutputPort: vpcRppName("p0", "vpc1")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("vpc1 routes: got %v, want %v", got, want)
+	}
+}
@@ -24,15 +24,16 @@ type resolvedRoutes []resolvedRoute
 type resolvedRoute struct {
 	Cidr         string
 	NextHop      string
+	OutputPort   string
 	Network      *agentmodels.Network
 	Guestnetwork *agentmodels.Guestnetwork
 }
 
-func resolveRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets) resolvedRoutes {
+func resolveRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets, peerSlots map[string]int) resolvedRoutes {
+	r := resolvePeeringRoutes(vpc, peerSlots)
 	if vpc.RouteTable == nil || vpc.RouteTable.Routes == nil {
-		return nil
+		return r
 	}
-	var r resolvedRoutes
 
 	routesModel := *vpc.RouteTable.Routes
 	for _, routeModel := range routesModel {
@@ -62,7 +63,7 @@ func resolveRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets) resolvedRou
 				})
 			}
 		default:
-			return nil
+			return resolvePeeringRoutes(vpc, peerSlots)
 		}
 	}
 	return r
@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+// peerSecgroupAddrs returns ipv4 addresses of member guests for each
+// security group referred to by peer security group rules
+func peerSecgroupAddrs(mss *agentmodels.ModelSets) map[string][]string {
+	sets := map[string]map[string]struct{}{}
+	for _, sgr := range mss.SecurityGroupRules {
+		if sgr.PeerSecgroupId != "" {
+			sets[sgr.PeerSecgroupId] = map[string]struct{}{}
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	for _, guest := range mss.Guests {
+		var addrs []string
+		for _, guestnetwork := range guest.Guestnetworks {
+			if guestnetwork.IpAddr != "" {
+				addrs = append(addrs, guestnetwork.IpAddr)
+			}
+			for _, na := range guestnetwork.SubIPs {
+				addrs = append(addrs, na.IpAddr)
+			}
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+		secgroupIds := make([]string, 0, len(guest.SecurityGroups)+1)
+		for secgroupId := range guest.SecurityGroups {
+			secgroupIds = append(secgroupIds, secgroupId)
+		}
+		if guest.AdminSecurityGroup != nil {
+			secgroupIds = append(secgroupIds, guest.AdminSecurityGroup.Id)
+		}
+		for _, secgroupId := range secgroupIds {
+			set, ok := sets[secgroupId]
+			if !ok {
+				continue
+			}
+			for _, addr := range addrs {
+				set[addr] = struct{}{}
+			}
+		}
+	}
+
+	r := make(map[string][]string, len(sets))
+	for secgroupId, set := range sets {
+		// empty, not nil, as match condition
+		addrs := make([]string, 0, len(set))
+		for addr := range set {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+		r[secgroupId] = addrs
+	}
+	return r
+}
+
+// ClaimSecgroupAddressSets keeps address sets referred to in acls of peer
+// security group rules up to date as guests join and leave the groups.
+// They must exist before acls referring to them
+func (keeper *OVNNorthboundKeeper) ClaimSecgroupAddressSets(ctx context.Context, mss *agentmodels.ModelSets) error {
+	var (
+		sgAddrs   = peerSecgroupAddrs(mss)
+		secgroups = make([]string, 0, len(sgAddrs))
+		irows     = make([]types.IRow, 0, len(sgAddrs))
+		args      []string
+	)
+	for secgroupId := range sgAddrs {
+		secgroups = append(secgroups, secgroupId)
+	}
+	sort.Strings(secgroups)
+	for _, secgroupId := range secgroups {
+		addrSet := &ovn_nb.AddressSet{
+			Name:      sgAddrSetName(secgroupId),
+			Addresses: sgAddrs[secgroupId],
+			ExternalIds: map[string]string{
+				externalKeyOcRef: secgroupId,
+			},
+		}
+		ocVersion := ""
+		if secgroup, ok := mss.SecurityGroups[secgroupId]; ok {
+			ocVersion = fmt.Sprintf("%s.%d", secgroup.UpdatedAt, secgroup.UpdateVersion)
+		}
+		if allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, addrSet); !allFound {
+			args = append(args, cleanupArgs...)
+			irows = append(irows, addrSet)
+		}
+	}
+	for i, irow := range irows {
+		args = append(args, ovnCreateArgs(irow, fmt.Sprintf("as%d", i))...)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return keeper.cli.Must(ctx, "ClaimSecgroupAddressSets", args)
+}
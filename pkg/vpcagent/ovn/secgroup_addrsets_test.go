@@ -0,0 +1,75 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestPeerSecgroupAddrs(t *testing.T) {
+	mss := agentmodels.NewModelSets()
+	newSecgroup := func(id string) *agentmodels.SecurityGroup {
+		sg := &agentmodels.SecurityGroup{}
+		sg.Id = id
+		mss.SecurityGroups[id] = sg
+		return sg
+	}
+	newGuest := func(id string, addrs []string, secgroups ...*agentmodels.SecurityGroup) *agentmodels.Guest {
+		guest := &agentmodels.Guest{
+			SecurityGroups: agentmodels.SecurityGroups{},
+			Guestnetworks:  agentmodels.Guestnetworks{},
+		}
+		guest.Id = id
+		for _, sg := range secgroups {
+			guest.SecurityGroups[sg.Id] = sg
+		}
+		for i, addr := range addrs {
+			gn := &agentmodels.Guestnetwork{}
+			gn.IpAddr = addr
+			guest.Guestnetworks[string(rune('a'+i))] = gn
+		}
+		mss.Guests[id] = guest
+		return guest
+	}
+	var (
+		sgApp   = newSecgroup("app")
+		sgDb    = newSecgroup("db")
+		sgAdmin = newSecgroup("admin")
+		sgEmpty = newSecgroup("empty")
+	)
+	newGuest("g0", []string{"10.0.0.2", "192.168.0.2"}, sgApp)
+	newGuest("g1", []string{"10.0.0.3"}, sgApp, sgDb)
+	newGuest("g2", []string{"10.0.0.4"}, sgDb).AdminSecurityGroup = sgAdmin
+	for i, peerId := range []string{sgApp.Id, sgAdmin.Id, sgEmpty.Id, ""} {
+		sgr := &agentmodels.SecurityGroupRule{}
+		sgr.Id = string(rune('a' + i))
+		sgr.SecgroupId = sgDb.Id
+		sgr.PeerSecgroupId = peerId
+		mss.SecurityGroupRules[sgr.Id] = sgr
+	}
+
+	got := peerSecgroupAddrs(mss)
+	want := map[string][]string{
+		"app":   {"10.0.0.2", "10.0.0.3", "192.168.0.2"},
+		"admin": {"10.0.0.4"},
+		"empty": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
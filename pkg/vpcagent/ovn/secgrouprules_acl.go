@@ -71,9 +71,26 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL,
 		return nil, errors.Wrapf(errBadSecgroupRule, "unknown action %q", rule.Action)
 	}
 
+	// rules without cidr match ipv4 only, ipv6 traffic must be allowed
+	// explicitly with ipv6 cidr like ::/0
+	cidr := strings.TrimSpace(rule.CIDR)
+	isIp6 := strings.Contains(cidr, ":")
 	addL3Match := func() {
+		if rule.PeerSecgroupId != "" {
+			// addresses of peer guests are kept in address set
+			matches = append(matches, "ip4")
+			matches = append(matches, fmt.Sprintf("ip4.%s == $%s", l3subfn, sgAddrSetName(rule.PeerSecgroupId)))
+			return
+		}
+		if isIp6 {
+			matches = append(matches, "ip6")
+			if cidr != "::/0" {
+				matches = append(matches, fmt.Sprintf("ip6.%s == %s", l3subfn, cidr))
+			}
+			return
+		}
 		matches = append(matches, "ip4")
-		if cidr := strings.TrimSpace(rule.CIDR); cidr != "" && cidr != "0.0.0.0/0" {
+		if cidr != "" && cidr != "0.0.0.0/0" {
 			matches = append(matches, fmt.Sprintf("ip4.%s == %s", l3subfn, cidr))
 		}
 	}
@@ -153,7 +170,11 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL,
 		addL4Match("udp")
 	case secrules.PROTO_ICMP:
 		addL3Match()
-		matches = append(matches, "icmp4")
+		if isIp6 {
+			matches = append(matches, "icmp6")
+		} else {
+			matches = append(matches, "icmp4")
+		}
 	default:
 		return nil, errors.Wrapf(errBadSecgroupRule, "unknown protocol %q", rule.Protocol)
 	}
@@ -106,6 +106,62 @@ func TestRuleToACL(t *testing.T) {
 				Priority:  100,
 			},
 		},
+		{
+			// ingress allow ssh from ipv6 subnet
+			rule: &agentmodels.SecurityGroupRule{
+				SSecurityGroupRule: models.SSecurityGroupRule{
+					Direction: string(secrules.SecurityRuleIngress),
+					CIDR:      "2001:db8::/64",
+					Action:    string(secrules.SecurityRuleAllow),
+					Protocol:  secrules.PROTO_TCP,
+					Ports:     "22",
+					Priority:  100,
+				},
+			},
+			acl: &ovn_nb.ACL{
+				Direction: aclDirToLport,
+				Action:    "allow-related",
+				Match:     fmt.Sprintf("outport == %q && ip6 && ip6.src == 2001:db8::/64 && tcp && tcp.dst == 22", lport),
+				Priority:  100,
+			},
+		},
+		{
+			// egress deny icmpv6
+			rule: &agentmodels.SecurityGroupRule{
+				SSecurityGroupRule: models.SSecurityGroupRule{
+					Direction: string(secrules.SecurityRuleEgress),
+					CIDR:      "::/0",
+					Action:    string(secrules.SecurityRuleDeny),
+					Protocol:  secrules.PROTO_ICMP,
+					Priority:  90,
+				},
+			},
+			acl: &ovn_nb.ACL{
+				Direction: aclDirFromLport,
+				Action:    "drop",
+				Match:     fmt.Sprintf("inport == %q && ip6 && icmp6", lport),
+				Priority:  90,
+			},
+		},
+		{
+			// ingress allow tcp 5432 from peer secgroup
+			rule: &agentmodels.SecurityGroupRule{
+				SSecurityGroupRule: models.SSecurityGroupRule{
+					Direction:      string(secrules.SecurityRuleIngress),
+					PeerSecgroupId: "0d4f1e3c-app-tier",
+					Action:         string(secrules.SecurityRuleAllow),
+					Protocol:       secrules.PROTO_TCP,
+					Ports:          "5432",
+					Priority:       50,
+				},
+			},
+			acl: &ovn_nb.ACL{
+				Direction: aclDirToLport,
+				Action:    "allow-related",
+				Match:     fmt.Sprintf("outport == %q && ip4 && ip4.src == $sg_0d4f1e3c_app_tier && tcp && tcp.dst == 5432", lport),
+				Priority:  50,
+			},
+		},
 	}
 
 	for _, c := range cases {
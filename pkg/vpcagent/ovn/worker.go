@@ -118,6 +118,8 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 	}
 
 	ovndb.Mark(ctx)
+	peerSlots := vpcPeeringSlots(mss.VpcPeeringConnections)
+	ovndb.ClaimSecgroupAddressSets(ctx, mss)
 	for _, vpc := range mss.Vpcs {
 		if vpc.Id == apis.DEFAULT_VPC_ID {
 			continue
@@ -170,8 +172,19 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 				ovndb.ClaimGroupnetwork(ctx, groupnetwork)
 			}
 		}
-		routes := resolveRoutes(vpc, mss)
+		routes := resolveRoutes(vpc, mss, peerSlots)
 		ovndb.ClaimRoutes(ctx, vpc, routes)
+		for _, network := range vpc.Networks {
+			for _, lb := range network.Loadbalancers {
+				ovndb.ClaimLoadbalancer(ctx, lb)
+			}
+		}
+		for _, nat := range vpc.NatGateways {
+			ovndb.ClaimNatGateway(ctx, nat, w.opts)
+		}
+	}
+	for peerId, slot := range peerSlots {
+		ovndb.ClaimVpcPeeringConnection(ctx, mss.VpcPeeringConnections[peerId], slot)
 	}
 	for _, vpc := range mss.Vpcs {
 		if vpc.Id == apis.DEFAULT_VPC_ID {
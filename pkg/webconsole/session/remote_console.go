@@ -42,6 +42,7 @@ const (
 	APSARA    = api.APSARA
 	JDCLOUD   = api.JDCLOUD
 	CLOUDPODS = api.CLOUDPODS
+	PROXMOX   = api.PROXMOX
 )
 
 type RemoteConsoleInfo cloudprovider.ServerVncOutput
@@ -121,7 +122,7 @@ func (info *RemoteConsoleInfo) GetConnectParams() (string, error) {
 		return info.getQcloudURL()
 	case CLOUDPODS:
 		return info.getCloudpodsURL()
-	case OPENSTACK, VMRC, ZSTACK, CTYUN, HUAWEI, JDCLOUD:
+	case OPENSTACK, VMRC, ZSTACK, CTYUN, HUAWEI, JDCLOUD, PROXMOX:
 		return info.Url, nil
 	default:
 		return "", fmt.Errorf("Can't convert protocol %s to connect params", info.Protocol)